@@ -1,19 +1,25 @@
 package main
 
 import (
+	"context"
 	"log"
 	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
-	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
-	"github.com/go-chi/cors"
 
 	"github.com/NoroSaroyan/log-parser/internal/app"
+	"github.com/NoroSaroyan/log-parser/internal/config"
 	v1 "github.com/NoroSaroyan/log-parser/internal/handlers/api/v1"
+	"github.com/NoroSaroyan/log-parser/internal/infrastructure/logger"
+	"github.com/NoroSaroyan/log-parser/internal/infrastructure/metrics"
+	"github.com/NoroSaroyan/log-parser/internal/infrastructure/tracing"
+	"github.com/NoroSaroyan/log-parser/internal/services/dispatcher"
+	"github.com/NoroSaroyan/log-parser/internal/services/health"
+	"github.com/NoroSaroyan/log-parser/internal/services/ingestion"
 	httpSwagger "github.com/swaggo/http-swagger"
 
 	_ "github.com/NoroSaroyan/log-parser/cmd/api/docs"
@@ -29,59 +35,115 @@ func main() {
 	if len(file) == 0 {
 		file = "configs/config.yaml"
 	}
-	application, err := app.InitializeApp(file)
+	cfg, err := config.LoadConfig(file)
+	if err != nil {
+		log.Fatalf("Failed to load config: %v", err)
+	}
+	// Set up the logger first, so that the services log as configured from
+	// the start.
+	if err := logger.Init(cfg.Logger); err != nil {
+		log.Fatalf("Failed to initialize logger: %v", err)
+	}
+	defer logger.Close()
+	application, err := app.InitializeAppWithConfig(cfg)
 	if err != nil {
 		log.Fatalf("Failed to initialize app: %v", err)
 	}
 	defer application.CloseDB()
 
+	// Uploaded files go through the same services as the CLI process mode.
+	ingestionCfg := application.Config.Ingestion
+	ingestionService := ingestion.NewIngestionService(ingestion.Config{
+		Workers:        ingestionCfg.Workers,
+		QueueSize:      ingestionCfg.QueueSize,
+		RetainJobs:     ingestionCfg.RetainJobs,
+		Dir:            ingestionCfg.Dir,
+		MaxUploadBytes: int64(ingestionCfg.MaxUploadMB) << 20,
+	}, dispatcher.NewDispatcherService(
+		application.DownloadInfoService,
+		application.LogisticService,
+		application.TestStationService,
+		application.TestStepService,
+	), application.ValidationService, application.ConsistencyService)
+
 	r := chi.NewRouter()
 
-	corsMiddleware := cors.New(cors.Options{
-		AllowedOrigins:   []string{"*"},
-		AllowedMethods:   []string{"GET"},
-		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token"},
-		AllowCredentials: false,
-		MaxAge:           300,
-	})
-	r.Use(corsMiddleware.Handler)
+	if application.Config.Tracing.Enabled {
+		r.Use(tracing.Middleware)
+	}
+	metricsCfg := application.Config.Metrics
+	if metricsCfg.Enabled {
+		r.Use(metrics.Middleware)
+	}
 
-	r.Use(middleware.Logger)
+	r.Use(v1.CORS(application.Config.CORS))
+
+	r.Use(v1.RequestLogger)
 	r.Use(middleware.Recoverer)
 
+	v1.RegisterHealth(r, application.HealthService.With(health.IngestionBacklog(ingestionService)))
+
 	r.Route("/api/v1", func(r chi.Router) {
-		v1.RegisterAPIV1(r,
-			application.DownloadInfoService,
-			application.LogisticService,
-			application.TestStationService,
-			application.TestStepService,
-		)
+		v1.RegisterAPIV1(r, v1.Services{
+			DownloadInfo: application.DownloadInfoService,
+			Logistic:     application.LogisticService,
+			TestStation:  application.TestStationService,
+			TestStep:     application.TestStepService,
+			Consistency:  application.ConsistencyService,
+			Device:       application.DeviceService,
+			Analytics:    application.AnalyticsService,
+			Auth:         application.AuthService,
+			Ingestion:    ingestionService,
+		})
 	})
 
 	r.Get("/swagger/*", httpSwagger.WrapHandler)
 
+	if metricsCfg.Enabled {
+		r.Handle(metricsCfg.Path, metrics.Handler())
+	}
+
+	serverCfg := application.Config.Server
+	security := application.Config.Security
 	server := &http.Server{
-		Addr:         ":8080",
+		Addr:         serverCfg.Address,
 		Handler:      r,
-		ReadTimeout:  10 * time.Second,
-		WriteTimeout: 15 * time.Second,
+		ReadTimeout:  serverCfg.ReadTimeout,
+		WriteTimeout: serverCfg.WriteTimeout,
+		IdleTimeout:  serverCfg.IdleTimeout,
 	}
 
 	errs := make(chan error, 1)
 
 	go func() {
+		if security.EnableTLS {
+			log.Printf("Starting REST API server on %s with TLS", server.Addr)
+			errs <- server.ListenAndServeTLS(security.CertFile, security.KeyFile)
+			return
+		}
 		log.Printf("Starting REST API server on %s", server.Addr)
 		errs <- server.ListenAndServe()
 	}()
 
 	stop := make(chan os.Signal, 1)
 	signal.Notify(stop, syscall.SIGINT, syscall.SIGTERM)
-	<-stop
+	select {
+	case <-stop:
+	case err := <-errs:
+		// The server did not start, e.g. the address is in use.
+		ingestionService.Close()
+		log.Fatalf("Fatal error: %v", err)
+	}
 
 	log.Println("Received shutdown signal, shutting down server gracefully...")
-	if err := server.Close(); err != nil {
+	ctx, cancel := context.WithTimeout(context.Background(), serverCfg.ShutdownTimeout)
+	defer cancel()
+	if err := server.Shutdown(ctx); err != nil {
 		log.Printf("Error during server shutdown: %v", err)
+		_ = server.Close()
 	}
+	log.Println("Waiting for running ingestion jobs...")
+	ingestionService.Close()
 
 	if err := <-errs; err != nil && err != http.ErrServerClosed {
 		log.Fatalf("Fatal error: %v", err)
@@ -1,15 +1,29 @@
 package app
 
 import (
+	"context"
 	"fmt"
 	"github.com/NoroSaroyan/log-parser/internal/config"
+	"github.com/NoroSaroyan/log-parser/internal/domain/repositories"
 	"github.com/NoroSaroyan/log-parser/internal/infrastructure/database"
-	"github.com/NoroSaroyan/log-parser/internal/infrastructure/database/repositories"
+	"github.com/NoroSaroyan/log-parser/internal/infrastructure/database/memory"
+	postgresrepo "github.com/NoroSaroyan/log-parser/internal/infrastructure/database/repositories"
+	"github.com/NoroSaroyan/log-parser/internal/infrastructure/encryption"
+	"github.com/NoroSaroyan/log-parser/internal/infrastructure/metrics"
+	"github.com/NoroSaroyan/log-parser/internal/infrastructure/redaction"
+	"github.com/NoroSaroyan/log-parser/internal/infrastructure/tracing"
+	"github.com/NoroSaroyan/log-parser/internal/services/analytics"
+	"github.com/NoroSaroyan/log-parser/internal/services/auth"
+	"github.com/NoroSaroyan/log-parser/internal/services/consistency"
+	"github.com/NoroSaroyan/log-parser/internal/services/device"
 	"github.com/NoroSaroyan/log-parser/internal/services/downloadinfo"
+	"github.com/NoroSaroyan/log-parser/internal/services/health"
 	"github.com/NoroSaroyan/log-parser/internal/services/logistic"
 	"github.com/NoroSaroyan/log-parser/internal/services/teststation"
 	"github.com/NoroSaroyan/log-parser/internal/services/teststep"
+	"github.com/NoroSaroyan/log-parser/internal/services/validation"
 	"log"
+	"time"
 )
 
 type App struct {
@@ -17,41 +31,164 @@ type App struct {
 	LogisticService     logistic.LogisticDataService
 	TestStationService  teststation.TestStationService
 	TestStepService     teststep.TestStepService
-	CloseDB             func() error
+	ValidationService   validation.ValidationService
+	ConsistencyService  consistency.ConsistencyService
+	DeviceService       device.DeviceService
+	AnalyticsService    analytics.AnalyticsService
+	AuthService         auth.AuthService
+	HealthService       health.HealthService
+	// CloseDB closes the database connection and flushes the pending spans.
+	CloseDB func() error
+	// Config is the configuration the app was initialized with; it is the
+	// default configuration for in-memory apps.
+	Config *config.Config
 }
 
+// tracingShutdownTimeout bounds the export of the last spans on CloseDB.
+const tracingShutdownTimeout = 5 * time.Second
+
+// InitializeApp loads the configuration at configPath and initializes the
+// app with it.
 func InitializeApp(configPath string) (*App, error) {
 	cfg, err := config.LoadConfig(configPath)
 	if err != nil {
 		return nil, err
 	}
+	return InitializeAppWithConfig(cfg)
+}
+
+// InitializeAppWithConfig connects to the database of cfg and wires all
+// services on top of it. Callers that need the configuration before the app,
+// e.g. to set up the logger, load it themselves.
+func InitializeAppWithConfig(cfg *config.Config) (*App, error) {
+	policy, err := NewRedactionPolicy(cfg.Redaction)
+	if err != nil {
+		return nil, err
+	}
+	redaction.SetPolicy(policy)
+
+	keys, err := encryption.LoadKeyring(cfg.Encryption)
+	if err != nil {
+		return nil, err
+	}
+	if keys == nil {
+		log.Println("No encryption keys configured: BLE pairing keys are stored in plaintext")
+	}
 
 	db, err := database.NewPostgresDB(&cfg.Database)
 
 	if err != nil {
-		log.Printf("Failed to connect to database: %w", err)
-		fmt.Printf("Config: %v", cfg.Database)
+		log.Printf("Failed to connect to database: %v", err)
 		return nil, err
 	}
 	log.Println("Connected to Postgres database")
+	metrics.RegisterDBStats(db.Stats)
 
-	downloadRepo := repositories.NewDownloadInfoRepository(db)
-	logisticRepo := repositories.NewLogisticDataRepository(db)
-	testStationRepo := repositories.NewTestStationRecordRepository(db)
-	testStepRepo := repositories.NewTestStepRepository(db)
-
-	downloadService := downloadinfo.NewDownloadInfoService(downloadRepo)
-	logisticService := logistic.NewLogisticDataService(logisticRepo)
-	testStationService := teststation.NewTestStationService(testStationRepo)
-	testStepService := teststep.NewTestStepService(testStepRepo)
+	shutdownTracing, err := tracing.Init(context.Background(), cfg.Tracing)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	closeAll := func() error {
+		err := db.Close()
+		ctx, cancel := context.WithTimeout(context.Background(), tracingShutdownTimeout)
+		defer cancel()
+		if terr := shutdownTracing(ctx); err == nil {
+			err = terr
+		}
+		return err
+	}
 
-	app := &App{
-		DownloadInfoService: downloadService,
-		LogisticService:     logisticService,
-		TestStationService:  testStationService,
-		TestStepService:     testStepService,
-		CloseDB:             db.Close,
+	app, err := newApp(
+		cfg.Auth,
+		postgresrepo.NewDownloadInfoRepository(db),
+		postgresrepo.NewLogisticDataRepository(db, keys),
+		postgresrepo.NewTestStationRecordRepository(db),
+		postgresrepo.NewTestStepRepository(db),
+		postgresrepo.NewValidationRepository(db),
+		postgresrepo.NewLogisticConflictRepository(db),
+		postgresrepo.NewAnalyticsRepository(db),
+		postgresrepo.NewAPIKeyRepository(db),
+		postgresrepo.NewHealthRepository(db),
+		closeAll,
+	)
+	if err != nil {
+		closeAll()
+		return nil, err
 	}
+	app.Config = cfg
 
 	return app, nil
 }
+
+// InitializeInMemoryApp wires all services on top of the given in-memory store
+// instead of Postgres. It is used by tests and by CLI modes that must not
+// touch the database. Authentication is disabled and anonymous callers are
+// admins.
+func InitializeInMemoryApp(store *memory.Store) *App {
+	cfg := config.Default()
+	cfg.Auth.AllowAnonymousAdmin = true
+	app, _ := newApp(
+		cfg.Auth,
+		memory.NewDownloadInfoRepository(store),
+		memory.NewLogisticDataRepository(store, nil),
+		memory.NewTestStationRecordRepository(store),
+		memory.NewTestStepRepository(store),
+		memory.NewValidationRepository(store),
+		memory.NewLogisticConflictRepository(store),
+		memory.NewAnalyticsRepository(store),
+		memory.NewAPIKeyRepository(store),
+		memory.NewHealthRepository(store),
+		func() error { return nil },
+	)
+	app.Config = cfg
+	return app
+}
+
+func newApp(
+	authCfg config.AuthConfig,
+	downloadRepo repositories.DownloadInfoRepository,
+	logisticRepo repositories.LogisticDataRepository,
+	testStationRepo repositories.TestStationRecordRepository,
+	testStepRepo repositories.TestStepRepository,
+	validationRepo repositories.ValidationRepository,
+	conflictRepo repositories.LogisticConflictRepository,
+	analyticsRepo repositories.AnalyticsRepository,
+	apiKeyRepo repositories.APIKeyRepository,
+	healthRepo repositories.HealthRepository,
+	closeDB func() error,
+) (*App, error) {
+	authService, err := auth.NewAuthService(authCfg, apiKeyRepo)
+	if err != nil {
+		return nil, err
+	}
+
+	return &App{
+		DownloadInfoService: downloadinfo.NewDownloadInfoService(downloadRepo),
+		LogisticService:     logistic.NewLogisticDataService(logisticRepo),
+		TestStationService:  teststation.NewTestStationService(testStationRepo),
+		TestStepService:     teststep.NewTestStepService(testStepRepo),
+		ValidationService:   validation.NewValidationService(validationRepo),
+		ConsistencyService:  consistency.NewConsistencyService(testStationRepo, logisticRepo, conflictRepo),
+		DeviceService:       device.NewDeviceService(downloadRepo, logisticRepo, testStationRepo, testStepRepo),
+		AnalyticsService:    analytics.NewAnalyticsService(analyticsRepo),
+		AuthService:         authService,
+		HealthService:       health.NewHealthService(healthRepo),
+		CloseDB:             closeDB,
+	}, nil
+}
+
+// NewRedactionPolicy builds the redaction policy of cfg on top of the
+// built-in rules.
+func NewRedactionPolicy(cfg config.RedactionConfig) (*redaction.Policy, error) {
+	rules := make(map[string]redaction.Rule, len(cfg.Fields))
+	for field, rule := range cfg.Fields {
+		if rule.RevealTo != "" {
+			if _, err := auth.ParseRole(rule.RevealTo); err != nil {
+				return nil, fmt.Errorf("invalid reveal_to for redacted field %s: %w", field, err)
+			}
+		}
+		rules[field] = redaction.Rule{Action: redaction.Action(rule.Action), RevealTo: rule.RevealTo}
+	}
+	return redaction.NewPolicy(rules)
+}
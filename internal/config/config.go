@@ -1,31 +1,96 @@
+/*
+Package config loads the configuration of the application from a YAML file
+such as configs/config.yaml.
+
+Implementation notes:
+  - LoadConfig starts from Default, so a key missing from the file keeps its
+    default value; then environment variables override the file (see
+    ApplyEnv) and the result is validated (see Validate).
+  - Durations are written as Go durations, e.g. 30s or 5m.
+  - The configuration holds secrets such as the database password and must
+    not be printed as a whole.
+*/
 package config
 
 import (
 	"fmt"
-	_ "github.com/lib/pq"
-	"gopkg.in/yaml.v3"
 	"os"
+	"time"
+
+	"gopkg.in/yaml.v3"
 )
 
 type Config struct {
-	Database DatabaseConfig `yaml:"database"`
-	Logger   LoggerConfig   `yaml:"logger"`
-	Server   ServerConfig   `yaml:"server"`
+	Database   DatabaseConfig   `yaml:"database"`
+	Logger     LoggerConfig     `yaml:"logger"`
+	Server     ServerConfig     `yaml:"server"`
+	Ingestion  IngestionConfig  `yaml:"ingestion"`
+	Metrics    MetricsConfig    `yaml:"metrics"`
+	Tracing    TracingConfig    `yaml:"tracing"`
+	Auth       AuthConfig       `yaml:"auth"`
+	Redaction  RedactionConfig  `yaml:"redaction"`
+	Encryption EncryptionConfig `yaml:"encryption"`
+	Security   SecurityConfig   `yaml:"security"`
+	CORS       CORSConfig       `yaml:"cors"`
 }
 
+// DatabaseConfig configures the Postgres connection and its pool. A zero
+// MaxOpenConns means no limit; a zero ConnMaxLifetime keeps connections
+// forever.
 type DatabaseConfig struct {
-	Host     string `yaml:"host"`
-	Port     int    `yaml:"port"`
-	User     string `yaml:"user"`
-	Password string `yaml:"password"`
-	Name     string `yaml:"name"`
-	SSLMode  string `yaml:"sslmode"`
+	Host            string        `yaml:"host"`
+	Port            int           `yaml:"port"`
+	User            string        `yaml:"user"`
+	Password        string        `yaml:"password"`
+	Name            string        `yaml:"name"`
+	SSLMode         string        `yaml:"sslmode"`
+	MaxOpenConns    int           `yaml:"max_open_conns"`
+	MaxIdleConns    int           `yaml:"max_idle_conns"`
+	ConnMaxLifetime time.Duration `yaml:"conn_max_lifetime"`
 }
 
+// LoggerConfig configures the application logger. Format is json or
+// console; Output lists the sinks every line is written to, stdout, stderr
+// and file, in which case File says where. Packages overrides Level for the
+// packages named by the last elements of their import path, e.g. parser or
+// services/parser.
 type LoggerConfig struct {
-	Level string `yaml:"level"`
+	Level    string            `yaml:"level"`
+	Format   string            `yaml:"format"`
+	Output   Sinks             `yaml:"output"`
+	Packages map[string]string `yaml:"packages"`
+	File     LogFileConfig     `yaml:"file"`
+}
+
+// Sinks are the outputs of the logger. In the file they are a single output,
+// output: stdout, or a list, output: [stdout, file].
+type Sinks []string
+
+// UnmarshalYAML accepts a single sink as well as a list of them.
+func (s *Sinks) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		*s = Sinks{node.Value}
+		return nil
+	}
+	var list []string
+	if err := node.Decode(&list); err != nil {
+		return err
+	}
+	*s = list
+	return nil
 }
 
+// LogFileConfig is the log file and its rotation.
+type LogFileConfig struct {
+	Path       string `yaml:"path"`
+	MaxSizeMB  int    `yaml:"max_size_mb"`
+	MaxBackups int    `yaml:"max_backups"`
+	MaxAgeDays int    `yaml:"max_age_days"`
+	Compress   bool   `yaml:"compress"`
+}
+
+// LoadConfig reads the configuration at path over the defaults, applies the
+// environment overrides and validates the result.
 func LoadConfig(path string) (*Config, error) {
 	f, err := os.Open(path)
 	if err != nil {
@@ -33,15 +98,169 @@ func LoadConfig(path string) (*Config, error) {
 	}
 	defer f.Close()
 
-	cfg := &Config{}
+	cfg := Default()
 	decoder := yaml.NewDecoder(f)
+	decoder.KnownFields(true)
 	if err := decoder.Decode(cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse config %s: %w", path, err)
+	}
+	if err := cfg.ApplyEnv(os.LookupEnv); err != nil {
 		return nil, err
 	}
-	fmt.Printf("database config: %v", cfg)
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid config %s: %w", path, err)
+	}
 	return cfg, nil
 }
 
+// Default returns the configuration used for the keys a file leaves out.
+func Default() *Config {
+	return &Config{
+		Database: DatabaseConfig{
+			Host:            "localhost",
+			Port:            5432,
+			SSLMode:         "disable",
+			MaxOpenConns:    25,
+			MaxIdleConns:    25,
+			ConnMaxLifetime: 5 * time.Minute,
+		},
+		Logger: LoggerConfig{
+			Level:  "info",
+			Format: "console",
+			Output: Sinks{"stdout"},
+			File: LogFileConfig{
+				MaxSizeMB:  100,
+				MaxBackups: 7,
+				MaxAgeDays: 30,
+			},
+		},
+		Server: ServerConfig{
+			Address:         ":8080",
+			ReadTimeout:     10 * time.Second,
+			WriteTimeout:    15 * time.Second,
+			IdleTimeout:     60 * time.Second,
+			ShutdownTimeout: 15 * time.Second,
+		},
+		Ingestion: IngestionConfig{
+			Workers:     2,
+			QueueSize:   16,
+			RetainJobs:  1000,
+			MaxUploadMB: 512,
+		},
+		Metrics: MetricsConfig{Path: "/metrics"},
+		Tracing: TracingConfig{
+			Exporter:    "otlp",
+			ServiceName: "log-parser",
+			SampleRatio: 1,
+		},
+		CORS: CORSConfig{
+			AllowedOrigins: []string{"*"},
+			AllowedMethods: []string{"GET", "POST", "PUT", "OPTIONS"},
+			AllowedHeaders: []string{"Accept", "Authorization", "Content-Type", "X-API-Key", "X-CSRF-Token"},
+			MaxAge:         5 * time.Minute,
+		},
+	}
+}
+
+// ServerConfig configures the HTTP server of the REST API. ShutdownTimeout
+// bounds how long requests in flight may take to finish on shutdown.
 type ServerConfig struct {
-	Address string `yaml:"address"`
+	Address         string        `yaml:"address"`
+	ReadTimeout     time.Duration `yaml:"read_timeout"`
+	WriteTimeout    time.Duration `yaml:"write_timeout"`
+	IdleTimeout     time.Duration `yaml:"idle_timeout"`
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout"`
+}
+
+// IngestionConfig configures the background ingestion of the logs uploaded
+// to the REST API. Workers ingest QueueSize waiting uploads, stored in Dir
+// (default: the system temporary directory); the status of the last
+// RetainJobs finished jobs can be queried.
+type IngestionConfig struct {
+	Workers     int    `yaml:"workers"`
+	QueueSize   int    `yaml:"queue_size"`
+	RetainJobs  int    `yaml:"retain_jobs"`
+	Dir         string `yaml:"dir"`
+	MaxUploadMB int    `yaml:"max_upload_mb"`
+}
+
+// MetricsConfig configures the Prometheus endpoint of the REST API. Path
+// defaults to /metrics.
+type MetricsConfig struct {
+	Enabled bool   `yaml:"enabled"`
+	Path    string `yaml:"path"`
+}
+
+// TracingConfig configures OpenTelemetry tracing. Exporter is otlp, which
+// sends spans over OTLP/HTTP to Endpoint (by default the OTEL_EXPORTER_OTLP_*
+// environment variables, or localhost:4318), stdout or file, which write one
+// JSON span per line to stdout or File. SampleRatio is the fraction of
+// traces recorded, unless the caller of a request decided already.
+type TracingConfig struct {
+	Enabled     bool    `yaml:"enabled"`
+	Exporter    string  `yaml:"exporter"`
+	Endpoint    string  `yaml:"endpoint"`
+	File        string  `yaml:"file"`
+	ServiceName string  `yaml:"service_name"`
+	SampleRatio float64 `yaml:"sample_ratio"`
+}
+
+// AuthConfig configures authentication of the REST API. When Enabled is
+// false every request is served anonymously with the viewer role, or the
+// admin role if AllowAnonymousAdmin opts in, e.g. on a developer machine.
+type AuthConfig struct {
+	Enabled             bool      `yaml:"enabled"`
+	AllowAnonymousAdmin bool      `yaml:"allow_anonymous_admin"`
+	JWT                 JWTConfig `yaml:"jwt"`
+}
+
+// JWTConfig configures the verification of bearer tokens. A token is only
+// accepted for an algorithm whose key is configured.
+type JWTConfig struct {
+	HS256SecretFile    string `yaml:"hs256_secret_file"`
+	RS256PublicKeyFile string `yaml:"rs256_public_key_file"`
+	Issuer             string `yaml:"issuer"`
+	Audience           string `yaml:"audience"`
+	RoleClaim          string `yaml:"role_claim"` // default "role"
+}
+
+// RedactionConfig configures how personal data and secrets are redacted in
+// API responses and logs. Fields override the built-in rules by field name.
+type RedactionConfig struct {
+	Fields map[string]RedactionRuleConfig `yaml:"fields"`
+}
+
+// RedactionRuleConfig is the redaction of one field: Action is none, mask,
+// hash or drop; RevealTo is the lowest role that sees the field in clear.
+type RedactionRuleConfig struct {
+	Action   string `yaml:"action"`
+	RevealTo string `yaml:"reveal_to"`
+}
+
+// EncryptionConfig configures the keys secrets are encrypted with in the
+// database. KeysFile holds one "ID:base64 key" entry per line; ActiveKeyID
+// defaults to the last one. The LOG_PARSER_ENCRYPTION_KEYS and
+// LOG_PARSER_ENCRYPTION_ACTIVE_KEY_ID environment variables take precedence.
+type EncryptionConfig struct {
+	KeysFile    string `yaml:"keys_file"`
+	ActiveKeyID string `yaml:"active_key_id"`
+}
+
+// SecurityConfig configures TLS. With EnableTLS the REST API serves HTTPS
+// only, with the PEM certificate chain and key of CertFile and KeyFile.
+type SecurityConfig struct {
+	EnableTLS bool   `yaml:"enable_tls"`
+	CertFile  string `yaml:"cert_file"`
+	KeyFile   string `yaml:"key_file"`
+}
+
+// CORSConfig configures cross-origin requests to the REST API. "*" in
+// AllowedOrigins, AllowedMethods or AllowedHeaders allows any value.
+// MaxAge is how long browsers may cache a preflight response.
+type CORSConfig struct {
+	AllowedOrigins   []string      `yaml:"allowed_origins"`
+	AllowedMethods   []string      `yaml:"allowed_methods"`
+	AllowedHeaders   []string      `yaml:"allowed_headers"`
+	AllowCredentials bool          `yaml:"allow_credentials"`
+	MaxAge           time.Duration `yaml:"max_age"`
 }
@@ -2,12 +2,20 @@ package config
 
 import (
 	"fmt"
-	_ "github.com/lib/pq"
+	"strings"
 )
 
+// DSN returns the connection string of lib/pq. Values are quoted, so a
+// password may contain spaces and quotes.
 func (db *DatabaseConfig) DSN() string {
 	return fmt.Sprintf(
 		"host=%s port=%d user=%s password=%s dbname=%s sslmode=%s",
-		db.Host, db.Port, db.User, db.Password, db.Name, db.SSLMode,
+		quoteDSN(db.Host), db.Port, quoteDSN(db.User), quoteDSN(db.Password), quoteDSN(db.Name), quoteDSN(db.SSLMode),
 	)
 }
+
+var dsnEscaper = strings.NewReplacer(`\`, `\\`, `'`, `\'`)
+
+func quoteDSN(s string) string {
+	return "'" + dsnEscaper.Replace(s) + "'"
+}
@@ -0,0 +1,119 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// EnvPrefix prefixes the environment variable of every configuration key:
+// server.read_timeout is LOG_PARSER_SERVER_READ_TIMEOUT.
+const EnvPrefix = "LOG_PARSER_"
+
+// postgresEnv are the variables of the official Postgres image, which the
+// compose files set for both containers, by the database key they override.
+// The LOG_PARSER_DATABASE_* variables take precedence over them.
+var postgresEnv = []struct{ name, key string }{
+	{"POSTGRES_HOST", "host"},
+	{"POSTGRES_PORT", "port"},
+	{"POSTGRES_USER", "user"},
+	{"POSTGRES_PASSWORD", "password"},
+	{"POSTGRES_DB", "name"},
+	{"POSTGRES_SSLMODE", "sslmode"},
+}
+
+// ApplyEnv overrides the configuration with the environment variables
+// returned by lookup, usually os.LookupEnv. Every key of a string, number,
+// boolean, duration or list has a variable named after its path, see
+// EnvPrefix; lists are comma-separated. Maps, such as redaction.fields, can
+// only be set in the file.
+func (c *Config) ApplyEnv(lookup func(string) (string, bool)) error {
+	database := reflect.ValueOf(&c.Database).Elem()
+	for _, env := range postgresEnv {
+		if value, ok := lookup(env.name); ok {
+			if err := setField(fieldByKey(database, env.key), value); err != nil {
+				return fmt.Errorf("invalid %s: %w", env.name, err)
+			}
+		}
+	}
+	return applyEnv(reflect.ValueOf(c).Elem(), strings.TrimSuffix(EnvPrefix, "_"), lookup)
+}
+
+func applyEnv(v reflect.Value, prefix string, lookup func(string) (string, bool)) error {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		key, _, _ := strings.Cut(t.Field(i).Tag.Get("yaml"), ",")
+		if key == "" || key == "-" {
+			continue
+		}
+		name := prefix + "_" + strings.ToUpper(key)
+		field := v.Field(i)
+		if field.Kind() == reflect.Struct {
+			if err := applyEnv(field, name, lookup); err != nil {
+				return err
+			}
+			continue
+		}
+		if value, ok := lookup(name); ok {
+			if err := setField(field, value); err != nil {
+				return fmt.Errorf("invalid %s: %w", name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// fieldByKey returns the field of struct v whose YAML key is key.
+func fieldByKey(v reflect.Value, key string) reflect.Value {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		if k, _, _ := strings.Cut(t.Field(i).Tag.Get("yaml"), ","); k == key {
+			return v.Field(i)
+		}
+	}
+	panic("config: no field for key " + key)
+}
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+func setField(field reflect.Value, value string) error {
+	switch {
+	case field.Type() == durationType:
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return fmt.Errorf("%q is not a duration such as 30s or 5m", value)
+		}
+		field.SetInt(int64(d))
+	case field.Kind() == reflect.String:
+		field.SetString(value)
+	case field.Kind() == reflect.Int:
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("%q is not an integer", value)
+		}
+		field.SetInt(int64(n))
+	case field.Kind() == reflect.Float64:
+		f, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return fmt.Errorf("%q is not a number", value)
+		}
+		field.SetFloat(f)
+	case field.Kind() == reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("%q is not a boolean", value)
+		}
+		field.SetBool(b)
+	case field.Kind() == reflect.Slice && field.Type().Elem().Kind() == reflect.String:
+		var items []string
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		field.Set(reflect.ValueOf(items))
+	}
+	return nil
+}
@@ -0,0 +1,164 @@
+package config
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"maps"
+	"net"
+	"net/url"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Validate reports every invalid value of the configuration, one error per
+// key, named by its path in the file.
+func (c *Config) Validate() error {
+	var v validator
+
+	db := c.Database
+	v.require("database.host", db.Host)
+	v.require("database.user", db.User)
+	v.require("database.name", db.Name)
+	if db.Port < 1 || db.Port > 65535 {
+		v.addf("database.port", "%d is not a port number", db.Port)
+	}
+	v.oneOf("database.sslmode", db.SSLMode, "disable", "allow", "prefer", "require", "verify-ca", "verify-full")
+	v.nonNegative("database.max_open_conns", db.MaxOpenConns)
+	v.nonNegative("database.max_idle_conns", db.MaxIdleConns)
+	if db.MaxOpenConns > 0 && db.MaxIdleConns > db.MaxOpenConns {
+		v.addf("database.max_idle_conns", "%d exceeds database.max_open_conns (%d)", db.MaxIdleConns, db.MaxOpenConns)
+	}
+	v.nonNegativeDuration("database.conn_max_lifetime", db.ConnMaxLifetime)
+
+	lg := c.Logger
+	v.oneOf("logger.level", strings.ToLower(lg.Level), "debug", "info", "warn", "error", "fatal")
+	v.oneOf("logger.format", lg.Format, "json", "console")
+	if len(lg.Output) == 0 {
+		v.addf("logger.output", "is required")
+	}
+	for i, sink := range lg.Output {
+		v.oneOf("logger.output", sink, "stdout", "stderr", "file")
+		if slices.Contains(lg.Output[:i], sink) {
+			v.addf("logger.output", "%q is listed twice", sink)
+		}
+	}
+	if slices.Contains(lg.Output, "file") {
+		v.require("logger.file.path", lg.File.Path)
+	}
+	for _, pkg := range slices.Sorted(maps.Keys(lg.Packages)) {
+		level := lg.Packages[pkg]
+		if pkg == "" || strings.HasPrefix(pkg, "/") || strings.HasSuffix(pkg, "/") {
+			v.addf("logger.packages", "%q is not a package path", pkg)
+		}
+		v.oneOf("logger.packages."+pkg, strings.ToLower(level), "debug", "info", "warn", "error", "fatal")
+	}
+	v.nonNegative("logger.file.max_size_mb", lg.File.MaxSizeMB)
+	v.nonNegative("logger.file.max_backups", lg.File.MaxBackups)
+	v.nonNegative("logger.file.max_age_days", lg.File.MaxAgeDays)
+
+	srv := c.Server
+	if _, port, err := net.SplitHostPort(srv.Address); err != nil {
+		v.addf("server.address", "%q is not host:port, e.g. :8080", srv.Address)
+	} else if n, err := strconv.Atoi(port); err != nil || n < 0 || n > 65535 {
+		v.addf("server.address", "%q has an invalid port", srv.Address)
+	}
+	v.nonNegativeDuration("server.read_timeout", srv.ReadTimeout)
+	v.nonNegativeDuration("server.write_timeout", srv.WriteTimeout)
+	v.nonNegativeDuration("server.idle_timeout", srv.IdleTimeout)
+	v.nonNegativeDuration("server.shutdown_timeout", srv.ShutdownTimeout)
+
+	ing := c.Ingestion
+	v.positive("ingestion.workers", ing.Workers)
+	v.positive("ingestion.queue_size", ing.QueueSize)
+	v.positive("ingestion.retain_jobs", ing.RetainJobs)
+	v.positive("ingestion.max_upload_mb", ing.MaxUploadMB)
+	if ing.Dir != "" {
+		if fi, err := os.Stat(ing.Dir); err != nil {
+			v.addf("ingestion.dir", "%v", err)
+		} else if !fi.IsDir() {
+			v.addf("ingestion.dir", "%q is not a directory", ing.Dir)
+		}
+	}
+
+	if c.Metrics.Enabled && !strings.HasPrefix(c.Metrics.Path, "/") {
+		v.addf("metrics.path", "%q must start with /", c.Metrics.Path)
+	}
+	tr := c.Tracing
+	v.oneOf("tracing.exporter", tr.Exporter, "otlp", "stdout", "file")
+	if tr.Exporter == "file" && tr.Enabled {
+		v.require("tracing.file", tr.File)
+	}
+	if tr.Endpoint != "" {
+		if u, err := url.Parse(tr.Endpoint); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			v.addf("tracing.endpoint", "%q is not an http(s) URL, e.g. http://otel-collector:4318", tr.Endpoint)
+		}
+	}
+	if tr.SampleRatio < 0 || tr.SampleRatio > 1 {
+		v.addf("tracing.sample_ratio", "%g is not between 0 and 1", tr.SampleRatio)
+	}
+	v.require("tracing.service_name", tr.ServiceName)
+
+	if c.Auth.Enabled && c.Auth.AllowAnonymousAdmin {
+		v.addf("auth.allow_anonymous_admin", "only applies while auth.enabled is false")
+	}
+
+	if sec := c.Security; sec.EnableTLS {
+		v.require("security.cert_file", sec.CertFile)
+		v.require("security.key_file", sec.KeyFile)
+		if sec.CertFile != "" && sec.KeyFile != "" {
+			if _, err := tls.LoadX509KeyPair(sec.CertFile, sec.KeyFile); err != nil {
+				v.addf("security.cert_file", "cannot load the key pair: %v", err)
+			}
+		}
+	}
+
+	if c.CORS.AllowCredentials && slices.Contains(c.CORS.AllowedOrigins, "*") {
+		v.addf("cors.allow_credentials", `browsers reject credentials for any origin; list the origins instead of "*"`)
+	}
+	v.nonNegativeDuration("cors.max_age", c.CORS.MaxAge)
+
+	return errors.Join(v.errs...)
+}
+
+// validator collects the errors of Validate.
+type validator struct {
+	errs []error
+}
+
+func (v *validator) addf(key, format string, args ...any) {
+	v.errs = append(v.errs, fmt.Errorf("%s: %s", key, fmt.Sprintf(format, args...)))
+}
+
+func (v *validator) require(key, value string) {
+	if value == "" {
+		v.addf(key, "is required")
+	}
+}
+
+func (v *validator) oneOf(key, value string, allowed ...string) {
+	if !slices.Contains(allowed, value) {
+		v.addf(key, "%q is not one of %s", value, strings.Join(allowed, ", "))
+	}
+}
+
+func (v *validator) nonNegative(key string, n int) {
+	if n < 0 {
+		v.addf(key, "%d must not be negative", n)
+	}
+}
+
+func (v *validator) positive(key string, n int) {
+	if n < 1 {
+		v.addf(key, "%d must be at least 1", n)
+	}
+}
+
+func (v *validator) nonNegativeDuration(key string, d time.Duration) {
+	if d < 0 {
+		v.addf(key, "%s must not be negative", d)
+	}
+}
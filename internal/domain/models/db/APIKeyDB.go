@@ -0,0 +1,16 @@
+package db
+
+import "time"
+
+// APIKeyDB is an API key of the REST API. Only the SHA-256 hash of the key
+// is stored; Prefix is its first characters, to recognise it in listings.
+type APIKeyDB struct {
+	ID         int        `db:"id"`
+	Name       string     `db:"name"`
+	Prefix     string     `db:"prefix"`
+	KeyHash    string     `db:"key_hash" json:"-"`
+	Role       string     `db:"role"`
+	CreatedAt  time.Time  `db:"created_at"`
+	LastUsedAt *time.Time `db:"last_used_at"`
+	RevokedAt  *time.Time `db:"revoked_at"`
+}
@@ -0,0 +1,48 @@
+package db
+
+// DeviceDB is one device (PCBA number) aggregated over the station records
+// that matched a DeviceFilter.
+type DeviceDB struct {
+	PCBANumber      string `db:"pcba_number"`
+	PartNumber      string `db:"part_number"`
+	ProductLine     string `db:"product_line"`
+	TestToolVersion string `db:"test_tool_version"`
+	Stations        string `db:"stations"` // distinct station types, comma separated and sorted
+	LastPassed      bool   `db:"last_passed"`
+	FirstTested     string `db:"first_tested"`
+	LastTested      string `db:"last_tested"`
+	RecordCount     int    `db:"record_count"`
+}
+
+// Device sort keys.
+const (
+	DeviceSortPCBANumber  = "pcba_number"
+	DeviceSortFirstTested = "first_tested"
+	DeviceSortLastTested  = "last_tested"
+)
+
+// DeviceFilter selects and orders devices. Empty fields do not filter.
+//
+// A device matches when at least one of its station records matches every
+// record filter; the aggregated columns of DeviceDB are computed over the
+// matching records only. Passed is not a record filter: it selects devices
+// by LastPassed, the verdict of the latest matching record.
+type DeviceFilter struct {
+	StationType     string
+	PartNumber      string
+	ProductLine     string
+	TestToolVersion string
+	Passed          *bool
+	TestedFrom      string // inclusive, "2006-01-02 15:04:05"
+	TestedTo        string // inclusive, "2006-01-02 15:04:05"
+
+	Sort string // one of the DeviceSort keys
+	Desc bool
+
+	// Keyset pagination: only devices ordered after (AfterKey, AfterPCBA).
+	After     bool
+	AfterKey  string
+	AfterPCBA string
+
+	Limit int
+}
@@ -0,0 +1,35 @@
+package db
+
+// ErrorCodeDB is one normalized error code. Station records and test steps
+// are linked to it through test_station_record_error_code and
+// test_step_error_code.
+type ErrorCodeDB struct {
+	ID   int    `db:"id"`
+	Code string `db:"code"`
+}
+
+// ErrorParetoQuery selects the station records whose error codes are
+// counted. Empty filters do not filter.
+type ErrorParetoQuery struct {
+	StationType string
+	PartNumber  string
+	From        string // inclusive, "2006-01-02 15:04:05"
+	To          string // inclusive, "2006-01-02 15:04:05"
+}
+
+// ErrorCodeCountDB counts one error code. An occurrence is a station record
+// that carries the code itself or in one of its test steps.
+type ErrorCodeCountDB struct {
+	Code        string `db:"code"`
+	Occurrences int    `db:"occurrences"`
+	Devices     int    `db:"devices"` // distinct PCBA numbers
+}
+
+// ErrorCodeDeviceDB is one device behind an error code.
+type ErrorCodeDeviceDB struct {
+	PCBANumber  string `db:"pcba_number"`
+	Occurrences int    `db:"occurrences"`
+	Stations    string `db:"stations"` // comma separated, sorted
+	FirstSeen   string `db:"first_seen"`
+	LastSeen    string `db:"last_seen"`
+}
@@ -0,0 +1,16 @@
+package db
+
+import "time"
+
+type LogisticConflictDB struct {
+	ID                  int       `db:"id"`
+	PCBANumber          string    `db:"pcba_number"`
+	Field               string    `db:"field"`
+	PCBAValue           string    `db:"pcba_value"`
+	FinalValue          string    `db:"final_value"`
+	Kind                string    `db:"kind"`
+	Classification      string    `db:"classification"`
+	PCBALogisticDataID  int       `db:"pcba_logistic_data_id"`
+	FinalLogisticDataID int       `db:"final_logistic_data_id"`
+	DetectedAt          time.Time `db:"detected_at"`
+}
@@ -23,3 +23,13 @@ type LogisticDataDB struct {
 	IMSI                        string `db:"imsi"`
 	ProductionDate              string `db:"production_date"`
 }
+
+// Secondary device identifiers LogisticData can be looked up by.
+const (
+	IdentifierIMEI      = "imei"
+	IdentifierIMSI      = "imsi"
+	IdentifierICCID     = "iccid"
+	IdentifierBleMac    = "ble_mac"
+	IdentifierBleSN     = "ble_sn"
+	IdentifierProductSN = "product_sn"
+)
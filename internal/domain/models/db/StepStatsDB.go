@@ -0,0 +1,34 @@
+package db
+
+// StepStatsQuery selects the numeric measurements of one test step. Empty
+// filters do not filter.
+type StepStatsQuery struct {
+	StepName string
+
+	StationType     string
+	PartNumber      string
+	TestToolVersion string
+	From            string // inclusive, "2006-01-02 15:04:05", applied to the station record
+	To              string // inclusive, "2006-01-02 15:04:05", applied to the station record
+
+	Percentiles []float64 // fractions in [0,1], e.g. 0.5 for the median
+}
+
+// StepStatsDB holds the distribution of the numeric measured values of a step.
+// The aggregates are zero when Count is zero; StdDev is the sample standard
+// deviation and zero for a single measurement.
+type StepStatsDB struct {
+	Count       int       `db:"count"`
+	Min         float64   `db:"min"`
+	Max         float64   `db:"max"`
+	Mean        float64   `db:"mean"`
+	StdDev      float64   `db:"stddev"`
+	Percentiles []float64 `db:"percentiles"` // continuous percentiles, in StepStatsQuery.Percentiles order
+}
+
+// StepThresholdDB is one distinct threshold of a step with the number of
+// measurements logged against it.
+type StepThresholdDB struct {
+	Threshold string `db:"test_threshold_value"`
+	Count     int    `db:"count"`
+}
@@ -11,4 +11,8 @@ type TestStationRecordDB struct {
 	IsAllPassed      bool   `db:"is_all_passed"`
 	ErrorCodes       string `db:"error_codes"`
 	LogisticDataID   int    `db:"logistic_data_id"`
+
+	// Codes are the normalized ErrorCodes, linked through
+	// test_station_record_error_code when the record is inserted.
+	Codes []string `db:"-"`
 }
@@ -1,12 +1,19 @@
 package db
 
 type TestStepDB struct {
-	ID                  int    `db:"id"`
-	TestStepName        string `db:"test_step_name"`
-	TestThresholdValue  string `db:"test_threshold_value"`
-	TestMeasuredValue   string `db:"test_measured_value"`
-	TestStepElapsedTime int    `db:"test_step_elapsed_time"`
-	TestStepResult      string `db:"test_step_result"`
-	TestStepErrorCode   string `db:"test_step_error_code"`
-	TestStationRecordID int    `db:"test_station_record_id"`
+	ID                  int      `db:"id"`
+	TestStepName        string   `db:"test_step_name"`
+	TestThresholdValue  string   `db:"test_threshold_value"`
+	TestMeasuredValue   string   `db:"test_measured_value"`
+	MeasuredNumeric     *float64 `db:"measured_numeric"` // nil when the measured value is not a number
+	TestStepElapsedTime int      `db:"test_step_elapsed_time"`
+	TestStepResult      string   `db:"test_step_result"`
+	TestStepErrorCode   string   `db:"test_step_error_code"`
+	RecomputedResult    string   `db:"recomputed_result"`
+	VerdictMismatch     bool     `db:"verdict_mismatch"`
+	TestStationRecordID int      `db:"test_station_record_id"`
+
+	// Codes are the normalized TestStepErrorCode, linked through
+	// test_step_error_code when the step is inserted.
+	Codes []string `db:"-"`
 }
@@ -0,0 +1,30 @@
+package db
+
+import "time"
+
+type ValidationFindingDB struct {
+	ID          int       `db:"id"`
+	SourceFile  string    `db:"source_file"`
+	PCBANumber  string    `db:"pcba_number"`
+	RecordType  string    `db:"record_type"`
+	RecordIndex int       `db:"record_index"`
+	StationType string    `db:"station_type"`
+	Rule        string    `db:"rule"`
+	Severity    string    `db:"severity"`
+	Field       string    `db:"field"`
+	Value       string    `db:"value"`
+	Message     string    `db:"message"`
+	CreatedAt   time.Time `db:"created_at"`
+}
+
+type ValidationSummaryDB struct {
+	ID                 int       `db:"id"`
+	SourceFile         string    `db:"source_file"`
+	GroupsTotal        int       `db:"groups_total"`
+	GroupsRejected     int       `db:"groups_rejected"`
+	GroupsWithFindings int       `db:"groups_with_findings"`
+	RejectFindings     int       `db:"reject_findings"`
+	WarnFindings       int       `db:"warn_findings"`
+	AnnotateFindings   int       `db:"annotate_findings"`
+	CreatedAt          time.Time `db:"created_at"`
+}
@@ -0,0 +1,49 @@
+package db
+
+import "time"
+
+// Yield grouping dimensions.
+const (
+	YieldGroupStationType = "station_type"
+	YieldGroupPartNumber  = "part_number"
+	YieldGroupProductLine = "product_line"
+	YieldGroupToolVersion = "test_tool_version"
+)
+
+// Yield time buckets.
+const (
+	YieldBucketHour = "hour"
+	YieldBucketDay  = "day"
+	YieldBucketWeek = "week"
+)
+
+// YieldQuery selects the station records yield is computed over and how the
+// result is grouped. Empty filters do not filter.
+//
+// Yield counts devices per station type: a device is attributed to the bucket
+// and group values of its first attempt at that station.
+type YieldQuery struct {
+	GroupBy []string // YieldGroup dimensions
+	Bucket  string   // YieldBucket size
+
+	StationType string
+	PartNumber  string
+	ProductLine string
+	From        string // inclusive, "2006-01-02 15:04:05", applied to the first attempt
+	To          string // inclusive, "2006-01-02 15:04:05", applied to the first attempt
+}
+
+// YieldRowDB is the yield of one bucket and group. Dimensions that are not
+// grouped by are empty.
+type YieldRowDB struct {
+	Bucket           time.Time `db:"bucket"`
+	StationType      string    `db:"station_type"`
+	PartNumber       string    `db:"part_number"`
+	ProductLine      string    `db:"product_line"`
+	TestToolVersion  string    `db:"test_tool_version"`
+	Attempted        int       `db:"attempted"`         // devices with at least one attempt
+	Sessions         int       `db:"sessions"`          // station sessions of those devices
+	FirstPassPassed  int       `db:"first_pass_passed"` // devices whose first attempt passed
+	EventuallyPassed int       `db:"eventually_passed"` // devices with any passing attempt
+	Failed           int       `db:"failed"`            // devices that never passed
+}
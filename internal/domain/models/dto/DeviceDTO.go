@@ -0,0 +1,79 @@
+package dto
+
+// DeviceDTO summarizes one device in the device listing
+//
+// swagger:model
+type DeviceDTO struct {
+	PCBANumber      string   `json:"PCBANumber"`
+	PartNumber      string   `json:"PartNumber"`
+	ProductLine     string   `json:"ProductLine"`
+	TestToolVersion string   `json:"TestToolVersion"`
+	Stations        []string `json:"Stations"`
+	LastPassed      bool     `json:"LastPassed"`
+	FirstTested     string   `json:"FirstTested"`
+	LastTested      string   `json:"LastTested"`
+	RecordCount     int      `json:"RecordCount"`
+}
+
+// DevicePageDTO is one page of the device listing
+//
+// swagger:model
+type DevicePageDTO struct {
+	Devices    []DeviceDTO `json:"Devices"`
+	Total      int         `json:"Total"`
+	NextCursor string      `json:"NextCursor,omitempty"`
+}
+
+// DeviceSessionDTO is one station session (test station record) of a device
+//
+// swagger:model
+type DeviceSessionDTO struct {
+	TestStation      string        `json:"TestStation"`
+	Attempt          int           `json:"Attempt"`
+	TestFinishedTime string        `json:"TestFinishedTime"`
+	Result           string        `json:"Result"`
+	IsAllPassed      bool          `json:"IsAllPassed"`
+	ErrorCodes       string        `json:"ErrorCodes"`
+	PartNumber       string        `json:"PartNumber"`
+	ProductLine      string        `json:"ProductLine"`
+	TestToolVersion  string        `json:"TestToolVersion"`
+	LogisticDataID   int           `json:"LogisticDataID"`
+	TestSteps        []TestStepDTO `json:"TestSteps"`
+}
+
+// DeviceTimelineDTO aggregates everything stored for one device
+//
+// swagger:model
+type DeviceTimelineDTO struct {
+	PCBANumber   string             `json:"PCBANumber"`
+	Status       string             `json:"Status"`
+	StuckAt      string             `json:"StuckAt,omitempty"`
+	Downloads    []DownloadInfoDTO  `json:"Downloads"`
+	LogisticData LogisticDataDTO    `json:"LogisticData"`
+	Sessions     []DeviceSessionDTO `json:"Sessions"`
+}
+
+// DeviceMatchDTO is one device found by a secondary identifier
+//
+// swagger:model
+type DeviceMatchDTO struct {
+	PCBANumber string `json:"PCBANumber"`
+	ProductSN  string `json:"ProductSN"`
+	IMEI       string `json:"IMEI"`
+	IMSI       string `json:"IMSI"`
+	TcuICCID   string `json:"TcuICCID"`
+	BleMac     string `json:"BleMac"`
+	BleSN      string `json:"BleSN"`
+	Snapshots  int    `json:"Snapshots"`
+}
+
+// DeviceSearchDTO is the result of a lookup by secondary identifier.
+// Ambiguous is set when the identifier maps to more than one device.
+//
+// swagger:model
+type DeviceSearchDTO struct {
+	Identifier string           `json:"Identifier"`
+	Value      string           `json:"Value"`
+	Ambiguous  bool             `json:"Ambiguous"`
+	Matches    []DeviceMatchDTO `json:"Matches"`
+}
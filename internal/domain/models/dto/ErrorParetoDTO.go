@@ -0,0 +1,41 @@
+package dto
+
+// ErrorCodeCountDTO is one error code of the Pareto. Share and
+// CumulativeShare are fractions of the total of the sort metric.
+//
+// swagger:model
+type ErrorCodeCountDTO struct {
+	Code            string  `json:"Code"`
+	Occurrences     int     `json:"Occurrences"`
+	Devices         int     `json:"Devices"`
+	Share           float64 `json:"Share"`
+	CumulativeShare float64 `json:"CumulativeShare"`
+}
+
+// ErrorParetoDTO ranks error codes by occurrences or affected devices
+//
+// swagger:model
+type ErrorParetoDTO struct {
+	SortBy string              `json:"SortBy"`
+	Total  int                 `json:"Total"`
+	Codes  []ErrorCodeCountDTO `json:"Codes"`
+}
+
+// ErrorCodeDeviceDTO is one device behind an error code
+//
+// swagger:model
+type ErrorCodeDeviceDTO struct {
+	PCBANumber  string   `json:"PCBANumber"`
+	Occurrences int      `json:"Occurrences"`
+	Stations    []string `json:"Stations"`
+	FirstSeen   string   `json:"FirstSeen"`
+	LastSeen    string   `json:"LastSeen"`
+}
+
+// ErrorCodeDevicesDTO is the drill-down of an error code to its devices
+//
+// swagger:model
+type ErrorCodeDevicesDTO struct {
+	Code    string               `json:"Code"`
+	Devices []ErrorCodeDeviceDTO `json:"Devices"`
+}
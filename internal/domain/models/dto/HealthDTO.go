@@ -0,0 +1,20 @@
+package dto
+
+// HealthCheckDTO is the outcome of one readiness check.
+//
+// swagger:model
+type HealthCheckDTO struct {
+	Name      string  `json:"Name"`
+	Status    string  `json:"Status"`
+	LatencyMs float64 `json:"LatencyMs"`
+	Error     string  `json:"Error,omitempty"`
+}
+
+// HealthDTO is the answer of the liveness and readiness probes. Status is
+// "ok" or "fail"; Checks are only reported by readiness.
+//
+// swagger:model
+type HealthDTO struct {
+	Status string           `json:"Status"`
+	Checks []HealthCheckDTO `json:"Checks,omitempty"`
+}
@@ -0,0 +1,48 @@
+package dto
+
+// IngestionProgressDTO is how far an ingestion job got in its current file.
+//
+// swagger:model
+type IngestionProgressDTO struct {
+	GroupsTotal      int     `json:"GroupsTotal"`
+	GroupsDispatched int     `json:"GroupsDispatched"`
+	Percent          float64 `json:"Percent"`
+}
+
+// IngestionStatsDTO counts what an ingestion job parsed, rejected and stored.
+//
+// swagger:model
+type IngestionStatsDTO struct {
+	TotalBlocks        int `json:"TotalBlocks"`
+	FilteredBlocks     int `json:"FilteredBlocks"`
+	PCBAStations       int `json:"PCBAStations"`
+	FinalStations      int `json:"FinalStations"`
+	DownloadInfo       int `json:"DownloadInfo"`
+	TestStepArrays     int `json:"TestStepArrays"`
+	TotalTestSteps     int `json:"TotalTestSteps"`
+	GroupsTotal        int `json:"GroupsTotal"`
+	GroupsRejected     int `json:"GroupsRejected"`
+	Findings           int `json:"Findings"`
+	GroupsOK           int `json:"GroupsOK"`
+	GroupsFailed       int `json:"GroupsFailed"`
+	GroupsWithExcess   int `json:"GroupsWithExcess"`
+	GroupsMismatchType int `json:"GroupsMismatchType"`
+}
+
+// IngestionJobDTO is the state of an uploaded log file that is ingested in
+// the background. Timestamps are RFC 3339 in UTC.
+//
+// swagger:model
+type IngestionJobDTO struct {
+	ID          string               `json:"ID"`
+	FileName    string               `json:"FileName"`
+	SizeBytes   int64                `json:"SizeBytes"`
+	Status      string               `json:"Status"`
+	Stage       string               `json:"Stage"`
+	Progress    IngestionProgressDTO `json:"Progress"`
+	Stats       *IngestionStatsDTO   `json:"Stats,omitempty"`
+	Errors      []string             `json:"Errors,omitempty"`
+	SubmittedAt string               `json:"SubmittedAt"`
+	StartedAt   string               `json:"StartedAt,omitempty"`
+	FinishedAt  string               `json:"FinishedAt,omitempty"`
+}
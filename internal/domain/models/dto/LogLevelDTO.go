@@ -0,0 +1,10 @@
+package dto
+
+// LogLevelsDTO is the level of the logger and its overrides, by package
+// named with the last elements of its import path, e.g. "parser".
+//
+// swagger:model
+type LogLevelsDTO struct {
+	Level    string            `json:"Level"`
+	Packages map[string]string `json:"Packages"`
+}
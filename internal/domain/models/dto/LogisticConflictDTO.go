@@ -0,0 +1,24 @@
+package dto
+
+// LogisticConflictDTO is one LogisticData field that differs between the PCBA
+// and the Final station record of a device. Both values are redacted like the
+// field they belong to
+//
+// swagger:model
+type LogisticConflictDTO struct {
+	Field          string `json:"Field"`
+	PCBAValue      string `json:"PCBAValue" redact:"Field"`
+	FinalValue     string `json:"FinalValue" redact:"Field"`
+	Kind           string `json:"Kind"`
+	Classification string `json:"Classification"`
+	DetectedAt     string `json:"DetectedAt,omitempty"`
+}
+
+// DeviceLogisticConflictsDTO lists the LogisticData conflicts of one device
+//
+// swagger:model
+type DeviceLogisticConflictsDTO struct {
+	PCBANumber string                `json:"PCBANumber"`
+	Suspicious bool                  `json:"Suspicious"`
+	Conflicts  []LogisticConflictDTO `json:"Conflicts"`
+}
@@ -0,0 +1,41 @@
+package dto
+
+// HistogramBinDTO is one bin of a measurement histogram; Upper is exclusive
+// except for the last bin.
+//
+// swagger:model
+type HistogramBinDTO struct {
+	Lower float64 `json:"Lower"`
+	Upper float64 `json:"Upper"`
+	Count int     `json:"Count"`
+}
+
+// StepCapabilityDTO is the process capability of a step against the limits
+// of its most frequent threshold. Cp needs both limits; Cpk uses the limits
+// that exist. Both are omitted when the standard deviation is zero.
+//
+// swagger:model
+type StepCapabilityDTO struct {
+	Threshold  string   `json:"Threshold"`
+	LSL        *float64 `json:"LSL,omitempty"`
+	USL        *float64 `json:"USL,omitempty"`
+	Cp         *float64 `json:"Cp,omitempty"`
+	Cpk        *float64 `json:"Cpk,omitempty"`
+	Thresholds int      `json:"Thresholds"` // distinct thresholds of the measurements
+}
+
+// StepStatsDTO describes the distribution of the numeric measured values of
+// a test step
+//
+// swagger:model
+type StepStatsDTO struct {
+	StepName    string             `json:"StepName"`
+	Count       int                `json:"Count"`
+	Min         float64            `json:"Min"`
+	Max         float64            `json:"Max"`
+	Mean        float64            `json:"Mean"`
+	StdDev      float64            `json:"StdDev"`
+	Percentiles map[string]float64 `json:"Percentiles"` // p1, p5, p25, p50, p75, p95, p99
+	Histogram   []HistogramBinDTO  `json:"Histogram"`
+	Capability  *StepCapabilityDTO `json:"Capability,omitempty"`
+}
@@ -12,6 +12,11 @@ type TestStepDTO struct {
 	TestStepElapsedTime int         `json:"TestStepElapsedTime"`
 	TestStepResult      string      `json:"TestStepResult"`
 	TestStepErrorCode   string      `json:"TestStepErrorCode"`
+	// RecomputedResult is the verdict recomputed from TestThresholdValue and
+	// TestMeasuredValue; empty if the threshold cannot be evaluated.
+	RecomputedResult string `json:"RecomputedResult,omitempty"`
+	// VerdictMismatch is set when RecomputedResult disagrees with TestStepResult.
+	VerdictMismatch bool `json:"VerdictMismatch,omitempty"`
 }
 
 func (t *TestStepDTO) GetMeasuredValueString() string {
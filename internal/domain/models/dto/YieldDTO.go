@@ -0,0 +1,31 @@
+package dto
+
+// YieldRowDTO is the yield of one time bucket and group. Dimensions that are
+// not grouped by are omitted.
+//
+// swagger:model
+type YieldRowDTO struct {
+	Bucket           string  `json:"Bucket"`
+	StationType      string  `json:"StationType,omitempty"`
+	PartNumber       string  `json:"PartNumber,omitempty"`
+	ProductLine      string  `json:"ProductLine,omitempty"`
+	TestToolVersion  string  `json:"TestToolVersion,omitempty"`
+	Attempted        int     `json:"Attempted"`
+	Sessions         int     `json:"Sessions"`
+	FirstPassPassed  int     `json:"FirstPassPassed"`
+	EventuallyPassed int     `json:"EventuallyPassed"`
+	Failed           int     `json:"Failed"`
+	FirstPassYield   float64 `json:"FirstPassYield"`
+	FinalYield       float64 `json:"FinalYield"`
+}
+
+// YieldReportDTO is the result of a yield query
+//
+// swagger:model
+type YieldReportDTO struct {
+	GroupBy []string      `json:"GroupBy"`
+	Bucket  string        `json:"Bucket"`
+	From    string        `json:"From,omitempty"`
+	To      string        `json:"To,omitempty"`
+	Rows    []YieldRowDTO `json:"Rows"`
+}
@@ -2,12 +2,15 @@ package repositories
 
 import (
 	"context"
+	"time"
+
 	"github.com/NoroSaroyan/log-parser/internal/domain/models/db"
 )
 
 type DownloadInfoRepository interface {
 	Insert(ctx context.Context, info *db.DownloadInfoDB) error
 	GetByPCBANumber(ctx context.Context, pcba string) (*db.DownloadInfoDB, error)
+	GetAllByPCBANumber(ctx context.Context, pcba string) ([]*db.DownloadInfoDB, error)
 	GetByPartNumber(ctx context.Context, partNumber string) (*db.DownloadInfoDB, error)
 }
 
@@ -18,13 +21,18 @@ type LogisticDataRepository interface {
 	GetIDByPCBANumber(ctx context.Context, pcba string) (int, error)
 	GetById(ctx context.Context, id int) (*db.LogisticDataDB, error)
 	GetByPCBANumber(ctx context.Context, pcba string) (*db.LogisticDataDB, error)
+	GetByIdentifier(ctx context.Context, identifier, value string) ([]*db.LogisticDataDB, error)
+	ReencryptSecrets(ctx context.Context, batchSize int) (int, error)
 }
 
 type TestStationRecordRepository interface {
 	Insert(ctx context.Context, record *db.TestStationRecordDB) error
 	GetByPCBANumber(ctx context.Context, pcba string) ([]*db.TestStationRecordDB, error)
 	GetByPartNumber(ctx context.Context, partNumber string) ([]*db.TestStationRecordDB, error)
-	GetAllPCBANumbers(ctx context.Context) ([]string, error)
+	GetAllPCBANumbers(ctx context.Context, stationType string) ([]string, error)
+	ForEachPCBANumber(ctx context.Context, stationType string, fn func(pcba string) error) error
+	ListDevices(ctx context.Context, filter db.DeviceFilter) ([]*db.DeviceDB, error)
+	CountDevices(ctx context.Context, filter db.DeviceFilter) (int, error)
 }
 
 type TestStepRepository interface {
@@ -32,3 +40,39 @@ type TestStepRepository interface {
 	GetByTestStationRecordID(ctx context.Context, recordID int) ([]*db.TestStepDB, error)
 	GetByPartNumber(ctx context.Context, partNumber string) ([]*db.TestStepDB, error)
 }
+
+type ValidationRepository interface {
+	InsertFindings(ctx context.Context, findings []*db.ValidationFindingDB) error
+	InsertSummary(ctx context.Context, summary *db.ValidationSummaryDB) error
+	GetFindingsByPCBANumber(ctx context.Context, pcba string) ([]*db.ValidationFindingDB, error)
+	GetSummariesBySourceFile(ctx context.Context, sourceFile string) ([]*db.ValidationSummaryDB, error)
+}
+
+type LogisticConflictRepository interface {
+	ReplaceForPCBANumber(ctx context.Context, pcba string, conflicts []*db.LogisticConflictDB) error
+	GetByPCBANumber(ctx context.Context, pcba string) ([]*db.LogisticConflictDB, error)
+	GetAll(ctx context.Context, classification string) ([]*db.LogisticConflictDB, error)
+	ForEach(ctx context.Context, classification string, fn func(c *db.LogisticConflictDB) error) error
+}
+
+type AnalyticsRepository interface {
+	Yield(ctx context.Context, q db.YieldQuery) ([]*db.YieldRowDB, error)
+	StepStats(ctx context.Context, q db.StepStatsQuery) (*db.StepStatsDB, error)
+	StepHistogram(ctx context.Context, q db.StepStatsQuery, lower, upper float64, bins int) ([]int, error)
+	StepThresholds(ctx context.Context, q db.StepStatsQuery) ([]*db.StepThresholdDB, error)
+	ErrorPareto(ctx context.Context, q db.ErrorParetoQuery) ([]*db.ErrorCodeCountDB, error)
+	ErrorCodeDevices(ctx context.Context, q db.ErrorParetoQuery, code string) ([]*db.ErrorCodeDeviceDB, error)
+}
+
+type APIKeyRepository interface {
+	Insert(ctx context.Context, key *db.APIKeyDB) error
+	GetByHash(ctx context.Context, keyHash string) (*db.APIKeyDB, error)
+	List(ctx context.Context) ([]*db.APIKeyDB, error)
+	Revoke(ctx context.Context, name string) (bool, error)
+	TouchLastUsed(ctx context.Context, id int, at time.Time) error
+}
+
+type HealthRepository interface {
+	Ping(ctx context.Context) error
+	SchemaVersion(ctx context.Context) (int, error)
+}
@@ -0,0 +1,248 @@
+package v1
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/NoroSaroyan/log-parser/internal/infrastructure/logger"
+	"github.com/NoroSaroyan/log-parser/internal/services/analytics"
+	"github.com/go-chi/chi/v5"
+)
+
+// AnalyticsHandler provides HTTP handlers for aggregate production metrics.
+type AnalyticsHandler struct {
+	svc analytics.AnalyticsService
+}
+
+// NewAnalyticsHandler creates a new AnalyticsHandler with the provided AnalyticsService.
+func NewAnalyticsHandler(svc analytics.AnalyticsService) *AnalyticsHandler {
+	return &AnalyticsHandler{svc: svc}
+}
+
+// Yield handles HTTP GET requests for first-pass and final yield.
+//
+// Devices are counted once per station type, in the time bucket and group of
+// their first attempt. Every row holds the attempted devices, the devices that
+// passed on the first attempt, those that passed eventually and those that
+// never passed, plus both yields as ratios. "group_by" is a comma separated
+// list of station_type (default), part_number, product_line and
+// test_tool_version; grouping by tester is rejected because the parsed records
+// carry no tester ID. Returns HTTP 400 for invalid parameters and 500 for
+// server errors.
+//
+// Swagger annotations:
+//
+// @Summary      Get yield
+// @Description  Returns first-pass and final yield per time bucket, grouped by station type, part number, product line or tool version
+// @Tags         analytics
+// @Accept       json
+// @Produce      json,text/csv,application/x-ndjson
+// @Param        group_by      query  string  false  "Comma separated: station_type (default), part_number, product_line, test_tool_version"
+// @Param        bucket        query  string  false  "hour, day (default) or week"
+// @Param        station_type  query  string  false  "PCBA or Final"
+// @Param        part_number   query  string  false  "Part number"
+// @Param        product_line  query  string  false  "Product line"
+// @Param        from          query  string  false  "Earliest first attempt (date, 'YYYY-MM-DD hh:mm:ss' or RFC 3339)"
+// @Param        to            query  string  false  "Latest first attempt; a bare date includes the whole day"
+// @Success      200  {object}  dto.YieldReportDTO
+// @Failure      400  {object}  map[string]string  "invalid query parameter"
+// @Failure      500  {object}  map[string]string  "internal error"
+// @Router       /analytics/yield [get]
+func (h *AnalyticsHandler) Yield(w http.ResponseWriter, r *http.Request) {
+	params := r.URL.Query()
+	report, err := h.svc.Yield(r.Context(), analytics.YieldQuery{
+		GroupBy:     params.Get("group_by"),
+		Bucket:      params.Get("bucket"),
+		StationType: params.Get("station_type"),
+		PartNumber:  params.Get("part_number"),
+		ProductLine: params.Get("product_line"),
+		From:        params.Get("from"),
+		To:          params.Get("to"),
+	})
+	if errors.Is(err, analytics.ErrInvalidQuery) {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err != nil {
+		logger.ErrorContext(r.Context(), "Failed to compute yield", err, logger.WithField("query", r.URL.RawQuery))
+		respondError(w, http.StatusInternalServerError, "internal error")
+		return
+	}
+
+	respond(w, r, http.StatusOK, report, report.Rows)
+}
+
+// StepStats handles HTTP GET requests for the measurement statistics of one
+// test step.
+//
+// Only measured values that parse as numbers are included. The response holds
+// count, min, max, mean, sample standard deviation, percentiles (p1 to p99)
+// and a histogram over [min, max]. When the most frequent threshold of the
+// measurements has numeric limits, Capability holds them with Cp and Cpk.
+// Returns HTTP 400 for invalid parameters and 500 for server errors; a step
+// without numeric measurements yields Count 0.
+//
+// Swagger annotations:
+//
+// @Summary      Get step measurement statistics
+// @Description  Returns distribution statistics, histogram and Cp/Cpk of the numeric measured values of a test step
+// @Tags         analytics
+// @Accept       json
+// @Produce      json,text/csv,application/x-ndjson
+// @Param        name               path   string  true   "TestStepName"
+// @Param        station_type       query  string  false  "PCBA or Final"
+// @Param        part_number        query  string  false  "Part number"
+// @Param        test_tool_version  query  string  false  "Test tool version"
+// @Param        from               query  string  false  "Earliest TestFinishedTime (date, 'YYYY-MM-DD hh:mm:ss' or RFC 3339)"
+// @Param        to                 query  string  false  "Latest TestFinishedTime; a bare date includes the whole day"
+// @Param        bins               query  int     false  "Histogram bins, 1-200 (default 20)"
+// @Success      200  {object}  dto.StepStatsDTO
+// @Failure      400  {object}  map[string]string  "invalid query parameter"
+// @Failure      500  {object}  map[string]string  "internal error"
+// @Router       /analytics/steps/{name} [get]
+func (h *AnalyticsHandler) StepStats(w http.ResponseWriter, r *http.Request) {
+	name := chi.URLParam(r, "name")
+	// chi matches on the raw path when it differs from the decoded one, e.g.
+	// for an escaped "/" in the step name.
+	if r.URL.RawPath != "" {
+		if unescaped, err := url.PathUnescape(name); err == nil {
+			name = unescaped
+		}
+	}
+
+	params := r.URL.Query()
+	q := analytics.StepQuery{
+		StationType:     params.Get("station_type"),
+		PartNumber:      params.Get("part_number"),
+		TestToolVersion: params.Get("test_tool_version"),
+		From:            params.Get("from"),
+		To:              params.Get("to"),
+	}
+	if v := params.Get("bins"); v != "" {
+		bins, err := strconv.Atoi(v)
+		if err != nil {
+			respondError(w, http.StatusBadRequest, "bins must be a number")
+			return
+		}
+		q.Bins = bins
+	}
+
+	stats, err := h.svc.StepStats(r.Context(), name, q)
+	if errors.Is(err, analytics.ErrInvalidQuery) {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err != nil {
+		logger.ErrorContext(r.Context(), "Failed to compute step statistics", err, logger.WithFields(map[string]interface{}{
+			"step":  name,
+			"query": r.URL.RawQuery,
+		}))
+		respondError(w, http.StatusInternalServerError, "internal error")
+		return
+	}
+
+	respond(w, r, http.StatusOK, stats, stats)
+}
+
+// errorQuery reads the filters shared by the error Pareto and its drill-down.
+func errorQuery(r *http.Request) analytics.ErrorQuery {
+	params := r.URL.Query()
+	return analytics.ErrorQuery{
+		StationType: params.Get("station_type"),
+		PartNumber:  params.Get("part_number"),
+		From:        params.Get("from"),
+		To:          params.Get("to"),
+		SortBy:      params.Get("sort"),
+	}
+}
+
+// ErrorPareto handles HTTP GET requests for the error code Pareto.
+//
+// Every error code is counted by occurrences (station records carrying the
+// code itself or in one of their steps) and by affected devices, ranked by
+// "sort" with each code's share and the cumulative share of the total.
+// Returns HTTP 400 for invalid parameters and 500 for server errors.
+//
+// Swagger annotations:
+//
+// @Summary      Get the error code Pareto
+// @Description  Ranks normalized error codes by occurrences or affected devices, with cumulative shares
+// @Tags         analytics
+// @Accept       json
+// @Produce      json,text/csv,application/x-ndjson
+// @Param        station_type  query  string  false  "PCBA or Final"
+// @Param        part_number   query  string  false  "Part number"
+// @Param        from          query  string  false  "Earliest TestFinishedTime (date, 'YYYY-MM-DD hh:mm:ss' or RFC 3339)"
+// @Param        to            query  string  false  "Latest TestFinishedTime; a bare date includes the whole day"
+// @Param        sort          query  string  false  "occurrences (default) or devices"
+// @Param        limit         query  int     false  "Number of top codes to return (default all)"
+// @Success      200  {object}  dto.ErrorParetoDTO
+// @Failure      400  {object}  map[string]string  "invalid query parameter"
+// @Failure      500  {object}  map[string]string  "internal error"
+// @Router       /analytics/errors [get]
+func (h *AnalyticsHandler) ErrorPareto(w http.ResponseWriter, r *http.Request) {
+	q := errorQuery(r)
+	if v := r.URL.Query().Get("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil {
+			respondError(w, http.StatusBadRequest, "limit must be a number")
+			return
+		}
+		q.Limit = limit
+	}
+
+	pareto, err := h.svc.ErrorPareto(r.Context(), q)
+	if errors.Is(err, analytics.ErrInvalidQuery) {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err != nil {
+		logger.ErrorContext(r.Context(), "Failed to compute error pareto", err, logger.WithField("query", r.URL.RawQuery))
+		respondError(w, http.StatusInternalServerError, "internal error")
+		return
+	}
+
+	respond(w, r, http.StatusOK, pareto, pareto.Codes)
+}
+
+// ErrorCodeDevices handles HTTP GET requests for the devices behind one
+// error code.
+//
+// Takes the same filters as ErrorPareto and lists every PCBA number with the
+// code, with its occurrences, the station types it occurred at and when it
+// was first and last seen. Returns HTTP 400 for invalid parameters and 500
+// for server errors.
+//
+// Swagger annotations:
+//
+// @Summary      Get the devices behind an error code
+// @Description  Drill-down of the error code Pareto to the PCBA numbers with the code
+// @Tags         analytics
+// @Accept       json
+// @Produce      json,text/csv,application/x-ndjson
+// @Param        code          path   string  true   "Error code"
+// @Param        station_type  query  string  false  "PCBA or Final"
+// @Param        part_number   query  string  false  "Part number"
+// @Param        from          query  string  false  "Earliest TestFinishedTime"
+// @Param        to            query  string  false  "Latest TestFinishedTime"
+// @Success      200  {object}  dto.ErrorCodeDevicesDTO
+// @Failure      400  {object}  map[string]string  "invalid query parameter"
+// @Failure      500  {object}  map[string]string  "internal error"
+// @Router       /analytics/errors/{code} [get]
+func (h *AnalyticsHandler) ErrorCodeDevices(w http.ResponseWriter, r *http.Request) {
+	code := chi.URLParam(r, "code")
+	devices, err := h.svc.ErrorCodeDevices(r.Context(), code, errorQuery(r))
+	if errors.Is(err, analytics.ErrInvalidQuery) {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err != nil {
+		logger.ErrorContext(r.Context(), "Failed to get devices of error code", err, logger.WithField("code", code))
+		respondError(w, http.StatusInternalServerError, "internal error")
+		return
+	}
+
+	respond(w, r, http.StatusOK, devices, devices.Devices)
+}
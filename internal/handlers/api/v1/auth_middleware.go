@@ -0,0 +1,100 @@
+package v1
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/NoroSaroyan/log-parser/internal/infrastructure/logger"
+	"github.com/NoroSaroyan/log-parser/internal/infrastructure/redaction"
+	"github.com/NoroSaroyan/log-parser/internal/services/auth"
+)
+
+// Authenticate returns middleware that identifies the caller of every request
+// and stores the principal in the request context.
+//
+// Credentials are read from the X-API-Key header or from an
+// "Authorization: Bearer <JWT>" header. Requests without valid credentials
+// are rejected with HTTP 401. While authentication is disabled every request
+// gets the read-only auth.Anonymous principal, or auth.AnonymousAdmin if the
+// configuration opts in.
+//
+// Example usage:
+//
+//	r.Use(v1.Authenticate(authService))
+func Authenticate(svc auth.AuthService) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			apiKey := strings.TrimSpace(r.Header.Get("X-API-Key"))
+			bearer := ""
+			if h := r.Header.Get("Authorization"); len(h) > 7 && strings.EqualFold(h[:7], "Bearer ") {
+				bearer = strings.TrimSpace(h[7:])
+			}
+
+			p, err := svc.Authenticate(r.Context(), apiKey, bearer)
+			if errors.Is(err, auth.ErrUnauthenticated) {
+				logger.WarnContext(r.Context(), "Rejected unauthenticated request", logger.WithFields(map[string]interface{}{
+					"path":   r.URL.Path,
+					"remote": r.RemoteAddr,
+					"reason": err.Error(),
+				}))
+				w.Header().Set("WWW-Authenticate", `Bearer realm="log-parser"`)
+				respondError(w, http.StatusUnauthorized, "authentication required")
+				return
+			}
+			if err != nil {
+				logger.ErrorContext(r.Context(), "Failed to authenticate request", err, logger.WithField("path", r.URL.Path))
+				respondError(w, http.StatusInternalServerError, "internal error")
+				return
+			}
+
+			next.ServeHTTP(w, r.WithContext(auth.WithPrincipal(r.Context(), p)))
+		})
+	}
+}
+
+// RequireRole returns middleware that rejects requests whose principal does
+// not have at least role, with HTTP 401 when there is no principal and 403
+// when its role is too low. It must run after Authenticate.
+//
+// Example usage:
+//
+//	r.With(v1.RequireRole(auth.RoleEngineer)).Post("/ingestions", h.Create)
+func RequireRole(role auth.Role) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			p := auth.FromContext(r.Context())
+			if p == nil {
+				respondError(w, http.StatusUnauthorized, "authentication required")
+				return
+			}
+			if !p.Role.Allows(role) {
+				message := "requires the " + string(role) + " role"
+				if p.Method == auth.MethodAnonymous {
+					message += ": enable authentication or set auth.allow_anonymous_admin"
+				}
+				respondError(w, http.StatusForbidden, message)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// revealed returns the reveal function of the redaction policy for the
+// principal of ctx: a field is shown in clear when its rule reveals it to the
+// role of the principal.
+func revealed(ctx context.Context) func(field string) bool {
+	policy := redaction.Current()
+	return func(field string) bool {
+		rule, ok := policy.Rule(field)
+		return ok && rule.RevealTo != "" && auth.Reveals(ctx, auth.Role(rule.RevealTo))
+	}
+}
+
+// redacted returns v with the fields the principal of ctx may not see in
+// clear redacted.
+func redacted(ctx context.Context, v any) any {
+	return redaction.Current().Redact(v, revealed(ctx))
+}
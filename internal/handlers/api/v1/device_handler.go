@@ -0,0 +1,234 @@
+package v1
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/NoroSaroyan/log-parser/internal/domain/models/db"
+	"github.com/NoroSaroyan/log-parser/internal/domain/models/dto"
+	"github.com/NoroSaroyan/log-parser/internal/infrastructure/logger"
+	"github.com/NoroSaroyan/log-parser/internal/infrastructure/redaction"
+	"github.com/NoroSaroyan/log-parser/internal/services/auth"
+	"github.com/NoroSaroyan/log-parser/internal/services/device"
+	"github.com/go-chi/chi/v5"
+)
+
+// DeviceHandler provides HTTP handlers for the device listing and the
+// timeline of a single device.
+type DeviceHandler struct {
+	svc device.DeviceService
+}
+
+// NewDeviceHandler creates a new DeviceHandler with the provided DeviceService.
+func NewDeviceHandler(svc device.DeviceService) *DeviceHandler {
+	return &DeviceHandler{svc: svc}
+}
+
+// List handles HTTP GET requests for one page of devices.
+//
+// All query parameters are optional. Filters apply to station records: a
+// device is listed when one of its records matches all of them. The response
+// holds the page, the total number of matching devices and, when more devices
+// follow, a NextCursor to pass as "cursor" for the next page. Returns HTTP 400
+// for invalid parameters and 500 for server errors.
+//
+// Swagger annotations:
+//
+// @Summary      List devices
+// @Description  Returns a page of devices (PCBA numbers) with their station summary, filtered and sorted
+// @Tags         device
+// @Accept       json
+// @Produce      json,text/csv,application/x-ndjson
+// @Param        station_type       query  string  false  "PCBA or Final"
+// @Param        part_number        query  string  false  "Part number"
+// @Param        product_line       query  string  false  "Product line"
+// @Param        passed             query  bool    false  "IsAllPassed of the latest matching station record"
+// @Param        test_tool_version  query  string  false  "Test tool version"
+// @Param        tested_from        query  string  false  "Earliest TestFinishedTime (date, 'YYYY-MM-DD hh:mm:ss' or RFC 3339)"
+// @Param        tested_to          query  string  false  "Latest TestFinishedTime; a bare date includes the whole day"
+// @Param        sort               query  string  false  "pcba_number (default), first_tested or last_tested"
+// @Param        order              query  string  false  "asc (default) or desc"
+// @Param        limit              query  int     false  "Page size, 1-500 (default 50)"
+// @Param        cursor             query  string  false  "NextCursor of the previous page"
+// @Success      200  {object}  dto.DevicePageDTO
+// @Failure      400  {object}  map[string]string  "invalid query parameter"
+// @Failure      500  {object}  map[string]string  "internal error"
+// @Router       /devices [get]
+func (h *DeviceHandler) List(w http.ResponseWriter, r *http.Request) {
+	params := r.URL.Query()
+	q := device.ListQuery{
+		StationType:     params.Get("station_type"),
+		PartNumber:      params.Get("part_number"),
+		ProductLine:     params.Get("product_line"),
+		TestToolVersion: params.Get("test_tool_version"),
+		TestedFrom:      params.Get("tested_from"),
+		TestedTo:        params.Get("tested_to"),
+		Sort:            params.Get("sort"),
+		Order:           params.Get("order"),
+		Cursor:          params.Get("cursor"),
+	}
+	if v := params.Get("passed"); v != "" {
+		passed, err := strconv.ParseBool(v)
+		if err != nil {
+			respondError(w, http.StatusBadRequest, "passed must be true or false")
+			return
+		}
+		q.Passed = &passed
+	}
+	if v := params.Get("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil {
+			respondError(w, http.StatusBadRequest, "limit must be a number")
+			return
+		}
+		q.Limit = limit
+	}
+
+	page, err := h.svc.ListDevices(r.Context(), q)
+	if errors.Is(err, device.ErrInvalidQuery) {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err != nil {
+		logger.ErrorContext(r.Context(), "Failed to list devices", err, logger.WithField("query", r.URL.RawQuery))
+		respondError(w, http.StatusInternalServerError, "internal error")
+		return
+	}
+
+	w.Header().Set("X-Total-Count", strconv.Itoa(page.Total))
+	if page.NextCursor != "" {
+		w.Header().Set("X-Next-Cursor", page.NextCursor)
+	}
+	respond(w, r, http.StatusOK, page, page.Devices)
+}
+
+// Get handles HTTP GET requests for the timeline of one device.
+//
+// The response aggregates what /download, /pcba and /final return separately:
+// the flash history, the latest LogisticData and every station session in
+// chronological order with attempt number, result, error codes and steps,
+// plus the lifecycle status (flashed, pcba_passed, final_passed or stuck).
+// Returns HTTP 404 if nothing is stored for the PCBA number and 500 for
+// server errors.
+//
+// Swagger annotations:
+//
+// @Summary      Get the timeline of a device
+// @Description  Returns flash history, logistic data, station sessions with steps and lifecycle status of a PCBANumber
+// @Tags         device
+// @Accept       json
+// @Produce      json,text/csv,application/x-ndjson
+// @Param        pcba  path      string  true  "PCBA Number"
+// @Success      200  {object}  dto.DeviceTimelineDTO
+// @Failure      404  {object}  map[string]string  "not found"
+// @Failure      500  {object}  map[string]string  "internal error"
+// @Router       /devices/{pcba} [get]
+func (h *DeviceHandler) Get(w http.ResponseWriter, r *http.Request) {
+	pcba := chi.URLParam(r, "pcba")
+
+	timeline, found, err := h.svc.GetTimeline(r.Context(), pcba)
+	if err != nil {
+		logger.ErrorContext(r.Context(), "Failed to build device timeline", err, logger.WithField("pcba_number", pcba))
+		respondError(w, http.StatusInternalServerError, "internal error")
+		return
+	}
+	if !found {
+		respondError(w, http.StatusNotFound, "not found")
+		return
+	}
+
+	sessions := make([]timelineSession, len(timeline.Sessions))
+	for i, s := range timeline.Sessions {
+		sessions[i] = timelineSession{PCBANumber: timeline.PCBANumber, Status: timeline.Status, DeviceSessionDTO: s}
+	}
+	respond(w, r, http.StatusOK, timeline, sessions)
+}
+
+// Search handles HTTP GET requests looking devices up by a secondary identifier.
+//
+// Exactly one of the query parameters imei, imsi, iccid, ble_mac, ble_sn or
+// product_sn must be given. The response lists every matching device and sets
+// Ambiguous when the identifier maps to more than one. BLE MAC addresses match
+// regardless of case and separators. Returns HTTP 400 unless exactly one
+// identifier is given and 500 for server errors; no match is an empty list.
+//
+// Swagger annotations:
+//
+// @Summary      Search devices by secondary identifier
+// @Description  Returns the devices whose logistic data carries the given IMEI, IMSI, ICCID, BLE MAC, BLE SN or ProductSN
+// @Tags         device
+// @Accept       json
+// @Produce      json,text/csv,application/x-ndjson
+// @Param        imei        query  string  false  "IMEI"
+// @Param        imsi        query  string  false  "IMSI"
+// @Param        iccid       query  string  false  "TCU ICCID"
+// @Param        ble_mac     query  string  false  "BLE MAC, with or without separators"
+// @Param        ble_sn      query  string  false  "BLE serial number"
+// @Param        product_sn  query  string  false  "Product serial number"
+// @Success      200  {object}  dto.DeviceSearchDTO
+// @Failure      400  {object}  map[string]string  "exactly one identifier is required"
+// @Failure      500  {object}  map[string]string  "internal error"
+// @Router       /devices/search [get]
+func (h *DeviceHandler) Search(w http.ResponseWriter, r *http.Request) {
+	params := r.URL.Query()
+	var identifier string
+	for _, id := range device.Identifiers {
+		if !params.Has(id) {
+			continue
+		}
+		if identifier != "" {
+			respondError(w, http.StatusBadRequest, "exactly one identifier is required")
+			return
+		}
+		identifier = id
+	}
+	if identifier == "" {
+		respondError(w, http.StatusBadRequest, "exactly one identifier is required")
+		return
+	}
+	field := identifierFields[identifier]
+	rule, redactedField := redaction.Current().Rule(field)
+	if redactedField && !revealed(r.Context())(field) {
+		// Authenticated callers may not probe values they are not allowed to see.
+		if p := auth.FromContext(r.Context()); p != nil && p.Method != auth.MethodAnonymous {
+			respondError(w, http.StatusForbidden, "searching by "+identifier+" requires the "+rule.RevealTo+" role")
+			return
+		}
+	}
+
+	result, err := h.svc.Search(r.Context(), identifier, params.Get(identifier))
+	if errors.Is(err, device.ErrInvalidQuery) {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err != nil {
+		logger.ErrorContext(r.Context(), "Failed to search devices", err, logger.WithField("identifier", identifier))
+		respondError(w, http.StatusInternalServerError, "internal error")
+		return
+	}
+	if redactedField && !revealed(r.Context())(field) {
+		result.Value, _ = redaction.Current().String(field, result.Value)
+	}
+
+	respond(w, r, http.StatusOK, result, result.Matches)
+}
+
+// identifierFields maps the search identifiers to the LogisticData fields
+// they are looked up in, by JSON name.
+var identifierFields = map[string]string{
+	db.IdentifierIMEI:      "IMEI",
+	db.IdentifierIMSI:      "IMSI",
+	db.IdentifierICCID:     "TcuICCID",
+	db.IdentifierBleMac:    "BleMac",
+	db.IdentifierBleSN:     "BleSN",
+	db.IdentifierProductSN: "ProductSN",
+}
+
+// timelineSession is a session of a device timeline as a CSV or NDJSON
+// record. Downloads and LogisticData are only part of the JSON timeline.
+type timelineSession struct {
+	PCBANumber string `json:"PCBANumber"`
+	Status     string `json:"Status"`
+	dto.DeviceSessionDTO
+}
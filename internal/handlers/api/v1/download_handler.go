@@ -39,7 +39,7 @@ func NewDownloadHandler(svc downloadinfo.DownloadInfoService) *DownloadHandler {
 // @Description  Returns the DownloadInfo for the specified PCBANumber
 // @Tags         download
 // @Accept       json
-// @Produce      json
+// @Produce      json,text/csv,application/x-ndjson
 // @Param        pcbanumber  query     string  true  "PCBA Number"
 // @Success      200  {object}  dto.DownloadInfoDTO
 // @Failure      400  {object}  map[string]string  "pcbanumber is required"
@@ -55,7 +55,7 @@ func (h *DownloadHandler) Get(w http.ResponseWriter, r *http.Request) {
 
 	dto, err := h.svc.GetByPCBANumber(r.Context(), pcba)
 	if err != nil {
-		logger.Error("Failed to retrieve DownloadInfo by PCBA number",
+		logger.ErrorContext(r.Context(), "Failed to retrieve DownloadInfo by PCBA number",
 			err,
 			logger.WithFields(map[string]interface{}{
 				"pcba_number": pcba,
@@ -70,5 +70,5 @@ func (h *DownloadHandler) Get(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	respondJSON(w, http.StatusOK, dto)
+	respond(w, r, http.StatusOK, dto, dto)
 }
@@ -0,0 +1,52 @@
+package v1
+
+import (
+	"net/http"
+
+	"github.com/NoroSaroyan/log-parser/internal/infrastructure/logger"
+	"github.com/NoroSaroyan/log-parser/internal/services/health"
+	"github.com/go-chi/chi/v5"
+)
+
+// HealthHandler provides the liveness and readiness probes.
+type HealthHandler struct {
+	svc health.HealthService
+}
+
+// NewHealthHandler creates a new HealthHandler with the provided HealthService.
+func NewHealthHandler(svc health.HealthService) *HealthHandler {
+	return &HealthHandler{svc: svc}
+}
+
+// RegisterHealth registers /healthz and /readyz on r. They are meant for the
+// root router, outside /api/v1 and its authentication, and are therefore not
+// part of the Swagger documentation.
+func RegisterHealth(r chi.Router, svc health.HealthService) {
+	h := NewHealthHandler(svc)
+	r.Get("/healthz", h.Live)
+	r.Get("/readyz", h.Ready)
+}
+
+// Live handles HTTP GET requests of the liveness probe.
+//
+// It answers HTTP 200 as long as the process serves requests and checks no
+// dependency, so orchestrators restart the API only when it hangs.
+func (h *HealthHandler) Live(w http.ResponseWriter, r *http.Request) {
+	respondJSON(w, http.StatusOK, h.svc.Live(r.Context()))
+}
+
+// Ready handles HTTP GET requests of the readiness probe.
+//
+// It checks the database connection, that the schema migrations are current
+// and that the ingestion queue accepts uploads, and reports the status and
+// latency of every check. Returns HTTP 200 when all of them pass and 503
+// otherwise.
+func (h *HealthHandler) Ready(w http.ResponseWriter, r *http.Request) {
+	report := h.svc.Ready(r.Context())
+	status := http.StatusOK
+	if report.Status != health.StatusOK {
+		status = http.StatusServiceUnavailable
+		logger.WarnContext(r.Context(), "Readiness check failed", logger.WithField("checks", report.Checks))
+	}
+	respondJSON(w, status, report)
+}
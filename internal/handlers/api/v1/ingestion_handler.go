@@ -0,0 +1,138 @@
+package v1
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/NoroSaroyan/log-parser/internal/infrastructure/logger"
+	"github.com/NoroSaroyan/log-parser/internal/services/ingestion"
+	"github.com/go-chi/chi/v5"
+)
+
+// uploadTimeout is how long a client may take to send an upload. It replaces
+// the read and write deadlines of the server for the upload request only.
+const uploadTimeout = 10 * time.Minute
+
+// IngestionHandler provides HTTP handlers for uploading log files and
+// following their ingestion.
+type IngestionHandler struct {
+	svc ingestion.IngestionService
+}
+
+// NewIngestionHandler creates a new IngestionHandler with the provided IngestionService.
+func NewIngestionHandler(svc ingestion.IngestionService) *IngestionHandler {
+	return &IngestionHandler{svc: svc}
+}
+
+// Create handles HTTP POST requests uploading a log file for ingestion.
+//
+// The request is multipart/form-data with the file in the "file" field; only
+// .log and .gz files are accepted. The file is stored and queued, and the
+// response is the queued job with HTTP 202 and a Location header pointing to
+// its status. The job runs the same pipeline as the CLI process mode.
+// Returns HTTP 400 for a missing or unsupported file, 413 when the upload is
+// larger than the configured limit, 503 when the ingestion queue is full and
+// 500 for server errors.
+//
+// Swagger annotations:
+//
+// @Summary      Upload a log file
+// @Description  Queues a .log or .gz file for background ingestion and returns the job
+// @Tags         ingestion
+// @Accept       multipart/form-data
+// @Produce      json
+// @Param        file  formData  file  true  "Log file (.log or .gz)"
+// @Success      202  {object}  dto.IngestionJobDTO
+// @Failure      400  {object}  map[string]string  "missing or unsupported file"
+// @Failure      413  {object}  map[string]string  "upload too large"
+// @Failure      503  {object}  map[string]string  "ingestion queue is full"
+// @Failure      500  {object}  map[string]string  "internal error"
+// @Router       /ingestions [post]
+func (h *IngestionHandler) Create(w http.ResponseWriter, r *http.Request) {
+	rc := http.NewResponseController(w)
+	_ = rc.SetReadDeadline(time.Now().Add(uploadTimeout))
+	_ = rc.SetWriteDeadline(time.Now().Add(uploadTimeout + 15*time.Second))
+	r.Body = http.MaxBytesReader(w, r.Body, h.svc.MaxUploadBytes())
+
+	reader, err := r.MultipartReader()
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "expected a multipart/form-data upload")
+		return
+	}
+	for {
+		part, err := reader.NextPart()
+		if err != nil {
+			if isTooLarge(err) {
+				respondError(w, http.StatusRequestEntityTooLarge, "upload too large")
+				return
+			}
+			respondError(w, http.StatusBadRequest, "missing \"file\" field")
+			return
+		}
+		if part.FormName() != "file" || part.FileName() == "" {
+			part.Close()
+			continue
+		}
+
+		job, err := h.svc.Submit(r.Context(), part.FileName(), part)
+		part.Close()
+		switch {
+		case errors.Is(err, ingestion.ErrUnsupportedFile):
+			respondError(w, http.StatusBadRequest, err.Error())
+		case errors.Is(err, ingestion.ErrQueueFull), errors.Is(err, ingestion.ErrClosed):
+			w.Header().Set("Retry-After", "30")
+			respondError(w, http.StatusServiceUnavailable, err.Error())
+		case isTooLarge(err):
+			respondError(w, http.StatusRequestEntityTooLarge, "upload too large")
+		case err != nil:
+			logger.ErrorContext(r.Context(), "Failed to queue upload", err, logger.WithField("file", part.FileName()))
+			respondError(w, http.StatusInternalServerError, "internal error")
+		default:
+			w.Header().Set("Location", r.URL.Path+"/"+job.ID)
+			respondJSON(w, http.StatusAccepted, job)
+		}
+		return
+	}
+}
+
+// Get handles HTTP GET requests for the state of an ingestion job.
+//
+// The response holds the status (queued, running, succeeded or failed), the
+// current stage, the dispatch progress, the parsing and dispatch statistics
+// once known and the errors met so far. Jobs are kept in memory, so a job is
+// unknown after a restart or once enough newer jobs have finished. Returns
+// HTTP 404 for unknown jobs and 500 for server errors.
+//
+// Swagger annotations:
+//
+// @Summary      Get an ingestion job
+// @Description  Returns the progress, statistics and errors of an uploaded file
+// @Tags         ingestion
+// @Accept       json
+// @Produce      json,text/csv,application/x-ndjson
+// @Param        id  path  string  true  "Job ID"
+// @Success      200  {object}  dto.IngestionJobDTO
+// @Failure      404  {object}  map[string]string  "job not found"
+// @Failure      500  {object}  map[string]string  "internal error"
+// @Router       /ingestions/{id} [get]
+func (h *IngestionHandler) Get(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	job, err := h.svc.Get(r.Context(), id)
+	if errors.Is(err, ingestion.ErrJobNotFound) {
+		respondError(w, http.StatusNotFound, "ingestion job not found")
+		return
+	}
+	if err != nil {
+		logger.ErrorContext(r.Context(), "Failed to get ingestion job", err, logger.WithField("job_id", id))
+		respondError(w, http.StatusInternalServerError, "internal error")
+		return
+	}
+
+	respond(w, r, http.StatusOK, job, job)
+}
+
+func isTooLarge(err error) bool {
+	var maxErr *http.MaxBytesError
+	return errors.As(err, &maxErr)
+}
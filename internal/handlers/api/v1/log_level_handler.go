@@ -0,0 +1,88 @@
+package v1
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/NoroSaroyan/log-parser/internal/domain/models/dto"
+	"github.com/NoroSaroyan/log-parser/internal/infrastructure/logger"
+	"github.com/NoroSaroyan/log-parser/internal/services/auth"
+)
+
+// maxLogLevelsBody bounds the body of a log level change.
+const maxLogLevelsBody = 64 << 10
+
+// LogLevelHandler provides HTTP handlers reading and changing the levels of
+// the running logger, so a problem can be investigated at debug level
+// without a restart.
+type LogLevelHandler struct{}
+
+// NewLogLevelHandler creates a new LogLevelHandler.
+func NewLogLevelHandler() *LogLevelHandler {
+	return &LogLevelHandler{}
+}
+
+// Get handles HTTP GET requests for the levels of the logger.
+//
+// Swagger annotations:
+//
+// @Summary      Get the log level and the package overrides
+// @Tags         admin
+// @Produce      json
+// @Success      200  {object}  dto.LogLevelsDTO
+// @Failure      401  {object}  map[string]string  "authentication required"
+// @Failure      403  {object}  map[string]string  "requires the admin role"
+// @Router       /admin/log-level [get]
+func (h *LogLevelHandler) Get(w http.ResponseWriter, r *http.Request) {
+	respondJSON(w, http.StatusOK, currentLogLevels())
+}
+
+// Put handles HTTP PUT requests changing the levels of the logger.
+//
+// The body replaces the level and all package overrides, which last until
+// the next change or restart; the configured levels are not touched.
+// Returns the levels now in effect, or HTTP 400 for an invalid body or
+// level.
+//
+// Swagger annotations:
+//
+// @Summary      Change the log level and the package overrides
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Param        levels  body      dto.LogLevelsDTO  true  "Level and package overrides"
+// @Success      200  {object}  dto.LogLevelsDTO
+// @Failure      400  {object}  map[string]string  "invalid level"
+// @Failure      401  {object}  map[string]string  "authentication required"
+// @Failure      403  {object}  map[string]string  "requires the admin role"
+// @Router       /admin/log-level [put]
+func (h *LogLevelHandler) Put(w http.ResponseWriter, r *http.Request) {
+	var body dto.LogLevelsDTO
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxLogLevelsBody))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&body); err != nil {
+		respondError(w, http.StatusBadRequest, "invalid body: "+err.Error())
+		return
+	}
+	if err := logger.SetLevels(body.Level, body.Packages); err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	levels := currentLogLevels()
+	caller := ""
+	if p := auth.FromContext(r.Context()); p != nil {
+		caller = p.Name
+	}
+	logger.WarnContext(r.Context(), "Log levels changed", logger.WithFields(map[string]interface{}{
+		"level":    levels.Level,
+		"packages": levels.Packages,
+		"by":       caller,
+	}))
+	respondJSON(w, http.StatusOK, levels)
+}
+
+func currentLogLevels() dto.LogLevelsDTO {
+	level, packages := logger.Levels()
+	return dto.LogLevelsDTO{Level: level, Packages: packages}
+}
@@ -0,0 +1,83 @@
+package v1
+
+import (
+	"net/http"
+
+	"github.com/NoroSaroyan/log-parser/internal/domain/models/dto"
+	"github.com/NoroSaroyan/log-parser/internal/infrastructure/logger"
+	"github.com/NoroSaroyan/log-parser/internal/services/consistency"
+)
+
+// LogisticConflictHandler provides HTTP handlers for the LogisticData
+// conflicts recorded between the PCBA and the Final station of a device.
+type LogisticConflictHandler struct {
+	svc consistency.ConsistencyService
+}
+
+// NewLogisticConflictHandler creates a new LogisticConflictHandler with the provided ConsistencyService.
+func NewLogisticConflictHandler(svc consistency.ConsistencyService) *LogisticConflictHandler {
+	return &LogisticConflictHandler{svc: svc}
+}
+
+// Get handles HTTP GET requests for the recorded LogisticData conflicts.
+//
+// The response is a JSON array with one entry per device. The optional
+// "classification" query parameter ("expected" or "suspicious") keeps only
+// conflicts of that classification; "pcbanumber" restricts the result to one
+// device. CSV and NDJSON are streamed device by device as the repository
+// reads the conflicts. Returns HTTP 400 for an unknown classification and 500 for server
+// errors.
+//
+// Swagger annotations:
+//
+// @Summary      Get LogisticData conflicts between PCBA and Final
+// @Description  Returns the fields whose LogisticData differs between the PCBA and the Final station of a device
+// @Tags         logistic
+// @Accept       json
+// @Produce      json,text/csv,application/x-ndjson
+// @Param        classification  query     string  false  "expected or suspicious"
+// @Param        pcbanumber      query     string  false  "PCBA Number"
+// @Success      200  {array}   dto.DeviceLogisticConflictsDTO
+// @Failure      400  {object}  map[string]string  "invalid classification"
+// @Failure      500  {object}  map[string]string  "internal error"
+// @Router       /logistic/conflicts [get]
+func (h *LogisticConflictHandler) Get(w http.ResponseWriter, r *http.Request) {
+	classification := r.URL.Query().Get("classification")
+	if classification != "" && classification != consistency.Expected && classification != consistency.Suspicious {
+		respondError(w, http.StatusBadRequest, "classification must be expected or suspicious")
+		return
+	}
+	pcba := r.URL.Query().Get("pcbanumber")
+
+	if media := negotiate(r); media != mediaJSON {
+		started, err := streamRecords(w, r, media, func(yield func(dto.DeviceLogisticConflictsDTO) error) error {
+			return h.svc.ForEachConflicts(r.Context(), classification, pcba, yield)
+		})
+		if err != nil {
+			logger.ErrorContext(r.Context(), "Failed to stream LogisticData conflicts",
+				err,
+				logger.WithFields(map[string]interface{}{
+					"classification": classification,
+					"pcba_number":    pcba,
+				}),
+			)
+			abortStream(w, started, http.StatusInternalServerError, "internal error")
+		}
+		return
+	}
+
+	devices, err := h.svc.GetConflicts(r.Context(), classification, pcba)
+	if err != nil {
+		logger.ErrorContext(r.Context(), "Failed to retrieve LogisticData conflicts",
+			err,
+			logger.WithFields(map[string]interface{}{
+				"classification": classification,
+				"pcba_number":    pcba,
+			}),
+		)
+		respondError(w, http.StatusInternalServerError, "internal error")
+		return
+	}
+
+	respond(w, r, http.StatusOK, devices, nil)
+}
@@ -5,21 +5,29 @@ package v1
 
 import (
 	"encoding/json"
-	"log"
 	"net/http"
+	"net/url"
+	"slices"
+	"strings"
 	"time"
 
 	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/cors"
+
+	"github.com/NoroSaroyan/log-parser/internal/config"
+	"github.com/NoroSaroyan/log-parser/internal/infrastructure/logger"
+	"github.com/NoroSaroyan/log-parser/internal/infrastructure/redaction"
 )
 
 // JSON is a collection of HTTP middleware handlers for JSON APIs.
 //
 // It includes:
 //
-//   - A request logger with standard log formatting.
 //   - A panic recovery middleware that converts panics into HTTP 500 errors.
 //   - A timeout middleware that enforces a maximum request duration of 15 seconds.
 //
+// Requests are logged once, by RequestLogger on the root router.
+//
 // Example usage:
 //
 //	r := chi.NewRouter()
@@ -27,13 +35,101 @@ import (
 //
 // These middleware are designed to be composable and work well in Go HTTP servers.
 var JSON = []func(http.Handler) http.Handler{
-	middleware.RequestLogger(&middleware.DefaultLogFormatter{
-		Logger: log.New(log.Writer(), "", log.LstdFlags),
-	}),
 	middleware.Recoverer,
 	middleware.Timeout(15 * time.Second),
 }
 
+// Upload is JSON without the timeout, for requests that stream a file body.
+// Such handlers set their own read and write deadlines instead.
+var Upload = []func(http.Handler) http.Handler{
+	middleware.Recoverer,
+}
+
+// Stream is JSON for the list endpoints that stream CSV and NDJSON record by
+// record (see streamRecords): the timeout only applies to requests that
+// negotiate JSON, as an export runs for as long as the client keeps reading
+// it. Streamed responses push their write deadline back on every flush
+// instead.
+var Stream = []func(http.Handler) http.Handler{
+	middleware.Recoverer,
+	unlessStreamed(middleware.Timeout(15 * time.Second)),
+}
+
+// unlessStreamed applies mw only to requests that negotiate JSON.
+func unlessStreamed(mw func(http.Handler) http.Handler) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		wrapped := mw(next)
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if negotiate(r) == mediaJSON {
+				wrapped.ServeHTTP(w, r)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// RequestLogger logs every request once it is served, with its method,
+// path, status, size and duration, through the application logger.
+//
+// The query is logged with the values of redacted fields redacted as in
+// every log line, e.g. the IMSI of /devices/search?imsi=...; the path is
+// logged as it is. It is meant for the root router, ahead of
+// middleware.Recoverer.
+//
+// Example usage:
+//
+//	r.Use(v1.RequestLogger)
+func RequestLogger(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+		defer func() {
+			status := ww.Status()
+			if status == 0 {
+				status = http.StatusOK
+			}
+			fields := map[string]interface{}{
+				"method":   r.Method,
+				"path":     r.URL.Path,
+				"status":   status,
+				"bytes":    ww.BytesWritten(),
+				"duration": time.Since(start).String(),
+			}
+			if query := redactQuery(r.URL.Query()); query != "" {
+				fields["query"] = query
+			}
+			logger.InfoContext(r.Context(), "HTTP request", logger.WithFields(fields))
+		}()
+		next.ServeHTTP(ww, r)
+	})
+}
+
+// redactQuery encodes query with the values of the redacted fields redacted
+// by the current policy; dropped values are left empty. Parameters are
+// matched to fields by the search identifiers, e.g. iccid to TcuICCID, and
+// otherwise by field name, ignoring case.
+func redactQuery(query url.Values) string {
+	policy := redaction.Current()
+	out := make(url.Values, len(query))
+	for key, values := range query {
+		field, ok := identifierFields[key]
+		if !ok {
+			field = key
+			for _, f := range policy.Fields() {
+				if strings.EqualFold(f, key) {
+					field = f
+				}
+			}
+		}
+		for _, value := range values {
+			value, _ = policy.String(field, value)
+			out.Add(key, value)
+		}
+	}
+	return out.Encode()
+}
+
 // respondJSON writes a JSON-encoded response with the given HTTP status code.
 //
 // It sets the Content-Type header to "application/json" and serializes the provided
@@ -62,3 +158,30 @@ func respondError(w http.ResponseWriter, code int, message string) {
 	w.WriteHeader(code)
 	_ = json.NewEncoder(w).Encode(map[string]string{"error": message})
 }
+
+// allMethods replaces "*" in the allowed CORS methods, which go-chi/cors
+// only supports for origins and headers.
+var allMethods = []string{
+	http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut,
+	http.MethodPatch, http.MethodDelete, http.MethodOptions,
+}
+
+// CORS returns the middleware answering cross-origin requests as cfg
+// allows.
+//
+// Example usage:
+//
+//	r.Use(v1.CORS(cfg.CORS))
+func CORS(cfg config.CORSConfig) func(http.Handler) http.Handler {
+	methods := cfg.AllowedMethods
+	if slices.Contains(methods, "*") {
+		methods = allMethods
+	}
+	return cors.New(cors.Options{
+		AllowedOrigins:   cfg.AllowedOrigins,
+		AllowedMethods:   methods,
+		AllowedHeaders:   cfg.AllowedHeaders,
+		AllowCredentials: cfg.AllowCredentials,
+		MaxAge:           int(cfg.MaxAge.Seconds()),
+	}).Handler
+}
@@ -0,0 +1,465 @@
+package v1
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net/http"
+	"path"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Media types the read endpoints can produce.
+const (
+	mediaJSON   = "application/json"
+	mediaCSV    = "text/csv"
+	mediaNDJSON = "application/x-ndjson"
+)
+
+// flushEvery is the number of CSV rows or NDJSON lines written between
+// flushes, so large responses reach the client while they are produced.
+const flushEvery = 256
+
+// listSeparator joins list values, e.g. the stations of a device, in a CSV cell.
+const listSeparator = ";"
+
+// negotiate returns the media type of the response from the Accept header of
+// r: text/csv, application/x-ndjson or application/json, by quality and then
+// by order. Anything else, including a missing header, yields JSON.
+func negotiate(r *http.Request) string {
+	best, bestQ := mediaJSON, 0.0
+	for _, part := range strings.Split(r.Header.Get("Accept"), ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		q := 1.0
+		if v, ok := params["q"]; ok {
+			if q, err = strconv.ParseFloat(v, 64); err != nil {
+				continue
+			}
+		}
+
+		var candidate string
+		switch mediaType {
+		case mediaCSV:
+			candidate = mediaCSV
+		case mediaNDJSON, "application/ndjson":
+			candidate = mediaNDJSON
+		case mediaJSON, "application/*", "*/*":
+			candidate = mediaJSON
+		}
+		if candidate != "" && q > bestQ {
+			best, bestQ = candidate, q
+		}
+	}
+	return best
+}
+
+// respond writes payload as JSON, or records as CSV or NDJSON when the
+// client asks for either in its Accept header.
+//
+// records is a slice whose elements are the NDJSON lines, or a single struct
+// written as one line. In CSV every record is flattened into columns named
+// after its JSON path ("LogisticData.IMEI", "TestSteps.TestStepName"); the
+// first nested list of structs, such as the steps of a session, is expanded
+// into one row per element with the record columns repeated. Other nested
+// lists of structs are left out, lists of values are joined with ";" and
+// maps get one column per key of the first record.
+//
+// CSV and NDJSON are redacted, written and flushed record by record; see
+// streamRecords for results too large to collect in a slice first. Metadata
+// of the JSON document that is not part of the records (totals, cursors) is
+// only available in JSON unless the handler sets it as a header.
+//
+// Whatever the format, the redaction policy is applied to the response here,
+// so handlers never need to redact fields themselves.
+func respond(w http.ResponseWriter, r *http.Request, status int, payload any, records any) {
+	w.Header().Add("Vary", "Accept")
+	media := negotiate(r)
+	if media == mediaJSON {
+		respondJSON(w, status, redacted(r.Context(), payload))
+		return
+	}
+
+	v := reflect.ValueOf(records)
+	if v.Kind() != reflect.Slice {
+		rw := newRecordWriter(w, r, media, status, v.Type())
+		if rw.write(v) == nil {
+			rw.close()
+		}
+		return
+	}
+	rw := newRecordWriter(w, r, media, status, v.Type().Elem())
+	for i := 0; i < v.Len(); i++ {
+		if rw.write(v.Index(i)) != nil {
+			return
+		}
+	}
+	rw.close()
+}
+
+// streamRecords writes the records each yields with HTTP 200 as media, CSV
+// or NDJSON, like respond does but without collecting them first: every
+// record is redacted, encoded and dropped before each yields the next one,
+// so the response takes the memory of one record whatever its size.
+//
+// It returns the error of each, which stops at the first error of yield, and
+// whether the response was started by then; see abortStream.
+func streamRecords[T any](w http.ResponseWriter, r *http.Request, media string, each func(yield func(record T) error) error) (started bool, err error) {
+	w.Header().Add("Vary", "Accept")
+	rw := newRecordWriter(w, r, media, http.StatusOK, reflect.TypeFor[T]())
+	if err := each(func(record T) error {
+		return rw.write(reflect.ValueOf(record))
+	}); err != nil {
+		return rw.started, err
+	}
+	return true, rw.close()
+}
+
+// abortStream ends a streamed response that failed: with status and message
+// when nothing was written yet, otherwise by aborting the connection, so the
+// client cannot take the truncated body for a complete one.
+func abortStream(w http.ResponseWriter, started bool, status int, message string) {
+	if started {
+		panic(http.ErrAbortHandler)
+	}
+	respondError(w, status, message)
+}
+
+// streamWriteTimeout is the time a CSV or NDJSON response is given to send
+// the records written until the next flush. Each flush pushes the write
+// deadline back, so a long response is not cut off by the write timeout of
+// the server as long as the client keeps reading it.
+const streamWriteTimeout = time.Minute
+
+// recordWriter writes the records of a CSV or NDJSON response one at a time.
+// The headers and, in CSV, the column row are written with the first record,
+// whose map keys name the map columns, or by close for an empty response.
+type recordWriter struct {
+	w       http.ResponseWriter
+	r       *http.Request
+	rc      *http.ResponseController
+	media   string
+	status  int
+	t       reflect.Type
+	started bool
+	written int
+
+	cw  *csv.Writer
+	f   *flattener
+	enc *json.Encoder
+}
+
+func newRecordWriter(w http.ResponseWriter, r *http.Request, media string, status int, t reflect.Type) *recordWriter {
+	return &recordWriter{
+		w:      w,
+		r:      r,
+		rc:     http.NewResponseController(w),
+		media:  media,
+		status: status,
+		t:      t,
+	}
+}
+
+// start writes the headers of the response, and in CSV the column row taken
+// from sample, the first record, if any.
+func (rw *recordWriter) start(sample reflect.Value) error {
+	rw.started = true
+	_ = rw.rc.SetWriteDeadline(time.Now().Add(streamWriteTimeout))
+	switch rw.media {
+	case mediaCSV:
+		rw.w.Header().Set("Content-Type", mediaCSV+"; charset=utf-8")
+		rw.w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", path.Base(rw.r.URL.Path)+".csv"))
+		rw.w.WriteHeader(rw.status)
+		rw.cw = csv.NewWriter(rw.w)
+		rw.f = newFlattener(rw.t, sample)
+		return rw.cw.Write(rw.f.columns)
+	default:
+		rw.w.Header().Set("Content-Type", mediaNDJSON)
+		rw.w.WriteHeader(rw.status)
+		rw.enc = json.NewEncoder(rw.w)
+		return nil
+	}
+}
+
+// write redacts v and writes it as one NDJSON line or its CSV rows.
+func (rw *recordWriter) write(v reflect.Value) error {
+	v = reflect.ValueOf(redacted(rw.r.Context(), v.Interface()))
+	if !rw.started {
+		if err := rw.start(v); err != nil {
+			return err
+		}
+	}
+
+	if rw.media != mediaCSV {
+		if err := rw.enc.Encode(v.Interface()); err != nil {
+			return err
+		}
+		rw.written++
+		return rw.maybeFlush()
+	}
+	for _, row := range rw.f.rows(v) {
+		if err := rw.cw.Write(row); err != nil {
+			return err
+		}
+		rw.written++
+		if err := rw.maybeFlush(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// maybeFlush flushes the response every flushEvery rows or lines and pushes
+// the write deadline back.
+func (rw *recordWriter) maybeFlush() error {
+	if rw.written%flushEvery != 0 {
+		return nil
+	}
+	if rw.cw != nil {
+		rw.cw.Flush()
+		if err := rw.cw.Error(); err != nil {
+			return err
+		}
+	}
+	_ = rw.rc.Flush()
+	_ = rw.rc.SetWriteDeadline(time.Now().Add(streamWriteTimeout))
+	return nil
+}
+
+// close ends the response: it writes the headers of an empty one and what
+// is still buffered.
+func (rw *recordWriter) close() error {
+	if !rw.started {
+		if err := rw.start(reflect.Value{}); err != nil {
+			return err
+		}
+	}
+	if rw.cw != nil {
+		rw.cw.Flush()
+		return rw.cw.Error()
+	}
+	return nil
+}
+
+// flattener turns records of one type into CSV rows; see respond.
+type flattener struct {
+	columns []string
+	// mapKeys holds the keys of every map column, by column name prefix,
+	// taken from the first record.
+	mapKeys map[string][]string
+}
+
+func newFlattener(t reflect.Type, sample reflect.Value) *flattener {
+	f := &flattener{mapKeys: map[string][]string{}}
+	expanded := false
+	f.columns = f.appendColumns(nil, t, "", sample, &expanded)
+	return f
+}
+
+// appendColumns appends the columns of a value of type t named name.
+// expanded tells whether the nested list expanded into rows was seen yet.
+func (f *flattener) appendColumns(cols []string, t reflect.Type, name string, sample reflect.Value, expanded *bool) []string {
+	switch t.Kind() {
+	case reflect.Pointer:
+		if sample.IsValid() && !sample.IsNil() {
+			sample = sample.Elem()
+		} else {
+			sample = reflect.Value{}
+		}
+		return f.appendColumns(cols, t.Elem(), name, sample, expanded)
+
+	case reflect.Struct:
+		for i := 0; i < t.NumField(); i++ {
+			field, ok := jsonField(t.Field(i), name)
+			if !ok {
+				continue
+			}
+			var fieldSample reflect.Value
+			if sample.IsValid() {
+				fieldSample = sample.Field(i)
+			}
+			cols = f.appendColumns(cols, t.Field(i).Type, field, fieldSample, expanded)
+		}
+		return cols
+
+	case reflect.Map:
+		keys, ok := f.mapKeys[name]
+		if !ok {
+			if sample.IsValid() {
+				for _, k := range sample.MapKeys() {
+					keys = append(keys, fmt.Sprint(k.Interface()))
+				}
+				sort.Strings(keys)
+			}
+			f.mapKeys[name] = keys
+		}
+		for _, k := range keys {
+			cols = append(cols, joinName(name, k))
+		}
+		return cols
+
+	case reflect.Slice:
+		if elem := structElem(t); elem != nil {
+			if *expanded {
+				return cols
+			}
+			*expanded = true
+			var elemSample reflect.Value
+			if sample.IsValid() && sample.Len() > 0 {
+				elemSample = sample.Index(0)
+			}
+			return f.appendColumns(cols, t.Elem(), name, elemSample, expanded)
+		}
+	}
+	return append(cols, name)
+}
+
+// rows returns the CSV rows of one record.
+func (f *flattener) rows(v reflect.Value) [][]string {
+	expanded := false
+	return f.appendRows([][]string{nil}, v, v.Type(), "", &expanded)
+}
+
+// appendRows appends the cells of v, a value of type t named name, to every
+// row. An invalid v stands for a nil pointer or an empty list and yields
+// empty cells.
+func (f *flattener) appendRows(rows [][]string, v reflect.Value, t reflect.Type, name string, expanded *bool) [][]string {
+	switch t.Kind() {
+	case reflect.Pointer:
+		if v.IsValid() && !v.IsNil() {
+			v = v.Elem()
+		} else {
+			v = reflect.Value{}
+		}
+		return f.appendRows(rows, v, t.Elem(), name, expanded)
+
+	case reflect.Struct:
+		for i := 0; i < t.NumField(); i++ {
+			field, ok := jsonField(t.Field(i), name)
+			if !ok {
+				continue
+			}
+			var fv reflect.Value
+			if v.IsValid() {
+				fv = v.Field(i)
+			}
+			rows = f.appendRows(rows, fv, t.Field(i).Type, field, expanded)
+		}
+		return rows
+
+	case reflect.Map:
+		for _, k := range f.mapKeys[name] {
+			var cell string
+			if v.IsValid() {
+				cell = formatCell(v.MapIndex(reflect.ValueOf(k).Convert(t.Key())))
+			}
+			rows = appendCell(rows, cell)
+		}
+		return rows
+
+	case reflect.Slice:
+		if elem := structElem(t); elem != nil {
+			if *expanded {
+				return rows
+			}
+			*expanded = true
+			if !v.IsValid() || v.Len() == 0 {
+				return f.appendRows(rows, reflect.Value{}, t.Elem(), name, expanded)
+			}
+			var out [][]string
+			for i := 0; i < v.Len(); i++ {
+				for _, sub := range f.appendRows([][]string{nil}, v.Index(i), t.Elem(), name, expanded) {
+					for _, row := range rows {
+						out = append(out, append(append([]string(nil), row...), sub...))
+					}
+				}
+			}
+			return out
+		}
+	}
+	return appendCell(rows, formatCell(v))
+}
+
+func appendCell(rows [][]string, cell string) [][]string {
+	for i := range rows {
+		rows[i] = append(rows[i], cell)
+	}
+	return rows
+}
+
+// jsonField returns the column name of a struct field under prefix, or false
+// for fields JSON leaves out. Embedded structs without a name are inlined
+// like encoding/json does.
+func jsonField(sf reflect.StructField, prefix string) (string, bool) {
+	if !sf.IsExported() {
+		return "", false
+	}
+	tag, _, _ := strings.Cut(sf.Tag.Get("json"), ",")
+	if tag == "-" {
+		return "", false
+	}
+	if tag == "" {
+		if sf.Anonymous && sf.Type.Kind() == reflect.Struct {
+			return prefix, true
+		}
+		tag = sf.Name
+	}
+	return joinName(prefix, tag), true
+}
+
+func joinName(prefix, name string) string {
+	if prefix == "" {
+		return name
+	}
+	return prefix + "." + name
+}
+
+// structElem returns the struct element type of a slice type, or nil.
+func structElem(t reflect.Type) reflect.Type {
+	elem := t.Elem()
+	if elem.Kind() == reflect.Pointer {
+		elem = elem.Elem()
+	}
+	if elem.Kind() != reflect.Struct {
+		return nil
+	}
+	return elem
+}
+
+// formatCell renders a value as a CSV cell; nil and invalid values are empty.
+func formatCell(v reflect.Value) string {
+	if !v.IsValid() {
+		return ""
+	}
+	switch v.Kind() {
+	case reflect.Interface, reflect.Pointer:
+		if v.IsNil() {
+			return ""
+		}
+		return formatCell(v.Elem())
+	case reflect.String:
+		return v.String()
+	case reflect.Bool:
+		return strconv.FormatBool(v.Bool())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(v.Uint(), 10)
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(v.Float(), 'g', -1, 64)
+	case reflect.Slice, reflect.Array:
+		cells := make([]string, v.Len())
+		for i := range cells {
+			cells[i] = formatCell(v.Index(i))
+		}
+		return strings.Join(cells, listSeparator)
+	}
+	return fmt.Sprint(v.Interface())
+}
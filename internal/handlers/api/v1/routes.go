@@ -1,42 +1,58 @@
 package v1
 
 import (
+	"github.com/NoroSaroyan/log-parser/internal/services/analytics"
+	"github.com/NoroSaroyan/log-parser/internal/services/auth"
+	"github.com/NoroSaroyan/log-parser/internal/services/consistency"
+	"github.com/NoroSaroyan/log-parser/internal/services/device"
 	"github.com/NoroSaroyan/log-parser/internal/services/downloadinfo"
+	"github.com/NoroSaroyan/log-parser/internal/services/ingestion"
 	"github.com/NoroSaroyan/log-parser/internal/services/logistic"
 	"github.com/NoroSaroyan/log-parser/internal/services/teststation"
 	"github.com/NoroSaroyan/log-parser/internal/services/teststep"
 	"github.com/go-chi/chi/v5"
 )
 
+// Services are the services the v1 API is built on.
+type Services struct {
+	DownloadInfo downloadinfo.DownloadInfoService
+	Logistic     logistic.LogisticDataService
+	TestStation  teststation.TestStationService
+	TestStep     teststep.TestStepService
+	Consistency  consistency.ConsistencyService
+	Device       device.DeviceService
+	Analytics    analytics.AnalyticsService
+	Ingestion    ingestion.IngestionService
+	Auth         auth.AuthService
+}
+
 // RegisterAPIV1 registers all v1 API routes.
 //
 // @Summary      Register API v1 routes
-// @Description  Registers endpoints for download info and test stations (Final, PCBA)
+// @Description  Registers endpoints for download info, test stations (Final, PCBA), devices, logistic conflicts, analytics, uploads and log levels
 // @Tags         api,v1
-func RegisterAPIV1(r chi.Router,
-	downloadSvc downloadinfo.DownloadInfoService,
-	logisticSvc logistic.LogisticDataService,
-	testStationSvc teststation.TestStationService,
-	testStepSvc teststep.TestStepService,
-) {
+func RegisterAPIV1(r chi.Router, svc Services) {
+	r.Use(Authenticate(svc.Auth))
+
 	// GET /api/v1/download
 	// @Summary      Get download info by PCBA number
 	// @Tags         downloadinfo
-	// @Produce      json
+	// @Produce      json,text/csv,application/x-ndjson
 	// @Param        pcbanumber query string true "PCBA Number"
 	// @Success      200 {object} downloadinfo.DTO
 	// @Failure      400 {object} map[string]string
 	// @Failure      500 {object} map[string]string
 	// @Router       /download [get]
 	r.With(JSON...).
-		Get("/download", NewDownloadHandler(downloadSvc).Get)
+		Get("/download", NewDownloadHandler(svc.DownloadInfo).Get)
 
-	finalH := NewTestStationHandler("Final", logisticSvc, testStationSvc, testStepSvc)
+	finalH := NewTestStationHandler("Final", svc.Logistic, svc.TestStation, svc.TestStep)
 	// GET /api/v1/final
 	// @Summary      Get Final TestStation records by PCBA number
 	// @Tags         teststation
-	// @Produce      json
+	// @Produce      json,text/csv,application/x-ndjson
 	// @Param        pcbanumber query string true "PCBA Number"
+	// @Param        verdict_mismatch query bool false "Filter steps by recomputed verdict mismatch"
 	// @Success      200 {array} struct{ /* see TestStationHandler.GetFinal */ }
 	// @Failure      400 {object} map[string]string
 	// @Failure      404 {object} map[string]string
@@ -45,12 +61,13 @@ func RegisterAPIV1(r chi.Router,
 	r.With(JSON...).
 		Get("/final", finalH.GetFinal)
 
-	pcbaH := NewTestStationHandler("PCBA", logisticSvc, testStationSvc, testStepSvc)
+	pcbaH := NewTestStationHandler("PCBA", svc.Logistic, svc.TestStation, svc.TestStep)
 	// GET /api/v1/pcba
 	// @Summary      Get PCBA TestStation records by PCBA number
 	// @Tags         teststation
-	// @Produce      json
+	// @Produce      json,text/csv,application/x-ndjson
 	// @Param        pcbanumber query string true "PCBA Number"
+	// @Param        verdict_mismatch query bool false "Filter steps by recomputed verdict mismatch"
 	// @Success      200 {array} struct{ /* see TestStationHandler.GetPCBA */ }
 	// @Failure      400 {object} map[string]string
 	// @Failure      404 {object} map[string]string
@@ -60,12 +77,206 @@ func RegisterAPIV1(r chi.Router,
 		Get("/pcba", pcbaH.GetPCBA)
 
 	// GET /api/v1/pcbanumbers
+	// Deprecated: unpaginated; use /devices.
 	// @Summary      Get all PCBA numbers
 	// @Tags         teststation
-	// @Produce      json
+	// @Produce      json,text/csv,application/x-ndjson
 	// @Success      200 {object} struct{PCBANumbers []string `json:"PCBANumbers"`}
 	// @Failure      500 {object} map[string]string
 	// @Router       /pcbanumbers [get]
+	r.With(Stream...).
+		Get("/pcbanumbers", NewTestStationHandler("", svc.Logistic, svc.TestStation, svc.TestStep).GetPCBANumbers)
+
+	deviceH := NewDeviceHandler(svc.Device)
+	// GET /api/v1/devices
+	// @Summary      List devices with filters, sorting and cursor pagination
+	// @Tags         device
+	// @Produce      json,text/csv,application/x-ndjson
+	// @Param        station_type query string false "PCBA or Final"
+	// @Param        part_number query string false "Part number"
+	// @Param        product_line query string false "Product line"
+	// @Param        passed query bool false "IsAllPassed of the latest matching station record"
+	// @Param        test_tool_version query string false "Test tool version"
+	// @Param        tested_from query string false "Earliest TestFinishedTime"
+	// @Param        tested_to query string false "Latest TestFinishedTime"
+	// @Param        sort query string false "pcba_number, first_tested or last_tested"
+	// @Param        order query string false "asc or desc"
+	// @Param        limit query int false "Page size (default 50, max 500)"
+	// @Param        cursor query string false "NextCursor of the previous page"
+	// @Success      200 {object} dto.DevicePageDTO
+	// @Failure      400 {object} map[string]string
+	// @Failure      500 {object} map[string]string
+	// @Router       /devices [get]
+	r.With(JSON...).
+		Get("/devices", deviceH.List)
+
+	// GET /api/v1/devices/search
+	// @Summary      Search devices by IMEI, IMSI, ICCID, BLE MAC, BLE SN or ProductSN
+	// @Tags         device
+	// @Produce      json,text/csv,application/x-ndjson
+	// @Param        imei query string false "IMEI"
+	// @Param        imsi query string false "IMSI"
+	// @Param        iccid query string false "TCU ICCID"
+	// @Param        ble_mac query string false "BLE MAC"
+	// @Param        ble_sn query string false "BLE serial number"
+	// @Param        product_sn query string false "Product serial number"
+	// @Success      200 {object} dto.DeviceSearchDTO
+	// @Failure      400 {object} map[string]string
+	// @Failure      500 {object} map[string]string
+	// @Router       /devices/search [get]
+	r.With(JSON...).
+		Get("/devices/search", deviceH.Search)
+
+	// GET /api/v1/devices/{pcba}
+	// @Summary      Get the timeline and lifecycle status of a device
+	// @Tags         device
+	// @Produce      json,text/csv,application/x-ndjson
+	// @Param        pcba path string true "PCBA Number"
+	// @Success      200 {object} dto.DeviceTimelineDTO
+	// @Failure      404 {object} map[string]string
+	// @Failure      500 {object} map[string]string
+	// @Router       /devices/{pcba} [get]
+	r.With(JSON...).
+		Get("/devices/{pcba}", deviceH.Get)
+
+	// GET /api/v1/logistic/conflicts
+	// @Summary      Get LogisticData conflicts between PCBA and Final
+	// @Tags         logistic
+	// @Produce      json,text/csv,application/x-ndjson
+	// @Param        classification query string false "expected or suspicious"
+	// @Param        pcbanumber query string false "PCBA Number"
+	// @Success      200 {array} dto.DeviceLogisticConflictsDTO
+	// @Failure      400 {object} map[string]string
+	// @Failure      500 {object} map[string]string
+	// @Router       /logistic/conflicts [get]
+	r.With(Stream...).
+		Get("/logistic/conflicts", NewLogisticConflictHandler(svc.Consistency).Get)
+
+	analyticsH := NewAnalyticsHandler(svc.Analytics)
+	// GET /api/v1/analytics/yield
+	// @Summary      Get first-pass and final yield per time bucket and group
+	// @Tags         analytics
+	// @Produce      json,text/csv,application/x-ndjson
+	// @Param        group_by query string false "station_type, part_number, product_line, test_tool_version"
+	// @Param        bucket query string false "hour, day or week"
+	// @Param        station_type query string false "PCBA or Final"
+	// @Param        part_number query string false "Part number"
+	// @Param        product_line query string false "Product line"
+	// @Param        from query string false "Earliest first attempt"
+	// @Param        to query string false "Latest first attempt"
+	// @Success      200 {object} dto.YieldReportDTO
+	// @Failure      400 {object} map[string]string
+	// @Failure      500 {object} map[string]string
+	// @Router       /analytics/yield [get]
+	r.With(JSON...).
+		Get("/analytics/yield", analyticsH.Yield)
+
+	// GET /api/v1/analytics/steps/{name}
+	// @Summary      Get measurement statistics, histogram and Cp/Cpk of a test step
+	// @Tags         analytics
+	// @Produce      json,text/csv,application/x-ndjson
+	// @Param        name path string true "TestStepName"
+	// @Param        station_type query string false "PCBA or Final"
+	// @Param        part_number query string false "Part number"
+	// @Param        test_tool_version query string false "Test tool version"
+	// @Param        from query string false "Earliest TestFinishedTime"
+	// @Param        to query string false "Latest TestFinishedTime"
+	// @Param        bins query int false "Histogram bins (default 20, max 200)"
+	// @Success      200 {object} dto.StepStatsDTO
+	// @Failure      400 {object} map[string]string
+	// @Failure      500 {object} map[string]string
+	// @Router       /analytics/steps/{name} [get]
+	r.With(JSON...).
+		Get("/analytics/steps/{name}", analyticsH.StepStats)
+
+	// GET /api/v1/analytics/errors
+	// @Summary      Get the error code Pareto by occurrences or affected devices
+	// @Tags         analytics
+	// @Produce      json,text/csv,application/x-ndjson
+	// @Param        station_type query string false "PCBA or Final"
+	// @Param        part_number query string false "Part number"
+	// @Param        from query string false "Earliest TestFinishedTime"
+	// @Param        to query string false "Latest TestFinishedTime"
+	// @Param        sort query string false "occurrences or devices"
+	// @Param        limit query int false "Number of top codes"
+	// @Success      200 {object} dto.ErrorParetoDTO
+	// @Failure      400 {object} map[string]string
+	// @Failure      500 {object} map[string]string
+	// @Router       /analytics/errors [get]
+	r.With(JSON...).
+		Get("/analytics/errors", analyticsH.ErrorPareto)
+
+	// GET /api/v1/analytics/errors/{code}
+	// @Summary      Get the devices behind an error code
+	// @Tags         analytics
+	// @Produce      json,text/csv,application/x-ndjson
+	// @Param        code path string true "Error code"
+	// @Param        station_type query string false "PCBA or Final"
+	// @Param        part_number query string false "Part number"
+	// @Param        from query string false "Earliest TestFinishedTime"
+	// @Param        to query string false "Latest TestFinishedTime"
+	// @Success      200 {object} dto.ErrorCodeDevicesDTO
+	// @Failure      400 {object} map[string]string
+	// @Failure      500 {object} map[string]string
+	// @Router       /analytics/errors/{code} [get]
+	r.With(JSON...).
+		Get("/analytics/errors/{code}", analyticsH.ErrorCodeDevices)
+
+	ingestionH := NewIngestionHandler(svc.Ingestion)
+	// POST /api/v1/ingestions
+	// @Summary      Upload a .log or .gz file for background ingestion
+	// @Tags         ingestion
+	// @Accept       multipart/form-data
+	// @Produce      json
+	// @Param        file formData file true "Log file (.log or .gz)"
+	// @Success      202 {object} dto.IngestionJobDTO
+	// @Failure      400 {object} map[string]string
+	// @Failure      413 {object} map[string]string
+	// @Failure      503 {object} map[string]string
+	// @Failure      401 {object} map[string]string
+	// @Failure      403 {object} map[string]string
+	// @Router       /ingestions [post]
+	r.With(Upload...).
+		With(RequireRole(auth.RoleEngineer)).
+		Post("/ingestions", ingestionH.Create)
+
+	// GET /api/v1/ingestions/{id}
+	// @Summary      Get the progress, statistics and errors of an ingestion job
+	// @Tags         ingestion
+	// @Produce      json,text/csv,application/x-ndjson
+	// @Param        id path string true "Job ID"
+	// @Success      200 {object} dto.IngestionJobDTO
+	// @Failure      404 {object} map[string]string
+	// @Failure      500 {object} map[string]string
+	// @Router       /ingestions/{id} [get]
+	r.With(JSON...).
+		Get("/ingestions/{id}", ingestionH.Get)
+
+	logLevelH := NewLogLevelHandler()
+	// GET /api/v1/admin/log-level
+	// @Summary      Get the log level and the package overrides
+	// @Tags         admin
+	// @Produce      json
+	// @Success      200 {object} dto.LogLevelsDTO
+	// @Failure      401 {object} map[string]string
+	// @Failure      403 {object} map[string]string
+	// @Router       /admin/log-level [get]
+	r.With(JSON...).
+		With(RequireRole(auth.RoleAdmin)).
+		Get("/admin/log-level", logLevelH.Get)
+
+	// PUT /api/v1/admin/log-level
+	// @Summary      Change the log level and the package overrides until the next restart
+	// @Tags         admin
+	// @Accept       json
+	// @Produce      json
+	// @Param        levels body dto.LogLevelsDTO true "Level and package overrides"
+	// @Success      200 {object} dto.LogLevelsDTO
+	// @Failure      400 {object} map[string]string
+	// @Failure      401 {object} map[string]string
+	// @Failure      403 {object} map[string]string
+	// @Router       /admin/log-level [put]
 	r.With(JSON...).
-		Get("/pcbanumbers", pcbaH.GetPCBANumbers)
+		With(RequireRole(auth.RoleAdmin)).
+		Put("/admin/log-level", logLevelH.Put)
 }
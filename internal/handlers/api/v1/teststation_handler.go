@@ -2,10 +2,12 @@ package v1
 
 import (
 	"github.com/NoroSaroyan/log-parser/internal/domain/models/dto"
+	"github.com/NoroSaroyan/log-parser/internal/infrastructure/logger"
 	"github.com/NoroSaroyan/log-parser/internal/services/logistic"
 	"github.com/NoroSaroyan/log-parser/internal/services/teststation"
 	"github.com/NoroSaroyan/log-parser/internal/services/teststep"
 	"net/http"
+	"strconv"
 )
 
 // TestStationHandler provides HTTP handlers for managing TestStation records.
@@ -36,8 +38,15 @@ func NewTestStationHandler(stationType string,
 //
 // It fetches TestStation records, associated logistic data, and related test steps
 // for the specified "pcbanumber" query parameter. The response is a JSON array of
-// TestStationWithSteps objects. Returns HTTP 400 if the parameter is missing,
-// 404 if no matching records are found, and 500 for server errors.
+// TestStationWithSteps objects. The optional "verdict_mismatch" parameter keeps
+// only the test steps whose recomputed verdict does (true) or does not (false)
+// disagree with the logged result, and drops records left without steps.
+// Returns HTTP 400 if a parameter is missing or invalid, 404 if no matching
+// records are found, and 500 for server errors.
+//
+// With "Accept: text/csv" every test step is a row carrying the columns of its
+// station record and LogisticData; "application/x-ndjson" writes one record
+// per line (see respond).
 //
 // Swagger annotations:
 //
@@ -45,10 +54,11 @@ func NewTestStationHandler(stationType string,
 // @Description  Returns TestStation records and their related logistic and test step data for a specified PCBANumber
 // @Tags         teststation
 // @Accept       json
-// @Produce      json
+// @Produce      json,text/csv,application/x-ndjson
 // @Param        pcbanumber  query     string  true  "PCBA Number"
+// @Param        verdict_mismatch  query  bool  false  "Only steps whose recomputed verdict disagrees (true) or agrees (false) with TestStepResult"
 // @Success      200  {array}  dto.TestStationWithSteps
-// @Failure      400  {object}  map[string]string  "pcbanumber is required"
+// @Failure      400  {object}  map[string]string  "pcbanumber is required / invalid verdict_mismatch"
 // @Failure      404  {object}  map[string]string  "no matching records"
 // @Failure      500  {object}  map[string]string  "internal server error"
 func (h *TestStationHandler) Get(w http.ResponseWriter, r *http.Request) {
@@ -60,6 +70,16 @@ func (h *TestStationHandler) Get(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	var mismatchFilter *bool
+	if v := r.URL.Query().Get("verdict_mismatch"); v != "" {
+		mismatch, err := strconv.ParseBool(v)
+		if err != nil {
+			respondError(w, http.StatusBadRequest, "verdict_mismatch must be true or false")
+			return
+		}
+		mismatchFilter = &mismatch
+	}
+
 	records, err := h.testStationSvc.GetByPCBANumber(ctx, pcba)
 	if err != nil {
 		respondError(w, http.StatusInternalServerError, "failed to fetch records")
@@ -72,7 +92,7 @@ func (h *TestStationHandler) Get(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	var out []any
+	var out []dto.TestStationWithSteps
 	for i, rec := range records {
 		if rec.TestStation != h.stationType {
 			continue
@@ -94,13 +114,16 @@ func (h *TestStationHandler) Get(w http.ResponseWriter, r *http.Request) {
 			respondError(w, http.StatusInternalServerError, "failed to fetch steps")
 			return
 		}
+		if mismatchFilter != nil {
+			steps = filterByVerdictMismatch(steps, *mismatchFilter)
+			if len(steps) == 0 {
+				continue
+			}
+		}
 
-		out = append(out, struct {
-			dto.TestStationRecordDTO
-			TestSteps []dto.TestStepDTO `json:"TestSteps"`
-		}{
-			rec,
-			steps,
+		out = append(out, dto.TestStationWithSteps{
+			TestStationRecordDTO: rec,
+			TestSteps:            steps,
 		})
 	}
 
@@ -109,7 +132,18 @@ func (h *TestStationHandler) Get(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	respondJSON(w, http.StatusOK, out)
+	respond(w, r, http.StatusOK, out, out)
+}
+
+// filterByVerdictMismatch returns the steps whose VerdictMismatch equals mismatch.
+func filterByVerdictMismatch(steps []dto.TestStepDTO, mismatch bool) []dto.TestStepDTO {
+	var out []dto.TestStepDTO
+	for _, s := range steps {
+		if s.VerdictMismatch == mismatch {
+			out = append(out, s)
+		}
+	}
+	return out
 }
 
 // GetFinal is a shortcut handler for fetching "Final" TestStation records.
@@ -131,7 +165,8 @@ func (h *TestStationHandler) GetPCBA(w http.ResponseWriter, r *http.Request) {
 // GetPCBANumbers handles HTTP GET requests to retrieve all PCBA numbers
 // for the configured TestStation type.
 //
-// The response is a JSON object with a "PCBANumbers" array.
+// The response is a JSON object with a "PCBANumbers" array. CSV and NDJSON
+// are streamed one PCBA number per row as the repository reads them.
 //
 // Swagger annotations:
 //
@@ -139,22 +174,36 @@ func (h *TestStationHandler) GetPCBA(w http.ResponseWriter, r *http.Request) {
 // @Description  Returns all PCBANumbers available for the configured TestStation type
 // @Tags         teststation
 // @Accept       json
-// @Produce      json
+// @Produce      json,text/csv,application/x-ndjson
 // @Success      200  {object}  dto.PCBANumbersResponse
 // @Failure      500  {object}  map[string]string  "failed to fetch PCBA numbers"
 // @Router       /pcbanumbers [get]
 func (h *TestStationHandler) GetPCBANumbers(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
+	if media := negotiate(r); media != mediaJSON {
+		started, err := streamRecords(w, r, media, func(yield func(pcbaNumberRow) error) error {
+			return h.testStationSvc.ForEachPCBANumber(ctx, h.stationType, func(pcba string) error {
+				return yield(pcbaNumberRow{PCBANumber: pcba})
+			})
+		})
+		if err != nil {
+			logger.ErrorContext(ctx, "Failed to stream PCBA numbers", err, logger.WithField("station_type", h.stationType))
+			abortStream(w, started, http.StatusInternalServerError, "failed to fetch PCBA numbers")
+		}
+		return
+	}
+
 	pcbaNumbers, err := h.testStationSvc.GetAllPCBANumbers(ctx, h.stationType)
 	if err != nil {
 		respondError(w, http.StatusInternalServerError, "failed to fetch PCBA numbers")
 		return
 	}
 
-	respondJSON(w, http.StatusOK, struct {
-		PCBANumbers []string `json:"PCBANumbers"`
-	}{
-		PCBANumbers: pcbaNumbers,
-	})
+	respond(w, r, http.StatusOK, dto.PCBANumbersResponse{PCBANumbers: pcbaNumbers}, nil)
+}
+
+// pcbaNumberRow is a PCBA number as a CSV or NDJSON record.
+type pcbaNumberRow struct {
+	PCBANumber string `json:"PCBANumber"`
 }
@@ -0,0 +1,178 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/NoroSaroyan/log-parser/internal/app"
+	"github.com/NoroSaroyan/log-parser/internal/infrastructure/database/memory"
+	"github.com/NoroSaroyan/log-parser/internal/infrastructure/logger"
+	"github.com/NoroSaroyan/log-parser/internal/services/dispatcher"
+	"github.com/NoroSaroyan/log-parser/internal/services/pipeline"
+	"github.com/NoroSaroyan/log-parser/internal/services/processor"
+)
+
+// FileAnalysis is the dry-run report for a single log file.
+type FileAnalysis struct {
+	File           string                      `json:"file"`
+	Error          string                      `json:"error,omitempty"`
+	TotalBlocks    int                         `json:"total_blocks"`
+	FilteredBlocks int                         `json:"filtered_blocks"`
+	Stats          pipeline.ParsingStatistics  `json:"parsing_statistics"`
+	Grouping       processor.GroupingSummary   `json:"grouping"`
+	Validation     processor.ValidationSummary `json:"validation"`
+	Dispatch       dispatcher.DispatchReport   `json:"dispatch"`
+}
+
+// runAnalyze runs extraction, parsing, grouping and dispatch pairing for every
+// given file without a database. Dispatch runs against an in-memory store that
+// is shared by all files, so the predicted outcomes match a real import of the
+// same files into an empty database.
+func runAnalyze(ctx context.Context, args []string, format string, out io.Writer) error {
+	if len(args) == 0 {
+		return fmt.Errorf("please specify at least one file or directory to analyze")
+	}
+	if format == "" {
+		format = "text"
+	}
+	if format != "text" && format != "json" {
+		return fmt.Errorf("unsupported analyze format: %s (expected text or json)", format)
+	}
+
+	appInstance := app.InitializeInMemoryApp(memory.NewStore())
+	dispatcherService := newDispatcher(appInstance)
+
+	var reports []FileAnalysis
+	walkFiles(args, func(path string) error {
+		report := FileAnalysis{File: path}
+		defer func() { reports = append(reports, report) }()
+
+		result, err := pipeline.ParseFile(path)
+		if err != nil {
+			report.Error = err.Error()
+			return err
+		}
+		report.TotalBlocks = result.TotalBlocks
+		report.FilteredBlocks = result.FilteredBlocks
+		report.Stats = result.Stats
+		report.Grouping = processor.SummarizeGroups(result.Groups)
+		report.Validation = result.Validation.Summary
+
+		report.Dispatch, err = dispatcherService.DispatchGroups(ctx, result.Groups)
+		sort.Slice(report.Dispatch.Outcomes, func(i, j int) bool {
+			return report.Dispatch.Outcomes[i].PCBA < report.Dispatch.Outcomes[j].PCBA
+		})
+		if err != nil {
+			report.Error = err.Error()
+		}
+		return nil
+	})
+
+	logger.Info("Analysis finished", logger.WithField("files", len(reports)))
+
+	if format == "json" {
+		enc := json.NewEncoder(out)
+		enc.SetIndent("", "  ")
+		return enc.Encode(reports)
+	}
+	return writeAnalysisText(out, reports)
+}
+
+// writeAnalysisText renders the analysis reports as human-readable text.
+func writeAnalysisText(out io.Writer, reports []FileAnalysis) error {
+	tw := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	for _, r := range reports {
+		fmt.Fprintf(tw, "======== %s ========\n", r.File)
+		if r.Error != "" {
+			fmt.Fprintf(tw, "error:\t%s\n", r.Error)
+		}
+		if r.TotalBlocks == 0 && r.Error != "" {
+			fmt.Fprintln(tw)
+			continue
+		}
+
+		fmt.Fprintln(tw, "Parsing statistics")
+		fmt.Fprintf(tw, "  JSON blocks (total / relevant):\t%d / %d\n", r.TotalBlocks, r.FilteredBlocks)
+		fmt.Fprintf(tw, "  Download:\t%d\n", r.Stats.DownloadInfo)
+		fmt.Fprintf(tw, "  PCBA stations:\t%d\n", r.Stats.PCBAStations)
+		fmt.Fprintf(tw, "  Final stations:\t%d\n", r.Stats.FinalStations)
+		fmt.Fprintf(tw, "  Test step arrays:\t%d\n", r.Stats.TestStepArrays)
+		fmt.Fprintf(tw, "  Test steps:\t%d\n", r.Stats.TotalTestSteps)
+
+		fmt.Fprintln(tw, "Grouping")
+		fmt.Fprintf(tw, "  Groups:\t%d\n", r.Grouping.GroupsTotal)
+		fmt.Fprintf(tw, "  With download / station / steps:\t%d / %d / %d\n",
+			r.Grouping.GroupsWithDownload, r.Grouping.GroupsWithStation, r.Grouping.GroupsWithSteps)
+		fmt.Fprintf(tw, "  Stations by type:\t%s\n", formatCounts(r.Grouping.StationsByType))
+		fmt.Fprintf(tw, "  Step arrays by type:\t%s\n", formatCounts(r.Grouping.StepArraysByType))
+		fmt.Fprintf(tw, "  Groups with more step arrays than stations:\t%d\n", r.Grouping.GroupsWithMismatch)
+
+		fmt.Fprintln(tw, "Validation")
+		fmt.Fprintf(tw, "  Groups rejected / with findings:\t%d / %d\n", r.Validation.GroupsRejected, r.Validation.GroupsWithFindings)
+		fmt.Fprintf(tw, "  Findings by severity:\t%s\n", formatSeverityCounts(r.Validation.FindingsBySeverity))
+		fmt.Fprintf(tw, "  Findings by rule:\t%s\n", formatCounts(r.Validation.FindingsByRule))
+		if len(r.Validation.RejectedPCBANumbers) > 0 {
+			fmt.Fprintf(tw, "  Rejected:\t%s\n", strings.Join(r.Validation.RejectedPCBANumbers, " "))
+		}
+
+		fmt.Fprintln(tw, "Predicted dispatch")
+		fmt.Fprintf(tw, "  ok / failed:\t%d / %d\n", r.Dispatch.GroupsOK, r.Dispatch.GroupsFailed)
+		fmt.Fprintf(tw, "  with excess steps / type mismatch:\t%d / %d\n", r.Dispatch.GroupsWithExcess, r.Dispatch.GroupsMismatchType)
+		fmt.Fprintln(tw, "  PCBA\tSTATUS\tDETAIL")
+		for _, o := range r.Dispatch.Outcomes {
+			fmt.Fprintf(tw, "  %s\t%s\t%s\n", o.PCBA, o.Status, outcomeDetail(o))
+		}
+		fmt.Fprintln(tw)
+	}
+	return tw.Flush()
+}
+
+// outcomeDetail describes the non-ok parts of a dispatch outcome.
+func outcomeDetail(o dispatcher.GroupOutcome) string {
+	var parts []string
+	if o.FailedStage != "" {
+		parts = append(parts, "stage="+o.FailedStage)
+	}
+	if o.UnmatchedStepArrays > 0 {
+		parts = append(parts, fmt.Sprintf("excess_step_arrays=%d", o.UnmatchedStepArrays))
+	}
+	if o.TypeMismatches > 0 {
+		parts = append(parts, fmt.Sprintf("type_mismatches=%d", o.TypeMismatches))
+	}
+	if o.Error != "" {
+		parts = append(parts, "error="+o.Error)
+	}
+	return strings.Join(parts, " ")
+}
+
+// formatSeverityCounts renders finding counts by severity like formatCounts.
+func formatSeverityCounts(counts map[processor.Severity]int) string {
+	plain := make(map[string]int, len(counts))
+	for k, v := range counts {
+		plain[string(k)] = v
+	}
+	return formatCounts(plain)
+}
+
+// formatCounts renders a map of counters as "a=1 b=2" with sorted keys.
+func formatCounts(counts map[string]int) string {
+	keys := make([]string, 0, len(counts))
+	for k := range counts {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	parts := make([]string, 0, len(keys))
+	for _, k := range keys {
+		parts = append(parts, fmt.Sprintf("%s=%d", k, counts[k]))
+	}
+	if len(parts) == 0 {
+		return "-"
+	}
+	return strings.Join(parts, " ")
+}
@@ -0,0 +1,91 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"text/tabwriter"
+	"time"
+
+	"github.com/NoroSaroyan/log-parser/internal/app"
+	"github.com/NoroSaroyan/log-parser/internal/config"
+	"github.com/NoroSaroyan/log-parser/internal/infrastructure/logger"
+	"github.com/NoroSaroyan/log-parser/internal/services/auth"
+)
+
+// runAPIKey manages the API keys of the REST API:
+//
+//	apikey create NAME ROLE   prints the new key; it is not shown again
+//	apikey list               lists keys without their secret
+//	apikey revoke NAME        rejects the key from now on
+func runAPIKey(ctx context.Context, cfg *config.Config, args []string, format string, out io.Writer) error {
+	if len(args) == 0 {
+		return fmt.Errorf("apikey mode requires a command: create NAME ROLE, list or revoke NAME")
+	}
+	if err := checkReportFormat(format); err != nil {
+		return err
+	}
+
+	appInstance, err := app.InitializeAppWithConfig(cfg)
+	if err != nil {
+		return fmt.Errorf("failed to initialize app: %w", err)
+	}
+	defer func() {
+		if err := appInstance.CloseDB(); err != nil {
+			logger.Error("Failed to close DB connection", logger.WithField("error", err))
+		}
+	}()
+	svc := appInstance.AuthService
+
+	switch cmd := args[0]; {
+	case cmd == "create" && len(args) == 3:
+		role, err := auth.ParseRole(args[2])
+		if err != nil {
+			return err
+		}
+		plain, key, err := svc.CreateAPIKey(ctx, args[1], role)
+		if err != nil {
+			return err
+		}
+		logger.Info("Created API key", logger.WithFields(map[string]interface{}{
+			"name": key.Name,
+			"role": key.Role,
+		}))
+		if format == "json" {
+			return writeJSON(out, map[string]string{"Name": key.Name, "Role": key.Role, "Key": plain})
+		}
+		_, err = fmt.Fprintf(out, "%s\n", plain)
+		return err
+
+	case cmd == "list" && len(args) == 1:
+		keys, err := svc.ListAPIKeys(ctx)
+		if err != nil {
+			return err
+		}
+		if format == "json" {
+			return writeJSON(out, keys)
+		}
+		tw := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(tw, "NAME\tROLE\tPREFIX\tCREATED\tLAST USED\tREVOKED")
+		for _, k := range keys {
+			fmt.Fprintf(tw, "%s\t%s\t%s…\t%s\t%s\t%s\n",
+				k.Name, k.Role, k.Prefix, k.CreatedAt.Format(time.DateTime), formatOptionalTime(k.LastUsedAt), formatOptionalTime(k.RevokedAt))
+		}
+		return tw.Flush()
+
+	case cmd == "revoke" && len(args) == 2:
+		if err := svc.RevokeAPIKey(ctx, args[1]); err != nil {
+			return err
+		}
+		logger.Info("Revoked API key", logger.WithField("name", args[1]))
+		return nil
+	}
+	return fmt.Errorf("invalid apikey command %q: expected create NAME ROLE, list or revoke NAME", args)
+}
+
+func formatOptionalTime(t *time.Time) string {
+	if t == nil {
+		return "-"
+	}
+	return t.Format(time.DateTime)
+}
@@ -1,47 +1,166 @@
 package cli
 
 import (
-	"bufio"
-	"bytes"
-	"compress/gzip"
 	"context"
+	"errors"
 	"flag"
 	"fmt"
+	"io"
 	"io/fs"
 	"os"
 	"path/filepath"
-	"strings"
+	"slices"
 	"time"
 
 	"github.com/NoroSaroyan/log-parser/internal/app"
-	"github.com/NoroSaroyan/log-parser/internal/domain/models/dto"
+	"github.com/NoroSaroyan/log-parser/internal/config"
 	"github.com/NoroSaroyan/log-parser/internal/infrastructure/logger"
+	"github.com/NoroSaroyan/log-parser/internal/infrastructure/redaction"
+	"github.com/NoroSaroyan/log-parser/internal/services/consistency"
 	"github.com/NoroSaroyan/log-parser/internal/services/dispatcher"
-	"github.com/NoroSaroyan/log-parser/internal/services/parser"
-	"github.com/NoroSaroyan/log-parser/internal/services/processor"
+	"github.com/NoroSaroyan/log-parser/internal/services/ingestion"
+	"github.com/NoroSaroyan/log-parser/internal/services/pipeline"
+	"github.com/NoroSaroyan/log-parser/internal/services/validation"
+	"github.com/NoroSaroyan/log-parser/internal/services/watch"
 )
 
+// Run runs the CLI with the command line of the process.
 func Run() error {
-	mode := flag.String("mode", "process", "Mode to run: process (default), ...")
-	configPath := flag.String("config", "configs/config.yaml", "Path to config file")
-	logLevel := flag.String("log-level", "INFO", "Log level: DEBUG, INFO, WARN, ERROR")
-	flag.Parse()
+	return RunArgs(os.Args[1:], os.Stdout)
+}
+
+// RunArgs runs the CLI with the given arguments, not including the program
+// name. Reports are written to stdout.
+func RunArgs(arguments []string, stdout io.Writer) error {
+	flags := flag.NewFlagSet(os.Args[0], flag.ContinueOnError)
+	mode := flags.String("mode", "process", "Mode to run: process (default), analyze, export, watch, stats, compare, trace, apikey, keys, healthcheck")
+	configPath := flags.String("config", "configs/config.yaml", "Path to config file")
+	logLevel := flags.String("log-level", "", "Log level: DEBUG, INFO, WARN, ERROR (default: logger.level of the config)")
+	dryRun := flags.Bool("dry-run", false, "Parse and report without touching the database (same as -mode analyze)")
+	format := flags.String("format", "", "Output format: text (default) or json for analyze, stats, compare, trace and apikey; jsonl (default) or csv for export")
+	outDir := flags.String("out", "", "Output directory for export mode")
+	reveal := flags.String("reveal", "", "Comma-separated redacted fields export mode writes in clear, e.g. IMSI,TcuICCID (default: none)")
+	oldDir := flags.String("old", "", "Directory with the old logs for compare mode")
+	newDir := flags.String("new", "", "Directory with the new logs for compare mode")
+	pcba := flags.String("pcba", "", "PCBA number to follow in trace mode")
+	checkpointPath := flags.String("checkpoint", "watch.checkpoint.json", "Checkpoint file for watch mode")
+	pollInterval := flags.Duration("interval", watch.DefaultPollInterval, "Poll interval for watch mode")
+	settleTimeout := flags.Duration("settle", watch.DefaultSettleTimeout, "How long watch mode holds step arrays waiting for their station record")
+	metricsAddr := flags.String("metrics-addr", "", "Address watch mode serves Prometheus metrics on, e.g. :9100 (default: not served)")
+	batchSize := flags.Int("batch", 500, "Rows re-encrypted per transaction in keys rotate mode")
+	if err := flags.Parse(arguments); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return nil
+		}
+		return err
+	}
+
+	if *dryRun {
+		*mode = "analyze"
+	}
 
-	// Initialize structured logging
-	if err := logger.InitLogger(*logLevel); err != nil {
+	args := flags.Args()
+	cfg, err := loadConfig(*configPath, *mode, args)
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	// Initialize structured logging from the config; -log-level only
+	// replaces its level. Report modes print to stdout, so their log lines
+	// go to stderr to keep the report machine-readable.
+	flags.Visit(func(f *flag.Flag) {
+		if f.Name == "log-level" {
+			cfg.Logger.Level = *logLevel
+		}
+	})
+	switch *mode {
+	case "analyze", "stats", "compare", "trace", "apikey", "keys":
+		cfg.Logger.Output = logsOffStdout(cfg.Logger.Output)
+	}
+	if err := logger.Init(cfg.Logger); err != nil {
 		return fmt.Errorf("failed to initialize logger: %w", err)
 	}
+	defer logger.Close()
+
+	// Log lines and exported files are redacted as the config says.
+	policy, err := app.NewRedactionPolicy(cfg.Redaction)
+	if err != nil {
+		return err
+	}
+	redaction.SetPolicy(policy)
 
 	logger.Info("Starting log parser", logger.WithFields(map[string]interface{}{
 		"mode":      *mode,
-		"log_level": *logLevel,
+		"log_level": cfg.Logger.Level,
 	}))
 
-	if *mode != "process" {
+	ctx := context.Background()
+
+	switch *mode {
+	case "process":
+		return runProcess(ctx, cfg, args)
+	case "analyze":
+		return runAnalyze(ctx, args, *format, stdout)
+	case "export":
+		return runExport(ctx, args, *format, *outDir, *reveal)
+	case "stats":
+		return runStats(args, *format, stdout)
+	case "compare":
+		return runCompare(*oldDir, *newDir, *format, stdout)
+	case "trace":
+		return runTrace(*pcba, args, *format, stdout)
+	case "apikey":
+		return runAPIKey(ctx, cfg, args, *format, stdout)
+	case "keys":
+		return runKeys(ctx, cfg, args, *batchSize, stdout)
+	case "watch":
+		return runWatch(ctx, cfg, args, *checkpointPath, *pollInterval, *settleTimeout, *metricsAddr)
+	case "healthcheck":
+		return runHealthcheck(ctx, cfg)
+	default:
 		return fmt.Errorf("unsupported mode: %s", *mode)
 	}
+}
+
+// loadConfig loads the config file at path. Modes that do not use the
+// database fall back to the defaults, with the environment overrides, when
+// there is no such file: they only read the logger section.
+func loadConfig(path, mode string, args []string) (*config.Config, error) {
+	cfg, err := config.LoadConfig(path)
+	if errors.Is(err, fs.ErrNotExist) && !usesDatabase(mode, args) {
+		cfg = config.Default()
+		err = cfg.ApplyEnv(os.LookupEnv)
+	}
+	return cfg, err
+}
+
+// usesDatabase reports whether mode, run with args, connects to Postgres.
+func usesDatabase(mode string, args []string) bool {
+	switch mode {
+	case "process", "watch", "apikey":
+		return true
+	case "keys":
+		return len(args) > 0 && args[0] == "rotate"
+	}
+	return false
+}
 
-	args := flag.Args()
+// logsOffStdout returns sinks with stdout replaced by stderr.
+func logsOffStdout(sinks config.Sinks) config.Sinks {
+	var out config.Sinks
+	for _, sink := range sinks {
+		if sink == "stdout" {
+			sink = "stderr"
+		}
+		if !slices.Contains(out, sink) {
+			out = append(out, sink)
+		}
+	}
+	return out
+}
+
+// runProcess parses every given file or directory and inserts the result into Postgres.
+func runProcess(ctx context.Context, cfg *config.Config, args []string) error {
 	if len(args) == 0 {
 		logger.Error("No files or directories specified")
 		return fmt.Errorf("please specify at least one file or directory to process")
@@ -49,8 +168,7 @@ func Run() error {
 
 	logger.Info("Processing files", logger.WithField("files", args))
 
-	ctx := context.Background()
-	appInstance, err := app.InitializeApp(*configPath)
+	appInstance, err := app.InitializeAppWithConfig(cfg)
 	if err != nil {
 		logger.Error("Failed to initialize app", logger.WithField("error", err))
 		return fmt.Errorf("failed to initialize app: %w", err)
@@ -61,13 +179,29 @@ func Run() error {
 		}
 	}()
 
-	dispatcherService := dispatcher.NewDispatcherService(
+	dispatcherService := newDispatcher(appInstance)
+
+	walkFiles(args, func(path string) error {
+		return processSingleFile(ctx, path, dispatcherService, appInstance.ValidationService, appInstance.ConsistencyService)
+	})
+
+	return nil
+}
+
+// newDispatcher builds a DispatcherService on top of the services of appInstance.
+func newDispatcher(appInstance *app.App) dispatcher.DispatcherService {
+	return dispatcher.NewDispatcherService(
 		appInstance.DownloadInfoService,
 		appInstance.LogisticService,
 		appInstance.TestStationService,
 		appInstance.TestStepService,
 	)
+}
 
+// walkFiles calls fn for every supported file in args. Directories are walked
+// recursively. Errors are logged and never stop the walk, so one bad file
+// cannot prevent the others from being handled.
+func walkFiles(args []string, fn func(path string) error) {
 	for _, path := range args {
 		fi, err := os.Stat(path)
 		if err != nil {
@@ -86,8 +220,8 @@ func Run() error {
 				if err != nil {
 					return err
 				}
-				if !d.IsDir() && isSupportedFile(p) {
-					if err := processSingleFile(ctx, p, appInstance, dispatcherService); err != nil {
+				if !d.IsDir() && pipeline.IsSupportedFile(p) {
+					if err := fn(p); err != nil {
 						logger.Error("Error processing file",
 							err,
 							logger.WithFields(map[string]interface{}{
@@ -109,7 +243,7 @@ func Run() error {
 				)
 			}
 		} else {
-			if err := processSingleFile(ctx, path, appInstance, dispatcherService); err != nil {
+			if err := fn(path); err != nil {
 				logger.Error("Error processing file",
 					err,
 					logger.WithFields(map[string]interface{}{
@@ -120,181 +254,30 @@ func Run() error {
 			}
 		}
 	}
-
-	return nil
 }
 
-func isSupportedFile(path string) bool {
-	lower := strings.ToLower(path)
-	return strings.HasSuffix(lower, ".log") || strings.HasSuffix(lower, ".txt") ||
-		strings.HasSuffix(lower, ".json") || strings.HasSuffix(lower, ".gz")
-}
-
-func processSingleFile(ctx context.Context, filepath string, appInstance *app.App, dispatcherService dispatcher.DispatcherService) error {
+func processSingleFile(ctx context.Context, filepath string, dispatcherService dispatcher.DispatcherService, validationService validation.ValidationService, consistencyService consistency.ConsistencyService) error {
 	startTime := time.Now()
 
 	logger.Info("Starting file processing", logger.WithField("file", filepath))
 
-	// Read file
-	logData, err := readFileContent(filepath)
+	result, err := pipeline.ParseFile(filepath)
 	if err != nil {
-		logger.Error("Failed to read file", logger.WithFields(map[string]interface{}{
-			"file":  filepath,
-			"error": err,
-		}))
-		return fmt.Errorf("failed to read file: %w", err)
+		return err
 	}
 
-	logger.Debug("File read successfully", logger.WithFields(map[string]interface{}{
-		"file":       filepath,
-		"size_bytes": len(logData),
-	}))
-
-	// Extract JSON blocks
-	allBlocks, err := parser.ExtractJson(string(logData))
-	if err != nil {
-		logger.Error("Failed to extract JSON blocks", logger.WithFields(map[string]interface{}{
-			"file":  filepath,
-			"error": err,
-		}))
-		return fmt.Errorf("failed to extract JSON blocks: %w", err)
-	}
-
-	logger.Debug("JSON extraction completed", logger.WithFields(map[string]interface{}{
-		"file":         filepath,
-		"total_blocks": len(allBlocks),
-	}))
-
-	// Filter relevant blocks
-	filteredBlocks, err := parser.FilterRelevantJsonBlocks(allBlocks)
-	if err != nil {
-		logger.Error("Failed to filter relevant JSON blocks", logger.WithFields(map[string]interface{}{
-			"file":  filepath,
-			"error": err,
-		}))
-		return fmt.Errorf("failed to filter relevant JSON blocks: %w", err)
-	}
-
-	logger.Debug("Block filtering completed", logger.WithFields(map[string]interface{}{
-		"file":             filepath,
-		"filtered_blocks":  len(filteredBlocks),
-		"discarded_blocks": len(allBlocks) - len(filteredBlocks),
-	}))
-
-	// Parse JSON
-	combinedJSON := "[\n" + strings.Join(filteredBlocks, ",\n") + "\n]"
-	parsedItems, err := parser.ParseMixedJSONArray([]byte(combinedJSON))
-	if err != nil {
-		logger.Error("Failed to parse mixed JSON array", logger.WithFields(map[string]interface{}{
-			"file":  filepath,
-			"error": err,
-		}))
-		return fmt.Errorf("failed to parse mixed JSON array: %w", err)
-	}
-
-	// Calculate statistics
-	stats := calculateParsingStatistics(parsedItems)
-
-	logger.Info("PARSING STATISTICS", logger.WithFields(map[string]interface{}{
-		"file":           filepath,
-		"Final":          stats.FinalStations,
-		"PCBA":           stats.PCBAStations,
-		"Download":       stats.DownloadInfo,
-		"TestStepArrays": stats.TestStepArrays,
-		"TotalTestSteps": stats.TotalTestSteps,
-	}))
-
-	// Group data
-	groupedData, err := processor.GroupByPCBANumber(parsedItems)
-	if err != nil {
-		logger.Error("Failed to group data", logger.WithFields(map[string]interface{}{
-			"file":  filepath,
-			"error": err,
-		}))
-		return fmt.Errorf("failed to group data: %w", err)
-	}
-
-	logger.Debug("Data grouping completed", logger.WithFields(map[string]interface{}{
-		"file":   filepath,
-		"groups": len(groupedData),
-	}))
-
-	// Dispatch to database
-	if err := dispatcherService.DispatchGroups(ctx, groupedData); err != nil {
-		logger.Error("Failed to dispatch groups to database", logger.WithFields(map[string]interface{}{
-			"file":  filepath,
-			"error": err,
-		}))
-		return fmt.Errorf("failed to dispatch groups: %w", err)
+	// Store findings, groups and consistency checks like the upload jobs do
+	if _, err := ingestion.Store(ctx, result, dispatcherService, validationService, consistencyService, nil); err != nil {
+		return err
 	}
 
 	duration := time.Since(startTime)
 	logger.Info("File processing completed", logger.WithFields(map[string]interface{}{
 		"file":            filepath,
 		"duration":        duration,
-		"groups_inserted": len(groupedData),
+		"groups_inserted": len(result.Groups),
+		"groups_rejected": result.Validation.Summary.GroupsRejected,
 	}))
 
 	return nil
 }
-
-// ParsingStatistics holds statistics about parsed items
-type ParsingStatistics struct {
-	FinalStations  int
-	PCBAStations   int
-	DownloadInfo   int
-	TestStepArrays int
-	TotalTestSteps int
-}
-
-// calculateParsingStatistics analyzes parsed items and returns detailed statistics
-func calculateParsingStatistics(parsedItems []interface{}) ParsingStatistics {
-	stats := ParsingStatistics{}
-
-	for _, item := range parsedItems {
-		switch v := item.(type) {
-		case dto.TestStationRecordDTO:
-			if v.TestStation == "Final" {
-				stats.FinalStations++
-			} else if v.TestStation == "PCBA" {
-				stats.PCBAStations++
-			}
-		case dto.DownloadInfoDTO:
-			stats.DownloadInfo++
-		case []dto.TestStepDTO:
-			stats.TestStepArrays++
-			stats.TotalTestSteps += len(v)
-		}
-	}
-
-	return stats
-}
-
-func readFileContent(path string) ([]byte, error) {
-	f, err := os.Open(path)
-	if err != nil {
-		return nil, err
-	}
-	defer f.Close()
-
-	if strings.HasSuffix(strings.ToLower(path), ".gz") {
-		gr, err := gzip.NewReader(f)
-		if err != nil {
-			return nil, fmt.Errorf("failed to create gzip reader: %w", err)
-		}
-		defer gr.Close()
-
-		var buf bytes.Buffer
-		scanner := bufio.NewScanner(gr)
-		for scanner.Scan() {
-			buf.Write(scanner.Bytes())
-			buf.WriteByte('\n')
-		}
-		if err := scanner.Err(); err != nil {
-			return nil, fmt.Errorf("error reading gzip content: %w", err)
-		}
-		return buf.Bytes(), nil
-	}
-
-	return os.ReadFile(path)
-}
@@ -0,0 +1,69 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/NoroSaroyan/log-parser/internal/infrastructure/logger"
+	"github.com/NoroSaroyan/log-parser/internal/infrastructure/redaction"
+	"github.com/NoroSaroyan/log-parser/internal/services/export"
+	"github.com/NoroSaroyan/log-parser/internal/services/pipeline"
+)
+
+// runExport parses every given file or directory and writes the result to
+// outDir in the given format, without a database. Output is flushed after
+// every file. Redacted fields are written in clear only when listed in
+// reveal, a comma-separated list of field names.
+func runExport(ctx context.Context, args []string, format, outDir, reveal string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("please specify at least one file or directory to export")
+	}
+	if format == "" {
+		format = export.FormatJSONL
+	}
+	revealed := map[string]bool{}
+	for _, field := range strings.Split(reveal, ",") {
+		if field = strings.TrimSpace(field); field == "" {
+			continue
+		}
+		if _, ok := redaction.Current().Rule(field); !ok {
+			return fmt.Errorf("cannot reveal %s: it is not a redacted field", field)
+		}
+		revealed[field] = true
+	}
+
+	exporter, err := export.New(format, outDir, func(field string) bool { return revealed[field] })
+	if err != nil {
+		return err
+	}
+
+	files, groups := 0, 0
+	walkFiles(args, func(path string) error {
+		result, err := pipeline.ParseFile(path)
+		if err != nil {
+			return err
+		}
+		if err := exporter.WriteResult(ctx, result); err != nil {
+			return err
+		}
+		files++
+		groups += len(result.Groups)
+		return nil
+	})
+
+	if err := exporter.Close(); err != nil {
+		return fmt.Errorf("failed to finish export: %w", err)
+	}
+
+	logger.Info("Export finished", logger.WithFields(map[string]interface{}{
+		"format":   format,
+		"revealed": slices.Sorted(maps.Keys(revealed)),
+		"out":      outDir,
+		"files":    files,
+		"devices":  groups,
+	}))
+	return nil
+}
@@ -0,0 +1,64 @@
+package cli
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/NoroSaroyan/log-parser/internal/config"
+)
+
+// healthcheckTimeout bounds the liveness probe of healthcheck mode.
+const healthcheckTimeout = 5 * time.Second
+
+// runHealthcheck probes the liveness endpoint of the API configured by cfg and
+// fails unless it answers HTTP 200. Container health checks run it instead of
+// a fixed URL, so they follow server.address and security.enable_tls.
+func runHealthcheck(ctx context.Context, cfg *config.Config) error {
+	url, err := healthURL(cfg)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, healthcheckTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	client := &http.Client{Transport: &http.Transport{
+		// The probe connects to the local address, which the certificate
+		// usually does not name.
+		TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+	}}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("health check failed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("health check failed: %s answered %s", url, resp.Status)
+	}
+	return nil
+}
+
+// healthURL returns the URL of /healthz on the address the API of cfg listens
+// on. An address without a host, or with an unspecified one such as 0.0.0.0,
+// is probed on localhost.
+func healthURL(cfg *config.Config) (string, error) {
+	host, port, err := net.SplitHostPort(cfg.Server.Address)
+	if err != nil {
+		return "", fmt.Errorf("invalid server.address %q: %w", cfg.Server.Address, err)
+	}
+	if ip := net.ParseIP(host); host == "" || ip != nil && ip.IsUnspecified() {
+		host = "localhost"
+	}
+	scheme := "http"
+	if cfg.Security.EnableTLS {
+		scheme = "https"
+	}
+	return fmt.Sprintf("%s://%s/healthz", scheme, net.JoinHostPort(host, port)), nil
+}
@@ -0,0 +1,48 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/NoroSaroyan/log-parser/internal/app"
+	"github.com/NoroSaroyan/log-parser/internal/config"
+	"github.com/NoroSaroyan/log-parser/internal/infrastructure/encryption"
+	"github.com/NoroSaroyan/log-parser/internal/infrastructure/logger"
+)
+
+// runKeys manages the encryption of the BLE pairing keys at rest:
+//
+//	keys generate ID   prints a new key entry for the keys file
+//	keys rotate        re-encrypts every stored key under the active key,
+//	                   including the ones stored in plaintext
+func runKeys(ctx context.Context, cfg *config.Config, args []string, batchSize int, out io.Writer) error {
+	switch {
+	case len(args) == 2 && args[0] == "generate":
+		key, err := encryption.GenerateKey()
+		if err != nil {
+			return fmt.Errorf("failed to generate key: %w", err)
+		}
+		_, err = fmt.Fprintf(out, "%s:%s\n", args[1], key)
+		return err
+
+	case len(args) == 1 && args[0] == "rotate":
+		appInstance, err := app.InitializeAppWithConfig(cfg)
+		if err != nil {
+			return fmt.Errorf("failed to initialize app: %w", err)
+		}
+		defer func() {
+			if err := appInstance.CloseDB(); err != nil {
+				logger.Error("Failed to close DB connection", logger.WithField("error", err))
+			}
+		}()
+
+		n, err := appInstance.LogisticService.ReencryptSecrets(ctx, batchSize)
+		if err != nil {
+			return err
+		}
+		logger.Info("Re-encrypted BLE pairing keys", logger.WithField("rows", n))
+		return nil
+	}
+	return fmt.Errorf("keys mode requires a command: generate ID or rotate")
+}
@@ -0,0 +1,115 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/NoroSaroyan/log-parser/internal/infrastructure/logger"
+	"github.com/NoroSaroyan/log-parser/internal/services/stats"
+)
+
+// runStats prints payload statistics for every given file or directory.
+func runStats(args []string, format string, out io.Writer) error {
+	if len(args) == 0 {
+		return fmt.Errorf("please specify at least one file or directory to analyze")
+	}
+	if err := checkReportFormat(format); err != nil {
+		return err
+	}
+
+	var files []stats.FileStats
+	walkFiles(args, func(path string) error {
+		s, err := stats.AnalyzeFile(path)
+		files = append(files, s)
+		return err
+	})
+	logger.Info("Statistics finished", logger.WithField("files", len(files)))
+
+	if format == "json" {
+		return writeJSON(out, files)
+	}
+	return stats.WriteText(out, files, nil)
+}
+
+// runCompare prints the statistics of the logs in oldDir and newDir side by
+// side, followed by the step names that appear in only one of them.
+func runCompare(oldDir, newDir, format string, out io.Writer) error {
+	if oldDir == "" || newDir == "" {
+		return fmt.Errorf("compare mode requires both -old and -new directories")
+	}
+	if err := checkReportFormat(format); err != nil {
+		return err
+	}
+
+	analyzeDir := func(dir string) ([]stats.FileStats, error) {
+		paths, err := listLogFiles(dir)
+		if err != nil {
+			return nil, err
+		}
+		var result []stats.FileStats
+		for _, path := range paths {
+			logger.Info("Scanning log file", logger.WithField("file", path))
+			s, err := stats.AnalyzeFile(path)
+			if err != nil {
+				logger.Error("Error analyzing file", err, logger.WithField("file", path))
+			}
+			result = append(result, s)
+		}
+		return result, nil
+	}
+
+	oldStats, err := analyzeDir(oldDir)
+	if err != nil {
+		return err
+	}
+	newStats, err := analyzeDir(newDir)
+	if err != nil {
+		return err
+	}
+	comparison := stats.Compare(oldStats, newStats)
+
+	if format == "json" {
+		return writeJSON(out, comparison)
+	}
+	return stats.WriteText(out, comparison.Files, &comparison)
+}
+
+// listLogFiles returns the non-empty log files directly inside dir, sorted by
+// name. Rotated logs without an extension (mesrestapi.log-20250531) are
+// included; subdirectories are not descended into.
+func listLogFiles(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read directory %s: %w", dir, err)
+	}
+	var paths []string
+	for _, e := range entries {
+		if !e.Type().IsRegular() || !strings.Contains(e.Name(), ".log") {
+			continue
+		}
+		if info, err := e.Info(); err != nil || info.Size() == 0 {
+			continue
+		}
+		paths = append(paths, filepath.Join(dir, e.Name()))
+	}
+	sort.Strings(paths)
+	return paths, nil
+}
+
+func checkReportFormat(format string) error {
+	if format != "" && format != "text" && format != "json" {
+		return fmt.Errorf("unsupported report format: %s (expected text or json)", format)
+	}
+	return nil
+}
+
+func writeJSON(out io.Writer, v interface{}) error {
+	enc := json.NewEncoder(out)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
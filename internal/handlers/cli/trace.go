@@ -0,0 +1,44 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/NoroSaroyan/log-parser/internal/services/trace"
+)
+
+// runTrace prints the timeline, session pairing and verdict of one PCBA
+// across the given files or directories.
+func runTrace(pcba string, args []string, format string, out io.Writer) error {
+	if pcba == "" {
+		return fmt.Errorf("trace mode requires -pcba")
+	}
+	if len(args) == 0 {
+		return fmt.Errorf("please specify at least one file or directory to trace")
+	}
+	if err := checkReportFormat(format); err != nil {
+		return err
+	}
+
+	var paths []string
+	walkFiles(args, func(path string) error {
+		paths = append(paths, path)
+		return nil
+	})
+	// Rotated logs are named by date, so name order is chronological.
+	sort.Strings(paths)
+
+	tracer := trace.NewTracer(pcba)
+	for _, path := range paths {
+		if err := tracer.AddFile(path); err != nil {
+			return fmt.Errorf("failed to trace %s: %w", path, err)
+		}
+	}
+	result := tracer.Result()
+
+	if format == "json" {
+		return writeJSON(out, result)
+	}
+	return trace.WriteText(out, result)
+}
@@ -0,0 +1,77 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/NoroSaroyan/log-parser/internal/app"
+	"github.com/NoroSaroyan/log-parser/internal/config"
+	"github.com/NoroSaroyan/log-parser/internal/infrastructure/logger"
+	"github.com/NoroSaroyan/log-parser/internal/infrastructure/metrics"
+	"github.com/NoroSaroyan/log-parser/internal/services/watch"
+)
+
+// runWatch follows the active log in dir and inserts new payloads into
+// Postgres until SIGINT or SIGTERM is received. With metricsAddr set it
+// serves the same metrics as the REST API on that address.
+func runWatch(ctx context.Context, cfg *config.Config, args []string, checkpointPath string, interval, settle time.Duration, metricsAddr string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("please specify exactly one directory to watch")
+	}
+	if fi, err := os.Stat(args[0]); err != nil || !fi.IsDir() {
+		return fmt.Errorf("watch target %s is not a directory", args[0])
+	}
+
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	appInstance, err := app.InitializeAppWithConfig(cfg)
+	if err != nil {
+		logger.Error("Failed to initialize app", logger.WithField("error", err))
+		return fmt.Errorf("failed to initialize app: %w", err)
+	}
+	defer func() {
+		if err := appInstance.CloseDB(); err != nil {
+			logger.Error("Failed to close DB connection", logger.WithField("error", err))
+		}
+	}()
+
+	if metricsAddr != "" {
+		path := appInstance.Config.Metrics.Path
+		if path == "" {
+			path = metrics.DefaultPath
+		}
+		mux := http.NewServeMux()
+		mux.Handle(path, metrics.Handler())
+		server := &http.Server{Addr: metricsAddr, Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+		go func() {
+			logger.Info("Serving metrics", logger.WithFields(map[string]interface{}{
+				"address": metricsAddr,
+				"path":    path,
+			}))
+			if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				logger.Error("Metrics server failed", err, logger.WithField("address", metricsAddr))
+			}
+		}()
+		defer server.Close()
+	}
+
+	w := watch.New(watch.Config{
+		Dir:            args[0],
+		CheckpointPath: checkpointPath,
+		PollInterval:   interval,
+		SettleTimeout:  settle,
+	}, newDispatcher(appInstance), appInstance.ValidationService, appInstance.ConsistencyService)
+
+	if err := w.Run(ctx); err != nil {
+		return fmt.Errorf("watch failed: %w", err)
+	}
+	logger.Info("Watch stopped")
+	return nil
+}
@@ -3,33 +3,42 @@ Package database provides utilities for initializing and managing
 database connections for the application.
 
 This package currently supports PostgreSQL connection initialization
-with a sane default connection pool configuration.
+with the connection pool of the configuration. Queries are traced when
+tracing is enabled, see the tracing package.
 */
 package database
 
 import (
 	"database/sql"
 	"fmt"
-	"time"
+
+	"github.com/lib/pq"
+	"go.opentelemetry.io/otel/attribute"
 
 	"github.com/NoroSaroyan/log-parser/internal/config"
-	_ "github.com/lib/pq" // PostgreSQL driver
+	"github.com/NoroSaroyan/log-parser/internal/infrastructure/tracing"
 )
 
+// repositoriesPackage is the package of the Postgres repositories, after
+// whose methods query spans are named.
+const repositoriesPackage = "github.com/NoroSaroyan/log-parser/internal/infrastructure/database/repositories"
+
 // NewPostgresDB initializes and returns a PostgreSQL database connection
-// configured with optimal connection pool settings.
+// configured with the connection pool settings of cfg.
 //
 // The function performs the following steps:
 //  1. Opens a new connection to the PostgreSQL database using the DSN
-//     provided by the configuration.
+//     provided by the configuration, tracing the queries of the
+//     repositories package.
 //  2. Configures the connection pool with:
-//     - Max open connections: 25
-//     - Max idle connections: 25
-//     - Connection max lifetime: 5 minutes
+//     - Max open connections: database.max_open_conns
+//     - Max idle connections: database.max_idle_conns
+//     - Connection max lifetime: database.conn_max_lifetime
 //  3. Pings the database to ensure the connection is valid.
 //
 // Parameters:
-//   - cfg: pointer to DatabaseConfig which provides the DSN string.
+//   - cfg: pointer to DatabaseConfig which provides the DSN string and the
+//     pool settings.
 //
 // Returns:
 //   - *sql.DB: a live database connection ready for queries.
@@ -43,16 +52,20 @@ import (
 //	}
 //	defer db.Close()
 func NewPostgresDB(cfg *config.DatabaseConfig) (*sql.DB, error) {
-	db, err := sql.Open("postgres", cfg.DSN())
+	connector, err := pq.NewConnector(cfg.DSN())
 	if err != nil {
-		fmt.Printf("failed to open DB with DSN: %v\n", cfg.DSN())
-		return nil, err
+		return nil, fmt.Errorf("failed to open database %s on %s:%d: %w", cfg.Name, cfg.Host, cfg.Port, err)
 	}
+	db := sql.OpenDB(tracing.WrapConnector(connector, repositoriesPackage,
+		attribute.String("db.namespace", cfg.Name),
+		attribute.String("server.address", cfg.Host),
+		attribute.Int("server.port", cfg.Port),
+	))
 
 	// Configure connection pool parameters
-	db.SetMaxOpenConns(25)
-	db.SetMaxIdleConns(25)
-	db.SetConnMaxLifetime(5 * time.Minute)
+	db.SetMaxOpenConns(cfg.MaxOpenConns)
+	db.SetMaxIdleConns(cfg.MaxIdleConns)
+	db.SetConnMaxLifetime(cfg.ConnMaxLifetime)
 
 	// Verify the database connection is alive
 	if err := db.Ping(); err != nil {
@@ -0,0 +1,118 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/NoroSaroyan/log-parser/internal/domain/models/db"
+	"github.com/NoroSaroyan/log-parser/internal/domain/repositories"
+)
+
+// APIKeyRepository is an in-memory implementation of
+// repositories.APIKeyRepository backed by a Store.
+type APIKeyRepository struct {
+	store *Store
+}
+
+// NewAPIKeyRepository creates an APIKeyRepository on top of the given Store.
+func NewAPIKeyRepository(store *Store) *APIKeyRepository {
+	return &APIKeyRepository{store: store}
+}
+
+// Insert stores a copy of key and writes the generated ID and CreatedAt
+// back. Names and hashes are unique like in Postgres.
+func (r *APIKeyRepository) Insert(ctx context.Context, key *db.APIKeyDB) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	for _, k := range r.store.apiKeys {
+		if k.Name == key.Name || k.KeyHash == key.KeyHash {
+			return fmt.Errorf("failed to insert APIKey: duplicate key %q", key.Name)
+		}
+	}
+	key.ID = r.store.nextAPIKeyID
+	key.CreatedAt = time.Now()
+	r.store.nextAPIKeyID++
+	r.store.apiKeys = append(r.store.apiKeys, *key)
+	return nil
+}
+
+// GetByHash returns the key with the given hash, or nil if there is none.
+func (r *APIKeyRepository) GetByHash(ctx context.Context, keyHash string) (*db.APIKeyDB, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	for _, k := range r.store.apiKeys {
+		if k.KeyHash == keyHash {
+			key := k
+			return &key, nil
+		}
+	}
+	return nil, nil
+}
+
+// List returns all keys ordered by name.
+func (r *APIKeyRepository) List(ctx context.Context) ([]*db.APIKeyDB, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	keys := make([]*db.APIKeyDB, 0, len(r.store.apiKeys))
+	for _, k := range r.store.apiKeys {
+		key := k
+		keys = append(keys, &key)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i].Name < keys[j].Name })
+	return keys, nil
+}
+
+// Revoke marks the active key with the given name as revoked.
+func (r *APIKeyRepository) Revoke(ctx context.Context, name string) (bool, error) {
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	for i, k := range r.store.apiKeys {
+		if k.Name == name && k.RevokedAt == nil {
+			now := time.Now()
+			r.store.apiKeys[i].RevokedAt = &now
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// TouchLastUsed records when the key with the given ID was last used.
+func (r *APIKeyRepository) TouchLastUsed(ctx context.Context, id int, at time.Time) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	for i, k := range r.store.apiKeys {
+		if k.ID == id {
+			r.store.apiKeys[i].LastUsedAt = &at
+		}
+	}
+	return nil
+}
+
+var _ repositories.APIKeyRepository = (*APIKeyRepository)(nil)
@@ -0,0 +1,440 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/NoroSaroyan/log-parser/internal/domain/models/db"
+	"github.com/NoroSaroyan/log-parser/internal/domain/repositories"
+)
+
+// AnalyticsRepository is an in-memory implementation of
+// repositories.AnalyticsRepository backed by a Store.
+type AnalyticsRepository struct {
+	store *Store
+}
+
+// NewAnalyticsRepository creates an AnalyticsRepository on top of the given Store.
+func NewAnalyticsRepository(store *Store) *AnalyticsRepository {
+	return &AnalyticsRepository{store: store}
+}
+
+// yieldDevice collects the sessions of one device at one station type.
+type yieldDevice struct {
+	first    db.TestStationRecordDB
+	sessions int
+	passed   bool
+}
+
+// Yield computes first-pass and final yield per bucket and group, with the
+// same attribution rules as the SQL window query: a device counts once per
+// station type, in the bucket and group of its first attempt.
+func (r *AnalyticsRepository) Yield(ctx context.Context, q db.YieldQuery) ([]*db.YieldRowDB, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	for _, dim := range q.GroupBy {
+		switch dim {
+		case db.YieldGroupStationType, db.YieldGroupPartNumber, db.YieldGroupProductLine, db.YieldGroupToolVersion:
+		default:
+			return nil, fmt.Errorf("unsupported yield grouping %q", dim)
+		}
+	}
+
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	type deviceKey struct{ pcba, station string }
+	devices := make(map[deviceKey]*yieldDevice)
+	var order []*yieldDevice
+	for _, rec := range r.store.testStationRecords {
+		switch {
+		case q.StationType != "" && rec.TestStation != q.StationType,
+			q.PartNumber != "" && rec.PartNumber != q.PartNumber,
+			q.ProductLine != "" && rec.ProductLine != q.ProductLine:
+			continue
+		}
+		ld, ok := r.store.logisticByID(rec.LogisticDataID)
+		if !ok {
+			continue
+		}
+		k := deviceKey{ld.PCBANumber, rec.TestStation}
+		d := devices[k]
+		if d == nil {
+			d = &yieldDevice{first: rec}
+			devices[k] = d
+			order = append(order, d)
+		} else if rec.TestFinishedTime < d.first.TestFinishedTime {
+			// Records are kept in ID order, so ties keep the lower ID.
+			d.first = rec
+		}
+		d.sessions++
+		d.passed = d.passed || rec.IsAllPassed
+	}
+
+	groups := make(map[db.YieldRowDB]*db.YieldRowDB)
+	var results []*db.YieldRowDB
+	for _, d := range order {
+		rec := d.first
+		if rec.TestFinishedTime == "" ||
+			q.From != "" && rec.TestFinishedTime < q.From ||
+			q.To != "" && rec.TestFinishedTime > q.To {
+			continue
+		}
+		t, err := time.Parse("2006-01-02 15:04:05", rec.TestFinishedTime)
+		if err != nil {
+			return nil, fmt.Errorf("failed to query yield: invalid test_finished_time %q", rec.TestFinishedTime)
+		}
+		bucket, err := truncateBucket(t, q.Bucket)
+		if err != nil {
+			return nil, err
+		}
+
+		k := db.YieldRowDB{Bucket: bucket}
+		for _, dim := range q.GroupBy {
+			switch dim {
+			case db.YieldGroupStationType:
+				k.StationType = rec.TestStation
+			case db.YieldGroupPartNumber:
+				k.PartNumber = rec.PartNumber
+			case db.YieldGroupProductLine:
+				k.ProductLine = rec.ProductLine
+			case db.YieldGroupToolVersion:
+				k.TestToolVersion = rec.TestToolVersion
+			}
+		}
+		row := groups[k]
+		if row == nil {
+			row = &db.YieldRowDB{}
+			*row = k
+			groups[k] = row
+			results = append(results, row)
+		}
+		row.Attempted++
+		row.Sessions += d.sessions
+		if rec.IsAllPassed {
+			row.FirstPassPassed++
+		}
+		if d.passed {
+			row.EventuallyPassed++
+		} else {
+			row.Failed++
+		}
+	}
+
+	sort.Slice(results, func(i, j int) bool {
+		a, b := results[i], results[j]
+		if !a.Bucket.Equal(b.Bucket) {
+			return a.Bucket.Before(b.Bucket)
+		}
+		if a.StationType != b.StationType {
+			return a.StationType < b.StationType
+		}
+		if a.PartNumber != b.PartNumber {
+			return a.PartNumber < b.PartNumber
+		}
+		if a.ProductLine != b.ProductLine {
+			return a.ProductLine < b.ProductLine
+		}
+		return a.TestToolVersion < b.TestToolVersion
+	})
+	return results, nil
+}
+
+// truncateBucket mirrors date_trunc for the yield buckets; weeks start on Monday.
+func truncateBucket(t time.Time, bucket string) (time.Time, error) {
+	switch bucket {
+	case db.YieldBucketHour:
+		return t.Truncate(time.Hour), nil
+	case db.YieldBucketDay:
+		return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location()), nil
+	case db.YieldBucketWeek:
+		day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+		return day.AddDate(0, 0, -((int(day.Weekday()) + 6) % 7)), nil
+	}
+	return time.Time{}, fmt.Errorf("unsupported yield bucket %q", bucket)
+}
+
+// stepMeasurement is one numeric measured value with its threshold.
+type stepMeasurement struct {
+	value     float64
+	threshold string
+}
+
+// stepMeasurements returns the numeric measurements of the steps selected by
+// q. The caller must hold s.mu.
+func (s *Store) stepMeasurements(q db.StepStatsQuery) []stepMeasurement {
+	var out []stepMeasurement
+	for _, step := range s.testSteps {
+		if step.TestStepName != q.StepName || step.MeasuredNumeric == nil {
+			continue
+		}
+		rec, ok := s.stationByID(step.TestStationRecordID)
+		if !ok {
+			continue
+		}
+		switch {
+		case q.StationType != "" && rec.TestStation != q.StationType,
+			q.PartNumber != "" && rec.PartNumber != q.PartNumber,
+			q.TestToolVersion != "" && rec.TestToolVersion != q.TestToolVersion,
+			q.From != "" && rec.TestFinishedTime < q.From,
+			q.To != "" && rec.TestFinishedTime > q.To:
+			continue
+		}
+		out = append(out, stepMeasurement{value: *step.MeasuredNumeric, threshold: step.TestThresholdValue})
+	}
+	return out
+}
+
+// StepStats computes count, range, mean, sample standard deviation and the
+// requested percentiles with the interpolation of percentile_cont.
+func (r *AnalyticsRepository) StepStats(ctx context.Context, q db.StepStatsQuery) (*db.StepStatsDB, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	ms := r.store.stepMeasurements(q)
+	st := &db.StepStatsDB{Count: len(ms)}
+	if len(ms) == 0 {
+		return st, nil
+	}
+
+	values := make([]float64, len(ms))
+	var sum float64
+	for i, m := range ms {
+		values[i] = m.value
+		sum += m.value
+	}
+	sort.Float64s(values)
+	st.Min, st.Max = values[0], values[len(values)-1]
+	st.Mean = sum / float64(len(values))
+	if len(values) > 1 {
+		var sq float64
+		for _, v := range values {
+			sq += (v - st.Mean) * (v - st.Mean)
+		}
+		st.StdDev = math.Sqrt(sq / float64(len(values)-1))
+	}
+	for _, p := range q.Percentiles {
+		pos := p * float64(len(values)-1)
+		lo := int(math.Floor(pos))
+		hi := int(math.Ceil(pos))
+		st.Percentiles = append(st.Percentiles, values[lo]+(values[hi]-values[lo])*(pos-float64(lo)))
+	}
+	return st, nil
+}
+
+// StepHistogram counts the measurements of a step in bins equal-width bins
+// between lower and upper, like width_bucket. Values equal to upper fall into
+// the last bin.
+func (r *AnalyticsRepository) StepHistogram(ctx context.Context, q db.StepStatsQuery, lower, upper float64, bins int) ([]int, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if bins < 1 || !(lower < upper) {
+		return nil, fmt.Errorf("invalid histogram range [%v,%v] with %d bins", lower, upper, bins)
+	}
+
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	counts := make([]int, bins)
+	for _, m := range r.store.stepMeasurements(q) {
+		if m.value < lower || m.value > upper {
+			continue
+		}
+		bin := int(float64(bins) * (m.value - lower) / (upper - lower))
+		if bin >= bins {
+			bin = bins - 1
+		}
+		counts[bin]++
+	}
+	return counts, nil
+}
+
+// StepThresholds returns the distinct thresholds of the numeric measurements
+// of a step, most frequent first.
+func (r *AnalyticsRepository) StepThresholds(ctx context.Context, q db.StepStatsQuery) ([]*db.StepThresholdDB, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	byThreshold := make(map[string]*db.StepThresholdDB)
+	var results []*db.StepThresholdDB
+	for _, m := range r.store.stepMeasurements(q) {
+		t := byThreshold[m.threshold]
+		if t == nil {
+			t = &db.StepThresholdDB{Threshold: m.threshold}
+			byThreshold[m.threshold] = t
+			results = append(results, t)
+		}
+		t.Count++
+	}
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].Count != results[j].Count {
+			return results[i].Count > results[j].Count
+		}
+		return results[i].Threshold < results[j].Threshold
+	})
+	return results, nil
+}
+
+// errorOccurrence is a station record carrying an error code itself or in one
+// of its steps.
+type errorOccurrence struct {
+	code string
+	pcba string
+	rec  db.TestStationRecordDB
+}
+
+// errorOccurrences returns one occurrence per station record and error code,
+// in record order, restricted to the records selected by q. The caller must
+// hold s.mu.
+func (s *Store) errorOccurrences(q db.ErrorParetoQuery) []errorOccurrence {
+	codes := make(map[int]string, len(s.errorCodes))
+	for _, ec := range s.errorCodes {
+		codes[ec.ID] = ec.Code
+	}
+	stepRecords := make(map[int]int, len(s.testSteps))
+	for _, step := range s.testSteps {
+		stepRecords[step.ID] = step.TestStationRecordID
+	}
+	linked := make(map[int]map[int]bool) // record ID -> error code IDs
+	link := func(recordID, codeID int) {
+		if linked[recordID] == nil {
+			linked[recordID] = make(map[int]bool)
+		}
+		linked[recordID][codeID] = true
+	}
+	for _, l := range s.recordErrorCodes {
+		link(l.ownerID, l.codeID)
+	}
+	for _, l := range s.stepErrorCodes {
+		link(stepRecords[l.ownerID], l.codeID)
+	}
+
+	var out []errorOccurrence
+	for _, rec := range s.testStationRecords {
+		if len(linked[rec.ID]) == 0 {
+			continue
+		}
+		switch {
+		case q.StationType != "" && rec.TestStation != q.StationType,
+			q.PartNumber != "" && rec.PartNumber != q.PartNumber,
+			q.From != "" && rec.TestFinishedTime < q.From,
+			q.To != "" && rec.TestFinishedTime > q.To:
+			continue
+		}
+		ld, ok := s.logisticByID(rec.LogisticDataID)
+		if !ok {
+			continue
+		}
+		for codeID := range linked[rec.ID] {
+			out = append(out, errorOccurrence{code: codes[codeID], pcba: ld.PCBANumber, rec: rec})
+		}
+	}
+	return out
+}
+
+// ErrorPareto counts the occurrences and affected devices of every error
+// code, most frequent first.
+func (r *AnalyticsRepository) ErrorPareto(ctx context.Context, q db.ErrorParetoQuery) ([]*db.ErrorCodeCountDB, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	byCode := make(map[string]*db.ErrorCodeCountDB)
+	devices := make(map[string]map[string]bool)
+	var results []*db.ErrorCodeCountDB
+	for _, o := range r.store.errorOccurrences(q) {
+		c := byCode[o.code]
+		if c == nil {
+			c = &db.ErrorCodeCountDB{Code: o.code}
+			byCode[o.code] = c
+			devices[o.code] = make(map[string]bool)
+			results = append(results, c)
+		}
+		c.Occurrences++
+		devices[o.code][o.pcba] = true
+	}
+	for _, c := range results {
+		c.Devices = len(devices[c.Code])
+	}
+	sort.Slice(results, func(i, j int) bool {
+		a, b := results[i], results[j]
+		if a.Occurrences != b.Occurrences {
+			return a.Occurrences > b.Occurrences
+		}
+		if a.Devices != b.Devices {
+			return a.Devices > b.Devices
+		}
+		return a.Code < b.Code
+	})
+	return results, nil
+}
+
+// ErrorCodeDevices returns the devices behind one error code, most affected
+// first.
+func (r *AnalyticsRepository) ErrorCodeDevices(ctx context.Context, q db.ErrorParetoQuery, code string) ([]*db.ErrorCodeDeviceDB, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	byPCBA := make(map[string]*db.ErrorCodeDeviceDB)
+	stations := make(map[string]map[string]bool)
+	var results []*db.ErrorCodeDeviceDB
+	for _, o := range r.store.errorOccurrences(q) {
+		if o.code != code {
+			continue
+		}
+		d := byPCBA[o.pcba]
+		if d == nil {
+			d = &db.ErrorCodeDeviceDB{PCBANumber: o.pcba, FirstSeen: o.rec.TestFinishedTime, LastSeen: o.rec.TestFinishedTime}
+			byPCBA[o.pcba] = d
+			stations[o.pcba] = make(map[string]bool)
+			results = append(results, d)
+		}
+		d.Occurrences++
+		stations[o.pcba][o.rec.TestStation] = true
+		if o.rec.TestFinishedTime < d.FirstSeen {
+			d.FirstSeen = o.rec.TestFinishedTime
+		}
+		if o.rec.TestFinishedTime > d.LastSeen {
+			d.LastSeen = o.rec.TestFinishedTime
+		}
+	}
+	for _, d := range results {
+		var types []string
+		for t := range stations[d.PCBANumber] {
+			types = append(types, t)
+		}
+		sort.Strings(types)
+		d.Stations = strings.Join(types, ",")
+	}
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].Occurrences != results[j].Occurrences {
+			return results[i].Occurrences > results[j].Occurrences
+		}
+		return results[i].PCBANumber < results[j].PCBANumber
+	})
+	return results, nil
+}
+
+// Ensure AnalyticsRepository implements the repositories.AnalyticsRepository interface.
+var _ repositories.AnalyticsRepository = (*AnalyticsRepository)(nil)
@@ -0,0 +1,108 @@
+package memory
+
+import (
+	"context"
+
+	"github.com/NoroSaroyan/log-parser/internal/domain/models/db"
+	"github.com/NoroSaroyan/log-parser/internal/domain/repositories"
+)
+
+// DownloadInfoRepository is an in-memory implementation of
+// repositories.DownloadInfoRepository backed by a Store.
+type DownloadInfoRepository struct {
+	store *Store
+}
+
+// NewDownloadInfoRepository creates a DownloadInfoRepository on top of the given Store.
+func NewDownloadInfoRepository(store *Store) *DownloadInfoRepository {
+	return &DownloadInfoRepository{store: store}
+}
+
+// Insert stores a copy of the DownloadInfoDB record.
+//
+// Like the Postgres implementation, the generated ID is not written back
+// into the provided model.
+func (r *DownloadInfoRepository) Insert(ctx context.Context, d *db.DownloadInfoDB) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	row := *d
+	row.ID = r.store.nextDownloadInfoID
+	r.store.nextDownloadInfoID++
+	r.store.downloadInfo = append(r.store.downloadInfo, row)
+	return nil
+}
+
+// GetByPCBANumber returns the first DownloadInfoDB record with the given tcu_pcba_number.
+//
+// Returns (nil, nil) if no record exists for the given PCBA number.
+func (r *DownloadInfoRepository) GetByPCBANumber(ctx context.Context, pcba string) (*db.DownloadInfoDB, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	for _, d := range r.store.downloadInfo {
+		if d.TcuPCBANumber == pcba {
+			out := selectDownloadInfo(d)
+			return &out, nil
+		}
+	}
+	return nil, nil
+}
+
+// GetAllByPCBANumber returns every DownloadInfoDB record with the given
+// tcu_pcba_number in insertion order.
+func (r *DownloadInfoRepository) GetAllByPCBANumber(ctx context.Context, pcba string) ([]*db.DownloadInfoDB, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	var results []*db.DownloadInfoDB
+	for _, d := range r.store.downloadInfo {
+		if d.TcuPCBANumber == pcba {
+			out := d
+			results = append(results, &out)
+		}
+	}
+	return results, nil
+}
+
+// GetByPartNumber returns the first DownloadInfoDB record with the given part_number.
+//
+// Returns (nil, nil) if no record exists for the given part number.
+func (r *DownloadInfoRepository) GetByPartNumber(ctx context.Context, partNumber string) (*db.DownloadInfoDB, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	for _, d := range r.store.downloadInfo {
+		if d.PartNumber == partNumber {
+			out := selectDownloadInfo(d)
+			return &out, nil
+		}
+	}
+	return nil, nil
+}
+
+// selectDownloadInfo returns the columns read by the Postgres SELECT statements,
+// which do not include the id column.
+func selectDownloadInfo(d db.DownloadInfoDB) db.DownloadInfoDB {
+	d.ID = 0
+	return d
+}
+
+// Ensure DownloadInfoRepository implements the repositories.DownloadInfoRepository interface.
+var _ repositories.DownloadInfoRepository = (*DownloadInfoRepository)(nil)
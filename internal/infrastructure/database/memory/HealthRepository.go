@@ -0,0 +1,36 @@
+package memory
+
+import (
+	"context"
+
+	"github.com/NoroSaroyan/log-parser/internal/domain/repositories"
+	"github.com/NoroSaroyan/log-parser/internal/infrastructure/database"
+)
+
+// HealthRepository is an in-memory implementation of
+// repositories.HealthRepository. A Store has no migrations, so its schema is
+// always current.
+type HealthRepository struct {
+	store *Store
+}
+
+// NewHealthRepository creates a HealthRepository on top of the given Store.
+func NewHealthRepository(store *Store) *HealthRepository {
+	return &HealthRepository{store: store}
+}
+
+// Ping only fails when ctx is done.
+func (r *HealthRepository) Ping(ctx context.Context) error {
+	return ctx.Err()
+}
+
+// SchemaVersion returns database.SchemaVersion.
+func (r *HealthRepository) SchemaVersion(ctx context.Context) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	return database.SchemaVersion, nil
+}
+
+// Ensure HealthRepository satisfies the HealthRepository interface.
+var _ repositories.HealthRepository = (*HealthRepository)(nil)
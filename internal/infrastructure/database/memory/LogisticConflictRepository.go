@@ -0,0 +1,113 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/NoroSaroyan/log-parser/internal/domain/models/db"
+	"github.com/NoroSaroyan/log-parser/internal/domain/repositories"
+)
+
+// LogisticConflictRepository is an in-memory implementation of
+// repositories.LogisticConflictRepository backed by a Store.
+type LogisticConflictRepository struct {
+	store *Store
+}
+
+// NewLogisticConflictRepository creates a LogisticConflictRepository on top of the given Store.
+func NewLogisticConflictRepository(store *Store) *LogisticConflictRepository {
+	return &LogisticConflictRepository{store: store}
+}
+
+// ReplaceForPCBANumber drops the conflicts stored for a PCBA number, stores
+// copies of the given ones and writes the generated IDs back.
+func (r *LogisticConflictRepository) ReplaceForPCBANumber(ctx context.Context, pcba string, conflicts []*db.LogisticConflictDB) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	kept := r.store.logisticConflicts[:0]
+	for _, c := range r.store.logisticConflicts {
+		if c.PCBANumber != pcba {
+			kept = append(kept, c)
+		}
+	}
+	r.store.logisticConflicts = kept
+
+	now := time.Now()
+	for _, c := range conflicts {
+		c.ID = r.store.nextLogisticConflictID
+		c.PCBANumber = pcba
+		c.DetectedAt = now
+		r.store.nextLogisticConflictID++
+		r.store.logisticConflicts = append(r.store.logisticConflicts, *c)
+	}
+	return nil
+}
+
+// GetByPCBANumber returns the conflicts stored for a PCBA number.
+func (r *LogisticConflictRepository) GetByPCBANumber(ctx context.Context, pcba string) ([]*db.LogisticConflictDB, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	var results []*db.LogisticConflictDB
+	for _, c := range r.store.logisticConflicts {
+		if c.PCBANumber == pcba {
+			out := c
+			results = append(results, &out)
+		}
+	}
+	return results, nil
+}
+
+// GetAll returns all stored conflicts ordered by PCBA number, optionally
+// restricted to one classification ("" returns every classification).
+func (r *LogisticConflictRepository) GetAll(ctx context.Context, classification string) ([]*db.LogisticConflictDB, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	var results []*db.LogisticConflictDB
+	for _, c := range r.store.logisticConflicts {
+		if classification == "" || c.Classification == classification {
+			out := c
+			results = append(results, &out)
+		}
+	}
+	sort.SliceStable(results, func(i, j int) bool {
+		if results[i].PCBANumber != results[j].PCBANumber {
+			return results[i].PCBANumber < results[j].PCBANumber
+		}
+		return results[i].ID < results[j].ID
+	})
+	return results, nil
+}
+
+// ForEach calls fn with every conflict GetAll returns, in the same order. It
+// stops at, and returns, the first error of fn.
+func (r *LogisticConflictRepository) ForEach(ctx context.Context, classification string, fn func(c *db.LogisticConflictDB) error) error {
+	conflicts, err := r.GetAll(ctx, classification)
+	if err != nil {
+		return err
+	}
+	for _, c := range conflicts {
+		if err := fn(c); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Ensure LogisticConflictRepository implements the repositories.LogisticConflictRepository interface.
+var _ repositories.LogisticConflictRepository = (*LogisticConflictRepository)(nil)
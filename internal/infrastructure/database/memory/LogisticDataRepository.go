@@ -0,0 +1,235 @@
+package memory
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/NoroSaroyan/log-parser/internal/domain/models/db"
+	"github.com/NoroSaroyan/log-parser/internal/domain/repositories"
+	"github.com/NoroSaroyan/log-parser/internal/infrastructure/encryption"
+)
+
+// LogisticDataRepository is an in-memory implementation of
+// repositories.LogisticDataRepository backed by a Store. Like the Postgres
+// implementation it stores BlePassworkKey encrypted with keys, if any.
+type LogisticDataRepository struct {
+	store *Store
+	keys  *encryption.Keyring
+}
+
+// NewLogisticDataRepository creates a LogisticDataRepository on top of the
+// given Store. keys may be nil to store BlePassworkKey in plaintext.
+func NewLogisticDataRepository(store *Store, keys *encryption.Keyring) *LogisticDataRepository {
+	return &LogisticDataRepository{store: store, keys: keys}
+}
+
+// Insert stores a copy of the LogisticDataDB record and writes the generated
+// ID back into the provided model.
+func (r *LogisticDataRepository) Insert(ctx context.Context, d *db.LogisticDataDB) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	blePassworkKey, err := r.keys.Encrypt(d.BlePassworkKey)
+	if err != nil {
+		return fmt.Errorf("failed to encrypt BlePassworkKey: %w", err)
+	}
+
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	row := *d
+	row.BlePassworkKey = blePassworkKey
+	row.ID = r.store.nextLogisticDataID
+	r.store.nextLogisticDataID++
+	r.store.logisticData = append(r.store.logisticData, row)
+
+	d.ID = row.ID
+	return nil
+}
+
+// GetAllByPCBANumber returns all LogisticDataDB records with the given PCBA number.
+func (r *LogisticDataRepository) GetAllByPCBANumber(ctx context.Context, pcba string) ([]*db.LogisticDataDB, error) {
+	return r.filter(ctx, func(d db.LogisticDataDB) bool { return d.PCBANumber == pcba })
+}
+
+// GetByPartNumber returns all LogisticDataDB records with the given part number.
+func (r *LogisticDataRepository) GetByPartNumber(ctx context.Context, partNumber string) ([]*db.LogisticDataDB, error) {
+	return r.filter(ctx, func(d db.LogisticDataDB) bool { return d.PartNumber == partNumber })
+}
+
+// GetIDByPCBANumber returns the ID of the first logistic_data record with the given PCBA number.
+//
+// Returns sql.ErrNoRows if no record is found, matching the Postgres implementation.
+func (r *LogisticDataRepository) GetIDByPCBANumber(ctx context.Context, pcba string) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	for _, d := range r.store.logisticData {
+		if d.PCBANumber == pcba {
+			return d.ID, nil
+		}
+	}
+	return 0, sql.ErrNoRows
+}
+
+// GetById returns the LogisticDataDB record with the given ID.
+//
+// Returns (nil, nil) if no record is found.
+func (r *LogisticDataRepository) GetById(ctx context.Context, id int) (*db.LogisticDataDB, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	d, ok := r.store.logisticByID(id)
+	if !ok {
+		return nil, nil
+	}
+	return r.selectLogisticData(d)
+}
+
+// GetByPCBANumber returns the first LogisticDataDB record with the given PCBA number.
+//
+// Returns (nil, nil) if no record is found.
+func (r *LogisticDataRepository) GetByPCBANumber(ctx context.Context, pcba string) (*db.LogisticDataDB, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	for _, d := range r.store.logisticData {
+		if d.PCBANumber == pcba {
+			return r.selectLogisticData(d)
+		}
+	}
+	return nil, nil
+}
+
+// filter returns copies of all logistic_data rows matching keep, in insertion order.
+func (r *LogisticDataRepository) filter(ctx context.Context, keep func(db.LogisticDataDB) bool) ([]*db.LogisticDataDB, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	var results []*db.LogisticDataDB
+	for _, d := range r.store.logisticData {
+		if keep(d) {
+			out, err := r.selectLogisticData(d)
+			if err != nil {
+				return nil, err
+			}
+			results = append(results, out)
+		}
+	}
+	return results, nil
+}
+
+// identifierValues returns the value a secondary identifier is matched on,
+// mirroring the expressions of the Postgres implementation.
+var identifierValues = map[string]func(db.LogisticDataDB) string{
+	db.IdentifierIMEI:  func(d db.LogisticDataDB) string { return d.IMEI },
+	db.IdentifierIMSI:  func(d db.LogisticDataDB) string { return d.IMSI },
+	db.IdentifierICCID: func(d db.LogisticDataDB) string { return d.TcuICCID },
+	db.IdentifierBleMac: func(d db.LogisticDataDB) string {
+		return strings.ToUpper(strings.NewReplacer(":", "", "-", "").Replace(d.BleMac))
+	},
+	db.IdentifierBleSN:     func(d db.LogisticDataDB) string { return d.BleSN },
+	db.IdentifierProductSN: func(d db.LogisticDataDB) string { return d.ProductSN },
+}
+
+// GetByIdentifier returns every LogisticDataDB record, including its ID,
+// whose secondary identifier matches value, oldest first.
+func (r *LogisticDataRepository) GetByIdentifier(ctx context.Context, identifier, value string) ([]*db.LogisticDataDB, error) {
+	get, ok := identifierValues[identifier]
+	if !ok {
+		return nil, fmt.Errorf("unsupported identifier %q", identifier)
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	var results []*db.LogisticDataDB
+	for _, d := range r.store.logisticData {
+		if get(d) == value {
+			out, err := r.decrypt(d)
+			if err != nil {
+				return nil, err
+			}
+			results = append(results, &out)
+		}
+	}
+	return results, nil
+}
+
+// selectLogisticData returns the columns read by the Postgres SELECT statements,
+// which do not include the id column, with BlePassworkKey decrypted.
+func (r *LogisticDataRepository) selectLogisticData(d db.LogisticDataDB) (*db.LogisticDataDB, error) {
+	out, err := r.decrypt(d)
+	if err != nil {
+		return nil, err
+	}
+	out.ID = 0
+	return &out, nil
+}
+
+// decrypt returns d with its BlePassworkKey decrypted.
+func (r *LogisticDataRepository) decrypt(d db.LogisticDataDB) (db.LogisticDataDB, error) {
+	plaintext, err := r.keys.Decrypt(d.BlePassworkKey)
+	if err != nil {
+		return d, fmt.Errorf("failed to decrypt BlePassworkKey of PCBA %s: %w", d.PCBANumber, err)
+	}
+	d.BlePassworkKey = plaintext
+	return d, nil
+}
+
+// ReencryptSecrets encrypts every BlePassworkKey stored in plaintext or under
+// a key other than the active one with the active key and returns the number
+// of rows updated.
+func (r *LogisticDataRepository) ReencryptSecrets(ctx context.Context, batchSize int) (int, error) {
+	if r.keys == nil {
+		return 0, fmt.Errorf("no encryption keys configured")
+	}
+	if batchSize <= 0 {
+		return 0, fmt.Errorf("batch size must be positive, got %d", batchSize)
+	}
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	updated := 0
+	for i := range r.store.logisticData {
+		d := &r.store.logisticData[i]
+		next, rotate, err := r.keys.Rotate(d.BlePassworkKey)
+		if err != nil {
+			return updated, fmt.Errorf("failed to re-encrypt BlePassworkKey of logistic_data %d: %w", d.ID, err)
+		}
+		if rotate {
+			d.BlePassworkKey = next
+			updated++
+		}
+	}
+	return updated, nil
+}
+
+// Ensure LogisticDataRepository satisfies the LogisticDataRepository interface.
+var _ repositories.LogisticDataRepository = (*LogisticDataRepository)(nil)
@@ -0,0 +1,290 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/NoroSaroyan/log-parser/internal/domain/models/db"
+	"github.com/NoroSaroyan/log-parser/internal/domain/repositories"
+)
+
+// TestStationRecordRepository is an in-memory implementation of
+// repositories.TestStationRecordRepository backed by a Store.
+type TestStationRecordRepository struct {
+	store *Store
+}
+
+// NewTestStationRecordRepository creates a TestStationRecordRepository on top of the given Store.
+func NewTestStationRecordRepository(store *Store) *TestStationRecordRepository {
+	return &TestStationRecordRepository{store: store}
+}
+
+// Insert stores a copy of the TestStationRecordDB, links it to its error codes
+// and writes the generated ID back into the provided record.
+//
+// The logistic_data_id must reference an existing logistic_data row, mirroring
+// the foreign key constraint in the database schema.
+func (r *TestStationRecordRepository) Insert(ctx context.Context, rec *db.TestStationRecordDB) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if _, ok := r.store.logisticByID(rec.LogisticDataID); !ok {
+		return fmt.Errorf("failed to insert TestStationRecord and retrieve ID: logistic_data_id %d does not exist", rec.LogisticDataID)
+	}
+
+	row := *rec
+	row.ID = r.store.nextTestStationRecordID
+	row.Codes = nil
+	r.store.nextTestStationRecordID++
+	r.store.testStationRecords = append(r.store.testStationRecords, row)
+	r.store.recordErrorCodes = r.store.linkErrorCodes(r.store.recordErrorCodes, row.ID, rec.Codes)
+
+	rec.ID = row.ID
+	return nil
+}
+
+// GetByPCBANumber returns all TestStationRecordDB entries linked to a
+// logistic_data row with the given PCBA number.
+func (r *TestStationRecordRepository) GetByPCBANumber(ctx context.Context, pcba string) ([]*db.TestStationRecordDB, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	var results []*db.TestStationRecordDB
+	for _, rec := range r.store.testStationRecords {
+		ld, ok := r.store.logisticByID(rec.LogisticDataID)
+		if !ok || ld.PCBANumber != pcba {
+			continue
+		}
+		out := rec
+		results = append(results, &out)
+	}
+	return results, nil
+}
+
+// GetByPartNumber returns all TestStationRecordDB entries with the given part number.
+func (r *TestStationRecordRepository) GetByPartNumber(ctx context.Context, partNumber string) ([]*db.TestStationRecordDB, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	var results []*db.TestStationRecordDB
+	for _, rec := range r.store.testStationRecords {
+		if rec.PartNumber == partNumber {
+			out := rec
+			results = append(results, &out)
+		}
+	}
+	return results, nil
+}
+
+// GetAllPCBANumbers returns the distinct PCBA numbers of all logistic_data rows
+// referenced by at least one station record of the given type ("" for any type).
+func (r *TestStationRecordRepository) GetAllPCBANumbers(ctx context.Context, stationType string) ([]string, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	seen := make(map[string]bool)
+	var pcbas []string
+	for _, rec := range r.store.testStationRecords {
+		if stationType != "" && rec.TestStation != stationType {
+			continue
+		}
+		ld, ok := r.store.logisticByID(rec.LogisticDataID)
+		if !ok || seen[ld.PCBANumber] {
+			continue
+		}
+		seen[ld.PCBANumber] = true
+		pcbas = append(pcbas, ld.PCBANumber)
+	}
+	return pcbas, nil
+}
+
+// ForEachPCBANumber calls fn with every PCBA number GetAllPCBANumbers
+// returns. It stops at, and returns, the first error of fn.
+func (r *TestStationRecordRepository) ForEachPCBANumber(ctx context.Context, stationType string, fn func(pcba string) error) error {
+	pcbas, err := r.GetAllPCBANumbers(ctx, stationType)
+	if err != nil {
+		return err
+	}
+	for _, pcba := range pcbas {
+		if err := fn(pcba); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ListDevices returns one page of devices matching f, mirroring the devices
+// CTE of the Postgres implementation.
+func (r *TestStationRecordRepository) ListDevices(ctx context.Context, f db.DeviceFilter) ([]*db.DeviceDB, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.store.mu.RLock()
+	devices := r.store.devices(f)
+	r.store.mu.RUnlock()
+
+	key, ok := deviceSortKeys[f.Sort]
+	if !ok {
+		return nil, fmt.Errorf("unsupported device sort %q", f.Sort)
+	}
+	less := func(a, b *db.DeviceDB) bool {
+		if ka, kb := key(a), key(b); ka != kb {
+			return ka < kb
+		}
+		return a.PCBANumber < b.PCBANumber
+	}
+	sort.Slice(devices, func(i, j int) bool {
+		if f.Desc {
+			return less(devices[j], devices[i])
+		}
+		return less(devices[i], devices[j])
+	})
+
+	var results []*db.DeviceDB
+	for _, d := range devices {
+		if f.After {
+			k := key(d)
+			after := k > f.AfterKey || (k == f.AfterKey && d.PCBANumber > f.AfterPCBA)
+			if f.Desc {
+				after = k < f.AfterKey || (k == f.AfterKey && d.PCBANumber < f.AfterPCBA)
+			}
+			if !after {
+				continue
+			}
+		}
+		results = append(results, d)
+		if f.Limit > 0 && len(results) == f.Limit {
+			break
+		}
+	}
+	return results, nil
+}
+
+// CountDevices returns the number of devices matching the record filters of f.
+func (r *TestStationRecordRepository) CountDevices(ctx context.Context, f db.DeviceFilter) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	return len(r.store.devices(f)), nil
+}
+
+var deviceSortKeys = map[string]func(*db.DeviceDB) string{
+	db.DeviceSortPCBANumber:  func(d *db.DeviceDB) string { return d.PCBANumber },
+	db.DeviceSortFirstTested: func(d *db.DeviceDB) string { return d.FirstTested },
+	db.DeviceSortLastTested:  func(d *db.DeviceDB) string { return d.LastTested },
+}
+
+// devices aggregates the station records matching the record filters of f by
+// PCBA number, unordered, and keeps those whose latest record has the passed
+// verdict of f. The caller must hold s.mu.
+func (s *Store) devices(f db.DeviceFilter) []*db.DeviceDB {
+	byPCBA := make(map[string]*db.DeviceDB)
+	stations := make(map[string]map[string]bool)
+	var order []*db.DeviceDB
+	// Records are kept in ID order, so the last matching record is the latest.
+	for _, rec := range s.testStationRecords {
+		if !matchesDeviceFilter(rec, f) {
+			continue
+		}
+		ld, ok := s.logisticByID(rec.LogisticDataID)
+		if !ok {
+			continue
+		}
+		d := byPCBA[ld.PCBANumber]
+		if d == nil {
+			d = &db.DeviceDB{PCBANumber: ld.PCBANumber, FirstTested: rec.TestFinishedTime, LastTested: rec.TestFinishedTime}
+			byPCBA[ld.PCBANumber] = d
+			stations[ld.PCBANumber] = make(map[string]bool)
+			order = append(order, d)
+		}
+		d.PartNumber = rec.PartNumber
+		d.ProductLine = rec.ProductLine
+		d.TestToolVersion = rec.TestToolVersion
+		d.LastPassed = rec.IsAllPassed
+		if rec.TestFinishedTime < d.FirstTested {
+			d.FirstTested = rec.TestFinishedTime
+		}
+		if rec.TestFinishedTime > d.LastTested {
+			d.LastTested = rec.TestFinishedTime
+		}
+		d.RecordCount++
+		stations[ld.PCBANumber][rec.TestStation] = true
+	}
+
+	if f.Passed != nil {
+		kept := order[:0]
+		for _, d := range order {
+			if d.LastPassed == *f.Passed {
+				kept = append(kept, d)
+			}
+		}
+		order = kept
+	}
+
+	for _, d := range order {
+		var types []string
+		for t := range stations[d.PCBANumber] {
+			types = append(types, t)
+		}
+		sort.Strings(types)
+		d.Stations = strings.Join(types, ",")
+	}
+	return order
+}
+
+func matchesDeviceFilter(rec db.TestStationRecordDB, f db.DeviceFilter) bool {
+	switch {
+	case f.StationType != "" && rec.TestStation != f.StationType,
+		f.PartNumber != "" && rec.PartNumber != f.PartNumber,
+		f.ProductLine != "" && rec.ProductLine != f.ProductLine,
+		f.TestToolVersion != "" && rec.TestToolVersion != f.TestToolVersion,
+		f.TestedFrom != "" && rec.TestFinishedTime < f.TestedFrom,
+		f.TestedTo != "" && rec.TestFinishedTime > f.TestedTo:
+		return false
+	}
+	return true
+}
+
+// GetByID returns the TestStationRecordDB with the given ID.
+//
+// Returns (nil, nil) if no record is found.
+func (r *TestStationRecordRepository) GetByID(ctx context.Context, id int) (*db.TestStationRecordDB, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	rec, ok := r.store.stationByID(id)
+	if !ok {
+		return nil, nil
+	}
+	return &rec, nil
+}
+
+// Ensure TestStationRecordRepository implements the repositories.TestStationRecordRepository interface.
+var _ repositories.TestStationRecordRepository = (*TestStationRecordRepository)(nil)
@@ -0,0 +1,103 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/NoroSaroyan/log-parser/internal/domain/models/db"
+	"github.com/NoroSaroyan/log-parser/internal/domain/repositories"
+)
+
+// TestStepRepository is an in-memory implementation of
+// repositories.TestStepRepository backed by a Store.
+type TestStepRepository struct {
+	store *Store
+}
+
+// NewTestStepRepository creates a TestStepRepository on top of the given Store.
+func NewTestStepRepository(store *Store) *TestStepRepository {
+	return &TestStepRepository{store: store}
+}
+
+// InsertBatch stores all steps linked to testStationRecordID atomically.
+//
+// As in the Postgres transaction, either every step is stored or none is.
+// Every step is linked to its error codes and gets its generated ID.
+// The test_station_record_id must reference an existing station record.
+func (r *TestStepRepository) InsertBatch(ctx context.Context, steps []*db.TestStepDB, testStationRecordID int) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if _, ok := r.store.stationByID(testStationRecordID); !ok {
+		return fmt.Errorf("test_station_record_id %d does not exist", testStationRecordID)
+	}
+
+	for _, step := range steps {
+		row := *step
+		row.ID = r.store.nextTestStepID
+		row.TestStationRecordID = testStationRecordID
+		row.Codes = nil
+		r.store.nextTestStepID++
+		r.store.testSteps = append(r.store.testSteps, row)
+		r.store.stepErrorCodes = r.store.linkErrorCodes(r.store.stepErrorCodes, row.ID, step.Codes)
+
+		step.ID = row.ID
+		step.TestStationRecordID = testStationRecordID
+	}
+	return nil
+}
+
+// GetByTestStationRecordID returns all TestStepDB records linked to the given station record.
+func (r *TestStepRepository) GetByTestStationRecordID(ctx context.Context, recordID int) ([]*db.TestStepDB, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	var results []*db.TestStepDB
+	for _, s := range r.store.testSteps {
+		if s.TestStationRecordID == recordID {
+			out := selectTestStep(s)
+			results = append(results, &out)
+		}
+	}
+	return results, nil
+}
+
+// GetByPartNumber returns all TestStepDB records whose station record has the given part number.
+func (r *TestStepRepository) GetByPartNumber(ctx context.Context, partNumber string) ([]*db.TestStepDB, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	var results []*db.TestStepDB
+	for _, s := range r.store.testSteps {
+		rec, ok := r.store.stationByID(s.TestStationRecordID)
+		if !ok || rec.PartNumber != partNumber {
+			continue
+		}
+		out := selectTestStep(s)
+		results = append(results, &out)
+	}
+	return results, nil
+}
+
+// selectTestStep returns the columns read by the Postgres SELECT statements,
+// which include neither id nor test_station_record_id.
+func selectTestStep(s db.TestStepDB) db.TestStepDB {
+	s.ID = 0
+	s.TestStationRecordID = 0
+	return s
+}
+
+// Ensure TestStepRepository implements the repositories.TestStepRepository interface.
+var _ repositories.TestStepRepository = (*TestStepRepository)(nil)
@@ -0,0 +1,96 @@
+package memory
+
+import (
+	"context"
+	"time"
+
+	"github.com/NoroSaroyan/log-parser/internal/domain/models/db"
+	"github.com/NoroSaroyan/log-parser/internal/domain/repositories"
+)
+
+// ValidationRepository is an in-memory implementation of
+// repositories.ValidationRepository backed by a Store.
+type ValidationRepository struct {
+	store *Store
+}
+
+// NewValidationRepository creates a ValidationRepository on top of the given Store.
+func NewValidationRepository(store *Store) *ValidationRepository {
+	return &ValidationRepository{store: store}
+}
+
+// InsertFindings stores copies of all findings and writes the generated IDs back.
+func (r *ValidationRepository) InsertFindings(ctx context.Context, findings []*db.ValidationFindingDB) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	now := time.Now()
+	for _, f := range findings {
+		f.ID = r.store.nextValidationID
+		f.CreatedAt = now
+		r.store.nextValidationID++
+		r.store.validationFindings = append(r.store.validationFindings, *f)
+	}
+	return nil
+}
+
+// InsertSummary stores a copy of the summary and writes the generated ID back.
+func (r *ValidationRepository) InsertSummary(ctx context.Context, summary *db.ValidationSummaryDB) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	summary.ID = r.store.nextValidationID
+	summary.CreatedAt = time.Now()
+	r.store.nextValidationID++
+	r.store.validationSummary = append(r.store.validationSummary, *summary)
+	return nil
+}
+
+// GetFindingsByPCBANumber returns all findings recorded for a PCBA number, oldest first.
+func (r *ValidationRepository) GetFindingsByPCBANumber(ctx context.Context, pcba string) ([]*db.ValidationFindingDB, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	var results []*db.ValidationFindingDB
+	for _, f := range r.store.validationFindings {
+		if f.PCBANumber == pcba {
+			out := f
+			results = append(results, &out)
+		}
+	}
+	return results, nil
+}
+
+// GetSummariesBySourceFile returns every summary recorded for a file, oldest first.
+func (r *ValidationRepository) GetSummariesBySourceFile(ctx context.Context, sourceFile string) ([]*db.ValidationSummaryDB, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	var results []*db.ValidationSummaryDB
+	for _, s := range r.store.validationSummary {
+		if s.SourceFile == sourceFile {
+			out := s
+			results = append(results, &out)
+		}
+	}
+	return results, nil
+}
+
+// Ensure ValidationRepository implements the repositories.ValidationRepository interface.
+var _ repositories.ValidationRepository = (*ValidationRepository)(nil)
@@ -0,0 +1,150 @@
+/*
+Package memory provides thread-safe in-memory implementations of the
+repository interfaces declared in internal/domain/repositories.
+
+The implementations mirror the behaviour of the PostgreSQL repositories in
+internal/infrastructure/database/repositories as closely as is practical:
+
+  - Inserts into logistic_data, test_station_record and test_step assign
+    sequential IDs (SERIAL semantics) and write them back into the model.
+  - Lookups by PCBA number for station records join through logistic_data,
+    exactly like the SQL JOIN in the Postgres implementation.
+  - Read methods only populate the columns that the corresponding SQL
+    SELECT returns (e.g. LogisticDataDB.ID is left zero by GetByPCBANumber).
+  - "Not found" is reported the same way as in Postgres: (nil, nil) for
+    single-row getters and sql.ErrNoRows from GetIDByPCBANumber.
+
+All repositories built from the same Store share one set of tables, so a
+Store behaves like a single database. This makes it possible to run the
+services, dispatcher and HTTP handlers end to end without Postgres, which is
+used by the integration tests and by dry-run modes of the CLI.
+
+Usage:
+
+	store := memory.NewStore()
+	logisticRepo := memory.NewLogisticDataRepository(store, nil)
+	stationRepo := memory.NewTestStationRecordRepository(store)
+*/
+package memory
+
+import (
+	"sync"
+
+	"github.com/NoroSaroyan/log-parser/internal/domain/models/db"
+)
+
+// Store holds the in-memory tables shared by all repositories created from it.
+//
+// Rows are kept in insertion order, which is also the order in which
+// Postgres returns them for the unordered queries used by the repositories.
+type Store struct {
+	mu sync.RWMutex
+
+	downloadInfo       []db.DownloadInfoDB
+	logisticData       []db.LogisticDataDB
+	testStationRecords []db.TestStationRecordDB
+	testSteps          []db.TestStepDB
+	validationFindings []db.ValidationFindingDB
+	validationSummary  []db.ValidationSummaryDB
+	logisticConflicts  []db.LogisticConflictDB
+	errorCodes         []db.ErrorCodeDB
+	recordErrorCodes   []errorCodeLink // test_station_record_error_code
+	stepErrorCodes     []errorCodeLink // test_step_error_code
+	apiKeys            []db.APIKeyDB
+
+	nextDownloadInfoID      int
+	nextLogisticDataID      int
+	nextTestStationRecordID int
+	nextTestStepID          int
+	nextValidationID        int
+	nextLogisticConflictID  int
+	nextErrorCodeID         int
+	nextAPIKeyID            int
+}
+
+// errorCodeLink is a row of a link table between error_code and the station
+// record or test step with ID ownerID.
+type errorCodeLink struct {
+	ownerID int
+	codeID  int
+}
+
+// NewStore creates an empty Store.
+func NewStore() *Store {
+	return &Store{
+		nextDownloadInfoID:      1,
+		nextLogisticDataID:      1,
+		nextTestStationRecordID: 1,
+		nextTestStepID:          1,
+		nextValidationID:        1,
+		nextLogisticConflictID:  1,
+		nextErrorCodeID:         1,
+		nextAPIKeyID:            1,
+	}
+}
+
+// Counts returns the number of rows currently stored in each table, keyed by
+// table name. It is intended for tests and dry-run reporting.
+func (s *Store) Counts() map[string]int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return map[string]int{
+		"download_info":       len(s.downloadInfo),
+		"logistic_data":       len(s.logisticData),
+		"test_station_record": len(s.testStationRecords),
+		"test_step":           len(s.testSteps),
+		"validation_finding":  len(s.validationFindings),
+		"validation_summary":  len(s.validationSummary),
+		"logistic_conflict":   len(s.logisticConflicts),
+		"error_code":          len(s.errorCodes),
+		"api_key":             len(s.apiKeys),
+
+		"test_station_record_error_code": len(s.recordErrorCodes),
+		"test_step_error_code":           len(s.stepErrorCodes),
+	}
+}
+
+// logisticByID returns the logistic_data row with the given ID.
+// The caller must hold s.mu.
+func (s *Store) logisticByID(id int) (db.LogisticDataDB, bool) {
+	for _, d := range s.logisticData {
+		if d.ID == id {
+			return d, true
+		}
+	}
+	return db.LogisticDataDB{}, false
+}
+
+// stationByID returns the test_station_record row with the given ID.
+// The caller must hold s.mu.
+func (s *Store) stationByID(id int) (db.TestStationRecordDB, bool) {
+	for _, rec := range s.testStationRecords {
+		if rec.ID == id {
+			return rec, true
+		}
+	}
+	return db.TestStationRecordDB{}, false
+}
+
+// linkErrorCodes stores codes in errorCodes, if they are new, and appends a
+// link to ownerID for each of them to links, like the upsert in Postgres.
+// The caller must hold s.mu for writing.
+func (s *Store) linkErrorCodes(links []errorCodeLink, ownerID int, codes []string) []errorCodeLink {
+	for _, code := range codes {
+		codeID := 0
+		for _, ec := range s.errorCodes {
+			if ec.Code == code {
+				codeID = ec.ID
+				break
+			}
+		}
+		if codeID == 0 {
+			codeID = s.nextErrorCodeID
+			s.nextErrorCodeID++
+			s.errorCodes = append(s.errorCodes, db.ErrorCodeDB{ID: codeID, Code: code})
+		}
+		links = append(links, errorCodeLink{ownerID: ownerID, codeID: codeID})
+	}
+	return links
+}
@@ -0,0 +1,4 @@
+-- Drop validation findings and per-file summaries
+
+DROP TABLE IF EXISTS validation_summary;
+DROP TABLE IF EXISTS validation_finding;
@@ -0,0 +1,36 @@
+-- Validation findings and per-file summaries
+-- Written by the validation step that runs between grouping and dispatch
+
+CREATE TABLE validation_finding
+(
+    id           SERIAL PRIMARY KEY,
+    source_file  TEXT        NOT NULL,
+    pcba_number  TEXT        NOT NULL,
+    record_type  TEXT        NOT NULL,
+    record_index INTEGER     NOT NULL,
+    station_type TEXT,
+    rule         TEXT        NOT NULL,
+    severity     TEXT        NOT NULL CHECK (severity IN ('reject', 'warn', 'annotate')),
+    field        TEXT,
+    value        TEXT,
+    message      TEXT,
+    created_at   TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX idx_validation_finding_pcba_number ON validation_finding (pcba_number);
+CREATE INDEX idx_validation_finding_source_file ON validation_finding (source_file);
+
+CREATE TABLE validation_summary
+(
+    id                   SERIAL PRIMARY KEY,
+    source_file          TEXT        NOT NULL,
+    groups_total         INTEGER     NOT NULL,
+    groups_rejected      INTEGER     NOT NULL,
+    groups_with_findings INTEGER     NOT NULL,
+    reject_findings      INTEGER     NOT NULL,
+    warn_findings        INTEGER     NOT NULL,
+    annotate_findings    INTEGER     NOT NULL,
+    created_at           TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX idx_validation_summary_source_file ON validation_summary (source_file);
@@ -0,0 +1,5 @@
+-- Drop recomputed test step verdicts
+
+DROP INDEX IF EXISTS idx_test_step_verdict_mismatch;
+ALTER TABLE test_step DROP COLUMN IF EXISTS verdict_mismatch;
+ALTER TABLE test_step DROP COLUMN IF EXISTS recomputed_result;
@@ -0,0 +1,11 @@
+-- Recomputed test step verdicts
+-- The verdict recomputed from test_threshold_value and test_measured_value,
+-- and whether it disagrees with the test_step_result logged by the tester
+
+ALTER TABLE test_step ADD COLUMN IF NOT EXISTS recomputed_result TEXT;
+ALTER TABLE test_step ADD COLUMN IF NOT EXISTS verdict_mismatch BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- Mismatches are rare, so a partial index keeps the filter cheap
+CREATE INDEX IF NOT EXISTS idx_test_step_verdict_mismatch
+    ON test_step (test_station_record_id)
+    WHERE verdict_mismatch;
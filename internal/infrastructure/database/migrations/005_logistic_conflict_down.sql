@@ -0,0 +1,3 @@
+-- Drop cross-station LogisticData conflicts
+
+DROP TABLE IF EXISTS logistic_conflict;
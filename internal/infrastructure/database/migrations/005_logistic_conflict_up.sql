@@ -0,0 +1,19 @@
+-- Cross-station LogisticData conflicts
+-- One row per field that differs between the PCBA and the Final snapshot of a device
+
+CREATE TABLE logistic_conflict
+(
+    id                     SERIAL PRIMARY KEY,
+    pcba_number            TEXT        NOT NULL,
+    field                  TEXT        NOT NULL,
+    pcba_value             TEXT,
+    final_value            TEXT,
+    kind                   TEXT        NOT NULL,
+    classification         TEXT        NOT NULL CHECK (classification IN ('expected', 'suspicious')),
+    pcba_logistic_data_id  INTEGER     NOT NULL REFERENCES logistic_data (id) ON DELETE CASCADE,
+    final_logistic_data_id INTEGER     NOT NULL REFERENCES logistic_data (id) ON DELETE CASCADE,
+    detected_at            TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX idx_logistic_conflict_pcba_number ON logistic_conflict (pcba_number);
+CREATE INDEX idx_logistic_conflict_classification ON logistic_conflict (classification, pcba_number);
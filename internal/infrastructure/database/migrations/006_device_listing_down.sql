@@ -0,0 +1,6 @@
+-- Drop the /devices listing indexes
+
+DROP INDEX IF EXISTS idx_logistic_data_pcba_number;
+DROP INDEX IF EXISTS idx_test_station_record_part_number;
+DROP INDEX IF EXISTS idx_test_station_record_station_finished;
+DROP INDEX IF EXISTS idx_test_station_record_logistic_data_id;
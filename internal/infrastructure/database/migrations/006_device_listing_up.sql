@@ -0,0 +1,7 @@
+-- Indexes backing the /devices listing
+-- The listing joins every station record to its logistic_data row and filters on station columns
+
+CREATE INDEX IF NOT EXISTS idx_test_station_record_logistic_data_id ON test_station_record (logistic_data_id);
+CREATE INDEX IF NOT EXISTS idx_test_station_record_station_finished ON test_station_record (test_station, test_finished_time);
+CREATE INDEX IF NOT EXISTS idx_test_station_record_part_number ON test_station_record (part_number);
+CREATE INDEX IF NOT EXISTS idx_logistic_data_pcba_number ON logistic_data (pcba_number);
@@ -0,0 +1,8 @@
+-- Drop the secondary identifier indexes
+
+DROP INDEX IF EXISTS idx_logistic_data_product_sn;
+DROP INDEX IF EXISTS idx_logistic_data_ble_sn;
+DROP INDEX IF EXISTS idx_logistic_data_ble_mac_normalized;
+DROP INDEX IF EXISTS idx_logistic_data_tcu_iccid;
+DROP INDEX IF EXISTS idx_logistic_data_imsi;
+DROP INDEX IF EXISTS idx_logistic_data_imei;
@@ -0,0 +1,10 @@
+-- Indexes for looking devices up by secondary identifiers (/devices/search)
+-- BLE MAC addresses are logged with and without separators; the lookup matches them in upper case without them
+
+CREATE INDEX IF NOT EXISTS idx_logistic_data_imei ON logistic_data (imei);
+CREATE INDEX IF NOT EXISTS idx_logistic_data_imsi ON logistic_data (imsi);
+CREATE INDEX IF NOT EXISTS idx_logistic_data_tcu_iccid ON logistic_data (tcu_iccid);
+CREATE INDEX IF NOT EXISTS idx_logistic_data_ble_mac_normalized
+    ON logistic_data (upper(replace(replace(ble_mac, ':', ''), '-', '')));
+CREATE INDEX IF NOT EXISTS idx_logistic_data_ble_sn ON logistic_data (ble_sn);
+CREATE INDEX IF NOT EXISTS idx_logistic_data_product_sn ON logistic_data (product_sn);
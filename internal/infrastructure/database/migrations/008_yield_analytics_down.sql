@@ -0,0 +1,4 @@
+-- Drop the /analytics/yield indexes
+
+DROP INDEX IF EXISTS idx_test_station_record_product_line;
+DROP INDEX IF EXISTS idx_test_station_record_yield;
@@ -0,0 +1,8 @@
+-- Indexes backing /analytics/yield
+-- The yield query numbers the sessions of every device per station type in time order and aggregates
+-- the first ones; covering the grouped columns lets it run on index-only scans of test_station_record
+
+CREATE INDEX IF NOT EXISTS idx_test_station_record_yield
+    ON test_station_record (test_station, logistic_data_id, test_finished_time, id)
+    INCLUDE (is_all_passed, part_number, product_line, test_tool_version);
+CREATE INDEX IF NOT EXISTS idx_test_station_record_product_line ON test_station_record (product_line);
@@ -0,0 +1,4 @@
+-- Drop the numeric measured values of test steps
+
+DROP INDEX IF EXISTS idx_test_step_name_numeric;
+ALTER TABLE test_step DROP COLUMN IF EXISTS measured_numeric;
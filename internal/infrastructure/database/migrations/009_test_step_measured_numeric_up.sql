@@ -0,0 +1,71 @@
+-- Numeric measured values of test steps
+-- test_measured_value is free text; measured_numeric holds it as a number when it is one,
+-- so the step statistics endpoint can aggregate it in SQL
+
+ALTER TABLE test_step ADD COLUMN IF NOT EXISTS measured_numeric DOUBLE PRECISION;
+
+-- Backfill the existing rows with the rules of threshold.Numeric. The length and exponent
+-- limits keep the cast within DOUBLE PRECISION; longer values stay NULL, as they do for new
+-- rows that do not parse.
+-- Only steps with numeric limits (a range, a comparison or a number) are measurements:
+-- identifiers such as the IMEI are checked against text or a pattern, or not at all.
+-- The threshold is split like threshold.Parse does, and every bound it states must be a
+-- number, so malformed limits such as "[OK,NG]", "[1,2,3]", ">=OK" or "abc~def" are not
+-- numeric limits either.
+WITH step AS (
+    SELECT id, test_threshold_value AS raw,
+           regexp_replace(test_threshold_value, '^\s+|\s+$', '', 'g') AS t
+    FROM test_step
+    WHERE measured_numeric IS NULL
+      AND length(test_measured_value) < 64
+      AND test_measured_value ~ '^\s*[-+]?([0-9]+\.?[0-9]*|\.[0-9]+)([eE][-+]?[0-9]{1,2})?\s*$'
+),
+-- kind is range, comparison or exact for thresholds that may state numeric limits, with
+-- the bounds in lo and hi (the operand of a comparison and the value of an exact match
+-- in lo), and NULL for regular expressions and bracketed ranges without exactly two bounds.
+limits AS (
+    SELECT id,
+           CASE
+               WHEN t ~ '^[\[(].*,.*[\])]$' THEN CASE WHEN t ~ '^.[^,]*,[^,]*.$' THEN 'range' END
+               WHEN t ~ '^/.*/$' THEN NULL
+               WHEN t ~ '^(>=|<=|==|!=|>|<)' THEN 'comparison'
+               WHEN strpos(t, '~') > 0 THEN 'range'
+               ELSE 'exact'
+           END AS kind,
+           CASE
+               WHEN t ~ '^[\[(].*,.*[\])]$' THEN split_part(substr(t, 2, length(t) - 2), ',', 1)
+               WHEN t ~ '^/.*/$' THEN NULL
+               WHEN t ~ '^(>=|<=|==|!=)' THEN substr(t, 3)
+               WHEN t ~ '^(>|<)' THEN substr(t, 2)
+               WHEN strpos(t, '~') > 0 THEN split_part(t, '~', 1)
+               ELSE raw
+           END AS lo,
+           CASE
+               WHEN t ~ '^[\[(].*,.*[\])]$' THEN split_part(substr(t, 2, length(t) - 2), ',', 2)
+               WHEN t ~ '^/.*/$' OR t ~ '^(>=|<=|==|!=|>|<)' THEN NULL
+               WHEN strpos(t, '~') > 0 THEN substr(t, strpos(t, '~') + 1)
+           END AS hi
+    FROM step
+)
+UPDATE test_step s
+SET measured_numeric = btrim(s.test_measured_value)::double precision
+FROM limits l
+WHERE s.id = l.id
+  AND CASE
+          WHEN l.kind IN ('comparison', 'exact') THEN length(l.lo) < 64 AND l.lo ~ '^\s*[-+]?([0-9]+\.?[0-9]*|\.[0-9]+)([eE][-+]?[0-9]{1,2})?\s*$'
+          -- A range bound may be empty (unbounded); the minimum may not be above the maximum.
+          WHEN l.kind = 'range' THEN
+              CASE
+                  WHEN NOT (l.lo ~ '^\s*$' OR (length(l.lo) < 64 AND l.lo ~ '^\s*[-+]?([0-9]+\.?[0-9]*|\.[0-9]+)([eE][-+]?[0-9]{1,2})?\s*$'))
+                      OR NOT (l.hi ~ '^\s*$' OR (length(l.hi) < 64 AND l.hi ~ '^\s*[-+]?([0-9]+\.?[0-9]*|\.[0-9]+)([eE][-+]?[0-9]{1,2})?\s*$')) THEN FALSE
+                  WHEN l.lo ~ '^\s*$' OR l.hi ~ '^\s*$' THEN TRUE
+                  ELSE l.lo::double precision <= l.hi::double precision
+              END
+          ELSE FALSE
+      END;
+
+-- Statistics select one step name and only its numeric values
+CREATE INDEX IF NOT EXISTS idx_test_step_name_numeric
+    ON test_step (test_step_name)
+    INCLUDE (measured_numeric, test_station_record_id)
+    WHERE measured_numeric IS NOT NULL;
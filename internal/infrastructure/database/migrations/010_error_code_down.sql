@@ -0,0 +1,5 @@
+-- Drop the normalized error codes
+
+DROP TABLE IF EXISTS test_step_error_code;
+DROP TABLE IF EXISTS test_station_record_error_code;
+DROP TABLE IF EXISTS error_code;
@@ -0,0 +1,50 @@
+-- Normalized error codes
+-- test_station_record.error_codes and test_step.test_step_error_code are free text that may hold
+-- several codes; each code is stored once in error_code and linked to the records and steps carrying it
+
+CREATE TABLE IF NOT EXISTS error_code
+(
+    id   SERIAL PRIMARY KEY,
+    code TEXT NOT NULL UNIQUE
+);
+
+CREATE TABLE IF NOT EXISTS test_station_record_error_code
+(
+    test_station_record_id INTEGER NOT NULL REFERENCES test_station_record (id) ON DELETE CASCADE,
+    error_code_id          INTEGER NOT NULL REFERENCES error_code (id),
+    PRIMARY KEY (test_station_record_id, error_code_id)
+);
+
+CREATE TABLE IF NOT EXISTS test_step_error_code
+(
+    test_step_id  INTEGER NOT NULL REFERENCES test_step (id) ON DELETE CASCADE,
+    error_code_id INTEGER NOT NULL REFERENCES error_code (id),
+    PRIMARY KEY (test_step_id, error_code_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_test_station_record_error_code_code ON test_station_record_error_code (error_code_id);
+CREATE INDEX IF NOT EXISTS idx_test_step_error_code_code ON test_step_error_code (error_code_id);
+
+-- Backfill existing rows with the rules of the errorcode package: split on commas, semicolons,
+-- pipes and whitespace, upper-case, drop "no error" placeholders. The statements are idempotent.
+INSERT INTO error_code (code)
+SELECT DISTINCT upper(c)
+FROM (SELECT regexp_split_to_table(error_codes, '[\s,;|]+') AS c FROM test_station_record
+      UNION ALL
+      SELECT regexp_split_to_table(test_step_error_code, '[\s,;|]+') FROM test_step) s
+WHERE c <> '' AND upper(c) NOT IN ('0', 'N/A', 'NA', 'NONE', 'NULL', '-')
+ON CONFLICT (code) DO NOTHING;
+
+INSERT INTO test_station_record_error_code (test_station_record_id, error_code_id)
+SELECT DISTINCT tsr.id, ec.id
+FROM test_station_record tsr
+CROSS JOIN LATERAL regexp_split_to_table(tsr.error_codes, '[\s,;|]+') AS c
+JOIN error_code ec ON ec.code = upper(c)
+ON CONFLICT DO NOTHING;
+
+INSERT INTO test_step_error_code (test_step_id, error_code_id)
+SELECT DISTINCT ts.id, ec.id
+FROM test_step ts
+CROSS JOIN LATERAL regexp_split_to_table(ts.test_step_error_code, '[\s,;|]+') AS c
+JOIN error_code ec ON ec.code = upper(c)
+ON CONFLICT DO NOTHING;
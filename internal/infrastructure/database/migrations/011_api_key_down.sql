@@ -0,0 +1,3 @@
+-- Drop the API keys
+
+DROP TABLE IF EXISTS api_key;
@@ -0,0 +1,14 @@
+-- API keys of the REST API
+-- Only the SHA-256 hash of a key is stored; the key itself is shown once
+-- when it is created with the CLI "apikey" mode
+
+CREATE TABLE IF NOT EXISTS api_key (
+    id           SERIAL PRIMARY KEY,
+    name         TEXT NOT NULL UNIQUE,
+    prefix       TEXT NOT NULL,
+    key_hash     TEXT NOT NULL UNIQUE,
+    role         TEXT NOT NULL CHECK (role IN ('viewer', 'engineer', 'admin')),
+    created_at   TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    last_used_at TIMESTAMPTZ,
+    revoked_at   TIMESTAMPTZ
+);
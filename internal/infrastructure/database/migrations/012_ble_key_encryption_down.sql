@@ -0,0 +1,4 @@
+-- Remove the encryption note of the BLE pairing keys
+-- Encrypted values are left as they are: they can only be read with the keys.
+
+COMMENT ON COLUMN logistic_data.ble_passwork_key IS NULL;
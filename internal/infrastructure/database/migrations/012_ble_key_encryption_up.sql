@@ -0,0 +1,7 @@
+-- Encryption of the BLE pairing keys at rest
+-- The keys are encrypted by the application, which holds the encryption keys, so
+-- this migration only documents the column. Rows written before encryption was
+-- enabled stay readable in plaintext until "-mode keys rotate" encrypts them.
+
+COMMENT ON COLUMN logistic_data.ble_passwork_key IS
+    'AES-GCM envelope "enc:v1:<key id>:<wrapped data key>:<ciphertext>"; plaintext rows are encrypted by the CLI "keys rotate" mode';
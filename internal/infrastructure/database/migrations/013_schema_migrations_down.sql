@@ -0,0 +1,3 @@
+-- Drop the record of applied migrations
+
+DROP TABLE IF EXISTS schema_migrations;
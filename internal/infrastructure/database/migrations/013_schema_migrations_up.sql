@@ -0,0 +1,13 @@
+-- Applied migrations
+-- The readiness endpoint (/readyz) compares the highest version in this table with
+-- the schema version the application expects. Every migration from now on ends by
+-- inserting its own version; this one records itself and the migrations before it.
+
+CREATE TABLE IF NOT EXISTS schema_migrations (
+    version    INT PRIMARY KEY,
+    applied_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+INSERT INTO schema_migrations (version)
+SELECT generate_series(1, 13)
+ON CONFLICT (version) DO NOTHING;
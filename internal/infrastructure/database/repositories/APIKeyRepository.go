@@ -0,0 +1,115 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/NoroSaroyan/log-parser/internal/domain/models/db"
+	"github.com/NoroSaroyan/log-parser/internal/domain/repositories"
+)
+
+// apiKeyRepository stores the hashed API keys of the REST API.
+type apiKeyRepository struct {
+	db *sql.DB
+}
+
+// NewAPIKeyRepository initializes a new APIKey repository.
+func NewAPIKeyRepository(db *sql.DB) *apiKeyRepository {
+	return &apiKeyRepository{db: db}
+}
+
+const apiKeyColumns = `id, name, prefix, key_hash, role, created_at, last_used_at, revoked_at`
+
+// Insert inserts a key and populates its ID and CreatedAt.
+func (r *apiKeyRepository) Insert(ctx context.Context, key *db.APIKeyDB) error {
+	query := `
+    INSERT INTO api_key (name, prefix, key_hash, role)
+    VALUES ($1,$2,$3,$4)
+    RETURNING id, created_at
+    `
+	if err := r.db.QueryRowContext(ctx, query, key.Name, key.Prefix, key.KeyHash, key.Role).
+		Scan(&key.ID, &key.CreatedAt); err != nil {
+		return fmt.Errorf("failed to insert APIKey: %w", err)
+	}
+	return nil
+}
+
+// GetByHash returns the key with the given hash, revoked or not, or nil if
+// there is none.
+func (r *apiKeyRepository) GetByHash(ctx context.Context, keyHash string) (*db.APIKeyDB, error) {
+	query := `SELECT ` + apiKeyColumns + ` FROM api_key WHERE key_hash = $1`
+	key, err := scanAPIKey(r.db.QueryRowContext(ctx, query, keyHash))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to query APIKey: %w", err)
+	}
+	return key, nil
+}
+
+// List returns all keys ordered by name.
+func (r *apiKeyRepository) List(ctx context.Context) ([]*db.APIKeyDB, error) {
+	query := `SELECT ` + apiKeyColumns + ` FROM api_key ORDER BY name`
+	rows, err := r.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query APIKeys: %w", err)
+	}
+	defer rows.Close()
+
+	var keys []*db.APIKeyDB
+	for rows.Next() {
+		key, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan APIKey: %w", err)
+		}
+		keys = append(keys, key)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %w", err)
+	}
+	return keys, nil
+}
+
+// Revoke marks the key with the given name as revoked. It reports whether
+// an active key with that name existed.
+func (r *apiKeyRepository) Revoke(ctx context.Context, name string) (bool, error) {
+	res, err := r.db.ExecContext(ctx,
+		`UPDATE api_key SET revoked_at = NOW() WHERE name = $1 AND revoked_at IS NULL`, name)
+	if err != nil {
+		return false, fmt.Errorf("failed to revoke APIKey: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
+
+// TouchLastUsed records when the key with the given ID was last used.
+func (r *apiKeyRepository) TouchLastUsed(ctx context.Context, id int, at time.Time) error {
+	if _, err := r.db.ExecContext(ctx, `UPDATE api_key SET last_used_at = $2 WHERE id = $1`, id, at); err != nil {
+		return fmt.Errorf("failed to update APIKey: %w", err)
+	}
+	return nil
+}
+
+func scanAPIKey(row interface{ Scan(dest ...any) error }) (*db.APIKeyDB, error) {
+	var key db.APIKeyDB
+	var lastUsed, revoked sql.NullTime
+	if err := row.Scan(&key.ID, &key.Name, &key.Prefix, &key.KeyHash, &key.Role, &key.CreatedAt, &lastUsed, &revoked); err != nil {
+		return nil, err
+	}
+	if lastUsed.Valid {
+		key.LastUsedAt = &lastUsed.Time
+	}
+	if revoked.Valid {
+		key.RevokedAt = &revoked.Time
+	}
+	return &key, nil
+}
+
+var _ repositories.APIKeyRepository = (*apiKeyRepository)(nil)
@@ -0,0 +1,381 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/NoroSaroyan/log-parser/internal/domain/models/db"
+	"github.com/NoroSaroyan/log-parser/internal/domain/repositories"
+	"github.com/lib/pq"
+)
+
+// analyticsRepository runs the aggregate queries behind the analytics
+// endpoints. It has no write side; it reads the tables filled by the dispatcher.
+type analyticsRepository struct {
+	db *sql.DB
+}
+
+// NewAnalyticsRepository initializes a new Analytics repository.
+func NewAnalyticsRepository(db *sql.DB) *analyticsRepository {
+	return &analyticsRepository{db: db}
+}
+
+// yieldGroupColumns maps the yield dimensions to columns of the attempts CTE.
+var yieldGroupColumns = map[string]string{
+	db.YieldGroupStationType: "test_station",
+	db.YieldGroupPartNumber:  "part_number",
+	db.YieldGroupProductLine: "product_line",
+	db.YieldGroupToolVersion: "test_tool_version",
+}
+
+// Yield computes first-pass and final yield per bucket and group.
+//
+// Every station session is numbered per device and station type in
+// chronological order (ROW_NUMBER), and each device is flagged when any of its
+// sessions at that station passed (bool_or over the same window). The first
+// sessions then carry everything needed: bucket and group values, first-pass
+// result, eventual result and the session count of the device.
+func (r *analyticsRepository) Yield(ctx context.Context, q db.YieldQuery) ([]*db.YieldRowDB, error) {
+	switch q.Bucket {
+	case db.YieldBucketHour, db.YieldBucketDay, db.YieldBucketWeek:
+	default:
+		return nil, fmt.Errorf("unsupported yield bucket %q", q.Bucket)
+	}
+	for _, dim := range q.GroupBy {
+		if _, ok := yieldGroupColumns[dim]; !ok {
+			return nil, fmt.Errorf("unsupported yield grouping %q", dim)
+		}
+	}
+
+	var where, firstWhere []string
+	var args []any
+	add := func(conds *[]string, cond string, v any) {
+		args = append(args, v)
+		*conds = append(*conds, fmt.Sprintf(cond, len(args)))
+	}
+	if q.StationType != "" {
+		add(&where, "tsr.test_station = $%d", q.StationType)
+	}
+	if q.PartNumber != "" {
+		add(&where, "tsr.part_number = $%d", q.PartNumber)
+	}
+	if q.ProductLine != "" {
+		add(&where, "tsr.product_line = $%d", q.ProductLine)
+	}
+	firstWhere = append(firstWhere, "attempt = 1", "test_finished_time <> ''")
+	if q.From != "" {
+		add(&firstWhere, "test_finished_time >= $%d", q.From)
+	}
+	if q.To != "" {
+		add(&firstWhere, "test_finished_time <= $%d", q.To)
+	}
+
+	selectCols := []string{"bucket"}
+	groupCols := []string{"bucket"}
+	for _, dim := range []string{db.YieldGroupStationType, db.YieldGroupPartNumber, db.YieldGroupProductLine, db.YieldGroupToolVersion} {
+		if containsString(q.GroupBy, dim) {
+			selectCols = append(selectCols, yieldGroupColumns[dim])
+			groupCols = append(groupCols, yieldGroupColumns[dim])
+		} else {
+			selectCols = append(selectCols, "''")
+		}
+	}
+
+	cond := ""
+	if len(where) > 0 {
+		cond = "WHERE " + strings.Join(where, " AND ")
+	}
+	query := `
+    WITH attempts AS (
+        SELECT l.pcba_number,
+               tsr.test_station,
+               COALESCE(tsr.part_number,'')        AS part_number,
+               COALESCE(tsr.product_line,'')       AS product_line,
+               COALESCE(tsr.test_tool_version,'')  AS test_tool_version,
+               COALESCE(tsr.test_finished_time,'') AS test_finished_time,
+               COALESCE(tsr.is_all_passed,false)   AS passed,
+               ROW_NUMBER() OVER w                 AS attempt,
+               bool_or(COALESCE(tsr.is_all_passed,false)) OVER d AS ever_passed,
+               COUNT(*) OVER d                     AS sessions
+        FROM test_station_record tsr
+        JOIN logistic_data l ON tsr.logistic_data_id = l.id
+        ` + cond + `
+        WINDOW d AS (PARTITION BY l.pcba_number, tsr.test_station),
+               w AS (d ORDER BY tsr.test_finished_time, tsr.id)
+    ), firsts AS (
+        SELECT *, date_trunc('` + q.Bucket + `', to_timestamp(test_finished_time, 'YYYY-MM-DD HH24:MI:SS')::timestamp) AS bucket
+        FROM attempts
+        WHERE ` + strings.Join(firstWhere, " AND ") + `
+    )
+    SELECT ` + strings.Join(selectCols, ", ") + `,
+           COUNT(*)                               AS attempted,
+           SUM(sessions)::bigint                  AS sessions,
+           COUNT(*) FILTER (WHERE passed)         AS first_pass_passed,
+           COUNT(*) FILTER (WHERE ever_passed)    AS eventually_passed,
+           COUNT(*) FILTER (WHERE NOT ever_passed) AS failed
+    FROM firsts
+    GROUP BY ` + strings.Join(groupCols, ", ") + `
+    ORDER BY ` + strings.Join(groupCols, ", ")
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query yield: %w", err)
+	}
+	defer rows.Close()
+
+	var results []*db.YieldRowDB
+	for rows.Next() {
+		var y db.YieldRowDB
+		if err := rows.Scan(
+			&y.Bucket, &y.StationType, &y.PartNumber, &y.ProductLine, &y.TestToolVersion,
+			&y.Attempted, &y.Sessions, &y.FirstPassPassed, &y.EventuallyPassed, &y.Failed,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan yield row: %w", err)
+		}
+		results = append(results, &y)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %w", err)
+	}
+	return results, nil
+}
+
+// stepMeasurements returns a CTE named m with the numeric measured values (v)
+// and thresholds of the steps selected by q, and its arguments.
+func stepMeasurements(q db.StepStatsQuery) (string, []any) {
+	args := []any{q.StepName}
+	where := []string{"ts.test_step_name = $1", "ts.measured_numeric IS NOT NULL"}
+	add := func(cond string, v any) {
+		args = append(args, v)
+		where = append(where, fmt.Sprintf(cond, len(args)))
+	}
+	if q.StationType != "" {
+		add("tsr.test_station = $%d", q.StationType)
+	}
+	if q.PartNumber != "" {
+		add("tsr.part_number = $%d", q.PartNumber)
+	}
+	if q.TestToolVersion != "" {
+		add("tsr.test_tool_version = $%d", q.TestToolVersion)
+	}
+	if q.From != "" {
+		add("tsr.test_finished_time >= $%d", q.From)
+	}
+	if q.To != "" {
+		add("tsr.test_finished_time <= $%d", q.To)
+	}
+	cte := `
+    WITH m AS (
+        SELECT ts.measured_numeric AS v, COALESCE(ts.test_threshold_value, '') AS threshold
+        FROM test_step ts
+        JOIN test_station_record tsr ON ts.test_station_record_id = tsr.id
+        WHERE ` + strings.Join(where, " AND ") + `
+    )`
+	return cte, args
+}
+
+// StepStats computes count, range, mean, sample standard deviation and the
+// requested continuous percentiles (percentile_cont) of a step.
+func (r *analyticsRepository) StepStats(ctx context.Context, q db.StepStatsQuery) (*db.StepStatsDB, error) {
+	cte, args := stepMeasurements(q)
+	args = append(args, pq.Array(q.Percentiles))
+	query := cte + fmt.Sprintf(`
+    SELECT COUNT(*),
+           COALESCE(MIN(v), 0), COALESCE(MAX(v), 0), COALESCE(AVG(v), 0), COALESCE(stddev_samp(v), 0),
+           percentile_cont($%d::float8[]) WITHIN GROUP (ORDER BY v)
+    FROM m`, len(args))
+
+	var st db.StepStatsDB
+	var percentiles pq.Float64Array
+	if err := r.db.QueryRowContext(ctx, query, args...).Scan(
+		&st.Count, &st.Min, &st.Max, &st.Mean, &st.StdDev, &percentiles,
+	); err != nil {
+		return nil, fmt.Errorf("failed to query step statistics: %w", err)
+	}
+	st.Percentiles = percentiles
+	return &st, nil
+}
+
+// StepHistogram counts the measurements of a step in bins equal-width bins
+// between lower and upper. Values equal to upper fall into the last bin.
+func (r *analyticsRepository) StepHistogram(ctx context.Context, q db.StepStatsQuery, lower, upper float64, bins int) ([]int, error) {
+	if bins < 1 || !(lower < upper) {
+		return nil, fmt.Errorf("invalid histogram range [%v,%v] with %d bins", lower, upper, bins)
+	}
+	cte, args := stepMeasurements(q)
+	n := len(args)
+	args = append(args, lower, upper, bins)
+	query := cte + fmt.Sprintf(`
+    SELECT LEAST(width_bucket(v, $%d, $%d, $%d), $%d) AS bin, COUNT(*)
+    FROM m
+    WHERE v BETWEEN $%d AND $%d
+    GROUP BY bin`, n+1, n+2, n+3, n+3, n+1, n+2)
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query step histogram: %w", err)
+	}
+	defer rows.Close()
+
+	counts := make([]int, bins)
+	for rows.Next() {
+		var bin, count int
+		if err := rows.Scan(&bin, &count); err != nil {
+			return nil, fmt.Errorf("failed to scan histogram bin: %w", err)
+		}
+		counts[bin-1] = count
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %w", err)
+	}
+	return counts, nil
+}
+
+// StepThresholds returns the distinct thresholds of the numeric measurements
+// of a step, most frequent first.
+func (r *analyticsRepository) StepThresholds(ctx context.Context, q db.StepStatsQuery) ([]*db.StepThresholdDB, error) {
+	cte, args := stepMeasurements(q)
+	query := cte + `
+    SELECT threshold, COUNT(*)
+    FROM m
+    GROUP BY threshold
+    ORDER BY COUNT(*) DESC, threshold`
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query step thresholds: %w", err)
+	}
+	defer rows.Close()
+
+	var results []*db.StepThresholdDB
+	for rows.Next() {
+		var t db.StepThresholdDB
+		if err := rows.Scan(&t.Threshold, &t.Count); err != nil {
+			return nil, fmt.Errorf("failed to scan step threshold: %w", err)
+		}
+		results = append(results, &t)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %w", err)
+	}
+	return results, nil
+}
+
+// errorOccurrences returns a CTE named occ with one row per station record
+// and error code (record_id, code), carried by the record or one of its
+// steps, restricted to the records selected by q, and its arguments.
+func errorOccurrences(q db.ErrorParetoQuery) (string, []any) {
+	var args []any
+	where := []string{"TRUE"}
+	add := func(cond string, v any) {
+		args = append(args, v)
+		where = append(where, fmt.Sprintf(cond, len(args)))
+	}
+	if q.StationType != "" {
+		add("tsr.test_station = $%d", q.StationType)
+	}
+	if q.PartNumber != "" {
+		add("tsr.part_number = $%d", q.PartNumber)
+	}
+	if q.From != "" {
+		add("tsr.test_finished_time >= $%d", q.From)
+	}
+	if q.To != "" {
+		add("tsr.test_finished_time <= $%d", q.To)
+	}
+	cte := `
+    WITH links AS (
+        SELECT test_station_record_id AS record_id, error_code_id
+        FROM test_station_record_error_code
+        UNION
+        SELECT ts.test_station_record_id, sec.error_code_id
+        FROM test_step_error_code sec
+        JOIN test_step ts ON ts.id = sec.test_step_id
+    ), occ AS (
+        SELECT links.record_id, ec.code, l.pcba_number, tsr.test_station, COALESCE(tsr.test_finished_time, '') AS finished
+        FROM links
+        JOIN error_code ec ON ec.id = links.error_code_id
+        JOIN test_station_record tsr ON tsr.id = links.record_id
+        JOIN logistic_data l ON l.id = tsr.logistic_data_id
+        WHERE ` + strings.Join(where, " AND ") + `
+    )`
+	return cte, args
+}
+
+// ErrorPareto counts the occurrences and affected devices of every error
+// code, most frequent first.
+func (r *analyticsRepository) ErrorPareto(ctx context.Context, q db.ErrorParetoQuery) ([]*db.ErrorCodeCountDB, error) {
+	cte, args := errorOccurrences(q)
+	query := cte + `
+    SELECT code, COUNT(*) AS occurrences, COUNT(DISTINCT pcba_number) AS devices
+    FROM occ
+    GROUP BY code
+    ORDER BY occurrences DESC, devices DESC, code`
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query error pareto: %w", err)
+	}
+	defer rows.Close()
+
+	var results []*db.ErrorCodeCountDB
+	for rows.Next() {
+		var c db.ErrorCodeCountDB
+		if err := rows.Scan(&c.Code, &c.Occurrences, &c.Devices); err != nil {
+			return nil, fmt.Errorf("failed to scan error code count: %w", err)
+		}
+		results = append(results, &c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %w", err)
+	}
+	return results, nil
+}
+
+// ErrorCodeDevices returns the devices behind one error code, most affected
+// first.
+func (r *analyticsRepository) ErrorCodeDevices(ctx context.Context, q db.ErrorParetoQuery, code string) ([]*db.ErrorCodeDeviceDB, error) {
+	cte, args := errorOccurrences(q)
+	args = append(args, code)
+	query := cte + fmt.Sprintf(`
+    SELECT pcba_number, COUNT(*) AS occurrences,
+           string_agg(DISTINCT test_station, ',' ORDER BY test_station) AS stations,
+           MIN(finished) AS first_seen, MAX(finished) AS last_seen
+    FROM occ
+    WHERE code = $%d
+    GROUP BY pcba_number
+    ORDER BY occurrences DESC, pcba_number`, len(args))
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query error code devices: %w", err)
+	}
+	defer rows.Close()
+
+	var results []*db.ErrorCodeDeviceDB
+	for rows.Next() {
+		var d db.ErrorCodeDeviceDB
+		if err := rows.Scan(&d.PCBANumber, &d.Occurrences, &d.Stations, &d.FirstSeen, &d.LastSeen); err != nil {
+			return nil, fmt.Errorf("failed to scan error code device: %w", err)
+		}
+		results = append(results, &d)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %w", err)
+	}
+	return results, nil
+}
+
+func containsString(values []string, v string) bool {
+	for _, s := range values {
+		if s == v {
+			return true
+		}
+	}
+	return false
+}
+
+// Ensure analyticsRepository satisfies the AnalyticsRepository interface.
+var _ repositories.AnalyticsRepository = (*analyticsRepository)(nil)
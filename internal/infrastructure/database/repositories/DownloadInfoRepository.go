@@ -3,6 +3,7 @@ package repositories
 import (
 	"context"
 	"database/sql"
+	"fmt"
 	"github.com/NoroSaroyan/log-parser/internal/domain/models/db"
 	"github.com/NoroSaroyan/log-parser/internal/domain/repositories"
 	"log"
@@ -80,6 +81,50 @@ func (r *DownloadInfoRepository) GetByPCBANumber(ctx context.Context, pcba strin
 	return &d, nil
 }
 
+// GetAllByPCBANumber retrieves every DownloadInfoDB record of a tcu_pcba_number
+// in insertion order, i.e. the flash history of the device.
+//
+// Returns an empty slice if no record exists for the given PCBA number.
+func (r *DownloadInfoRepository) GetAllByPCBANumber(ctx context.Context, pcba string) ([]*db.DownloadInfoDB, error) {
+	query := `
+	SELECT id, test_station, flash_entity_type, tcu_pcba_number, flash_elapsed_time,
+	       tcu_entity_flash_state, part_number, product_line, download_tool_version, download_finished_time
+	FROM download_info
+	WHERE tcu_pcba_number = $1
+	ORDER BY id
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, pcba)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query DownloadInfo by PCBA number: %w", err)
+	}
+	defer rows.Close()
+
+	var results []*db.DownloadInfoDB
+	for rows.Next() {
+		var d db.DownloadInfoDB
+		if err := rows.Scan(
+			&d.ID,
+			&d.TestStation,
+			&d.FlashEntityType,
+			&d.TcuPCBANumber,
+			&d.FlashElapsedTime,
+			&d.TcuEntityFlashState,
+			&d.PartNumber,
+			&d.ProductLine,
+			&d.DownloadToolVersion,
+			&d.DownloadFinishedTime,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan DownloadInfo row: %w", err)
+		}
+		results = append(results, &d)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %w", err)
+	}
+	return results, nil
+}
+
 // GetByPartNumber retrieves a single DownloadInfoDB record by part_number.
 //
 // TODO: Implement this method.
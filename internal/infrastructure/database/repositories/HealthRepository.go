@@ -0,0 +1,47 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/lib/pq"
+
+	"github.com/NoroSaroyan/log-parser/internal/domain/repositories"
+)
+
+// healthRepository answers the readiness checks of the database.
+type healthRepository struct {
+	db *sql.DB
+}
+
+// NewHealthRepository initializes a new Health repository.
+func NewHealthRepository(db *sql.DB) *healthRepository {
+	return &healthRepository{db: db}
+}
+
+// Ping verifies that a connection to the database can be used.
+func (r *healthRepository) Ping(ctx context.Context) error {
+	if err := r.db.PingContext(ctx); err != nil {
+		return fmt.Errorf("failed to ping database: %w", err)
+	}
+	return nil
+}
+
+// SchemaVersion returns the highest applied migration, or 0 when
+// schema_migrations does not exist yet.
+func (r *healthRepository) SchemaVersion(ctx context.Context) (int, error) {
+	var version int
+	err := r.db.QueryRowContext(ctx, `SELECT COALESCE(MAX(version), 0) FROM schema_migrations`).Scan(&version)
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == "42P01" { // undefined_table
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to query schema version: %w", err)
+	}
+	return version, nil
+}
+
+var _ repositories.HealthRepository = (*healthRepository)(nil)
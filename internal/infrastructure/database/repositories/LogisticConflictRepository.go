@@ -0,0 +1,139 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/NoroSaroyan/log-parser/internal/domain/models/db"
+	"github.com/NoroSaroyan/log-parser/internal/domain/repositories"
+)
+
+// logisticConflictRepository stores the differences found between the PCBA
+// and the Final LogisticData snapshot of a device.
+type logisticConflictRepository struct {
+	db *sql.DB
+}
+
+// NewLogisticConflictRepository initializes a new LogisticConflict repository.
+func NewLogisticConflictRepository(db *sql.DB) *logisticConflictRepository {
+	return &logisticConflictRepository{db: db}
+}
+
+// ReplaceForPCBANumber deletes the conflicts stored for a PCBA number and
+// inserts the given ones in a single transaction.
+func (r *logisticConflictRepository) ReplaceForPCBANumber(ctx context.Context, pcba string, conflicts []*db.LogisticConflictDB) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM logistic_conflict WHERE pcba_number = $1`, pcba); err != nil {
+		_ = tx.Rollback()
+		return fmt.Errorf("failed to delete LogisticConflicts: %w", err)
+	}
+
+	query := `
+    INSERT INTO logistic_conflict
+    (pcba_number, field, pcba_value, final_value, kind, classification, pcba_logistic_data_id, final_logistic_data_id)
+    VALUES ($1,$2,$3,$4,$5,$6,$7,$8)
+    RETURNING id, detected_at
+    `
+	stmt, err := tx.PrepareContext(ctx, query)
+	if err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	defer stmt.Close()
+
+	for _, c := range conflicts {
+		if err := stmt.QueryRowContext(ctx,
+			pcba, c.Field, c.PCBAValue, c.FinalValue, c.Kind, c.Classification,
+			c.PCBALogisticDataID, c.FinalLogisticDataID,
+		).Scan(&c.ID, &c.DetectedAt); err != nil {
+			_ = tx.Rollback()
+			return fmt.Errorf("failed to insert LogisticConflict: %w", err)
+		}
+		c.PCBANumber = pcba
+	}
+	return tx.Commit()
+}
+
+// GetByPCBANumber returns the conflicts stored for a PCBA number.
+func (r *logisticConflictRepository) GetByPCBANumber(ctx context.Context, pcba string) ([]*db.LogisticConflictDB, error) {
+	query := `
+    SELECT id, pcba_number, field, COALESCE(pcba_value,''), COALESCE(final_value,''), kind, classification,
+           pcba_logistic_data_id, final_logistic_data_id, detected_at
+    FROM logistic_conflict
+    WHERE pcba_number = $1
+    ORDER BY id
+    `
+	return r.query(ctx, query, pcba)
+}
+
+// GetAll returns all stored conflicts ordered by PCBA number, optionally
+// restricted to one classification ("" returns every classification).
+func (r *logisticConflictRepository) GetAll(ctx context.Context, classification string) ([]*db.LogisticConflictDB, error) {
+	var results []*db.LogisticConflictDB
+	err := r.ForEach(ctx, classification, func(c *db.LogisticConflictDB) error {
+		results = append(results, c)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// ForEach calls fn with every conflict GetAll returns, in the same order, as
+// the rows are read, so callers can stream them without holding the whole
+// result. It stops at, and returns, the first error of fn.
+func (r *logisticConflictRepository) ForEach(ctx context.Context, classification string, fn func(c *db.LogisticConflictDB) error) error {
+	query := `
+    SELECT id, pcba_number, field, COALESCE(pcba_value,''), COALESCE(final_value,''), kind, classification,
+           pcba_logistic_data_id, final_logistic_data_id, detected_at
+    FROM logistic_conflict
+    WHERE $1 = '' OR classification = $1
+    ORDER BY pcba_number, id
+    `
+	return r.each(ctx, query, classification, fn)
+}
+
+func (r *logisticConflictRepository) query(ctx context.Context, query string, arg string) ([]*db.LogisticConflictDB, error) {
+	var results []*db.LogisticConflictDB
+	err := r.each(ctx, query, arg, func(c *db.LogisticConflictDB) error {
+		results = append(results, c)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+func (r *logisticConflictRepository) each(ctx context.Context, query string, arg string, fn func(c *db.LogisticConflictDB) error) error {
+	rows, err := r.db.QueryContext(ctx, query, arg)
+	if err != nil {
+		return fmt.Errorf("failed to query LogisticConflicts: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var c db.LogisticConflictDB
+		if err := rows.Scan(
+			&c.ID, &c.PCBANumber, &c.Field, &c.PCBAValue, &c.FinalValue, &c.Kind, &c.Classification,
+			&c.PCBALogisticDataID, &c.FinalLogisticDataID, &c.DetectedAt,
+		); err != nil {
+			return fmt.Errorf("failed to scan LogisticConflict row: %w", err)
+		}
+		if err := fn(&c); err != nil {
+			return err
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("row iteration error: %w", err)
+	}
+	return nil
+}
+
+// Ensure logisticConflictRepository satisfies the LogisticConflictRepository interface.
+var _ repositories.LogisticConflictRepository = (*logisticConflictRepository)(nil)
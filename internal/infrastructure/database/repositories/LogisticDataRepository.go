@@ -6,17 +6,22 @@ import (
 	"fmt"
 	"github.com/NoroSaroyan/log-parser/internal/domain/models/db"
 	"github.com/NoroSaroyan/log-parser/internal/domain/repositories"
+	"github.com/NoroSaroyan/log-parser/internal/infrastructure/encryption"
 )
 
 // logisticDataRepository provides methods to perform CRUD operations
 // on the logistic_data table.
+//
+// ble_passwork_key is encrypted with keys on insert and decrypted on every
+// read; with a nil keyring it is stored in plaintext.
 type logisticDataRepository struct {
-	db *sql.DB
+	db   *sql.DB
+	keys *encryption.Keyring
 }
 
 // NewLogisticDataRepository creates a new instance of logisticDataRepository.
-func NewLogisticDataRepository(db *sql.DB) *logisticDataRepository {
-	return &logisticDataRepository{db: db}
+func NewLogisticDataRepository(db *sql.DB, keys *encryption.Keyring) *logisticDataRepository {
+	return &logisticDataRepository{db: db, keys: keys}
 }
 
 // Insert inserts a new LogisticDataDB record into the logistic_data table.
@@ -33,15 +38,19 @@ func (r *logisticDataRepository) Insert(ctx context.Context, d *db.LogisticDataD
         ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12,$13,$14,$15,$16,$17,$18,$19,$20)
         RETURNING id
     `
+	blePassworkKey, err := r.keys.Encrypt(d.BlePassworkKey)
+	if err != nil {
+		return fmt.Errorf("failed to encrypt BlePassworkKey: %w", err)
+	}
 	params := []interface{}{
 		d.PCBANumber, d.ProductSN, d.PartNumber, d.VPAppVersion, d.VPBootLoaderVersion, d.VPCoreVersion,
 		d.SupplierHardwareVersion, d.ManufacturerHardwareVersion, d.ManufacturerSoftwareVersion,
-		d.BleMac, d.BleSN, d.BleVersion, d.BlePassworkKey, d.APAppVersion, d.APKernelVersion,
+		d.BleMac, d.BleSN, d.BleVersion, blePassworkKey, d.APAppVersion, d.APKernelVersion,
 		d.TcuICCID, d.PhoneNumber, d.IMEI, d.IMSI, d.ProductionDate,
 	}
 
 	var id int
-	err := r.db.QueryRowContext(ctx, query, params...).Scan(&id)
+	err = r.db.QueryRowContext(ctx, query, params...).Scan(&id)
 	if err != nil {
 		return fmt.Errorf("failed to insert LogisticData and retrieve ID: %w", err)
 	}
@@ -81,6 +90,9 @@ func (r *logisticDataRepository) GetByPartNumber(ctx context.Context, partNumber
 		); err != nil {
 			return nil, err
 		}
+		if err := r.decrypt(&d); err != nil {
+			return nil, err
+		}
 		results = append(results, &d)
 	}
 	if err := rows.Err(); err != nil {
@@ -118,6 +130,9 @@ func (r *logisticDataRepository) GetAllByPCBANumber(ctx context.Context, pcba st
 		); err != nil {
 			return nil, err
 		}
+		if err := r.decrypt(&d); err != nil {
+			return nil, err
+		}
 		results = append(results, &d)
 	}
 	if err := rows.Err(); err != nil {
@@ -152,6 +167,9 @@ func (r *logisticDataRepository) GetByPCBANumber(ctx context.Context, pcba strin
 		}
 		return nil, err
 	}
+	if err := r.decrypt(&d); err != nil {
+		return nil, err
+	}
 
 	return &d, nil
 }
@@ -203,9 +221,160 @@ func (r *logisticDataRepository) GetById(ctx context.Context, id int) (*db.Logis
 		}
 		return nil, err
 	}
+	if err := r.decrypt(&d); err != nil {
+		return nil, err
+	}
 
 	return &d, nil
 }
 
+// identifierColumns maps the secondary identifiers to the expression they are
+// matched on. BLE MAC addresses are logged with and without separators, so
+// they are compared in upper case without them (see migration 007).
+var identifierColumns = map[string]string{
+	db.IdentifierIMEI:      "imei",
+	db.IdentifierIMSI:      "imsi",
+	db.IdentifierICCID:     "tcu_iccid",
+	db.IdentifierBleMac:    "upper(replace(replace(ble_mac, ':', ''), '-', ''))",
+	db.IdentifierBleSN:     "ble_sn",
+	db.IdentifierProductSN: "product_sn",
+}
+
+// GetByIdentifier retrieves every LogisticDataDB record, including its ID,
+// whose secondary identifier matches value, oldest first. A BLE MAC value must
+// already be upper case without separators.
+func (r *logisticDataRepository) GetByIdentifier(ctx context.Context, identifier, value string) ([]*db.LogisticDataDB, error) {
+	column, ok := identifierColumns[identifier]
+	if !ok {
+		return nil, fmt.Errorf("unsupported identifier %q", identifier)
+	}
+	query := `
+    SELECT id, pcba_number, product_sn, part_number, vp_app_version, vp_boot_loader_version, vp_core_version,
+    supplier_hardware_version, manufacturer_hardware_version, manufacturer_software_version,
+    ble_mac, ble_sn, ble_version, ble_passwork_key, ap_app_version, ap_kernel_version,
+    tcu_iccid, phone_number, imei, imsi, production_date
+    FROM logistic_data
+    WHERE ` + column + ` = $1
+    ORDER BY id
+    `
+	rows, err := r.db.QueryContext(ctx, query, value)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query LogisticData by %s: %w", identifier, err)
+	}
+	defer rows.Close()
+
+	var results []*db.LogisticDataDB
+	for rows.Next() {
+		var d db.LogisticDataDB
+		if err := rows.Scan(
+			&d.ID, &d.PCBANumber, &d.ProductSN, &d.PartNumber, &d.VPAppVersion, &d.VPBootLoaderVersion, &d.VPCoreVersion,
+			&d.SupplierHardwareVersion, &d.ManufacturerHardwareVersion, &d.ManufacturerSoftwareVersion,
+			&d.BleMac, &d.BleSN, &d.BleVersion, &d.BlePassworkKey, &d.APAppVersion, &d.APKernelVersion,
+			&d.TcuICCID, &d.PhoneNumber, &d.IMEI, &d.IMSI, &d.ProductionDate,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan LogisticData row: %w", err)
+		}
+		if err := r.decrypt(&d); err != nil {
+			return nil, err
+		}
+		results = append(results, &d)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %w", err)
+	}
+	return results, nil
+}
+
+// decrypt replaces the stored BlePassworkKey of d with its plaintext.
+func (r *logisticDataRepository) decrypt(d *db.LogisticDataDB) error {
+	plaintext, err := r.keys.Decrypt(d.BlePassworkKey)
+	if err != nil {
+		return fmt.Errorf("failed to decrypt BlePassworkKey of PCBA %s: %w", d.PCBANumber, err)
+	}
+	d.BlePassworkKey = plaintext
+	return nil
+}
+
+// ReencryptSecrets encrypts every ble_passwork_key stored in plaintext or
+// under a key other than the active one with the active key. It walks the
+// table by ID in batches of batchSize rows, one transaction per batch, and
+// returns the number of rows updated. A row changed concurrently is skipped.
+func (r *logisticDataRepository) ReencryptSecrets(ctx context.Context, batchSize int) (int, error) {
+	if r.keys == nil {
+		return 0, fmt.Errorf("no encryption keys configured")
+	}
+	if batchSize <= 0 {
+		return 0, fmt.Errorf("batch size must be positive, got %d", batchSize)
+	}
+
+	updated, lastID := 0, 0
+	for {
+		rows, err := r.db.QueryContext(ctx, `
+			SELECT id, ble_passwork_key
+			FROM logistic_data
+			WHERE id > $1 AND ble_passwork_key <> ''
+			ORDER BY id
+			LIMIT $2
+		`, lastID, batchSize)
+		if err != nil {
+			return updated, fmt.Errorf("failed to query LogisticData secrets: %w", err)
+		}
+		type secret struct {
+			id           int
+			stored, next string
+		}
+		var batch []secret
+		n := 0
+		for rows.Next() {
+			var s secret
+			if err := rows.Scan(&s.id, &s.stored); err != nil {
+				rows.Close()
+				return updated, fmt.Errorf("failed to scan LogisticData secret: %w", err)
+			}
+			n++
+			lastID = s.id
+			next, rotate, err := r.keys.Rotate(s.stored)
+			if err != nil {
+				rows.Close()
+				return updated, fmt.Errorf("failed to re-encrypt BlePassworkKey of logistic_data %d: %w", s.id, err)
+			}
+			if rotate {
+				s.next = next
+				batch = append(batch, s)
+			}
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return updated, fmt.Errorf("row iteration error: %w", err)
+		}
+
+		if len(batch) > 0 {
+			tx, err := r.db.BeginTx(ctx, nil)
+			if err != nil {
+				return updated, fmt.Errorf("failed to begin transaction: %w", err)
+			}
+			count := 0
+			for _, s := range batch {
+				res, err := tx.ExecContext(ctx,
+					`UPDATE logistic_data SET ble_passwork_key = $1 WHERE id = $2 AND ble_passwork_key = $3`,
+					s.next, s.id, s.stored)
+				if err != nil {
+					_ = tx.Rollback()
+					return updated, fmt.Errorf("failed to update logistic_data %d: %w", s.id, err)
+				}
+				affected, _ := res.RowsAffected()
+				count += int(affected)
+			}
+			if err := tx.Commit(); err != nil {
+				return updated, fmt.Errorf("failed to commit re-encrypted secrets: %w", err)
+			}
+			updated += count
+		}
+		if n < batchSize {
+			return updated, nil
+		}
+	}
+}
+
 // Ensure logisticDataRepository satisfies the LogisticDataRepository interface.
 var _ repositories.LogisticDataRepository = (*logisticDataRepository)(nil)
@@ -5,6 +5,7 @@ import (
 	"database/sql"
 	"fmt"
 	"github.com/NoroSaroyan/log-parser/internal/domain/models/db"
+	"strings"
 )
 
 // testStationRecordRepository provides methods for accessing and manipulating
@@ -23,8 +24,9 @@ func NewTestStationRecordRepository(db *sql.DB) *testStationRecordRepository {
 
 // Insert adds a new TestStationRecordDB into the database.
 //
-// It populates the given record's ID field with the auto-generated primary key.
-// Returns an error if the insert fails or if no ID is returned.
+// It populates the given record's ID field with the auto-generated primary key
+// and links the record to its normalized error codes (rec.Codes) in the same
+// transaction. Returns an error if the insert fails or if no ID is returned.
 func (r *testStationRecordRepository) Insert(ctx context.Context, rec *db.TestStationRecordDB) error {
 	query := `
     INSERT INTO test_station_record 
@@ -32,16 +34,48 @@ func (r *testStationRecordRepository) Insert(ctx context.Context, rec *db.TestSt
     VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9)
     RETURNING id
     `
-	err := r.db.QueryRowContext(ctx, query,
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	err = tx.QueryRowContext(ctx, query,
 		rec.PartNumber, rec.TestStation, rec.EntityType, rec.ProductLine,
 		rec.TestToolVersion, rec.TestFinishedTime, rec.IsAllPassed, rec.ErrorCodes, rec.LogisticDataID,
 	).Scan(&rec.ID)
 	if err != nil {
+		_ = tx.Rollback()
 		return fmt.Errorf("failed to insert TestStationRecord and retrieve ID: %w", err)
 	}
 	if rec.ID == 0 {
+		_ = tx.Rollback()
 		return fmt.Errorf("unexpected: inserted TestStationRecord returned ID=0")
 	}
+	if err := linkErrorCodes(ctx, tx, "test_station_record_error_code", "test_station_record_id", rec.ID, rec.Codes); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// linkErrorCodes stores codes in error_code, if they are new, and links them
+// to ownerID through linkTable, whose owner column is ownerColumn.
+func linkErrorCodes(ctx context.Context, tx *sql.Tx, linkTable, ownerColumn string, ownerID int, codes []string) error {
+	// DO UPDATE instead of DO NOTHING, so RETURNING also yields existing codes.
+	upsert := `
+    INSERT INTO error_code (code) VALUES ($1)
+    ON CONFLICT (code) DO UPDATE SET code = EXCLUDED.code
+    RETURNING id
+    `
+	link := `INSERT INTO ` + linkTable + ` (` + ownerColumn + `, error_code_id) VALUES ($1, $2) ON CONFLICT DO NOTHING`
+	for _, code := range codes {
+		var codeID int
+		if err := tx.QueryRowContext(ctx, upsert, code).Scan(&codeID); err != nil {
+			return fmt.Errorf("failed to insert error code %q: %w", code, err)
+		}
+		if _, err := tx.ExecContext(ctx, link, ownerID, codeID); err != nil {
+			return fmt.Errorf("failed to link error code %q: %w", code, err)
+		}
+	}
 	return nil
 }
 
@@ -83,33 +117,182 @@ func (r *testStationRecordRepository) GetByPCBANumber(ctx context.Context, pcba
 // GetAllPCBANumbers retrieves all distinct PCBA numbers present in the database.
 //
 // This method performs a JOIN with the LogisticData table to extract unique PCBA numbers
-// associated with TestStationRecords. Returns an error if the query or row scanning fails.
-func (r *testStationRecordRepository) GetAllPCBANumbers(ctx context.Context) ([]string, error) {
+// associated with TestStationRecords of the given station type ("" for any type).
+// Returns an error if the query or row scanning fails.
+func (r *testStationRecordRepository) GetAllPCBANumbers(ctx context.Context, stationType string) ([]string, error) {
+	var pcbas []string
+	err := r.ForEachPCBANumber(ctx, stationType, func(pcba string) error {
+		pcbas = append(pcbas, pcba)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return pcbas, nil
+}
+
+// ForEachPCBANumber calls fn with every PCBA number GetAllPCBANumbers returns,
+// as the rows are read, so callers can stream them without holding the whole
+// result. It stops at, and returns, the first error of fn.
+func (r *testStationRecordRepository) ForEachPCBANumber(ctx context.Context, stationType string, fn func(pcba string) error) error {
 	query := `
 		SELECT DISTINCT l.pcba_number
 		FROM test_station_record tsr
 		JOIN logistic_data l ON tsr.logistic_data_id = l.id
+		WHERE $1 = '' OR tsr.test_station = $1
 	`
 
-	rows, err := r.db.QueryContext(ctx, query)
+	rows, err := r.db.QueryContext(ctx, query, stationType)
 	if err != nil {
-		return nil, fmt.Errorf("failed to query PCBA numbers: %w", err)
+		return fmt.Errorf("failed to query PCBA numbers: %w", err)
 	}
 	defer rows.Close()
 
-	var pcbas []string
 	for rows.Next() {
 		var pcba string
 		if err := rows.Scan(&pcba); err != nil {
-			return nil, fmt.Errorf("failed to scan PCBA number: %w", err)
+			return fmt.Errorf("failed to scan PCBA number: %w", err)
+		}
+		if err := fn(pcba); err != nil {
+			return err
 		}
-		pcbas = append(pcbas, pcba)
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("row iteration error: %w", err)
+	}
+	return nil
+}
+
+// deviceSortColumns maps the DeviceFilter sort keys to columns of the devices CTE.
+var deviceSortColumns = map[string]string{
+	db.DeviceSortPCBANumber:  "pcba_number",
+	db.DeviceSortFirstTested: "first_tested",
+	db.DeviceSortLastTested:  "last_tested",
+}
+
+// devicesCTE builds the "devices" CTE aggregating the station records that
+// match the record filters of f, together with its arguments. The passed
+// filter applies to the aggregated devices, so a device that failed and was
+// retested is listed by the verdict of its latest record.
+func devicesCTE(f db.DeviceFilter) (string, []any) {
+	var where []string
+	var args []any
+	add := func(cond string, v any) {
+		args = append(args, v)
+		where = append(where, fmt.Sprintf(cond, len(args)))
+	}
+	if f.StationType != "" {
+		add("tsr.test_station = $%d", f.StationType)
+	}
+	if f.PartNumber != "" {
+		add("tsr.part_number = $%d", f.PartNumber)
+	}
+	if f.ProductLine != "" {
+		add("tsr.product_line = $%d", f.ProductLine)
+	}
+	if f.TestToolVersion != "" {
+		add("tsr.test_tool_version = $%d", f.TestToolVersion)
+	}
+	if f.TestedFrom != "" {
+		add("tsr.test_finished_time >= $%d", f.TestedFrom)
+	}
+	if f.TestedTo != "" {
+		add("tsr.test_finished_time <= $%d", f.TestedTo)
+	}
+	cond := ""
+	if len(where) > 0 {
+		cond = "WHERE " + strings.Join(where, " AND ")
+	}
+	deviceCond := ""
+	if f.Passed != nil {
+		args = append(args, *f.Passed)
+		deviceCond = fmt.Sprintf("WHERE last_passed = $%d", len(args))
+	}
+
+	cte := `
+    WITH aggregated AS (
+        SELECT l.pcba_number,
+               (array_agg(COALESCE(tsr.part_number,'') ORDER BY tsr.id DESC))[1]       AS part_number,
+               (array_agg(COALESCE(tsr.product_line,'') ORDER BY tsr.id DESC))[1]      AS product_line,
+               (array_agg(COALESCE(tsr.test_tool_version,'') ORDER BY tsr.id DESC))[1] AS test_tool_version,
+               string_agg(DISTINCT tsr.test_station, ',' ORDER BY tsr.test_station)    AS stations,
+               (array_agg(COALESCE(tsr.is_all_passed,false) ORDER BY tsr.id DESC))[1]  AS last_passed,
+               COALESCE(MIN(tsr.test_finished_time),'')                                AS first_tested,
+               COALESCE(MAX(tsr.test_finished_time),'')                                AS last_tested,
+               COUNT(*)                                                                AS record_count
+        FROM test_station_record tsr
+        JOIN logistic_data l ON tsr.logistic_data_id = l.id
+        ` + cond + `
+        GROUP BY l.pcba_number
+    ),
+    devices AS (
+        SELECT * FROM aggregated
+        ` + deviceCond + `
+    )`
+	return cte, args
+}
+
+// ListDevices returns one page of devices matching f, ordered by f.Sort and
+// the PCBA number. Pagination is keyset based: with f.After set only devices
+// ordered after (f.AfterKey, f.AfterPCBA) are returned.
+func (r *testStationRecordRepository) ListDevices(ctx context.Context, f db.DeviceFilter) ([]*db.DeviceDB, error) {
+	col, ok := deviceSortColumns[f.Sort]
+	if !ok {
+		return nil, fmt.Errorf("unsupported device sort %q", f.Sort)
+	}
+	dir, cmp := "ASC", ">"
+	if f.Desc {
+		dir, cmp = "DESC", "<"
+	}
+
+	cte, args := devicesCTE(f)
+	query := cte + `
+    SELECT pcba_number, part_number, product_line, test_tool_version, stations,
+           last_passed, first_tested, last_tested, record_count
+    FROM devices
+    `
+	if f.After {
+		args = append(args, f.AfterKey, f.AfterPCBA)
+		query += fmt.Sprintf("WHERE (%s, pcba_number) %s ($%d, $%d)\n", col, cmp, len(args)-1, len(args))
+	}
+	query += fmt.Sprintf("ORDER BY %s %s, pcba_number %s\n", col, dir, dir)
+	if f.Limit > 0 {
+		args = append(args, f.Limit)
+		query += fmt.Sprintf("LIMIT $%d\n", len(args))
+	}
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query devices: %w", err)
+	}
+	defer rows.Close()
+
+	var results []*db.DeviceDB
+	for rows.Next() {
+		var d db.DeviceDB
+		if err := rows.Scan(
+			&d.PCBANumber, &d.PartNumber, &d.ProductLine, &d.TestToolVersion, &d.Stations,
+			&d.LastPassed, &d.FirstTested, &d.LastTested, &d.RecordCount,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan device row: %w", err)
+		}
+		results = append(results, &d)
 	}
 	if err := rows.Err(); err != nil {
 		return nil, fmt.Errorf("row iteration error: %w", err)
 	}
+	return results, nil
+}
 
-	return pcbas, nil
+// CountDevices returns the number of devices matching the record filters of f,
+// ignoring its pagination.
+func (r *testStationRecordRepository) CountDevices(ctx context.Context, f db.DeviceFilter) (int, error) {
+	cte, args := devicesCTE(f)
+	var n int
+	if err := r.db.QueryRowContext(ctx, cte+` SELECT COUNT(*) FROM devices`, args...).Scan(&n); err != nil {
+		return 0, fmt.Errorf("failed to count devices: %w", err)
+	}
+	return n, nil
 }
 
 // GetByPartNumber retrieves all TestStationRecordDB entries that match the given part number.
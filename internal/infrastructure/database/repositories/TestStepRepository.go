@@ -60,7 +60,8 @@ func NewTestStepRepository(db *sql.DB) *testStepRepository {
 
 // InsertBatch inserts multiple TestStepDB records in a single database transaction.
 //
-// Each step in the provided slice is linked to the specified testStationRecordID.
+// Each step in the provided slice is linked to the specified testStationRecordID
+// and to its normalized error codes (step.Codes), and gets its generated ID.
 // If any insertion fails, the entire transaction is rolled back.
 //
 // Parameters:
@@ -72,8 +73,10 @@ func NewTestStepRepository(db *sql.DB) *testStepRepository {
 func (r *testStepRepository) InsertBatch(ctx context.Context, steps []*db.TestStepDB, testStationRecordID int) error {
 	query := `
     INSERT INTO test_step 
-    (test_step_name, test_threshold_value, test_measured_value, test_step_elapsed_time, test_step_result, test_step_error_code, test_station_record_id)
-    VALUES ($1,$2,$3,$4,$5,$6,$7)
+    (test_step_name, test_threshold_value, test_measured_value, test_step_elapsed_time, test_step_result, test_step_error_code,
+     recomputed_result, verdict_mismatch, test_station_record_id, measured_numeric)
+    VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10)
+    RETURNING id
     `
 	tx, err := r.db.BeginTx(ctx, nil)
 	if err != nil {
@@ -87,10 +90,16 @@ func (r *testStepRepository) InsertBatch(ctx context.Context, steps []*db.TestSt
 	defer stmt.Close()
 
 	for _, step := range steps {
-		if _, err := stmt.ExecContext(ctx,
+		if err := stmt.QueryRowContext(ctx,
 			step.TestStepName, step.TestThresholdValue, step.TestMeasuredValue, step.TestStepElapsedTime,
-			step.TestStepResult, step.TestStepErrorCode, testStationRecordID,
-		); err != nil {
+			step.TestStepResult, step.TestStepErrorCode, step.RecomputedResult, step.VerdictMismatch, testStationRecordID,
+			step.MeasuredNumeric,
+		).Scan(&step.ID); err != nil {
+			_ = tx.Rollback()
+			return err
+		}
+		step.TestStationRecordID = testStationRecordID
+		if err := linkErrorCodes(ctx, tx, "test_step_error_code", "test_step_id", step.ID, step.Codes); err != nil {
 			_ = tx.Rollback()
 			return err
 		}
@@ -107,7 +116,8 @@ func (r *testStepRepository) InsertBatch(ctx context.Context, steps []*db.TestSt
 // Returns a slice of TestStepDB pointers or an error if the query or row scanning fails.
 func (r *testStepRepository) GetByTestStationRecordID(ctx context.Context, recordID int) ([]*db.TestStepDB, error) {
 	query := `
-    SELECT test_step_name, test_threshold_value, test_measured_value, test_step_elapsed_time, test_step_result, test_step_error_code
+    SELECT test_step_name, test_threshold_value, test_measured_value, test_step_elapsed_time, test_step_result, test_step_error_code,
+           COALESCE(recomputed_result, ''), verdict_mismatch
     FROM test_step
     WHERE test_station_record_id = $1
     `
@@ -124,7 +134,7 @@ func (r *testStepRepository) GetByTestStationRecordID(ctx context.Context, recor
 		var s db.TestStepDB
 		if err := rows.Scan(
 			&s.TestStepName, &s.TestThresholdValue, &s.TestMeasuredValue, &s.TestStepElapsedTime,
-			&s.TestStepResult, &s.TestStepErrorCode,
+			&s.TestStepResult, &s.TestStepErrorCode, &s.RecomputedResult, &s.VerdictMismatch,
 		); err != nil {
 			return nil, err
 		}
@@ -149,7 +159,8 @@ func (r *testStepRepository) GetByTestStationRecordID(ctx context.Context, recor
 func (r *testStepRepository) GetByPartNumber(ctx context.Context, partNumber string) ([]*db.TestStepDB, error) {
 	query := `
 	SELECT ts.test_step_name, ts.test_threshold_value, ts.test_measured_value, 
-	       ts.test_step_elapsed_time, ts.test_step_result, ts.test_step_error_code
+	       ts.test_step_elapsed_time, ts.test_step_result, ts.test_step_error_code,
+	       COALESCE(ts.recomputed_result, ''), ts.verdict_mismatch
 	FROM test_step ts
 	INNER JOIN test_station_record tsr ON ts.test_station_record_id = tsr.id
 	WHERE tsr.part_number = $1
@@ -167,7 +178,7 @@ func (r *testStepRepository) GetByPartNumber(ctx context.Context, partNumber str
 		var s db.TestStepDB
 		if err := rows.Scan(
 			&s.TestStepName, &s.TestThresholdValue, &s.TestMeasuredValue, &s.TestStepElapsedTime,
-			&s.TestStepResult, &s.TestStepErrorCode,
+			&s.TestStepResult, &s.TestStepErrorCode, &s.RecomputedResult, &s.VerdictMismatch,
 		); err != nil {
 			return nil, err
 		}
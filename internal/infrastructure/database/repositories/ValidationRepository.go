@@ -0,0 +1,138 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/NoroSaroyan/log-parser/internal/domain/models/db"
+	"github.com/NoroSaroyan/log-parser/internal/domain/repositories"
+)
+
+// validationRepository stores the findings and per-file summaries of the
+// validation step that runs between grouping and dispatch.
+type validationRepository struct {
+	db *sql.DB
+}
+
+// NewValidationRepository initializes a new Validation repository.
+func NewValidationRepository(db *sql.DB) *validationRepository {
+	return &validationRepository{db: db}
+}
+
+// InsertFindings inserts all findings in a single transaction.
+func (r *validationRepository) InsertFindings(ctx context.Context, findings []*db.ValidationFindingDB) error {
+	if len(findings) == 0 {
+		return nil
+	}
+	query := `
+    INSERT INTO validation_finding
+    (source_file, pcba_number, record_type, record_index, station_type, rule, severity, field, value, message)
+    VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10)
+    RETURNING id, created_at
+    `
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	stmt, err := tx.PrepareContext(ctx, query)
+	if err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	defer stmt.Close()
+
+	for _, f := range findings {
+		if err := stmt.QueryRowContext(ctx,
+			f.SourceFile, f.PCBANumber, f.RecordType, f.RecordIndex, f.StationType,
+			f.Rule, f.Severity, f.Field, f.Value, f.Message,
+		).Scan(&f.ID, &f.CreatedAt); err != nil {
+			_ = tx.Rollback()
+			return fmt.Errorf("failed to insert ValidationFinding: %w", err)
+		}
+	}
+	return tx.Commit()
+}
+
+// InsertSummary inserts the validation summary of one file and populates its ID.
+func (r *validationRepository) InsertSummary(ctx context.Context, s *db.ValidationSummaryDB) error {
+	query := `
+    INSERT INTO validation_summary
+    (source_file, groups_total, groups_rejected, groups_with_findings, reject_findings, warn_findings, annotate_findings)
+    VALUES ($1,$2,$3,$4,$5,$6,$7)
+    RETURNING id, created_at
+    `
+	if err := r.db.QueryRowContext(ctx, query,
+		s.SourceFile, s.GroupsTotal, s.GroupsRejected, s.GroupsWithFindings,
+		s.RejectFindings, s.WarnFindings, s.AnnotateFindings,
+	).Scan(&s.ID, &s.CreatedAt); err != nil {
+		return fmt.Errorf("failed to insert ValidationSummary: %w", err)
+	}
+	return nil
+}
+
+// GetFindingsByPCBANumber returns all findings recorded for a PCBA number, oldest first.
+func (r *validationRepository) GetFindingsByPCBANumber(ctx context.Context, pcba string) ([]*db.ValidationFindingDB, error) {
+	query := `
+    SELECT id, source_file, pcba_number, record_type, record_index, station_type, rule, severity, field, value, message, created_at
+    FROM validation_finding
+    WHERE pcba_number = $1
+    ORDER BY id
+    `
+	rows, err := r.db.QueryContext(ctx, query, pcba)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query ValidationFindings by PCBA number: %w", err)
+	}
+	defer rows.Close()
+
+	var results []*db.ValidationFindingDB
+	for rows.Next() {
+		var f db.ValidationFindingDB
+		if err := rows.Scan(
+			&f.ID, &f.SourceFile, &f.PCBANumber, &f.RecordType, &f.RecordIndex, &f.StationType,
+			&f.Rule, &f.Severity, &f.Field, &f.Value, &f.Message, &f.CreatedAt,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan ValidationFinding row: %w", err)
+		}
+		results = append(results, &f)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %w", err)
+	}
+	return results, nil
+}
+
+// GetSummariesBySourceFile returns every validation summary recorded for a file, oldest first.
+func (r *validationRepository) GetSummariesBySourceFile(ctx context.Context, sourceFile string) ([]*db.ValidationSummaryDB, error) {
+	query := `
+    SELECT id, source_file, groups_total, groups_rejected, groups_with_findings,
+           reject_findings, warn_findings, annotate_findings, created_at
+    FROM validation_summary
+    WHERE source_file = $1
+    ORDER BY id
+    `
+	rows, err := r.db.QueryContext(ctx, query, sourceFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query ValidationSummaries by source file: %w", err)
+	}
+	defer rows.Close()
+
+	var results []*db.ValidationSummaryDB
+	for rows.Next() {
+		var s db.ValidationSummaryDB
+		if err := rows.Scan(
+			&s.ID, &s.SourceFile, &s.GroupsTotal, &s.GroupsRejected, &s.GroupsWithFindings,
+			&s.RejectFindings, &s.WarnFindings, &s.AnnotateFindings, &s.CreatedAt,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan ValidationSummary row: %w", err)
+		}
+		results = append(results, &s)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %w", err)
+	}
+	return results, nil
+}
+
+// Ensure validationRepository satisfies the ValidationRepository interface.
+var _ repositories.ValidationRepository = (*validationRepository)(nil)
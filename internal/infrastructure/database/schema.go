@@ -0,0 +1,6 @@
+package database
+
+// SchemaVersion is the number of the latest migration the application needs.
+// It must be raised with every migration; the readiness check compares it
+// with the highest version recorded in schema_migrations.
+const SchemaVersion = 13
@@ -0,0 +1,279 @@
+/*
+Package encryption encrypts secrets stored in the database, such as the BLE
+pairing keys of logistic_data, with envelope encryption.
+
+Implementation notes:
+  - Every value is encrypted with its own random 256-bit data key using
+    AES-GCM. The data key is in turn encrypted ("wrapped") with the active key
+    encryption key of the Keyring, also with AES-GCM, and stored next to the
+    ciphertext. Key encryption keys never touch the database.
+  - Stored values look like "enc:v1:<key id>:<wrapped data key>:<ciphertext>"
+    with both parts base64 (raw URL) encoded, nonce first. The key ID is bound
+    to the wrapped data key as additional data, so it cannot be swapped.
+  - Keys are versioned by ID. Values are always encrypted with the active key
+    and decrypted with the key they name, so old keys stay in the keyring
+    until Rotate has re-encrypted every value under the new one.
+  - Values without the "enc:" prefix are stored before encryption was enabled
+    and are returned as they are; Rotate encrypts them.
+  - Empty values are not encrypted.
+*/
+package encryption
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/NoroSaroyan/log-parser/internal/config"
+)
+
+// KeysEnv and ActiveKeyEnv override the keys file and the active key ID of
+// the configuration.
+const (
+	KeysEnv      = "LOG_PARSER_ENCRYPTION_KEYS"
+	ActiveKeyEnv = "LOG_PARSER_ENCRYPTION_ACTIVE_KEY_ID"
+)
+
+// prefix starts every encrypted value; the version allows changing the
+// format later.
+const prefix = "enc:v1:"
+
+// KeySize is the size of key encryption keys and data keys (AES-256).
+const KeySize = 32
+
+var keyIDPattern = regexp.MustCompile(`^[A-Za-z0-9_-]{1,64}$`)
+
+var (
+	// ErrNoKeys is returned when reading an encrypted value without a keyring.
+	ErrNoKeys = errors.New("value is encrypted but no encryption keys are configured")
+	// ErrUnknownKey is returned for values encrypted with a key that is not
+	// in the keyring.
+	ErrUnknownKey = errors.New("unknown encryption key")
+	// ErrCorrupt is returned for encrypted values that are malformed or fail
+	// authentication.
+	ErrCorrupt = errors.New("corrupt encrypted value")
+)
+
+// Keyring holds the key encryption keys by ID and the ID of the active one.
+// A nil *Keyring stores values in plaintext and cannot read encrypted ones.
+type Keyring struct {
+	keys   map[string]cipher.AEAD
+	active string
+}
+
+// NewKeyring returns a keyring of keys, which must be KeySize bytes long,
+// encrypting with the key named active.
+func NewKeyring(keys map[string][]byte, active string) (*Keyring, error) {
+	k := &Keyring{keys: make(map[string]cipher.AEAD, len(keys)), active: active}
+	for id, key := range keys {
+		if !keyIDPattern.MatchString(id) {
+			return nil, fmt.Errorf("invalid encryption key ID %q: use letters, digits, '-' and '_'", id)
+		}
+		aead, err := newAEAD(key)
+		if err != nil {
+			return nil, fmt.Errorf("invalid encryption key %s: %w", id, err)
+		}
+		k.keys[id] = aead
+	}
+	if _, ok := k.keys[active]; !ok {
+		return nil, fmt.Errorf("active encryption key %q is not in the keyring", active)
+	}
+	return k, nil
+}
+
+// LoadKeyring reads the keys from the KeysEnv environment variable or, when
+// it is unset, from cfg.KeysFile. Both hold one "ID:base64 key" entry per
+// line or comma-separated. The active key is ActiveKeyEnv, cfg.ActiveKeyID or
+// else the last entry. It returns nil when no keys are configured.
+func LoadKeyring(cfg config.EncryptionConfig) (*Keyring, error) {
+	source, entries := KeysEnv, os.Getenv(KeysEnv)
+	if entries == "" && cfg.KeysFile != "" {
+		data, err := os.ReadFile(cfg.KeysFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read encryption keys: %w", err)
+		}
+		source, entries = cfg.KeysFile, string(data)
+	}
+	if strings.TrimSpace(entries) == "" {
+		return nil, nil
+	}
+
+	keys := map[string][]byte{}
+	var last string
+	for _, entry := range strings.FieldsFunc(entries, func(r rune) bool { return r == ',' || r == '\n' }) {
+		entry = strings.TrimSpace(entry)
+		if entry == "" || strings.HasPrefix(entry, "#") {
+			continue
+		}
+		id, encoded, ok := strings.Cut(entry, ":")
+		if !ok {
+			return nil, fmt.Errorf("invalid encryption key entry in %s: expected ID:base64", source)
+		}
+		key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+		if err != nil {
+			return nil, fmt.Errorf("invalid encryption key %s in %s: %w", id, source, err)
+		}
+		id = strings.TrimSpace(id)
+		keys[id] = key
+		last = id
+	}
+
+	active := os.Getenv(ActiveKeyEnv)
+	if active == "" {
+		active = cfg.ActiveKeyID
+	}
+	if active == "" {
+		active = last
+	}
+	return NewKeyring(keys, active)
+}
+
+// GenerateKey returns a random key encryption key, base64 encoded as
+// LoadKeyring expects it.
+func GenerateKey() (string, error) {
+	key := make([]byte, KeySize)
+	if _, err := rand.Read(key); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(key), nil
+}
+
+// ActiveKeyID returns the ID of the key new values are encrypted with.
+func (k *Keyring) ActiveKeyID() string {
+	if k == nil {
+		return ""
+	}
+	return k.active
+}
+
+// Encrypt returns the envelope of plaintext under the active key. Without a
+// keyring, and for empty values, it returns plaintext.
+func (k *Keyring) Encrypt(plaintext string) (string, error) {
+	if k == nil || plaintext == "" {
+		return plaintext, nil
+	}
+
+	dataKey := make([]byte, KeySize)
+	if _, err := rand.Read(dataKey); err != nil {
+		return "", fmt.Errorf("failed to generate data key: %w", err)
+	}
+	dataAEAD, err := newAEAD(dataKey)
+	if err != nil {
+		return "", err
+	}
+	ciphertext, err := seal(dataAEAD, []byte(plaintext), nil)
+	if err != nil {
+		return "", err
+	}
+	wrapped, err := seal(k.keys[k.active], dataKey, []byte(k.active))
+	if err != nil {
+		return "", err
+	}
+
+	enc := base64.RawURLEncoding
+	return prefix + k.active + ":" + enc.EncodeToString(wrapped) + ":" + enc.EncodeToString(ciphertext), nil
+}
+
+// Decrypt returns the plaintext of a value returned by Encrypt. Values that
+// are not encrypted are returned unchanged.
+func (k *Keyring) Decrypt(value string) (string, error) {
+	rest, encrypted := strings.CutPrefix(value, prefix)
+	if !encrypted {
+		return value, nil
+	}
+	if k == nil {
+		return "", ErrNoKeys
+	}
+
+	parts := strings.Split(rest, ":")
+	if len(parts) != 3 {
+		return "", ErrCorrupt
+	}
+	id := parts[0]
+	kek, ok := k.keys[id]
+	if !ok {
+		return "", fmt.Errorf("%w %q", ErrUnknownKey, id)
+	}
+	enc := base64.RawURLEncoding
+	wrapped, err1 := enc.DecodeString(parts[1])
+	ciphertext, err2 := enc.DecodeString(parts[2])
+	if err1 != nil || err2 != nil {
+		return "", ErrCorrupt
+	}
+
+	dataKey, err := open(kek, wrapped, []byte(id))
+	if err != nil {
+		return "", err
+	}
+	dataAEAD, err := newAEAD(dataKey)
+	if err != nil {
+		return "", ErrCorrupt
+	}
+	plaintext, err := open(dataAEAD, ciphertext, nil)
+	if err != nil {
+		return "", err
+	}
+	return string(plaintext), nil
+}
+
+// NeedsRotation reports whether value should be re-encrypted: it is stored
+// in plaintext or under a key other than the active one.
+func (k *Keyring) NeedsRotation(value string) bool {
+	if k == nil || value == "" {
+		return false
+	}
+	return !strings.HasPrefix(value, prefix+k.active+":")
+}
+
+// Rotate re-encrypts value under the active key if it needs rotation.
+func (k *Keyring) Rotate(value string) (string, bool, error) {
+	if !k.NeedsRotation(value) {
+		return value, false, nil
+	}
+	plaintext, err := k.Decrypt(value)
+	if err != nil {
+		return "", false, err
+	}
+	rotated, err := k.Encrypt(plaintext)
+	if err != nil {
+		return "", false, err
+	}
+	return rotated, true, nil
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	if len(key) != KeySize {
+		return nil, fmt.Errorf("key must be %d bytes, got %d", KeySize, len(key))
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func seal(aead cipher.AEAD, plaintext, additionalData []byte) ([]byte, error) {
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(plaintext)+aead.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	return aead.Seal(nonce, nonce, plaintext, additionalData), nil
+}
+
+func open(aead cipher.AEAD, sealed, additionalData []byte) ([]byte, error) {
+	if len(sealed) < aead.NonceSize() {
+		return nil, ErrCorrupt
+	}
+	nonce, ciphertext := sealed[:aead.NonceSize()], sealed[aead.NonceSize():]
+	plaintext, err := aead.Open(nil, nonce, ciphertext, additionalData)
+	if err != nil {
+		return nil, ErrCorrupt
+	}
+	return plaintext, nil
+}
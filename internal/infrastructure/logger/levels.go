@@ -0,0 +1,154 @@
+package logger
+
+import (
+	"fmt"
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// levels are the level of the logger and its overrides for single packages.
+// They are replaced as a whole, so a line is filtered by one consistent set.
+type levels struct {
+	root zapcore.Level
+	// packages maps the last elements of an import path, e.g. "parser" or
+	// "services/parser", to the level of the packages they name.
+	packages map[string]zapcore.Level
+	// min is the lowest of all levels: lines below it are dropped before
+	// their package is looked up.
+	min zapcore.Level
+	// byPackage caches the level of every import path seen.
+	byPackage sync.Map
+}
+
+var current atomic.Pointer[levels]
+
+func newLevels(root zapcore.Level, packages map[string]zapcore.Level) *levels {
+	lv := &levels{root: root, packages: packages, min: root}
+	for _, l := range packages {
+		lv.min = min(lv.min, l)
+	}
+	return lv
+}
+
+// of returns the level of the package with import path pkg: that of the
+// longest override naming it, or the root level.
+func (lv *levels) of(pkg string) zapcore.Level {
+	if l, ok := lv.byPackage.Load(pkg); ok {
+		return l.(zapcore.Level)
+	}
+	level, matched := lv.root, ""
+	for name, l := range lv.packages {
+		if (pkg == name || strings.HasSuffix(pkg, "/"+name)) && len(name) > len(matched) {
+			level, matched = l, name
+		}
+	}
+	lv.byPackage.Store(pkg, level)
+	return level
+}
+
+// enabled reports whether a line at level l, logged by the caller of the
+// function calling enabled, passes the levels in effect.
+func enabled(l zapcore.Level) bool {
+	lv := current.Load()
+	if lv == nil || l < lv.min {
+		return false
+	}
+	if len(lv.packages) == 0 {
+		return l >= lv.root
+	}
+	return l >= lv.of(callerPackage(3))
+}
+
+// packageOfPC caches the import path of the function of every call site.
+var packageOfPC sync.Map
+
+// callerPackage returns the import path of the package of the function skip
+// frames up the stack, as counted by runtime.Caller.
+func callerPackage(skip int) string {
+	pc, _, _, ok := runtime.Caller(skip)
+	if !ok {
+		return ""
+	}
+	if pkg, ok := packageOfPC.Load(pc); ok {
+		return pkg.(string)
+	}
+	pkg := ""
+	if fn := runtime.FuncForPC(pc); fn != nil {
+		// e.g. github.com/NoroSaroyan/log-parser/internal/services/parser.(*JSONParser).Parse
+		name := fn.Name()
+		slash := strings.LastIndex(name, "/") + 1
+		if dot := strings.Index(name[slash:], "."); dot >= 0 {
+			pkg = name[:slash+dot]
+		}
+	}
+	packageOfPC.Store(pc, pkg)
+	return pkg
+}
+
+// ParseLevel returns the level named s: debug, info, warn, error or fatal,
+// in any case.
+func ParseLevel(s string) (zapcore.Level, error) {
+	switch strings.ToUpper(s) {
+	case "DEBUG":
+		return zapcore.DebugLevel, nil
+	case "INFO":
+		return zapcore.InfoLevel, nil
+	case "WARN":
+		return zapcore.WarnLevel, nil
+	case "ERROR":
+		return zapcore.ErrorLevel, nil
+	case "FATAL":
+		return zapcore.FatalLevel, nil
+	}
+	return zapcore.InfoLevel, fmt.Errorf("invalid log level %q: expected debug, info, warn, error or fatal", s)
+}
+
+// parseLevels returns the levels named by root and the package overrides.
+func parseLevels(root string, packages map[string]string) (*levels, error) {
+	rootLevel, err := ParseLevel(root)
+	if err != nil {
+		return nil, err
+	}
+	byName := make(map[string]zapcore.Level, len(packages))
+	for pkg, s := range packages {
+		name := strings.Trim(pkg, "/")
+		if name == "" {
+			return nil, fmt.Errorf("invalid package %q", pkg)
+		}
+		l, err := ParseLevel(s)
+		if err != nil {
+			return nil, fmt.Errorf("package %s: %w", name, err)
+		}
+		byName[name] = l
+	}
+	return newLevels(rootLevel, byName), nil
+}
+
+// SetLevels changes the level of the running logger to root and replaces
+// the package overrides with packages, which map the last elements of an
+// import path to a level, e.g. {"parser": "debug"}.
+func SetLevels(root string, packages map[string]string) error {
+	lv, err := parseLevels(root, packages)
+	if err != nil {
+		return err
+	}
+	current.Store(lv)
+	return nil
+}
+
+// Levels returns the level of the logger and the package overrides.
+func Levels() (root string, packages map[string]string) {
+	lv := current.Load()
+	if lv == nil {
+		return zapcore.InfoLevel.String(), map[string]string{}
+	}
+	packages = make(map[string]string, len(lv.packages))
+	for name, l := range lv.packages {
+		packages[name] = l.String()
+	}
+	return lv.root.String(), packages
+}
@@ -1,37 +1,85 @@
 package logger
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
 	"os"
-	"strings"
+	"sync"
 
+	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
+
+	"github.com/NoroSaroyan/log-parser/internal/config"
+	"github.com/NoroSaroyan/log-parser/internal/infrastructure/redaction"
+)
+
+var (
+	log *zap.Logger
+	// files are the log files log writes to, closed when it is replaced.
+	files []io.Closer
+	// installMu serializes the installation of loggers.
+	installMu sync.Mutex
 )
 
-var log *zap.Logger
+// Init initializes the logger from cfg: its level and package overrides,
+// the encoding of its lines and the sinks they are written to. It replaces
+// the running logger and closes its files.
+func Init(cfg config.LoggerConfig) error {
+	lv, err := parseLevels(cfg.Level, cfg.Packages)
+	if err != nil {
+		return err
+	}
+
+	var cores []zapcore.Core
+	var opened []io.Closer
+	for _, sink := range cfg.Output {
+		var w zapcore.WriteSyncer
+		switch sink {
+		case "stdout":
+			w = zapcore.Lock(os.Stdout)
+		case "stderr":
+			w = zapcore.Lock(os.Stderr)
+		case "file":
+			f, err := openRotatingFile(cfg.File)
+			if err != nil {
+				closeFiles(opened)
+				return err
+			}
+			opened = append(opened, f)
+			w = f
+		default:
+			closeFiles(opened)
+			return fmt.Errorf("unknown log output %q", sink)
+		}
+		cores = append(cores, newCore(cfg.Format, w, isTerminalOutput(sinkFile(sink))))
+	}
+
+	return install(zapcore.NewTee(cores...), lv, opened)
+}
 
 // InitLogger initializes zap logger with the specified level
 func InitLogger(level string) error {
-	// Determine log level
-	logLevel := zapcore.InfoLevel
-	switch strings.ToUpper(level) {
-	case "DEBUG":
-		logLevel = zapcore.DebugLevel
-	case "INFO":
-		logLevel = zapcore.InfoLevel
-	case "WARN":
-		logLevel = zapcore.WarnLevel
-	case "ERROR":
-		logLevel = zapcore.ErrorLevel
-	case "FATAL":
-		logLevel = zapcore.FatalLevel
-	default:
+	return InitLoggerWithWriter(level, os.Stdout)
+}
+
+// InitLoggerWithWriter initializes zap logger with the specified level,
+// writing to w instead of stdout. CLI modes that print a report on stdout
+// use it to send log lines to stderr. Unknown levels mean info.
+func InitLoggerWithWriter(level string, w io.Writer) error {
+	logLevel, err := ParseLevel(level)
+	if err != nil {
 		logLevel = zapcore.InfoLevel
 	}
+	core := newCore("console", zapcore.AddSync(w), isTerminalOutput(w))
+	return install(core, newLevels(logLevel, nil), nil)
+}
 
-	// Check if running in terminal
-	isTerminal := isTerminalOutput()
-
+// newCore returns the core writing the lines of the levels in effect to w,
+// encoded as JSON or for the console, in color on a terminal.
+func newCore(format string, w zapcore.WriteSyncer, terminal bool) zapcore.Core {
 	// Create encoder config
 	encoderConfig := zapcore.EncoderConfig{
 		TimeKey:        "ts",
@@ -48,114 +96,179 @@ func InitLogger(level string) error {
 		EncodeCaller:   zapcore.ShortCallerEncoder,
 	}
 
-	if !isTerminal {
+	var encoder zapcore.Encoder
+	switch {
+	case format == "json":
+		encoderConfig.EncodeLevel = zapcore.LowercaseLevelEncoder
+		encoder = zapcore.NewJSONEncoder(encoderConfig)
+	case terminal:
+		encoder = zapcore.NewConsoleEncoder(encoderConfig)
+	default:
 		encoderConfig.EncodeLevel = zapcore.CapitalLevelEncoder
+		encoder = zapcore.NewConsoleEncoder(encoderConfig)
 	}
 
-	// Create core
-	core := zapcore.NewCore(
-		zapcore.NewConsoleEncoder(encoderConfig),
-		zapcore.AddSync(os.Stdout),
-		logLevel,
-	)
+	// Lines below the lowest level in effect are dropped here; the package
+	// functions filter the others by the package logging them.
+	return zapcore.NewCore(encoder, w, zap.LevelEnablerFunc(func(l zapcore.Level) bool {
+		lv := current.Load()
+		return lv != nil && l >= lv.min
+	}))
+}
+
+// install makes the logger of core, with levels lv and writing to files,
+// the running logger.
+func install(core zapcore.Core, lv *levels, opened []io.Closer) error {
+	installMu.Lock()
+	defer installMu.Unlock()
 
-	// Create logger with stack trace on error and above
-	log = zap.New(core, zap.AddCallerSkip(1))
+	previous, previousFiles := log, files
+	current.Store(lv)
+	log = zap.New(core, zap.AddCaller(), zap.AddCallerSkip(1))
+	files = opened
+
+	if previous != nil {
+		_ = previous.Sync()
+	}
+	return closeFiles(previousFiles)
+}
 
+// Close flushes the logger and closes its log files. Lines logged
+// afterwards are dropped.
+func Close() error {
+	installMu.Lock()
+	defer installMu.Unlock()
+
+	if log == nil {
+		return nil
+	}
+	_ = log.Sync()
+	log = nil
+	err := closeFiles(files)
+	files = nil
+	return err
+}
+
+func closeFiles(fs []io.Closer) error {
+	var errs []error
+	for _, f := range fs {
+		errs = append(errs, f.Close())
+	}
+	return errors.Join(errs...)
+}
+
+// sinkFile returns the standard stream of sink, if it is one.
+func sinkFile(sink string) io.Writer {
+	switch sink {
+	case "stdout":
+		return os.Stdout
+	case "stderr":
+		return os.Stderr
+	}
 	return nil
 }
 
-// GetLogger returns the logger instance for direct use if needed
+// GetLogger returns the logger instance for direct use if needed. It logs at
+// the level of the logger; the package overrides only apply to the package
+// functions, such as Info.
 func GetLogger() *zap.Logger {
 	if log == nil {
 		// Initialize default logger if not initialized
 		_ = InitLogger("info")
 	}
-	return log
+	return log.WithOptions(zap.IncreaseLevel(zap.LevelEnablerFunc(func(l zapcore.Level) bool {
+		lv := current.Load()
+		return lv != nil && l >= lv.root
+	})))
 }
 
 // Info logs an info message
 func Info(msg string, fields ...interface{}) {
-	if log == nil {
+	if log == nil || !enabled(zapcore.InfoLevel) {
 		return
 	}
-	if len(fields) > 0 {
-		log.Info(msg, zap.Any("fields", fields[0]))
-	} else {
-		log.Info(msg)
-	}
+	log.Info(msg, fieldsOf(fields)...)
 }
 
 // Debug logs a debug message
 func Debug(msg string, fields ...interface{}) {
-	if log == nil {
+	if log == nil || !enabled(zapcore.DebugLevel) {
 		return
 	}
-	if len(fields) > 0 {
-		log.Debug(msg, zap.Any("fields", fields[0]))
-	} else {
-		log.Debug(msg)
-	}
+	log.Debug(msg, fieldsOf(fields)...)
 }
 
 // Warn logs a warning message
 func Warn(msg string, fields ...interface{}) {
-	if log == nil {
+	if log == nil || !enabled(zapcore.WarnLevel) {
 		return
 	}
-	if len(fields) > 0 {
-		log.Warn(msg, zap.Any("fields", fields[0]))
-	} else {
-		log.Warn(msg)
-	}
+	log.Warn(msg, fieldsOf(fields)...)
 }
 
 // Error logs an error message
 // Can be called as: Error(msg), Error(msg, err), or Error(msg, err, fields)
 func Error(msg string, args ...interface{}) {
+	if log == nil || !enabled(zapcore.ErrorLevel) {
+		return
+	}
+	log.Error(msg, errorFieldsOf(args)...)
+}
+
+// Fatal logs a fatal message and exits
+// Can be called as: Fatal(msg), Fatal(msg, err), or Fatal(msg, err, fields)
+func Fatal(msg string, args ...interface{}) {
 	if log == nil {
 		return
 	}
+	log.Fatal(msg, errorFieldsOf(args)...)
+}
 
-	var err error
-	var fields interface{}
+// InfoContext is Info with the trace_id and span_id of the span in ctx, if
+// any, so the line can be found from a trace and the other way round.
+func InfoContext(ctx context.Context, msg string, fields ...interface{}) {
+	if log == nil || !enabled(zapcore.InfoLevel) {
+		return
+	}
+	log.Info(msg, append(traceFields(ctx), fieldsOf(fields)...)...)
+}
 
-	// Parse arguments
-	if len(args) > 0 {
-		// First arg could be error or fields map
-		if errVal, ok := args[0].(error); ok {
-			err = errVal
-			if len(args) > 1 {
-				fields = args[1]
-			}
-		} else if mapVal, ok := args[0].(map[string]interface{}); ok {
-			fields = mapVal
-		}
+// DebugContext is Debug with the trace of ctx, see InfoContext.
+func DebugContext(ctx context.Context, msg string, fields ...interface{}) {
+	if log == nil || !enabled(zapcore.DebugLevel) {
+		return
 	}
+	log.Debug(msg, append(traceFields(ctx), fieldsOf(fields)...)...)
+}
 
-	// Build log call
-	if err != nil {
-		if fields != nil {
-			log.Error(msg, zap.Error(err), zap.Any("fields", fields))
-		} else {
-			log.Error(msg, zap.Error(err))
-		}
-	} else {
-		if fields != nil {
-			log.Error(msg, zap.Any("fields", fields))
-		} else {
-			log.Error(msg)
-		}
+// WarnContext is Warn with the trace of ctx, see InfoContext.
+func WarnContext(ctx context.Context, msg string, fields ...interface{}) {
+	if log == nil || !enabled(zapcore.WarnLevel) {
+		return
 	}
+	log.Warn(msg, append(traceFields(ctx), fieldsOf(fields)...)...)
 }
 
-// Fatal logs a fatal message and exits
-// Can be called as: Fatal(msg), Fatal(msg, err), or Fatal(msg, err, fields)
-func Fatal(msg string, args ...interface{}) {
-	if log == nil {
+// ErrorContext is Error with the trace of ctx, see InfoContext.
+func ErrorContext(ctx context.Context, msg string, args ...interface{}) {
+	if log == nil || !enabled(zapcore.ErrorLevel) {
 		return
 	}
+	log.Error(msg, append(traceFields(ctx), errorFieldsOf(args)...)...)
+}
+
+// fieldsOf returns the zap fields of the optional fields map of Info, Debug
+// and Warn.
+func fieldsOf(fields []interface{}) []zap.Field {
+	if len(fields) > 0 {
+		return []zap.Field{zap.Any("fields", redacted(fields[0]))}
+	}
+	return nil
+}
 
+// errorFieldsOf returns the zap fields of the arguments of Error and Fatal:
+// an optional error, then an optional fields map, or a fields map alone.
+func errorFieldsOf(args []interface{}) []zap.Field {
 	var err error
 	var fields interface{}
 
@@ -172,19 +285,26 @@ func Fatal(msg string, args ...interface{}) {
 		}
 	}
 
-	// Build log call
+	var out []zap.Field
 	if err != nil {
-		if fields != nil {
-			log.Fatal(msg, zap.Error(err), zap.Any("fields", fields))
-		} else {
-			log.Fatal(msg, zap.Error(err))
-		}
-	} else {
-		if fields != nil {
-			log.Fatal(msg, zap.Any("fields", fields))
-		} else {
-			log.Fatal(msg)
-		}
+		out = append(out, zap.Error(err))
+	}
+	if fields != nil {
+		out = append(out, zap.Any("fields", redacted(fields)))
+	}
+	return out
+}
+
+// traceFields returns the trace and span IDs of the span in ctx, or nothing
+// if ctx holds no span.
+func traceFields(ctx context.Context) []zap.Field {
+	sc := trace.SpanContextFromContext(ctx)
+	if !sc.IsValid() {
+		return nil
+	}
+	return []zap.Field{
+		zap.String("trace_id", sc.TraceID().String()),
+		zap.String("span_id", sc.SpanID().String()),
 	}
 }
 
@@ -198,9 +318,19 @@ func WithFields(fields map[string]interface{}) map[string]interface{} {
 	return fields
 }
 
-// isTerminalOutput checks if stdout is connected to a terminal
-func isTerminalOutput() bool {
-	fileInfo, err := os.Stdout.Stat()
+// redacted applies the redaction policy to the fields of a log line, so
+// personal data and secrets never reach the log, whatever the caller passes.
+func redacted(fields interface{}) interface{} {
+	return redaction.Current().Redact(fields, nil)
+}
+
+// isTerminalOutput checks if w is a file connected to a terminal
+func isTerminalOutput(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+	fileInfo, err := f.Stat()
 	if err != nil {
 		return false
 	}
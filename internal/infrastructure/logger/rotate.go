@@ -0,0 +1,283 @@
+package logger
+
+import (
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/NoroSaroyan/log-parser/internal/config"
+)
+
+// backupTimeFormat is the time of rotation in the name of a backup, e.g.
+// app-2026-04-16T10-30-00.000.log for app.log. It sorts chronologically.
+const backupTimeFormat = "2006-01-02T15-04-05.000"
+
+// rotatingFile appends to a log file and, before it grows past MaxSizeMB,
+// renames it to a backup named after the time of rotation and starts a new
+// one. Backups are compressed and pruned by count and age in the background,
+// so a rotation never holds up the lines logged meanwhile.
+type rotatingFile struct {
+	cfg     config.LogFileConfig
+	maxSize int64
+
+	mu     sync.Mutex
+	file   *os.File
+	size   int64
+	closed bool
+
+	// mill wakes the goroutine compressing and pruning the backups.
+	mill chan struct{}
+	done chan struct{}
+}
+
+// openRotatingFile opens the log file of cfg for appending, creating it and
+// its directory if needed, and tidies the backups left by previous runs.
+func openRotatingFile(cfg config.LogFileConfig) (*rotatingFile, error) {
+	if err := os.MkdirAll(filepath.Dir(cfg.Path), 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create log directory: %w", err)
+	}
+	f := &rotatingFile{
+		cfg:     cfg,
+		maxSize: int64(cfg.MaxSizeMB) << 20,
+		mill:    make(chan struct{}, 1),
+		done:    make(chan struct{}),
+	}
+	if err := f.open(); err != nil {
+		return nil, err
+	}
+	go f.runMill()
+	f.mill <- struct{}{}
+	return f, nil
+}
+
+func (f *rotatingFile) open() error {
+	file, err := os.OpenFile(f.cfg.Path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to open log file: %w", err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("failed to stat log file: %w", err)
+	}
+	f.file, f.size = file, info.Size()
+	return nil
+}
+
+// Write appends p to the file, rotating it first if p would take it past
+// the maximum size. A line larger than the maximum gets a file of its own.
+func (f *rotatingFile) Write(p []byte) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.closed || f.file == nil {
+		return 0, os.ErrClosed
+	}
+	if f.maxSize > 0 && f.size > 0 && f.size+int64(len(p)) > f.maxSize {
+		if err := f.rotate(); err != nil {
+			return 0, err
+		}
+	}
+	n, err := f.file.Write(p)
+	f.size += int64(n)
+	return n, err
+}
+
+// rotate renames the file to a backup and opens a new one.
+func (f *rotatingFile) rotate() error {
+	if err := f.file.Close(); err != nil {
+		return fmt.Errorf("failed to close log file: %w", err)
+	}
+	f.file = nil
+	if err := os.Rename(f.cfg.Path, f.backupName(time.Now())); err != nil {
+		return fmt.Errorf("failed to rotate log file: %w", err)
+	}
+	if err := f.open(); err != nil {
+		return err
+	}
+	select {
+	case f.mill <- struct{}{}:
+	default:
+		// The mill has yet to run and will see this backup too.
+	}
+	return nil
+}
+
+// Sync flushes the file to disk.
+func (f *rotatingFile) Sync() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.file == nil {
+		return nil
+	}
+	return f.file.Sync()
+}
+
+// Close closes the file and waits for the backups to be compressed and
+// pruned.
+func (f *rotatingFile) Close() error {
+	f.mu.Lock()
+	if f.closed {
+		f.mu.Unlock()
+		return nil
+	}
+	var err error
+	if f.file != nil {
+		err = f.file.Close()
+		f.file = nil
+	}
+	f.closed = true
+	close(f.mill)
+	f.mu.Unlock()
+
+	<-f.done
+	return err
+}
+
+// base splits the path of the file into its directory, its name without
+// the extension and the extension, e.g. "logs/", "app" and ".log".
+func (f *rotatingFile) base() (dir, base, ext string) {
+	dir, name := filepath.Split(f.cfg.Path)
+	ext = filepath.Ext(name)
+	return dir, strings.TrimSuffix(name, ext), ext
+}
+
+// backupName returns the name of a backup rotated at t, or a millisecond
+// later if a backup, compressed or not, already has that name.
+func (f *rotatingFile) backupName(t time.Time) string {
+	dir, base, ext := f.base()
+	for ; ; t = t.Add(time.Millisecond) {
+		name := filepath.Join(dir, base+"-"+t.UTC().Format(backupTimeFormat)+ext)
+		if !exists(name) && !exists(name+".gz") {
+			return name
+		}
+	}
+}
+
+func exists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+func (f *rotatingFile) runMill() {
+	defer close(f.done)
+	for range f.mill {
+		if err := f.tidyBackups(); err != nil {
+			// The logger cannot log its own failures; they go where the
+			// standard library would report them.
+			fmt.Fprintf(os.Stderr, "logger: %v\n", err)
+		}
+	}
+}
+
+// backup is a rotated log file.
+type backup struct {
+	path    string
+	rotated time.Time
+	gzipped bool
+}
+
+// backups returns the backups of the file, newest first.
+func (f *rotatingFile) backups() ([]backup, error) {
+	dir, base, ext := f.base()
+	if dir == "" {
+		dir = "."
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list log backups: %w", err)
+	}
+	var out []backup
+	for _, e := range entries {
+		name := e.Name()
+		stamp, ok := strings.CutPrefix(name, base+"-")
+		if !ok || e.IsDir() {
+			continue
+		}
+		b := backup{path: filepath.Join(dir, name)}
+		stamp, b.gzipped = strings.CutSuffix(stamp, ".gz")
+		stamp, ok = strings.CutSuffix(stamp, ext)
+		if !ok {
+			continue
+		}
+		if b.rotated, err = time.Parse(backupTimeFormat, stamp); err != nil {
+			continue
+		}
+		out = append(out, b)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].rotated.After(out[j].rotated) })
+	return out, nil
+}
+
+// tidyBackups removes the backups beyond MaxBackups or older than
+// MaxAgeDays, zero meaning no limit, and compresses the others.
+func (f *rotatingFile) tidyBackups() error {
+	all, err := f.backups()
+	if err != nil {
+		return err
+	}
+	cutoff := time.Time{}
+	if f.cfg.MaxAgeDays > 0 {
+		cutoff = time.Now().Add(-time.Duration(f.cfg.MaxAgeDays) * 24 * time.Hour)
+	}
+	var errs []error
+	for i, b := range all {
+		if (f.cfg.MaxBackups > 0 && i >= f.cfg.MaxBackups) || b.rotated.Before(cutoff) {
+			if err := os.Remove(b.path); err != nil && !os.IsNotExist(err) {
+				errs = append(errs, err)
+			}
+			continue
+		}
+		if f.cfg.Compress && !b.gzipped {
+			if err := compress(b.path); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	if err := errors.Join(errs...); err != nil {
+		return fmt.Errorf("failed to tidy log backups: %w", err)
+	}
+	return nil
+}
+
+// compress replaces the file at path with path.gz.
+func compress(path string) (err error) {
+	src, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	tmp := path + ".gz.tmp"
+	dst, err := os.OpenFile(tmp, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o644)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			dst.Close()
+			os.Remove(tmp)
+		}
+	}()
+	zw := gzip.NewWriter(dst)
+	if _, err = io.Copy(zw, src); err != nil {
+		return err
+	}
+	if err = zw.Close(); err != nil {
+		return err
+	}
+	if err = dst.Close(); err != nil {
+		return err
+	}
+	if err = os.Rename(tmp, path+".gz"); err != nil {
+		return err
+	}
+	return os.Remove(path)
+}
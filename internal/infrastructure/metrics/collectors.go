@@ -0,0 +1,117 @@
+package metrics
+
+import (
+	"database/sql"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// HTTP metrics, recorded by Middleware. Routes are chi route patterns, so
+// path parameters do not create new series.
+var (
+	HTTPRequests = factory.NewCounterVec(prometheus.CounterOpts{
+		Name: "logparser_http_requests_total",
+		Help: "HTTP requests by method, route pattern and status code.",
+	}, []string{"method", "route", "status"})
+	HTTPDuration = factory.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "logparser_http_request_duration_seconds",
+		Help:    "Latency of HTTP requests by method and route pattern.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"method", "route"})
+)
+
+// Ingestion metrics. They cover every log file and watched payload parsed or
+// stored by the process, whether uploaded, processed by the CLI or followed
+// by watch mode; they are the counters of the parser classification summary.
+var (
+	BlocksExtracted = factory.NewCounter(prometheus.CounterOpts{
+		Name: "logparser_ingest_blocks_extracted_total",
+		Help: "JSON blocks extracted from logs.",
+	})
+	BlocksRelevant = factory.NewCounter(prometheus.CounterOpts{
+		Name: "logparser_ingest_blocks_relevant_total",
+		Help: "Extracted JSON blocks recognised as domain payloads.",
+	})
+	Payloads = factory.NewCounterVec(prometheus.CounterOpts{
+		Name: "logparser_ingest_payloads_total",
+		Help: "Parsed payloads by type: download, station_pcba, station_final or test_steps.",
+	}, []string{"type"})
+	StepArrays = factory.NewCounterVec(prometheus.CounterOpts{
+		Name: "logparser_ingest_step_arrays_total",
+		Help: "Parsed test step arrays by how they match station records: same_type, different_type, orphan (no station record at all), missing_pcba_scan or unknown_type.",
+	}, []string{"match"})
+	Groups = factory.NewCounterVec(prometheus.CounterOpts{
+		Name: "logparser_ingest_groups_total",
+		Help: "Dispatched PCBA groups by result: ok or failed.",
+	}, []string{"result"})
+	RowsInserted = factory.NewCounterVec(prometheus.CounterOpts{
+		Name: "logparser_ingest_rows_inserted_total",
+		Help: "Rows inserted by the dispatcher by table. Existing rows reused by the dispatcher are not counted.",
+	}, []string{"table"})
+)
+
+// dbStats are the metrics of RegisterDBStats.
+var dbStats = []struct {
+	desc      *prometheus.Desc
+	valueType prometheus.ValueType
+	value     func(sql.DBStats) float64
+}{
+	{dbDesc("logparser_db_max_open_connections", "Maximum number of open connections to the database."), prometheus.GaugeValue,
+		func(s sql.DBStats) float64 { return float64(s.MaxOpenConnections) }},
+	{dbDesc("logparser_db_open_connections", "Established connections, in use or idle."), prometheus.GaugeValue,
+		func(s sql.DBStats) float64 { return float64(s.OpenConnections) }},
+	{dbDesc("logparser_db_in_use_connections", "Connections currently in use."), prometheus.GaugeValue,
+		func(s sql.DBStats) float64 { return float64(s.InUse) }},
+	{dbDesc("logparser_db_idle_connections", "Idle connections."), prometheus.GaugeValue,
+		func(s sql.DBStats) float64 { return float64(s.Idle) }},
+	{dbDesc("logparser_db_wait_count_total", "Connections waited for."), prometheus.CounterValue,
+		func(s sql.DBStats) float64 { return float64(s.WaitCount) }},
+	{dbDesc("logparser_db_wait_duration_seconds_total", "Time blocked waiting for a new connection."), prometheus.CounterValue,
+		func(s sql.DBStats) float64 { return s.WaitDuration.Seconds() }},
+	{dbDesc("logparser_db_max_idle_closed_total", "Connections closed due to the maximum of idle connections."), prometheus.CounterValue,
+		func(s sql.DBStats) float64 { return float64(s.MaxIdleClosed) }},
+	{dbDesc("logparser_db_max_idle_time_closed_total", "Connections closed due to the maximum idle time."), prometheus.CounterValue,
+		func(s sql.DBStats) float64 { return float64(s.MaxIdleTimeClosed) }},
+	{dbDesc("logparser_db_max_lifetime_closed_total", "Connections closed due to the maximum connection lifetime."), prometheus.CounterValue,
+		func(s sql.DBStats) float64 { return float64(s.MaxLifetimeClosed) }},
+}
+
+func dbDesc(name, help string) *prometheus.Desc {
+	return prometheus.NewDesc(name, help, nil, nil)
+}
+
+// dbStatsCollector reads the connection pool statistics at every scrape.
+type dbStatsCollector struct {
+	stats func() sql.DBStats
+}
+
+func (c dbStatsCollector) Describe(ch chan<- *prometheus.Desc) {
+	for _, m := range dbStats {
+		ch <- m.desc
+	}
+}
+
+func (c dbStatsCollector) Collect(ch chan<- prometheus.Metric) {
+	s := c.stats()
+	for _, m := range dbStats {
+		ch <- prometheus.MustNewConstMetric(m.desc, m.valueType, m.value(s))
+	}
+}
+
+var (
+	dbStatsMu        sync.Mutex
+	dbStatsCollected prometheus.Collector
+)
+
+// RegisterDBStats exposes the connection pool statistics returned by stats,
+// usually the Stats method of a sql.DB. Calling it again replaces stats.
+func RegisterDBStats(stats func() sql.DBStats) {
+	dbStatsMu.Lock()
+	defer dbStatsMu.Unlock()
+	if dbStatsCollected != nil {
+		Default.Unregister(dbStatsCollected)
+	}
+	dbStatsCollected = dbStatsCollector{stats: stats}
+	Default.MustRegister(dbStatsCollected)
+}
@@ -0,0 +1,48 @@
+/*
+Package metrics exposes the metrics of the process in the Prometheus
+exposition format.
+
+The metrics themselves are package-level variables (see collectors.go), so
+any package can count without wiring; Handler serves them all.
+
+Implementation notes:
+  - Metrics are collectors of the Prometheus client library registered in
+    Default, a registry of their own rather than the global one of the
+    library, together with the Go runtime and process collectors.
+  - Labelled counters and histograms create the child of a combination of
+    label values on first use and never remove it, so label values must
+    come from a small, fixed set.
+  - Values kept elsewhere, such as the connection pool statistics of sql.DB,
+    are read when the metrics are scraped.
+*/
+package metrics
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// DefaultPath is the path metrics are served on when none is configured.
+const DefaultPath = "/metrics"
+
+// Default is the registry of the package-level metrics and of Handler.
+var Default = prometheus.NewRegistry()
+
+// factory registers the package-level metrics in Default.
+var factory = promauto.With(Default)
+
+func init() {
+	Default.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+}
+
+// Handler serves the metrics of Default.
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Default, promhttp.HandlerOpts{})
+}
@@ -0,0 +1,155 @@
+/*
+Package redaction masks, hashes or drops personal data and secrets before
+they leave the process, in API responses and in log lines.
+
+A Policy maps field names to actions. Fields are matched by their JSON name
+(falling back to the Go field name) anywhere inside the value being redacted,
+and by key in maps such as the logger field maps.
+
+Implementation notes:
+  - Redact never modifies its argument. Values that contain nothing to redact
+    are returned as they are; otherwise the containers on the path to a
+    redacted field are copied.
+  - A dropped struct field is set to the empty string, a dropped map key is
+    removed.
+  - A string field tagged `redact:"Other"` is redacted by the rule of the
+    field named by the Other field of the same struct, e.g. the values of a
+    LogisticData conflict by its Field.
+  - Rules may name a role allowed to see the field in clear (RevealTo). The
+    package does not know roles: callers pass a reveal function deciding per
+    field, and log lines are never revealed.
+  - The process-wide policy used by the logger and the API is set once at
+    start-up with SetPolicy; until then DefaultRules apply.
+*/
+package redaction
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"sync/atomic"
+)
+
+// Action is what happens to the value of a redacted field.
+type Action string
+
+const (
+	// ActionNone leaves the field as it is, e.g. to lift a default rule.
+	ActionNone Action = "none"
+	// ActionMask replaces all but the last four characters with '*'.
+	ActionMask Action = "mask"
+	// ActionHash replaces the value with a truncated SHA-256, so equal
+	// values can still be correlated.
+	ActionHash Action = "hash"
+	// ActionDrop removes the value.
+	ActionDrop Action = "drop"
+)
+
+// Rule is the redaction of one field.
+type Rule struct {
+	Action Action
+	// RevealTo is the lowest role that sees the field in clear in API
+	// responses; empty means nobody does.
+	RevealTo string
+}
+
+// DefaultRules are the rules applied when the configuration sets none.
+var DefaultRules = map[string]Rule{
+	"BlePassworkKey": {Action: ActionDrop, RevealTo: "admin"},
+	"PhoneNumber":    {Action: ActionMask, RevealTo: "engineer"},
+	"IMSI":           {Action: ActionMask, RevealTo: "engineer"},
+	"TcuICCID":       {Action: ActionMask, RevealTo: "engineer"},
+}
+
+// maskKeep is the number of trailing characters ActionMask leaves readable.
+const maskKeep = 4
+
+// hashPrefix marks hashed values.
+const hashPrefix = "sha256:"
+
+// Policy is a set of rules by field name. The zero value redacts nothing.
+type Policy struct {
+	rules map[string]Rule
+}
+
+// NewPolicy returns a policy with DefaultRules overridden by rules. It fails
+// for unknown actions.
+func NewPolicy(rules map[string]Rule) (*Policy, error) {
+	p := &Policy{rules: make(map[string]Rule, len(DefaultRules)+len(rules))}
+	for field, rule := range DefaultRules {
+		p.rules[field] = rule
+	}
+	for field, rule := range rules {
+		switch rule.Action {
+		case ActionNone:
+			delete(p.rules, field)
+			continue
+		case ActionMask, ActionHash, ActionDrop:
+		default:
+			return nil, fmt.Errorf("invalid redaction action %q for %s: expected none, mask, hash or drop", rule.Action, field)
+		}
+		p.rules[field] = rule
+	}
+	return p, nil
+}
+
+// Rule returns the rule of field and whether the field is redacted.
+func (p *Policy) Rule(field string) (Rule, bool) {
+	r, ok := p.rules[field]
+	return r, ok
+}
+
+// Fields returns the names of the redacted fields.
+func (p *Policy) Fields() []string {
+	fields := make([]string, 0, len(p.rules))
+	for f := range p.rules {
+		fields = append(fields, f)
+	}
+	return fields
+}
+
+// String redacts value as field. It returns false when the value is dropped.
+// Values of fields without a rule, and empty values, are returned unchanged.
+func (p *Policy) String(field, value string) (string, bool) {
+	rule, ok := p.rules[field]
+	if !ok || value == "" {
+		return value, true
+	}
+	return apply(rule.Action, value)
+}
+
+func apply(action Action, value string) (string, bool) {
+	switch action {
+	case ActionMask:
+		runes := []rune(value)
+		keep := 0
+		if len(runes) > maskKeep*2 {
+			keep = maskKeep
+		}
+		return strings.Repeat("*", len(runes)-keep) + string(runes[len(runes)-keep:]), true
+	case ActionHash:
+		sum := sha256.Sum256([]byte(value))
+		return hashPrefix + hex.EncodeToString(sum[:8]), true
+	case ActionDrop:
+		return "", false
+	}
+	return value, true
+}
+
+var current atomic.Pointer[Policy]
+
+func init() {
+	p, _ := NewPolicy(nil)
+	current.Store(p)
+}
+
+// SetPolicy makes p the process-wide policy.
+func SetPolicy(p *Policy) {
+	current.Store(p)
+}
+
+// Current returns the process-wide policy.
+func Current() *Policy {
+	return current.Load()
+}
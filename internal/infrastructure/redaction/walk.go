@@ -0,0 +1,236 @@
+package redaction
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// maxDepth bounds the walk over nested values, which guards against cycles.
+const maxDepth = 32
+
+// Redact returns v with every redacted field replaced according to the
+// policy, leaving v itself untouched. reveal, if non-nil, reports the fields
+// the caller may see in clear.
+func (p *Policy) Redact(v any, reveal func(field string) bool) any {
+	if v == nil || len(p.rules) == 0 {
+		return v
+	}
+	w := walker{p: p, reveal: reveal}
+	out, changed := w.walk(reflect.ValueOf(v), 0)
+	if !changed {
+		return v
+	}
+	return out.Interface()
+}
+
+type walker struct {
+	p      *Policy
+	reveal func(field string) bool
+}
+
+// rule returns the action for field, if it is redacted and not revealed.
+func (w walker) rule(field string) (Action, bool) {
+	r, ok := w.p.rules[field]
+	if !ok || (w.reveal != nil && w.reveal(field)) {
+		return "", false
+	}
+	return r.Action, true
+}
+
+// walk returns a redacted copy of v and true, or v and false when nothing
+// in v is redacted.
+func (w walker) walk(v reflect.Value, depth int) (reflect.Value, bool) {
+	if depth > maxDepth {
+		return v, false
+	}
+	switch v.Kind() {
+	case reflect.Pointer:
+		if v.IsNil() {
+			return v, false
+		}
+		elem, changed := w.walk(v.Elem(), depth+1)
+		if !changed {
+			return v, false
+		}
+		out := reflect.New(v.Type().Elem())
+		out.Elem().Set(elem)
+		return out, true
+
+	case reflect.Interface:
+		if v.IsNil() {
+			return v, false
+		}
+		elem, changed := w.walk(v.Elem(), depth+1)
+		if !changed {
+			return v, false
+		}
+		out := reflect.New(v.Type()).Elem()
+		out.Set(elem)
+		return out, true
+
+	case reflect.Struct:
+		return w.walkStruct(v, depth)
+
+	case reflect.Slice, reflect.Array:
+		if v.Kind() == reflect.Slice && v.IsNil() {
+			return v, false
+		}
+		var out reflect.Value
+		for i := 0; i < v.Len(); i++ {
+			elem, changed := w.walk(v.Index(i), depth+1)
+			if !changed {
+				continue
+			}
+			if !out.IsValid() {
+				out = copySequence(v)
+			}
+			out.Index(i).Set(elem)
+		}
+		if !out.IsValid() {
+			return v, false
+		}
+		return out, true
+
+	case reflect.Map:
+		return w.walkMap(v, depth)
+	}
+	return v, false
+}
+
+func (w walker) walkStruct(v reflect.Value, depth int) (reflect.Value, bool) {
+	t := v.Type()
+	var out reflect.Value
+	set := func(i int, value reflect.Value) {
+		if !out.IsValid() {
+			out = reflect.New(t).Elem()
+			out.Set(v)
+		}
+		out.Field(i).Set(value)
+	}
+
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		fv := v.Field(i)
+
+		if fv.Kind() == reflect.String {
+			field := fieldName(f)
+			if by := f.Tag.Get("redact"); by != "" {
+				if other := v.FieldByName(by); other.IsValid() && other.Kind() == reflect.String {
+					field = other.String()
+				}
+			}
+			action, ok := w.rule(field)
+			if !ok || fv.String() == "" {
+				continue
+			}
+			redacted, _ := apply(action, fv.String())
+			set(i, reflect.ValueOf(redacted).Convert(f.Type))
+			continue
+		}
+
+		if redacted, changed := w.walk(fv, depth+1); changed {
+			set(i, redacted)
+		}
+	}
+	if !out.IsValid() {
+		return v, false
+	}
+	return out, true
+}
+
+// mapUpdate is a change to one entry of a map; an invalid value removes it.
+type mapUpdate struct {
+	key, value reflect.Value
+}
+
+func (w walker) walkMap(v reflect.Value, depth int) (reflect.Value, bool) {
+	if v.IsNil() {
+		return v, false
+	}
+	stringKeys := v.Type().Key().Kind() == reflect.String
+
+	var updates []mapUpdate
+	iter := v.MapRange()
+	for iter.Next() {
+		key, value := iter.Key(), iter.Value()
+		if stringKeys {
+			if action, ok := w.rule(key.String()); ok {
+				updates = append(updates, mapUpdate{key, redactEntry(v.Type().Elem(), value, action)})
+				continue
+			}
+		}
+		if redacted, changed := w.walk(value, depth+1); changed {
+			updates = append(updates, mapUpdate{key, redacted})
+		}
+	}
+	if len(updates) == 0 {
+		return v, false
+	}
+
+	out := reflect.MakeMapWithSize(v.Type(), v.Len())
+	iter = v.MapRange()
+	for iter.Next() {
+		out.SetMapIndex(iter.Key(), iter.Value())
+	}
+	for _, u := range updates {
+		out.SetMapIndex(u.key, u.value)
+	}
+	return out, true
+}
+
+// redactEntry returns the redacted value of a map entry whose key is a
+// redacted field. Values that are not strings are formatted first. It returns
+// an invalid value, which removes the entry, when the value is dropped or the
+// map cannot hold a string.
+func redactEntry(elemType reflect.Type, value reflect.Value, action Action) reflect.Value {
+	for value.Kind() == reflect.Interface || value.Kind() == reflect.Pointer {
+		if value.IsNil() {
+			return reflect.Zero(elemType)
+		}
+		value = value.Elem()
+	}
+	s := fmt.Sprint(value.Interface())
+	if value.Kind() == reflect.String {
+		s = value.String()
+	}
+	if s == "" {
+		return reflect.Zero(elemType)
+	}
+
+	redacted, keep := apply(action, s)
+	rv := reflect.ValueOf(redacted)
+	switch {
+	case !keep:
+		return reflect.Value{}
+	case elemType.Kind() == reflect.Interface && rv.Type().Implements(elemType):
+		return rv
+	case elemType.Kind() == reflect.String:
+		return rv.Convert(elemType)
+	}
+	return reflect.Value{}
+}
+
+func copySequence(v reflect.Value) reflect.Value {
+	if v.Kind() == reflect.Array {
+		out := reflect.New(v.Type()).Elem()
+		out.Set(v)
+		return out
+	}
+	out := reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+	reflect.Copy(out, v)
+	return out
+}
+
+// fieldName returns the JSON name of f, or its Go name.
+func fieldName(f reflect.StructField) string {
+	if tag := f.Tag.Get("json"); tag != "" && tag != "-" {
+		if name, _, _ := strings.Cut(tag, ","); name != "" {
+			return name
+		}
+	}
+	return f.Name
+}
@@ -0,0 +1,218 @@
+package tracing
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"regexp"
+	"runtime"
+	"strings"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+// WrapConnector returns a connector whose connections trace every query and
+// statement execution in a client span with the SQL text and attrs, such as
+// the database name. A span is named after the statement: the first
+// function of package statementPkg on the call stack, usually the repository
+// method running the query (e.g. "DownloadInfoRepository.GetByPCBANumber"),
+// or else the SQL command (e.g. "SELECT").
+//
+// Spans cover the execution of the statement until its first rows arrive,
+// not the scanning of the rows. Transactions are not spans of their own.
+func WrapConnector(c driver.Connector, statementPkg string, attrs ...attribute.KeyValue) driver.Connector {
+	return &connector{Connector: c, pkg: statementPkg + ".", attrs: attrs}
+}
+
+type connector struct {
+	driver.Connector
+	pkg   string
+	attrs []attribute.KeyValue
+}
+
+func (c *connector) Connect(ctx context.Context) (driver.Conn, error) {
+	cn, err := c.Connector.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &conn{Conn: cn, c: c}, nil
+}
+
+// start starts the span of query, or a no-op span when tracing is disabled,
+// which spares the walk of the call stack.
+func (c *connector) start(ctx context.Context, query string) (context.Context, trace.Span) {
+	if !Enabled() {
+		return ctx, noop.Span{}
+	}
+	operation := strings.ToUpper(firstWord(query))
+	name := callerIn(c.pkg)
+	if name == "" {
+		name = operation
+	}
+	attrs := append([]attribute.KeyValue{
+		attribute.String("db.system.name", "postgresql"),
+		attribute.String("db.operation.name", operation),
+		attribute.String("db.query.text", strings.Join(strings.Fields(query), " ")),
+	}, c.attrs...)
+	return otel.Tracer(instrumentationName).Start(ctx, name,
+		trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(attrs...))
+}
+
+// end ends span with err, unless err only tells database/sql to fall back
+// to another method.
+func end(span trace.Span, err error) {
+	if !errors.Is(err, driver.ErrSkip) {
+		Fail(span, err)
+	}
+	span.End()
+}
+
+// conn traces the queries of a driver connection. It implements the
+// optional interfaces database/sql uses from lib/pq and passes the others
+// through.
+type conn struct {
+	driver.Conn
+	c *connector
+}
+
+func (cn *conn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	q, ok := cn.Conn.(driver.QueryerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+	ctx, span := cn.c.start(ctx, query)
+	rows, err := q.QueryContext(ctx, query, args)
+	end(span, err)
+	return rows, err
+}
+
+func (cn *conn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	e, ok := cn.Conn.(driver.ExecerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+	ctx, span := cn.c.start(ctx, query)
+	res, err := e.ExecContext(ctx, query, args)
+	end(span, err)
+	return res, err
+}
+
+func (cn *conn) Prepare(query string) (driver.Stmt, error) {
+	return cn.PrepareContext(context.Background(), query)
+}
+
+func (cn *conn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	var st driver.Stmt
+	var err error
+	if p, ok := cn.Conn.(driver.ConnPrepareContext); ok {
+		st, err = p.PrepareContext(ctx, query)
+	} else {
+		st, err = cn.Conn.Prepare(query)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &stmt{Stmt: st, c: cn.c, query: query}, nil
+}
+
+func (cn *conn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	if b, ok := cn.Conn.(driver.ConnBeginTx); ok {
+		return b.BeginTx(ctx, opts)
+	}
+	return cn.Conn.Begin()
+}
+
+func (cn *conn) Ping(ctx context.Context) error {
+	if p, ok := cn.Conn.(driver.Pinger); ok {
+		return p.Ping(ctx)
+	}
+	return nil
+}
+
+func (cn *conn) ResetSession(ctx context.Context) error {
+	if r, ok := cn.Conn.(driver.SessionResetter); ok {
+		return r.ResetSession(ctx)
+	}
+	return nil
+}
+
+func (cn *conn) IsValid() bool {
+	if v, ok := cn.Conn.(driver.Validator); ok {
+		return v.IsValid()
+	}
+	return true
+}
+
+// stmt traces the executions of a prepared statement, one span each.
+type stmt struct {
+	driver.Stmt
+	c     *connector
+	query string
+}
+
+func (st *stmt) ExecContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
+	ctx, span := st.c.start(ctx, st.query)
+	var res driver.Result
+	var err error
+	if e, ok := st.Stmt.(driver.StmtExecContext); ok {
+		res, err = e.ExecContext(ctx, args)
+	} else {
+		res, err = st.Stmt.Exec(values(args))
+	}
+	end(span, err)
+	return res, err
+}
+
+func (st *stmt) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	ctx, span := st.c.start(ctx, st.query)
+	var rows driver.Rows
+	var err error
+	if q, ok := st.Stmt.(driver.StmtQueryContext); ok {
+		rows, err = q.QueryContext(ctx, args)
+	} else {
+		rows, err = st.Stmt.Query(values(args))
+	}
+	end(span, err)
+	return rows, err
+}
+
+func values(args []driver.NamedValue) []driver.Value {
+	out := make([]driver.Value, len(args))
+	for i, a := range args {
+		out[i] = a.Value
+	}
+	return out
+}
+
+func firstWord(query string) string {
+	fields := strings.Fields(query)
+	if len(fields) == 0 {
+		return ""
+	}
+	return strings.TrimLeft(fields[0], "(")
+}
+
+// closureSuffix matches the suffix of the functions of closures, e.g.
+// ".func1" or ".func2.3".
+var closureSuffix = regexp.MustCompile(`\.func\d+(\.\d+)*$`)
+
+// callerIn returns the name of the first function of package pkg (with its
+// trailing dot) on the call stack, without the package and receiver
+// pointer, or "" if there is none.
+func callerIn(pkg string) string {
+	pcs := make([]uintptr, 64)
+	frames := runtime.CallersFrames(pcs[:runtime.Callers(3, pcs)])
+	for {
+		frame, more := frames.Next()
+		if name, ok := strings.CutPrefix(frame.Function, pkg); ok {
+			name = closureSuffix.ReplaceAllString(name, "")
+			return strings.NewReplacer("(*", "", ")", "").Replace(name)
+		}
+		if !more {
+			return ""
+		}
+	}
+}
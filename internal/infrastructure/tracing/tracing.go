@@ -0,0 +1,165 @@
+/*
+Package tracing traces HTTP requests, service calls, database queries and
+ingestion stages with OpenTelemetry.
+
+Instrumented code calls Start wherever it is; until Init installs a tracer
+provider, and when tracing is disabled, spans are no-ops and cost next to
+nothing.
+
+Implementation notes:
+  - Spans are exported in batches over OTLP/HTTP to a collector, or written
+    as JSON lines to stdout or a file for offline use.
+  - HTTP spans are named after the chi route pattern, like the metrics, and
+    continue the trace of an incoming W3C traceparent header.
+  - Database spans come from a database/sql connector wrapping the driver
+    (see WrapConnector), so every query is traced without changes to the
+    repositories. Query parameters are never recorded.
+  - Start looks the tracer up on every call, so installing another provider
+    (as tests do) applies to every package at once.
+*/
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"sync/atomic"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+
+	"github.com/NoroSaroyan/log-parser/internal/config"
+)
+
+// Exporters of config.TracingConfig.
+const (
+	ExporterOTLP   = "otlp"
+	ExporterStdout = "stdout"
+	ExporterFile   = "file"
+)
+
+// instrumentationName is the instrumentation scope of every span.
+const instrumentationName = "github.com/NoroSaroyan/log-parser"
+
+// otlpTracesPath is the path of the OTLP/HTTP traces endpoint of a collector.
+const otlpTracesPath = "/v1/traces"
+
+// enabled is set while a provider installed by Init is recording, so
+// instrumentation can skip work whose only use is a span.
+var enabled atomic.Bool
+
+// Init installs the tracer provider configured by cfg and returns the
+// function flushing the pending spans and uninstalling it. With tracing
+// disabled it installs nothing.
+func Init(ctx context.Context, cfg config.TracingConfig) (shutdown func(context.Context) error, err error) {
+	if !cfg.Enabled {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	exporter, closeOutput, err := newExporter(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+	res, err := resource.Merge(resource.Default(),
+		resource.NewSchemaless(attribute.String("service.name", cfg.ServiceName)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create tracing resource: %w", err)
+	}
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(cfg.SampleRatio))),
+	)
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	enabled.Store(true)
+
+	return func(ctx context.Context) error {
+		enabled.Store(false)
+		otel.SetTracerProvider(noop.NewTracerProvider())
+		err := provider.Shutdown(ctx)
+		if closeOutput != nil {
+			if cerr := closeOutput.Close(); err == nil {
+				err = cerr
+			}
+		}
+		return err
+	}, nil
+}
+
+// newExporter returns the exporter of cfg and, for the file exporter, the
+// file to close after the last export.
+func newExporter(ctx context.Context, cfg config.TracingConfig) (sdktrace.SpanExporter, io.Closer, error) {
+	switch cfg.Exporter {
+	case ExporterOTLP:
+		var opts []otlptracehttp.Option
+		if cfg.Endpoint != "" {
+			u, err := url.Parse(cfg.Endpoint)
+			if err != nil {
+				return nil, nil, fmt.Errorf("invalid tracing endpoint: %w", err)
+			}
+			if u.Path == "" || u.Path == "/" {
+				u.Path = otlpTracesPath
+			}
+			opts = append(opts, otlptracehttp.WithEndpointURL(u.String()))
+		}
+		exporter, err := otlptracehttp.New(ctx, opts...)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to create OTLP exporter: %w", err)
+		}
+		return exporter, nil, nil
+	case ExporterStdout:
+		exporter, err := stdouttrace.New(stdouttrace.WithWriter(os.Stdout))
+		return exporter, nil, err
+	case ExporterFile:
+		f, err := os.OpenFile(cfg.File, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to open trace file: %w", err)
+		}
+		exporter, err := stdouttrace.New(stdouttrace.WithWriter(f))
+		if err != nil {
+			f.Close()
+			return nil, nil, err
+		}
+		return exporter, f, nil
+	}
+	return nil, nil, fmt.Errorf("unknown tracing exporter %q", cfg.Exporter)
+}
+
+// Enabled reports whether spans are being recorded.
+func Enabled() bool {
+	return enabled.Load()
+}
+
+// Start starts a span named name, a child of the span in ctx if any, and
+// returns it with the context holding it. The caller must end the span.
+func Start(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return otel.Tracer(instrumentationName).Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// Fail records err, if any, on span and marks the span failed.
+func Fail(span trace.Span, err error) {
+	if err == nil {
+		return
+	}
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+}
+
+// StartLinked starts a span named name in a new trace linked to link, for
+// background work that outlives the request that queued it, such as an
+// ingestion job. Use trace.LinkFromContext to get the link of a request.
+func StartLinked(link trace.Link, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return otel.Tracer(instrumentationName).Start(context.Background(), name,
+		trace.WithNewRoot(), trace.WithLinks(link), trace.WithAttributes(attrs...))
+}
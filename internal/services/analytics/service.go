@@ -0,0 +1,471 @@
+/*
+Package analytics provides aggregate production metrics over the stored
+station records.
+
+The AnalyticsService interface defines:
+- Computing first-pass and final yield per time bucket, grouped by station type, part number, product line and test tool version.
+- Describing the numeric measurements of one test step: summary statistics, percentiles, histogram and process capability.
+- Ranking error codes in a Pareto by occurrences or affected devices, and drilling down to the devices behind a code.
+
+Implementation notes:
+  - Yield counts devices, not sessions: every device is counted once per
+    station type, in the bucket and group of its first attempt there.
+    FirstPassPassed counts devices whose first attempt passed,
+    EventuallyPassed those with any passing attempt and Failed those that
+    never passed, so Attempted = EventuallyPassed + Failed.
+  - The time range applies to the first attempt, so a device retested after
+    the range still counts as eventually passed when the retest passed.
+  - Step statistics only cover measured values that parse as numbers; they
+    are stored as such next to the raw text when the steps are inserted.
+  - Cp and Cpk use the limits of the most frequent threshold of the
+    measurements, parsed by the threshold package (ranges and >, >=, <, <=
+    comparisons). Capability.Thresholds tells when the filters mix several
+    thresholds, e.g. across part numbers, and the result should be narrowed.
+  - Error codes are the normalized codes linked at ingestion (see the
+    errorcode package). An occurrence is a station record carrying the code
+    itself or in one of its steps, so a code repeated across the steps of one
+    session counts once.
+  - Grouping by tester is not supported: the parsed records carry no tester
+    or fixture ID (it only appears in free-text log lines), so the request is
+    rejected instead of returning a meaningless single group.
+*/
+package analytics
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/NoroSaroyan/log-parser/internal/domain/models/db"
+	"github.com/NoroSaroyan/log-parser/internal/domain/models/dto"
+	"github.com/NoroSaroyan/log-parser/internal/domain/repositories"
+	"github.com/NoroSaroyan/log-parser/internal/infrastructure/tracing"
+	"github.com/NoroSaroyan/log-parser/internal/services/threshold"
+)
+
+// GroupTester is the tester grouping of the API, which cannot be served.
+const GroupTester = "tester"
+
+// testTimeLayout is the layout of TestFinishedTime in the logs.
+const testTimeLayout = "2006-01-02 15:04:05"
+
+// Histogram bin limits.
+const (
+	DefaultBins = 20
+	MaxBins     = 200
+)
+
+// Pareto sort metrics.
+const (
+	SortOccurrences = "occurrences"
+	SortDevices     = "devices"
+)
+
+// Percentiles are the percentiles reported by StepStats, in percent.
+var Percentiles = []float64{1, 5, 25, 50, 75, 95, 99}
+
+// ErrInvalidQuery is returned for unknown groupings, buckets or time ranges.
+var ErrInvalidQuery = errors.New("invalid analytics query")
+
+// YieldQuery holds the raw yield parameters. Empty filters do not filter.
+type YieldQuery struct {
+	GroupBy     string // comma separated; station_type when empty
+	Bucket      string // hour, day (default) or week
+	StationType string
+	PartNumber  string
+	ProductLine string
+	From        string // "2006-01-02", "2006-01-02 15:04:05" or RFC 3339
+	To          string // same layouts; a bare date includes the whole day
+}
+
+// StepQuery holds the raw step statistics parameters. Empty filters do not filter.
+type StepQuery struct {
+	StationType     string
+	PartNumber      string
+	TestToolVersion string
+	From            string // same layouts as YieldQuery.From, applied to the station record
+	To              string
+	Bins            int // DefaultBins when zero, at most MaxBins
+}
+
+// ErrorQuery holds the raw error Pareto parameters. Empty filters do not filter.
+type ErrorQuery struct {
+	StationType string
+	PartNumber  string
+	From        string // same layouts as YieldQuery.From, applied to the station record
+	To          string
+	SortBy      string // occurrences (default) or devices
+	Limit       int    // top codes to return; all when zero
+}
+
+type AnalyticsService interface {
+	Yield(ctx context.Context, q YieldQuery) (dto.YieldReportDTO, error)
+	StepStats(ctx context.Context, name string, q StepQuery) (dto.StepStatsDTO, error)
+	ErrorPareto(ctx context.Context, q ErrorQuery) (dto.ErrorParetoDTO, error)
+	ErrorCodeDevices(ctx context.Context, code string, q ErrorQuery) (dto.ErrorCodeDevicesDTO, error)
+}
+
+type analyticsService struct {
+	repo repositories.AnalyticsRepository
+}
+
+func NewAnalyticsService(repo repositories.AnalyticsRepository) AnalyticsService {
+	return &analyticsService{repo: repo}
+}
+
+func (s *analyticsService) Yield(ctx context.Context, q YieldQuery) (dto.YieldReportDTO, error) {
+	ctx, span := tracing.Start(ctx, "analytics.Yield")
+	defer span.End()
+
+	yq, err := buildYieldQuery(q)
+	if err != nil {
+		return dto.YieldReportDTO{}, err
+	}
+
+	rows, err := s.repo.Yield(ctx, yq)
+	if err != nil {
+		return dto.YieldReportDTO{}, fmt.Errorf("failed to compute yield: %w", err)
+	}
+
+	report := dto.YieldReportDTO{
+		GroupBy: yq.GroupBy,
+		Bucket:  yq.Bucket,
+		From:    yq.From,
+		To:      yq.To,
+		Rows:    []dto.YieldRowDTO{},
+	}
+	for _, r := range rows {
+		report.Rows = append(report.Rows, dto.YieldRowDTO{
+			Bucket:           formatBucket(r.Bucket, yq.Bucket),
+			StationType:      r.StationType,
+			PartNumber:       r.PartNumber,
+			ProductLine:      r.ProductLine,
+			TestToolVersion:  r.TestToolVersion,
+			Attempted:        r.Attempted,
+			Sessions:         r.Sessions,
+			FirstPassPassed:  r.FirstPassPassed,
+			EventuallyPassed: r.EventuallyPassed,
+			Failed:           r.Failed,
+			FirstPassYield:   ratio(r.FirstPassPassed, r.Attempted),
+			FinalYield:       ratio(r.EventuallyPassed, r.Attempted),
+		})
+	}
+	return report, nil
+}
+
+func (s *analyticsService) StepStats(ctx context.Context, name string, q StepQuery) (dto.StepStatsDTO, error) {
+	ctx, span := tracing.Start(ctx, "analytics.StepStats")
+	defer span.End()
+
+	sq, bins, err := buildStepQuery(name, q)
+	if err != nil {
+		return dto.StepStatsDTO{}, err
+	}
+
+	st, err := s.repo.StepStats(ctx, sq)
+	if err != nil {
+		return dto.StepStatsDTO{}, fmt.Errorf("failed to compute step statistics: %w", err)
+	}
+	out := dto.StepStatsDTO{
+		StepName:    sq.StepName,
+		Count:       st.Count,
+		Min:         st.Min,
+		Max:         st.Max,
+		Mean:        st.Mean,
+		StdDev:      st.StdDev,
+		Percentiles: map[string]float64{},
+		Histogram:   []dto.HistogramBinDTO{},
+	}
+	if st.Count == 0 {
+		return out, nil
+	}
+	for i, p := range Percentiles {
+		if i < len(st.Percentiles) {
+			out.Percentiles["p"+strconv.FormatFloat(p, 'f', -1, 64)] = st.Percentiles[i]
+		}
+	}
+
+	// All values are equal: a single bin holds them.
+	if st.Min == st.Max {
+		out.Histogram = append(out.Histogram, dto.HistogramBinDTO{Lower: st.Min, Upper: st.Max, Count: st.Count})
+	} else {
+		counts, err := s.repo.StepHistogram(ctx, sq, st.Min, st.Max, bins)
+		if err != nil {
+			return dto.StepStatsDTO{}, fmt.Errorf("failed to compute step histogram: %w", err)
+		}
+		width := (st.Max - st.Min) / float64(bins)
+		for i, c := range counts {
+			upper := st.Min + width*float64(i+1)
+			if i == bins-1 {
+				upper = st.Max
+			}
+			out.Histogram = append(out.Histogram, dto.HistogramBinDTO{Lower: st.Min + width*float64(i), Upper: upper, Count: c})
+		}
+	}
+
+	thresholds, err := s.repo.StepThresholds(ctx, sq)
+	if err != nil {
+		return dto.StepStatsDTO{}, fmt.Errorf("failed to get step thresholds: %w", err)
+	}
+	out.Capability = capability(thresholds, st)
+	return out, nil
+}
+
+// capability computes Cp and Cpk against the limits of the most frequent
+// threshold, or returns nil when it has no numeric limits.
+func capability(thresholds []*db.StepThresholdDB, st *db.StepStatsDB) *dto.StepCapabilityDTO {
+	if len(thresholds) == 0 {
+		return nil
+	}
+	t, err := threshold.Parse(thresholds[0].Threshold)
+	if err != nil {
+		return nil
+	}
+	lsl, usl := t.Limits()
+	if lsl == nil && usl == nil {
+		return nil
+	}
+
+	c := &dto.StepCapabilityDTO{Threshold: thresholds[0].Threshold, LSL: lsl, USL: usl, Thresholds: len(thresholds)}
+	if st.StdDev == 0 {
+		return c
+	}
+	cpk := math.Inf(1)
+	if usl != nil {
+		cpk = math.Min(cpk, (*usl-st.Mean)/(3*st.StdDev))
+	}
+	if lsl != nil {
+		cpk = math.Min(cpk, (st.Mean-*lsl)/(3*st.StdDev))
+	}
+	c.Cpk = &cpk
+	if lsl != nil && usl != nil {
+		cp := (*usl - *lsl) / (6 * st.StdDev)
+		c.Cp = &cp
+	}
+	return c
+}
+
+func (s *analyticsService) ErrorPareto(ctx context.Context, q ErrorQuery) (dto.ErrorParetoDTO, error) {
+	ctx, span := tracing.Start(ctx, "analytics.ErrorPareto")
+	defer span.End()
+
+	eq, err := buildErrorQuery(q)
+	if err != nil {
+		return dto.ErrorParetoDTO{}, err
+	}
+	sortBy := strings.ToLower(strings.TrimSpace(q.SortBy))
+	switch sortBy {
+	case "":
+		sortBy = SortOccurrences
+	case SortOccurrences, SortDevices:
+	default:
+		return dto.ErrorParetoDTO{}, fmt.Errorf("%w: unknown sort %q", ErrInvalidQuery, q.SortBy)
+	}
+	if q.Limit < 0 {
+		return dto.ErrorParetoDTO{}, fmt.Errorf("%w: limit must not be negative", ErrInvalidQuery)
+	}
+
+	counts, err := s.repo.ErrorPareto(ctx, eq)
+	if err != nil {
+		return dto.ErrorParetoDTO{}, fmt.Errorf("failed to compute error pareto: %w", err)
+	}
+	metric := func(c *db.ErrorCodeCountDB) int { return c.Occurrences }
+	if sortBy == SortDevices {
+		metric = func(c *db.ErrorCodeCountDB) int { return c.Devices }
+		sort.SliceStable(counts, func(i, j int) bool { return counts[i].Devices > counts[j].Devices })
+	}
+
+	pareto := dto.ErrorParetoDTO{SortBy: sortBy, Codes: []dto.ErrorCodeCountDTO{}}
+	for _, c := range counts {
+		pareto.Total += metric(c)
+	}
+	cumulative := 0
+	for i, c := range counts {
+		if q.Limit > 0 && i == q.Limit {
+			break
+		}
+		cumulative += metric(c)
+		pareto.Codes = append(pareto.Codes, dto.ErrorCodeCountDTO{
+			Code:            c.Code,
+			Occurrences:     c.Occurrences,
+			Devices:         c.Devices,
+			Share:           ratio(metric(c), pareto.Total),
+			CumulativeShare: ratio(cumulative, pareto.Total),
+		})
+	}
+	return pareto, nil
+}
+
+func (s *analyticsService) ErrorCodeDevices(ctx context.Context, code string, q ErrorQuery) (dto.ErrorCodeDevicesDTO, error) {
+	ctx, span := tracing.Start(ctx, "analytics.ErrorCodeDevices")
+	defer span.End()
+
+	code = strings.ToUpper(strings.TrimSpace(code))
+	if code == "" {
+		return dto.ErrorCodeDevicesDTO{}, fmt.Errorf("%w: error code is required", ErrInvalidQuery)
+	}
+	eq, err := buildErrorQuery(q)
+	if err != nil {
+		return dto.ErrorCodeDevicesDTO{}, err
+	}
+
+	devices, err := s.repo.ErrorCodeDevices(ctx, eq, code)
+	if err != nil {
+		return dto.ErrorCodeDevicesDTO{}, fmt.Errorf("failed to get devices of error code: %w", err)
+	}
+	out := dto.ErrorCodeDevicesDTO{Code: code, Devices: []dto.ErrorCodeDeviceDTO{}}
+	for _, d := range devices {
+		out.Devices = append(out.Devices, dto.ErrorCodeDeviceDTO{
+			PCBANumber:  d.PCBANumber,
+			Occurrences: d.Occurrences,
+			Stations:    strings.Split(d.Stations, ","),
+			FirstSeen:   d.FirstSeen,
+			LastSeen:    d.LastSeen,
+		})
+	}
+	return out, nil
+}
+
+func buildErrorQuery(q ErrorQuery) (db.ErrorParetoQuery, error) {
+	eq := db.ErrorParetoQuery{
+		StationType: strings.TrimSpace(q.StationType),
+		PartNumber:  strings.TrimSpace(q.PartNumber),
+	}
+	var err error
+	if eq.From, err = normalizeTime(q.From, false); err != nil {
+		return eq, fmt.Errorf("%w: from: %v", ErrInvalidQuery, err)
+	}
+	if eq.To, err = normalizeTime(q.To, true); err != nil {
+		return eq, fmt.Errorf("%w: to: %v", ErrInvalidQuery, err)
+	}
+	if eq.From != "" && eq.To != "" && eq.From > eq.To {
+		return eq, fmt.Errorf("%w: from is after to", ErrInvalidQuery)
+	}
+	return eq, nil
+}
+
+func buildStepQuery(name string, q StepQuery) (db.StepStatsQuery, int, error) {
+	sq := db.StepStatsQuery{
+		StepName:        strings.TrimSpace(name),
+		StationType:     strings.TrimSpace(q.StationType),
+		PartNumber:      strings.TrimSpace(q.PartNumber),
+		TestToolVersion: strings.TrimSpace(q.TestToolVersion),
+	}
+	if sq.StepName == "" {
+		return sq, 0, fmt.Errorf("%w: step name is required", ErrInvalidQuery)
+	}
+	for _, p := range Percentiles {
+		sq.Percentiles = append(sq.Percentiles, p/100)
+	}
+
+	bins := q.Bins
+	switch {
+	case bins == 0:
+		bins = DefaultBins
+	case bins < 1 || bins > MaxBins:
+		return sq, 0, fmt.Errorf("%w: bins must be between 1 and %d", ErrInvalidQuery, MaxBins)
+	}
+
+	var err error
+	if sq.From, err = normalizeTime(q.From, false); err != nil {
+		return sq, 0, fmt.Errorf("%w: from: %v", ErrInvalidQuery, err)
+	}
+	if sq.To, err = normalizeTime(q.To, true); err != nil {
+		return sq, 0, fmt.Errorf("%w: to: %v", ErrInvalidQuery, err)
+	}
+	if sq.From != "" && sq.To != "" && sq.From > sq.To {
+		return sq, 0, fmt.Errorf("%w: from is after to", ErrInvalidQuery)
+	}
+	return sq, bins, nil
+}
+
+func buildYieldQuery(q YieldQuery) (db.YieldQuery, error) {
+	yq := db.YieldQuery{
+		Bucket:      strings.ToLower(strings.TrimSpace(q.Bucket)),
+		StationType: strings.TrimSpace(q.StationType),
+		PartNumber:  strings.TrimSpace(q.PartNumber),
+		ProductLine: strings.TrimSpace(q.ProductLine),
+	}
+
+	seen := make(map[string]bool)
+	for _, g := range strings.Split(q.GroupBy, ",") {
+		g = strings.ToLower(strings.TrimSpace(g))
+		switch g {
+		case "":
+			continue
+		case db.YieldGroupStationType, db.YieldGroupPartNumber, db.YieldGroupProductLine, db.YieldGroupToolVersion:
+		case GroupTester:
+			return yq, fmt.Errorf("%w: grouping by tester is not available, the parsed records carry no tester ID", ErrInvalidQuery)
+		default:
+			return yq, fmt.Errorf("%w: unknown group_by %q", ErrInvalidQuery, g)
+		}
+		if !seen[g] {
+			seen[g] = true
+			yq.GroupBy = append(yq.GroupBy, g)
+		}
+	}
+	if len(yq.GroupBy) == 0 {
+		yq.GroupBy = []string{db.YieldGroupStationType}
+	}
+
+	switch yq.Bucket {
+	case "":
+		yq.Bucket = db.YieldBucketDay
+	case db.YieldBucketHour, db.YieldBucketDay, db.YieldBucketWeek:
+	default:
+		return yq, fmt.Errorf("%w: unknown bucket %q", ErrInvalidQuery, q.Bucket)
+	}
+
+	var err error
+	if yq.From, err = normalizeTime(q.From, false); err != nil {
+		return yq, fmt.Errorf("%w: from: %v", ErrInvalidQuery, err)
+	}
+	if yq.To, err = normalizeTime(q.To, true); err != nil {
+		return yq, fmt.Errorf("%w: to: %v", ErrInvalidQuery, err)
+	}
+	if yq.From != "" && yq.To != "" && yq.From > yq.To {
+		return yq, fmt.Errorf("%w: from is after to", ErrInvalidQuery)
+	}
+	return yq, nil
+}
+
+// normalizeTime converts v to testTimeLayout. A bare date is the start of the
+// day, or its last second when endOfDay is set.
+func normalizeTime(v string, endOfDay bool) (string, error) {
+	v = strings.TrimSpace(v)
+	if v == "" {
+		return "", nil
+	}
+	if t, err := time.Parse("2006-01-02", v); err == nil {
+		if endOfDay {
+			t = t.Add(24*time.Hour - time.Second)
+		}
+		return t.Format(testTimeLayout), nil
+	}
+	for _, layout := range []string{testTimeLayout, time.RFC3339} {
+		if t, err := time.Parse(layout, v); err == nil {
+			return t.Format(testTimeLayout), nil
+		}
+	}
+	return "", fmt.Errorf("%q is not a date or time", v)
+}
+
+// formatBucket renders the start of a bucket; days and weeks as a date.
+func formatBucket(t time.Time, bucket string) string {
+	if bucket == db.YieldBucketHour {
+		return t.Format("2006-01-02 15:00")
+	}
+	return t.Format("2006-01-02")
+}
+
+func ratio(n, total int) float64 {
+	if total == 0 {
+		return 0
+	}
+	return float64(n) / float64(total)
+}
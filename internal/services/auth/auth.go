@@ -0,0 +1,118 @@
+/*
+Package auth authenticates REST API callers and decides what they may see.
+
+The AuthService interface defines:
+- Authenticating a request by API key or by JWT bearer token.
+- Creating, listing and revoking API keys (used by the CLI "apikey" mode).
+
+Implementation notes:
+  - Roles are ordered: viewer < engineer < admin. A role is allowed
+    everything the roles below it are allowed.
+  - API keys are random and shown once on creation; only their SHA-256 hash
+    is stored, so a lookup by hash identifies the key. Revoked keys are
+    rejected. The last use of a key is recorded at most once a minute.
+  - JWTs are verified locally with an HS256 secret or an RS256 public key
+    read from files. Tokens must carry "exp" and a role claim, and must match
+    the configured issuer and audience when those are set. The algorithm of
+    the token header must be one with a configured key, so "none" and
+    algorithm confusion are rejected.
+  - With authentication disabled every request gets the Anonymous principal,
+    which has the viewer role: the read endpoints stay usable, while uploads
+    and administration are refused. Setting auth.allow_anonymous_admin opts
+    in to the AnonymousAdmin principal instead, which has the admin role.
+  - Personal data and secrets are redacted in responses by the redaction
+    policy; a rule may reveal a field to a role and above. Reveals makes that
+    decision, and never reveals anything to Anonymous.
+*/
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// Role is the role of an authenticated caller.
+type Role string
+
+// Roles, from least to most privileged.
+const (
+	RoleViewer   Role = "viewer"
+	RoleEngineer Role = "engineer"
+	RoleAdmin    Role = "admin"
+)
+
+var roleRank = map[Role]int{RoleViewer: 1, RoleEngineer: 2, RoleAdmin: 3}
+
+var (
+	// ErrUnauthenticated is returned for missing, unknown, revoked, expired
+	// or otherwise invalid credentials.
+	ErrUnauthenticated = errors.New("unauthenticated")
+	// ErrInvalidRole is returned for role names other than viewer, engineer
+	// and admin.
+	ErrInvalidRole = errors.New("invalid role")
+	// ErrKeyExists is returned when an API key name is already taken.
+	ErrKeyExists = errors.New("api key already exists")
+	// ErrKeyNotFound is returned when revoking an unknown or revoked key.
+	ErrKeyNotFound = errors.New("api key not found")
+)
+
+// ParseRole returns the role named s.
+func ParseRole(s string) (Role, error) {
+	r := Role(s)
+	if _, ok := roleRank[r]; !ok {
+		return "", fmt.Errorf("%w %q: expected viewer, engineer or admin", ErrInvalidRole, s)
+	}
+	return r, nil
+}
+
+// Allows reports whether r is at least minRole. Unknown roles allow nothing.
+func (r Role) Allows(minRole Role) bool {
+	rank, ok := roleRank[r]
+	return ok && rank >= roleRank[minRole]
+}
+
+// Authentication methods reported in Principal.Method.
+const (
+	MethodAPIKey    = "api_key"
+	MethodJWT       = "jwt"
+	MethodAnonymous = "anonymous"
+)
+
+// Principal is an authenticated caller.
+type Principal struct {
+	// Name is the API key name or the "sub" claim of the token.
+	Name   string
+	Role   Role
+	Method string
+}
+
+// Anonymous is the principal of every request while authentication is
+// disabled. It may only read.
+var Anonymous = &Principal{Name: "anonymous", Role: RoleViewer, Method: MethodAnonymous}
+
+// AnonymousAdmin replaces Anonymous when auth.allow_anonymous_admin opts
+// in to unauthenticated uploads and administration.
+var AnonymousAdmin = &Principal{Name: "anonymous", Role: RoleAdmin, Method: MethodAnonymous}
+
+type principalKey struct{}
+
+// WithPrincipal returns a copy of ctx carrying p.
+func WithPrincipal(ctx context.Context, p *Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, p)
+}
+
+// FromContext returns the principal of ctx, or nil if there is none.
+func FromContext(ctx context.Context) *Principal {
+	p, _ := ctx.Value(principalKey{}).(*Principal)
+	return p
+}
+
+// Reveals reports whether the principal of ctx may see in clear a field
+// whose redaction rule reveals it to minRole. Requests without an
+// authenticated principal, including all requests while authentication is
+// disabled, see redacted values only.
+func Reveals(ctx context.Context, minRole Role) bool {
+	p := FromContext(ctx)
+	return p != nil && p.Method != MethodAnonymous && p.Role.Allows(minRole)
+}
@@ -0,0 +1,184 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/NoroSaroyan/log-parser/internal/config"
+)
+
+// DefaultRoleClaim is the claim holding the role when none is configured.
+const DefaultRoleClaim = "role"
+
+// clockSkew is the leeway applied to "exp" and "nbf".
+const clockSkew = time.Minute
+
+// jwtVerifier verifies compact HS256 and RS256 tokens.
+type jwtVerifier struct {
+	hsSecret  []byte
+	rsKey     *rsa.PublicKey
+	issuer    string
+	audience  string
+	roleClaim string
+	now       func() time.Time
+}
+
+// newJWTVerifier reads the keys configured in cfg. It returns nil when no
+// key is configured, in which case bearer tokens are not accepted.
+func newJWTVerifier(cfg config.JWTConfig) (*jwtVerifier, error) {
+	v := &jwtVerifier{
+		issuer:    cfg.Issuer,
+		audience:  cfg.Audience,
+		roleClaim: cfg.RoleClaim,
+		now:       time.Now,
+	}
+	if v.roleClaim == "" {
+		v.roleClaim = DefaultRoleClaim
+	}
+
+	if cfg.HS256SecretFile != "" {
+		secret, err := os.ReadFile(cfg.HS256SecretFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read HS256 secret: %w", err)
+		}
+		v.hsSecret = []byte(strings.TrimSpace(string(secret)))
+		if len(v.hsSecret) < 32 {
+			return nil, fmt.Errorf("HS256 secret in %s must be at least 32 bytes", cfg.HS256SecretFile)
+		}
+	}
+	if cfg.RS256PublicKeyFile != "" {
+		data, err := os.ReadFile(cfg.RS256PublicKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read RS256 public key: %w", err)
+		}
+		if v.rsKey, err = parseRSAPublicKey(data); err != nil {
+			return nil, fmt.Errorf("invalid RS256 public key in %s: %w", cfg.RS256PublicKeyFile, err)
+		}
+	}
+
+	if v.hsSecret == nil && v.rsKey == nil {
+		return nil, nil
+	}
+	return v, nil
+}
+
+// parseRSAPublicKey parses a PEM encoded PKIX or PKCS #1 RSA public key.
+func parseRSAPublicKey(data []byte) (*rsa.PublicKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("no PEM block found")
+	}
+	if key, err := x509.ParsePKCS1PublicKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	pub, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	key, ok := pub.(*rsa.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("not an RSA public key")
+	}
+	return key, nil
+}
+
+// verify checks the signature and claims of token and returns its
+// principal. Every error wraps ErrUnauthenticated.
+func (v *jwtVerifier) verify(token string) (*Principal, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("%w: malformed token", ErrUnauthenticated)
+	}
+
+	var header struct {
+		Alg string `json:"alg"`
+	}
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, fmt.Errorf("%w: invalid token header", ErrUnauthenticated)
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid token signature", ErrUnauthenticated)
+	}
+	signed := []byte(parts[0] + "." + parts[1])
+
+	switch {
+	case header.Alg == "HS256" && v.hsSecret != nil:
+		mac := hmac.New(sha256.New, v.hsSecret)
+		mac.Write(signed)
+		if !hmac.Equal(sig, mac.Sum(nil)) {
+			return nil, fmt.Errorf("%w: invalid token signature", ErrUnauthenticated)
+		}
+	case header.Alg == "RS256" && v.rsKey != nil:
+		digest := sha256.Sum256(signed)
+		if err := rsa.VerifyPKCS1v15(v.rsKey, crypto.SHA256, digest[:], sig); err != nil {
+			return nil, fmt.Errorf("%w: invalid token signature", ErrUnauthenticated)
+		}
+	default:
+		return nil, fmt.Errorf("%w: token algorithm %q is not accepted", ErrUnauthenticated, header.Alg)
+	}
+
+	var claims map[string]any
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return nil, fmt.Errorf("%w: invalid token claims", ErrUnauthenticated)
+	}
+	now := v.now()
+	exp, ok := claims["exp"].(float64)
+	if !ok {
+		return nil, fmt.Errorf("%w: token has no exp claim", ErrUnauthenticated)
+	}
+	if now.After(time.Unix(int64(exp), 0).Add(clockSkew)) {
+		return nil, fmt.Errorf("%w: token expired", ErrUnauthenticated)
+	}
+	if nbf, ok := claims["nbf"].(float64); ok && now.Add(clockSkew).Before(time.Unix(int64(nbf), 0)) {
+		return nil, fmt.Errorf("%w: token not valid yet", ErrUnauthenticated)
+	}
+	if v.issuer != "" && claims["iss"] != v.issuer {
+		return nil, fmt.Errorf("%w: unexpected token issuer", ErrUnauthenticated)
+	}
+	if v.audience != "" && !hasAudience(claims["aud"], v.audience) {
+		return nil, fmt.Errorf("%w: unexpected token audience", ErrUnauthenticated)
+	}
+
+	roleName, _ := claims[v.roleClaim].(string)
+	role, err := ParseRole(roleName)
+	if err != nil {
+		return nil, fmt.Errorf("%w: token has no valid %s claim", ErrUnauthenticated, v.roleClaim)
+	}
+	sub, _ := claims["sub"].(string)
+	return &Principal{Name: sub, Role: role, Method: MethodJWT}, nil
+}
+
+// hasAudience reports whether the "aud" claim, a string or a list of
+// strings, contains audience.
+func hasAudience(aud any, audience string) bool {
+	switch a := aud.(type) {
+	case string:
+		return a == audience
+	case []any:
+		for _, v := range a {
+			if v == audience {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func decodeSegment(seg string, v any) error {
+	data, err := base64.RawURLEncoding.DecodeString(seg)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
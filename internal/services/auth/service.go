@@ -0,0 +1,191 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/NoroSaroyan/log-parser/internal/config"
+	"github.com/NoroSaroyan/log-parser/internal/domain/models/db"
+	"github.com/NoroSaroyan/log-parser/internal/domain/repositories"
+	"github.com/NoroSaroyan/log-parser/internal/infrastructure/logger"
+	"github.com/NoroSaroyan/log-parser/internal/infrastructure/tracing"
+)
+
+// APIKeyPrefix starts every API key, so keys are easy to recognise, e.g. by
+// secret scanners.
+const APIKeyPrefix = "lpk_"
+
+// displayPrefixLen is the number of leading characters of a key stored in
+// clear to recognise it in listings.
+const displayPrefixLen = len(APIKeyPrefix) + 6
+
+// touchInterval is how stale the recorded last use of a key may get before
+// a request updates it.
+const touchInterval = time.Minute
+
+type AuthService interface {
+	Enabled() bool
+	Authenticate(ctx context.Context, apiKey, bearerToken string) (*Principal, error)
+	CreateAPIKey(ctx context.Context, name string, role Role) (string, *db.APIKeyDB, error)
+	ListAPIKeys(ctx context.Context) ([]*db.APIKeyDB, error)
+	RevokeAPIKey(ctx context.Context, name string) error
+}
+
+type authService struct {
+	enabled bool
+	// anonymous is the principal of every request while disabled.
+	anonymous *Principal
+	repo      repositories.APIKeyRepository
+	jwt       *jwtVerifier
+	now       func() time.Time
+}
+
+// NewAuthService creates a new AuthService for cfg. It fails when a
+// configured JWT key cannot be read.
+func NewAuthService(cfg config.AuthConfig, repo repositories.APIKeyRepository) (AuthService, error) {
+	s := &authService{enabled: cfg.Enabled, anonymous: Anonymous, repo: repo, now: time.Now}
+	if cfg.AllowAnonymousAdmin {
+		s.anonymous = AnonymousAdmin
+	}
+	if cfg.Enabled {
+		v, err := newJWTVerifier(cfg.JWT)
+		if err != nil {
+			return nil, err
+		}
+		s.jwt = v
+	}
+	return s, nil
+}
+
+// Enabled reports whether requests must be authenticated.
+func (s *authService) Enabled() bool {
+	return s.enabled
+}
+
+// Authenticate returns the principal of an API key or, when no key is
+// given, of a bearer token. It returns Anonymous, or AnonymousAdmin, while
+// authentication is disabled and an error wrapping ErrUnauthenticated for
+// bad credentials.
+func (s *authService) Authenticate(ctx context.Context, apiKey, bearerToken string) (*Principal, error) {
+	ctx, span := tracing.Start(ctx, "auth.Authenticate")
+	defer span.End()
+
+	if !s.enabled {
+		return s.anonymous, nil
+	}
+
+	switch {
+	case apiKey != "":
+		return s.authenticateKey(ctx, apiKey)
+	case bearerToken != "":
+		if s.jwt == nil {
+			return nil, fmt.Errorf("%w: bearer tokens are not configured", ErrUnauthenticated)
+		}
+		return s.jwt.verify(bearerToken)
+	}
+	return nil, fmt.Errorf("%w: no credentials", ErrUnauthenticated)
+}
+
+func (s *authService) authenticateKey(ctx context.Context, apiKey string) (*Principal, error) {
+	key, err := s.repo.GetByHash(ctx, hashKey(apiKey))
+	if err != nil {
+		return nil, err
+	}
+	if key == nil {
+		return nil, fmt.Errorf("%w: unknown api key", ErrUnauthenticated)
+	}
+	if key.RevokedAt != nil {
+		return nil, fmt.Errorf("%w: api key %q is revoked", ErrUnauthenticated, key.Name)
+	}
+	role, err := ParseRole(key.Role)
+	if err != nil {
+		return nil, fmt.Errorf("%w: api key %q has %v", ErrUnauthenticated, key.Name, err)
+	}
+
+	now := s.now()
+	if key.LastUsedAt == nil || now.Sub(*key.LastUsedAt) > touchInterval {
+		if err := s.repo.TouchLastUsed(ctx, key.ID, now); err != nil {
+			logger.WarnContext(ctx, "Failed to record API key use", logger.WithFields(map[string]interface{}{
+				"api_key": key.Name,
+				"error":   err.Error(),
+			}))
+		}
+	}
+	return &Principal{Name: key.Name, Role: role, Method: MethodAPIKey}, nil
+}
+
+// CreateAPIKey generates a key for name with role and stores its hash. The
+// returned plaintext key cannot be retrieved again.
+func (s *authService) CreateAPIKey(ctx context.Context, name string, role Role) (string, *db.APIKeyDB, error) {
+	ctx, span := tracing.Start(ctx, "auth.CreateAPIKey")
+	defer span.End()
+
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return "", nil, fmt.Errorf("api key name is required")
+	}
+	if _, err := ParseRole(string(role)); err != nil {
+		return "", nil, err
+	}
+	existing, err := s.repo.List(ctx)
+	if err != nil {
+		return "", nil, err
+	}
+	for _, k := range existing {
+		if k.Name == name {
+			return "", nil, fmt.Errorf("%w: %q", ErrKeyExists, name)
+		}
+	}
+
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", nil, fmt.Errorf("failed to generate api key: %w", err)
+	}
+	plain := APIKeyPrefix + base64.RawURLEncoding.EncodeToString(secret)
+	key := &db.APIKeyDB{
+		Name:    name,
+		Prefix:  plain[:displayPrefixLen],
+		KeyHash: hashKey(plain),
+		Role:    string(role),
+	}
+	if err := s.repo.Insert(ctx, key); err != nil {
+		return "", nil, err
+	}
+	return plain, key, nil
+}
+
+// ListAPIKeys returns every key, including revoked ones.
+func (s *authService) ListAPIKeys(ctx context.Context) ([]*db.APIKeyDB, error) {
+	ctx, span := tracing.Start(ctx, "auth.ListAPIKeys")
+	defer span.End()
+
+	return s.repo.List(ctx)
+}
+
+// RevokeAPIKey revokes the active key named name.
+func (s *authService) RevokeAPIKey(ctx context.Context, name string) error {
+	ctx, span := tracing.Start(ctx, "auth.RevokeAPIKey")
+	defer span.End()
+
+	ok, err := s.repo.Revoke(ctx, name)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return fmt.Errorf("%w: %q", ErrKeyNotFound, name)
+	}
+	return nil
+}
+
+// hashKey returns the hex SHA-256 of an API key. Keys carry 256 random
+// bits, so a fast unsalted hash is enough to make the stored value useless.
+func hashKey(apiKey string) string {
+	sum := sha256.Sum256([]byte(apiKey))
+	return hex.EncodeToString(sum[:])
+}
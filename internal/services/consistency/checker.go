@@ -0,0 +1,101 @@
+package consistency
+
+import (
+	"strings"
+
+	"github.com/NoroSaroyan/log-parser/internal/domain/models/dto"
+)
+
+// Classifications.
+const (
+	Expected   = "expected"
+	Suspicious = "suspicious"
+)
+
+// Conflict kinds.
+const (
+	KindFilledAtFinal = "filled_at_final"
+	KindChanged       = "changed"
+	KindCleared       = "cleared"
+	KindUpdated       = "updated"
+)
+
+// Conflict is one LogisticData field that differs between the PCBA and the
+// Final snapshot of a device.
+type Conflict struct {
+	Field          string
+	PCBAValue      string
+	FinalValue     string
+	Kind           string
+	Classification string
+}
+
+type field struct {
+	name       string
+	identifier bool
+	get        func(dto.LogisticDataDTO) string
+}
+
+// fields are the compared LogisticData fields in report order. Identifiers
+// must never change once assigned; versions are expected to move when the
+// device is flashed between the two stations.
+var fields = []field{
+	{"ProductSN", true, func(d dto.LogisticDataDTO) string { return d.ProductSN }},
+	{"PartNumber", true, func(d dto.LogisticDataDTO) string { return d.PartNumber }},
+	{"IMEI", true, func(d dto.LogisticDataDTO) string { return d.IMEI }},
+	{"IMSI", true, func(d dto.LogisticDataDTO) string { return d.IMSI }},
+	{"TcuICCID", true, func(d dto.LogisticDataDTO) string { return d.TcuICCID }},
+	{"PhoneNumber", true, func(d dto.LogisticDataDTO) string { return d.PhoneNumber }},
+	{"BleMac", true, func(d dto.LogisticDataDTO) string { return d.BleMac }},
+	{"BleSN", true, func(d dto.LogisticDataDTO) string { return d.BleSN }},
+	{"VPAppVersion", false, func(d dto.LogisticDataDTO) string { return d.VPAppVersion }},
+	{"VPBootLoaderVersion", false, func(d dto.LogisticDataDTO) string { return d.VPBootLoaderVersion }},
+	{"VPCoreVersion", false, func(d dto.LogisticDataDTO) string { return d.VPCoreVersion }},
+	{"BleVersion", false, func(d dto.LogisticDataDTO) string { return d.BleVersion }},
+	{"APAppVersion", false, func(d dto.LogisticDataDTO) string { return d.APAppVersion }},
+	{"APKernelVersion", false, func(d dto.LogisticDataDTO) string { return d.APKernelVersion }},
+	{"ManufacturerSoftwareVersion", false, func(d dto.LogisticDataDTO) string { return d.ManufacturerSoftwareVersion }},
+	{"ManufacturerHardwareVersion", false, func(d dto.LogisticDataDTO) string { return d.ManufacturerHardwareVersion }},
+	{"SupplierHardwareVersion", false, func(d dto.LogisticDataDTO) string { return d.SupplierHardwareVersion }},
+}
+
+// Compare compares the LogisticData snapshots of the PCBA and the Final
+// station field by field and returns one Conflict per differing field.
+//
+// A field empty at PCBA and set at Final is expected (filled_at_final). A
+// changed or cleared identifier is suspicious; a changed or cleared version
+// is expected.
+func Compare(pcba, final dto.LogisticDataDTO) []Conflict {
+	var conflicts []Conflict
+	for _, f := range fields {
+		a, b := strings.TrimSpace(f.get(pcba)), strings.TrimSpace(f.get(final))
+		if normalize(f.name, a) == normalize(f.name, b) {
+			continue
+		}
+		c := Conflict{Field: f.name, PCBAValue: a, FinalValue: b, Classification: Expected}
+		switch {
+		case a == "":
+			c.Kind = KindFilledAtFinal
+		case b == "":
+			c.Kind = KindCleared
+		case f.identifier:
+			c.Kind = KindChanged
+		default:
+			c.Kind = KindUpdated
+		}
+		if f.identifier && a != "" {
+			c.Classification = Suspicious
+		}
+		conflicts = append(conflicts, c)
+	}
+	return conflicts
+}
+
+// normalize drops formatting differences that do not change the value:
+// letter case everywhere, and separators in BLE MAC addresses.
+func normalize(name, v string) string {
+	if name == "BleMac" {
+		v = strings.NewReplacer(":", "", "-", "").Replace(v)
+	}
+	return strings.ToUpper(v)
+}
@@ -0,0 +1,240 @@
+/*
+Package consistency checks that the PCBA and the Final station of a device
+agree on its LogisticData.
+
+Both station records embed their own LogisticData snapshot and the dispatcher
+stores each of them as a separate logistic_data row. The ConsistencyService
+compares the two snapshots field by field (see Compare) and records the
+differences in logistic_conflict, so devices whose identity changed between
+the stations can be queried later.
+
+The ConsistencyService interface defines:
+  - Checking one device and replacing its stored conflicts,
+  - Checking every device of a batch of dispatched groups,
+  - Retrieving the stored conflicts grouped by device, as a list or one device
+    at a time.
+
+Implementation notes:
+  - When a device was tested several times, the latest PCBA and the latest
+    Final record (highest ID) are compared.
+  - A device without both stations has nothing to compare; its stored
+    conflicts are cleared.
+  - Checking is idempotent: re-running it for a device replaces its conflicts.
+*/
+package consistency
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/NoroSaroyan/log-parser/internal/domain/models/db"
+	"github.com/NoroSaroyan/log-parser/internal/domain/models/dto"
+	"github.com/NoroSaroyan/log-parser/internal/domain/repositories"
+	"github.com/NoroSaroyan/log-parser/internal/infrastructure/tracing"
+	"github.com/NoroSaroyan/log-parser/internal/services/converter/logistic"
+)
+
+type ConsistencyService interface {
+	CheckDevice(ctx context.Context, pcba string) ([]Conflict, error)
+	CheckGroups(ctx context.Context, groups []dto.GroupedDataDTO) error
+	GetConflicts(ctx context.Context, classification, pcba string) ([]dto.DeviceLogisticConflictsDTO, error)
+	ForEachConflicts(ctx context.Context, classification, pcba string, fn func(device dto.DeviceLogisticConflictsDTO) error) error
+}
+
+type consistencyService struct {
+	stationRepo  repositories.TestStationRecordRepository
+	logisticRepo repositories.LogisticDataRepository
+	conflictRepo repositories.LogisticConflictRepository
+}
+
+func NewConsistencyService(
+	stationRepo repositories.TestStationRecordRepository,
+	logisticRepo repositories.LogisticDataRepository,
+	conflictRepo repositories.LogisticConflictRepository,
+) ConsistencyService {
+	return &consistencyService{
+		stationRepo:  stationRepo,
+		logisticRepo: logisticRepo,
+		conflictRepo: conflictRepo,
+	}
+}
+
+func (s *consistencyService) CheckDevice(ctx context.Context, pcba string) ([]Conflict, error) {
+	ctx, span := tracing.Start(ctx, "consistency.CheckDevice")
+	defer span.End()
+
+	pcba = strings.TrimSpace(pcba)
+	records, err := s.stationRepo.GetByPCBANumber(ctx, pcba)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get TestStationRecords by PCBA number: %w", err)
+	}
+
+	var pcbaRec, finalRec *db.TestStationRecordDB
+	for _, rec := range records {
+		switch rec.TestStation {
+		case "PCBA":
+			if pcbaRec == nil || rec.ID > pcbaRec.ID {
+				pcbaRec = rec
+			}
+		case "Final":
+			if finalRec == nil || rec.ID > finalRec.ID {
+				finalRec = rec
+			}
+		}
+	}
+	if pcbaRec == nil || finalRec == nil {
+		if err := s.conflictRepo.ReplaceForPCBANumber(ctx, pcba, nil); err != nil {
+			return nil, fmt.Errorf("failed to clear LogisticConflicts: %w", err)
+		}
+		return nil, nil
+	}
+
+	pcbaData, err := s.logisticByID(ctx, pcbaRec.LogisticDataID)
+	if err != nil {
+		return nil, err
+	}
+	finalData, err := s.logisticByID(ctx, finalRec.LogisticDataID)
+	if err != nil {
+		return nil, err
+	}
+
+	conflicts := Compare(pcbaData, finalData)
+	rows := make([]*db.LogisticConflictDB, 0, len(conflicts))
+	for _, c := range conflicts {
+		rows = append(rows, &db.LogisticConflictDB{
+			PCBANumber:          pcba,
+			Field:               c.Field,
+			PCBAValue:           c.PCBAValue,
+			FinalValue:          c.FinalValue,
+			Kind:                c.Kind,
+			Classification:      c.Classification,
+			PCBALogisticDataID:  pcbaRec.LogisticDataID,
+			FinalLogisticDataID: finalRec.LogisticDataID,
+		})
+	}
+	if err := s.conflictRepo.ReplaceForPCBANumber(ctx, pcba, rows); err != nil {
+		return nil, fmt.Errorf("failed to store LogisticConflicts: %w", err)
+	}
+	return conflicts, nil
+}
+
+func (s *consistencyService) logisticByID(ctx context.Context, id int) (dto.LogisticDataDTO, error) {
+	data, err := s.logisticRepo.GetById(ctx, id)
+	if err != nil {
+		return dto.LogisticDataDTO{}, fmt.Errorf("failed to get LogisticData by ID: %w", err)
+	}
+	if data == nil {
+		return dto.LogisticDataDTO{}, fmt.Errorf("LogisticData %d not found", id)
+	}
+	return logistic.ConvertToDTO(*data), nil
+}
+
+// CheckGroups checks every device that has a station record in groups. It
+// continues with the remaining devices when one fails and returns the first
+// error.
+func (s *consistencyService) CheckGroups(ctx context.Context, groups []dto.GroupedDataDTO) error {
+	ctx, span := tracing.Start(ctx, "consistency.CheckGroups")
+	defer span.End()
+
+	seen := make(map[string]bool)
+	var firstErr error
+	for _, g := range groups {
+		for _, rec := range g.TestStationRecords {
+			pcba := strings.TrimSpace(rec.LogisticData.PCBANumber)
+			if pcba == "" || seen[pcba] {
+				continue
+			}
+			seen[pcba] = true
+			if _, err := s.CheckDevice(ctx, pcba); err != nil && firstErr == nil {
+				firstErr = fmt.Errorf("consistency check for %s: %w", pcba, err)
+			}
+		}
+	}
+	return firstErr
+}
+
+// GetConflicts returns the stored conflicts grouped by device. classification
+// and pcba are optional filters.
+func (s *consistencyService) GetConflicts(ctx context.Context, classification, pcba string) ([]dto.DeviceLogisticConflictsDTO, error) {
+	ctx, span := tracing.Start(ctx, "consistency.GetConflicts")
+	defer span.End()
+
+	devices := []dto.DeviceLogisticConflictsDTO{}
+	err := s.forEachConflicts(ctx, classification, pcba, func(device dto.DeviceLogisticConflictsDTO) error {
+		devices = append(devices, device)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return devices, nil
+}
+
+// ForEachConflicts calls fn with every device GetConflicts returns, in the
+// same order, as soon as the repository has read its last conflict, so the
+// conflicts of all devices are never held at once. It stops at, and returns,
+// the first error of fn.
+func (s *consistencyService) ForEachConflicts(ctx context.Context, classification, pcba string, fn func(device dto.DeviceLogisticConflictsDTO) error) error {
+	ctx, span := tracing.Start(ctx, "consistency.ForEachConflicts")
+	defer span.End()
+
+	return s.forEachConflicts(ctx, classification, pcba, fn)
+}
+
+// forEachConflicts groups the conflicts read from the repository, which are
+// ordered by PCBA number, into devices and calls fn with each one.
+func (s *consistencyService) forEachConflicts(ctx context.Context, classification, pcba string, fn func(device dto.DeviceLogisticConflictsDTO) error) error {
+	var device *dto.DeviceLogisticConflictsDTO
+	// fnErr keeps the errors of fn apart from those of the repository.
+	var fnErr error
+	add := func(r *db.LogisticConflictDB) error {
+		if classification != "" && r.Classification != classification {
+			return nil
+		}
+		if device != nil && device.PCBANumber != r.PCBANumber {
+			if fnErr = fn(*device); fnErr != nil {
+				return fnErr
+			}
+			device = nil
+		}
+		if device == nil {
+			device = &dto.DeviceLogisticConflictsDTO{PCBANumber: r.PCBANumber}
+		}
+		device.Suspicious = device.Suspicious || r.Classification == Suspicious
+		device.Conflicts = append(device.Conflicts, dto.LogisticConflictDTO{
+			Field:          r.Field,
+			PCBAValue:      r.PCBAValue,
+			FinalValue:     r.FinalValue,
+			Kind:           r.Kind,
+			Classification: r.Classification,
+			DetectedAt:     r.DetectedAt.Format(time.RFC3339),
+		})
+		return nil
+	}
+
+	var err error
+	if pcba = strings.TrimSpace(pcba); pcba != "" {
+		var rows []*db.LogisticConflictDB
+		if rows, err = s.conflictRepo.GetByPCBANumber(ctx, pcba); err == nil {
+			for _, r := range rows {
+				if err = add(r); err != nil {
+					break
+				}
+			}
+		}
+	} else {
+		err = s.conflictRepo.ForEach(ctx, classification, add)
+	}
+	if fnErr != nil {
+		return fnErr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get LogisticConflicts: %w", err)
+	}
+	if device != nil {
+		return fn(*device)
+	}
+	return nil
+}
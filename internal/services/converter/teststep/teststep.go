@@ -13,6 +13,8 @@ func ConvertToDB(dto dto.TestStepDTO, testStationRecordID int) db.TestStepDB {
 		TestStepElapsedTime: dto.TestStepElapsedTime,
 		TestStepResult:      dto.TestStepResult,
 		TestStepErrorCode:   dto.TestStepErrorCode,
+		RecomputedResult:    dto.RecomputedResult,
+		VerdictMismatch:     dto.VerdictMismatch,
 		TestStationRecordID: testStationRecordID,
 	}
 }
@@ -25,5 +27,7 @@ func ConvertToDTO(db db.TestStepDB) dto.TestStepDTO {
 		TestStepElapsedTime: db.TestStepElapsedTime,
 		TestStepResult:      db.TestStepResult,
 		TestStepErrorCode:   db.TestStepErrorCode,
+		RecomputedResult:    db.RecomputedResult,
+		VerdictMismatch:     db.VerdictMismatch,
 	}
 }
@@ -0,0 +1,74 @@
+package device
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/NoroSaroyan/log-parser/internal/domain/models/db"
+	"github.com/NoroSaroyan/log-parser/internal/domain/models/dto"
+	"github.com/NoroSaroyan/log-parser/internal/infrastructure/tracing"
+)
+
+// Identifiers lists the secondary identifiers Search accepts.
+var Identifiers = []string{
+	db.IdentifierIMEI,
+	db.IdentifierIMSI,
+	db.IdentifierICCID,
+	db.IdentifierBleMac,
+	db.IdentifierBleSN,
+	db.IdentifierProductSN,
+}
+
+// Search finds the devices whose LogisticData carries value as the given
+// secondary identifier. Every station stores its own LogisticData snapshot,
+// so matches are grouped by PCBA number and report the identifiers of the
+// latest matching snapshot. The result is Ambiguous when value maps to more
+// than one device.
+func (s *deviceService) Search(ctx context.Context, identifier, value string) (dto.DeviceSearchDTO, error) {
+	ctx, span := tracing.Start(ctx, "device.Search")
+	defer span.End()
+
+	value = strings.TrimSpace(value)
+	result := dto.DeviceSearchDTO{Identifier: identifier, Value: value, Matches: []dto.DeviceMatchDTO{}}
+	if !isIdentifier(identifier) {
+		return result, fmt.Errorf("%w: identifier must be one of %s", ErrInvalidQuery, strings.Join(Identifiers, ", "))
+	}
+	if value == "" {
+		return result, fmt.Errorf("%w: %s must not be empty", ErrInvalidQuery, identifier)
+	}
+	if identifier == db.IdentifierBleMac {
+		value = strings.ToUpper(strings.NewReplacer(":", "", "-", "").Replace(value))
+	}
+
+	rows, err := s.logisticRepo.GetByIdentifier(ctx, identifier, value)
+	if err != nil {
+		return result, fmt.Errorf("failed to get LogisticData by %s: %w", identifier, err)
+	}
+
+	index := make(map[string]int)
+	for _, d := range rows {
+		i, ok := index[d.PCBANumber]
+		if !ok {
+			i = len(result.Matches)
+			index[d.PCBANumber] = i
+			result.Matches = append(result.Matches, dto.DeviceMatchDTO{PCBANumber: d.PCBANumber})
+		}
+		// Rows are ordered by ID, so the last one is the latest snapshot.
+		m := &result.Matches[i]
+		m.ProductSN, m.IMEI, m.IMSI = d.ProductSN, d.IMEI, d.IMSI
+		m.TcuICCID, m.BleMac, m.BleSN = d.TcuICCID, d.BleMac, d.BleSN
+		m.Snapshots++
+	}
+	result.Ambiguous = len(result.Matches) > 1
+	return result, nil
+}
+
+func isIdentifier(identifier string) bool {
+	for _, id := range Identifiers {
+		if id == identifier {
+			return true
+		}
+	}
+	return false
+}
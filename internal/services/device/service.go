@@ -0,0 +1,254 @@
+/*
+Package device provides the device-centric read model of the API.
+
+A device is a PCBA number together with all station records stored for it.
+The DeviceService interface defines:
+- Listing devices page by page with filters, sorting and a total count,
+- Building the timeline and lifecycle status of one device,
+- Looking devices up by a secondary identifier (IMEI, IMSI, ICCID, BLE MAC, BLE SN, ProductSN).
+
+Implementation notes:
+  - Filters apply to station records: a device is listed when at least one
+    of its records matches all of them, and its summary (stations, latest
+    part number, pass/fail, test time range) covers the matching records.
+  - Pagination is keyset based. NextCursor is an opaque token encoding the
+    sort order and the sort key of the last device on the page, so pages stay
+    stable while new devices are inserted and deep pages cost the same as the
+    first one.
+  - Test times are stored as text ("2006-01-02 15:04:05"), so the time range
+    is normalized to that layout and compared as text; sessions of a timeline
+    are ordered the same way.
+  - Attempts are numbered per station type in chronological order.
+*/
+package device
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/NoroSaroyan/log-parser/internal/domain/models/db"
+	"github.com/NoroSaroyan/log-parser/internal/domain/models/dto"
+	"github.com/NoroSaroyan/log-parser/internal/domain/repositories"
+	"github.com/NoroSaroyan/log-parser/internal/infrastructure/tracing"
+)
+
+// Page size limits.
+const (
+	DefaultLimit = 50
+	MaxLimit     = 500
+)
+
+// testTimeLayout is the layout of TestFinishedTime in the logs.
+const testTimeLayout = "2006-01-02 15:04:05"
+
+// ErrInvalidQuery is returned for malformed filters, sort options or cursors.
+var ErrInvalidQuery = errors.New("invalid device query")
+
+// ListQuery holds the raw listing parameters. Empty fields do not filter.
+type ListQuery struct {
+	StationType     string
+	PartNumber      string
+	ProductLine     string
+	TestToolVersion string
+	Passed          *bool
+	TestedFrom      string // "2006-01-02", "2006-01-02 15:04:05" or RFC 3339
+	TestedTo        string // same layouts; a bare date includes the whole day
+
+	Sort   string // pcba_number (default), first_tested or last_tested
+	Order  string // asc (default) or desc
+	Limit  int    // DefaultLimit when zero, at most MaxLimit
+	Cursor string // NextCursor of the previous page
+}
+
+type DeviceService interface {
+	ListDevices(ctx context.Context, q ListQuery) (dto.DevicePageDTO, error)
+	GetTimeline(ctx context.Context, pcba string) (dto.DeviceTimelineDTO, bool, error)
+	Search(ctx context.Context, identifier, value string) (dto.DeviceSearchDTO, error)
+}
+
+type deviceService struct {
+	downloadRepo repositories.DownloadInfoRepository
+	logisticRepo repositories.LogisticDataRepository
+	stationRepo  repositories.TestStationRecordRepository
+	stepRepo     repositories.TestStepRepository
+}
+
+func NewDeviceService(
+	downloadRepo repositories.DownloadInfoRepository,
+	logisticRepo repositories.LogisticDataRepository,
+	stationRepo repositories.TestStationRecordRepository,
+	stepRepo repositories.TestStepRepository,
+) DeviceService {
+	return &deviceService{
+		downloadRepo: downloadRepo,
+		logisticRepo: logisticRepo,
+		stationRepo:  stationRepo,
+		stepRepo:     stepRepo,
+	}
+}
+
+// cursor is the decoded form of NextCursor.
+type cursor struct {
+	Sort string `json:"s"`
+	Desc bool   `json:"d"`
+	Key  string `json:"k"`
+	PCBA string `json:"p"`
+}
+
+func (s *deviceService) ListDevices(ctx context.Context, q ListQuery) (dto.DevicePageDTO, error) {
+	ctx, span := tracing.Start(ctx, "device.ListDevices")
+	defer span.End()
+
+	f, err := buildFilter(q)
+	if err != nil {
+		return dto.DevicePageDTO{}, err
+	}
+
+	total, err := s.stationRepo.CountDevices(ctx, f)
+	if err != nil {
+		return dto.DevicePageDTO{}, fmt.Errorf("failed to count devices: %w", err)
+	}
+
+	// One extra row tells whether another page follows.
+	limit := f.Limit
+	f.Limit++
+	rows, err := s.stationRepo.ListDevices(ctx, f)
+	if err != nil {
+		return dto.DevicePageDTO{}, fmt.Errorf("failed to list devices: %w", err)
+	}
+
+	page := dto.DevicePageDTO{Devices: []dto.DeviceDTO{}, Total: total}
+	if len(rows) > limit {
+		rows = rows[:limit]
+		last := rows[limit-1]
+		page.NextCursor = encodeCursor(cursor{Sort: f.Sort, Desc: f.Desc, Key: sortKey(f.Sort, last), PCBA: last.PCBANumber})
+	}
+	for _, d := range rows {
+		page.Devices = append(page.Devices, toDTO(d))
+	}
+	return page, nil
+}
+
+func buildFilter(q ListQuery) (db.DeviceFilter, error) {
+	f := db.DeviceFilter{
+		StationType:     strings.TrimSpace(q.StationType),
+		PartNumber:      strings.TrimSpace(q.PartNumber),
+		ProductLine:     strings.TrimSpace(q.ProductLine),
+		TestToolVersion: strings.TrimSpace(q.TestToolVersion),
+		Passed:          q.Passed,
+		Sort:            q.Sort,
+		Limit:           q.Limit,
+	}
+
+	switch f.Sort {
+	case "":
+		f.Sort = db.DeviceSortPCBANumber
+	case db.DeviceSortPCBANumber, db.DeviceSortFirstTested, db.DeviceSortLastTested:
+	default:
+		return f, fmt.Errorf("%w: sort must be one of %s, %s, %s", ErrInvalidQuery,
+			db.DeviceSortPCBANumber, db.DeviceSortFirstTested, db.DeviceSortLastTested)
+	}
+	switch strings.ToLower(q.Order) {
+	case "", "asc":
+	case "desc":
+		f.Desc = true
+	default:
+		return f, fmt.Errorf("%w: order must be asc or desc", ErrInvalidQuery)
+	}
+
+	switch {
+	case f.Limit == 0:
+		f.Limit = DefaultLimit
+	case f.Limit < 0 || f.Limit > MaxLimit:
+		return f, fmt.Errorf("%w: limit must be between 1 and %d", ErrInvalidQuery, MaxLimit)
+	}
+
+	var err error
+	if f.TestedFrom, err = normalizeTime(q.TestedFrom, false); err != nil {
+		return f, fmt.Errorf("%w: tested_from: %v", ErrInvalidQuery, err)
+	}
+	if f.TestedTo, err = normalizeTime(q.TestedTo, true); err != nil {
+		return f, fmt.Errorf("%w: tested_to: %v", ErrInvalidQuery, err)
+	}
+	if f.TestedFrom != "" && f.TestedTo != "" && f.TestedFrom > f.TestedTo {
+		return f, fmt.Errorf("%w: tested_from is after tested_to", ErrInvalidQuery)
+	}
+
+	if q.Cursor != "" {
+		c, err := decodeCursor(q.Cursor)
+		if err != nil || c.Sort != f.Sort || c.Desc != f.Desc {
+			return f, fmt.Errorf("%w: cursor does not belong to this sort order", ErrInvalidQuery)
+		}
+		f.After, f.AfterKey, f.AfterPCBA = true, c.Key, c.PCBA
+	}
+	return f, nil
+}
+
+// normalizeTime converts v to testTimeLayout. A bare date is the start of the
+// day, or its last second when endOfDay is set.
+func normalizeTime(v string, endOfDay bool) (string, error) {
+	v = strings.TrimSpace(v)
+	if v == "" {
+		return "", nil
+	}
+	if t, err := time.Parse("2006-01-02", v); err == nil {
+		if endOfDay {
+			t = t.Add(24*time.Hour - time.Second)
+		}
+		return t.Format(testTimeLayout), nil
+	}
+	for _, layout := range []string{testTimeLayout, time.RFC3339} {
+		if t, err := time.Parse(layout, v); err == nil {
+			return t.Format(testTimeLayout), nil
+		}
+	}
+	return "", fmt.Errorf("%q is not a date or time", v)
+}
+
+func sortKey(sort string, d *db.DeviceDB) string {
+	switch sort {
+	case db.DeviceSortFirstTested:
+		return d.FirstTested
+	case db.DeviceSortLastTested:
+		return d.LastTested
+	}
+	return d.PCBANumber
+}
+
+func encodeCursor(c cursor) string {
+	b, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func decodeCursor(s string) (cursor, error) {
+	var c cursor
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return c, err
+	}
+	err = json.Unmarshal(b, &c)
+	return c, err
+}
+
+func toDTO(d *db.DeviceDB) dto.DeviceDTO {
+	stations := []string{}
+	if d.Stations != "" {
+		stations = strings.Split(d.Stations, ",")
+	}
+	return dto.DeviceDTO{
+		PCBANumber:      d.PCBANumber,
+		PartNumber:      d.PartNumber,
+		ProductLine:     d.ProductLine,
+		TestToolVersion: d.TestToolVersion,
+		Stations:        stations,
+		LastPassed:      d.LastPassed,
+		FirstTested:     d.FirstTested,
+		LastTested:      d.LastTested,
+		RecordCount:     d.RecordCount,
+	}
+}
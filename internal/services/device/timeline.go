@@ -0,0 +1,153 @@
+package device
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/NoroSaroyan/log-parser/internal/domain/models/db"
+	"github.com/NoroSaroyan/log-parser/internal/domain/models/dto"
+	"github.com/NoroSaroyan/log-parser/internal/infrastructure/tracing"
+	"github.com/NoroSaroyan/log-parser/internal/services/converter/download"
+	"github.com/NoroSaroyan/log-parser/internal/services/converter/logistic"
+	"github.com/NoroSaroyan/log-parser/internal/services/converter/teststep"
+)
+
+// Lifecycle statuses, in production order.
+const (
+	StatusFlashed     = "flashed"
+	StatusPCBAPassed  = "pcba_passed"
+	StatusFinalPassed = "final_passed"
+	StatusStuck       = "stuck"
+)
+
+// Session results.
+const (
+	ResultPass = "PASS"
+	ResultFail = "FAIL"
+)
+
+// Production stages reported in StuckAt.
+const (
+	StageDownload = "Download"
+	StagePCBA     = "PCBA"
+	StageFinal    = "Final"
+)
+
+// GetTimeline returns everything stored for pcba: the flash history, the
+// latest LogisticData snapshot and every station session in chronological
+// order with its steps. found is false when nothing is stored for pcba.
+func (s *deviceService) GetTimeline(ctx context.Context, pcba string) (dto.DeviceTimelineDTO, bool, error) {
+	ctx, span := tracing.Start(ctx, "device.GetTimeline")
+	defer span.End()
+
+	pcba = strings.TrimSpace(pcba)
+	tl := dto.DeviceTimelineDTO{PCBANumber: pcba, Downloads: []dto.DownloadInfoDTO{}, Sessions: []dto.DeviceSessionDTO{}}
+
+	downloads, err := s.downloadRepo.GetAllByPCBANumber(ctx, pcba)
+	if err != nil {
+		return tl, false, fmt.Errorf("failed to get DownloadInfo history: %w", err)
+	}
+	sort.SliceStable(downloads, func(i, j int) bool {
+		return downloads[i].DownloadFinishedTime < downloads[j].DownloadFinishedTime
+	})
+	for _, d := range downloads {
+		tl.Downloads = append(tl.Downloads, download.ConvertToDTO(*d))
+	}
+
+	records, err := s.stationRepo.GetByPCBANumber(ctx, pcba)
+	if err != nil {
+		return tl, false, fmt.Errorf("failed to get TestStationRecords: %w", err)
+	}
+	if len(downloads) == 0 && len(records) == 0 {
+		return tl, false, nil
+	}
+	sortChronologically(records)
+
+	attempts := make(map[string]int)
+	for _, rec := range records {
+		steps, err := s.stepRepo.GetByTestStationRecordID(ctx, rec.ID)
+		if err != nil {
+			return tl, false, fmt.Errorf("failed to get TestSteps of record %d: %w", rec.ID, err)
+		}
+		attempts[rec.TestStation]++
+		tl.Sessions = append(tl.Sessions, toSession(rec, attempts[rec.TestStation], steps))
+	}
+
+	if len(records) > 0 {
+		latest := records[len(records)-1]
+		data, err := s.logisticRepo.GetById(ctx, latest.LogisticDataID)
+		if err != nil {
+			return tl, false, fmt.Errorf("failed to get LogisticData: %w", err)
+		}
+		if data != nil {
+			tl.LogisticData = logistic.ConvertToDTO(*data)
+		}
+	}
+
+	tl.Status, tl.StuckAt = lifecycle(tl.Downloads, tl.Sessions)
+	return tl, true, nil
+}
+
+// sortChronologically orders records by TestFinishedTime, then by insertion.
+func sortChronologically(records []*db.TestStationRecordDB) {
+	sort.SliceStable(records, func(i, j int) bool {
+		if records[i].TestFinishedTime != records[j].TestFinishedTime {
+			return records[i].TestFinishedTime < records[j].TestFinishedTime
+		}
+		return records[i].ID < records[j].ID
+	})
+}
+
+func toSession(rec *db.TestStationRecordDB, attempt int, steps []*db.TestStepDB) dto.DeviceSessionDTO {
+	session := dto.DeviceSessionDTO{
+		TestStation:      rec.TestStation,
+		Attempt:          attempt,
+		TestFinishedTime: rec.TestFinishedTime,
+		Result:           ResultFail,
+		IsAllPassed:      rec.IsAllPassed,
+		ErrorCodes:       rec.ErrorCodes,
+		PartNumber:       rec.PartNumber,
+		ProductLine:      rec.ProductLine,
+		TestToolVersion:  rec.TestToolVersion,
+		LogisticDataID:   rec.LogisticDataID,
+		TestSteps:        []dto.TestStepDTO{},
+	}
+	if rec.IsAllPassed {
+		session.Result = ResultPass
+	}
+	for _, step := range steps {
+		session.TestSteps = append(session.TestSteps, teststep.ConvertToDTO(*step))
+	}
+	return session
+}
+
+// lifecycle derives the status of a device from its furthest stage: the
+// latest Final session if there is one, else the latest PCBA session, else
+// the latest flash. A failed latest attempt at that stage means the device is
+// stuck there. The status reflects the stored data only; a device that passed
+// PCBA long ago and never reached Final is still pcba_passed.
+func lifecycle(downloads []dto.DownloadInfoDTO, sessions []dto.DeviceSessionDTO) (status, stuckAt string) {
+	latest := make(map[string]dto.DeviceSessionDTO)
+	for _, s := range sessions {
+		latest[s.TestStation] = s
+	}
+
+	if s, ok := latest[StageFinal]; ok {
+		if s.IsAllPassed {
+			return StatusFinalPassed, ""
+		}
+		return StatusStuck, StageFinal
+	}
+	if s, ok := latest[StagePCBA]; ok {
+		if s.IsAllPassed {
+			return StatusPCBAPassed, ""
+		}
+		return StatusStuck, StagePCBA
+	}
+	if len(downloads) > 0 && strings.EqualFold(strings.TrimSpace(downloads[len(downloads)-1].TcuEntityFlashState), "Success") {
+		return StatusFlashed, ""
+	}
+	return StatusStuck, StageDownload
+}
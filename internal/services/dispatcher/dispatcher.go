@@ -5,8 +5,12 @@ import (
 	"fmt"
 	"strings"
 
+	"go.opentelemetry.io/otel/attribute"
+
 	"github.com/NoroSaroyan/log-parser/internal/domain/models/dto"
 	"github.com/NoroSaroyan/log-parser/internal/infrastructure/logger"
+	"github.com/NoroSaroyan/log-parser/internal/infrastructure/metrics"
+	"github.com/NoroSaroyan/log-parser/internal/infrastructure/tracing"
 	"github.com/NoroSaroyan/log-parser/internal/services/downloadinfo"
 	"github.com/NoroSaroyan/log-parser/internal/services/logistic"
 	"github.com/NoroSaroyan/log-parser/internal/services/parser"
@@ -88,8 +92,36 @@ type DispatcherService interface {
 	//   - groups: slice of grouped data DTOs, each containing DownloadInfo, TestStationRecords, and TestSteps.
 	//
 	// Returns:
+	//   - DispatchReport with the outcome of every group.
 	//   - error if any insertion operation fails or data mismatches occur.
-	DispatchGroups(ctx context.Context, groups []dto.GroupedDataDTO) error
+	DispatchGroups(ctx context.Context, groups []dto.GroupedDataDTO) (DispatchReport, error)
+}
+
+// Group outcome statuses reported in GroupOutcome.Status.
+const (
+	OutcomeOK           = "ok"            // all rows inserted, every step array paired
+	OutcomeExcessSteps  = "excess_steps"  // inserted, but unmatched step arrays were skipped
+	OutcomeTypeMismatch = "type_mismatch" // inserted, but steps were paired to a station of another type
+	OutcomeFailed       = "failed"        // abandoned at FailedStage
+)
+
+// GroupOutcome describes what happened to one group during dispatch.
+type GroupOutcome struct {
+	PCBA                string `json:"pcba"`
+	Status              string `json:"status"`
+	FailedStage         string `json:"failed_stage,omitempty"`
+	Error               string `json:"error,omitempty"`
+	UnmatchedStepArrays int    `json:"unmatched_step_arrays,omitempty"`
+	TypeMismatches      int    `json:"type_mismatches,omitempty"`
+}
+
+// DispatchReport summarises a DispatchGroups call.
+type DispatchReport struct {
+	GroupsOK           int            `json:"groups_ok"`
+	GroupsFailed       int            `json:"groups_failed"`
+	GroupsWithExcess   int            `json:"groups_with_excess"`
+	GroupsMismatchType int            `json:"groups_mismatch_type"`
+	Outcomes           []GroupOutcome `json:"outcomes"`
 }
 
 type dispatcherService struct {
@@ -124,25 +156,30 @@ func NewDispatcherService(
 // scenario — e.g. DB unreachable).
 //
 // See interface documentation for full details.
-func (s *dispatcherService) DispatchGroups(ctx context.Context, groups []dto.GroupedDataDTO) error {
-	logger.Info("Dispatch starting",
+func (s *dispatcherService) DispatchGroups(ctx context.Context, groups []dto.GroupedDataDTO) (DispatchReport, error) {
+	ctx, span := tracing.Start(ctx, "dispatcher.DispatchGroups", attribute.Int("groups", len(groups)))
+	defer span.End()
+
+	logger.InfoContext(ctx, "Dispatch starting",
 		logger.WithFields(map[string]interface{}{
 			"group_count": len(groups),
 		}),
 	)
 
-	var (
-		groupsOK           int
-		groupsFailed       int
-		groupsWithExcess   int // processed OK but had unmatched step arrays
-		groupsMismatchType int // processed OK but had step/station type mismatches
-	)
+	report := DispatchReport{
+		Outcomes: make([]GroupOutcome, 0, len(groups)),
+	}
 
 	for _, group := range groups {
-		result := s.dispatchSingleGroup(ctx, group)
+		groupCtx, groupSpan := tracing.Start(ctx, "dispatcher.dispatchGroup", attribute.String("pcba", groupKey(group)))
+		result := s.dispatchSingleGroup(groupCtx, group)
+		tracing.Fail(groupSpan, result.err)
+		groupSpan.End()
+		report.Outcomes = append(report.Outcomes, result.outcome(groupKey(group)))
 		if result.err != nil {
-			groupsFailed++
-			logger.Warn("Group dispatch failed — skipping group, continuing with next",
+			report.GroupsFailed++
+			metrics.Groups.WithLabelValues("failed").Inc()
+			logger.WarnContext(ctx, "Group dispatch failed — skipping group, continuing with next",
 				logger.WithFields(map[string]interface{}{
 					"pcba":           groupKey(group),
 					"error":          result.err.Error(),
@@ -153,29 +190,32 @@ func (s *dispatcherService) DispatchGroups(ctx context.Context, groups []dto.Gro
 			)
 			continue
 		}
-		groupsOK++
+		report.GroupsOK++
+		metrics.Groups.WithLabelValues("ok").Inc()
 		if result.unmatchedStepArrays > 0 {
-			groupsWithExcess++
+			report.GroupsWithExcess++
 		}
 		if result.typeMismatches > 0 {
-			groupsMismatchType++
+			report.GroupsMismatchType++
 		}
 	}
 
-	logger.Info("Dispatch finished",
+	logger.InfoContext(ctx, "Dispatch finished",
 		logger.WithFields(map[string]interface{}{
 			"group_count":          len(groups),
-			"groups_ok":            groupsOK,
-			"groups_failed":        groupsFailed,
-			"groups_with_excess":   groupsWithExcess,
-			"groups_mismatch_type": groupsMismatchType,
+			"groups_ok":            report.GroupsOK,
+			"groups_failed":        report.GroupsFailed,
+			"groups_with_excess":   report.GroupsWithExcess,
+			"groups_mismatch_type": report.GroupsMismatchType,
 		}),
 	)
 
-	if len(groups) > 0 && groupsOK == 0 {
-		return fmt.Errorf("all %d groups failed to dispatch; see WARN events for details", len(groups))
+	if len(groups) > 0 && report.GroupsOK == 0 {
+		err := fmt.Errorf("all %d groups failed to dispatch; see WARN events for details", len(groups))
+		tracing.Fail(span, err)
+		return report, err
 	}
-	return nil
+	return report, nil
 }
 
 // groupDispatchResult summarises the outcome of dispatching one group.
@@ -189,6 +229,27 @@ type groupDispatchResult struct {
 	typeMismatches      int    // paired but station_type != inferred step type
 }
 
+// outcome converts the internal result into the exported GroupOutcome.
+func (r groupDispatchResult) outcome(pcba string) GroupOutcome {
+	o := GroupOutcome{
+		PCBA:                pcba,
+		Status:              OutcomeOK,
+		UnmatchedStepArrays: r.unmatchedStepArrays,
+		TypeMismatches:      r.typeMismatches,
+	}
+	switch {
+	case r.err != nil:
+		o.Status = OutcomeFailed
+		o.FailedStage = r.failedStage
+		o.Error = r.err.Error()
+	case r.typeMismatches > 0:
+		o.Status = OutcomeTypeMismatch
+	case r.unmatchedStepArrays > 0:
+		o.Status = OutcomeExcessSteps
+	}
+	return o
+}
+
 // dispatchSingleGroup inserts all rows for one grouped PCBA. Returns the
 // outcome via groupDispatchResult. Errors from DB operations are returned;
 // structural "more step arrays than station records" is logged as WARN and
@@ -196,7 +257,7 @@ type groupDispatchResult struct {
 func (s *dispatcherService) dispatchSingleGroup(ctx context.Context, group dto.GroupedDataDTO) groupDispatchResult {
 	key := groupKey(group)
 
-	logger.Debug("Dispatching group",
+	logger.DebugContext(ctx, "Dispatching group",
 		logger.WithFields(map[string]interface{}{
 			"pcba":                 key,
 			"has_download":         group.DownloadInfo != (dto.DownloadInfoDTO{}),
@@ -207,7 +268,7 @@ func (s *dispatcherService) dispatchSingleGroup(ctx context.Context, group dto.G
 
 	if (group.DownloadInfo != dto.DownloadInfoDTO{}) {
 		if err := s.downloadInfoService.InsertDownloadInfo(ctx, group.DownloadInfo); err != nil {
-			logger.Error("Failed to insert DownloadInfo",
+			logger.ErrorContext(ctx, "Failed to insert DownloadInfo",
 				err,
 				logger.WithFields(map[string]interface{}{
 					"pcba":     key,
@@ -219,12 +280,13 @@ func (s *dispatcherService) dispatchSingleGroup(ctx context.Context, group dto.G
 				failedStage: "download",
 			}
 		}
+		metrics.RowsInserted.WithLabelValues("download_info").Inc()
 	}
 
 	testStationIDs := make([]int, 0, len(group.TestStationRecords))
 	for _, tsr := range group.TestStationRecords {
 		if (tsr.LogisticData == dto.LogisticDataDTO{}) {
-			logger.Error("Station record has no LogisticData",
+			logger.ErrorContext(ctx, "Station record has no LogisticData",
 				logger.WithFields(map[string]interface{}{
 					"station_type": strings.TrimSpace(tsr.TestStation),
 					"group_key":    key,
@@ -236,9 +298,9 @@ func (s *dispatcherService) dispatchSingleGroup(ctx context.Context, group dto.G
 			}
 		}
 
-		logisticDataID, err := s.logisticDataService.GetOrInsertLogisticData(ctx, tsr.LogisticData)
+		logisticDataID, inserted, err := s.logisticDataService.GetOrInsertLogisticData(ctx, tsr.LogisticData)
 		if err != nil {
-			logger.Error("Failed to insert LogisticData",
+			logger.ErrorContext(ctx, "Failed to insert LogisticData",
 				err,
 				logger.WithFields(map[string]interface{}{
 					"pcba":             strings.TrimSpace(tsr.LogisticData.PCBANumber),
@@ -254,7 +316,7 @@ func (s *dispatcherService) dispatchSingleGroup(ctx context.Context, group dto.G
 			}
 		}
 		if logisticDataID == 0 {
-			logger.Error("Resolved LogisticDataID is 0",
+			logger.ErrorContext(ctx, "Resolved LogisticDataID is 0",
 				logger.WithFields(map[string]interface{}{
 					"pcba":         strings.TrimSpace(tsr.LogisticData.PCBANumber),
 					"product_sn":   strings.TrimSpace(tsr.LogisticData.ProductSN),
@@ -268,10 +330,13 @@ func (s *dispatcherService) dispatchSingleGroup(ctx context.Context, group dto.G
 				failedStage: "logistic",
 			}
 		}
+		if inserted {
+			metrics.RowsInserted.WithLabelValues("logistic_data").Inc()
+		}
 
 		testStationID, err := s.testStationService.InsertTestStationRecord(ctx, tsr, logisticDataID)
 		if err != nil {
-			logger.Error("Failed to insert TestStationRecord",
+			logger.ErrorContext(ctx, "Failed to insert TestStationRecord",
 				err,
 				logger.WithFields(map[string]interface{}{
 					"pcba":             strings.TrimSpace(tsr.LogisticData.PCBANumber),
@@ -285,6 +350,7 @@ func (s *dispatcherService) dispatchSingleGroup(ctx context.Context, group dto.G
 				failedStage: "station",
 			}
 		}
+		metrics.RowsInserted.WithLabelValues("test_station_record").Inc()
 		testStationIDs = append(testStationIDs, testStationID)
 	}
 
@@ -297,7 +363,7 @@ func (s *dispatcherService) dispatchSingleGroup(ctx context.Context, group dto.G
 			// properly by (PCBA, StationType).
 			if result.unmatchedStepArrays == 0 {
 				inferredType, stepPCBA := parser.InferStationTypeFromSteps(stepsSlice)
-				logger.Warn("Skipping unmatched step array(s) — more step arrays than station records",
+				logger.WarnContext(ctx, "Skipping unmatched step array(s) — more step arrays than station records",
 					logger.WithFields(map[string]interface{}{
 						"pcba":                      key,
 						"station_record_count":      len(testStationIDs),
@@ -319,7 +385,7 @@ func (s *dispatcherService) dispatchSingleGroup(ctx context.Context, group dto.G
 		pairedType := strings.TrimSpace(group.TestStationRecords[i].TestStation)
 		if inferredType != "" && pairedType != "" && inferredType != pairedType {
 			result.typeMismatches++
-			logger.Warn("Dispatcher paired step array to station of different type",
+			logger.WarnContext(ctx, "Dispatcher paired step array to station of different type",
 				logger.WithFields(map[string]interface{}{
 					"pcba":          key,
 					"array_index":   i,
@@ -331,7 +397,7 @@ func (s *dispatcherService) dispatchSingleGroup(ctx context.Context, group dto.G
 			)
 		}
 		if err := s.testStepService.InsertTestSteps(ctx, stepsSlice, testStationIDs[i]); err != nil {
-			logger.Error("Failed to insert TestSteps",
+			logger.ErrorContext(ctx, "Failed to insert TestSteps",
 				err,
 				logger.WithFields(map[string]interface{}{
 					"pcba":                   key,
@@ -343,6 +409,7 @@ func (s *dispatcherService) dispatchSingleGroup(ctx context.Context, group dto.G
 			result.failedStage = "steps"
 			return result
 		}
+		metrics.RowsInserted.WithLabelValues("test_step").Add(float64(len(stepsSlice)))
 	}
 
 	return result
@@ -4,7 +4,7 @@ which contain information related to firmware or software downloads tied to PCBA
 
 The DownloadInfoService interface offers methods to:
 - Insert new DownloadInfo records after sanitizing input,
-- Retrieve DownloadInfo records by PCBA number,
+- Retrieve DownloadInfo records by PCBA number, either one or the full flash history,
 with error handling that distinguishes between missing records and operational failures.
 
 Conversion between DTO and DB models is handled by the dedicated converter package.
@@ -17,6 +17,7 @@ import (
 	"fmt"
 	"github.com/NoroSaroyan/log-parser/internal/domain/models/dto"
 	"github.com/NoroSaroyan/log-parser/internal/domain/repositories"
+	"github.com/NoroSaroyan/log-parser/internal/infrastructure/tracing"
 	"github.com/NoroSaroyan/log-parser/internal/services/converter/download"
 	"log"
 	"strings"
@@ -44,6 +45,14 @@ type DownloadInfoService interface {
 	// - DownloadInfoDTO if found; zero-value DTO otherwise.
 	// - error if the query fails for reasons other than no rows found.
 	GetByPCBANumber(ctx context.Context, pcbaNumber string) (dto.DownloadInfoDTO, error)
+
+	// GetAllByPCBANumber retrieves every DownloadInfo record of a PCBA number
+	// in insertion order, i.e. its flash history.
+	//
+	// Returns:
+	// - the records, empty if none exist.
+	// - error if the query fails.
+	GetAllByPCBANumber(ctx context.Context, pcbaNumber string) ([]dto.DownloadInfoDTO, error)
 }
 
 type downloadInfoService struct {
@@ -61,6 +70,9 @@ func NewDownloadInfoService(repo repositories.DownloadInfoRepository) DownloadIn
 
 // InsertDownloadInfo trims whitespace from input string fields and inserts the record.
 func (s *downloadInfoService) InsertDownloadInfo(ctx context.Context, data dto.DownloadInfoDTO) error {
+	ctx, span := tracing.Start(ctx, "downloadinfo.InsertDownloadInfo")
+	defer span.End()
+
 	data.TestStation = strings.TrimSpace(data.TestStation)
 	data.FlashEntityType = strings.TrimSpace(data.FlashEntityType)
 	data.TcuPCBANumber = strings.TrimSpace(data.TcuPCBANumber)
@@ -81,6 +93,9 @@ func (s *downloadInfoService) InsertDownloadInfo(ctx context.Context, data dto.D
 // GetByPCBANumber retrieves a DownloadInfo record by PCBA number.
 // Returns zero-value DTO if not found, error on operational failure.
 func (s *downloadInfoService) GetByPCBANumber(ctx context.Context, pcbaNumber string) (dto.DownloadInfoDTO, error) {
+	ctx, span := tracing.Start(ctx, "downloadinfo.GetByPCBANumber")
+	defer span.End()
+
 	pcbaNumber = strings.TrimSpace(pcbaNumber)
 
 	if s == nil || s.repo == nil {
@@ -104,3 +119,20 @@ func (s *downloadInfoService) GetByPCBANumber(ctx context.Context, pcbaNumber st
 	dtoModel := download.ConvertToDTO(*dbModel)
 	return dtoModel, nil
 }
+
+// GetAllByPCBANumber retrieves the flash history of a PCBA number.
+func (s *downloadInfoService) GetAllByPCBANumber(ctx context.Context, pcbaNumber string) ([]dto.DownloadInfoDTO, error) {
+	ctx, span := tracing.Start(ctx, "downloadinfo.GetAllByPCBANumber")
+	defer span.End()
+
+	dbModels, err := s.repo.GetAllByPCBANumber(ctx, strings.TrimSpace(pcbaNumber))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get DownloadInfo history by PCBA number: %w", err)
+	}
+
+	dtos := make([]dto.DownloadInfoDTO, 0, len(dbModels))
+	for _, m := range dbModels {
+		dtos = append(dtos, download.ConvertToDTO(*m))
+	}
+	return dtos, nil
+}
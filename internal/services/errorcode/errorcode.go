@@ -0,0 +1,42 @@
+/*
+Package errorcode normalizes the error code strings of station records
+(ErrorCodes) and test steps (TestStepErrorCode).
+
+The testers log error codes as free text, sometimes several per field. Split
+turns such a field into the normalized codes that are stored in the
+error_code table and linked to the record or step:
+
+  - codes are separated by commas, semicolons, pipes or whitespace;
+  - codes are upper-cased, so "e101" and "E101" are the same code;
+  - placeholders for "no error" ("0", "N/A", "NA", "NONE", "NULL", "-") are dropped;
+  - every code appears once, in order of first occurrence.
+
+Migration 010 applies the same rules in SQL to backfill existing rows.
+*/
+package errorcode
+
+import (
+	"regexp"
+	"strings"
+)
+
+// separators matches the characters between two codes of one field.
+var separators = regexp.MustCompile(`[\s,;|]+`)
+
+// placeholders are logged in place of an error code when there is none.
+var placeholders = map[string]bool{"0": true, "N/A": true, "NA": true, "NONE": true, "NULL": true, "-": true}
+
+// Split returns the normalized error codes of raw, or nil if it has none.
+func Split(raw string) []string {
+	var codes []string
+	seen := make(map[string]bool)
+	for _, c := range separators.Split(raw, -1) {
+		c = strings.ToUpper(c)
+		if c == "" || placeholders[c] || seen[c] {
+			continue
+		}
+		seen[c] = true
+		codes = append(codes, c)
+	}
+	return codes
+}
@@ -0,0 +1,310 @@
+package export
+
+import (
+	"context"
+	"database/sql"
+	"encoding/csv"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/NoroSaroyan/log-parser/internal/domain/models/db"
+	"github.com/NoroSaroyan/log-parser/internal/domain/repositories"
+	"github.com/NoroSaroyan/log-parser/internal/infrastructure/redaction"
+	"github.com/NoroSaroyan/log-parser/internal/services/dispatcher"
+	"github.com/NoroSaroyan/log-parser/internal/services/downloadinfo"
+	"github.com/NoroSaroyan/log-parser/internal/services/logistic"
+	"github.com/NoroSaroyan/log-parser/internal/services/pipeline"
+	"github.com/NoroSaroyan/log-parser/internal/services/teststation"
+	"github.com/NoroSaroyan/log-parser/internal/services/teststep"
+)
+
+// CSV file names and headers. Headers match the column names of the tables
+// created by migration 001.
+var (
+	downloadInfoColumns = []string{
+		"id", "test_station", "flash_entity_type", "tcu_pcba_number", "flash_elapsed_time",
+		"tcu_entity_flash_state", "part_number", "product_line", "download_tool_version",
+		"download_finished_time",
+	}
+	logisticDataColumns = []string{
+		"id", "pcba_number", "product_sn", "part_number", "vp_app_version", "vp_boot_loader_version",
+		"vp_core_version", "supplier_hardware_version", "manufacturer_hardware_version",
+		"manufacturer_software_version", "ble_mac", "ble_sn", "ble_version", "ble_passwork_key",
+		"ap_app_version", "ap_kernel_version", "tcu_iccid", "phone_number", "imei", "imsi",
+		"production_date",
+	}
+	testStationRecordColumns = []string{
+		"id", "part_number", "test_station", "entity_type", "product_line", "test_tool_version",
+		"test_finished_time", "is_all_passed", "error_codes", "logistic_data_id",
+	}
+	testStepColumns = []string{
+		"id", "test_step_name", "test_threshold_value", "test_measured_value",
+		"test_step_elapsed_time", "test_step_result", "test_step_error_code",
+		"test_station_record_id", "recomputed_result", "verdict_mismatch", "measured_numeric",
+	}
+)
+
+// csvTable is one output file with SERIAL-like ID assignment.
+type csvTable struct {
+	file   *os.File
+	w      *csv.Writer
+	nextID int
+}
+
+func newCSVTable(dir, name string, header []string) (*csvTable, error) {
+	f, err := os.Create(filepath.Join(dir, name))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create %s: %w", name, err)
+	}
+	w := csv.NewWriter(f)
+	if err := w.Write(header); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("failed to write %s header: %w", name, err)
+	}
+	return &csvTable{file: f, w: w, nextID: 1}, nil
+}
+
+// write appends a row prefixed with a newly assigned ID and returns the ID.
+func (t *csvTable) write(fields ...string) (int, error) {
+	id := t.nextID
+	if err := t.w.Write(append([]string{strconv.Itoa(id)}, fields...)); err != nil {
+		return 0, err
+	}
+	t.nextID++
+	return id, nil
+}
+
+func (t *csvTable) flush() error {
+	t.w.Flush()
+	return t.w.Error()
+}
+
+func (t *csvTable) close() error {
+	if err := t.flush(); err != nil {
+		t.file.Close()
+		return err
+	}
+	return t.file.Close()
+}
+
+type csvExporter struct {
+	tables     []*csvTable
+	dispatcher dispatcher.DispatcherService
+}
+
+func newCSVExporter(dir string, reveal func(field string) bool) (*csvExporter, error) {
+	e := &csvExporter{}
+	var tables [4]*csvTable
+	for i, spec := range []struct {
+		name   string
+		header []string
+	}{
+		{"download_info.csv", downloadInfoColumns},
+		{"logistic_data.csv", logisticDataColumns},
+		{"test_station_record.csv", testStationRecordColumns},
+		{"test_step.csv", testStepColumns},
+	} {
+		t, err := newCSVTable(dir, spec.name, spec.header)
+		if err != nil {
+			e.Close()
+			return nil, err
+		}
+		tables[i] = t
+		e.tables = append(e.tables, t)
+	}
+	download, logisticData, stations, steps := tables[0], tables[1], tables[2], tables[3]
+
+	e.dispatcher = dispatcher.NewDispatcherService(
+		downloadinfo.NewDownloadInfoService(&csvDownloadInfoRepository{table: download}),
+		logistic.NewLogisticDataService(&csvLogisticDataRepository{table: logisticData, reveal: reveal}),
+		teststation.NewTestStationService(&csvTestStationRecordRepository{table: stations}),
+		teststep.NewTestStepService(&csvTestStepRepository{table: steps}),
+	)
+	return e, nil
+}
+
+func (e *csvExporter) WriteResult(ctx context.Context, result *pipeline.Result) error {
+	if _, err := e.dispatcher.DispatchGroups(ctx, result.Groups); err != nil {
+		return fmt.Errorf("failed to export %s: %w", result.File, err)
+	}
+	for _, t := range e.tables {
+		if err := t.flush(); err != nil {
+			return fmt.Errorf("failed to flush CSV output: %w", err)
+		}
+	}
+	return nil
+}
+
+func (e *csvExporter) Close() error {
+	var firstErr error
+	for _, t := range e.tables {
+		if err := t.close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// The repositories below are write-only: they turn every insert into a CSV
+// row. Reads behave like an empty database, which is all the dispatcher
+// needs since it only inserts.
+
+type csvDownloadInfoRepository struct {
+	table *csvTable
+}
+
+func (r *csvDownloadInfoRepository) Insert(ctx context.Context, info *db.DownloadInfoDB) error {
+	_, err := r.table.write(
+		info.TestStation, info.FlashEntityType, info.TcuPCBANumber, strconv.Itoa(info.FlashElapsedTime),
+		info.TcuEntityFlashState, info.PartNumber, info.ProductLine, info.DownloadToolVersion,
+		info.DownloadFinishedTime,
+	)
+	return err
+}
+
+func (r *csvDownloadInfoRepository) GetByPCBANumber(ctx context.Context, pcba string) (*db.DownloadInfoDB, error) {
+	return nil, nil
+}
+
+func (r *csvDownloadInfoRepository) GetAllByPCBANumber(ctx context.Context, pcba string) ([]*db.DownloadInfoDB, error) {
+	return nil, nil
+}
+
+func (r *csvDownloadInfoRepository) GetByPartNumber(ctx context.Context, partNumber string) (*db.DownloadInfoDB, error) {
+	return nil, nil
+}
+
+// csvLogisticDataRepository writes LogisticData redacted by the current
+// policy, except for the fields reveal allows.
+type csvLogisticDataRepository struct {
+	table  *csvTable
+	reveal func(field string) bool
+}
+
+func (r *csvLogisticDataRepository) Insert(ctx context.Context, data *db.LogisticDataDB) error {
+	row := redaction.Current().Redact(*data, r.reveal).(db.LogisticDataDB)
+	id, err := r.table.write(
+		row.PCBANumber, row.ProductSN, row.PartNumber, row.VPAppVersion, row.VPBootLoaderVersion,
+		row.VPCoreVersion, row.SupplierHardwareVersion, row.ManufacturerHardwareVersion,
+		row.ManufacturerSoftwareVersion, row.BleMac, row.BleSN, row.BleVersion, row.BlePassworkKey,
+		row.APAppVersion, row.APKernelVersion, row.TcuICCID, row.PhoneNumber, row.IMEI, row.IMSI,
+		row.ProductionDate,
+	)
+	if err != nil {
+		return err
+	}
+	data.ID = id
+	return nil
+}
+
+func (r *csvLogisticDataRepository) GetAllByPCBANumber(ctx context.Context, pcba string) ([]*db.LogisticDataDB, error) {
+	return nil, nil
+}
+
+func (r *csvLogisticDataRepository) GetByPartNumber(ctx context.Context, partNumber string) ([]*db.LogisticDataDB, error) {
+	return nil, nil
+}
+
+func (r *csvLogisticDataRepository) GetIDByPCBANumber(ctx context.Context, pcba string) (int, error) {
+	return 0, sql.ErrNoRows
+}
+
+func (r *csvLogisticDataRepository) GetById(ctx context.Context, id int) (*db.LogisticDataDB, error) {
+	return nil, sql.ErrNoRows
+}
+
+func (r *csvLogisticDataRepository) GetByIdentifier(ctx context.Context, identifier, value string) ([]*db.LogisticDataDB, error) {
+	return nil, nil
+}
+
+func (r *csvLogisticDataRepository) GetByPCBANumber(ctx context.Context, pcba string) (*db.LogisticDataDB, error) {
+	return nil, nil
+}
+
+func (r *csvLogisticDataRepository) ReencryptSecrets(ctx context.Context, batchSize int) (int, error) {
+	return 0, nil
+}
+
+type csvTestStationRecordRepository struct {
+	table *csvTable
+}
+
+func (r *csvTestStationRecordRepository) Insert(ctx context.Context, record *db.TestStationRecordDB) error {
+	id, err := r.table.write(
+		record.PartNumber, record.TestStation, record.EntityType, record.ProductLine,
+		record.TestToolVersion, record.TestFinishedTime, strconv.FormatBool(record.IsAllPassed),
+		record.ErrorCodes, strconv.Itoa(record.LogisticDataID),
+	)
+	if err != nil {
+		return err
+	}
+	record.ID = id
+	return nil
+}
+
+func (r *csvTestStationRecordRepository) GetByPCBANumber(ctx context.Context, pcba string) ([]*db.TestStationRecordDB, error) {
+	return nil, nil
+}
+
+func (r *csvTestStationRecordRepository) GetByPartNumber(ctx context.Context, partNumber string) ([]*db.TestStationRecordDB, error) {
+	return nil, nil
+}
+
+func (r *csvTestStationRecordRepository) GetAllPCBANumbers(ctx context.Context, stationType string) ([]string, error) {
+	return nil, nil
+}
+
+func (r *csvTestStationRecordRepository) ForEachPCBANumber(ctx context.Context, stationType string, fn func(pcba string) error) error {
+	return nil
+}
+
+func (r *csvTestStationRecordRepository) ListDevices(ctx context.Context, filter db.DeviceFilter) ([]*db.DeviceDB, error) {
+	return nil, nil
+}
+
+func (r *csvTestStationRecordRepository) CountDevices(ctx context.Context, filter db.DeviceFilter) (int, error) {
+	return 0, nil
+}
+
+type csvTestStepRepository struct {
+	table *csvTable
+}
+
+func (r *csvTestStepRepository) InsertBatch(ctx context.Context, steps []*db.TestStepDB, testStationRecordID int) error {
+	for _, step := range steps {
+		if _, err := r.table.write(
+			step.TestStepName, step.TestThresholdValue, step.TestMeasuredValue,
+			strconv.Itoa(step.TestStepElapsedTime), step.TestStepResult, step.TestStepErrorCode,
+			strconv.Itoa(testStationRecordID), step.RecomputedResult, strconv.FormatBool(step.VerdictMismatch),
+			formatNullableFloat(step.MeasuredNumeric),
+		); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *csvTestStepRepository) GetByTestStationRecordID(ctx context.Context, recordID int) ([]*db.TestStepDB, error) {
+	return nil, nil
+}
+
+func (r *csvTestStepRepository) GetByPartNumber(ctx context.Context, partNumber string) ([]*db.TestStepDB, error) {
+	return nil, nil
+}
+
+// formatNullableFloat renders v for a nullable numeric column; NULL is empty.
+func formatNullableFloat(v *float64) string {
+	if v == nil {
+		return ""
+	}
+	return strconv.FormatFloat(*v, 'g', -1, 64)
+}
+
+var (
+	_ repositories.DownloadInfoRepository      = (*csvDownloadInfoRepository)(nil)
+	_ repositories.LogisticDataRepository      = (*csvLogisticDataRepository)(nil)
+	_ repositories.TestStationRecordRepository = (*csvTestStationRecordRepository)(nil)
+	_ repositories.TestStepRepository          = (*csvTestStepRepository)(nil)
+)
@@ -0,0 +1,81 @@
+/*
+Package export writes the output of the parsing pipeline to files instead of
+a database.
+
+Two formats are supported:
+
+  - jsonl: one JSON document per device (one line per PCBA group), holding the
+    download info, the latest logistic data and the station sessions with
+    their test steps. Sessions are paired by station type in log order
+    (see processor.PairSessions); step arrays that cannot be paired are kept
+    in UnpairedTestSteps instead of being dropped.
+  - csv: one normalized file per table (download_info.csv, logistic_data.csv,
+    test_station_record.csv, test_step.csv) with the same columns, IDs and
+    foreign keys that the "process" mode would insert into Postgres. The rows
+    are produced by the regular services and dispatcher running on top of
+    write-only CSV repositories, so both paths share the same pairing rules.
+
+Exported files are meant for other teams, so LogisticData is redacted by the
+current redaction policy like an API response: the BLE pairing key is
+dropped and the phone number, IMSI and ICCID are masked by default. The
+reveal function passed to New names the fields written in clear instead.
+
+Exporters are fed one pipeline.Result at a time and flush after every file,
+so output is streamed while a directory is being walked and memory use does
+not grow with the number of files.
+
+Usage:
+
+	exp, err := export.New("jsonl", "out/", nil)
+	if err != nil { ... }
+	defer exp.Close()
+
+	result, err := pipeline.ParseFile("mesrestapi.log")
+	if err != nil { ... }
+	if err := exp.WriteResult(ctx, result); err != nil { ... }
+*/
+package export
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/NoroSaroyan/log-parser/internal/services/pipeline"
+)
+
+// Supported export formats.
+const (
+	FormatJSONL = "jsonl"
+	FormatCSV   = "csv"
+)
+
+// Exporter writes parsed log files to an output directory.
+type Exporter interface {
+	// WriteResult writes all groups of one parsed file and flushes the output.
+	WriteResult(ctx context.Context, result *pipeline.Result) error
+
+	// Close flushes and closes all output files.
+	Close() error
+}
+
+// New creates an Exporter for format that writes into dir. The directory is
+// created if it does not exist; existing output files are truncated. reveal,
+// if non-nil, reports the redacted fields written in clear.
+func New(format, dir string, reveal func(field string) bool) (Exporter, error) {
+	if dir == "" {
+		return nil, fmt.Errorf("output directory is required")
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create output directory: %w", err)
+	}
+
+	switch format {
+	case FormatJSONL:
+		return newJSONLExporter(dir, reveal)
+	case FormatCSV:
+		return newCSVExporter(dir, reveal)
+	default:
+		return nil, fmt.Errorf("unsupported export format: %s (expected %s or %s)", format, FormatJSONL, FormatCSV)
+	}
+}
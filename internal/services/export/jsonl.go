@@ -0,0 +1,136 @@
+package export
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/NoroSaroyan/log-parser/internal/domain/models/dto"
+	"github.com/NoroSaroyan/log-parser/internal/infrastructure/redaction"
+	"github.com/NoroSaroyan/log-parser/internal/services/parser"
+	"github.com/NoroSaroyan/log-parser/internal/services/pipeline"
+	"github.com/NoroSaroyan/log-parser/internal/services/processor"
+	"github.com/NoroSaroyan/log-parser/internal/services/threshold"
+)
+
+// DevicesFileName is the name of the file written by the jsonl exporter.
+const DevicesFileName = "devices.jsonl"
+
+// Device is the JSON document written for every PCBA group.
+type Device struct {
+	PCBANumber string `json:"PCBANumber"`
+	SourceFile string `json:"SourceFile"`
+	// DownloadInfo is omitted when the log has no download record for the device.
+	DownloadInfo *dto.DownloadInfoDTO `json:"DownloadInfo,omitempty"`
+	// LogisticData is taken from the last station record of the device.
+	LogisticData *dto.LogisticDataDTO `json:"LogisticData,omitempty"`
+	// Sessions holds every station record with the step array paired to it.
+	Sessions []dto.TestStationWithSteps `json:"Sessions"`
+	// UnpairedTestSteps holds step arrays without a matching station record.
+	UnpairedTestSteps []UnpairedSteps `json:"UnpairedTestSteps,omitempty"`
+}
+
+// UnpairedSteps is a step array that could not be paired with a station record.
+type UnpairedSteps struct {
+	// InferredStation is the station type inferred from the steps, or "unknown".
+	InferredStation string            `json:"InferredStation"`
+	TestSteps       []dto.TestStepDTO `json:"TestSteps"`
+}
+
+type jsonlExporter struct {
+	file   *os.File
+	w      *bufio.Writer
+	enc    *json.Encoder
+	reveal func(field string) bool
+}
+
+func newJSONLExporter(dir string, reveal func(field string) bool) (*jsonlExporter, error) {
+	f, err := os.Create(filepath.Join(dir, DevicesFileName))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create %s: %w", DevicesFileName, err)
+	}
+	w := bufio.NewWriter(f)
+	return &jsonlExporter{file: f, w: w, enc: json.NewEncoder(w), reveal: reveal}, nil
+}
+
+func (e *jsonlExporter) WriteResult(ctx context.Context, result *pipeline.Result) error {
+	for _, group := range result.Groups {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		device := redaction.Current().Redact(BuildDevice(result.File, group), e.reveal)
+		if err := e.enc.Encode(device); err != nil {
+			return fmt.Errorf("failed to write device: %w", err)
+		}
+	}
+	return e.w.Flush()
+}
+
+func (e *jsonlExporter) Close() error {
+	if err := e.w.Flush(); err != nil {
+		e.file.Close()
+		return err
+	}
+	return e.file.Close()
+}
+
+// BuildDevice converts one PCBA group into its exported document, before
+// redaction.
+func BuildDevice(sourceFile string, group dto.GroupedDataDTO) Device {
+	device := Device{
+		PCBANumber: devicePCBA(group),
+		SourceFile: sourceFile,
+		Sessions:   []dto.TestStationWithSteps{},
+	}
+	if group.DownloadInfo != (dto.DownloadInfoDTO{}) {
+		download := group.DownloadInfo
+		device.DownloadInfo = &download
+	}
+	for i := len(group.TestStationRecords) - 1; i >= 0; i-- {
+		if ld := group.TestStationRecords[i].LogisticData; ld != (dto.LogisticDataDTO{}) {
+			device.LogisticData = &ld
+			break
+		}
+	}
+
+	for _, session := range processor.PairSessions(group) {
+		// Copy the steps so the recomputed verdicts do not alter the group.
+		steps := append([]dto.TestStepDTO{}, session.Steps...)
+		threshold.Annotate(steps)
+		if session.Record == nil {
+			device.UnpairedTestSteps = append(device.UnpairedTestSteps, UnpairedSteps{
+				InferredStation: session.StationType,
+				TestSteps:       steps,
+			})
+			continue
+		}
+		device.Sessions = append(device.Sessions, dto.TestStationWithSteps{
+			TestStationRecordDTO: *session.Record,
+			TestSteps:            steps,
+		})
+	}
+	return device
+}
+
+// devicePCBA returns the PCBA number of a group: from a station record, the
+// download info or, for groups made only of steps, the scanned PCBA.
+func devicePCBA(group dto.GroupedDataDTO) string {
+	for _, rec := range group.TestStationRecords {
+		if p := strings.TrimSpace(rec.LogisticData.PCBANumber); p != "" {
+			return p
+		}
+	}
+	if p := strings.TrimSpace(group.DownloadInfo.TcuPCBANumber); p != "" {
+		return p
+	}
+	for _, steps := range group.TestSteps {
+		if _, p := parser.InferStationTypeFromSteps(steps); p != "" {
+			return p
+		}
+	}
+	return ""
+}
@@ -0,0 +1,134 @@
+/*
+Package health answers the liveness and readiness probes of the REST API.
+
+Implementation notes:
+  - Liveness only tells that the process still serves requests. It checks no
+    dependency, so a database outage does not get the API restarted.
+  - Readiness runs every check concurrently, each bounded by CheckTimeout,
+    and reports the status and latency of each one. The API is ready when
+    every check passes.
+  - The database and migration checks come with the app; the ingestion
+    backlog check is added with With by the server owning the ingestion
+    service.
+*/
+package health
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/NoroSaroyan/log-parser/internal/domain/models/dto"
+	"github.com/NoroSaroyan/log-parser/internal/domain/repositories"
+	"github.com/NoroSaroyan/log-parser/internal/infrastructure/database"
+	"github.com/NoroSaroyan/log-parser/internal/services/ingestion"
+)
+
+// Statuses of a probe and of its checks.
+const (
+	StatusOK   = "ok"
+	StatusFail = "fail"
+)
+
+// CheckTimeout bounds every readiness check.
+const CheckTimeout = 2 * time.Second
+
+// Check is one readiness check; Run returns why the dependency is not ready.
+type Check struct {
+	Name string
+	Run  func(ctx context.Context) error
+}
+
+type HealthService interface {
+	Live(ctx context.Context) dto.HealthDTO
+	Ready(ctx context.Context) dto.HealthDTO
+	With(checks ...Check) HealthService
+}
+
+type healthService struct {
+	checks []Check
+}
+
+// NewHealthService creates a new HealthService checking the database
+// connection and schema through repo.
+func NewHealthService(repo repositories.HealthRepository) HealthService {
+	return &healthService{checks: []Check{
+		{Name: "database", Run: repo.Ping},
+		{Name: "migrations", Run: func(ctx context.Context) error {
+			version, err := repo.SchemaVersion(ctx)
+			if err != nil {
+				return err
+			}
+			if version < database.SchemaVersion {
+				return fmt.Errorf("schema version %d, want %d: apply the pending migrations", version, database.SchemaVersion)
+			}
+			return nil
+		}},
+	}}
+}
+
+// IngestionBacklog returns a check failing when svc would refuse uploads:
+// its queue is full or it is shutting down.
+func IngestionBacklog(svc ingestion.IngestionService) Check {
+	return Check{Name: "ingestion", Run: func(ctx context.Context) error {
+		queued, capacity, err := svc.Backlog()
+		if err != nil {
+			return err
+		}
+		if queued >= capacity {
+			return fmt.Errorf("ingestion queue is full: %d of %d uploads waiting", queued, capacity)
+		}
+		return nil
+	}}
+}
+
+// With returns a HealthService running checks in addition to those of s.
+func (s *healthService) With(checks ...Check) HealthService {
+	return &healthService{checks: append(append([]Check{}, s.checks...), checks...)}
+}
+
+// Live reports that the process is serving.
+func (s *healthService) Live(ctx context.Context) dto.HealthDTO {
+	return dto.HealthDTO{Status: StatusOK}
+}
+
+// Ready runs every check and fails if any of them does.
+func (s *healthService) Ready(ctx context.Context) dto.HealthDTO {
+	results := make([]dto.HealthCheckDTO, len(s.checks))
+	var wg sync.WaitGroup
+	for i, c := range s.checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i] = run(ctx, c)
+		}()
+	}
+	wg.Wait()
+
+	out := dto.HealthDTO{Status: StatusOK, Checks: results}
+	for _, r := range results {
+		if r.Status != StatusOK {
+			out.Status = StatusFail
+		}
+	}
+	return out
+}
+
+func run(ctx context.Context, c Check) dto.HealthCheckDTO {
+	ctx, cancel := context.WithTimeout(ctx, CheckTimeout)
+	defer cancel()
+
+	start := time.Now()
+	err := c.Run(ctx)
+	r := dto.HealthCheckDTO{
+		Name:      c.Name,
+		Status:    StatusOK,
+		LatencyMs: float64(time.Since(start).Microseconds()) / 1000,
+	}
+	if err != nil {
+		r.Status = StatusFail
+		r.Error = err.Error()
+	}
+	return r
+}
@@ -0,0 +1,144 @@
+/*
+Package ingestion stores parsed log files and runs uploaded files as
+background jobs.
+
+Store is the storage half of the CLI "process" mode: it saves the validation
+findings, dispatches the accepted groups and checks LogisticData consistency.
+The CLI and the upload jobs both go through it, so a file ingested over HTTP
+ends up exactly like one ingested from the command line.
+
+Jobs:
+
+  - An upload is written to a temporary file and queued. A fixed pool of
+    workers takes jobs from a bounded queue; when the queue is full the
+    upload is refused with ErrQueueFull instead of piling up on disk.
+  - A job reports its stage, the number of groups dispatched so far, the
+    parsing and dispatch statistics and every error it met. Groups are
+    dispatched in batches so progress moves while a large file is stored.
+  - Jobs live in memory only. Finished jobs are kept until Config.RetainJobs
+    newer jobs have finished; jobs still queued when the service is closed
+    are failed and their files removed.
+*/
+package ingestion
+
+import (
+	"context"
+	"fmt"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/NoroSaroyan/log-parser/internal/infrastructure/logger"
+	"github.com/NoroSaroyan/log-parser/internal/infrastructure/tracing"
+	"github.com/NoroSaroyan/log-parser/internal/services/consistency"
+	"github.com/NoroSaroyan/log-parser/internal/services/dispatcher"
+	"github.com/NoroSaroyan/log-parser/internal/services/pipeline"
+	"github.com/NoroSaroyan/log-parser/internal/services/validation"
+)
+
+// Stages of a file, in the order Store goes through them.
+const (
+	StageQueued      = "queued"
+	StageParsing     = "parsing"
+	StageValidating  = "saving_findings"
+	StageDispatching = "dispatching"
+	StageChecking    = "checking_consistency"
+	StageDone        = "done"
+)
+
+// dispatchBatchSize is the number of groups dispatched at a time when Store
+// reports progress.
+const dispatchBatchSize = 50
+
+// ProgressFunc is called by Store when it enters a stage and after every
+// dispatched batch with the number of groups dispatched so far.
+type ProgressFunc func(stage string, dispatched, total int)
+
+// Outcome is what Store did with a parsed file.
+type Outcome struct {
+	Dispatch dispatcher.DispatchReport
+	// Warnings are failures that did not stop the file: storing the
+	// validation findings and checking consistency.
+	Warnings []error
+}
+
+// Store persists result through d, v and c. It only fails when the groups
+// could not be dispatched at all; see dispatcher.DispatchGroups. progress may
+// be nil, in which case all groups are dispatched in a single call.
+func Store(ctx context.Context, result *pipeline.Result, d dispatcher.DispatcherService, v validation.ValidationService, c consistency.ConsistencyService, progress ProgressFunc) (Outcome, error) {
+	var out Outcome
+	total := len(result.Groups)
+	report := func(stage string, dispatched int) {
+		if progress != nil {
+			progress(stage, dispatched, total)
+		}
+	}
+
+	// Store validation findings, including those of rejected groups
+	report(StageValidating, 0)
+	stageCtx, span := startStage(ctx, StageValidating)
+	if err := v.SaveReport(stageCtx, result.Validation); err != nil {
+		tracing.Fail(span, err)
+		logger.ErrorContext(stageCtx, "Failed to save validation findings", err, logger.WithFields(map[string]interface{}{
+			"file":     result.File,
+			"findings": len(result.Validation.Findings),
+		}))
+		out.Warnings = append(out.Warnings, fmt.Errorf("failed to save validation findings: %w", err))
+	}
+	span.End()
+
+	// Dispatch to database
+	report(StageDispatching, 0)
+	stageCtx, span = startStage(ctx, StageDispatching, attribute.Int("groups", total))
+	batch := total
+	if progress != nil {
+		batch = dispatchBatchSize
+	}
+	out.Dispatch.Outcomes = make([]dispatcher.GroupOutcome, 0, total)
+	var lastErr error
+	for start, end := 0, 0; ; start = end {
+		end = min(start+batch, total)
+		r, err := d.DispatchGroups(stageCtx, result.Groups[start:end])
+		if err != nil {
+			lastErr = err
+		}
+		out.Dispatch.GroupsOK += r.GroupsOK
+		out.Dispatch.GroupsFailed += r.GroupsFailed
+		out.Dispatch.GroupsWithExcess += r.GroupsWithExcess
+		out.Dispatch.GroupsMismatchType += r.GroupsMismatchType
+		out.Dispatch.Outcomes = append(out.Dispatch.Outcomes, r.Outcomes...)
+		report(StageDispatching, end)
+		if end == total {
+			break
+		}
+	}
+	if lastErr != nil && out.Dispatch.GroupsOK == 0 {
+		logger.ErrorContext(stageCtx, "Failed to dispatch groups to database", logger.WithFields(map[string]interface{}{
+			"file":  result.File,
+			"error": lastErr,
+		}))
+		err := fmt.Errorf("failed to dispatch groups: %w", lastErr)
+		tracing.Fail(span, err)
+		span.End()
+		return out, err
+	}
+	span.End()
+
+	// Compare the PCBA and Final LogisticData of every dispatched device
+	report(StageChecking, total)
+	stageCtx, span = startStage(ctx, StageChecking)
+	if err := c.CheckGroups(stageCtx, result.Groups); err != nil {
+		tracing.Fail(span, err)
+		logger.ErrorContext(stageCtx, "Failed to check logistic data consistency", err, logger.WithField("file", result.File))
+		out.Warnings = append(out.Warnings, fmt.Errorf("failed to check logistic data consistency: %w", err))
+	}
+	span.End()
+
+	return out, nil
+}
+
+// startStage starts the span of a stage of Store, named after the stage,
+// e.g. "ingestion.dispatching".
+func startStage(ctx context.Context, stage string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return tracing.Start(ctx, "ingestion."+stage, attrs...)
+}
@@ -0,0 +1,462 @@
+package ingestion
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/NoroSaroyan/log-parser/internal/domain/models/dto"
+	"github.com/NoroSaroyan/log-parser/internal/infrastructure/logger"
+	"github.com/NoroSaroyan/log-parser/internal/infrastructure/tracing"
+	"github.com/NoroSaroyan/log-parser/internal/services/consistency"
+	"github.com/NoroSaroyan/log-parser/internal/services/dispatcher"
+	"github.com/NoroSaroyan/log-parser/internal/services/pipeline"
+	"github.com/NoroSaroyan/log-parser/internal/services/validation"
+)
+
+// Job statuses reported in IngestionJobDTO.Status.
+const (
+	StatusQueued    = "queued"
+	StatusRunning   = "running"
+	StatusSucceeded = "succeeded"
+	StatusFailed    = "failed"
+)
+
+// Default configuration values.
+const (
+	DefaultWorkers        = 2
+	DefaultQueueSize      = 16
+	DefaultRetainJobs     = 1000
+	DefaultMaxUploadBytes = 512 << 20
+)
+
+// maxJobErrors caps the errors kept per job; a file where every group fails
+// would otherwise hold one error per device.
+const maxJobErrors = 100
+
+var (
+	// ErrUnsupportedFile is returned for uploads that are not .log or .gz files.
+	ErrUnsupportedFile = errors.New("unsupported file type")
+	// ErrQueueFull is returned when every queue slot is taken.
+	ErrQueueFull = errors.New("ingestion queue is full")
+	// ErrClosed is returned for uploads after Close.
+	ErrClosed = errors.New("ingestion service is closed")
+	// ErrJobNotFound is returned by Get for unknown or expired job IDs.
+	ErrJobNotFound = errors.New("ingestion job not found")
+)
+
+// Config configures the ingestion service.
+type Config struct {
+	// Workers is the number of files ingested concurrently.
+	Workers int
+	// QueueSize is the number of uploads that may wait for a worker.
+	QueueSize int
+	// RetainJobs is the number of finished jobs kept for status queries.
+	RetainJobs int
+	// Dir is where uploads are stored until their job ran (default: the
+	// system temporary directory).
+	Dir string
+	// MaxUploadBytes is the largest accepted upload.
+	MaxUploadBytes int64
+}
+
+type IngestionService interface {
+	Submit(ctx context.Context, fileName string, r io.Reader) (dto.IngestionJobDTO, error)
+	Get(ctx context.Context, id string) (dto.IngestionJobDTO, error)
+	MaxUploadBytes() int64
+	Backlog() (queued, capacity int, err error)
+	Close()
+}
+
+// job is a queued or processed upload. Its state is guarded by the mutex of
+// the service.
+type job struct {
+	state dto.IngestionJobDTO
+	path  string
+	link  trace.Link // to the span of the upload request
+}
+
+type ingestionService struct {
+	cfg         Config
+	dispatcher  dispatcher.DispatcherService
+	validation  validation.ValidationService
+	consistency consistency.ConsistencyService
+
+	queue chan *job
+	wg    sync.WaitGroup
+
+	mu        sync.Mutex
+	closed    bool
+	receiving int // queue slots reserved by uploads still being stored
+	jobs      map[string]*job
+	finished  []string // IDs of finished jobs, oldest first
+}
+
+// NewIngestionService starts cfg.Workers workers that ingest uploads through
+// d, v and c, the same services the CLI process mode uses.
+func NewIngestionService(cfg Config, d dispatcher.DispatcherService, v validation.ValidationService, c consistency.ConsistencyService) IngestionService {
+	if cfg.Workers <= 0 {
+		cfg.Workers = DefaultWorkers
+	}
+	if cfg.QueueSize <= 0 {
+		cfg.QueueSize = DefaultQueueSize
+	}
+	if cfg.RetainJobs <= 0 {
+		cfg.RetainJobs = DefaultRetainJobs
+	}
+	if cfg.Dir == "" {
+		cfg.Dir = os.TempDir()
+	}
+	if cfg.MaxUploadBytes <= 0 {
+		cfg.MaxUploadBytes = DefaultMaxUploadBytes
+	}
+
+	s := &ingestionService{
+		cfg:         cfg,
+		dispatcher:  d,
+		validation:  v,
+		consistency: c,
+		queue:       make(chan *job, cfg.QueueSize),
+		jobs:        make(map[string]*job),
+	}
+	for i := 0; i < cfg.Workers; i++ {
+		s.wg.Add(1)
+		go s.worker()
+	}
+	return s
+}
+
+// Submit stores the content of r as fileName and queues it. It returns the
+// queued job, or ErrUnsupportedFile, ErrQueueFull or ErrClosed.
+func (s *ingestionService) Submit(ctx context.Context, fileName string, r io.Reader) (dto.IngestionJobDTO, error) {
+	ctx, span := tracing.Start(ctx, "ingestion.Submit")
+	defer span.End()
+
+	name := filepath.Base(fileName)
+	ext := strings.ToLower(filepath.Ext(name))
+	if ext != ".log" && ext != ".gz" {
+		return dto.IngestionJobDTO{}, fmt.Errorf("%w: %q, expected .log or .gz", ErrUnsupportedFile, name)
+	}
+	// Reserve a queue slot before reading the upload, so a full queue is
+	// refused without storing a file that would be deleted right away.
+	if err := s.reserve(); err != nil {
+		return dto.IngestionJobDTO{}, err
+	}
+	queued := false
+	defer func() {
+		if !queued {
+			s.release()
+		}
+	}()
+
+	id, err := newJobID()
+	if err != nil {
+		return dto.IngestionJobDTO{}, err
+	}
+	// Keep the extension: pipeline.ReadFile decompresses by suffix.
+	f, err := os.CreateTemp(s.cfg.Dir, "ingestion-"+id+"-*"+ext)
+	if err != nil {
+		return dto.IngestionJobDTO{}, fmt.Errorf("failed to create upload file: %w", err)
+	}
+	size, err := io.Copy(f, r)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(f.Name())
+		return dto.IngestionJobDTO{}, fmt.Errorf("failed to store upload: %w", err)
+	}
+
+	span.SetAttributes(attribute.String("job_id", id))
+	j := &job{
+		path: f.Name(),
+		link: trace.LinkFromContext(ctx),
+		state: dto.IngestionJobDTO{
+			ID:          id,
+			FileName:    name,
+			SizeBytes:   size,
+			Status:      StatusQueued,
+			Stage:       StageQueued,
+			SubmittedAt: formatTime(time.Now()),
+		},
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		os.Remove(j.path)
+		return dto.IngestionJobDTO{}, ErrClosed
+	}
+	// The reserved slot guarantees room in the queue.
+	s.queue <- j
+	s.receiving--
+	queued = true
+	s.jobs[id] = j
+
+	logger.Info("Ingestion job queued", logger.WithFields(map[string]interface{}{
+		"job_id":     id,
+		"file":       name,
+		"size_bytes": size,
+	}))
+	return snapshot(j), nil
+}
+
+// Get returns the current state of the job with the given ID.
+func (s *ingestionService) Get(ctx context.Context, id string) (dto.IngestionJobDTO, error) {
+	if err := ctx.Err(); err != nil {
+		return dto.IngestionJobDTO{}, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	j, ok := s.jobs[id]
+	if !ok {
+		return dto.IngestionJobDTO{}, ErrJobNotFound
+	}
+	return snapshot(j), nil
+}
+
+// MaxUploadBytes returns the largest upload Submit should be given.
+func (s *ingestionService) MaxUploadBytes() int64 {
+	return s.cfg.MaxUploadBytes
+}
+
+// Backlog returns the number of uploads waiting for a worker, counting those
+// still being received, and how many may wait, or ErrClosed once the service
+// is closed.
+func (s *ingestionService) Backlog() (queued, capacity int, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return 0, 0, ErrClosed
+	}
+	return len(s.queue) + s.receiving, cap(s.queue), nil
+}
+
+// Close stops accepting uploads, lets running jobs finish and fails the jobs
+// that are still queued.
+func (s *ingestionService) Close() {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return
+	}
+	s.closed = true
+	close(s.queue)
+	s.mu.Unlock()
+
+	s.wg.Wait()
+}
+
+func (s *ingestionService) isClosed() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.closed
+}
+
+// reserve takes a queue slot for an upload being received, or returns
+// ErrQueueFull when every slot is taken by queued or received uploads.
+func (s *ingestionService) reserve() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return ErrClosed
+	}
+	if len(s.queue)+s.receiving >= cap(s.queue) {
+		return ErrQueueFull
+	}
+	s.receiving++
+	return nil
+}
+
+// release gives back the slot of an upload that was not queued.
+func (s *ingestionService) release() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.receiving--
+}
+
+func (s *ingestionService) worker() {
+	defer s.wg.Done()
+	for j := range s.queue {
+		if s.isClosed() {
+			s.fail(j, ErrClosed)
+			os.Remove(j.path)
+			continue
+		}
+		s.run(j)
+	}
+}
+
+// run ingests the file of j like the CLI process mode and records progress,
+// statistics and errors on j as it goes.
+func (s *ingestionService) run(j *job) {
+	defer os.Remove(j.path)
+	startTime := time.Now()
+
+	// The job is not tied to the upload request, and a running job is
+	// completed on shutdown, so it runs without cancellation, in a trace of
+	// its own linked to the upload. The span ends before the job is finished,
+	// so the trace is complete once the job is.
+	ctx, span := tracing.StartLinked(j.link, "ingestion.job",
+		attribute.String("job_id", j.state.ID), attribute.String("file", j.state.FileName))
+	endSpan := func(err error) {
+		tracing.Fail(span, err)
+		span.End()
+	}
+
+	s.update(j, func(st *dto.IngestionJobDTO) {
+		st.Status = StatusRunning
+		st.Stage = StageParsing
+		st.StartedAt = formatTime(startTime)
+	})
+
+	_, parseSpan := tracing.Start(ctx, "ingestion."+StageParsing)
+	result, err := pipeline.ParseFile(j.path)
+	tracing.Fail(parseSpan, err)
+	parseSpan.End()
+	if err != nil {
+		endSpan(err)
+		s.fail(j, err)
+		return
+	}
+	result.File = j.state.FileName
+
+	s.update(j, func(st *dto.IngestionJobDTO) {
+		st.Stats = &dto.IngestionStatsDTO{
+			TotalBlocks:    result.TotalBlocks,
+			FilteredBlocks: result.FilteredBlocks,
+			PCBAStations:   result.Stats.PCBAStations,
+			FinalStations:  result.Stats.FinalStations,
+			DownloadInfo:   result.Stats.DownloadInfo,
+			TestStepArrays: result.Stats.TestStepArrays,
+			TotalTestSteps: result.Stats.TotalTestSteps,
+			GroupsTotal:    result.Validation.Summary.GroupsTotal,
+			GroupsRejected: result.Validation.Summary.GroupsRejected,
+			Findings:       len(result.Validation.Findings),
+		}
+		st.Progress.GroupsTotal = len(result.Groups)
+	})
+
+	out, err := Store(ctx, result, s.dispatcher, s.validation, s.consistency, func(stage string, dispatched, total int) {
+		s.update(j, func(st *dto.IngestionJobDTO) {
+			st.Stage = stage
+			st.Progress = progress(dispatched, total)
+		})
+	})
+
+	s.update(j, func(st *dto.IngestionJobDTO) {
+		st.Stats.GroupsOK = out.Dispatch.GroupsOK
+		st.Stats.GroupsFailed = out.Dispatch.GroupsFailed
+		st.Stats.GroupsWithExcess = out.Dispatch.GroupsWithExcess
+		st.Stats.GroupsMismatchType = out.Dispatch.GroupsMismatchType
+		for _, o := range out.Dispatch.Outcomes {
+			if o.Status == dispatcher.OutcomeFailed {
+				addError(st, fmt.Sprintf("group %s failed at %s: %s", o.PCBA, o.FailedStage, o.Error))
+			}
+		}
+		for _, w := range out.Warnings {
+			addError(st, w.Error())
+		}
+	})
+	if err != nil {
+		endSpan(err)
+		s.fail(j, err)
+		return
+	}
+
+	endSpan(nil)
+	s.finish(j, func(st *dto.IngestionJobDTO) {
+		st.Status = StatusSucceeded
+		st.Stage = StageDone
+	})
+
+	logger.InfoContext(ctx, "Ingestion job completed", logger.WithFields(map[string]interface{}{
+		"job_id":          j.state.ID,
+		"file":            result.File,
+		"duration":        time.Since(startTime),
+		"groups_inserted": out.Dispatch.GroupsOK,
+		"groups_rejected": result.Validation.Summary.GroupsRejected,
+	}))
+}
+
+// fail finishes j with err.
+func (s *ingestionService) fail(j *job, err error) {
+	s.finish(j, func(st *dto.IngestionJobDTO) {
+		st.Status = StatusFailed
+		addError(st, err.Error())
+	})
+	logger.Error("Ingestion job failed", err, logger.WithFields(map[string]interface{}{
+		"job_id": j.state.ID,
+		"file":   j.state.FileName,
+	}))
+}
+
+// finish applies fn to j, stamps its end and drops the oldest finished jobs
+// beyond cfg.RetainJobs.
+func (s *ingestionService) finish(j *job, fn func(*dto.IngestionJobDTO)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	fn(&j.state)
+	j.state.FinishedAt = formatTime(time.Now())
+
+	s.finished = append(s.finished, j.state.ID)
+	for len(s.finished) > s.cfg.RetainJobs {
+		delete(s.jobs, s.finished[0])
+		s.finished = s.finished[1:]
+	}
+}
+
+func (s *ingestionService) update(j *job, fn func(*dto.IngestionJobDTO)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	fn(&j.state)
+}
+
+// snapshot copies the state of j; the caller holds the mutex.
+func snapshot(j *job) dto.IngestionJobDTO {
+	st := j.state
+	if st.Stats != nil {
+		stats := *st.Stats
+		st.Stats = &stats
+	}
+	st.Errors = append([]string(nil), st.Errors...)
+	return st
+}
+
+func addError(st *dto.IngestionJobDTO, msg string) {
+	if len(st.Errors) < maxJobErrors {
+		st.Errors = append(st.Errors, msg)
+	}
+}
+
+func progress(dispatched, total int) dto.IngestionProgressDTO {
+	p := dto.IngestionProgressDTO{GroupsTotal: total, GroupsDispatched: dispatched, Percent: 100}
+	if total > 0 {
+		p.Percent = math.Round(float64(dispatched)/float64(total)*1000) / 10
+	}
+	return p
+}
+
+func formatTime(t time.Time) string {
+	return t.UTC().Format(time.RFC3339)
+}
+
+func newJobID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate job ID: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -26,6 +26,7 @@ InsertLogisticData:
 GetOrInsertLogisticData:
 - Attempts to insert a LogisticData record unconditionally.
 - Returns the assigned record ID or 0 if insertion failed or no ID was assigned.
+- Reports whether a new row was inserted rather than an existing one reused.
 
 GetById:
 - Retrieves a LogisticData record by its integer ID.
@@ -37,6 +38,10 @@ GetByPCBANumber:
 - Returns a zero-value DTO if no record is found.
 - Returns errors only for operational failures.
 
+ReencryptSecrets:
+- Re-encrypts BLE pairing keys under the active encryption key.
+- Encrypts the keys stored before encryption was enabled.
+
 This package cleanly separates business logic from persistence,
 enabling robust and maintainable handling of logistic metadata.
 */
@@ -48,6 +53,7 @@ import (
 	"fmt"
 	"github.com/NoroSaroyan/log-parser/internal/domain/models/dto"
 	"github.com/NoroSaroyan/log-parser/internal/domain/repositories"
+	"github.com/NoroSaroyan/log-parser/internal/infrastructure/tracing"
 	"github.com/NoroSaroyan/log-parser/internal/services/converter/logistic"
 	"strings"
 )
@@ -59,9 +65,10 @@ type LogisticDataService interface {
 	// Trims input string fields for consistency.
 	InsertLogisticData(ctx context.Context, data dto.LogisticDataDTO) (int, error)
 
-	// GetOrInsertLogisticData attempts to insert LogisticData and returns its ID.
+	// GetOrInsertLogisticData attempts to insert LogisticData and returns its ID
+	// and whether a new row was inserted.
 	// Returns 0 if insertion failed or no ID was assigned.
-	GetOrInsertLogisticData(ctx context.Context, data dto.LogisticDataDTO) (int, error)
+	GetOrInsertLogisticData(ctx context.Context, data dto.LogisticDataDTO) (int, bool, error)
 
 	// GetById retrieves a LogisticData record by its ID.
 	// Returns a zero-value DTO if no record is found.
@@ -70,6 +77,11 @@ type LogisticDataService interface {
 	// GetByPCBANumber retrieves a LogisticData record by PCBA number.
 	// Returns a zero-value DTO if no record is found.
 	GetByPCBANumber(ctx context.Context, PCBANumber string) (dto.LogisticDataDTO, error)
+
+	// ReencryptSecrets re-encrypts the stored BLE pairing keys that are in
+	// plaintext or under an old encryption key, batchSize rows at a time.
+	// Returns the number of rows updated.
+	ReencryptSecrets(ctx context.Context, batchSize int) (int, error)
 }
 
 type logisticDataService struct {
@@ -85,6 +97,9 @@ func NewLogisticDataService(repo repositories.LogisticDataRepository) LogisticDa
 // inserts the data if new, and returns the record ID or existing record ID.
 // Returns an error on database failure.
 func (s *logisticDataService) InsertLogisticData(ctx context.Context, data dto.LogisticDataDTO) (int, error) {
+	ctx, span := tracing.Start(ctx, "logistic.InsertLogisticData")
+	defer span.End()
+
 	// Trim all relevant string fields for clean input.
 	data.PCBANumber = strings.TrimSpace(data.PCBANumber)
 	data.ProductSN = strings.TrimSpace(data.ProductSN)
@@ -120,23 +135,30 @@ func (s *logisticDataService) InsertLogisticData(ctx context.Context, data dto.L
 	return dbModel.ID, nil
 }
 
-// GetOrInsertLogisticData attempts to insert LogisticData and returns the assigned ID.
+// GetOrInsertLogisticData attempts to insert LogisticData and returns the assigned ID
+// and whether a new row was inserted.
 // Returns 0 if insertion failed or ID is zero.
-func (s *logisticDataService) GetOrInsertLogisticData(ctx context.Context, data dto.LogisticDataDTO) (int, error) {
+func (s *logisticDataService) GetOrInsertLogisticData(ctx context.Context, data dto.LogisticDataDTO) (int, bool, error) {
+	ctx, span := tracing.Start(ctx, "logistic.GetOrInsertLogisticData")
+	defer span.End()
+
 	dbModel := logistic.ConvertToDB(data)
 	err := s.repo.Insert(ctx, &dbModel)
 	if err != nil {
-		return 0, err
+		return 0, false, err
 	}
 	if dbModel.ID == 0 {
-		return 0, nil
+		return 0, false, nil
 	}
-	return dbModel.ID, nil
+	return dbModel.ID, true, nil
 }
 
 // GetById retrieves a LogisticData record by its ID.
 // Returns zero-value DTO if not found, error on failure.
 func (s *logisticDataService) GetById(ctx context.Context, id int) (dto.LogisticDataDTO, error) {
+	ctx, span := tracing.Start(ctx, "logistic.GetById")
+	defer span.End()
+
 	dbModel, err := s.repo.GetById(ctx, id)
 	if err != nil {
 		if err == sql.ErrNoRows {
@@ -152,6 +174,9 @@ func (s *logisticDataService) GetById(ctx context.Context, id int) (dto.Logistic
 // GetByPCBANumber retrieves a LogisticData record by PCBA number (trimmed).
 // Returns zero-value DTO if not found, error on failure.
 func (s *logisticDataService) GetByPCBANumber(ctx context.Context, PCBANumber string) (dto.LogisticDataDTO, error) {
+	ctx, span := tracing.Start(ctx, "logistic.GetByPCBANumber")
+	defer span.End()
+
 	PCBANumber = strings.TrimSpace(PCBANumber)
 
 	dbModel, err := s.repo.GetByPCBANumber(ctx, PCBANumber)
@@ -165,3 +190,16 @@ func (s *logisticDataService) GetByPCBANumber(ctx context.Context, PCBANumber st
 	dtoModel := logistic.ConvertToDTO(*dbModel)
 	return dtoModel, nil
 }
+
+// ReencryptSecrets re-encrypts the BLE pairing keys that need it under the
+// active encryption key and returns the number of rows updated.
+func (s *logisticDataService) ReencryptSecrets(ctx context.Context, batchSize int) (int, error) {
+	ctx, span := tracing.Start(ctx, "logistic.ReencryptSecrets")
+	defer span.End()
+
+	n, err := s.repo.ReencryptSecrets(ctx, batchSize)
+	if err != nil {
+		return n, fmt.Errorf("failed to re-encrypt LogisticData secrets: %w", err)
+	}
+	return n, nil
+}
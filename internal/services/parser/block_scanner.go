@@ -0,0 +1,147 @@
+package parser
+
+import "strings"
+
+// Block is a JSON block found by a BlockScanner, together with its position
+// in the scanned input.
+type Block struct {
+	// Text is the extracted JSON, one input line per text line.
+	Text string
+	// StartLine and EndLine are the 1-based input lines of the first and last
+	// line of the block.
+	StartLine int
+	EndLine   int
+	// StartOffset is the byte offset of the first line of the block and
+	// EndOffset the byte offset just after its last line.
+	StartOffset int64
+	EndOffset   int64
+}
+
+// BlockScanner is the line-fed state machine behind ExtractJson. Feeding it
+// the lines of a log one by one yields the same blocks as ExtractJson over the
+// whole text, which makes it usable on files that are still being written.
+//
+// Besides the blocks, the scanner tracks byte offsets so a caller can stop
+// and later resume at SafeOffset without losing or repeating a block.
+type BlockScanner struct {
+	line   int
+	offset int64
+
+	insideBlock bool
+	braceCount  int
+	current     strings.Builder
+	start       Block // position of the block being built
+	lastLine    int   // last line appended to the current block
+	lastOffset  int64 // offset just after lastLine
+}
+
+// NewBlockScanner creates a scanner for input starting at byte offset 0.
+func NewBlockScanner() *BlockScanner {
+	return &BlockScanner{}
+}
+
+// NewBlockScannerAt creates a scanner for input that starts at the given
+// byte offset of a larger file, e.g. when resuming from a checkpoint. Line
+// numbers are counted from the resume point.
+func NewBlockScannerAt(offset int64) *BlockScanner {
+	return &BlockScanner{offset: offset}
+}
+
+// Offset returns the byte offset just after the last fed line.
+func (s *BlockScanner) Offset() int64 {
+	return s.offset
+}
+
+// SafeOffset returns the offset from which scanning can be restarted with a
+// fresh scanner without losing or repeating any block: the start of the block
+// being built, or Offset when the scanner is between blocks.
+func (s *BlockScanner) SafeOffset() int64 {
+	if s.insideBlock {
+		return s.start.StartOffset
+	}
+	return s.offset
+}
+
+// Feed processes one line (without its trailing newline) and returns a block
+// if the line completed one. The byte offset advances by len(line)+1.
+func (s *BlockScanner) Feed(line string) (Block, bool) {
+	s.line++
+	lineOffset := s.offset
+	s.offset += int64(len(line)) + 1
+
+	isObject := strings.Contains(line, " Data  {")
+	if isObject || strings.Contains(line, " Data  [") {
+		// Start of a new Data block. A previous block that wasn't properly
+		// closed is emitted anyway.
+		var prev Block
+		var hasPrev bool
+		if s.insideBlock {
+			prev, hasPrev = s.finish(), true
+		}
+
+		s.insideBlock = true
+		s.current.Reset()
+		s.braceCount = 0
+		s.start = Block{StartLine: s.line, StartOffset: lineOffset}
+		s.lastLine, s.lastOffset = s.line, s.offset
+
+		// Extract everything after " Data  "
+		dataIdx := strings.Index(line, " Data  ")
+		jsonPart := line[dataIdx+7:] // 7 = len(" Data  ")
+		s.current.WriteString(jsonPart)
+		s.current.WriteByte('\n')
+		if isObject {
+			s.braceCount += strings.Count(jsonPart, "{") - strings.Count(jsonPart, "}")
+		} else {
+			s.braceCount += strings.Count(jsonPart, "[") - strings.Count(jsonPart, "]")
+		}
+		return prev, hasPrev
+	}
+
+	if !s.insideBlock {
+		return Block{}, false
+	}
+
+	// Continue building the current block with the JSON content after the
+	// log prefix.
+	prefixEnd := strings.Index(line, "]:")
+	if prefixEnd != -1 && len(line) > prefixEnd+2 {
+		jsonPart := line[prefixEnd+2:]
+		s.current.WriteString(jsonPart)
+		s.current.WriteByte('\n')
+		s.braceCount += strings.Count(jsonPart, "{") - strings.Count(jsonPart, "}")
+		s.braceCount += strings.Count(jsonPart, "[") - strings.Count(jsonPart, "]")
+		s.lastLine, s.lastOffset = s.line, s.offset
+	}
+
+	// Check if block is complete
+	if s.braceCount <= 0 && s.current.Len() > 10 {
+		return s.finish(), true
+	}
+	return Block{}, false
+}
+
+// Flush returns the block that is still being built at the end of the input,
+// if it is long enough to be a JSON payload, and resets the scanner state.
+func (s *BlockScanner) Flush() (Block, bool) {
+	if !s.insideBlock {
+		return Block{}, false
+	}
+	if s.current.Len() > 10 {
+		return s.finish(), true
+	}
+	s.insideBlock = false
+	s.current.Reset()
+	return Block{}, false
+}
+
+// finish closes the current block and returns it.
+func (s *BlockScanner) finish() Block {
+	b := s.start
+	b.Text = s.current.String()
+	b.EndLine = s.lastLine
+	b.EndOffset = s.lastOffset
+	s.insideBlock = false
+	s.current.Reset()
+	return b
+}
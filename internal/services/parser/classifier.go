@@ -1,6 +1,8 @@
 package parser
 
 import (
+	"encoding/json"
+	"fmt"
 	"strings"
 
 	"github.com/NoroSaroyan/log-parser/internal/domain/models/dto"
@@ -25,3 +27,56 @@ func InferStationTypeFromSteps(steps []dto.TestStepDTO) (stationType, pcba strin
 	}
 	return "", ""
 }
+
+// ClassifyBlock decodes a single JSON block into the DTO it represents, using
+// the same per-element rules as the first pass of ParseMixedJSONArray:
+//
+//	[ ... ]                            -> []dto.TestStepDTO
+//	{ "TestStation": "PCBA" | "Final" } -> dto.TestStationRecordDTO
+//	{ "TestStation": "Download" }       -> dto.DownloadInfoDTO
+//
+// Unlike ParseMixedJSONArray it does not look at other blocks, so step arrays
+// are returned even when no station record for their PCBA is known. Blocks
+// of any other shape yield an error.
+func ClassifyBlock(block string) (interface{}, error) {
+	raw := []byte(strings.TrimSpace(block))
+	if len(raw) == 0 {
+		return nil, fmt.Errorf("empty block")
+	}
+
+	switch raw[0] {
+	case '[':
+		var steps []dto.TestStepDTO
+		if err := json.Unmarshal(raw, &steps); err != nil {
+			return nil, fmt.Errorf("unmarshal test step array: %w", err)
+		}
+		return steps, nil
+
+	case '{':
+		var probe struct {
+			TestStation string `json:"TestStation"`
+		}
+		if err := json.Unmarshal(raw, &probe); err != nil {
+			return nil, fmt.Errorf("probe JSON object: %w", err)
+		}
+		switch probe.TestStation {
+		case "PCBA", "Final":
+			var record dto.TestStationRecordDTO
+			if err := json.Unmarshal(raw, &record); err != nil {
+				return nil, fmt.Errorf("unmarshal TestStationRecord: %w", err)
+			}
+			return record, nil
+		case "Download":
+			var download dto.DownloadInfoDTO
+			if err := json.Unmarshal(raw, &download); err != nil {
+				return nil, fmt.Errorf("unmarshal DownloadInfo: %w", err)
+			}
+			return download, nil
+		default:
+			return nil, fmt.Errorf("unknown TestStation type %q", probe.TestStation)
+		}
+
+	default:
+		return nil, fmt.Errorf("unexpected JSON element starting with %q", raw[0])
+	}
+}
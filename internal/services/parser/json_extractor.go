@@ -12,6 +12,9 @@ Functions:
   - ExtractJson: Scans a raw log string, identifies and extracts well-formed JSON blocks
     by matching balanced braces/brackets, even if spanning multiple lines.
 
+  - BlockScanner: The line-fed state machine behind ExtractJson. It reports line numbers
+    and byte offsets of every block and can be used on files that are still growing.
+
   - FilterRelevantJsonBlocks: Filters extracted JSON blocks, returning only those
     which can successfully unmarshal into the known domain data structures, thus
     identifying blocks relevant to the application’s domain logic.
@@ -27,7 +30,7 @@ import (
 
 // ExtractJson scans the provided raw log string line-by-line and extracts JSON blocks.
 //
-// It uses a simple state machine approach:
+// It feeds every line to a BlockScanner, a simple state machine that:
 //   - Detects lines containing JSON opening delimiters '{' or '[' after a known prefix marker.
 //   - Tracks balanced curly braces and square brackets to identify complete JSON structures,
 //     including nested objects or arrays spanning multiple lines.
@@ -39,75 +42,17 @@ import (
 // If the input contains no JSON blocks or malformed blocks that can't be balanced, those are ignored.
 // The function returns an error only if the input scanning encounters an I/O error.
 func ExtractJson(logs string) ([]string, error) {
-	lines := strings.Split(logs, "\n")
 	var blocks []string
-	var currentBlock strings.Builder
-	var insideBlock bool
-	var braceCount int
-
-	for _, line := range lines {
-		if strings.Contains(line, " Data  {") {
-			// Start of a new Data block
-			if insideBlock {
-				// Previous block wasn't properly closed, save it anyway
-				blocks = append(blocks, currentBlock.String())
-			}
-
-			insideBlock = true
-			currentBlock.Reset()
-			braceCount = 0
-
-			// Extract everything after " Data  "
-			dataIdx := strings.Index(line, " Data  ")
-			if dataIdx != -1 {
-				jsonPart := line[dataIdx+7:] // 7 = len(" Data  ")
-				currentBlock.WriteString(jsonPart)
-				currentBlock.WriteByte('\n')
-				braceCount += strings.Count(jsonPart, "{") - strings.Count(jsonPart, "}")
-			}
-		} else if strings.Contains(line, " Data  [") {
-			// Start of a new Data array block
-			if insideBlock {
-				// Previous block wasn't properly closed, save it anyway
-				blocks = append(blocks, currentBlock.String())
-			}
-
-			insideBlock = true
-			currentBlock.Reset()
-			braceCount = 0
-
-			// Extract everything after " Data  "
-			dataIdx := strings.Index(line, " Data  ")
-			if dataIdx != -1 {
-				jsonPart := line[dataIdx+7:] // 7 = len(" Data  ")
-				currentBlock.WriteString(jsonPart)
-				currentBlock.WriteByte('\n')
-				braceCount += strings.Count(jsonPart, "[") - strings.Count(jsonPart, "]")
-			}
-		} else if insideBlock {
-			// Continue building the current block
-			// Extract JSON content after the log prefix
-			prefixEnd := strings.Index(line, "]:")
-			if prefixEnd != -1 && len(line) > prefixEnd+2 {
-				jsonPart := line[prefixEnd+2:]
-				currentBlock.WriteString(jsonPart)
-				currentBlock.WriteByte('\n')
-				braceCount += strings.Count(jsonPart, "{") - strings.Count(jsonPart, "}")
-				braceCount += strings.Count(jsonPart, "[") - strings.Count(jsonPart, "]")
-			}
-
-			// Check if block is complete
-			if braceCount <= 0 && currentBlock.Len() > 10 {
-				blocks = append(blocks, currentBlock.String())
-				insideBlock = false
-				currentBlock.Reset()
-			}
+	scanner := NewBlockScanner()
+	for _, line := range strings.Split(logs, "\n") {
+		if block, ok := scanner.Feed(line); ok {
+			blocks = append(blocks, block.Text)
 		}
 	}
 
 	// Handle case where file ends while inside a block
-	if insideBlock && currentBlock.Len() > 10 {
-		blocks = append(blocks, currentBlock.String())
+	if block, ok := scanner.Flush(); ok {
+		blocks = append(blocks, block.Text)
 	}
 
 	return blocks, nil
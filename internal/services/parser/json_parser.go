@@ -23,6 +23,7 @@ import (
 
 	"github.com/NoroSaroyan/log-parser/internal/domain/models/dto"
 	"github.com/NoroSaroyan/log-parser/internal/infrastructure/logger"
+	"github.com/NoroSaroyan/log-parser/internal/infrastructure/metrics"
 )
 
 // ParseMixedJSONArray parses a raw JSON byte array representing a mixed-type JSON array.
@@ -314,6 +315,16 @@ func ParseMixedJSONArray(data []byte) ([]interface{}, error) {
 		}
 	}
 
+	metrics.Payloads.WithLabelValues("download").Add(float64(cntDownload))
+	metrics.Payloads.WithLabelValues("station_pcba").Add(float64(cntStationPCBA))
+	metrics.Payloads.WithLabelValues("station_final").Add(float64(cntStationFinal))
+	metrics.Payloads.WithLabelValues("test_steps").Add(float64(cntStepArrays))
+	metrics.StepArrays.WithLabelValues("same_type").Add(float64(cntStepsMatchedSameType))
+	metrics.StepArrays.WithLabelValues("different_type").Add(float64(cntStepsMatchedDiffType))
+	metrics.StepArrays.WithLabelValues("orphan").Add(float64(cntStepsOrphan))
+	metrics.StepArrays.WithLabelValues("missing_pcba_scan").Add(float64(cntStepsNoScan))
+	metrics.StepArrays.WithLabelValues("unknown_type").Add(float64(cntStepsUnknownInfer))
+
 	logger.Info("Parser classification summary",
 		logger.WithFields(map[string]interface{}{
 			"download_payloads":               cntDownload,
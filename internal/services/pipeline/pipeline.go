@@ -0,0 +1,252 @@
+/*
+Package pipeline implements the parsing half of log ingestion: reading a log
+file, extracting the embedded JSON blocks, filtering and parsing them into
+domain DTOs and grouping the result by PCBA number.
+
+The pipeline deliberately stops before anything touches a database. Callers
+decide what happens with the grouped data: the CLI "process" mode dispatches it
+to Postgres, the "analyze" mode dispatches it to an in-memory store to predict
+the outcome, and other consumers may simply inspect or export it.
+
+Functions:
+
+  - ReadFile: reads a plain-text or gzip-compressed log file into memory.
+  - Parse: runs extraction, filtering, parsing, grouping and validation over raw log data.
+  - ParseBlocks: runs filtering, parsing, grouping and validation over extracted JSON blocks.
+  - ParseFile: ReadFile followed by Parse.
+  - CalculateParsingStatistics: counts parsed items by kind.
+  - IsSupportedFile: reports whether a path looks like an ingestible log file.
+*/
+package pipeline
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/NoroSaroyan/log-parser/internal/domain/models/dto"
+	"github.com/NoroSaroyan/log-parser/internal/infrastructure/logger"
+	"github.com/NoroSaroyan/log-parser/internal/infrastructure/metrics"
+	"github.com/NoroSaroyan/log-parser/internal/services/parser"
+	"github.com/NoroSaroyan/log-parser/internal/services/processor"
+)
+
+// ParsingStatistics holds statistics about parsed items
+type ParsingStatistics struct {
+	FinalStations  int `json:"final_stations"`
+	PCBAStations   int `json:"pcba_stations"`
+	DownloadInfo   int `json:"download_info"`
+	TestStepArrays int `json:"test_step_arrays"`
+	TotalTestSteps int `json:"total_test_steps"`
+}
+
+// Result is the outcome of running the parsing pipeline over one log file.
+type Result struct {
+	// File is the name or path the data was read from.
+	File string `json:"file"`
+	// TotalBlocks is the number of JSON blocks found in the log.
+	TotalBlocks int `json:"total_blocks"`
+	// FilteredBlocks is the number of blocks recognised as domain payloads.
+	FilteredBlocks int `json:"filtered_blocks"`
+	// Stats counts the parsed items by kind.
+	Stats ParsingStatistics `json:"stats"`
+	// Groups holds the parsed data grouped by PCBA number, ready for dispatch.
+	// Groups rejected by validation are not included.
+	Groups []dto.GroupedDataDTO `json:"-"`
+	// Validation holds the findings and the summary of the validation step.
+	// Callers that dispatch the groups should persist it as well.
+	Validation processor.ValidationReport `json:"-"`
+}
+
+// validator runs the default rules between grouping and dispatch.
+var validator = processor.NewDefaultValidator()
+
+// IsSupportedFile reports whether path has an extension the pipeline can read.
+func IsSupportedFile(path string) bool {
+	lower := strings.ToLower(path)
+	return strings.HasSuffix(lower, ".log") || strings.HasSuffix(lower, ".txt") ||
+		strings.HasSuffix(lower, ".json") || strings.HasSuffix(lower, ".gz")
+}
+
+// ParseFile reads the file at path and runs Parse over its content.
+func ParseFile(path string) (*Result, error) {
+	logData, err := ReadFile(path)
+	if err != nil {
+		logger.Error("Failed to read file", logger.WithFields(map[string]interface{}{
+			"file":  path,
+			"error": err,
+		}))
+		return nil, fmt.Errorf("failed to read file: %w", err)
+	}
+
+	logger.Debug("File read successfully", logger.WithFields(map[string]interface{}{
+		"file":       path,
+		"size_bytes": len(logData),
+	}))
+
+	return Parse(path, logData)
+}
+
+// Parse extracts, filters, parses and groups the JSON payloads contained in
+// logData. The name is only used for logging and is copied into the Result.
+func Parse(name string, logData []byte) (*Result, error) {
+	// Extract JSON blocks
+	allBlocks, err := parser.ExtractJson(string(logData))
+	if err != nil {
+		logger.Error("Failed to extract JSON blocks", logger.WithFields(map[string]interface{}{
+			"file":  name,
+			"error": err,
+		}))
+		return nil, fmt.Errorf("failed to extract JSON blocks: %w", err)
+	}
+	metrics.BlocksExtracted.Add(float64(len(allBlocks)))
+
+	logger.Debug("JSON extraction completed", logger.WithFields(map[string]interface{}{
+		"file":         name,
+		"total_blocks": len(allBlocks),
+	}))
+
+	return ParseBlocks(name, allBlocks)
+}
+
+// ParseBlocks filters, parses and groups already extracted JSON blocks. It is
+// the part of Parse after extraction, used by callers that extract blocks
+// themselves (e.g. with a parser.BlockScanner on a growing file).
+func ParseBlocks(name string, allBlocks []string) (*Result, error) {
+	// Filter relevant blocks
+	filteredBlocks, err := parser.FilterRelevantJsonBlocks(allBlocks)
+	if err != nil {
+		logger.Error("Failed to filter relevant JSON blocks", logger.WithFields(map[string]interface{}{
+			"file":  name,
+			"error": err,
+		}))
+		return nil, fmt.Errorf("failed to filter relevant JSON blocks: %w", err)
+	}
+	metrics.BlocksRelevant.Add(float64(len(filteredBlocks)))
+
+	logger.Debug("Block filtering completed", logger.WithFields(map[string]interface{}{
+		"file":             name,
+		"filtered_blocks":  len(filteredBlocks),
+		"discarded_blocks": len(allBlocks) - len(filteredBlocks),
+	}))
+
+	// Parse JSON
+	combinedJSON := "[\n" + strings.Join(filteredBlocks, ",\n") + "\n]"
+	parsedItems, err := parser.ParseMixedJSONArray([]byte(combinedJSON))
+	if err != nil {
+		logger.Error("Failed to parse mixed JSON array", logger.WithFields(map[string]interface{}{
+			"file":  name,
+			"error": err,
+		}))
+		return nil, fmt.Errorf("failed to parse mixed JSON array: %w", err)
+	}
+
+	// Calculate statistics
+	stats := CalculateParsingStatistics(parsedItems)
+
+	logger.Info("PARSING STATISTICS", logger.WithFields(map[string]interface{}{
+		"file":           name,
+		"Final":          stats.FinalStations,
+		"PCBA":           stats.PCBAStations,
+		"Download":       stats.DownloadInfo,
+		"TestStepArrays": stats.TestStepArrays,
+		"TotalTestSteps": stats.TotalTestSteps,
+	}))
+
+	// Group data
+	groupedData, err := processor.GroupByPCBANumber(parsedItems)
+	if err != nil {
+		logger.Error("Failed to group data", logger.WithFields(map[string]interface{}{
+			"file":  name,
+			"error": err,
+		}))
+		return nil, fmt.Errorf("failed to group data: %w", err)
+	}
+
+	logger.Debug("Data grouping completed", logger.WithFields(map[string]interface{}{
+		"file":   name,
+		"groups": len(groupedData),
+	}))
+
+	// Validate groups
+	validation := validator.Validate(name, groupedData)
+	if validation.Summary.GroupsRejected > 0 {
+		logger.Warn("Validation rejected groups", logger.WithFields(map[string]interface{}{
+			"file":            name,
+			"groups_rejected": validation.Summary.GroupsRejected,
+			"rejected_pcbas":  validation.Summary.RejectedPCBANumbers,
+			"findings":        validation.Summary.FindingsByRule,
+		}))
+	}
+	logger.Debug("Validation completed", logger.WithFields(map[string]interface{}{
+		"file":                 name,
+		"groups_with_findings": validation.Summary.GroupsWithFindings,
+		"findings":             len(validation.Findings),
+	}))
+
+	return &Result{
+		File:           name,
+		TotalBlocks:    len(allBlocks),
+		FilteredBlocks: len(filteredBlocks),
+		Stats:          stats,
+		Groups:         validation.Accepted,
+		Validation:     validation,
+	}, nil
+}
+
+// CalculateParsingStatistics analyzes parsed items and returns detailed statistics
+func CalculateParsingStatistics(parsedItems []interface{}) ParsingStatistics {
+	stats := ParsingStatistics{}
+
+	for _, item := range parsedItems {
+		switch v := item.(type) {
+		case dto.TestStationRecordDTO:
+			if v.TestStation == "Final" {
+				stats.FinalStations++
+			} else if v.TestStation == "PCBA" {
+				stats.PCBAStations++
+			}
+		case dto.DownloadInfoDTO:
+			stats.DownloadInfo++
+		case []dto.TestStepDTO:
+			stats.TestStepArrays++
+			stats.TotalTestSteps += len(v)
+		}
+	}
+
+	return stats
+}
+
+// ReadFile reads a log file into memory, transparently decompressing files
+// with a .gz extension.
+func ReadFile(path string) ([]byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	if strings.HasSuffix(strings.ToLower(path), ".gz") {
+		gr, err := gzip.NewReader(f)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create gzip reader: %w", err)
+		}
+		defer gr.Close()
+
+		var buf bytes.Buffer
+		scanner := bufio.NewScanner(gr)
+		for scanner.Scan() {
+			buf.Write(scanner.Bytes())
+			buf.WriteByte('\n')
+		}
+		if err := scanner.Err(); err != nil {
+			return nil, fmt.Errorf("error reading gzip content: %w", err)
+		}
+		return buf.Bytes(), nil
+	}
+
+	return os.ReadFile(path)
+}
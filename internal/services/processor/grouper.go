@@ -7,6 +7,18 @@ Function:
     JSON parsing of logs) and groups them by their PCBANumber, aggregating related DTOs into
     a unified structure for downstream processing or database insertion.
 
+  - ItemKey: Returns the grouping key of a single parsed item.
+
+  - SummarizeGroups: Computes the diagnostic counters of the grouping phase (groups with
+    download/station/steps, station and step array counts by type, mismatching groups).
+
+  - PairSessions: Pairs the station records and step arrays of one group by station type in
+    log order, leaving unpaired records and step arrays on their own.
+
+  - Validator: Runs pluggable rules (PCBA format, required LogisticData fields, IMEI, ICCID,
+    BLE MAC, elapsed times) over the groups before dispatch. Each rule has a severity: reject
+    drops the group, warn and annotate only record a Finding.
+
 GroupByPCBANumber organizes parsed domain entities into logical groups keyed by the PCBANumber,
 which serves as the primary identifier linking DownloadInfoDTO, TestStationRecordDTO, and
 TestStepDTO data that belong together.
@@ -47,114 +59,44 @@ import (
 
 	"github.com/NoroSaroyan/log-parser/internal/domain/models/dto"
 	"github.com/NoroSaroyan/log-parser/internal/infrastructure/logger"
+	"github.com/NoroSaroyan/log-parser/internal/services/parser"
 )
 
 func GroupByPCBANumber(parsed []interface{}) ([]dto.GroupedDataDTO, error) {
 	groups := map[string]*dto.GroupedDataDTO{}
 	for _, item := range parsed {
+		key, err := ItemKey(item)
+		if err != nil {
+			return nil, err
+		}
+		if key == "" {
+			// Skip DownloadInfo records with empty TcuPCBANumber - they are optional
+			logger.Debug("Skipping DownloadInfo with empty TcuPCBANumber",
+				logger.WithFields(map[string]interface{}{
+					"reason": "DownloadInfo requires a TCU PCBA number for proper grouping and database insertion",
+				}),
+			)
+			continue
+		}
+		group, ok := groups[key]
+		if !ok {
+			group = &dto.GroupedDataDTO{}
+			groups[key] = group
+		}
+
 		switch v := item.(type) {
 		case dto.DownloadInfoDTO:
-			key := strings.TrimSpace(v.TcuPCBANumber)
-			if key == "" {
-				// Skip DownloadInfo records with empty TcuPCBANumber - they are optional
-				logger.Debug("Skipping DownloadInfo with empty TcuPCBANumber",
-					logger.WithFields(map[string]interface{}{
-						"reason": "DownloadInfo requires a TCU PCBA number for proper grouping and database insertion",
-					}),
-				)
-				continue
-			}
-			group, ok := groups[key]
-			if !ok {
-				group = &dto.GroupedDataDTO{}
-				groups[key] = group
-			}
 			group.DownloadInfo = v
-
 		case dto.TestStationRecordDTO:
-			key := strings.TrimSpace(v.LogisticData.PCBANumber)
-			// Fallback to ProductSN if PCBANumber is empty
-			if key == "" {
-				key = strings.TrimSpace(v.LogisticData.ProductSN)
-				if key == "" {
-					return nil, fmt.Errorf("TestStationRecordDTO missing both LogisticData.PCBANumber and ProductSN")
-				}
-			}
-			group, ok := groups[key]
-			if !ok {
-				group = &dto.GroupedDataDTO{}
-				groups[key] = group
-			}
 			group.TestStationRecords = append(group.TestStationRecords, v)
-
 		case []dto.TestStepDTO:
-			var key string
-			for _, step := range v {
-				if step.TestStepName == "PCBA Scan" || step.TestStepName == "Compare PCBA Serial Number" || step.TestStepName == "Valid PCBA Serial Number" {
-					key = strings.TrimSpace(step.GetMeasuredValueString())
-					break
-				}
-			}
-
-			if key == "" {
-				return nil, fmt.Errorf("TestStepDTO array missing PCBA Scan step with measured value")
-			}
-			group, ok := groups[key]
-			if !ok {
-				group = &dto.GroupedDataDTO{}
-				groups[key] = group
-			}
 			group.TestSteps = append(group.TestSteps, v)
-
-		default:
-			return nil, fmt.Errorf("unexpected type in parsed data: %T", v)
 		}
 	}
 
 	result := make([]dto.GroupedDataDTO, 0, len(groups))
-	// Diagnostic counters for the grouping phase.
-	var (
-		groupsWithDownload int
-		groupsWithAnyStation int
-		groupsWithAnySteps int
-		stationsByType = map[string]int{}
-		stepsByType    = map[string]int{}
-		mismatchGroups int // groups where step count > station record count (pre-dispatch)
-	)
 	for key, g := range groups {
-		if (g.DownloadInfo != dto.DownloadInfoDTO{}) {
-			groupsWithDownload++
-		}
-		if len(g.TestStationRecords) > 0 {
-			groupsWithAnyStation++
-			for _, tsr := range g.TestStationRecords {
-				stationsByType[strings.TrimSpace(tsr.TestStation)]++
-			}
-		}
-		if len(g.TestSteps) > 0 {
-			groupsWithAnySteps++
-			for _, steps := range g.TestSteps {
-				// Inline classifier (can't import parser here — cycle).
-				var t string
-				for _, s := range steps {
-					switch s.TestStepName {
-					case "PCBA Scan":
-						t = "PCBA"
-					case "Compare PCBA Serial Number", "Valid PCBA Serial Number":
-						t = "Final"
-					}
-					if t != "" {
-						break
-					}
-				}
-				if t == "" {
-					t = "unknown"
-				}
-				stepsByType[t]++
-			}
-		}
 		if len(g.TestSteps) > len(g.TestStationRecords) {
-			mismatchGroups++
 			// Classify the mismatch cause: is it a missing station (Bug #1) or
 			// a retry-asymmetry (same type, unequal counts)?
 			stationsCountByType := map[string]int{}
@@ -163,22 +105,7 @@ func GroupByPCBANumber(parsed []interface{}) ([]dto.GroupedDataDTO, error) {
 			}
 			stepsCountByType := map[string]int{}
 			for _, steps := range g.TestSteps {
-				var t string
-				for _, s := range steps {
-					switch s.TestStepName {
-					case "PCBA Scan":
-						t = "PCBA"
-					case "Compare PCBA Serial Number", "Valid PCBA Serial Number":
-						t = "Final"
-					}
-					if t != "" {
-						break
-					}
-				}
-				if t == "" {
-					t = "unknown"
-				}
-				stepsCountByType[t]++
+				stepsCountByType[stepArrayType(steps)]++
 			}
 			// Determine the category
 			var cause string
@@ -201,32 +128,125 @@ func GroupByPCBANumber(parsed []interface{}) ([]dto.GroupedDataDTO, error) {
 			}
 			logger.Warn("Group has more step arrays than station records (will fail in dispatcher)",
 				logger.WithFields(map[string]interface{}{
-					"group_key":              key,
-					"station_record_count":   len(g.TestStationRecords),
-					"step_array_count":       len(g.TestSteps),
-					"has_download":           g.DownloadInfo != (dto.DownloadInfoDTO{}),
-					"stations_by_type":       stationsCountByType,
-					"steps_by_type":          stepsCountByType,
-					"cause":                  cause,
-					"missing_station_types":  missing,
-					"asymmetric_types":       asymmetric,
+					"group_key":             key,
+					"station_record_count":  len(g.TestStationRecords),
+					"step_array_count":      len(g.TestSteps),
+					"has_download":          g.DownloadInfo != (dto.DownloadInfoDTO{}),
+					"stations_by_type":      stationsCountByType,
+					"steps_by_type":         stepsCountByType,
+					"cause":                 cause,
+					"missing_station_types": missing,
+					"asymmetric_types":      asymmetric,
 				}),
 			)
 		}
 		result = append(result, *g)
 	}
 
+	summary := SummarizeGroups(result)
 	logger.Info("Grouping summary",
 		logger.WithFields(map[string]interface{}{
-			"groups_total":          len(groups),
-			"groups_with_download":  groupsWithDownload,
-			"groups_with_station":   groupsWithAnyStation,
-			"groups_with_steps":     groupsWithAnySteps,
-			"stations_by_type":      stationsByType,
-			"step_arrays_by_type":   stepsByType,
-			"groups_with_mismatch":  mismatchGroups,
+			"groups_total":         summary.GroupsTotal,
+			"groups_with_download": summary.GroupsWithDownload,
+			"groups_with_station":  summary.GroupsWithStation,
+			"groups_with_steps":    summary.GroupsWithSteps,
+			"stations_by_type":     summary.StationsByType,
+			"step_arrays_by_type":  summary.StepArraysByType,
+			"groups_with_mismatch": summary.GroupsWithMismatch,
 		}),
 	)
 
 	return result, nil
 }
+
+// ItemKey returns the key GroupByPCBANumber files a parsed item under:
+// TcuPCBANumber for DownloadInfoDTO, LogisticData.PCBANumber (or ProductSN)
+// for TestStationRecordDTO and the scanned PCBA for a TestStepDTO array.
+//
+// An empty key without error is returned for DownloadInfo records without a
+// TCU PCBA number, which are skipped during grouping.
+func ItemKey(item interface{}) (string, error) {
+	switch v := item.(type) {
+	case dto.DownloadInfoDTO:
+		return strings.TrimSpace(v.TcuPCBANumber), nil
+
+	case dto.TestStationRecordDTO:
+		key := strings.TrimSpace(v.LogisticData.PCBANumber)
+		// Fallback to ProductSN if PCBANumber is empty
+		if key == "" {
+			key = strings.TrimSpace(v.LogisticData.ProductSN)
+			if key == "" {
+				return "", fmt.Errorf("TestStationRecordDTO missing both LogisticData.PCBANumber and ProductSN")
+			}
+		}
+		return key, nil
+
+	case []dto.TestStepDTO:
+		for _, step := range v {
+			if step.TestStepName == "PCBA Scan" || step.TestStepName == "Compare PCBA Serial Number" || step.TestStepName == "Valid PCBA Serial Number" {
+				if key := strings.TrimSpace(step.GetMeasuredValueString()); key != "" {
+					return key, nil
+				}
+				break
+			}
+		}
+		return "", fmt.Errorf("TestStepDTO array missing PCBA Scan step with measured value")
+
+	default:
+		return "", fmt.Errorf("unexpected type in parsed data: %T", v)
+	}
+}
+
+// GroupingSummary holds the diagnostic counters of the grouping phase.
+type GroupingSummary struct {
+	GroupsTotal        int            `json:"groups_total"`
+	GroupsWithDownload int            `json:"groups_with_download"`
+	GroupsWithStation  int            `json:"groups_with_station"`
+	GroupsWithSteps    int            `json:"groups_with_steps"`
+	StationsByType     map[string]int `json:"stations_by_type"`
+	StepArraysByType   map[string]int `json:"step_arrays_by_type"`
+	// GroupsWithMismatch counts groups where step count > station record count (pre-dispatch).
+	GroupsWithMismatch int `json:"groups_with_mismatch"`
+}
+
+// SummarizeGroups computes the grouping counters for the given groups.
+//
+// Step arrays without a recognised scan step are counted under "unknown".
+func SummarizeGroups(groups []dto.GroupedDataDTO) GroupingSummary {
+	summary := GroupingSummary{
+		GroupsTotal:      len(groups),
+		StationsByType:   map[string]int{},
+		StepArraysByType: map[string]int{},
+	}
+	for _, g := range groups {
+		if (g.DownloadInfo != dto.DownloadInfoDTO{}) {
+			summary.GroupsWithDownload++
+		}
+		if len(g.TestStationRecords) > 0 {
+			summary.GroupsWithStation++
+			for _, tsr := range g.TestStationRecords {
+				summary.StationsByType[strings.TrimSpace(tsr.TestStation)]++
+			}
+		}
+		if len(g.TestSteps) > 0 {
+			summary.GroupsWithSteps++
+			for _, steps := range g.TestSteps {
+				summary.StepArraysByType[stepArrayType(steps)]++
+			}
+		}
+		if len(g.TestSteps) > len(g.TestStationRecords) {
+			summary.GroupsWithMismatch++
+		}
+	}
+	return summary
+}
+
+// stepArrayType returns the station type inferred from a step array, or
+// "unknown" when the array has no recognised scan step.
+func stepArrayType(steps []dto.TestStepDTO) string {
+	t, _ := parser.InferStationTypeFromSteps(steps)
+	if t == "" {
+		return "unknown"
+	}
+	return t
+}
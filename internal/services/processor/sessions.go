@@ -0,0 +1,63 @@
+package processor
+
+import (
+	"strings"
+
+	"github.com/NoroSaroyan/log-parser/internal/domain/models/dto"
+)
+
+// Session is one attempt of a device on a station: a station record together
+// with the step array that belongs to it. Either side may be missing when the
+// log did not contain it.
+type Session struct {
+	StationType string
+	// Record is nil when only steps were found (orphan steps).
+	Record *dto.TestStationRecordDTO
+	// Steps is nil when only the station record was found.
+	Steps []dto.TestStepDTO
+}
+
+// PairSessions pairs the station records and step arrays of a group by
+// station type, in the order they appeared in the log: the k-th station record
+// of a type is paired with the k-th step array inferred to be of that type.
+// This is the FIFO pairing described in docs/fix-plan.md and, unlike the
+// positional pairing in the dispatcher, never attaches steps to a station of
+// another type.
+//
+// Paired sessions come first, in station record order, followed by station
+// records without steps and finally step arrays without a station record
+// (including arrays whose station type cannot be inferred).
+func PairSessions(group dto.GroupedDataDTO) []Session {
+	stationsByType := map[string]int{}
+	for _, rec := range group.TestStationRecords {
+		stationsByType[strings.TrimSpace(rec.TestStation)]++
+	}
+
+	// Split step arrays into per-type FIFO queues; arrays beyond the number
+	// of stations of their type can never be paired and are kept aside.
+	queues := map[string][][]dto.TestStepDTO{}
+	var orphanSteps []Session
+	for _, steps := range group.TestSteps {
+		t := stepArrayType(steps)
+		if len(queues[t]) < stationsByType[t] {
+			queues[t] = append(queues[t], steps)
+			continue
+		}
+		orphanSteps = append(orphanSteps, Session{StationType: t, Steps: steps})
+	}
+
+	var sessions, stationsOnly []Session
+	for i := range group.TestStationRecords {
+		rec := group.TestStationRecords[i]
+		t := strings.TrimSpace(rec.TestStation)
+		if queue := queues[t]; len(queue) > 0 {
+			sessions = append(sessions, Session{StationType: t, Record: &rec, Steps: queue[0]})
+			queues[t] = queue[1:]
+			continue
+		}
+		stationsOnly = append(stationsOnly, Session{StationType: t, Record: &rec})
+	}
+
+	sessions = append(sessions, stationsOnly...)
+	return append(sessions, orphanSteps...)
+}
@@ -1 +1,367 @@
 package processor
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+
+	"github.com/NoroSaroyan/log-parser/internal/domain/models/dto"
+	"github.com/NoroSaroyan/log-parser/internal/services/parser"
+)
+
+// Severity decides what happens to a group that violates a rule.
+type Severity string
+
+const (
+	// SeverityReject drops the whole group before dispatch.
+	SeverityReject Severity = "reject"
+	// SeverityWarn keeps the group and records the finding.
+	SeverityWarn Severity = "warn"
+	// SeverityAnnotate keeps the group and records the finding for information only.
+	SeverityAnnotate Severity = "annotate"
+)
+
+// Record types a finding can refer to.
+const (
+	RecordDownload = "download"
+	RecordStation  = "station"
+	RecordSteps    = "steps"
+)
+
+// Finding is one rule violation of one record in a group.
+type Finding struct {
+	Rule     string   `json:"rule"`
+	Severity Severity `json:"severity"`
+	PCBA     string   `json:"pcba"`
+	// RecordType is download, station or steps; RecordIndex is the position
+	// of the record in GroupedDataDTO.TestStationRecords or TestSteps.
+	RecordType  string `json:"record_type"`
+	RecordIndex int    `json:"record_index"`
+	StationType string `json:"station_type,omitempty"`
+	Field       string `json:"field"`
+	Value       string `json:"value"`
+	Message     string `json:"message"`
+}
+
+// Rule checks one aspect of a group. Check returns the violations; the
+// Validator fills in Rule, Severity and PCBA.
+type Rule interface {
+	Name() string
+	Severity() Severity
+	Check(group dto.GroupedDataDTO) []Finding
+}
+
+type ruleFunc struct {
+	name     string
+	severity Severity
+	check    func(group dto.GroupedDataDTO) []Finding
+}
+
+// NewRule builds a Rule from a check function.
+func NewRule(name string, severity Severity, check func(group dto.GroupedDataDTO) []Finding) Rule {
+	return &ruleFunc{name: name, severity: severity, check: check}
+}
+
+func (r *ruleFunc) Name() string                             { return r.name }
+func (r *ruleFunc) Severity() Severity                       { return r.severity }
+func (r *ruleFunc) Check(group dto.GroupedDataDTO) []Finding { return r.check(group) }
+
+// ValidationSummary counts the validation outcome of one file.
+type ValidationSummary struct {
+	File                string           `json:"file"`
+	GroupsTotal         int              `json:"groups_total"`
+	GroupsRejected      int              `json:"groups_rejected"`
+	GroupsWithFindings  int              `json:"groups_with_findings"`
+	FindingsBySeverity  map[Severity]int `json:"findings_by_severity"`
+	FindingsByRule      map[string]int   `json:"findings_by_rule"`
+	RejectedPCBANumbers []string         `json:"rejected_pcba_numbers,omitempty"`
+}
+
+// ValidationReport is the result of validating the groups of one file.
+type ValidationReport struct {
+	// Accepted holds the groups to dispatch, in their original order.
+	Accepted []dto.GroupedDataDTO
+	Findings []Finding
+	Summary  ValidationSummary
+}
+
+// Validator runs a set of rules over grouped data between grouping and dispatch.
+type Validator struct {
+	rules []Rule
+}
+
+// NewValidator creates a Validator with the given rules.
+func NewValidator(rules ...Rule) *Validator {
+	return &Validator{rules: rules}
+}
+
+// NewDefaultValidator creates a Validator with DefaultRules.
+func NewDefaultValidator() *Validator {
+	return NewValidator(DefaultRules()...)
+}
+
+// Validate runs every rule over every group. Groups with at least one
+// reject finding are left out of Accepted.
+func (v *Validator) Validate(file string, groups []dto.GroupedDataDTO) ValidationReport {
+	report := ValidationReport{
+		Accepted: make([]dto.GroupedDataDTO, 0, len(groups)),
+		Findings: []Finding{},
+		Summary: ValidationSummary{
+			File:               file,
+			GroupsTotal:        len(groups),
+			FindingsBySeverity: map[Severity]int{},
+			FindingsByRule:     map[string]int{},
+		},
+	}
+
+	for _, group := range groups {
+		key := groupPCBA(group)
+		rejected := false
+		found := 0
+		for _, rule := range v.rules {
+			for _, f := range rule.Check(group) {
+				f.Rule = rule.Name()
+				f.Severity = rule.Severity()
+				f.PCBA = key
+				report.Findings = append(report.Findings, f)
+				report.Summary.FindingsBySeverity[f.Severity]++
+				report.Summary.FindingsByRule[f.Rule]++
+				rejected = rejected || f.Severity == SeverityReject
+				found++
+			}
+		}
+		if found > 0 {
+			report.Summary.GroupsWithFindings++
+		}
+		if rejected {
+			report.Summary.GroupsRejected++
+			report.Summary.RejectedPCBANumbers = append(report.Summary.RejectedPCBANumbers, key)
+			continue
+		}
+		report.Accepted = append(report.Accepted, group)
+	}
+	return report
+}
+
+// groupPCBA returns the grouping key of a group, as used by GroupByPCBANumber.
+func groupPCBA(group dto.GroupedDataDTO) string {
+	if p := strings.TrimSpace(group.DownloadInfo.TcuPCBANumber); p != "" {
+		return p
+	}
+	for _, tsr := range group.TestStationRecords {
+		if key, err := ItemKey(tsr); err == nil && key != "" {
+			return key
+		}
+	}
+	for _, steps := range group.TestSteps {
+		if key, err := ItemKey(steps); err == nil && key != "" {
+			return key
+		}
+	}
+	return ""
+}
+
+// DefaultPCBAPattern matches the PCBA serial numbers written by the testers.
+var DefaultPCBAPattern = regexp.MustCompile(`^[A-Z0-9]{8,32}$`)
+
+// DefaultRequiredLogisticFields are the LogisticData fields every station
+// record is expected to carry.
+var DefaultRequiredLogisticFields = []string{"PCBANumber", "ProductSN", "PartNumber"}
+
+// DefaultRules returns the built-in rules with their default severities.
+// Only a malformed PCBA number rejects a group: it is the grouping key, so
+// everything in such a group is attributed to a device that does not exist.
+func DefaultRules() []Rule {
+	return []Rule{
+		PCBAFormatRule(DefaultPCBAPattern, SeverityReject),
+		RequiredLogisticFieldsRule(DefaultRequiredLogisticFields, SeverityWarn),
+		IMEIRule(SeverityWarn),
+		ICCIDRule(SeverityAnnotate),
+		BleMacRule(SeverityWarn),
+		NonNegativeElapsedRule(SeverityWarn),
+	}
+}
+
+// PCBAFormatRule checks every non-empty PCBA number of a group (Download,
+// LogisticData and the scan step of step arrays) against pattern.
+func PCBAFormatRule(pattern *regexp.Regexp, severity Severity) Rule {
+	return NewRule("pcba_format", severity, func(group dto.GroupedDataDTO) []Finding {
+		var findings []Finding
+		if p := strings.TrimSpace(group.DownloadInfo.TcuPCBANumber); p != "" && !pattern.MatchString(p) {
+			findings = append(findings, Finding{
+				RecordType: RecordDownload, Field: "TcuPCBANumber", Value: p,
+				Message: fmt.Sprintf("PCBA number does not match %s", pattern),
+			})
+		}
+		for i, tsr := range group.TestStationRecords {
+			if p := strings.TrimSpace(tsr.LogisticData.PCBANumber); p != "" && !pattern.MatchString(p) {
+				findings = append(findings, stationFinding(i, tsr, "PCBANumber", p,
+					fmt.Sprintf("PCBA number does not match %s", pattern)))
+			}
+		}
+		for i, steps := range group.TestSteps {
+			stationType, p := parser.InferStationTypeFromSteps(steps)
+			if p != "" && !pattern.MatchString(p) {
+				findings = append(findings, Finding{
+					RecordType: RecordSteps, RecordIndex: i, StationType: stationType,
+					Field: "TestMeasuredValue", Value: p,
+					Message: fmt.Sprintf("scanned PCBA number does not match %s", pattern),
+				})
+			}
+		}
+		return findings
+	})
+}
+
+// RequiredLogisticFieldsRule reports station records whose LogisticData has
+// an empty value in one of fields (LogisticDataDTO field names).
+func RequiredLogisticFieldsRule(fields []string, severity Severity) Rule {
+	return NewRule("required_logistic_fields", severity, func(group dto.GroupedDataDTO) []Finding {
+		var findings []Finding
+		for i, tsr := range group.TestStationRecords {
+			for _, field := range fields {
+				value, ok := logisticField(tsr.LogisticData, field)
+				if ok && strings.TrimSpace(value) == "" {
+					findings = append(findings, stationFinding(i, tsr, field, "", field+" is empty"))
+				}
+			}
+		}
+		return findings
+	})
+}
+
+// IMEIRule reports IMEIs that are not 15 digits with a valid Luhn check digit.
+func IMEIRule(severity Severity) Rule {
+	return logisticRule("imei", severity, "IMEI", func(imei string) string {
+		if len(imei) != 15 || !isDigits(imei) {
+			return "IMEI must be 15 digits"
+		}
+		if !luhnValid(imei) {
+			return "IMEI check digit is invalid"
+		}
+		return ""
+	})
+}
+
+// ICCIDRule reports ICCIDs that are not 19 or 20 digits starting with the
+// telecom prefix 89, or whose last digit is not a valid Luhn check digit.
+func ICCIDRule(severity Severity) Rule {
+	return logisticRule("iccid", severity, "TcuICCID", func(iccid string) string {
+		if (len(iccid) != 19 && len(iccid) != 20) || !isDigits(iccid) {
+			return "ICCID must be 19 or 20 digits"
+		}
+		if !strings.HasPrefix(iccid, "89") {
+			return "ICCID must start with 89"
+		}
+		if !luhnValid(iccid) {
+			return "ICCID check digit is invalid"
+		}
+		return ""
+	})
+}
+
+var bleMacPattern = regexp.MustCompile(`^([0-9A-Fa-f]{2}:){5}[0-9A-Fa-f]{2}$|^[0-9A-Fa-f]{12}$`)
+
+// BleMacRule reports BLE MAC addresses that are neither AA:BB:CC:DD:EE:FF nor
+// 12 hex digits.
+func BleMacRule(severity Severity) Rule {
+	return logisticRule("ble_mac", severity, "BleMac", func(mac string) string {
+		if !bleMacPattern.MatchString(mac) {
+			return "BLE MAC must be six hex octets"
+		}
+		return ""
+	})
+}
+
+// NonNegativeElapsedRule reports negative flash and test step elapsed times.
+func NonNegativeElapsedRule(severity Severity) Rule {
+	return NewRule("non_negative_elapsed", severity, func(group dto.GroupedDataDTO) []Finding {
+		var findings []Finding
+		if t := group.DownloadInfo.FlashElapsedTime; t < 0 {
+			findings = append(findings, Finding{
+				RecordType: RecordDownload, Field: "FlashElapsedTime", Value: fmt.Sprint(t),
+				Message: "elapsed time is negative",
+			})
+		}
+		for i, steps := range group.TestSteps {
+			stationType, _ := parser.InferStationTypeFromSteps(steps)
+			for _, step := range steps {
+				if step.TestStepElapsedTime < 0 {
+					findings = append(findings, Finding{
+						RecordType: RecordSteps, RecordIndex: i, StationType: stationType,
+						Field: "TestStepElapsedTime", Value: fmt.Sprint(step.TestStepElapsedTime),
+						Message: fmt.Sprintf("elapsed time of %q is negative", step.TestStepName),
+					})
+				}
+			}
+		}
+		return findings
+	})
+}
+
+// logisticRule builds a rule checking one non-empty LogisticData field of
+// every station record; check returns a message for invalid values.
+func logisticRule(name string, severity Severity, field string, check func(value string) string) Rule {
+	return NewRule(name, severity, func(group dto.GroupedDataDTO) []Finding {
+		var findings []Finding
+		for i, tsr := range group.TestStationRecords {
+			value, _ := logisticField(tsr.LogisticData, field)
+			value = strings.TrimSpace(value)
+			if value == "" {
+				continue
+			}
+			if msg := check(value); msg != "" {
+				findings = append(findings, stationFinding(i, tsr, field, value, msg))
+			}
+		}
+		return findings
+	})
+}
+
+func stationFinding(index int, tsr dto.TestStationRecordDTO, field, value, message string) Finding {
+	return Finding{
+		RecordType:  RecordStation,
+		RecordIndex: index,
+		StationType: strings.TrimSpace(tsr.TestStation),
+		Field:       field,
+		Value:       value,
+		Message:     message,
+	}
+}
+
+// logisticField returns the LogisticDataDTO field with the given name.
+func logisticField(d dto.LogisticDataDTO, field string) (string, bool) {
+	v := reflect.ValueOf(d).FieldByName(field)
+	if !v.IsValid() || v.Kind() != reflect.String {
+		return "", false
+	}
+	return v.String(), true
+}
+
+func isDigits(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return s != ""
+}
+
+// luhnValid reports whether the last digit of number is its Luhn check digit.
+func luhnValid(number string) bool {
+	sum := 0
+	double := false
+	for i := len(number) - 1; i >= 0; i-- {
+		d := int(number[i] - '0')
+		if double {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		double = !double
+	}
+	return sum%10 == 0
+}
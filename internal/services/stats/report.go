@@ -0,0 +1,129 @@
+package stats
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// column is one column of the per-file summary table.
+type column struct {
+	title string
+	value func(FileStats) interface{}
+}
+
+var summaryColumns = []column{
+	{"file", func(s FileStats) interface{} { return s.Name() }},
+	{"download", func(s FileStats) interface{} { return s.TypeCounts.Download }},
+	{"PCBA-st", func(s FileStats) interface{} { return s.TypeCounts.PCBAStation }},
+	{"Final-st", func(s FileStats) interface{} { return s.TypeCounts.FinalStation }},
+	{"PCBA-steps", func(s FileStats) interface{} { return s.TypeCounts.PCBASteps }},
+	{"Final-steps", func(s FileStats) interface{} { return s.TypeCounts.FinalSteps }},
+	{"emptyPCBA", func(s FileStats) interface{} { return s.StationsEmptyPCBA }},
+	{"full-cov", func(s FileStats) interface{} { return s.FullCoverage }},
+	{"bug1:missPCBA", func(s FileStats) interface{} { return s.Bug1MissingPCBARecord }},
+	{"bug1:missFinal", func(s FileStats) interface{} { return s.Bug1MissingFinalRecord }},
+	{"bug1:orphan", func(s FileStats) interface{} { return s.Bug1TotalOrphan }},
+	{"bug2:asymPCBA", func(s FileStats) interface{} { return s.Bug2RetryAsymmetryPCBA }},
+	{"bug2:asymFinal", func(s FileStats) interface{} { return s.Bug2RetryAsymmetryFinal }},
+}
+
+func header(label string) string {
+	return fmt.Sprintf("\n======== %s ========\n", label)
+}
+
+// WriteText renders the statistics of files as the plain-text side-by-side
+// report. When c is non-nil the step name differences between the old and
+// new era are included.
+func WriteText(w io.Writer, files []FileStats, c *Comparison) error {
+	var b strings.Builder
+
+	b.WriteString(header("Per-file summary"))
+	cells := make([]string, len(summaryColumns))
+	for i, col := range summaryColumns {
+		cells[i] = pad(col.title, i)
+	}
+	b.WriteString(strings.Join(cells, " | ") + "\n")
+	b.WriteString(strings.Repeat("-", 250) + "\n")
+	for _, s := range files {
+		if s.Error != "" {
+			fmt.Fprintf(&b, "%36s | ERROR: %s\n", s.Name(), s.Error)
+			continue
+		}
+		for i, col := range summaryColumns {
+			cells[i] = pad(fmt.Sprint(col.value(s)), i)
+		}
+		b.WriteString(strings.Join(cells, " | ") + "\n")
+	}
+
+	b.WriteString(header("TestToolVersion distribution"))
+	for _, s := range files {
+		fmt.Fprintf(&b, "  %-40s  PCBA: %s  Final: %s\n", s.Name(),
+			formatDistribution(s.TestToolVersions["PCBA"]), formatDistribution(s.TestToolVersions["Final"]))
+	}
+
+	b.WriteString(header("TestStation values (union per file)"))
+	for _, s := range files {
+		fmt.Fprintf(&b, "  %-40s  %s\n", s.Name(), formatList(s.StationValues))
+	}
+
+	b.WriteString(header("Unique TestStepName counts"))
+	for _, s := range files {
+		fmt.Fprintf(&b, "  %-40s  %d unique\n", s.Name(), len(s.StepNames))
+	}
+	if c != nil {
+		fmt.Fprintf(&b, "\n  OLD union: %d unique\n", len(c.OldStepNames))
+		fmt.Fprintf(&b, "  NEW union: %d unique\n", len(c.NewStepNames))
+		fmt.Fprintf(&b, "  in OLD but not in NEW (%d): %s\n", len(c.OnlyOld), formatList(c.OnlyOld))
+		fmt.Fprintf(&b, "  in NEW but not in OLD (%d): %s\n", len(c.OnlyNew), formatList(c.OnlyNew))
+	}
+
+	b.WriteString(header("Bug sample PCBAs (first ~5 per file)"))
+	for _, s := range files {
+		fmt.Fprintf(&b, "  %s:\n", s.Name())
+		if len(s.SampleMissingPCBA) > 0 {
+			fmt.Fprintf(&b, "    missing PCBA record: %s\n", formatList(s.SampleMissingPCBA))
+		}
+		if len(s.SampleMissingFinal) > 0 {
+			fmt.Fprintf(&b, "    missing Final record: %s\n", formatList(s.SampleMissingFinal))
+		}
+		if len(s.SampleOrphan) > 0 {
+			fmt.Fprintf(&b, "    total orphan (no station at all): %s\n", formatList(s.SampleOrphan))
+		}
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// pad right-aligns a table cell; the file column is wider than the counters.
+func pad(s string, column int) string {
+	if column == 0 {
+		return fmt.Sprintf("%36s", s)
+	}
+	return fmt.Sprintf("%15s", s)
+}
+
+// formatDistribution renders a version distribution as {v1: n, v2: m}.
+func formatDistribution(m map[string]int) string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	parts := make([]string, 0, len(keys))
+	for _, k := range keys {
+		parts = append(parts, fmt.Sprintf("%q: %d", k, m[k]))
+	}
+	return "{" + strings.Join(parts, ", ") + "}"
+}
+
+// formatList renders a list of strings as [a, b, c].
+func formatList(items []string) string {
+	quoted := make([]string, len(items))
+	for i, item := range items {
+		quoted[i] = fmt.Sprintf("%q", item)
+	}
+	return "[" + strings.Join(quoted, ", ") + "]"
+}
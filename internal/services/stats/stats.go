@@ -0,0 +1,297 @@
+/*
+Package stats computes per-file payload statistics of mesrestapi logs and
+compares two sets of logs (e.g. before and after a test tool upgrade).
+
+It replaces the former compare_old_new.py script. Classification uses the Go
+parser (parser.ExtractJson, parser.ClassifyBlock and
+parser.InferStationTypeFromSteps), so the numbers always agree with what the
+ingestion pipeline sees.
+
+For every file it reports:
+
+  - payload counts by kind (Download, PCBA/Final station records, PCBA/Final
+    step arrays, other stations, step arrays without a scan step);
+  - station records with an empty PCBANumber (Problem #3);
+  - devices with a step array but no station record of the same type
+    (Bug #1), split into missing PCBA record, missing Final record and
+    devices without any station record;
+  - devices whose station record and step array counts differ within a
+    type (Bug #2, retry asymmetry);
+  - devices with full PCBA and Final coverage;
+  - the TestToolVersion distribution per station type, the set of
+    TestStation values and the set of TestStepName values.
+*/
+package stats
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/NoroSaroyan/log-parser/internal/domain/models/dto"
+	"github.com/NoroSaroyan/log-parser/internal/services/parser"
+	"github.com/NoroSaroyan/log-parser/internal/services/pipeline"
+)
+
+// sampleSize is the number of example PCBAs kept per Bug #1 category.
+const sampleSize = 5
+
+// TypeCounts counts the payloads of a file by kind.
+type TypeCounts struct {
+	Download     int `json:"download"`
+	PCBAStation  int `json:"pcba_station"`
+	FinalStation int `json:"final_station"`
+	PCBASteps    int `json:"pcba_steps"`
+	FinalSteps   int `json:"final_steps"`
+	OtherStation int `json:"other_station"`
+	UnknownSteps int `json:"unknown_steps"`
+}
+
+// FileStats holds the statistics of one log file.
+type FileStats struct {
+	Path string `json:"path"`
+	// Era is "old" or "new" in a comparison and empty otherwise.
+	Era   string `json:"era,omitempty"`
+	Error string `json:"error,omitempty"`
+
+	TypeCounts        TypeCounts `json:"type_counts"`
+	StationsEmptyPCBA int        `json:"stations_empty_pcba"`
+	UniquePCBAs       int        `json:"unique_pcbas"`
+	FullCoverage      int        `json:"full_coverage"`
+
+	Bug1MissingPCBARecord   int `json:"bug1_missing_pcba_record"`
+	Bug1MissingFinalRecord  int `json:"bug1_missing_final_record"`
+	Bug1TotalOrphan         int `json:"bug1_total_orphan"`
+	Bug2RetryAsymmetryPCBA  int `json:"bug2_retry_asymmetry_pcba"`
+	Bug2RetryAsymmetryFinal int `json:"bug2_retry_asymmetry_final"`
+
+	// TestToolVersions maps station type to version to count.
+	TestToolVersions map[string]map[string]int `json:"testtool_versions"`
+	StepNames        []string                  `json:"step_names"`
+	StationValues    []string                  `json:"station_values"`
+	NoScanSteps      int                       `json:"noscan_steps"`
+
+	SampleMissingPCBA  []string `json:"bug1_sample_missing_pcba,omitempty"`
+	SampleMissingFinal []string `json:"bug1_sample_missing_final,omitempty"`
+	SampleOrphan       []string `json:"bug1_sample_orphan,omitempty"`
+}
+
+// Name returns the base name of the file.
+func (s FileStats) Name() string {
+	return filepath.Base(s.Path)
+}
+
+// AnalyzeFile reads a plain-text or gzip-compressed log and computes its statistics.
+func AnalyzeFile(path string) (FileStats, error) {
+	data, err := pipeline.ReadFile(path)
+	if err != nil {
+		return FileStats{Path: path, Error: err.Error()}, err
+	}
+	return Analyze(path, data), nil
+}
+
+// Analyze computes the statistics of the log content in data.
+func Analyze(path string, data []byte) FileStats {
+	s := FileStats{
+		Path:             path,
+		TestToolVersions: map[string]map[string]int{},
+	}
+
+	stationsByPCBA := map[string]map[string]int{} // pcba -> station type -> records
+	stepsByPCBA := map[string]map[string]int{}    // pcba -> station type -> step arrays
+	var pcbaOrder []string                        // first appearance, for stable samples
+	count := func(m map[string]map[string]int, pcba, stationType string) {
+		if _, ok := stationsByPCBA[pcba]; !ok {
+			if _, ok := stepsByPCBA[pcba]; !ok {
+				pcbaOrder = append(pcbaOrder, pcba)
+			}
+		}
+		if m[pcba] == nil {
+			m[pcba] = map[string]int{}
+		}
+		m[pcba][stationType]++
+	}
+	stepNames := map[string]bool{}
+	stationValues := map[string]bool{}
+
+	blocks, _ := parser.ExtractJson(string(data))
+	for _, block := range blocks {
+		item, err := parser.ClassifyBlock(block)
+		if err != nil {
+			// Objects with an unknown TestStation are still counted.
+			if ts := probeTestStation(block); ts != "" {
+				stationValues[ts] = true
+				s.TypeCounts.OtherStation++
+			}
+			continue
+		}
+
+		switch v := item.(type) {
+		case dto.DownloadInfoDTO:
+			stationValues[strings.TrimSpace(v.TestStation)] = true
+			s.TypeCounts.Download++
+
+		case dto.TestStationRecordDTO:
+			stationType := strings.TrimSpace(v.TestStation)
+			stationValues[stationType] = true
+			if stationType == "PCBA" {
+				s.TypeCounts.PCBAStation++
+			} else {
+				s.TypeCounts.FinalStation++
+			}
+			pcba := strings.TrimSpace(v.LogisticData.PCBANumber)
+			if pcba == "" {
+				// These pollute logistic_data but cannot take part in
+				// Bug #1 matching.
+				s.StationsEmptyPCBA++
+				continue
+			}
+			version := strings.TrimSpace(v.TestToolVersion)
+			if version == "" {
+				version = "<none>"
+			}
+			if s.TestToolVersions[stationType] == nil {
+				s.TestToolVersions[stationType] = map[string]int{}
+			}
+			s.TestToolVersions[stationType][version]++
+			count(stationsByPCBA, pcba, stationType)
+
+		case []dto.TestStepDTO:
+			for _, step := range v {
+				if step.TestStepName != "" {
+					stepNames[step.TestStepName] = true
+				}
+			}
+			stationType, pcba := parser.InferStationTypeFromSteps(v)
+			if stationType == "" || pcba == "" {
+				s.TypeCounts.UnknownSteps++
+				s.NoScanSteps++
+				continue
+			}
+			if stationType == "PCBA" {
+				s.TypeCounts.PCBASteps++
+			} else {
+				s.TypeCounts.FinalSteps++
+			}
+			count(stepsByPCBA, pcba, stationType)
+		}
+	}
+
+	var missingPCBA, missingFinal, orphan []string
+	for _, pcba := range pcbaOrder {
+		st, stp := stationsByPCBA[pcba], stepsByPCBA[pcba]
+		hasPCBAStation, hasFinalStation := st["PCBA"] > 0, st["Final"] > 0
+		hasPCBASteps, hasFinalSteps := stp["PCBA"] > 0, stp["Final"] > 0
+
+		switch {
+		case (hasPCBASteps || hasFinalSteps) && !hasPCBAStation && !hasFinalStation:
+			orphan = append(orphan, pcba)
+		default:
+			if hasPCBASteps && !hasPCBAStation {
+				missingPCBA = append(missingPCBA, pcba)
+			}
+			if hasFinalSteps && !hasFinalStation {
+				missingFinal = append(missingFinal, pcba)
+			}
+		}
+
+		for _, t := range []string{"PCBA", "Final"} {
+			if st[t] > 0 && stp[t] > 0 && st[t] != stp[t] {
+				if t == "PCBA" {
+					s.Bug2RetryAsymmetryPCBA++
+				} else {
+					s.Bug2RetryAsymmetryFinal++
+				}
+			}
+		}
+
+		if hasPCBAStation && hasFinalStation && hasPCBASteps && hasFinalSteps {
+			s.FullCoverage++
+		}
+	}
+
+	s.UniquePCBAs = len(pcbaOrder)
+	s.Bug1MissingPCBARecord = len(missingPCBA)
+	s.Bug1MissingFinalRecord = len(missingFinal)
+	s.Bug1TotalOrphan = len(orphan)
+	s.SampleMissingPCBA = sample(missingPCBA)
+	s.SampleMissingFinal = sample(missingFinal)
+	s.SampleOrphan = sample(orphan)
+	s.StepNames = sortedKeys(stepNames)
+	s.StationValues = sortedKeys(stationValues)
+	return s
+}
+
+// Comparison is the result of comparing two sets of log files.
+type Comparison struct {
+	Files []FileStats `json:"files"`
+	// OldStepNames and NewStepNames are the unions of step names per era.
+	OldStepNames []string `json:"old_step_names"`
+	NewStepNames []string `json:"new_step_names"`
+	OnlyOld      []string `json:"only_old"`
+	OnlyNew      []string `json:"only_new"`
+}
+
+// Compare marks the statistics with their era and computes the step name
+// differences between both eras.
+func Compare(old, new []FileStats) Comparison {
+	c := Comparison{}
+	oldNames, newNames := map[string]bool{}, map[string]bool{}
+	for _, s := range old {
+		s.Era = "old"
+		c.Files = append(c.Files, s)
+		for _, n := range s.StepNames {
+			oldNames[n] = true
+		}
+	}
+	for _, s := range new {
+		s.Era = "new"
+		c.Files = append(c.Files, s)
+		for _, n := range s.StepNames {
+			newNames[n] = true
+		}
+	}
+
+	c.OldStepNames = sortedKeys(oldNames)
+	c.NewStepNames = sortedKeys(newNames)
+	c.OnlyOld, c.OnlyNew = []string{}, []string{}
+	for _, n := range c.OldStepNames {
+		if !newNames[n] {
+			c.OnlyOld = append(c.OnlyOld, n)
+		}
+	}
+	for _, n := range c.NewStepNames {
+		if !oldNames[n] {
+			c.OnlyNew = append(c.OnlyNew, n)
+		}
+	}
+	return c
+}
+
+// probeTestStation returns the TestStation value of a JSON object, if any.
+func probeTestStation(block string) string {
+	var probe struct {
+		TestStation string `json:"TestStation"`
+	}
+	if json.Unmarshal([]byte(strings.TrimSpace(block)), &probe) != nil {
+		return ""
+	}
+	return strings.TrimSpace(probe.TestStation)
+}
+
+func sample(pcbas []string) []string {
+	if len(pcbas) > sampleSize {
+		return pcbas[:sampleSize]
+	}
+	return pcbas
+}
+
+func sortedKeys(m map[string]bool) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -19,6 +19,7 @@ InsertTestStationRecord:
 - Accepts a TestStationRecordDTO and a logisticDataID foreign key.
 - Cleans string fields for uniformity.
 - Converts DTO to DB model, sets the logistic data ID, and inserts into the database.
+- Splits ErrorCodes into normalized codes, which the repository links to the record in the same transaction.
 - Returns the newly inserted record's ID or an error if insertion fails or returns invalid ID.
 
 GetByPCBANumber:
@@ -35,7 +36,10 @@ GetDbObjectsByPCBANumber:
 GetAllPCBANumbers:
 - Fetches all distinct PCBA numbers from the repository.
 - Intended for listing or validation use cases.
-- The `stationType` parameter is currently unused but reserved for future filtering support.
+- Only PCBA numbers with a record of `stationType` are returned; an empty `stationType` returns all of them.
+
+ForEachPCBANumber:
+- Calls a callback with the PCBA numbers of GetAllPCBANumbers as they are read, for responses streamed record by record.
 
 Overall, this service encapsulates business logic around test station records with a focus on data integrity,
 cleanliness, and clear separation of concerns between domain, service, and persistence layers.
@@ -48,7 +52,9 @@ import (
 	"github.com/NoroSaroyan/log-parser/internal/domain/models/db"
 	"github.com/NoroSaroyan/log-parser/internal/domain/models/dto"
 	"github.com/NoroSaroyan/log-parser/internal/domain/repositories"
+	"github.com/NoroSaroyan/log-parser/internal/infrastructure/tracing"
 	"github.com/NoroSaroyan/log-parser/internal/services/converter/teststation"
+	"github.com/NoroSaroyan/log-parser/internal/services/errorcode"
 	"strings"
 )
 
@@ -57,6 +63,7 @@ type TestStationService interface {
 	GetByPCBANumber(ctx context.Context, pcbaNumber string) ([]dto.TestStationRecordDTO, error)
 	GetDbObjectsByPCBANumber(ctx context.Context, pcbaNumber string) ([]*db.TestStationRecordDB, error)
 	GetAllPCBANumbers(ctx context.Context, stationType string) ([]string, error)
+	ForEachPCBANumber(ctx context.Context, stationType string, fn func(pcba string) error) error
 }
 
 // testStationService is the concrete implementation of TestStationService.
@@ -72,8 +79,12 @@ func NewTestStationService(repo repositories.TestStationRecordRepository) TestSt
 // InsertTestStationRecord inserts a TestStationRecordDTO linked to logisticDataID into the database.
 //
 // It trims whitespace from relevant string fields, converts the DTO to a DB model,
-// and uses the repository to persist it. Returns the new record's ID or an error.
+// splits its error codes (see the errorcode package) and uses the repository to
+// persist both. Returns the new record's ID or an error.
 func (s *testStationService) InsertTestStationRecord(ctx context.Context, data dto.TestStationRecordDTO, logisticDataID int) (int, error) {
+	ctx, span := tracing.Start(ctx, "teststation.InsertTestStationRecord")
+	defer span.End()
+
 	data.PartNumber = strings.TrimSpace(data.PartNumber)
 	data.TestStation = strings.TrimSpace(data.TestStation)
 	data.EntityType = strings.TrimSpace(data.EntityType)
@@ -84,6 +95,7 @@ func (s *testStationService) InsertTestStationRecord(ctx context.Context, data d
 
 	dbModel := teststation.ConvertToDB(data)
 	dbModel.LogisticDataID = logisticDataID
+	dbModel.Codes = errorcode.Split(dbModel.ErrorCodes)
 
 	if err := s.repo.Insert(ctx, &dbModel); err != nil {
 		return 0, fmt.Errorf("failed to insert TestStationRecord: %w", err)
@@ -101,6 +113,9 @@ func (s *testStationService) InsertTestStationRecord(ctx context.Context, data d
 // Converts DB models to DTOs before returning.
 // Returns an error if the repository query fails.
 func (s *testStationService) GetByPCBANumber(ctx context.Context, pcbaNumber string) ([]dto.TestStationRecordDTO, error) {
+	ctx, span := tracing.Start(ctx, "teststation.GetByPCBANumber")
+	defer span.End()
+
 	pcbaNumber = strings.TrimSpace(pcbaNumber)
 
 	dbRecords, err := s.repo.GetByPCBANumber(ctx, pcbaNumber)
@@ -121,6 +136,9 @@ func (s *testStationService) GetByPCBANumber(ctx context.Context, pcbaNumber str
 // Validates the PCBA number is not empty.
 // Returns an error if no records are found or if the query fails.
 func (s *testStationService) GetDbObjectsByPCBANumber(ctx context.Context, pcbaNumber string) ([]*db.TestStationRecordDB, error) {
+	ctx, span := tracing.Start(ctx, "teststation.GetDbObjectsByPCBANumber")
+	defer span.End()
+
 	pcbaNumber = strings.TrimSpace(pcbaNumber)
 	if pcbaNumber == "" {
 		return nil, fmt.Errorf("pcbaNumber cannot be empty")
@@ -140,7 +158,19 @@ func (s *testStationService) GetDbObjectsByPCBANumber(ctx context.Context, pcbaN
 
 // GetAllPCBANumbers returns all distinct PCBA numbers stored in the repository.
 //
-// The stationType parameter is currently unused but reserved for future enhancements such as filtering by test station type.
+// Only PCBA numbers with a record of stationType are returned; an empty stationType returns all of them.
 func (s *testStationService) GetAllPCBANumbers(ctx context.Context, stationType string) ([]string, error) {
-	return s.repo.GetAllPCBANumbers(ctx)
+	ctx, span := tracing.Start(ctx, "teststation.GetAllPCBANumbers")
+	defer span.End()
+
+	return s.repo.GetAllPCBANumbers(ctx, strings.TrimSpace(stationType))
+}
+
+// ForEachPCBANumber calls fn with every PCBA number GetAllPCBANumbers returns,
+// as the repository reads them. It stops at, and returns, the first error of fn.
+func (s *testStationService) ForEachPCBANumber(ctx context.Context, stationType string, fn func(pcba string) error) error {
+	ctx, span := tracing.Start(ctx, "teststation.ForEachPCBANumber")
+	defer span.End()
+
+	return s.repo.ForEachPCBANumber(ctx, strings.TrimSpace(stationType), fn)
 }
@@ -9,6 +9,11 @@ The TestStepService interface defines key business operations:
 Implementation notes:
   - The service sanitizes string fields of each test step DTO before processing,
     ensuring consistent and clean data input.
+  - Each step is re-evaluated against its threshold (see the threshold package); the
+    recomputed verdict and a mismatch flag are stored with the step.
+  - Numeric measured values are also stored as numbers, next to the raw text.
+  - Error codes are split and normalized (see the errorcode package) and linked
+    to the step in the same transaction.
   - Conversion between DTO and DB models is handled by the dedicated converter package.
   - Batch insertion is performed via the repository to optimize database operations.
   - Retrieval operations convert DB models back into DTOs for external use.
@@ -18,6 +23,9 @@ Methods:
 InsertTestSteps:
 - Accepts a slice of TestStepDTOs and the parent TestStationRecordID.
 - Trims whitespace from all relevant string fields including nested measured values.
+- Recomputes the verdict of each step from its threshold and measured value.
+- Parses the measured value as a number when possible.
+- Splits the error code field into normalized error codes.
 - Converts each DTO to its DB representation and aggregates them.
 - Delegates batch insertion to the repository.
 - Returns a wrapped error if insertion fails.
@@ -38,7 +46,10 @@ import (
 	"github.com/NoroSaroyan/log-parser/internal/domain/models/db"
 	"github.com/NoroSaroyan/log-parser/internal/domain/models/dto"
 	"github.com/NoroSaroyan/log-parser/internal/domain/repositories"
+	"github.com/NoroSaroyan/log-parser/internal/infrastructure/tracing"
 	"github.com/NoroSaroyan/log-parser/internal/services/converter/teststep"
+	"github.com/NoroSaroyan/log-parser/internal/services/errorcode"
+	"github.com/NoroSaroyan/log-parser/internal/services/threshold"
 	"strings"
 )
 
@@ -56,6 +67,9 @@ func NewTestStepService(repo repositories.TestStepRepository) TestStepService {
 }
 
 func (s *testStepService) InsertTestSteps(ctx context.Context, steps []dto.TestStepDTO, testStationRecordID int) error {
+	ctx, span := tracing.Start(ctx, "teststep.InsertTestSteps")
+	defer span.End()
+
 	var dbModels []*db.TestStepDB
 	for _, step := range steps {
 		step.TestStepName = strings.TrimSpace(step.TestStepName)
@@ -63,8 +77,13 @@ func (s *testStepService) InsertTestSteps(ctx context.Context, steps []dto.TestS
 		step.TestStepErrorCode = strings.TrimSpace(step.TestStepErrorCode)
 		step.TestThresholdValue = strings.TrimSpace(step.TestThresholdValue)
 		step.TestMeasuredValue = strings.TrimSpace(step.GetMeasuredValueString())
+		step.RecomputedResult, step.VerdictMismatch = threshold.Recompute(step)
 
 		converted := teststep.ConvertToDB(step, testStationRecordID)
+		if v, ok := threshold.Numeric(step); ok {
+			converted.MeasuredNumeric = &v
+		}
+		converted.Codes = errorcode.Split(converted.TestStepErrorCode)
 		dbModels = append(dbModels, &converted)
 	}
 
@@ -76,6 +95,9 @@ func (s *testStepService) InsertTestSteps(ctx context.Context, steps []dto.TestS
 }
 
 func (s *testStepService) GetByTestStationRecordID(ctx context.Context, testStationRecordID int) ([]dto.TestStepDTO, error) {
+	ctx, span := tracing.Start(ctx, "teststep.GetByTestStationRecordID")
+	defer span.End()
+
 	dbSteps, err := s.repo.GetByTestStationRecordID(ctx, testStationRecordID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get TestSteps by TestStationRecordID: %w", err)
@@ -0,0 +1,286 @@
+/*
+Package threshold re-evaluates test steps against their TestThresholdValue.
+
+The testers log TestThresholdValue as free text and decide TestStepResult
+themselves. This package parses the threshold, recomputes the verdict from
+TestMeasuredValue and flags steps where the logged result disagrees.
+
+Supported threshold forms:
+
+  - ranges: "[min,max]" with "[" / "]" for inclusive and "(" / ")" for
+    exclusive bounds; either bound may be empty ("[,5]", "(0,]"). "min~max"
+    is an inclusive range as well;
+  - comparisons: ">x", ">=x", "<x", "<=x", "==x" and "!=x";
+  - regular expressions: "/pattern/", matched against the whole measured value;
+  - anything else is an exact match: numerically when both sides are numbers
+    ("5" equals "5.0"), otherwise as case-insensitive text ("OK" equals "ok").
+
+An empty threshold or a placeholder such as "N/A" cannot be evaluated;
+neither can a numeric threshold against a non-numeric measured value. Such
+steps get an empty recomputed result and are never flagged.
+*/
+package threshold
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/NoroSaroyan/log-parser/internal/domain/models/dto"
+)
+
+// Recomputed verdicts.
+const (
+	ResultPass = "PASS"
+	ResultFail = "FAIL"
+)
+
+// Threshold kinds.
+const (
+	KindNone       = "none"
+	KindRange      = "range"
+	KindComparison = "comparison"
+	KindRegex      = "regex"
+	KindExact      = "exact"
+)
+
+// Threshold is a parsed TestThresholdValue.
+type Threshold struct {
+	Kind string
+	Raw  string
+
+	// Range bounds; a nil bound is unbounded.
+	Min, Max                   *float64
+	MinInclusive, MaxInclusive bool
+
+	// Comparison operator and operand.
+	Op    string
+	Value float64
+
+	Pattern *regexp.Regexp
+}
+
+// notApplicable are placeholder thresholds of steps without a limit.
+var notApplicable = map[string]bool{"N/A": true, "NA": true, "-": true, "NULL": true}
+
+var comparisonOps = []string{">=", "<=", "==", "!=", ">", "<"}
+
+// Parse parses a TestThresholdValue. It returns an error for thresholds that
+// look like a range, comparison or regex but are malformed.
+func Parse(raw string) (Threshold, error) {
+	s := strings.TrimSpace(raw)
+	t := Threshold{Raw: raw}
+
+	switch {
+	case s == "" || notApplicable[strings.ToUpper(s)]:
+		t.Kind = KindNone
+		return t, nil
+
+	case len(s) >= 2 && (s[0] == '[' || s[0] == '(') && (s[len(s)-1] == ']' || s[len(s)-1] == ')') && strings.Contains(s, ","):
+		parts := strings.Split(s[1:len(s)-1], ",")
+		if len(parts) != 2 {
+			return t, fmt.Errorf("range %q must have exactly two bounds", raw)
+		}
+		return parseRange(t, parts[0], parts[1], s[0] == '[', s[len(s)-1] == ']')
+
+	case len(s) >= 2 && s[0] == '/' && s[len(s)-1] == '/':
+		re, err := regexp.Compile("^(?:" + s[1:len(s)-1] + ")$")
+		if err != nil {
+			return t, fmt.Errorf("invalid regex threshold %q: %w", raw, err)
+		}
+		t.Kind = KindRegex
+		t.Pattern = re
+		return t, nil
+	}
+
+	for _, op := range comparisonOps {
+		if strings.HasPrefix(s, op) {
+			v, err := parseNumber(s[len(op):])
+			if err != nil {
+				return t, fmt.Errorf("invalid comparison threshold %q: %w", raw, err)
+			}
+			t.Kind = KindComparison
+			t.Op = op
+			t.Value = v
+			return t, nil
+		}
+	}
+
+	if lo, hi, ok := strings.Cut(s, "~"); ok {
+		return parseRange(t, lo, hi, true, true)
+	}
+
+	t.Kind = KindExact
+	return t, nil
+}
+
+func parseRange(t Threshold, lo, hi string, minInclusive, maxInclusive bool) (Threshold, error) {
+	t.Kind = KindRange
+	t.MinInclusive, t.MaxInclusive = minInclusive, maxInclusive
+	if strings.TrimSpace(lo) != "" {
+		v, err := parseNumber(lo)
+		if err != nil {
+			return t, fmt.Errorf("invalid range minimum in %q: %w", t.Raw, err)
+		}
+		t.Min = &v
+	}
+	if strings.TrimSpace(hi) != "" {
+		v, err := parseNumber(hi)
+		if err != nil {
+			return t, fmt.Errorf("invalid range maximum in %q: %w", t.Raw, err)
+		}
+		t.Max = &v
+	}
+	if t.Min != nil && t.Max != nil && *t.Min > *t.Max {
+		return t, fmt.Errorf("range %q has minimum above maximum", t.Raw)
+	}
+	return t, nil
+}
+
+// Evaluate returns ResultPass or ResultFail for measured, or "" when the
+// threshold cannot be evaluated for it.
+func (t Threshold) Evaluate(measured string) string {
+	m := strings.TrimSpace(measured)
+	switch t.Kind {
+	case KindRange:
+		v, err := parseNumber(m)
+		if err != nil {
+			return ""
+		}
+		ok := true
+		if t.Min != nil {
+			ok = ok && (v > *t.Min || (t.MinInclusive && v == *t.Min))
+		}
+		if t.Max != nil {
+			ok = ok && (v < *t.Max || (t.MaxInclusive && v == *t.Max))
+		}
+		return verdict(ok)
+
+	case KindComparison:
+		v, err := parseNumber(m)
+		if err != nil {
+			return ""
+		}
+		switch t.Op {
+		case ">":
+			return verdict(v > t.Value)
+		case ">=":
+			return verdict(v >= t.Value)
+		case "<":
+			return verdict(v < t.Value)
+		case "<=":
+			return verdict(v <= t.Value)
+		case "==":
+			return verdict(v == t.Value)
+		default:
+			return verdict(v != t.Value)
+		}
+
+	case KindRegex:
+		return verdict(t.Pattern.MatchString(m))
+
+	case KindExact:
+		want := strings.TrimSpace(t.Raw)
+		if a, err := parseNumber(m); err == nil {
+			if b, err := parseNumber(want); err == nil {
+				return verdict(a == b)
+			}
+		}
+		return verdict(strings.EqualFold(m, want))
+	}
+	return ""
+}
+
+// Recompute evaluates step against its threshold. It returns the recomputed
+// verdict ("" if it cannot be evaluated) and whether it disagrees with the
+// logged TestStepResult.
+func Recompute(step dto.TestStepDTO) (string, bool) {
+	t, err := Parse(step.TestThresholdValue)
+	if err != nil {
+		return "", false
+	}
+	recomputed := t.Evaluate(step.GetMeasuredValueString())
+	if recomputed == "" {
+		return "", false
+	}
+	logged := strings.ToUpper(strings.TrimSpace(step.TestStepResult))
+	if logged != ResultPass && logged != ResultFail {
+		return recomputed, false
+	}
+	return recomputed, logged != recomputed
+}
+
+// Limits returns the numeric specification limits of a range or comparison
+// threshold: the lower limit (LSL) and the upper limit (USL), nil when
+// unbounded. Other thresholds, and "==" / "!=", have no limits.
+func (t Threshold) Limits() (lower, upper *float64) {
+	switch t.Kind {
+	case KindRange:
+		return t.Min, t.Max
+	case KindComparison:
+		v := t.Value
+		switch t.Op {
+		case ">", ">=":
+			return &v, nil
+		case "<", "<=":
+			return nil, &v
+		}
+	}
+	return nil, nil
+}
+
+// Numeric returns the measured value of a step as a number when the step
+// measures one: the value is a number and the threshold states numeric
+// limits (a range, a comparison or a number). Steps checked against text or
+// a pattern, or not checked at all, log identifiers such as the IMEI or the
+// scanned PCBA number, which are not measurements even when all digits.
+// It is the numeric measured value stored alongside the raw text.
+func Numeric(step dto.TestStepDTO) (float64, bool) {
+	t, err := Parse(step.TestThresholdValue)
+	if err != nil {
+		return 0, false
+	}
+	switch t.Kind {
+	case KindRange, KindComparison:
+	case KindExact:
+		if _, err := parseNumber(t.Raw); err != nil {
+			return 0, false
+		}
+	default:
+		return 0, false
+	}
+	v, err := parseNumber(step.GetMeasuredValueString())
+	return v, err == nil
+}
+
+// Annotate sets RecomputedResult and VerdictMismatch of every step.
+func Annotate(steps []dto.TestStepDTO) {
+	for i := range steps {
+		steps[i].RecomputedResult, steps[i].VerdictMismatch = Recompute(steps[i])
+	}
+}
+
+func verdict(ok bool) string {
+	if ok {
+		return ResultPass
+	}
+	return ResultFail
+}
+
+// number is the grammar of numbers: an optionally signed decimal with an
+// optional exponent of at most two digits, which keeps every number finite.
+// Migration 009 backfills measured_numeric with the same expression, so "Inf",
+// "NaN", hex floats and digit separators are not numbers in either place.
+var number = regexp.MustCompile(`^[-+]?([0-9]+\.?[0-9]*|\.[0-9]+)([eE][-+]?[0-9]{1,2})?$`)
+
+// maxNumberLength is the length from which migration 009 leaves a value
+// NULL rather than cast it.
+const maxNumberLength = 64
+
+func parseNumber(s string) (float64, error) {
+	if len(s) >= maxNumberLength || !number.MatchString(strings.TrimSpace(s)) {
+		return 0, fmt.Errorf("%q is not a number", s)
+	}
+	return strconv.ParseFloat(strings.TrimSpace(s), 64)
+}
@@ -0,0 +1,45 @@
+package trace
+
+import (
+	"fmt"
+	"io"
+	"text/tabwriter"
+)
+
+// WriteText renders a trace as a timeline, the session pairing and the verdict.
+func WriteText(w io.Writer, tr Trace) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+
+	fmt.Fprintf(tw, "PCBA %s (%d file(s) scanned)\n\n", tr.PCBA, len(tr.Files))
+
+	fmt.Fprintln(tw, "Timeline")
+	if len(tr.Events) == 0 {
+		fmt.Fprintln(tw, "  (no payloads mention this PCBA)")
+	}
+	for _, e := range tr.Events {
+		fmt.Fprintf(tw, "  %s\t%s\t%s\t%s\t%s\n", e.Time, e.Citation(), e.Kind, e.StationType, e.Detail)
+	}
+
+	if len(tr.Sessions) > 0 {
+		fmt.Fprintln(tw, "\nSessions")
+		for _, s := range tr.Sessions {
+			fmt.Fprintf(tw, "  %s #%d\tstation: %s\tsteps: %s\n", s.StationType, s.Index, cite(s.Station), cite(s.Steps))
+		}
+	}
+
+	fmt.Fprintf(tw, "\nVerdict: %s\n", tr.Verdict)
+	for _, f := range tr.Findings {
+		fmt.Fprintf(tw, "  - %s\n", f)
+	}
+	return tw.Flush()
+}
+
+func cite(e *Event) string {
+	if e == nil {
+		return "<missing>"
+	}
+	if e.Kind == KindStationInsert {
+		return e.Citation() + " (struct dump)"
+	}
+	return e.Citation()
+}
@@ -0,0 +1,309 @@
+/*
+Package trace follows a single device (PCBA number) through raw log files.
+
+It collects every payload that belongs to the PCBA, in log order and with
+file:line citations:
+
+  - Download payloads (TcuPCBANumber);
+  - StationInformation JSON payloads (LogisticData.PCBANumber);
+  - "Inserting StationInformation:" struct dumps, written by the MES service
+    when it actually inserts the station record;
+  - test step arrays whose scan step carries the PCBA.
+
+Step arrays are paired with station records of the same type in order of
+appearance (FIFO, as in processor.PairSessions). When a file only contains
+the struct dump of a station record, the dump stands in for the record.
+
+The resulting Trace ends with a verdict:
+
+  - complete: every step array has a station record of its type;
+  - bug1_missing_record: step arrays of a type without any station record of
+    that type, while a record of another type exists (Bug #1);
+  - bug2_retry_asymmetry: both exist for a type, but their counts differ (Bug #2);
+  - orphan: step arrays without any station record at all;
+  - not_found: the PCBA does not appear in the files.
+*/
+package trace
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/NoroSaroyan/log-parser/internal/domain/models/dto"
+	"github.com/NoroSaroyan/log-parser/internal/services/parser"
+	"github.com/NoroSaroyan/log-parser/internal/services/pipeline"
+)
+
+// Event kinds.
+const (
+	KindDownload      = "download"
+	KindStation       = "station"
+	KindStationInsert = "station_insert"
+	KindSteps         = "steps"
+)
+
+// Verdicts.
+const (
+	VerdictComplete      = "complete"
+	VerdictBug1Missing   = "bug1_missing_record"
+	VerdictBug2Asymmetry = "bug2_retry_asymmetry"
+	VerdictOrphan        = "orphan"
+	VerdictNotFound      = "not_found"
+)
+
+const (
+	insertStationMarker   = "Inserting StationInformation:"
+	syslogTimestampLength = len("Apr 10 12:09:35")
+)
+
+// Event is one log payload that mentions the traced PCBA.
+type Event struct {
+	File string `json:"file"`
+	Line int    `json:"line"`
+	// Time is the syslog timestamp of the line, e.g. "Apr 10 12:09:35".
+	Time        string `json:"time"`
+	Kind        string `json:"kind"`
+	StationType string `json:"station_type,omitempty"`
+	Detail      string `json:"detail"`
+}
+
+// Citation renders the event position as file:line.
+func (e Event) Citation() string {
+	return fmt.Sprintf("%s:%d", filepath.Base(e.File), e.Line)
+}
+
+// Session is a station record paired with its step array. Either side is nil
+// when missing.
+type Session struct {
+	StationType string `json:"station_type"`
+	Index       int    `json:"index"`
+	Station     *Event `json:"station,omitempty"`
+	Steps       *Event `json:"steps,omitempty"`
+}
+
+// Trace is the result of tracing one PCBA.
+type Trace struct {
+	PCBA     string    `json:"pcba"`
+	Files    []string  `json:"files"`
+	Events   []Event   `json:"events"`
+	Sessions []Session `json:"sessions"`
+	Verdict  string    `json:"verdict"`
+	Findings []string  `json:"findings"`
+}
+
+// Tracer accumulates the events of one PCBA over several files. Files must be
+// added in chronological order (rotated logs sort by name).
+type Tracer struct {
+	pcba  string
+	trace Trace
+}
+
+// NewTracer creates a Tracer for pcba.
+func NewTracer(pcba string) *Tracer {
+	pcba = strings.TrimSpace(pcba)
+	return &Tracer{pcba: pcba, trace: Trace{PCBA: pcba, Events: []Event{}}}
+}
+
+// AddFile scans a plain-text or gzip-compressed log file.
+func (t *Tracer) AddFile(path string) error {
+	data, err := pipeline.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	t.Add(path, data)
+	return nil
+}
+
+// Add scans the log content in data; path is used for citations.
+func (t *Tracer) Add(path string, data []byte) {
+	t.trace.Files = append(t.trace.Files, path)
+
+	scanner := parser.NewBlockScanner()
+	startTimes := map[int]string{} // start line -> timestamp of blocks in progress
+	for i, line := range strings.Split(string(data), "\n") {
+		lineNo := i + 1
+		if strings.Contains(line, " Data  {") || strings.Contains(line, " Data  [") {
+			startTimes[lineNo] = syslogTime(line)
+		}
+		if strings.Contains(line, insertStationMarker) && strings.Contains(line, t.pcba) {
+			t.trace.Events = append(t.trace.Events, insertEvent(path, lineNo, line))
+		}
+		if block, ok := scanner.Feed(line); ok {
+			t.addBlock(path, block, startTimes[block.StartLine])
+			delete(startTimes, block.StartLine)
+		}
+	}
+	if block, ok := scanner.Flush(); ok {
+		t.addBlock(path, block, startTimes[block.StartLine])
+	}
+}
+
+// addBlock records the block if it is a payload of the traced PCBA.
+func (t *Tracer) addBlock(path string, block parser.Block, ts string) {
+	if !strings.Contains(block.Text, t.pcba) {
+		return
+	}
+	item, err := parser.ClassifyBlock(block.Text)
+	if err != nil {
+		return
+	}
+
+	e := Event{File: path, Line: block.StartLine, Time: ts}
+	switch v := item.(type) {
+	case dto.DownloadInfoDTO:
+		if strings.TrimSpace(v.TcuPCBANumber) != t.pcba {
+			return
+		}
+		e.Kind = KindDownload
+		e.Detail = fmt.Sprintf("state=%s finished=%s", v.TcuEntityFlashState, v.DownloadFinishedTime)
+
+	case dto.TestStationRecordDTO:
+		if strings.TrimSpace(v.LogisticData.PCBANumber) != t.pcba {
+			return
+		}
+		e.Kind = KindStation
+		e.StationType = strings.TrimSpace(v.TestStation)
+		e.Detail = fmt.Sprintf("finished=%s passed=%t", v.TestFinishedTime, v.IsAllPassed)
+		if v.ErrorCodes != "" {
+			e.Detail += " errors=" + v.ErrorCodes
+		}
+		if v.TestToolVersion != "" {
+			e.Detail += " tool=" + v.TestToolVersion
+		}
+
+	case []dto.TestStepDTO:
+		stationType, pcba := parser.InferStationTypeFromSteps(v)
+		if pcba != t.pcba {
+			return
+		}
+		failed := 0
+		for _, s := range v {
+			if s.TestStepResult != "" && !strings.EqualFold(s.TestStepResult, "PASS") {
+				failed++
+			}
+		}
+		e.Kind = KindSteps
+		e.StationType = stationType
+		e.Detail = fmt.Sprintf("%d steps, %d not passed", len(v), failed)
+	}
+	t.trace.Events = append(t.trace.Events, e)
+}
+
+// insertEvent builds the event for an "Inserting StationInformation:" struct
+// dump such as "{0 703003734AA Final Tester_01 ... H8444A11100T32343298 ...}".
+func insertEvent(path string, lineNo int, line string) Event {
+	e := Event{File: path, Line: lineNo, Time: syslogTime(line), Kind: KindStationInsert}
+	dump := line[strings.Index(line, insertStationMarker)+len(insertStationMarker):]
+	for _, field := range strings.Fields(strings.Trim(strings.TrimSpace(dump), "{}")) {
+		if field == "PCBA" || field == "Final" {
+			e.StationType = field
+			break
+		}
+	}
+	e.Detail = "struct dump"
+	return e
+}
+
+// syslogTime returns the syslog timestamp at the start of a line.
+func syslogTime(line string) string {
+	if len(line) < syslogTimestampLength {
+		return ""
+	}
+	return line[:syslogTimestampLength]
+}
+
+// Result pairs the sessions, computes the verdict and returns the trace.
+func (t *Tracer) Result() Trace {
+	tr := t.trace
+	tr.Sessions = []Session{}
+	tr.Findings = []string{}
+	if len(tr.Events) == 0 {
+		tr.Verdict = VerdictNotFound
+		return tr
+	}
+
+	stations := map[string][]*Event{}
+	inserts := map[string][]*Event{}
+	steps := map[string][]*Event{}
+	var types []string
+	seen := map[string]bool{}
+	for i := range tr.Events {
+		e := &tr.Events[i]
+		if e.StationType == "" && e.Kind != KindDownload {
+			e.StationType = "unknown"
+		}
+		switch e.Kind {
+		case KindStation:
+			stations[e.StationType] = append(stations[e.StationType], e)
+		case KindStationInsert:
+			inserts[e.StationType] = append(inserts[e.StationType], e)
+		case KindSteps:
+			steps[e.StationType] = append(steps[e.StationType], e)
+		default:
+			continue
+		}
+		if !seen[e.StationType] {
+			seen[e.StationType] = true
+			types = append(types, e.StationType)
+		}
+	}
+	sort.Strings(types)
+
+	anyStation := false
+	for _, st := range types {
+		records := stations[st]
+		if len(records) == 0 {
+			records = inserts[st]
+		}
+		anyStation = anyStation || len(records) > 0
+		n := len(records)
+		if len(steps[st]) > n {
+			n = len(steps[st])
+		}
+		for i := 0; i < n; i++ {
+			s := Session{StationType: st, Index: i + 1}
+			if i < len(records) {
+				ev := *records[i]
+				s.Station = &ev
+			}
+			if i < len(steps[st]) {
+				ev := *steps[st][i]
+				s.Steps = &ev
+			}
+			tr.Sessions = append(tr.Sessions, s)
+		}
+	}
+
+	bug1, bug2 := false, false
+	for _, st := range types {
+		records := len(stations[st])
+		if records == 0 {
+			records = len(inserts[st])
+		}
+		arrays := len(steps[st])
+		switch {
+		case arrays > 0 && records == 0:
+			bug1 = true
+			tr.Findings = append(tr.Findings, fmt.Sprintf("%s: %d step array(s) without a %s station record", st, arrays, st))
+		case arrays > 0 && records != arrays:
+			bug2 = true
+			tr.Findings = append(tr.Findings, fmt.Sprintf("%s: %d station record(s) vs %d step array(s)", st, records, arrays))
+		case records > 0 && arrays == 0:
+			tr.Findings = append(tr.Findings, fmt.Sprintf("%s: %d station record(s) without step arrays", st, records))
+		}
+	}
+
+	switch {
+	case len(steps) > 0 && !anyStation:
+		tr.Verdict = VerdictOrphan
+	case bug1:
+		tr.Verdict = VerdictBug1Missing
+	case bug2:
+		tr.Verdict = VerdictBug2Asymmetry
+	default:
+		tr.Verdict = VerdictComplete
+	}
+	return tr
+}
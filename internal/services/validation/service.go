@@ -0,0 +1,119 @@
+/*
+Package validation provides the service layer that persists the outcome of the
+validation step (processor.Validator) running between grouping and dispatch.
+
+The ValidationService interface defines:
+- Saving the findings and the summary of one validated file,
+- Retrieving the findings recorded for a PCBA number,
+- Retrieving the summaries recorded for a source file.
+
+Implementation notes:
+  - Findings are stored per record: PCBA number, record type (download,
+    station or steps) and the index of the record within its group.
+  - Findings of rejected groups are stored as well; they are the only trace of
+    those groups in the database.
+  - The summary keeps the counters per severity; counters per rule can be
+    derived from the findings.
+*/
+package validation
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/NoroSaroyan/log-parser/internal/domain/models/db"
+	"github.com/NoroSaroyan/log-parser/internal/domain/repositories"
+	"github.com/NoroSaroyan/log-parser/internal/infrastructure/tracing"
+	"github.com/NoroSaroyan/log-parser/internal/services/processor"
+)
+
+type ValidationService interface {
+	SaveReport(ctx context.Context, report processor.ValidationReport) error
+	GetFindingsByPCBANumber(ctx context.Context, pcba string) ([]processor.Finding, error)
+	GetSummariesBySourceFile(ctx context.Context, sourceFile string) ([]*db.ValidationSummaryDB, error)
+}
+
+type validationService struct {
+	repo repositories.ValidationRepository
+}
+
+func NewValidationService(repo repositories.ValidationRepository) ValidationService {
+	return &validationService{repo: repo}
+}
+
+func (s *validationService) SaveReport(ctx context.Context, report processor.ValidationReport) error {
+	ctx, span := tracing.Start(ctx, "validation.SaveReport")
+	defer span.End()
+
+	file := report.Summary.File
+
+	findings := make([]*db.ValidationFindingDB, 0, len(report.Findings))
+	for _, f := range report.Findings {
+		findings = append(findings, &db.ValidationFindingDB{
+			SourceFile:  file,
+			PCBANumber:  f.PCBA,
+			RecordType:  f.RecordType,
+			RecordIndex: f.RecordIndex,
+			StationType: f.StationType,
+			Rule:        f.Rule,
+			Severity:    string(f.Severity),
+			Field:       f.Field,
+			Value:       f.Value,
+			Message:     f.Message,
+		})
+	}
+	if err := s.repo.InsertFindings(ctx, findings); err != nil {
+		return fmt.Errorf("failed to insert ValidationFindings: %w", err)
+	}
+
+	summary := &db.ValidationSummaryDB{
+		SourceFile:         file,
+		GroupsTotal:        report.Summary.GroupsTotal,
+		GroupsRejected:     report.Summary.GroupsRejected,
+		GroupsWithFindings: report.Summary.GroupsWithFindings,
+		RejectFindings:     report.Summary.FindingsBySeverity[processor.SeverityReject],
+		WarnFindings:       report.Summary.FindingsBySeverity[processor.SeverityWarn],
+		AnnotateFindings:   report.Summary.FindingsBySeverity[processor.SeverityAnnotate],
+	}
+	if err := s.repo.InsertSummary(ctx, summary); err != nil {
+		return fmt.Errorf("failed to insert ValidationSummary: %w", err)
+	}
+	return nil
+}
+
+func (s *validationService) GetFindingsByPCBANumber(ctx context.Context, pcba string) ([]processor.Finding, error) {
+	ctx, span := tracing.Start(ctx, "validation.GetFindingsByPCBANumber")
+	defer span.End()
+
+	rows, err := s.repo.GetFindingsByPCBANumber(ctx, pcba)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get ValidationFindings by PCBA number: %w", err)
+	}
+
+	findings := make([]processor.Finding, 0, len(rows))
+	for _, r := range rows {
+		findings = append(findings, processor.Finding{
+			Rule:        r.Rule,
+			Severity:    processor.Severity(r.Severity),
+			PCBA:        r.PCBANumber,
+			RecordType:  r.RecordType,
+			RecordIndex: r.RecordIndex,
+			StationType: r.StationType,
+			Field:       r.Field,
+			Value:       r.Value,
+			Message:     r.Message,
+		})
+	}
+	return findings, nil
+}
+
+func (s *validationService) GetSummariesBySourceFile(ctx context.Context, sourceFile string) ([]*db.ValidationSummaryDB, error) {
+	ctx, span := tracing.Start(ctx, "validation.GetSummariesBySourceFile")
+	defer span.End()
+
+	summaries, err := s.repo.GetSummariesBySourceFile(ctx, sourceFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get ValidationSummaries by source file: %w", err)
+	}
+	return summaries, nil
+}
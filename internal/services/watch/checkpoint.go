@@ -0,0 +1,114 @@
+package watch
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// fingerprintSize is the number of leading bytes hashed to recognise a log
+// file after it has been renamed or compressed by logrotate.
+const fingerprintSize = 1024
+
+// Fingerprint identifies a log file by the hash of its first bytes. Size is
+// the number of bytes that were hashed; it is below fingerprintSize only while
+// the file is shorter than that.
+type Fingerprint struct {
+	Size int    `json:"size"`
+	Hash string `json:"sha256"`
+}
+
+// Checkpoint is the persisted state of a Watcher.
+type Checkpoint struct {
+	// Path is the file the offset refers to.
+	Path string `json:"path"`
+	// Offset is the position of the last complete block boundary in the
+	// (decompressed) content of Path.
+	Offset      int64       `json:"offset"`
+	Fingerprint Fingerprint `json:"fingerprint"`
+	// Pending holds payloads that were read but not dispatched yet because
+	// their device was still waiting for a station record.
+	Pending   []PendingBlock `json:"pending,omitempty"`
+	UpdatedAt time.Time      `json:"updated_at"`
+}
+
+// PendingBlock is a relevant JSON block waiting to be dispatched.
+type PendingBlock struct {
+	Text   string    `json:"text"`
+	SeenAt time.Time `json:"seen_at"`
+}
+
+// loadCheckpoint reads the checkpoint at path. A missing file yields an empty
+// checkpoint and no error.
+func loadCheckpoint(path string) (Checkpoint, error) {
+	var cp Checkpoint
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return cp, nil
+	}
+	if err != nil {
+		return cp, fmt.Errorf("failed to read checkpoint: %w", err)
+	}
+	if err := json.Unmarshal(data, &cp); err != nil {
+		return cp, fmt.Errorf("failed to decode checkpoint %s: %w", path, err)
+	}
+	return cp, nil
+}
+
+// saveCheckpoint writes cp to path atomically (write to a temporary file in
+// the same directory, then rename).
+func saveCheckpoint(path string, cp Checkpoint) error {
+	data, err := json.MarshalIndent(cp, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode checkpoint: %w", err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("failed to create checkpoint: %w", err)
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write checkpoint: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write checkpoint: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to replace checkpoint: %w", err)
+	}
+	return nil
+}
+
+// computeFingerprint hashes the first fingerprintSize bytes of r.
+func computeFingerprint(r io.Reader) (Fingerprint, error) {
+	buf := make([]byte, fingerprintSize)
+	n, err := io.ReadFull(r, buf)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, io.EOF) {
+		return Fingerprint{}, err
+	}
+	sum := sha256.Sum256(buf[:n])
+	return Fingerprint{Size: n, Hash: hex.EncodeToString(sum[:])}, nil
+}
+
+// matches reports whether the content read from r starts with the bytes
+// fingerprint fp was computed from.
+func (fp Fingerprint) matches(r io.Reader) (bool, error) {
+	buf := make([]byte, fp.Size)
+	if _, err := io.ReadFull(r, buf); err != nil {
+		if errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, io.EOF) {
+			return false, nil
+		}
+		return false, err
+	}
+	sum := sha256.Sum256(buf)
+	return hex.EncodeToString(sum[:]) == fp.Hash, nil
+}
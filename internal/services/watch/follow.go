@@ -0,0 +1,196 @@
+package watch
+
+import (
+	"bufio"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/NoroSaroyan/log-parser/internal/services/parser"
+)
+
+// follower tails one growing log file. It only feeds complete lines to its
+// BlockScanner; a trailing partial line is kept until its newline arrives.
+type follower struct {
+	path    string
+	file    *os.File
+	info    os.FileInfo
+	reader  *bufio.Reader
+	scanner *parser.BlockScanner
+	partial string
+	fp      Fingerprint
+}
+
+// openFollower opens path and positions it at offset.
+func openFollower(path string, offset int64) (*follower, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	if offset > info.Size() {
+		// The file was truncated while we were not looking.
+		offset = 0
+	}
+	if _, err := f.Seek(offset, io.SeekStart); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return &follower{
+		path:    path,
+		file:    f,
+		info:    info,
+		reader:  bufio.NewReader(f),
+		scanner: parser.NewBlockScannerAt(offset),
+	}, nil
+}
+
+// readAvailable feeds every complete line appended since the last call to the
+// scanner and passes the completed blocks to emit.
+func (fl *follower) readAvailable(emit func(parser.Block)) error {
+	for {
+		chunk, err := fl.reader.ReadString('\n')
+		if err != nil && !errors.Is(err, io.EOF) {
+			return err
+		}
+		if strings.HasSuffix(chunk, "\n") {
+			line := fl.partial + strings.TrimSuffix(chunk, "\n")
+			fl.partial = ""
+			if block, ok := fl.scanner.Feed(line); ok {
+				emit(block)
+			}
+		} else {
+			fl.partial += chunk
+		}
+		if err != nil {
+			return nil
+		}
+	}
+}
+
+// finish treats the end of the file as final: the partial last line and any
+// unterminated block are emitted. Used once a file has been rotated away.
+func (fl *follower) finish(emit func(parser.Block)) {
+	if fl.partial != "" {
+		if block, ok := fl.scanner.Feed(fl.partial); ok {
+			emit(block)
+		}
+		fl.partial = ""
+	}
+	if block, ok := fl.scanner.Flush(); ok {
+		emit(block)
+	}
+}
+
+// rotated reports whether the active path now refers to another file, or the
+// file was truncated in place (copytruncate).
+func (fl *follower) rotated() (bool, error) {
+	info, err := os.Stat(fl.path)
+	if errors.Is(err, os.ErrNotExist) {
+		// Renamed away and the new file has not been created yet.
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if !os.SameFile(fl.info, info) {
+		return true, nil
+	}
+	return info.Size() < fl.scanner.Offset()+int64(len(fl.partial)), nil
+}
+
+// fingerprint returns the fingerprint of the followed file, recomputing it
+// while the file is shorter than fingerprintSize.
+func (fl *follower) fingerprint() (Fingerprint, error) {
+	if fl.fp.Size == fingerprintSize {
+		return fl.fp, nil
+	}
+	fp, err := computeFingerprint(io.NewSectionReader(fl.file, 0, fingerprintSize))
+	if err != nil {
+		return Fingerprint{}, err
+	}
+	fl.fp = fp
+	return fp, nil
+}
+
+func (fl *follower) close() error {
+	return fl.file.Close()
+}
+
+// openLog opens a log file for sequential reading, transparently
+// decompressing rotated .gz files.
+func openLog(path string) (io.ReadCloser, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	if !strings.HasSuffix(strings.ToLower(path), ".gz") {
+		return f, nil
+	}
+	gr, err := gzip.NewReader(f)
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("failed to create gzip reader: %w", err)
+	}
+	return struct {
+		io.Reader
+		io.Closer
+	}{gr, f}, nil
+}
+
+// fileFingerprint computes the fingerprint of the (decompressed) content of path.
+func fileFingerprint(path string) (Fingerprint, error) {
+	r, err := openLog(path)
+	if err != nil {
+		return Fingerprint{}, err
+	}
+	defer r.Close()
+	return computeFingerprint(r)
+}
+
+// fileMatches reports whether the (decompressed) content of path matches fp.
+func fileMatches(path string, fp Fingerprint) (bool, error) {
+	r, err := openLog(path)
+	if err != nil {
+		return false, err
+	}
+	defer r.Close()
+	return fp.matches(r)
+}
+
+// scanFinishedFile feeds the content of a rotated (no longer growing) file
+// from offset to its end to a fresh scanner and returns the end offset.
+func scanFinishedFile(path string, offset int64, emit func(parser.Block)) (int64, error) {
+	r, err := openLog(path)
+	if err != nil {
+		return 0, err
+	}
+	defer r.Close()
+
+	if _, err := io.CopyN(io.Discard, r, offset); err != nil {
+		return 0, fmt.Errorf("failed to skip to offset %d: %w", offset, err)
+	}
+
+	scanner := parser.NewBlockScannerAt(offset)
+	lines := bufio.NewScanner(r)
+	lines.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for lines.Scan() {
+		if block, ok := scanner.Feed(lines.Text()); ok {
+			emit(block)
+		}
+	}
+	if err := lines.Err(); err != nil {
+		return 0, err
+	}
+	if block, ok := scanner.Flush(); ok {
+		emit(block)
+	}
+	return scanner.Offset(), nil
+}
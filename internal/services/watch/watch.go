@@ -0,0 +1,487 @@
+/*
+Package watch implements live ingestion of the active mesrestapi log.
+
+A Watcher tails DIR/mesrestapi.log, extracts payloads with a
+parser.BlockScanner as lines arrive and dispatches them in batches every poll
+interval, so new devices show up in the database within seconds.
+
+Rotation:
+
+  - When logrotate renames the active file (to mesrestapi.log-YYYYMMDD and
+    later mesrestapi.log-YYYYMMDD.gz), the old handle is read to its end
+    before the new file is opened, so nothing written just before the
+    rotation is lost. Truncation in place (copytruncate) restarts at 0.
+
+Checkpoints:
+
+  - After every batch the watcher stores the path, the offset of the last
+    complete block boundary and a fingerprint (hash of the first bytes) of
+    the followed file. On restart it resumes at that offset; if the file has
+    been rotated meanwhile it is found again by fingerprint among the rotated
+    files, read to its end, followed by any newer rotated files and finally
+    the active file.
+  - Step arrays are logged shortly before their station record. Payloads of
+    a device and station type whose step arrays are still waiting for the
+    station record are held back (see Config.SettleTimeout) and stored in the
+    checkpoint as well, so they survive a restart. Step arrays that never get
+    a station record are dropped as orphans, like in a whole-file import.
+  - A graceful shutdown never re-ingests a payload. After a crash at most the
+    last batch can be dispatched a second time.
+
+Without a checkpoint the active file is ingested from its beginning; rotated
+files are left to the CLI "process" mode.
+*/
+package watch
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/NoroSaroyan/log-parser/internal/domain/models/dto"
+	"github.com/NoroSaroyan/log-parser/internal/infrastructure/logger"
+	"github.com/NoroSaroyan/log-parser/internal/infrastructure/metrics"
+	"github.com/NoroSaroyan/log-parser/internal/services/consistency"
+	"github.com/NoroSaroyan/log-parser/internal/services/dispatcher"
+	"github.com/NoroSaroyan/log-parser/internal/services/parser"
+	"github.com/NoroSaroyan/log-parser/internal/services/pipeline"
+	"github.com/NoroSaroyan/log-parser/internal/services/processor"
+	"github.com/NoroSaroyan/log-parser/internal/services/validation"
+)
+
+// Default configuration values.
+const (
+	DefaultFileName      = "mesrestapi.log"
+	DefaultPollInterval  = 2 * time.Second
+	DefaultSettleTimeout = 2 * time.Minute
+)
+
+// Config configures a Watcher.
+type Config struct {
+	// Dir is the directory containing the active log file.
+	Dir string
+	// FileName is the name of the active log file (default mesrestapi.log).
+	FileName string
+	// CheckpointPath is where the watcher state is persisted.
+	CheckpointPath string
+	// PollInterval is how often new lines are read and dispatched.
+	PollInterval time.Duration
+	// SettleTimeout is how long step arrays of a device may wait for their
+	// station record before they are dispatched anyway.
+	SettleTimeout time.Duration
+}
+
+// Watcher tails the active log file and dispatches new payloads.
+type Watcher struct {
+	cfg         Config
+	dispatcher  dispatcher.DispatcherService
+	validation  validation.ValidationService
+	consistency consistency.ConsistencyService
+
+	follower *follower
+	pending  []pendingBlock
+}
+
+// pendingBlock is a PendingBlock together with its parsed items.
+type pendingBlock struct {
+	PendingBlock
+	key      string
+	session  string // key and station type; payloads are held per session
+	item     interface{}
+	attempts int
+}
+
+// maxDispatchAttempts is how many polls a batch is retried after the
+// dispatcher failed every group in it, e.g. while the database is down.
+const maxDispatchAttempts = 5
+
+// New creates a Watcher that dispatches through d, stores validation
+// findings through v and checks LogisticData consistency through c.
+func New(cfg Config, d dispatcher.DispatcherService, v validation.ValidationService, c consistency.ConsistencyService) *Watcher {
+	if cfg.FileName == "" {
+		cfg.FileName = DefaultFileName
+	}
+	if cfg.PollInterval <= 0 {
+		cfg.PollInterval = DefaultPollInterval
+	}
+	if cfg.SettleTimeout <= 0 {
+		cfg.SettleTimeout = DefaultSettleTimeout
+	}
+	return &Watcher{cfg: cfg, dispatcher: d, validation: v, consistency: c}
+}
+
+// Run resumes from the checkpoint and follows the active log until ctx is
+// cancelled. A batch that is being dispatched when ctx is cancelled is
+// completed before the final checkpoint is written.
+func (w *Watcher) Run(ctx context.Context) error {
+	cp, err := loadCheckpoint(w.cfg.CheckpointPath)
+	if err != nil {
+		return err
+	}
+	for _, p := range cp.Pending {
+		w.addBlock(p.Text, p.SeenAt)
+	}
+
+	if err := w.resume(ctx, cp); err != nil {
+		return err
+	}
+	defer func() {
+		if w.follower != nil {
+			w.follower.close()
+		}
+	}()
+
+	logger.Info("Watching log file", logger.WithFields(map[string]interface{}{
+		"file":           w.activePath(),
+		"offset":         w.offset(),
+		"pending_blocks": len(w.pending),
+		"poll_interval":  w.cfg.PollInterval,
+		"settle_timeout": w.cfg.SettleTimeout,
+	}))
+
+	ticker := time.NewTicker(w.cfg.PollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			logger.Info("Watch stopping, writing final checkpoint", logger.WithFields(map[string]interface{}{
+				"file":           w.activePath(),
+				"offset":         w.offset(),
+				"pending_blocks": len(w.pending),
+			}))
+			return w.checkpoint()
+		case <-ticker.C:
+			if err := w.poll(ctx); err != nil {
+				logger.Error("Watch poll failed", err, logger.WithField("file", w.activePath()))
+			}
+		}
+	}
+}
+
+// poll reads new lines, follows a rotation, dispatches ready payloads and
+// writes the checkpoint.
+func (w *Watcher) poll(ctx context.Context) error {
+	if w.follower == nil {
+		fl, err := openFollower(w.activePath(), 0)
+		if os.IsNotExist(err) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		w.follower = fl
+	}
+
+	emit := w.emitter()
+	if err := w.follower.readAvailable(emit); err != nil {
+		return err
+	}
+
+	rotated, err := w.follower.rotated()
+	if err != nil {
+		return err
+	}
+	if rotated {
+		// Drain whatever was written to the old file before the rotation.
+		if err := w.follower.readAvailable(emit); err != nil {
+			return err
+		}
+		w.follower.finish(emit)
+		w.dispatchReady(ctx, time.Now())
+		logger.Info("Log file rotated, following new file", logger.WithFields(map[string]interface{}{
+			"file":       w.activePath(),
+			"old_offset": w.follower.scanner.Offset(),
+		}))
+		w.follower.close()
+		w.follower = nil
+
+		fl, err := openFollower(w.activePath(), 0)
+		if err == nil {
+			w.follower = fl
+			if err := w.follower.readAvailable(emit); err != nil {
+				return err
+			}
+		} else if !os.IsNotExist(err) {
+			return err
+		}
+	}
+
+	w.dispatchReady(ctx, time.Now())
+	return w.checkpoint()
+}
+
+// resume positions the watcher according to the checkpoint, ingesting rotated
+// files that were written while the watcher was not running.
+func (w *Watcher) resume(ctx context.Context, cp Checkpoint) error {
+	active := w.activePath()
+	if cp.Path == "" {
+		return w.follow(active, 0)
+	}
+
+	if ok, err := fileMatches(active, cp.Fingerprint); err == nil && ok {
+		return w.follow(active, cp.Offset)
+	}
+
+	rotated, err := w.rotatedFiles()
+	if err != nil {
+		return err
+	}
+	for i, path := range rotated {
+		ok, err := fileMatches(path, cp.Fingerprint)
+		if err != nil || !ok {
+			continue
+		}
+		logger.Info("Checkpointed file was rotated, catching up", logger.WithFields(map[string]interface{}{
+			"file":   path,
+			"offset": cp.Offset,
+			"newer":  len(rotated) - i - 1,
+		}))
+		if err := w.catchUp(ctx, path, cp.Offset); err != nil {
+			return err
+		}
+		for _, newer := range rotated[i+1:] {
+			if sameLog(newer, path) {
+				continue
+			}
+			if err := w.catchUp(ctx, newer, 0); err != nil {
+				return err
+			}
+		}
+		return w.follow(active, 0)
+	}
+
+	logger.Warn("Checkpointed file not found, starting at the beginning of the active file",
+		logger.WithFields(map[string]interface{}{
+			"checkpoint_path":   cp.Path,
+			"checkpoint_offset": cp.Offset,
+			"file":              active,
+		}),
+	)
+	return w.follow(active, 0)
+}
+
+// follow starts tailing path at offset. A missing file is picked up by the
+// next poll once it is created.
+func (w *Watcher) follow(path string, offset int64) error {
+	fl, err := openFollower(path, offset)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	w.follower = fl
+	return nil
+}
+
+// catchUp ingests a rotated file from offset to its end and checkpoints it.
+func (w *Watcher) catchUp(ctx context.Context, path string, offset int64) error {
+	end, err := scanFinishedFile(path, offset, w.emitter())
+	if err != nil {
+		return fmt.Errorf("failed to read rotated file %s: %w", path, err)
+	}
+	w.dispatchReady(ctx, time.Now())
+
+	fp, err := fileFingerprint(path)
+	if err != nil {
+		return fmt.Errorf("failed to fingerprint %s: %w", path, err)
+	}
+	return w.saveCheckpoint(path, end, fp)
+}
+
+// rotatedFiles lists the rotated siblings of the active file, oldest first.
+// Rotated names carry a YYYYMMDD suffix, so lexical order is date order.
+func (w *Watcher) rotatedFiles() ([]string, error) {
+	files, err := filepath.Glob(filepath.Join(w.cfg.Dir, w.cfg.FileName+"-*"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// sameLog reports whether a and b are the same rotated log before and after
+// compression (mesrestapi.log-20260416 and mesrestapi.log-20260416.gz).
+func sameLog(a, b string) bool {
+	return strings.TrimSuffix(a, ".gz") == strings.TrimSuffix(b, ".gz")
+}
+
+func (w *Watcher) activePath() string {
+	return filepath.Join(w.cfg.Dir, w.cfg.FileName)
+}
+
+func (w *Watcher) offset() int64 {
+	if w.follower == nil {
+		return 0
+	}
+	return w.follower.scanner.SafeOffset()
+}
+
+// emitter returns the callback that queues the blocks found by a scanner.
+func (w *Watcher) emitter() func(parser.Block) {
+	return func(b parser.Block) {
+		metrics.BlocksExtracted.Inc()
+		w.addBlock(b.Text, time.Now())
+	}
+}
+
+// addBlock classifies a block and queues it for dispatch. Blocks that are
+// not domain payloads are dropped.
+func (w *Watcher) addBlock(text string, seenAt time.Time) {
+	relevant, err := parser.FilterRelevantJsonBlocks([]string{text})
+	if err != nil {
+		return
+	}
+	item, err := parser.ClassifyBlock(relevant[0])
+	if err != nil {
+		logger.Debug("Skipping unclassifiable payload", logger.WithField("error", err.Error()))
+		return
+	}
+	key, err := processor.ItemKey(item)
+	if err != nil {
+		logger.Warn("Skipping payload without PCBA identifier", logger.WithField("error", err.Error()))
+		return
+	}
+	if key == "" {
+		return
+	}
+
+	var stationType string
+	switch v := item.(type) {
+	case dto.TestStationRecordDTO:
+		stationType = strings.TrimSpace(v.TestStation)
+	case []dto.TestStepDTO:
+		stationType, _ = parser.InferStationTypeFromSteps(v)
+	}
+	w.pending = append(w.pending, pendingBlock{
+		PendingBlock: PendingBlock{Text: relevant[0], SeenAt: seenAt},
+		key:          key,
+		session:      key + "/" + stationType,
+		item:         item,
+	})
+}
+
+// dispatchReady dispatches every queued payload that is ready. Payloads are
+// considered per device and station type: they are ready once there are no
+// more step arrays than station records of that type, or when the oldest of
+// them has waited longer than SettleTimeout. Other payloads stay queued. On a dispatch error the
+// batch stays queued and is retried on the next poll.
+func (w *Watcher) dispatchReady(ctx context.Context, now time.Time) {
+	type sessionState struct {
+		stations, stepArrays int
+		oldest               time.Time
+	}
+	sessions := map[string]*sessionState{}
+	for _, p := range w.pending {
+		st, ok := sessions[p.session]
+		if !ok {
+			st = &sessionState{oldest: p.SeenAt}
+			sessions[p.session] = st
+		}
+		if p.SeenAt.Before(st.oldest) {
+			st.oldest = p.SeenAt
+		}
+		switch p.item.(type) {
+		case dto.TestStationRecordDTO:
+			st.stations++
+		case []dto.TestStepDTO:
+			st.stepArrays++
+		}
+	}
+
+	var ready, held []pendingBlock
+	var blocks []string
+	for _, p := range w.pending {
+		st := sessions[p.session]
+		if st.stepArrays <= st.stations || now.Sub(st.oldest) >= w.cfg.SettleTimeout {
+			ready = append(ready, p)
+			blocks = append(blocks, p.Text)
+			continue
+		}
+		held = append(held, p)
+	}
+	if len(ready) == 0 {
+		return
+	}
+
+	// The batch goes through the same parsing and grouping as a whole file,
+	// so step arrays without any station record are dropped as orphans.
+	result, err := pipeline.ParseBlocks(filepath.Join(w.cfg.Dir, w.cfg.FileName), blocks)
+	if err != nil {
+		// Every block was classified in addBlock, so this only happens for
+		// malformed data; drop the batch rather than retrying forever.
+		logger.Error("Failed to parse watched payloads", err, logger.WithField("blocks", len(ready)))
+		w.pending = held
+		return
+	}
+	groups := result.Groups
+
+	// Finish the batch even if shutdown was requested meanwhile.
+	report, err := w.dispatcher.DispatchGroups(context.WithoutCancel(ctx), groups)
+	if err != nil {
+		attempts := 0
+		for i := range ready {
+			ready[i].attempts++
+			attempts = ready[i].attempts
+		}
+		if attempts < maxDispatchAttempts {
+			logger.Error("Failed to dispatch watched payloads, will retry", err, logger.WithFields(map[string]interface{}{
+				"blocks":  len(ready),
+				"groups":  len(groups),
+				"attempt": attempts,
+			}))
+			w.pending = append(held, ready...)
+			return
+		}
+		logger.Error("Giving up on watched payloads after repeated dispatch failures", err, logger.WithFields(map[string]interface{}{
+			"blocks":   len(ready),
+			"groups":   len(groups),
+			"attempts": attempts,
+		}))
+	}
+	w.pending = held
+
+	// Findings are stored once the batch is done, not for every retry.
+	if err := w.validation.SaveReport(context.WithoutCancel(ctx), result.Validation); err != nil {
+		logger.Error("Failed to save validation findings", err, logger.WithField("findings", len(result.Validation.Findings)))
+	}
+	if err := w.consistency.CheckGroups(context.WithoutCancel(ctx), groups); err != nil {
+		logger.Error("Failed to check logistic data consistency", err, logger.WithField("groups", len(groups)))
+	}
+
+	logger.Info("Watched payloads dispatched", logger.WithFields(map[string]interface{}{
+		"blocks":        len(ready),
+		"groups_ok":     report.GroupsOK,
+		"groups_failed": report.GroupsFailed,
+		"held_blocks":   len(held),
+	}))
+}
+
+// checkpoint persists the current position and queued payloads.
+func (w *Watcher) checkpoint() error {
+	if w.follower == nil {
+		return w.saveCheckpoint("", 0, Fingerprint{})
+	}
+	fp, err := w.follower.fingerprint()
+	if err != nil {
+		return fmt.Errorf("failed to fingerprint %s: %w", w.follower.path, err)
+	}
+	return w.saveCheckpoint(w.follower.path, w.follower.scanner.SafeOffset(), fp)
+}
+
+func (w *Watcher) saveCheckpoint(path string, offset int64, fp Fingerprint) error {
+	cp := Checkpoint{
+		Path:        path,
+		Offset:      offset,
+		Fingerprint: fp,
+		UpdatedAt:   time.Now().UTC(),
+	}
+	for _, p := range w.pending {
+		cp.Pending = append(cp.Pending, p.PendingBlock)
+	}
+	return saveCheckpoint(w.cfg.CheckpointPath, cp)
+}
@@ -0,0 +1,392 @@
+package watch
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/NoroSaroyan/log-parser/internal/domain/models/dto"
+	"github.com/NoroSaroyan/log-parser/internal/services/consistency"
+	"github.com/NoroSaroyan/log-parser/internal/services/dispatcher"
+	"github.com/NoroSaroyan/log-parser/internal/services/parser"
+	"github.com/NoroSaroyan/log-parser/internal/services/processor"
+	"github.com/NoroSaroyan/log-parser/internal/services/validation"
+)
+
+// fakeDispatcher records the station records of the groups it is given.
+type fakeDispatcher struct {
+	mu       sync.Mutex
+	stations []string // "<station type> <PCBA>"
+	steps    int
+}
+
+func (d *fakeDispatcher) DispatchGroups(_ context.Context, groups []dto.GroupedDataDTO) (dispatcher.DispatchReport, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for _, g := range groups {
+		for _, rec := range g.TestStationRecords {
+			d.stations = append(d.stations, rec.TestStation+" "+rec.LogisticData.PCBANumber)
+		}
+		d.steps += len(g.TestSteps)
+	}
+	return dispatcher.DispatchReport{GroupsOK: len(groups)}, nil
+}
+
+func (d *fakeDispatcher) dispatched() []string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return slices.Clone(d.stations)
+}
+
+type fakeValidation struct{ validation.ValidationService }
+
+func (fakeValidation) SaveReport(context.Context, processor.ValidationReport) error { return nil }
+
+type fakeConsistency struct{ consistency.ConsistencyService }
+
+func (fakeConsistency) CheckGroups(context.Context, []dto.GroupedDataDTO) error { return nil }
+
+// newTestWatcher returns a watcher of the active log in a temporary
+// directory, with the dispatcher it dispatches to.
+func newTestWatcher(t *testing.T) (*Watcher, *fakeDispatcher) {
+	t.Helper()
+	dir := t.TempDir()
+	d := &fakeDispatcher{}
+	w := New(Config{
+		Dir:            dir,
+		CheckpointPath: filepath.Join(dir, "watch.checkpoint.json"),
+		PollInterval:   10 * time.Millisecond,
+		SettleTimeout:  time.Hour,
+	}, d, fakeValidation{}, fakeConsistency{})
+	return w, d
+}
+
+// payload returns the log lines of one "Data" payload, pretty-printed over
+// several lines like the factory logs.
+func payload(t *testing.T, endpoint string, v interface{}) string {
+	t.Helper()
+	raw, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		t.Fatalf("marshal payload: %v", err)
+	}
+	prefix := "Apr 16 05:44:00 tcu-mes mesrestapi[4242]:"
+	lines := []string{fmt.Sprintf("%s INFO %s Serving: %s", prefix, endpoint, endpoint)}
+	jsonLines := strings.Split(string(raw), "\n")
+	lines = append(lines, fmt.Sprintf("%s INFO Data  %s", prefix, jsonLines[0]))
+	for _, l := range jsonLines[1:] {
+		lines = append(lines, prefix+" "+l)
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
+
+func stepsOf(t *testing.T, pcba string) string {
+	return payload(t, "/v1/testdatas", []dto.TestStepDTO{
+		{TestStepName: "DUT Power On", TestMeasuredValue: "12.1", TestThresholdValue: "[11.5,12.5]", TestStepResult: "PASS"},
+		{TestStepName: "PCBA Scan", TestMeasuredValue: pcba, TestStepResult: "PASS"},
+		{TestStepName: "Current Check", TestMeasuredValue: "2", TestThresholdValue: "[0,5]", TestStepResult: "PASS"},
+		{TestStepName: "CAN Loopback", TestMeasuredValue: "OK", TestThresholdValue: "OK", TestStepResult: "PASS"},
+	})
+}
+
+func stationOf(t *testing.T, pcba string) string {
+	return payload(t, "/v1/stationinformation", dto.TestStationRecordDTO{
+		PartNumber:       "703003736AA",
+		TestStation:      "PCBA",
+		EntityType:       "TCU",
+		TestFinishedTime: "2026-04-16 05:44:08",
+		IsAllPassed:      true,
+		LogisticData:     dto.LogisticDataDTO{PCBANumber: pcba, PartNumber: "703003736AA"},
+	})
+}
+
+// device returns the step array and station record of one PCBA test.
+func device(t *testing.T, pcba string) string {
+	return stepsOf(t, pcba) + stationOf(t, pcba)
+}
+
+// blockText returns the text the block scanner extracts from payload lines.
+func blockText(t *testing.T, lines string) string {
+	t.Helper()
+	s := parser.NewBlockScanner()
+	for _, line := range strings.Split(strings.TrimSuffix(lines, "\n"), "\n") {
+		if b, ok := s.Feed(line); ok {
+			return b.Text
+		}
+	}
+	if b, ok := s.Flush(); ok {
+		return b.Text
+	}
+	t.Fatal("no block in payload")
+	return ""
+}
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+}
+
+func appendFile(t *testing.T, path, content string) {
+	t.Helper()
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatalf("OpenFile: %v", err)
+	}
+	defer f.Close()
+	if _, err := f.WriteString(content); err != nil {
+		t.Fatalf("WriteString: %v", err)
+	}
+}
+
+func writeGzip(t *testing.T, path, content string) {
+	t.Helper()
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	zw := gzip.NewWriter(f)
+	zw.Write([]byte(content))
+	if err := zw.Close(); err != nil {
+		t.Fatalf("gzip: %v", err)
+	}
+	f.Close()
+}
+
+func fingerprintOf(t *testing.T, content string) Fingerprint {
+	t.Helper()
+	fp, err := computeFingerprint(strings.NewReader(content))
+	if err != nil {
+		t.Fatalf("computeFingerprint: %v", err)
+	}
+	return fp
+}
+
+// wantDispatched checks the station records dispatched so far, in any
+// order: the groups of one batch are not sorted.
+func wantDispatched(t *testing.T, d *fakeDispatcher, want ...string) {
+	t.Helper()
+	got := d.dispatched()
+	slices.Sort(got)
+	slices.Sort(want)
+	if !slices.Equal(got, want) {
+		t.Errorf("dispatched = %v, want %v", got, want)
+	}
+}
+
+func TestFingerprint(t *testing.T) {
+	w, _ := newTestWatcher(t)
+	active := w.activePath()
+	writeFile(t, active, "short\n")
+
+	fl, err := openFollower(active, 0)
+	if err != nil {
+		t.Fatalf("openFollower: %v", err)
+	}
+	defer fl.close()
+	fp, err := fl.fingerprint()
+	if err != nil || fp.Size != len("short\n") {
+		t.Fatalf("fingerprint = %+v, %v, want the 6 bytes of the file", fp, err)
+	}
+
+	// A short file is fingerprinted again as it grows, up to 1024 bytes.
+	long := "short\n" + strings.Repeat("x", 2000) + "\n"
+	writeFile(t, active, long)
+	if fp, _ = fl.fingerprint(); fp != fingerprintOf(t, long[:fingerprintSize]) || fp.Size != fingerprintSize {
+		t.Fatalf("fingerprint = %+v, want the first %d bytes", fp, fingerprintSize)
+	}
+	appendFile(t, active, "more\n")
+	if again, _ := fl.fingerprint(); again != fp {
+		t.Errorf("fingerprint changed after appending: %+v", again)
+	}
+
+	// The file is recognised after compression, and another file is not.
+	rotated := filepath.Join(w.cfg.Dir, DefaultFileName+"-20260416.gz")
+	writeGzip(t, rotated, long)
+	if ok, err := fileMatches(rotated, fp); err != nil || !ok {
+		t.Errorf("fileMatches(compressed copy) = %v, %v, want true", ok, err)
+	}
+	other := filepath.Join(w.cfg.Dir, "other.log")
+	writeFile(t, other, "short\n"+strings.Repeat("y", 2000))
+	if ok, _ := fileMatches(other, fp); ok {
+		t.Error("fileMatches(other file) = true")
+	}
+	writeFile(t, other, long[:100])
+	if ok, _ := fileMatches(other, fp); ok {
+		t.Error("fileMatches(shorter file) = true")
+	}
+}
+
+func TestPollFollowsRenameRotation(t *testing.T) {
+	w, d := newTestWatcher(t)
+	ctx := context.Background()
+	active := w.activePath()
+	writeFile(t, active, device(t, "PCBA000000A"))
+
+	if err := w.resume(ctx, Checkpoint{}); err != nil {
+		t.Fatalf("resume: %v", err)
+	}
+	if err := w.poll(ctx); err != nil {
+		t.Fatalf("poll: %v", err)
+	}
+	wantDispatched(t, d, "PCBA PCBA000000A")
+
+	// Lines written just before logrotate renames the file are not lost,
+	// including a last line without its newline.
+	last := device(t, "PCBA000000B")
+	appendFile(t, active, strings.TrimSuffix(last, "\n"))
+	if err := os.Rename(active, active+"-20260416"); err != nil {
+		t.Fatalf("Rename: %v", err)
+	}
+	writeFile(t, active, device(t, "PCBA000000C"))
+	if err := w.poll(ctx); err != nil {
+		t.Fatalf("poll: %v", err)
+	}
+	wantDispatched(t, d, "PCBA PCBA000000A", "PCBA PCBA000000B", "PCBA PCBA000000C")
+
+	cp, err := loadCheckpoint(w.cfg.CheckpointPath)
+	if err != nil {
+		t.Fatalf("loadCheckpoint: %v", err)
+	}
+	content := device(t, "PCBA000000C")
+	if cp.Path != active || cp.Offset != int64(len(content)) || cp.Fingerprint != fingerprintOf(t, content) {
+		t.Errorf("checkpoint = %+v, want the end of the new active file", cp)
+	}
+}
+
+func TestPollRestartsAfterTruncation(t *testing.T) {
+	w, d := newTestWatcher(t)
+	ctx := context.Background()
+	active := w.activePath()
+	writeFile(t, active, device(t, "PCBA000000A")+device(t, "PCBA000000B"))
+
+	if err := w.resume(ctx, Checkpoint{}); err != nil {
+		t.Fatalf("resume: %v", err)
+	}
+	if err := w.poll(ctx); err != nil {
+		t.Fatalf("poll: %v", err)
+	}
+
+	// copytruncate empties the file in place; the watcher starts over at 0.
+	writeFile(t, active, device(t, "PCBA000000C"))
+	if err := w.poll(ctx); err != nil {
+		t.Fatalf("poll: %v", err)
+	}
+	wantDispatched(t, d, "PCBA PCBA000000A", "PCBA PCBA000000B", "PCBA PCBA000000C")
+	if got, want := w.offset(), int64(len(device(t, "PCBA000000C"))); got != want {
+		t.Errorf("offset = %d, want %d", got, want)
+	}
+}
+
+func TestRunResumesFromCheckpointWithPendingBlocks(t *testing.T) {
+	w, d := newTestWatcher(t)
+	active := w.activePath()
+	dispatched := device(t, "PCBA000000A") + stepsOf(t, "PCBA000000B")
+	writeFile(t, active, dispatched+stationOf(t, "PCBA000000B"))
+
+	// The previous run dispatched device A and held the step array of B,
+	// whose station record it had not read yet.
+	if err := saveCheckpoint(w.cfg.CheckpointPath, Checkpoint{
+		Path:        active,
+		Offset:      int64(len(dispatched)),
+		Fingerprint: fingerprintOf(t, dispatched),
+		Pending:     []PendingBlock{{Text: blockText(t, stepsOf(t, "PCBA000000B")), SeenAt: time.Now()}},
+	}); err != nil {
+		t.Fatalf("saveCheckpoint: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- w.Run(ctx) }()
+	for deadline := time.Now().Add(5 * time.Second); len(d.dispatched()) == 0 && time.Now().Before(deadline); {
+		time.Sleep(5 * time.Millisecond)
+	}
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+
+	wantDispatched(t, d, "PCBA PCBA000000B")
+	if d.steps != 1 {
+		t.Errorf("%d step arrays dispatched, want the pending one", d.steps)
+	}
+	cp, err := loadCheckpoint(w.cfg.CheckpointPath)
+	if err != nil {
+		t.Fatalf("loadCheckpoint: %v", err)
+	}
+	if len(cp.Pending) != 0 || cp.Offset != int64(len(dispatched+stationOf(t, "PCBA000000B"))) {
+		t.Errorf("final checkpoint = %+v, want the end of the file and nothing pending", cp)
+	}
+}
+
+func TestResumeCatchesUpRotatedFiles(t *testing.T) {
+	w, d := newTestWatcher(t)
+	ctx := context.Background()
+	active := w.activePath()
+
+	// The checkpointed file was rotated and compressed, another day was
+	// rotated after it, and a new active file was started.
+	read := device(t, "PCBA000000A")
+	writeGzip(t, active+"-20260415.gz", read+device(t, "PCBA000000B"))
+	writeFile(t, active+"-20260416", device(t, "PCBA000000C"))
+	writeFile(t, active, device(t, "PCBA000000D"))
+
+	cp := Checkpoint{Path: active, Offset: int64(len(read)), Fingerprint: fingerprintOf(t, read+device(t, "PCBA000000B"))}
+	if err := w.resume(ctx, cp); err != nil {
+		t.Fatalf("resume: %v", err)
+	}
+	wantDispatched(t, d, "PCBA PCBA000000B", "PCBA PCBA000000C")
+	if err := w.poll(ctx); err != nil {
+		t.Fatalf("poll: %v", err)
+	}
+	wantDispatched(t, d, "PCBA PCBA000000B", "PCBA PCBA000000C", "PCBA PCBA000000D")
+}
+
+func TestSettleTimeoutHoldsStepArrays(t *testing.T) {
+	w, d := newTestWatcher(t)
+	w.cfg.SettleTimeout = 2 * time.Minute
+	ctx := context.Background()
+	start := time.Now()
+
+	// Step arrays wait for the station record logged after them.
+	w.addBlock(blockText(t, stepsOf(t, "PCBA000000A")), start)
+	w.dispatchReady(ctx, start.Add(time.Minute))
+	if len(w.pending) != 1 || len(d.dispatched()) != 0 || d.steps != 0 {
+		t.Fatalf("pending = %d, dispatched %v, want the step array held", len(w.pending), d.dispatched())
+	}
+
+	// Held payloads are part of the checkpoint.
+	if err := w.checkpoint(); err != nil {
+		t.Fatalf("checkpoint: %v", err)
+	}
+	if cp, _ := loadCheckpoint(w.cfg.CheckpointPath); len(cp.Pending) != 1 || !strings.Contains(cp.Pending[0].Text, "PCBA000000A") {
+		t.Errorf("checkpoint pending = %+v", cp.Pending)
+	}
+
+	w.addBlock(blockText(t, stationOf(t, "PCBA000000A")), start.Add(time.Minute))
+	w.dispatchReady(ctx, start.Add(time.Minute))
+	if len(w.pending) != 0 || d.steps != 1 {
+		t.Fatalf("pending = %d, %d step arrays dispatched, want the device dispatched", len(w.pending), d.steps)
+	}
+	wantDispatched(t, d, "PCBA PCBA000000A")
+
+	// Without a station record they are released after the settle timeout
+	// and dropped as orphans.
+	w.addBlock(blockText(t, stepsOf(t, "PCBA000000B")), start)
+	w.dispatchReady(ctx, start.Add(time.Minute))
+	if len(w.pending) != 1 {
+		t.Fatalf("pending = %d, want the step array held", len(w.pending))
+	}
+	w.dispatchReady(ctx, start.Add(2*time.Minute))
+	if len(w.pending) != 0 || d.steps != 1 {
+		t.Errorf("pending = %d, %d step arrays dispatched, want the orphan released and dropped", len(w.pending), d.steps)
+	}
+}
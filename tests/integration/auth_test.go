@@ -0,0 +1,285 @@
+package integration
+
+import (
+	"context"
+	"crypto"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/NoroSaroyan/log-parser/internal/app"
+	"github.com/NoroSaroyan/log-parser/internal/config"
+	"github.com/NoroSaroyan/log-parser/internal/domain/models/dto"
+	"github.com/NoroSaroyan/log-parser/internal/infrastructure/database/memory"
+	"github.com/NoroSaroyan/log-parser/internal/services/auth"
+)
+
+const hsSecret = "0123456789abcdef0123456789abcdef"
+
+// authSetup serves the fixture log with authentication enabled. HS256 tokens
+// are signed with hsSecret and RS256 tokens with the returned key.
+func authSetup(t *testing.T) (auth.AuthService, *rsa.PrivateKey, *httptest.Server) {
+	t.Helper()
+
+	dir := t.TempDir()
+	rsKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+	pubDER, err := x509.MarshalPKIXPublicKey(&rsKey.PublicKey)
+	if err != nil {
+		t.Fatalf("MarshalPKIXPublicKey: %v", err)
+	}
+	hsFile := filepath.Join(dir, "hs256.secret")
+	rsFile := filepath.Join(dir, "rs256.pem")
+	os.WriteFile(hsFile, []byte(hsSecret+"\n"), 0o600)
+	os.WriteFile(rsFile, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: pubDER}), 0o600)
+
+	store := memory.NewStore()
+	application := app.InitializeInMemoryApp(store)
+	ingest(t, application, fixtureLog(t))
+
+	svc, err := auth.NewAuthService(config.AuthConfig{
+		Enabled: true,
+		JWT: config.JWTConfig{
+			HS256SecretFile:    hsFile,
+			RS256PublicKeyFile: rsFile,
+			Audience:           "log-parser",
+		},
+	}, memory.NewAPIKeyRepository(store))
+	if err != nil {
+		t.Fatalf("NewAuthService: %v", err)
+	}
+	application.AuthService = svc
+
+	srv := newServer(application)
+	t.Cleanup(srv.Close)
+	return svc, rsKey, srv
+}
+
+// signToken returns a compact JWT with claims signed with alg.
+func signToken(t *testing.T, alg string, rsKey *rsa.PrivateKey, claims map[string]any) string {
+	t.Helper()
+
+	enc := func(v any) string {
+		data, _ := json.Marshal(v)
+		return base64.RawURLEncoding.EncodeToString(data)
+	}
+	signed := enc(map[string]string{"alg": alg, "typ": "JWT"}) + "." + enc(claims)
+
+	var sig []byte
+	switch alg {
+	case "HS256":
+		mac := hmac.New(sha256.New, []byte(hsSecret))
+		mac.Write([]byte(signed))
+		sig = mac.Sum(nil)
+	case "RS256":
+		digest := sha256.Sum256([]byte(signed))
+		var err error
+		if sig, err = rsa.SignPKCS1v15(rand.Reader, rsKey, crypto.SHA256, digest[:]); err != nil {
+			t.Fatalf("SignPKCS1v15: %v", err)
+		}
+	}
+	return signed + "." + base64.RawURLEncoding.EncodeToString(sig)
+}
+
+// getWith requests path with the given header and decodes a 200 response
+// into out.
+func getWith(t *testing.T, srv *httptest.Server, path, header, value string, out any) int {
+	t.Helper()
+
+	req, _ := http.NewRequest(http.MethodGet, srv.URL+path, nil)
+	if header != "" {
+		req.Header.Set(header, value)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("GET %s: %v", path, err)
+	}
+	defer resp.Body.Close()
+
+	if out != nil && resp.StatusCode == http.StatusOK {
+		if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+			t.Fatalf("decode %s: %v", path, err)
+		}
+	}
+	return resp.StatusCode
+}
+
+func createKey(t *testing.T, svc auth.AuthService, name string, role auth.Role) string {
+	t.Helper()
+
+	key, _, err := svc.CreateAPIKey(context.Background(), name, role)
+	if err != nil {
+		t.Fatalf("CreateAPIKey: %v", err)
+	}
+	return key
+}
+
+func TestAuthDisabledRevealsNothing(t *testing.T) {
+	_, srv := setup(t)
+
+	var timeline dto.DeviceTimelineDTO
+	if code := get(t, srv, "/api/v1/devices/"+completePCBA, &timeline); code != http.StatusOK {
+		t.Fatalf("status = %d, want 200", code)
+	}
+	if timeline.LogisticData.IMSI == fixtureIMSI || timeline.LogisticData.BlePassworkKey != "" {
+		t.Errorf("sensitive fields in clear without authentication: %+v", timeline.LogisticData)
+	}
+	// Anonymous callers may still search by redacted identifiers.
+	if code := get(t, srv, "/api/v1/devices/search?imsi="+fixtureIMSI, nil); code != http.StatusOK {
+		t.Errorf("search by imsi: status = %d, want 200", code)
+	}
+}
+
+func TestAuthDisabledIsReadOnly(t *testing.T) {
+	store := memory.NewStore()
+	application := app.InitializeInMemoryApp(store)
+	ingest(t, application, fixtureLog(t))
+	svc, err := auth.NewAuthService(config.AuthConfig{}, memory.NewAPIKeyRepository(store))
+	if err != nil {
+		t.Fatalf("NewAuthService: %v", err)
+	}
+	application.AuthService = svc
+	srv := newServer(application)
+	defer srv.Close()
+
+	if code := get(t, srv, "/api/v1/devices/"+completePCBA, nil); code != http.StatusOK {
+		t.Errorf("read: status = %d, want 200", code)
+	}
+	for _, c := range []struct{ method, path, body string }{
+		{http.MethodPost, "/api/v1/ingestions", ""},
+		{http.MethodGet, "/api/v1/admin/log-level", ""},
+		{http.MethodPut, "/api/v1/admin/log-level", `{"Level": "debug"}`},
+	} {
+		req, _ := http.NewRequest(c.method, srv.URL+c.path, strings.NewReader(c.body))
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("%s %s: %v", c.method, c.path, err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusForbidden {
+			t.Errorf("anonymous %s %s: status = %d, want 403", c.method, c.path, resp.StatusCode)
+		}
+	}
+}
+
+func TestAPIKeyRoles(t *testing.T) {
+	svc, _, srv := authSetup(t)
+	path := "/api/v1/devices/" + completePCBA
+
+	if code := get(t, srv, path, nil); code != http.StatusUnauthorized {
+		t.Errorf("no credentials: status = %d, want 401", code)
+	}
+	if code := getWith(t, srv, path, "X-API-Key", "lpk_unknown", nil); code != http.StatusUnauthorized {
+		t.Errorf("unknown key: status = %d, want 401", code)
+	}
+
+	cases := []struct {
+		role               auth.Role
+		wantIMSI, wantBLEK bool
+	}{
+		{auth.RoleViewer, false, false},
+		{auth.RoleEngineer, true, false},
+		{auth.RoleAdmin, true, true},
+	}
+	for _, c := range cases {
+		key := createKey(t, svc, string(c.role)+"-key", c.role)
+
+		var timeline dto.DeviceTimelineDTO
+		if code := getWith(t, srv, path, "X-API-Key", key, &timeline); code != http.StatusOK {
+			t.Fatalf("%s: status = %d, want 200", c.role, code)
+		}
+		if got := timeline.LogisticData.IMSI == fixtureIMSI; got != c.wantIMSI {
+			t.Errorf("%s: IMSI visible = %v, want %v", c.role, got, c.wantIMSI)
+		}
+		if got := timeline.LogisticData.BlePassworkKey == fixtureBLEKey; got != c.wantBLEK {
+			t.Errorf("%s: BlePassworkKey visible = %v, want %v", c.role, got, c.wantBLEK)
+		}
+
+		var stations []dto.TestStationWithSteps
+		getWith(t, srv, "/api/v1/pcba?pcbanumber="+completePCBA, "X-API-Key", key, &stations)
+		if len(stations) == 0 || (stations[0].LogisticData.BlePassworkKey == fixtureBLEKey) != c.wantBLEK {
+			t.Errorf("%s: /pcba BlePassworkKey visible, want %v: %+v", c.role, c.wantBLEK, stations)
+		}
+
+		code := getWith(t, srv, "/api/v1/devices/search?imsi="+fixtureIMSI, "X-API-Key", key, nil)
+		if want := map[bool]int{true: http.StatusOK, false: http.StatusForbidden}[c.wantIMSI]; code != want {
+			t.Errorf("%s: search by imsi status = %d, want %d", c.role, code, want)
+		}
+		var res dto.DeviceSearchDTO
+		getWith(t, srv, "/api/v1/devices/search?imei=860000000000009", "X-API-Key", key, &res)
+		if len(res.Matches) == 0 || (res.Matches[0].IMSI == fixtureIMSI) != c.wantIMSI {
+			t.Errorf("%s: search IMSI visible, want %v: %+v", c.role, c.wantIMSI, res)
+		}
+	}
+
+	// Uploads need the engineer role.
+	viewer := createKey(t, svc, "uploader", auth.RoleViewer)
+	req, _ := http.NewRequest(http.MethodPost, srv.URL+"/api/v1/ingestions", strings.NewReader(""))
+	req.Header.Set("X-API-Key", viewer)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("POST /ingestions: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusForbidden {
+		t.Errorf("viewer upload: status = %d, want 403", resp.StatusCode)
+	}
+
+	// Revoked keys are rejected.
+	if err := svc.RevokeAPIKey(context.Background(), "uploader"); err != nil {
+		t.Fatalf("RevokeAPIKey: %v", err)
+	}
+	if code := getWith(t, srv, path, "X-API-Key", viewer, nil); code != http.StatusUnauthorized {
+		t.Errorf("revoked key: status = %d, want 401", code)
+	}
+	if _, _, err := svc.CreateAPIKey(context.Background(), "uploader", auth.RoleViewer); err == nil {
+		t.Error("CreateAPIKey accepted a duplicate name")
+	}
+}
+
+func TestJWTAuthentication(t *testing.T) {
+	_, rsKey, srv := authSetup(t)
+	path := "/api/v1/devices/" + completePCBA
+	exp := time.Now().Add(time.Hour).Unix()
+
+	cases := []struct {
+		name     string
+		token    string
+		wantCode int
+		wantBLEK bool
+	}{
+		{"HS256 admin", signToken(t, "HS256", nil, map[string]any{"sub": "ops", "role": "admin", "aud": "log-parser", "exp": exp}), http.StatusOK, true},
+		{"RS256 viewer", signToken(t, "RS256", rsKey, map[string]any{"sub": "dash", "role": "viewer", "aud": []string{"other", "log-parser"}, "exp": exp}), http.StatusOK, false},
+		{"expired", signToken(t, "HS256", nil, map[string]any{"role": "admin", "aud": "log-parser", "exp": time.Now().Add(-time.Hour).Unix()}), http.StatusUnauthorized, false},
+		{"no exp", signToken(t, "HS256", nil, map[string]any{"role": "admin", "aud": "log-parser"}), http.StatusUnauthorized, false},
+		{"wrong audience", signToken(t, "HS256", nil, map[string]any{"role": "admin", "aud": "other", "exp": exp}), http.StatusUnauthorized, false},
+		{"unknown role", signToken(t, "HS256", nil, map[string]any{"role": "root", "aud": "log-parser", "exp": exp}), http.StatusUnauthorized, false},
+		{"alg none", signToken(t, "none", nil, map[string]any{"role": "admin", "aud": "log-parser", "exp": exp}), http.StatusUnauthorized, false},
+		{"tampered", signToken(t, "HS256", nil, map[string]any{"role": "viewer", "aud": "log-parser", "exp": exp}) + "x", http.StatusUnauthorized, false},
+	}
+	for _, c := range cases {
+		var timeline dto.DeviceTimelineDTO
+		code := getWith(t, srv, path, "Authorization", "Bearer "+c.token, &timeline)
+		if code != c.wantCode {
+			t.Errorf("%s: status = %d, want %d", c.name, code, c.wantCode)
+			continue
+		}
+		if code == http.StatusOK && (timeline.LogisticData.BlePassworkKey == fixtureBLEKey) != c.wantBLEK {
+			t.Errorf("%s: BlePassworkKey visible, want %v", c.name, c.wantBLEK)
+		}
+	}
+}
@@ -0,0 +1,585 @@
+package integration
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"io"
+	"math/big"
+	"net"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/NoroSaroyan/log-parser/internal/app"
+	"github.com/NoroSaroyan/log-parser/internal/handlers/cli"
+	"github.com/NoroSaroyan/log-parser/internal/infrastructure/database/memory"
+	"github.com/NoroSaroyan/log-parser/internal/infrastructure/logger"
+	"github.com/NoroSaroyan/log-parser/internal/services/dispatcher"
+	"github.com/NoroSaroyan/log-parser/internal/services/export"
+	"github.com/NoroSaroyan/log-parser/internal/services/stats"
+	"github.com/NoroSaroyan/log-parser/internal/services/trace"
+)
+
+// runCLI runs the CLI with args and returns the report it printed.
+func runCLI(t *testing.T, args ...string) string {
+	t.Helper()
+
+	t.Cleanup(func() { _ = logger.InitLoggerWithWriter("ERROR", io.Discard) })
+	var out bytes.Buffer
+	if err := cli.RunArgs(append([]string{"-log-level", "ERROR"}, args...), &out); err != nil {
+		t.Fatalf("cli %s: %v", strings.Join(args, " "), err)
+	}
+	return out.String()
+}
+
+// writeLog writes a log file named name in dir and returns its path.
+func writeLog(t *testing.T, dir, name, text string) string {
+	t.Helper()
+
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte(text), 0o644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	return path
+}
+
+// containsLine reports whether report has a line equal to want, ignoring
+// the alignment of its columns.
+func containsLine(report, want string) bool {
+	for _, line := range strings.Split(report, "\n") {
+		if strings.Join(strings.Fields(line), " ") == want {
+			return true
+		}
+	}
+	return false
+}
+
+func TestCLIAnalyze(t *testing.T) {
+	path := writeLog(t, t.TempDir(), "mesrestapi.log", fixtureLog(t))
+
+	var reports []cli.FileAnalysis
+	if err := json.Unmarshal([]byte(runCLI(t, "-mode", "analyze", "-format", "json", path)), &reports); err != nil {
+		t.Fatalf("analyze -format json: %v", err)
+	}
+	if len(reports) != 1 {
+		t.Fatalf("%d reports, want 1", len(reports))
+	}
+	r := reports[0]
+	if r.File != path || r.Error != "" {
+		t.Errorf("report of %s, error %q", r.File, r.Error)
+	}
+	if r.Stats.DownloadInfo != 1 || r.Stats.PCBAStations != 1 || r.Stats.FinalStations != 2 || r.Stats.TestStepArrays != 4 {
+		t.Errorf("parsing statistics = %+v", r.Stats)
+	}
+	if r.Dispatch.GroupsOK != 2 || r.Dispatch.GroupsFailed != 0 || len(r.Dispatch.Outcomes) != 2 {
+		t.Fatalf("dispatch = %+v, want 2 groups ok", r.Dispatch)
+	}
+	// Outcomes are sorted by PCBA.
+	if o := r.Dispatch.Outcomes[0]; o.PCBA != bug1PCBA || o.Status != dispatcher.OutcomeTypeMismatch || o.UnmatchedStepArrays != 1 {
+		t.Errorf("first outcome = %+v, want the Bug #1 device with a type mismatch", o)
+	}
+	if o := r.Dispatch.Outcomes[1]; o.PCBA != completePCBA || o.Status != dispatcher.OutcomeOK {
+		t.Errorf("second outcome = %+v, want the complete device ok", o)
+	}
+
+	// The text report has the same numbers; -dry-run is the same mode.
+	text := runCLI(t, "-dry-run", path)
+	for _, want := range []string{
+		"======== " + path + " ========",
+		"Download: 1",
+		"PCBA stations: 1",
+		"Final stations: 2",
+		"Test step arrays: 4",
+		"Groups: 2",
+		"Stations by type: Final=2 PCBA=1",
+		"ok / failed: 2 / 0",
+		"PCBA STATUS DETAIL",
+		bug1PCBA + " " + string(dispatcher.OutcomeTypeMismatch) + " excess_step_arrays=1 type_mismatches=1",
+		completePCBA + " " + string(dispatcher.OutcomeOK),
+	} {
+		if !containsLine(text, want) {
+			t.Errorf("analyze text has no line %q:\n%s", want, text)
+		}
+	}
+}
+
+func TestCLILogsAsConfigured(t *testing.T) {
+	dir := t.TempDir()
+	path := writeLog(t, dir, "mesrestapi.log", fixtureLog(t))
+	logFile := filepath.Join(dir, "cli.log")
+	configPath := writeConfig(t, `
+database:
+  host: localhost
+  user: parser
+  name: logs
+logger:
+  level: info
+  format: json
+  output: [stdout, file]
+  file:
+    path: `+logFile+`
+`)
+	t.Cleanup(func() { _ = logger.InitLoggerWithWriter("ERROR", io.Discard) })
+
+	var out bytes.Buffer
+	if err := cli.RunArgs([]string{"-config", configPath, "-mode", "analyze", path}, &out); err != nil {
+		t.Fatalf("analyze: %v", err)
+	}
+	lines := readLines(t, logFile)
+	if len(lines) == 0 {
+		t.Fatal("nothing logged to the configured file")
+	}
+	var start struct {
+		Msg    string `json:"msg"`
+		Fields struct {
+			LogLevel string `json:"log_level"`
+		} `json:"fields"`
+	}
+	if err := json.Unmarshal([]byte(lines[0]), &start); err != nil || start.Msg != "Starting log parser" || start.Fields.LogLevel != "info" {
+		t.Errorf("first line %q is not the JSON start line at level info (%v)", lines[0], err)
+	}
+	if strings.Contains(out.String(), "Starting log parser") {
+		t.Errorf("log lines in the report:\n%s", out.String())
+	}
+
+	// -log-level replaces only the level: the file still gets the lines.
+	if err := os.Remove(logFile); err != nil {
+		t.Fatalf("Remove: %v", err)
+	}
+	if err := cli.RunArgs([]string{"-config", configPath, "-log-level", "DEBUG", "-mode", "analyze", path}, io.Discard); err != nil {
+		t.Fatalf("analyze -log-level DEBUG: %v", err)
+	}
+	lines = readLines(t, logFile)
+	debug := 0
+	for _, line := range lines {
+		if strings.Contains(line, `"level":"debug"`) {
+			debug++
+		}
+	}
+	if debug == 0 {
+		t.Errorf("no debug lines in the configured file with -log-level DEBUG:\n%s", strings.Join(lines, "\n"))
+	}
+}
+
+func TestCLIExportJSONL(t *testing.T) {
+	path := writeLog(t, t.TempDir(), "mesrestapi.log", fixtureLog(t))
+	out := t.TempDir()
+	runCLI(t, "-mode", "export", "-out", out, path)
+
+	devices := map[string]export.Device{}
+	for _, line := range readLines(t, filepath.Join(out, export.DevicesFileName)) {
+		var d export.Device
+		if err := json.Unmarshal([]byte(line), &d); err != nil {
+			t.Fatalf("line %q is not a device: %v", line, err)
+		}
+		if d.SourceFile != path {
+			t.Errorf("%s: SourceFile = %q, want %q", d.PCBANumber, d.SourceFile, path)
+		}
+		devices[d.PCBANumber] = d
+	}
+	if len(devices) != 2 {
+		t.Fatalf("exported devices %v, want the complete and the Bug #1 device", devices)
+	}
+
+	d := devices[completePCBA]
+	if d.DownloadInfo == nil || d.LogisticData == nil || len(d.UnpairedTestSteps) != 0 {
+		t.Fatalf("complete device = %+v", d)
+	}
+	// Redacted fields are masked or dropped unless revealed.
+	if ld := d.LogisticData; ld.IMSI == fixtureIMSI || !strings.HasSuffix(ld.IMSI, "7890") || ld.BlePassworkKey != "" {
+		t.Errorf("complete device LogisticData = %+v, want the IMSI masked and no BLE key", ld)
+	}
+	data, err := os.ReadFile(filepath.Join(out, export.DevicesFileName))
+	if err != nil {
+		t.Fatalf("ReadFile: %v", err)
+	}
+	for _, secret := range []string{fixtureBLEKey, fixtureIMSI, fixtureICCID, fixturePhone} {
+		if strings.Contains(string(data), secret) {
+			t.Errorf("%s leaks %q", export.DevicesFileName, secret)
+		}
+	}
+	if len(d.Sessions) != 2 || d.Sessions[0].TestStation != "PCBA" || len(d.Sessions[0].TestSteps) != 4 ||
+		d.Sessions[1].TestStation != "Final" || len(d.Sessions[1].TestSteps) != 2 {
+		t.Errorf("complete device sessions = %+v, want PCBA with 4 steps then Final with 2", d.Sessions)
+	}
+
+	// Sessions are paired by station type: the PCBA steps of the Bug #1
+	// device have no station record.
+	d = devices[bug1PCBA]
+	if d.DownloadInfo != nil || len(d.Sessions) != 1 || d.Sessions[0].TestStation != "Final" || len(d.Sessions[0].TestSteps) != 2 {
+		t.Errorf("Bug #1 device sessions = %+v", d.Sessions)
+	}
+	if len(d.UnpairedTestSteps) != 1 || d.UnpairedTestSteps[0].InferredStation != "PCBA" || len(d.UnpairedTestSteps[0].TestSteps) != 4 {
+		t.Errorf("Bug #1 device unpaired steps = %+v, want the PCBA step array", d.UnpairedTestSteps)
+	}
+
+	// -reveal writes the listed fields in clear, and only them.
+	revealed := t.TempDir()
+	runCLI(t, "-mode", "export", "-reveal", "IMSI", "-out", revealed, path)
+	for _, line := range readLines(t, filepath.Join(revealed, export.DevicesFileName)) {
+		var d export.Device
+		if err := json.Unmarshal([]byte(line), &d); err != nil {
+			t.Fatalf("line %q is not a device: %v", line, err)
+		}
+		if d.PCBANumber != completePCBA {
+			continue
+		}
+		if ld := d.LogisticData; ld.IMSI != fixtureIMSI || ld.BlePassworkKey != "" || ld.TcuICCID == fixtureICCID {
+			t.Errorf("LogisticData with -reveal IMSI = %+v, want only the IMSI in clear", ld)
+		}
+	}
+	if err := cli.RunArgs([]string{"-log-level", "ERROR", "-mode", "export", "-reveal", "IMEI", "-out", revealed, path}, io.Discard); err == nil {
+		t.Error("export revealed IMEI, which is not redacted")
+	}
+}
+
+func TestCLIExportCSV(t *testing.T) {
+	path := writeLog(t, t.TempDir(), "mesrestapi.log", fixtureLog(t))
+	out := t.TempDir()
+	runCLI(t, "-mode", "export", "-format", "csv", "-out", out, path)
+
+	tables := map[string][]map[string]string{}
+	for name, firstColumns := range map[string]string{
+		"download_info.csv":       "id,test_station,flash_entity_type,tcu_pcba_number",
+		"logistic_data.csv":       "id,pcba_number,product_sn",
+		"test_station_record.csv": "id,part_number,test_station",
+		"test_step.csv":           "id,test_step_name,test_threshold_value,test_measured_value",
+	} {
+		data, err := os.ReadFile(filepath.Join(out, name))
+		if err != nil {
+			t.Fatalf("ReadFile: %v", err)
+		}
+		header, rows := readCSV(t, string(data))
+		if got := strings.Join(header, ","); !strings.HasPrefix(got, firstColumns+",") {
+			t.Errorf("%s header = %s, want it to start with %s", name, got, firstColumns)
+		}
+		tables[name] = rows
+	}
+
+	if rows := tables["download_info.csv"]; len(rows) != 1 || rows[0]["tcu_pcba_number"] != completePCBA {
+		t.Errorf("download_info rows = %v", rows)
+	}
+	logistic := map[string]string{} // id -> PCBA
+	for _, row := range tables["logistic_data.csv"] {
+		logistic[row["id"]] = row["pcba_number"]
+		if row["imsi"] == fixtureIMSI || !strings.HasSuffix(row["imsi"], "7890") || row["ble_passwork_key"] != "" {
+			t.Errorf("logistic_data imsi = %q, ble_passwork_key = %q, want the IMSI masked and no BLE key", row["imsi"], row["ble_passwork_key"])
+		}
+	}
+	data, err := os.ReadFile(filepath.Join(out, "logistic_data.csv"))
+	if err != nil {
+		t.Fatalf("ReadFile: %v", err)
+	}
+	for _, secret := range []string{fixtureBLEKey, fixtureIMSI, fixtureICCID, fixturePhone} {
+		if strings.Contains(string(data), secret) {
+			t.Errorf("logistic_data.csv leaks %q", secret)
+		}
+	}
+	revealed := t.TempDir()
+	runCLI(t, "-mode", "export", "-format", "csv", "-reveal", "IMSI,TcuICCID", "-out", revealed, path)
+	if data, err = os.ReadFile(filepath.Join(revealed, "logistic_data.csv")); err != nil {
+		t.Fatalf("ReadFile: %v", err)
+	}
+	_, rows := readCSV(t, string(data))
+	for _, row := range rows {
+		if row["imsi"] != fixtureIMSI || row["tcu_iccid"] != fixtureICCID || row["ble_passwork_key"] != "" {
+			t.Errorf("logistic_data with -reveal IMSI,TcuICCID = %v, want both in clear and no BLE key", row)
+		}
+	}
+
+	// Every row references an exported row of the table it links to.
+	stations := map[string]string{} // id -> "<PCBA> <station type>"
+	for _, row := range tables["test_station_record.csv"] {
+		pcba, ok := logistic[row["logistic_data_id"]]
+		if !ok {
+			t.Errorf("station record %s references unknown logistic data %s", row["id"], row["logistic_data_id"])
+		}
+		stations[row["id"]] = pcba + " " + row["test_station"]
+	}
+	if len(stations) != 3 {
+		t.Errorf("station records = %v, want the PCBA and Final records of the complete device and the Final one of the Bug #1 device", stations)
+	}
+	steps := map[string]int{}
+	for _, row := range tables["test_step.csv"] {
+		station, ok := stations[row["test_station_record_id"]]
+		if !ok {
+			t.Errorf("test step %s references unknown station record %s", row["id"], row["test_station_record_id"])
+		}
+		steps[station]++
+	}
+	for station, want := range map[string]int{
+		completePCBA + " PCBA":  4,
+		completePCBA + " Final": 2,
+	} {
+		if steps[station] != want {
+			t.Errorf("%s has %d steps, want %d (all: %v)", station, steps[station], want, steps)
+		}
+	}
+}
+
+func TestCLIStats(t *testing.T) {
+	path := writeLog(t, t.TempDir(), "mesrestapi.log-20260414", fixtureLog(t))
+
+	var files []stats.FileStats
+	if err := json.Unmarshal([]byte(runCLI(t, "-mode", "stats", "-format", "json", path)), &files); err != nil {
+		t.Fatalf("stats -format json: %v", err)
+	}
+	if len(files) != 1 {
+		t.Fatalf("%d file statistics, want 1", len(files))
+	}
+	s := files[0]
+	want := stats.TypeCounts{Download: 1, PCBAStation: 1, FinalStation: 2, PCBASteps: 3, FinalSteps: 2}
+	if s.Path != path || s.TypeCounts != want {
+		t.Errorf("type counts of %s = %+v, want %+v", s.Path, s.TypeCounts, want)
+	}
+	if s.UniquePCBAs != 3 || s.FullCoverage != 1 || s.Bug1MissingPCBARecord != 1 || s.Bug1TotalOrphan != 1 ||
+		s.SampleMissingPCBA[0] != bug1PCBA || s.SampleOrphan[0] != orphanPCBA {
+		t.Errorf("statistics = %+v", s)
+	}
+
+	text := runCLI(t, "-mode", "stats", path)
+	for _, want := range []string{
+		"mesrestapi.log-20260414 | 1 | 1 | 2 | 3 | 2 | 0 | 1 | 1 | 0 | 1 | 0 | 0",
+		`missing PCBA record: ["` + bug1PCBA + `"]`,
+		`total orphan (no station at all): ["` + orphanPCBA + `"]`,
+	} {
+		if !containsLine(text, want) {
+			t.Errorf("stats text has no line %q:\n%s", want, text)
+		}
+	}
+}
+
+func TestCLICompare(t *testing.T) {
+	oldDir, newDir := t.TempDir(), t.TempDir()
+	writeLog(t, oldDir, "mesrestapi.log-20260414", fixtureLog(t))
+	// The new test tool renamed a Final step.
+	finalSteps := finalStepsFor(completePCBA)
+	finalSteps[1].TestStepName = "Write IMEI And IMSI"
+	writeLog(t, newDir, "mesrestapi.log-20260501", newLogBuilder(t).
+		steps("May 01 05:44:00", pcbaStepsFor(completePCBA)).
+		station("May 01 05:44:09", stationFor("PCBA", completePCBA, "2026-05-01 05:44:08", true, "")).
+		steps("May 01 07:12:00", finalSteps).
+		station("May 01 07:12:30", stationFor("Final", completePCBA, "2026-05-01 07:12:29", true, "")).
+		String())
+	// Only log files directly inside the directories are compared.
+	writeLog(t, newDir, "notes.txt", fixtureLog(t))
+
+	var c stats.Comparison
+	if err := json.Unmarshal([]byte(runCLI(t, "-mode", "compare", "-old", oldDir, "-new", newDir, "-format", "json")), &c); err != nil {
+		t.Fatalf("compare -format json: %v", err)
+	}
+	if len(c.Files) != 2 || c.Files[0].Era != "old" || c.Files[0].Name() != "mesrestapi.log-20260414" ||
+		c.Files[1].Era != "new" || c.Files[1].Name() != "mesrestapi.log-20260501" {
+		t.Fatalf("compared files = %+v", c.Files)
+	}
+	if strings.Join(c.OnlyOld, ",") != "Write IMEI" || strings.Join(c.OnlyNew, ",") != "Write IMEI And IMSI" {
+		t.Errorf("only old = %q, only new = %q", c.OnlyOld, c.OnlyNew)
+	}
+	if len(c.OldStepNames) != 6 || len(c.NewStepNames) != 6 {
+		t.Errorf("step names old = %q, new = %q", c.OldStepNames, c.NewStepNames)
+	}
+
+	text := runCLI(t, "-mode", "compare", "-old", oldDir, "-new", newDir)
+	for _, want := range []string{
+		"OLD union: 6 unique",
+		"NEW union: 6 unique",
+		`in OLD but not in NEW (1): ["Write IMEI"]`,
+		`in NEW but not in OLD (1): ["Write IMEI And IMSI"]`,
+	} {
+		if !containsLine(text, want) {
+			t.Errorf("compare text has no line %q:\n%s", want, text)
+		}
+	}
+}
+
+func TestCLITrace(t *testing.T) {
+	dir := t.TempDir()
+	day1 := writeLog(t, dir, "mesrestapi.log-20260414", newLogBuilder(t).
+		download("Apr 14 05:10:00", downloadFor(completePCBA)).
+		steps("Apr 14 05:44:00", pcbaStepsFor(completePCBA)).
+		station("Apr 14 05:44:09", stationFor("PCBA", completePCBA, "2026-04-14 05:44:08", true, "")).
+		steps("Apr 14 08:00:00", pcbaStepsFor(bug1PCBA)).
+		String())
+	day2 := writeLog(t, dir, "mesrestapi.log-20260415", newLogBuilder(t).
+		steps("Apr 15 07:12:00", finalStepsFor(completePCBA)).
+		noise("Apr 15 07:12:29", "Inserting StationInformation: {0 703003736AA Final Tester_01 "+completePCBA+"}").
+		station("Apr 15 07:12:30", stationFor("Final", completePCBA, "2026-04-15 07:12:29", true, "")).
+		steps("Apr 15 09:30:00", finalStepsFor(bug1PCBA)).
+		station("Apr 15 09:30:10", stationFor("Final", bug1PCBA, "2026-04-15 09:30:09", false, "F202")).
+		String())
+
+	// Files are read in name order, whatever the order of the arguments.
+	var tr trace.Trace
+	if err := json.Unmarshal([]byte(runCLI(t, "-mode", "trace", "-pcba", completePCBA, "-format", "json", day2, day1)), &tr); err != nil {
+		t.Fatalf("trace -format json: %v", err)
+	}
+	if len(tr.Files) != 2 || tr.Files[0] != day1 || tr.Files[1] != day2 {
+		t.Errorf("files = %v, want %s then %s", tr.Files, day1, day2)
+	}
+	var timeline []string
+	for i, e := range tr.Events {
+		timeline = append(timeline, e.Kind+" "+e.StationType)
+		if i > 0 {
+			prev := tr.Events[i-1]
+			if e.File < prev.File || (e.File == prev.File && e.Line <= prev.Line) {
+				t.Errorf("event %s follows %s", e.Citation(), prev.Citation())
+			}
+		}
+	}
+	want := []string{
+		trace.KindDownload + " ",
+		trace.KindSteps + " PCBA",
+		trace.KindStation + " PCBA",
+		trace.KindSteps + " Final",
+		trace.KindStationInsert + " Final",
+		trace.KindStation + " Final",
+	}
+	if strings.Join(timeline, ",") != strings.Join(want, ",") {
+		t.Errorf("timeline = %q, want %q", timeline, want)
+	}
+	// The station record is preferred over its struct dump.
+	if len(tr.Sessions) != 2 || tr.Sessions[0].StationType != "Final" || tr.Sessions[0].Station.Kind != trace.KindStation ||
+		tr.Sessions[1].StationType != "PCBA" || tr.Sessions[1].Steps == nil || tr.Verdict != trace.VerdictComplete {
+		t.Errorf("sessions = %+v, verdict %s", tr.Sessions, tr.Verdict)
+	}
+
+	text := runCLI(t, "-mode", "trace", "-pcba", completePCBA, day2, day1)
+	// The timeline lists the payloads in log order, with their citations.
+	next := 0
+	lines := strings.Split(text, "\n")
+	for _, want := range []string{
+		"Apr 14 05:10:00 mesrestapi.log-20260414:2 download",
+		"Apr 14 05:44:00 mesrestapi.log-20260414:14 steps PCBA 4 steps, 0 not passed",
+		"Apr 14 05:44:09 mesrestapi.log-20260414:49 station PCBA",
+		"Apr 15 07:12:00 mesrestapi.log-20260415:2 steps Final 2 steps, 0 not passed",
+		"Apr 15 07:12:29 mesrestapi.log-20260415:20 station_insert Final struct dump",
+		"Apr 15 07:12:30 mesrestapi.log-20260415:22 station Final",
+		"Final #1 station: mesrestapi.log-20260415:22 steps: mesrestapi.log-20260415:2",
+		"PCBA #1 station: mesrestapi.log-20260414:49 steps: mesrestapi.log-20260414:14",
+	} {
+		for next < len(lines) && !strings.HasPrefix(strings.Join(strings.Fields(lines[next]), " "), want) {
+			next++
+		}
+		if next == len(lines) {
+			t.Fatalf("trace text has no line %q after the previous one:\n%s", want, text)
+		}
+	}
+	if !containsLine(text, "Verdict: "+trace.VerdictComplete) {
+		t.Errorf("trace text has no complete verdict:\n%s", text)
+	}
+
+	// The PCBA steps of the Bug #1 device never got their station record.
+	text = runCLI(t, "-mode", "trace", "-pcba", bug1PCBA, day1, day2)
+	for _, want := range []string{
+		"Verdict: " + trace.VerdictBug1Missing,
+		"- PCBA: 1 step array(s) without a PCBA station record",
+	} {
+		if !containsLine(text, want) {
+			t.Errorf("trace text has no line %q:\n%s", want, text)
+		}
+	}
+}
+
+// writeCertificate writes a self-signed certificate for localhost and its key
+// to dir as PEM files.
+func writeCertificate(t *testing.T, dir string) (certFile, keyFile string) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "localhost"},
+		DNSNames:     []string{"localhost"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("CreateCertificate: %v", err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("MarshalECPrivateKey: %v", err)
+	}
+	certFile = writeLog(t, dir, "cert.pem", string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})))
+	keyFile = writeLog(t, dir, "key.pem", string(pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})))
+	return certFile, keyFile
+}
+
+func TestCLIHealthcheck(t *testing.T) {
+	srv := newServer(app.InitializeInMemoryApp(memory.NewStore()))
+	defer srv.Close()
+	_, port, err := net.SplitHostPort(srv.Listener.Addr().String())
+	if err != nil {
+		t.Fatalf("SplitHostPort: %v", err)
+	}
+	healthcheck := func(configPath string) error {
+		t.Cleanup(func() { _ = logger.InitLoggerWithWriter("ERROR", io.Discard) })
+		return cli.RunArgs([]string{"-config", configPath, "-log-level", "ERROR", "-mode", "healthcheck"}, io.Discard)
+	}
+
+	// The address of the file is overridden by the environment, like in
+	// the API; an unspecified host is probed on localhost.
+	configPath := writeConfig(t, `
+database:
+  host: localhost
+  user: parser
+  name: logs
+server:
+  address: "127.0.0.1:1"
+`)
+	if err := healthcheck(configPath); err == nil {
+		t.Error("healthcheck passed against an address nothing listens on")
+	}
+	t.Setenv("LOG_PARSER_SERVER_ADDRESS", "0.0.0.0:"+port)
+	if err := healthcheck(configPath); err != nil {
+		t.Errorf("healthcheck of a live API: %v", err)
+	}
+	srv.Close()
+	if err := healthcheck(configPath); err == nil {
+		t.Error("healthcheck passed after the API stopped")
+	}
+
+	// With enable_tls the probe speaks HTTPS.
+	certFile, keyFile := writeCertificate(t, t.TempDir())
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		t.Fatalf("LoadX509KeyPair: %v", err)
+	}
+	tlsSrv := httptest.NewUnstartedServer(srv.Config.Handler)
+	tlsSrv.TLS = &tls.Config{Certificates: []tls.Certificate{cert}}
+	tlsSrv.StartTLS()
+	defer tlsSrv.Close()
+	t.Setenv("LOG_PARSER_SERVER_ADDRESS", tlsSrv.Listener.Addr().String())
+	if err := healthcheck(configPath); err == nil {
+		t.Error("plain HTTP healthcheck passed against the HTTPS API")
+	}
+	tlsConfig := writeConfig(t, `
+database:
+  host: localhost
+  user: parser
+  name: logs
+security:
+  enable_tls: true
+  cert_file: `+certFile+`
+  key_file: `+keyFile+`
+`)
+	if err := healthcheck(tlsConfig); err != nil {
+		t.Errorf("healthcheck of the HTTPS API: %v", err)
+	}
+}
@@ -0,0 +1,200 @@
+package integration
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/NoroSaroyan/log-parser/internal/config"
+	v1 "github.com/NoroSaroyan/log-parser/internal/handlers/api/v1"
+)
+
+// writeConfig writes yaml to a config file and returns its path.
+func writeConfig(t *testing.T, yaml string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(yaml), 0o600); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	return path
+}
+
+func TestLoadShippedConfigs(t *testing.T) {
+	for _, name := range []string{"config.yaml", "example.config.yaml"} {
+		cfg, err := config.LoadConfig(filepath.Join("..", "..", "configs", name))
+		if err != nil {
+			t.Errorf("%s: %v", name, err)
+			continue
+		}
+		if cfg.Database.MaxIdleConns != 10 || cfg.Database.ConnMaxLifetime != 30*time.Minute {
+			t.Errorf("%s: pool = %+v, want 10 idle connections for 30m", name, cfg.Database)
+		}
+		if cfg.Server.Address != "0.0.0.0:8080" || cfg.Server.ShutdownTimeout != 15*time.Second {
+			t.Errorf("%s: server = %+v", name, cfg.Server)
+		}
+		if cfg.Ingestion.Workers != 2 || cfg.Ingestion.QueueSize != 16 || cfg.Ingestion.MaxUploadMB != 512 {
+			t.Errorf("%s: ingestion = %+v", name, cfg.Ingestion)
+		}
+	}
+}
+
+func TestLoadConfigDefaultsAndEnv(t *testing.T) {
+	path := writeConfig(t, `
+database:
+  host: localhost
+  user: admino
+  password: admino
+  name: pandora_logs
+logger:
+  output: [stdout, file]
+  file:
+    path: /var/log/log-parser/app.log
+server:
+  read_timeout: 3s
+cors:
+  allowed_origins: ["https://a.example"]
+`)
+	t.Setenv("POSTGRES_HOST", "postgres")
+	t.Setenv("POSTGRES_PORT", "6543")
+	t.Setenv("POSTGRES_PASSWORD", "from env")
+	t.Setenv("LOG_PARSER_SERVER_ADDRESS", ":9090")
+	t.Setenv("LOG_PARSER_SERVER_WRITE_TIMEOUT", "1m")
+	t.Setenv("LOG_PARSER_CORS_ALLOWED_ORIGINS", "https://b.example, https://c.example")
+	t.Setenv("LOG_PARSER_METRICS_ENABLED", "true")
+	t.Setenv("LOG_PARSER_INGESTION_WORKERS", "4")
+
+	cfg, err := config.LoadConfig(path)
+	if err != nil {
+		t.Fatalf("LoadConfig: %v", err)
+	}
+	db := cfg.Database
+	if db.Host != "postgres" || db.Port != 6543 || db.Password != "from env" || db.User != "admino" {
+		t.Errorf("database = %+v, want the POSTGRES_* overrides", db)
+	}
+	if db.SSLMode != "disable" || db.MaxOpenConns != 25 || db.ConnMaxLifetime != 5*time.Minute {
+		t.Errorf("database = %+v, want the default sslmode and pool", db)
+	}
+	srv := cfg.Server
+	if srv.Address != ":9090" || srv.ReadTimeout != 3*time.Second || srv.WriteTimeout != time.Minute || srv.IdleTimeout != 60*time.Second {
+		t.Errorf("server = %+v", srv)
+	}
+	if got := strings.Join(cfg.CORS.AllowedOrigins, " "); got != "https://b.example https://c.example" {
+		t.Errorf("allowed_origins = %q", got)
+	}
+	if got := strings.Join(cfg.Logger.Output, " "); got != "stdout file" || cfg.Logger.File.MaxSizeMB != 100 {
+		t.Errorf("logger = %+v, want both sinks and the default rotation", cfg.Logger)
+	}
+	if want := (config.IngestionConfig{Workers: 4, QueueSize: 16, RetainJobs: 1000, MaxUploadMB: 512}); cfg.Ingestion != want {
+		t.Errorf("ingestion = %+v, want %+v", cfg.Ingestion, want)
+	}
+	if !cfg.Metrics.Enabled || cfg.Metrics.Path != "/metrics" {
+		t.Errorf("metrics = %+v", cfg.Metrics)
+	}
+	if !strings.Contains(db.DSN(), `password='from env'`) {
+		t.Errorf("DSN does not quote the password: %s", db.DSN())
+	}
+
+	// The LOG_PARSER_ variables take precedence over POSTGRES_*.
+	t.Setenv("LOG_PARSER_DATABASE_HOST", "db.internal")
+	if cfg, err := config.LoadConfig(path); err != nil || cfg.Database.Host != "db.internal" {
+		t.Errorf("LoadConfig = %+v, %v, want host db.internal", cfg, err)
+	}
+
+	t.Setenv("LOG_PARSER_SERVER_IDLE_TIMEOUT", "soon")
+	if _, err := config.LoadConfig(path); err == nil || !strings.Contains(err.Error(), "LOG_PARSER_SERVER_IDLE_TIMEOUT") {
+		t.Errorf("LoadConfig error = %v, want the invalid variable named", err)
+	}
+}
+
+func TestConfigValidation(t *testing.T) {
+	path := writeConfig(t, `
+database:
+  host: localhost
+  port: 70000
+  user: admino
+  name: pandora_logs
+  sslmode: sometimes
+  max_open_conns: 5
+  max_idle_conns: 10
+logger:
+  format: xml
+  output: file
+  packages: { parser: loud }
+server:
+  address: "8080"
+  shutdown_timeout: -1s
+ingestion:
+  workers: 0
+  dir: /no/such/dir
+security:
+  enable_tls: true
+  cert_file: /no/such/cert.pem
+auth:
+  enabled: true
+  allow_anonymous_admin: true
+cors:
+  allowed_origins: ["*"]
+  allow_credentials: true
+`)
+	_, err := config.LoadConfig(path)
+	if err == nil {
+		t.Fatal("LoadConfig accepted an invalid config")
+	}
+	for _, key := range []string{
+		"database.port", "database.sslmode", "database.max_idle_conns", "logger.format", "logger.file.path", "logger.packages.parser",
+		"server.address", "server.shutdown_timeout", "ingestion.workers", "ingestion.dir", "security.key_file", "auth.allow_anonymous_admin", "cors.allow_credentials",
+	} {
+		if !strings.Contains(err.Error(), key+":") {
+			t.Errorf("error does not mention %s:\n%v", key, err)
+		}
+	}
+	if strings.Contains(err.Error(), "database.host") {
+		t.Errorf("error mentions the valid database.host:\n%v", err)
+	}
+
+	typo := writeConfig(t, "server:\n  adress: \":8080\"\n")
+	if _, err := config.LoadConfig(typo); err == nil || !strings.Contains(err.Error(), "adress") {
+		t.Errorf("LoadConfig error = %v, want the unknown key reported", err)
+	}
+}
+
+func TestCORS(t *testing.T) {
+	handler := v1.CORS(config.CORSConfig{
+		AllowedOrigins:   []string{"https://a.example"},
+		AllowedMethods:   []string{"*"},
+		AllowedHeaders:   []string{"*"},
+		AllowCredentials: true,
+		MaxAge:           10 * time.Minute,
+	})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	preflight := func(origin, method string) http.Header {
+		req := httptest.NewRequest(http.MethodOptions, "/api/v1/devices", nil)
+		req.Header.Set("Origin", origin)
+		req.Header.Set("Access-Control-Request-Method", method)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec.Header()
+	}
+
+	h := preflight("https://a.example", http.MethodDelete)
+	if h.Get("Access-Control-Allow-Origin") != "https://a.example" || h.Get("Access-Control-Allow-Methods") != http.MethodDelete {
+		t.Errorf("preflight headers = %v, want DELETE allowed for the origin", h)
+	}
+	if h.Get("Access-Control-Allow-Credentials") != "true" || h.Get("Access-Control-Max-Age") != "600" {
+		t.Errorf("preflight headers = %v, want credentials and a max age of 600", h)
+	}
+	if h := preflight("https://evil.example", http.MethodGet); h.Get("Access-Control-Allow-Origin") != "" {
+		t.Errorf("preflight from another origin allowed: %v", h)
+	}
+
+	// The defaults allow PUT /admin/log-level from a browser.
+	handler = v1.CORS(config.Default().CORS)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	if h := preflight("https://a.example", http.MethodPut); h.Get("Access-Control-Allow-Methods") != http.MethodPut {
+		t.Errorf("default preflight headers = %v, want PUT allowed", h)
+	}
+}
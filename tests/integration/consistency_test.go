@@ -0,0 +1,131 @@
+package integration
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/NoroSaroyan/log-parser/internal/app"
+	"github.com/NoroSaroyan/log-parser/internal/domain/models/dto"
+	"github.com/NoroSaroyan/log-parser/internal/infrastructure/database/memory"
+	"github.com/NoroSaroyan/log-parser/internal/services/consistency"
+	"github.com/NoroSaroyan/log-parser/internal/services/pipeline"
+)
+
+func TestCompareLogisticData(t *testing.T) {
+	pcba := stationFor("PCBA", completePCBA, "2026-04-14 05:44:08", true, "").LogisticData
+	pcba.ProductSN = ""
+
+	final := pcba
+	final.ProductSN = "YCOT1EBG30900FB#"
+	final.IMEI = "860000000000017"
+	final.VPAppVersion = "3.2.0"
+	final.BleMac = "aabbccddeeff" // same address, different formatting
+
+	got := consistency.Compare(pcba, final)
+	want := map[string][2]string{
+		"ProductSN":    {consistency.KindFilledAtFinal, consistency.Expected},
+		"IMEI":         {consistency.KindChanged, consistency.Suspicious},
+		"VPAppVersion": {consistency.KindUpdated, consistency.Expected},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %d conflicts %+v, want %d", len(got), got, len(want))
+	}
+	for _, c := range got {
+		w, ok := want[c.Field]
+		if !ok || c.Kind != w[0] || c.Classification != w[1] {
+			t.Errorf("unexpected conflict %+v", c)
+		}
+	}
+
+	cleared := pcba
+	cleared.TcuICCID = ""
+	got = consistency.Compare(pcba, cleared)
+	if len(got) != 1 || got[0].Kind != consistency.KindCleared || got[0].Classification != consistency.Suspicious {
+		t.Errorf("cleared ICCID: got %+v, want one suspicious cleared conflict", got)
+	}
+}
+
+func TestLogisticConflictsEndpoint(t *testing.T) {
+	finalRec := stationFor("Final", completePCBA, "2026-04-14 07:12:29", true, "")
+	finalRec.LogisticData.IMEI = "860000000000017"
+	finalRec.LogisticData.VPAppVersion = "3.2.0"
+
+	logText := newLogBuilder(t).
+		steps("Apr 14 05:44:00", pcbaStepsFor(completePCBA)).
+		station("Apr 14 05:44:09", stationFor("PCBA", completePCBA, "2026-04-14 05:44:08", true, "")).
+		steps("Apr 14 07:12:00", finalStepsFor(completePCBA)).
+		station("Apr 14 07:12:30", finalRec).
+		steps("Apr 14 08:00:00", pcbaStepsFor(bug1PCBA)).
+		station("Apr 14 08:00:09", stationFor("PCBA", bug1PCBA, "2026-04-14 08:00:08", true, "")).
+		String()
+
+	store := memory.NewStore()
+	application := app.InitializeInMemoryApp(store)
+	ingest(t, application, logText)
+
+	result, err := pipeline.Parse("consistency.log", []byte(logText))
+	if err != nil {
+		t.Fatalf("pipeline.Parse: %v", err)
+	}
+	// Checking twice must not duplicate the stored conflicts.
+	for i := 0; i < 2; i++ {
+		if err := application.ConsistencyService.CheckGroups(context.Background(), result.Groups); err != nil {
+			t.Fatalf("CheckGroups: %v", err)
+		}
+	}
+	if got := store.Counts()["logistic_conflict"]; got != 2 {
+		t.Fatalf("logistic_conflict: got %d rows, want 2", got)
+	}
+
+	srv := newServer(application)
+	defer srv.Close()
+
+	var suspicious []dto.DeviceLogisticConflictsDTO
+	if code := get(t, srv, "/api/v1/logistic/conflicts?classification=suspicious", &suspicious); code != http.StatusOK {
+		t.Fatalf("/logistic/conflicts status = %d, want 200", code)
+	}
+	if len(suspicious) != 1 || suspicious[0].PCBANumber != completePCBA || !suspicious[0].Suspicious ||
+		len(suspicious[0].Conflicts) != 1 || suspicious[0].Conflicts[0].Field != "IMEI" {
+		t.Fatalf("unexpected suspicious devices: %+v", suspicious)
+	}
+
+	var device []dto.DeviceLogisticConflictsDTO
+	if code := get(t, srv, "/api/v1/logistic/conflicts?pcbanumber="+completePCBA, &device); code != http.StatusOK {
+		t.Fatalf("/logistic/conflicts?pcbanumber status = %d, want 200", code)
+	}
+	if len(device) != 1 || len(device[0].Conflicts) != 2 {
+		t.Fatalf("unexpected device conflicts: %+v", device)
+	}
+
+	// NDJSON is streamed device by device, with the same devices as JSON.
+	var all []dto.DeviceLogisticConflictsDTO
+	get(t, srv, "/api/v1/logistic/conflicts", &all)
+	resp, body := getAs(t, srv, "/api/v1/logistic/conflicts", "application/x-ndjson")
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("/logistic/conflicts as NDJSON: status = %d, want 200", resp.StatusCode)
+	}
+	lines := strings.Split(strings.TrimSpace(body), "\n")
+	if len(lines) != len(all) {
+		t.Fatalf("got %d NDJSON lines, want %d:\n%s", len(lines), len(all), body)
+	}
+	for i, line := range lines {
+		var d dto.DeviceLogisticConflictsDTO
+		if err := json.Unmarshal([]byte(line), &d); err != nil {
+			t.Fatalf("line %q: %v", line, err)
+		}
+		if d.PCBANumber != all[i].PCBANumber || len(d.Conflicts) != len(all[i].Conflicts) {
+			t.Errorf("NDJSON device %d = %+v, want %+v", i, d, all[i])
+		}
+	}
+
+	var none []dto.DeviceLogisticConflictsDTO
+	if code := get(t, srv, "/api/v1/logistic/conflicts?pcbanumber="+bug1PCBA, &none); code != http.StatusOK || len(none) != 0 {
+		t.Errorf("device without Final: status %d, conflicts %+v; want 200 and none", code, none)
+	}
+	if code := get(t, srv, "/api/v1/logistic/conflicts?classification=odd", nil); code != http.StatusBadRequest {
+		t.Errorf("invalid classification: status = %d, want 400", code)
+	}
+}
@@ -0,0 +1,165 @@
+package integration
+
+import (
+	"net/http"
+	"net/url"
+	"slices"
+	"testing"
+
+	"github.com/NoroSaroyan/log-parser/internal/app"
+	"github.com/NoroSaroyan/log-parser/internal/domain/models/dto"
+	"github.com/NoroSaroyan/log-parser/internal/infrastructure/database/memory"
+)
+
+const finalOnlyPCBA = "H8444A11100T32999001"
+
+// devicesLog holds three devices: completePCBA passed PCBA and Final,
+// bug1PCBA failed PCBA and finalOnlyPCBA only has a Final record.
+func devicesLog(t *testing.T) string {
+	t.Helper()
+
+	finalOnly := stationFor("Final", finalOnlyPCBA, "2026-04-15 09:00:08", true, "")
+	finalOnly.PartNumber = "703003737AA"
+
+	return newLogBuilder(t).
+		steps("Apr 14 05:44:00", pcbaStepsFor(completePCBA)).
+		station("Apr 14 05:44:09", stationFor("PCBA", completePCBA, "2026-04-14 05:44:08", true, "")).
+		steps("Apr 14 06:10:00", pcbaStepsFor(bug1PCBA)).
+		station("Apr 14 06:10:09", stationFor("PCBA", bug1PCBA, "2026-04-14 06:10:08", false, "E101")).
+		steps("Apr 14 07:12:00", finalStepsFor(completePCBA)).
+		station("Apr 14 07:12:30", stationFor("Final", completePCBA, "2026-04-14 07:12:29", true, "")).
+		steps("Apr 15 09:00:00", finalStepsFor(finalOnlyPCBA)).
+		station("Apr 15 09:00:09", finalOnly).
+		String()
+}
+
+func TestDevicesEndpoint(t *testing.T) {
+	application := app.InitializeInMemoryApp(memory.NewStore())
+	ingest(t, application, devicesLog(t))
+	srv := newServer(application)
+	defer srv.Close()
+
+	list := func(query string) dto.DevicePageDTO {
+		t.Helper()
+		var page dto.DevicePageDTO
+		if code := get(t, srv, "/api/v1/devices?"+query, &page); code != http.StatusOK {
+			t.Fatalf("/devices?%s status = %d, want 200", query, code)
+		}
+		return page
+	}
+	pcbas := func(page dto.DevicePageDTO) []string {
+		var out []string
+		for _, d := range page.Devices {
+			out = append(out, d.PCBANumber)
+		}
+		return out
+	}
+
+	all := list("")
+	if all.Total != 3 || len(all.Devices) != 3 || all.NextCursor != "" {
+		t.Fatalf("unfiltered listing = %+v, want 3 devices on one page", all)
+	}
+	if d := all.Devices[1]; d.PCBANumber != completePCBA || len(d.Stations) != 2 || d.RecordCount != 2 ||
+		d.FirstTested != "2026-04-14 05:44:08" || d.LastTested != "2026-04-14 07:12:29" {
+		t.Errorf("unexpected summary of %s: %+v", completePCBA, d)
+	}
+
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"station_type=PCBA", []string{bug1PCBA, completePCBA}},
+		{"station_type=Final&part_number=703003737AA", []string{finalOnlyPCBA}},
+		{"passed=false", []string{bug1PCBA}},
+		{"tested_from=2026-04-14+06:00:00&tested_to=2026-04-14", []string{bug1PCBA, completePCBA}},
+		{"tested_from=2026-04-15", []string{finalOnlyPCBA}},
+		{"sort=last_tested&order=desc", []string{finalOnlyPCBA, completePCBA, bug1PCBA}},
+	}
+	for _, tt := range tests {
+		page := list(tt.query)
+		if got := pcbas(page); !slices.Equal(got, tt.want) || page.Total != len(tt.want) {
+			t.Errorf("%s: got %v (total %d), want %v", tt.query, got, page.Total, tt.want)
+		}
+	}
+
+	// Walk the listing one device per page.
+	var walked []string
+	query := "sort=first_tested&limit=1"
+	for i := 0; i < 4; i++ {
+		page := list(query)
+		if page.Total != 3 {
+			t.Fatalf("page %d: total = %d, want 3", i, page.Total)
+		}
+		walked = append(walked, pcbas(page)...)
+		if page.NextCursor == "" {
+			break
+		}
+		query = "sort=first_tested&limit=1&cursor=" + url.QueryEscape(page.NextCursor)
+	}
+	if want := []string{completePCBA, bug1PCBA, finalOnlyPCBA}; !slices.Equal(walked, want) {
+		t.Errorf("paginated walk = %v, want %v", walked, want)
+	}
+
+	cursor := list("limit=1").NextCursor
+	for _, bad := range []string{
+		"sort=name", "order=up", "limit=0x", "limit=501", "passed=maybe",
+		"tested_from=yesterday", "tested_from=2026-04-15&tested_to=2026-04-14",
+		"cursor=garbage", "sort=last_tested&cursor=" + url.QueryEscape(cursor),
+	} {
+		if code := get(t, srv, "/api/v1/devices?"+bad, nil); code != http.StatusBadRequest {
+			t.Errorf("%s: status = %d, want 400", bad, code)
+		}
+	}
+
+	var numbers dto.PCBANumbersResponse
+	if code := get(t, srv, "/api/v1/pcbanumbers", &numbers); code != http.StatusOK || len(numbers.PCBANumbers) != 3 {
+		t.Errorf("/pcbanumbers: status %d, %v; want all 3 devices", code, numbers.PCBANumbers)
+	}
+	pcbaOnly, err := application.TestStationService.GetAllPCBANumbers(t.Context(), "PCBA")
+	if err != nil || len(pcbaOnly) != 2 {
+		t.Errorf("GetAllPCBANumbers(PCBA) = %v, %v; want 2 devices", pcbaOnly, err)
+	}
+}
+
+func TestDevicesPassedFilterUsesLatestRecord(t *testing.T) {
+	// completePCBA failed its first PCBA test and passed the retest;
+	// bug1PCBA passed, then failed at Final.
+	logText := newLogBuilder(t).
+		steps("Apr 14 05:30:00", pcbaStepsFor(completePCBA)).
+		station("Apr 14 05:30:09", stationFor("PCBA", completePCBA, "2026-04-14 05:30:08", false, "E101")).
+		steps("Apr 14 05:44:00", pcbaStepsFor(completePCBA)).
+		station("Apr 14 05:44:09", stationFor("PCBA", completePCBA, "2026-04-14 05:44:08", true, "")).
+		steps("Apr 14 06:10:00", pcbaStepsFor(bug1PCBA)).
+		station("Apr 14 06:10:09", stationFor("PCBA", bug1PCBA, "2026-04-14 06:10:08", true, "")).
+		steps("Apr 14 07:12:00", finalStepsFor(bug1PCBA)).
+		station("Apr 14 07:12:30", stationFor("Final", bug1PCBA, "2026-04-14 07:12:29", false, "F202")).
+		String()
+	application := app.InitializeInMemoryApp(memory.NewStore())
+	ingest(t, application, logText)
+	srv := newServer(application)
+	defer srv.Close()
+
+	for query, want := range map[string][]string{
+		"passed=true":                    {completePCBA},
+		"passed=false":                   {bug1PCBA},
+		"station_type=PCBA&passed=true":  {bug1PCBA, completePCBA},
+		"station_type=PCBA&passed=false": nil,
+	} {
+		var page dto.DevicePageDTO
+		if code := get(t, srv, "/api/v1/devices?"+query, &page); code != http.StatusOK {
+			t.Fatalf("/devices?%s status = %d, want 200", query, code)
+		}
+		var got []string
+		for _, d := range page.Devices {
+			got = append(got, d.PCBANumber)
+			// The aggregates cover every matching record, not only the
+			// records with the requested verdict.
+			if d.PCBANumber == completePCBA && d.RecordCount != 2 {
+				t.Errorf("%s: %s has %d records, want both PCBA tests", query, d.PCBANumber, d.RecordCount)
+			}
+		}
+		if !slices.Equal(got, want) || page.Total != len(want) {
+			t.Errorf("%s: got %v (total %d), want %v", query, got, page.Total, want)
+		}
+	}
+}
@@ -0,0 +1,184 @@
+package integration
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/NoroSaroyan/log-parser/internal/config"
+	"github.com/NoroSaroyan/log-parser/internal/domain/models/db"
+	"github.com/NoroSaroyan/log-parser/internal/infrastructure/database/memory"
+	"github.com/NoroSaroyan/log-parser/internal/infrastructure/encryption"
+)
+
+// keyring returns a keyring of fixed test keys named ids, encrypting
+// with the last one.
+func keyring(t *testing.T, ids ...string) (*encryption.Keyring, map[string][]byte) {
+	t.Helper()
+
+	keys := map[string][]byte{}
+	for _, id := range ids {
+		keys[id] = []byte(strings.Repeat(id[len(id)-1:], encryption.KeySize))
+	}
+	k, err := encryption.NewKeyring(keys, ids[len(ids)-1])
+	if err != nil {
+		t.Fatalf("NewKeyring: %v", err)
+	}
+	return k, keys
+}
+
+func TestEnvelopeEncryption(t *testing.T) {
+	k, _ := keyring(t, "k1")
+
+	enc, err := k.Encrypt(fixtureBLEKey)
+	if err != nil {
+		t.Fatalf("Encrypt: %v", err)
+	}
+	if !strings.HasPrefix(enc, "enc:v1:k1:") || strings.Contains(enc, fixtureBLEKey) {
+		t.Fatalf("Encrypt = %q, want an envelope under k1", enc)
+	}
+	if again, _ := k.Encrypt(fixtureBLEKey); again == enc {
+		t.Error("Encrypt is deterministic, want a fresh data key and nonce per value")
+	}
+	if got, err := k.Decrypt(enc); err != nil || got != fixtureBLEKey {
+		t.Errorf("Decrypt = %q, %v, want %q", got, err, fixtureBLEKey)
+	}
+
+	// Legacy plaintext and empty values pass through.
+	if got, err := k.Decrypt(fixtureBLEKey); err != nil || got != fixtureBLEKey {
+		t.Errorf("Decrypt(plaintext) = %q, %v", got, err)
+	}
+	if got, err := k.Encrypt(""); err != nil || got != "" {
+		t.Errorf("Encrypt(\"\") = %q, %v", got, err)
+	}
+
+	// Tampering with any part fails authentication.
+	i := strings.LastIndex(enc, ":") + 2
+	flipped := map[bool]string{true: "B", false: "A"}[enc[i] == 'A']
+	tampered := enc[:i] + flipped + enc[i+1:]
+	if _, err := k.Decrypt(tampered); !errors.Is(err, encryption.ErrCorrupt) {
+		t.Errorf("Decrypt(tampered) error = %v, want ErrCorrupt", err)
+	}
+	if _, err := k.Decrypt("enc:v1:k1:garbage"); !errors.Is(err, encryption.ErrCorrupt) {
+		t.Errorf("Decrypt(malformed) error = %v, want ErrCorrupt", err)
+	}
+
+	// The key ID is authenticated: relabelling the value under another key fails.
+	other, _ := keyring(t, "k1", "k2")
+	if _, err := other.Decrypt(strings.Replace(enc, ":k1:", ":k2:", 1)); !errors.Is(err, encryption.ErrCorrupt) {
+		t.Errorf("Decrypt(relabelled) error = %v, want ErrCorrupt", err)
+	}
+
+	unknown, _ := keyring(t, "k3")
+	if _, err := unknown.Decrypt(enc); !errors.Is(err, encryption.ErrUnknownKey) {
+		t.Errorf("Decrypt with another keyring error = %v, want ErrUnknownKey", err)
+	}
+	var none *encryption.Keyring
+	if _, err := none.Decrypt(enc); !errors.Is(err, encryption.ErrNoKeys) {
+		t.Errorf("Decrypt without keyring error = %v, want ErrNoKeys", err)
+	}
+}
+
+func TestRepositoryEncryptsAtRestAndRotates(t *testing.T) {
+	ctx := context.Background()
+	store := memory.NewStore()
+	plain := memory.NewLogisticDataRepository(store, nil)
+	k1, keys := keyring(t, "k1")
+
+	// A row written before encryption was enabled.
+	legacy := db.LogisticDataDB{PCBANumber: bug1PCBA, BlePassworkKey: "LEGACY-KEY"}
+	if err := plain.Insert(ctx, &legacy); err != nil {
+		t.Fatalf("Insert: %v", err)
+	}
+	repo := memory.NewLogisticDataRepository(store, k1)
+	if err := repo.Insert(ctx, &db.LogisticDataDB{PCBANumber: completePCBA, BlePassworkKey: fixtureBLEKey}); err != nil {
+		t.Fatalf("Insert: %v", err)
+	}
+
+	if d, err := repo.GetByPCBANumber(ctx, completePCBA); err != nil || d.BlePassworkKey != fixtureBLEKey {
+		t.Fatalf("GetByPCBANumber = %+v, %v, want the key in clear", d, err)
+	}
+	if d, err := repo.GetByPCBANumber(ctx, bug1PCBA); err != nil || d.BlePassworkKey != "LEGACY-KEY" {
+		t.Fatalf("legacy GetByPCBANumber = %+v, %v, want the plaintext key", d, err)
+	}
+	// Stored encrypted: a repository without the keys cannot read the row.
+	if _, err := plain.GetByPCBANumber(ctx, completePCBA); !errors.Is(err, encryption.ErrNoKeys) {
+		t.Fatalf("GetByPCBANumber without keys error = %v, want ErrNoKeys", err)
+	}
+
+	// Encrypting the legacy rows.
+	if n, err := repo.ReencryptSecrets(ctx, 1); err != nil || n != 1 {
+		t.Fatalf("ReencryptSecrets = %d, %v, want 1 legacy row", n, err)
+	}
+	if _, err := plain.GetByPCBANumber(ctx, bug1PCBA); !errors.Is(err, encryption.ErrNoKeys) {
+		t.Errorf("legacy row still in plaintext after rotation: %v", err)
+	}
+	if n, _ := repo.ReencryptSecrets(ctx, 1); n != 0 {
+		t.Errorf("second ReencryptSecrets updated %d rows, want 0", n)
+	}
+
+	// Rotating to k2, after which k1 can be removed.
+	k2, err := encryption.NewKeyring(map[string][]byte{"k1": keys["k1"], "k2": []byte(strings.Repeat("2", encryption.KeySize))}, "k2")
+	if err != nil {
+		t.Fatalf("NewKeyring: %v", err)
+	}
+	if n, err := memory.NewLogisticDataRepository(store, k2).ReencryptSecrets(ctx, 500); err != nil || n != 2 {
+		t.Fatalf("ReencryptSecrets to k2 = %d, %v, want 2", n, err)
+	}
+	onlyK2, _ := keyring(t, "k2")
+	rotated := memory.NewLogisticDataRepository(store, onlyK2)
+	for pcba, want := range map[string]string{completePCBA: fixtureBLEKey, bug1PCBA: "LEGACY-KEY"} {
+		if d, err := rotated.GetByPCBANumber(ctx, pcba); err != nil || d.BlePassworkKey != want {
+			t.Errorf("%s after rotation = %+v, %v, want %q", pcba, d, err, want)
+		}
+	}
+	if _, err := repo.GetByPCBANumber(ctx, completePCBA); !errors.Is(err, encryption.ErrUnknownKey) {
+		t.Errorf("GetByPCBANumber with only k1 error = %v, want ErrUnknownKey", err)
+	}
+
+	if _, err := plain.ReencryptSecrets(ctx, 500); err == nil {
+		t.Error("ReencryptSecrets without keys succeeded")
+	}
+}
+
+func TestLoadKeyring(t *testing.T) {
+	k1, _ := encryption.GenerateKey()
+	k2, _ := encryption.GenerateKey()
+
+	if k, err := encryption.LoadKeyring(config.EncryptionConfig{}); k != nil || err != nil {
+		t.Fatalf("LoadKeyring without keys = %v, %v, want nil", k, err)
+	}
+
+	file := filepath.Join(t.TempDir(), "keys")
+	os.WriteFile(file, []byte("# rotated 2026-10\nk1:"+k1+"\nk2:"+k2+"\n"), 0o600)
+	k, err := encryption.LoadKeyring(config.EncryptionConfig{KeysFile: file})
+	if err != nil || k.ActiveKeyID() != "k2" {
+		t.Fatalf("LoadKeyring(file) = %v, %v, want the last key active", k.ActiveKeyID(), err)
+	}
+	if k, err := encryption.LoadKeyring(config.EncryptionConfig{KeysFile: file, ActiveKeyID: "k1"}); err != nil || k.ActiveKeyID() != "k1" {
+		t.Errorf("LoadKeyring(active_key_id) = %v, %v, want k1", k.ActiveKeyID(), err)
+	}
+
+	// The environment takes precedence over the file.
+	t.Setenv(encryption.KeysEnv, "env1:"+k1+",env2:"+k2)
+	t.Setenv(encryption.ActiveKeyEnv, "env1")
+	if k, err := encryption.LoadKeyring(config.EncryptionConfig{KeysFile: file}); err != nil || k.ActiveKeyID() != "env1" {
+		t.Errorf("LoadKeyring(env) = %v, %v, want env1", k.ActiveKeyID(), err)
+	}
+
+	for _, bad := range []string{"k1", "k1:not base64!", "k1:" + k1[:8], "bad id:" + k1} {
+		t.Setenv(encryption.KeysEnv, bad)
+		t.Setenv(encryption.ActiveKeyEnv, "")
+		if _, err := encryption.LoadKeyring(config.EncryptionConfig{}); err == nil {
+			t.Errorf("LoadKeyring(%q) succeeded", bad)
+		}
+	}
+	t.Setenv(encryption.KeysEnv, "k1:"+k1)
+	t.Setenv(encryption.ActiveKeyEnv, "k9")
+	if _, err := encryption.LoadKeyring(config.EncryptionConfig{}); err == nil {
+		t.Error("LoadKeyring accepted an active key that is not in the keyring")
+	}
+}
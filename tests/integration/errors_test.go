@@ -0,0 +1,117 @@
+package integration
+
+import (
+	"net/http"
+	"slices"
+	"testing"
+
+	"github.com/NoroSaroyan/log-parser/internal/app"
+	"github.com/NoroSaroyan/log-parser/internal/domain/models/dto"
+	"github.com/NoroSaroyan/log-parser/internal/infrastructure/database/memory"
+	"github.com/NoroSaroyan/log-parser/internal/services/errorcode"
+)
+
+func TestSplitErrorCodes(t *testing.T) {
+	cases := map[string][]string{
+		"":                     nil,
+		"0":                    nil,
+		"E101":                 {"E101"},
+		"e101; E101 |N/A,,x-1": {"E101", "X-1"},
+		"E101\tE102":           {"E101", "E102"},
+	}
+	for raw, want := range cases {
+		if got := errorcode.Split(raw); !slices.Equal(got, want) {
+			t.Errorf("Split(%q) = %q, want %q", raw, got, want)
+		}
+	}
+}
+
+func TestErrorParetoEndpoint(t *testing.T) {
+	stepWithCode := func(steps []dto.TestStepDTO, code string) []dto.TestStepDTO {
+		steps[0].TestStepErrorCode = code
+		return steps
+	}
+
+	logText := newLogBuilder(t).
+		// E101 on the record and on a step of the same session counts once.
+		steps("Apr 14 05:44:00", stepWithCode(pcbaStepsFor(completePCBA), "E101")).
+		station("Apr 14 05:44:09", stationFor("PCBA", completePCBA, "2026-04-14 05:44:08", false, "E101;e102")).
+		steps("Apr 14 05:50:00", pcbaStepsFor(completePCBA)).
+		station("Apr 14 05:50:09", stationFor("PCBA", completePCBA, "2026-04-14 05:50:08", true, "")).
+		steps("Apr 14 08:00:00", pcbaStepsFor(bug1PCBA)).
+		station("Apr 14 08:00:09", stationFor("PCBA", bug1PCBA, "2026-04-14 08:00:08", false, "E101")).
+		steps("Apr 14 08:10:00", pcbaStepsFor(bug1PCBA)).
+		station("Apr 14 08:10:09", stationFor("PCBA", bug1PCBA, "2026-04-14 08:10:08", false, "E101")).
+		steps("Apr 14 08:20:00", pcbaStepsFor(bug1PCBA)).
+		station("Apr 14 08:20:09", stationFor("PCBA", bug1PCBA, "2026-04-14 08:20:08", false, "E101")).
+		steps("Apr 15 09:00:00", pcbaStepsFor(flashedPCBA)).
+		station("Apr 15 09:00:09", stationFor("PCBA", flashedPCBA, "2026-04-15 09:00:08", false, "E102, 0")).
+		steps("Apr 15 10:00:00", stepWithCode(finalStepsFor(finalOnlyPCBA), "F202")).
+		station("Apr 15 10:00:09", stationFor("Final", finalOnlyPCBA, "2026-04-15 10:00:08", false, "E102")).
+		String()
+
+	store := memory.NewStore()
+	application := app.InitializeInMemoryApp(store)
+	ingest(t, application, logText)
+	srv := newServer(application)
+	defer srv.Close()
+
+	counts := store.Counts()
+	if counts["error_code"] != 3 || counts["test_station_record_error_code"] != 7 || counts["test_step_error_code"] != 2 {
+		t.Errorf("error code tables = %d codes, %d record links, %d step links; want 3, 7, 2",
+			counts["error_code"], counts["test_station_record_error_code"], counts["test_step_error_code"])
+	}
+
+	pareto := func(query string) dto.ErrorParetoDTO {
+		t.Helper()
+		var p dto.ErrorParetoDTO
+		if code := get(t, srv, "/api/v1/analytics/errors"+query, &p); code != http.StatusOK {
+			t.Fatalf("/analytics/errors%s status = %d, want 200", query, code)
+		}
+		return p
+	}
+	type row struct {
+		code                 string
+		occurrences, devices int
+	}
+	check := func(query string, total int, want []row) {
+		t.Helper()
+		p := pareto(query)
+		var got []row
+		for _, c := range p.Codes {
+			got = append(got, row{c.Code, c.Occurrences, c.Devices})
+		}
+		if p.Total != total || !slices.Equal(got, want) {
+			t.Errorf("%s: total %d codes %+v, want total %d codes %+v", query, p.Total, got, total, want)
+		}
+	}
+
+	check("", 8, []row{{"E101", 4, 2}, {"E102", 3, 3}, {"F202", 1, 1}})
+	check("?sort=devices", 6, []row{{"E102", 3, 3}, {"E101", 4, 2}, {"F202", 1, 1}})
+	check("?station_type=Final", 2, []row{{"E102", 1, 1}, {"F202", 1, 1}})
+	check("?to=2026-04-14", 5, []row{{"E101", 4, 2}, {"E102", 1, 1}})
+
+	p := pareto("?limit=2")
+	if len(p.Codes) != 2 || p.Codes[0].Share != 0.5 || p.Codes[1].CumulativeShare != 7.0/8 {
+		t.Errorf("limited pareto = %+v, want 2 codes with shares of all 8 occurrences", p)
+	}
+
+	var devices dto.ErrorCodeDevicesDTO
+	if code := get(t, srv, "/api/v1/analytics/errors/e101", &devices); code != http.StatusOK {
+		t.Fatalf("/analytics/errors/e101 status = %d, want 200", code)
+	}
+	if devices.Code != "E101" || len(devices.Devices) != 2 {
+		t.Fatalf("drill-down = %+v, want the 2 devices behind E101", devices)
+	}
+	d := devices.Devices[0]
+	if d.PCBANumber != bug1PCBA || d.Occurrences != 3 || !slices.Equal(d.Stations, []string{"PCBA"}) ||
+		d.FirstSeen != "2026-04-14 08:00:08" || d.LastSeen != "2026-04-14 08:20:08" {
+		t.Errorf("first device = %+v, want %s with 3 PCBA occurrences from 08:00:08 to 08:20:08", d, bug1PCBA)
+	}
+
+	for _, path := range []string{"?sort=code", "?limit=-1", "?limit=x", "?from=soon", "/E101?to=later"} {
+		if code := get(t, srv, "/api/v1/analytics/errors"+path, nil); code != http.StatusBadRequest {
+			t.Errorf("/analytics/errors%s status = %d, want 400", path, code)
+		}
+	}
+}
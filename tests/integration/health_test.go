@@ -0,0 +1,108 @@
+package integration
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/NoroSaroyan/log-parser/internal/app"
+	"github.com/NoroSaroyan/log-parser/internal/domain/models/dto"
+	v1 "github.com/NoroSaroyan/log-parser/internal/handlers/api/v1"
+	"github.com/NoroSaroyan/log-parser/internal/infrastructure/database"
+	"github.com/NoroSaroyan/log-parser/internal/infrastructure/database/memory"
+	"github.com/NoroSaroyan/log-parser/internal/services/health"
+	"github.com/NoroSaroyan/log-parser/internal/services/ingestion"
+)
+
+// brokenDB is a HealthRepository of an unreachable database with an old
+// schema.
+type brokenDB struct{}
+
+func (brokenDB) Ping(ctx context.Context) error {
+	return errors.New("connection refused")
+}
+
+func (brokenDB) SchemaVersion(ctx context.Context) (int, error) {
+	return database.SchemaVersion - 1, nil
+}
+
+// readyz requests /readyz and decodes the report whatever the status.
+func readyz(t *testing.T, srv *httptest.Server) (int, dto.HealthDTO) {
+	t.Helper()
+
+	resp, err := http.Get(srv.URL + "/readyz")
+	if err != nil {
+		t.Fatalf("GET /readyz: %v", err)
+	}
+	defer resp.Body.Close()
+	var report dto.HealthDTO
+	if err := json.NewDecoder(resp.Body).Decode(&report); err != nil {
+		t.Fatalf("decode /readyz: %v", err)
+	}
+	return resp.StatusCode, report
+}
+
+func TestHealthProbes(t *testing.T) {
+	_, srv := setup(t)
+
+	var live dto.HealthDTO
+	if code := get(t, srv, "/healthz", &live); code != http.StatusOK || live.Status != health.StatusOK {
+		t.Errorf("/healthz = %d %+v, want 200 ok", code, live)
+	}
+
+	code, report := readyz(t, srv)
+	if code != http.StatusOK || report.Status != health.StatusOK {
+		t.Fatalf("/readyz = %d %+v, want 200 ok", code, report)
+	}
+	names := []string{}
+	for _, c := range report.Checks {
+		names = append(names, c.Name)
+		if c.Status != health.StatusOK || c.LatencyMs < 0 || c.Error != "" {
+			t.Errorf("check %+v, want ok", c)
+		}
+	}
+	if got := strings.Join(names, ","); got != "database,migrations,ingestion" {
+		t.Errorf("checks = %s, want database,migrations,ingestion", got)
+	}
+}
+
+func TestReadinessFailures(t *testing.T) {
+	application := app.InitializeInMemoryApp(memory.NewStore())
+	ingestionService := ingestion.NewIngestionService(ingestion.Config{}, newDispatcher(application),
+		application.ValidationService, application.ConsistencyService)
+	ingestionService.Close()
+
+	r := chi.NewRouter()
+	v1.RegisterHealth(r, health.NewHealthService(brokenDB{}).With(health.IngestionBacklog(ingestionService)))
+	srv := httptest.NewServer(r)
+	defer srv.Close()
+
+	// Liveness does not depend on the database.
+	if code := get(t, srv, "/healthz", nil); code != http.StatusOK {
+		t.Errorf("/healthz = %d, want 200", code)
+	}
+
+	code, report := readyz(t, srv)
+	if code != http.StatusServiceUnavailable || report.Status != health.StatusFail {
+		t.Fatalf("/readyz = %d %+v, want 503 fail", code, report)
+	}
+	wantErr := map[string]string{
+		"database":   "connection refused",
+		"migrations": "apply the pending migrations",
+		"ingestion":  ingestion.ErrClosed.Error(),
+	}
+	for _, c := range report.Checks {
+		if c.Status != health.StatusFail || !strings.Contains(c.Error, wantErr[c.Name]) {
+			t.Errorf("check %+v, want fail with %q", c, wantErr[c.Name])
+		}
+	}
+	if len(report.Checks) != len(wantErr) {
+		t.Errorf("got %d checks, want %d", len(report.Checks), len(wantErr))
+	}
+}
@@ -0,0 +1,224 @@
+package integration
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/NoroSaroyan/log-parser/internal/app"
+	"github.com/NoroSaroyan/log-parser/internal/domain/models/dto"
+	"github.com/NoroSaroyan/log-parser/internal/infrastructure/database/memory"
+	"github.com/NoroSaroyan/log-parser/internal/services/ingestion"
+)
+
+// upload posts content as the "file" field of a multipart form and decodes a
+// 202 response into job.
+func upload(t *testing.T, srv *httptest.Server, fileName string, content []byte, job *dto.IngestionJobDTO) *http.Response {
+	t.Helper()
+
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	fw, err := mw.CreateFormFile("file", fileName)
+	if err != nil {
+		t.Fatalf("CreateFormFile: %v", err)
+	}
+	fw.Write(content)
+	mw.Close()
+
+	resp, err := http.Post(srv.URL+"/api/v1/ingestions", mw.FormDataContentType(), &body)
+	if err != nil {
+		t.Fatalf("POST /ingestions: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if job != nil && resp.StatusCode == http.StatusAccepted {
+		if err := json.NewDecoder(resp.Body).Decode(job); err != nil {
+			t.Fatalf("decode upload response: %v", err)
+		}
+	}
+	return resp
+}
+
+// waitForJob polls the job until it is finished.
+func waitForJob(t *testing.T, srv *httptest.Server, id string) dto.IngestionJobDTO {
+	t.Helper()
+
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		var job dto.IngestionJobDTO
+		if code := get(t, srv, "/api/v1/ingestions/"+id, &job); code != http.StatusOK {
+			t.Fatalf("GET job status = %d, want 200", code)
+		}
+		if job.Status == ingestion.StatusSucceeded || job.Status == ingestion.StatusFailed {
+			return job
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("job %s still %s after 10s", id, job.Status)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestIngestionUpload(t *testing.T) {
+	store := memory.NewStore()
+	srv := newServer(app.InitializeInMemoryApp(store))
+	t.Cleanup(srv.Close)
+
+	var job dto.IngestionJobDTO
+	resp := upload(t, srv, "mesrestapi.log", []byte(fixtureLog(t)), &job)
+	if resp.StatusCode != http.StatusAccepted {
+		t.Fatalf("upload status = %d, want 202", resp.StatusCode)
+	}
+	if job.ID == "" || job.FileName != "mesrestapi.log" || job.SizeBytes == 0 {
+		t.Fatalf("unexpected queued job: %+v", job)
+	}
+	if loc := resp.Header.Get("Location"); loc != "/api/v1/ingestions/"+job.ID {
+		t.Errorf("Location = %q", loc)
+	}
+
+	job = waitForJob(t, srv, job.ID)
+	if job.Status != ingestion.StatusSucceeded || job.Stage != ingestion.StageDone || job.FinishedAt == "" {
+		t.Fatalf("unexpected finished job: %+v", job)
+	}
+	if job.Progress.GroupsDispatched != job.Progress.GroupsTotal || job.Progress.Percent != 100 {
+		t.Errorf("progress = %+v, want complete", job.Progress)
+	}
+	if s := job.Stats; s == nil || s.GroupsOK != 2 || s.GroupsFailed != 0 || s.PCBAStations != 1 || s.FinalStations != 2 {
+		t.Errorf("stats = %+v", job.Stats)
+	}
+
+	// The upload is stored exactly like the CLI process mode would store it.
+	want := ingestedCounts(t)
+	got := store.Counts()
+	for _, table := range []string{"download_info", "logistic_data", "test_station_record", "test_step"} {
+		if got[table] != want[table] {
+			t.Errorf("%s: got %d rows, want %d", table, got[table], want[table])
+		}
+	}
+}
+
+func TestIngestionUploadGzip(t *testing.T) {
+	store := memory.NewStore()
+	srv := newServer(app.InitializeInMemoryApp(store))
+	t.Cleanup(srv.Close)
+
+	var gz bytes.Buffer
+	zw := gzip.NewWriter(&gz)
+	zw.Write([]byte(fixtureLog(t)))
+	zw.Close()
+
+	var job dto.IngestionJobDTO
+	if resp := upload(t, srv, "mesrestapi.log-20260414.gz", gz.Bytes(), &job); resp.StatusCode != http.StatusAccepted {
+		t.Fatalf("upload status = %d, want 202", resp.StatusCode)
+	}
+	if job = waitForJob(t, srv, job.ID); job.Status != ingestion.StatusSucceeded {
+		t.Fatalf("gzip job = %+v, want succeeded", job)
+	}
+	if got := store.Counts()["test_station_record"]; got != 3 {
+		t.Errorf("test_station_record: got %d rows, want 3", got)
+	}
+}
+
+func TestIngestionUploadErrors(t *testing.T) {
+	srv := newServer(app.InitializeInMemoryApp(memory.NewStore()))
+	t.Cleanup(srv.Close)
+
+	if resp := upload(t, srv, "report.csv", []byte("a,b"), nil); resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("unsupported extension: status = %d, want 400", resp.StatusCode)
+	}
+
+	resp, err := http.Post(srv.URL+"/api/v1/ingestions", "text/plain", bytes.NewReader([]byte("log")))
+	if err != nil {
+		t.Fatalf("POST /ingestions: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("non-multipart body: status = %d, want 400", resp.StatusCode)
+	}
+
+	if code := get(t, srv, "/api/v1/ingestions/unknown", nil); code != http.StatusNotFound {
+		t.Errorf("unknown job: status = %d, want 404", code)
+	}
+
+	// A corrupt .gz fails the job, not the upload.
+	var job dto.IngestionJobDTO
+	if resp := upload(t, srv, "broken.gz", []byte("not gzip"), &job); resp.StatusCode != http.StatusAccepted {
+		t.Fatalf("corrupt gzip upload: status = %d, want 202", resp.StatusCode)
+	}
+	if job = waitForJob(t, srv, job.ID); job.Status != ingestion.StatusFailed || len(job.Errors) == 0 {
+		t.Errorf("corrupt gzip job = %+v, want failed with errors", job)
+	}
+}
+
+// readTracker is a reader that records whether it was read.
+type readTracker struct{ read bool }
+
+func (r *readTracker) Read(p []byte) (int, error) {
+	r.read = true
+	return 0, io.EOF
+}
+
+func TestIngestionQueueFullBeforeReading(t *testing.T) {
+	application := app.InitializeInMemoryApp(memory.NewStore())
+	dir := t.TempDir()
+	svc := ingestion.NewIngestionService(ingestion.Config{Workers: 1, QueueSize: 1, Dir: dir},
+		newDispatcher(application), application.ValidationService, application.ConsistencyService)
+	t.Cleanup(svc.Close)
+
+	// An upload still being received holds the only queue slot.
+	pr, pw := io.Pipe()
+	done := make(chan error, 1)
+	go func() {
+		_, err := svc.Submit(t.Context(), "slow.log", pr)
+		done <- err
+	}()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if queued, _, _ := svc.Backlog(); queued == 1 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("the slow upload never reserved a queue slot")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	r := &readTracker{}
+	if _, err := svc.Submit(t.Context(), "refused.log", r); !errors.Is(err, ingestion.ErrQueueFull) {
+		t.Fatalf("Submit with a full queue = %v, want ErrQueueFull", err)
+	}
+	if r.read {
+		t.Error("the refused upload was read")
+	}
+	if files, _ := filepath.Glob(filepath.Join(dir, "ingestion-*")); len(files) != 1 {
+		t.Errorf("upload files = %v, want only the slow upload", files)
+	}
+
+	// A failed upload gives its slot back.
+	pw.CloseWithError(errors.New("client went away"))
+	if err := <-done; err == nil {
+		t.Fatal("Submit of the interrupted upload succeeded")
+	}
+	if queued, _, _ := svc.Backlog(); queued != 0 {
+		t.Errorf("Backlog after the failed upload = %d, want 0", queued)
+	}
+	if _, err := svc.Submit(t.Context(), "next.log", strings.NewReader("")); err != nil {
+		t.Errorf("Submit after the slot was released: %v", err)
+	}
+}
+
+// ingestedCounts returns the row counts after ingesting the fixture directly.
+func ingestedCounts(t *testing.T) map[string]int {
+	store := memory.NewStore()
+	ingest(t, app.InitializeInMemoryApp(store), fixtureLog(t))
+	return store.Counts()
+}
@@ -0,0 +1,123 @@
+package integration
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/NoroSaroyan/log-parser/internal/domain/models/dto"
+)
+
+// logBuilder assembles a synthetic mesrestapi log in the same line format as
+// the factory logs: every line carries a syslog prefix ending in "]:" and
+// payloads start with a " Data  {" or " Data  [" line.
+type logBuilder struct {
+	t     *testing.T
+	lines []string
+}
+
+func newLogBuilder(t *testing.T) *logBuilder {
+	t.Helper()
+	return &logBuilder{t: t}
+}
+
+// payload appends one "Data" payload, pretty-printed over several lines.
+func (b *logBuilder) payload(ts, endpoint string, v interface{}) *logBuilder {
+	b.t.Helper()
+
+	raw, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		b.t.Fatalf("marshal payload: %v", err)
+	}
+	prefix := fmt.Sprintf("%s tcu-mes mesrestapi[4242]:", ts)
+
+	b.lines = append(b.lines, fmt.Sprintf("%s INFO %s Serving: %s", prefix, endpoint, endpoint))
+	jsonLines := strings.Split(string(raw), "\n")
+	b.lines = append(b.lines, fmt.Sprintf("%s INFO Data  %s", prefix, jsonLines[0]))
+	for _, l := range jsonLines[1:] {
+		b.lines = append(b.lines, prefix+" "+l)
+	}
+	return b
+}
+
+// noise appends an unrelated log line.
+func (b *logBuilder) noise(ts, msg string) *logBuilder {
+	b.lines = append(b.lines, fmt.Sprintf("%s tcu-mes mesrestapi[4242]: INFO %s", ts, msg))
+	return b
+}
+
+func (b *logBuilder) download(ts string, d dto.DownloadInfoDTO) *logBuilder {
+	return b.payload(ts, "/v1/stationinformation", d)
+}
+
+func (b *logBuilder) station(ts string, rec dto.TestStationRecordDTO) *logBuilder {
+	return b.payload(ts, "/v1/stationinformation", rec)
+}
+
+func (b *logBuilder) steps(ts string, steps []dto.TestStepDTO) *logBuilder {
+	return b.payload(ts, "/v1/testdatas", steps)
+}
+
+func (b *logBuilder) String() string {
+	return strings.Join(b.lines, "\n") + "\n"
+}
+
+func downloadFor(pcba string) dto.DownloadInfoDTO {
+	return dto.DownloadInfoDTO{
+		TestStation:          "Download",
+		FlashEntityType:      "TCU",
+		TcuPCBANumber:        pcba,
+		FlashElapsedTime:     95,
+		TcuEntityFlashState:  "Success",
+		PartNumber:           "703003736AA",
+		ProductLine:          "TCU",
+		DownloadToolVersion:  "1.4.2",
+		DownloadFinishedTime: "2026-04-14 05:10:00",
+	}
+}
+
+func stationFor(stationType, pcba, finished string, passed bool, errorCodes string) dto.TestStationRecordDTO {
+	return dto.TestStationRecordDTO{
+		PartNumber:       "703003736AA",
+		TestStation:      stationType,
+		EntityType:       "TCU",
+		ProductLine:      "TCU",
+		TestToolVersion:  "2.0.11-RC",
+		TestFinishedTime: finished,
+		IsAllPassed:      passed,
+		ErrorCodes:       errorCodes,
+		LogisticData: dto.LogisticDataDTO{
+			PCBANumber:     pcba,
+			ProductSN:      "YCOT1EBG30900FB#",
+			PartNumber:     "703003736AA",
+			VPAppVersion:   "3.1.0",
+			BleMac:         "AA:BB:CC:DD:EE:FF",
+			BlePassworkKey: fixtureBLEKey,
+			TcuICCID:       fixtureICCID,
+			IMEI:           "860000000000009",
+			IMSI:           fixtureIMSI,
+			PhoneNumber:    fixturePhone,
+			ProductionDate: "2026-04-14",
+		},
+	}
+}
+
+// pcbaStepsFor returns a PCBA-station step array. Note that step arrays of
+// exactly three elements are avoided on purpose: FilterRelevantJsonBlocks
+// treats any three-element array as a Download/steps/station compound.
+func pcbaStepsFor(pcba string) []dto.TestStepDTO {
+	return []dto.TestStepDTO{
+		{TestStepName: "DUT Power On", TestMeasuredValue: "12.1", TestThresholdValue: "[11.5,12.5]", TestStepElapsedTime: 120, TestStepResult: "PASS"},
+		{TestStepName: "PCBA Scan", TestMeasuredValue: pcba, TestStepElapsedTime: 40, TestStepResult: "PASS"},
+		{TestStepName: "Current Check", TestMeasuredValue: 2, TestThresholdValue: "[0,5]", TestStepElapsedTime: 35, TestStepResult: "PASS"},
+		{TestStepName: "CAN Loopback", TestMeasuredValue: "OK", TestThresholdValue: "OK", TestStepElapsedTime: 60, TestStepResult: "PASS"},
+	}
+}
+
+func finalStepsFor(pcba string) []dto.TestStepDTO {
+	return []dto.TestStepDTO{
+		{TestStepName: "Compare PCBA Serial Number", TestMeasuredValue: pcba, TestStepElapsedTime: 25, TestStepResult: "PASS"},
+		{TestStepName: "Write IMEI", TestMeasuredValue: "860000000000009", TestStepElapsedTime: 310, TestStepResult: "PASS"},
+	}
+}
@@ -0,0 +1,195 @@
+package integration
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/NoroSaroyan/log-parser/internal/app"
+	"github.com/NoroSaroyan/log-parser/internal/config"
+	"github.com/NoroSaroyan/log-parser/internal/domain/models/dto"
+	"github.com/NoroSaroyan/log-parser/internal/infrastructure/database/memory"
+	"github.com/NoroSaroyan/log-parser/internal/infrastructure/logger"
+	"github.com/NoroSaroyan/log-parser/internal/services/auth"
+)
+
+// logToFile initializes the logger from cfg writing to a file in a
+// temporary directory and returns the path of the file.
+func logToFile(t *testing.T, cfg config.LoggerConfig) string {
+	t.Helper()
+
+	cfg.Output = config.Sinks{"file"}
+	cfg.File.Path = filepath.Join(t.TempDir(), "logs", "app.log")
+	if err := logger.Init(cfg); err != nil {
+		t.Fatalf("Init: %v", err)
+	}
+	t.Cleanup(func() { _ = logger.InitLoggerWithWriter("ERROR", io.Discard) })
+	return cfg.File.Path
+}
+
+// readLines returns the lines of the file at path, decompressed if it is a
+// .gz file.
+func readLines(t *testing.T, path string) []string {
+	t.Helper()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("ReadFile: %v", err)
+	}
+	if strings.HasSuffix(path, ".gz") {
+		zr, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			t.Fatalf("%s: %v", path, err)
+		}
+		if data, err = io.ReadAll(zr); err != nil {
+			t.Fatalf("%s: %v", path, err)
+		}
+	}
+	var lines []string
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	sc.Buffer(nil, 1<<20)
+	for sc.Scan() {
+		lines = append(lines, sc.Text())
+	}
+	return lines
+}
+
+func TestLoggerJSONFileRotation(t *testing.T) {
+	cfg := config.Default().Logger
+	cfg.Format = "json"
+	cfg.File.MaxSizeMB = 1
+	cfg.File.MaxBackups = 2
+	cfg.File.Compress = true
+	path := logToFile(t, cfg)
+
+	// About 4 MB of lines: three rotations at least.
+	padding := strings.Repeat("x", 1000)
+	for i := 0; i < 4000; i++ {
+		logger.Info("Parsed block", logger.WithFields(map[string]interface{}{"n": i, "padding": padding}))
+	}
+	if err := logger.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	entries, err := os.ReadDir(filepath.Dir(path))
+	if err != nil {
+		t.Fatalf("ReadDir: %v", err)
+	}
+	var backups []string
+	for _, e := range entries {
+		if e.Name() != "app.log" {
+			backups = append(backups, e.Name())
+		}
+	}
+	sort.Strings(backups)
+	if len(backups) != 2 || !strings.HasSuffix(backups[0], ".log.gz") || !strings.HasSuffix(backups[1], ".log.gz") {
+		t.Fatalf("backups = %v, want the 2 newest compressed", backups)
+	}
+
+	var last int
+	for _, name := range append(backups, "app.log") {
+		file := filepath.Join(filepath.Dir(path), name)
+		if info, _ := os.Stat(file); !strings.HasSuffix(name, ".gz") && info.Size() > 1<<20 {
+			t.Errorf("%s has %d bytes, want at most 1 MB", name, info.Size())
+		}
+		for _, line := range readLines(t, file) {
+			var entry struct {
+				Level, Msg, Caller string
+				Fields             struct{ N int }
+			}
+			if err := json.Unmarshal([]byte(line), &entry); err != nil {
+				t.Fatalf("%s: line %q is not JSON: %v", name, line, err)
+			}
+			if entry.Level != "info" || entry.Msg != "Parsed block" || !strings.HasPrefix(entry.Caller, "integration/logger_test.go:") {
+				t.Fatalf("%s: entry = %+v", name, entry)
+			}
+			last = entry.Fields.N
+		}
+	}
+	if last != 3999 {
+		t.Errorf("last line logged n = %d, want 3999", last)
+	}
+}
+
+func TestLoggerPackageLevels(t *testing.T) {
+	cfg := config.Default().Logger
+	cfg.Level = "error"
+	cfg.Packages = map[string]string{"tests/integration": "debug"}
+	path := logToFile(t, cfg)
+
+	srv := newServer(app.InitializeInMemoryApp(memory.NewStore()))
+	defer srv.Close()
+	put := func(body string) (int, dto.LogLevelsDTO) {
+		t.Helper()
+		req, _ := http.NewRequest(http.MethodPut, srv.URL+"/api/v1/admin/log-level", strings.NewReader(body))
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("PUT log-level: %v", err)
+		}
+		defer resp.Body.Close()
+		var levels dto.LogLevelsDTO
+		json.NewDecoder(resp.Body).Decode(&levels)
+		return resp.StatusCode, levels
+	}
+
+	logger.Debug("debug from the test package")
+	var levels dto.LogLevelsDTO
+	if code := get(t, srv, "/api/v1/admin/log-level", &levels); code != http.StatusOK ||
+		levels.Level != "error" || levels.Packages["tests/integration"] != "debug" {
+		t.Fatalf("GET log-level = %d %+v", code, levels)
+	}
+
+	// The change replaces the overrides: the test package is back at error.
+	code, levels := put(`{"Level": "ERROR", "Packages": {"handlers/api/v1": "warn"}}`)
+	if code != http.StatusOK || levels.Level != "error" || len(levels.Packages) != 1 || levels.Packages["handlers/api/v1"] != "warn" {
+		t.Fatalf("PUT log-level = %d %+v", code, levels)
+	}
+	logger.Debug("hidden debug from the test package")
+	logger.Warn("hidden warning from the test package")
+	logger.Error("error from the test package")
+
+	// Its own warning is not logged at error level.
+	if code, _ := put(`{"Level": "error"}`); code != http.StatusOK {
+		t.Fatalf("PUT log-level = %d", code)
+	}
+	for _, body := range []string{`{"Level": "loud"}`, `{"Level": "info", "Packages": {"parser": "chatty"}}`, `{"Level": "info", "Verbose": true}`, `[]`} {
+		if code, _ := put(body); code != http.StatusBadRequest {
+			t.Errorf("PUT %s: status = %d, want 400", body, code)
+		}
+	}
+	if root, packages := logger.Levels(); root != "error" || len(packages) != 0 {
+		t.Errorf("levels after invalid changes = %s %v", root, packages)
+	}
+
+	logs := strings.Join(readLines(t, path), "\n")
+	for _, want := range []string{"debug from the test package", "error from the test package"} {
+		if !strings.Contains(logs, want) {
+			t.Errorf("log does not contain %q:\n%s", want, logs)
+		}
+	}
+	if strings.Contains(logs, "hidden") {
+		t.Errorf("log contains lines below the level of their package:\n%s", logs)
+	}
+	if n := strings.Count(logs, "Log levels changed"); n != 1 {
+		t.Errorf("%d level changes logged, want the one at warn level:\n%s", n, logs)
+	}
+}
+
+func TestLogLevelEndpointRequiresAdmin(t *testing.T) {
+	svc, _, srv := authSetup(t)
+
+	for role, want := range map[auth.Role]int{auth.RoleEngineer: http.StatusForbidden, auth.RoleAdmin: http.StatusOK} {
+		key := createKey(t, svc, string(role)+"-key", role)
+		if code := getWith(t, srv, "/api/v1/admin/log-level", "X-API-Key", key, nil); code != want {
+			t.Errorf("%s: status = %d, want %d", role, code, want)
+		}
+	}
+}
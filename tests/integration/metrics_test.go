@@ -0,0 +1,153 @@
+package integration
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
+	"github.com/prometheus/common/model"
+
+	"github.com/NoroSaroyan/log-parser/internal/app"
+	apidto "github.com/NoroSaroyan/log-parser/internal/domain/models/dto"
+	"github.com/NoroSaroyan/log-parser/internal/infrastructure/database/memory"
+	"github.com/NoroSaroyan/log-parser/internal/infrastructure/metrics"
+)
+
+// scrape parses the metrics served on /metrics with the exposition format
+// parser and returns the samples by series, e.g.
+// `logparser_ingest_groups_total{result="ok"}`. Histograms are flattened into
+// their _bucket, _sum and _count series.
+func scrape(t *testing.T, srv *httptest.Server) map[string]float64 {
+	t.Helper()
+
+	resp, err := http.Get(srv.URL + metrics.DefaultPath)
+	if err != nil {
+		t.Fatalf("GET /metrics: %v", err)
+	}
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); !strings.HasPrefix(ct, "text/plain; version=0.0.4") {
+		t.Fatalf("Content-Type = %q, want the text exposition format", ct)
+	}
+
+	parser := expfmt.NewTextParser(model.UTF8Validation)
+	families, err := parser.TextToMetricFamilies(resp.Body)
+	if err != nil {
+		t.Fatalf("invalid exposition format: %v", err)
+	}
+	samples := map[string]float64{}
+	for name, f := range families {
+		for _, m := range f.GetMetric() {
+			var labels []string
+			for _, l := range m.GetLabel() {
+				labels = append(labels, fmt.Sprintf("%s=%q", l.GetName(), l.GetValue()))
+			}
+			series := func(suffix string, extra ...string) string {
+				all := append(append([]string{}, labels...), extra...)
+				if len(all) == 0 {
+					return name + suffix
+				}
+				return name + suffix + "{" + strings.Join(all, ",") + "}"
+			}
+			switch f.GetType() {
+			case dto.MetricType_COUNTER:
+				samples[series("")] = m.GetCounter().GetValue()
+			case dto.MetricType_GAUGE:
+				samples[series("")] = m.GetGauge().GetValue()
+			case dto.MetricType_HISTOGRAM:
+				h := m.GetHistogram()
+				for _, b := range h.GetBucket() {
+					samples[series("_bucket", fmt.Sprintf("le=%q", strconv.FormatFloat(b.GetUpperBound(), 'g', -1, 64)))] = float64(b.GetCumulativeCount())
+				}
+				samples[series("_bucket", `le="+Inf"`)] = float64(h.GetSampleCount())
+				samples[series("_sum")] = h.GetSampleSum()
+				samples[series("_count")] = float64(h.GetSampleCount())
+			}
+		}
+	}
+	return samples
+}
+
+func TestMetrics(t *testing.T) {
+	application := app.InitializeInMemoryApp(memory.NewStore())
+	srv := newServer(application)
+	defer srv.Close()
+
+	// Counters are process-wide, so the test compares before and after.
+	before := scrape(t, srv)
+
+	var job apidto.IngestionJobDTO
+	if resp := upload(t, srv, "fixture.log", []byte(fixtureLog(t)), &job); resp.StatusCode != http.StatusAccepted {
+		t.Fatalf("upload status = %d, want 202", resp.StatusCode)
+	}
+	if done := waitForJob(t, srv, job.ID); done.Status != "succeeded" {
+		t.Fatalf("job = %+v, want succeeded", done)
+	}
+	get(t, srv, "/api/v1/devices/"+completePCBA, nil)
+	get(t, srv, "/api/v1/devices/"+bug1PCBA, nil)
+	get(t, srv, "/no/such/route", nil)
+
+	after := scrape(t, srv)
+	delta := func(series string) float64 { return after[series] - before[series] }
+
+	want := map[string]float64{
+		`logparser_ingest_blocks_relevant_total`:                            9,
+		`logparser_ingest_payloads_total{type="download"}`:                  1,
+		`logparser_ingest_payloads_total{type="station_pcba"}`:              1,
+		`logparser_ingest_payloads_total{type="station_final"}`:             2,
+		`logparser_ingest_payloads_total{type="test_steps"}`:                5,
+		`logparser_ingest_step_arrays_total{match="same_type"}`:             3,
+		`logparser_ingest_step_arrays_total{match="different_type"}`:        1,
+		`logparser_ingest_step_arrays_total{match="orphan"}`:                1,
+		`logparser_ingest_groups_total{result="ok"}`:                        2,
+		`logparser_ingest_groups_total{result="failed"}`:                    0,
+		`logparser_ingest_rows_inserted_total{table="download_info"}`:       1,
+		`logparser_ingest_rows_inserted_total{table="logistic_data"}`:       3,
+		`logparser_ingest_rows_inserted_total{table="test_station_record"}`: 3,
+
+		`logparser_http_requests_total{method="GET",route="/api/v1/devices/{pcba}",status="200"}`:    2,
+		`logparser_http_request_duration_seconds_count{method="GET",route="/api/v1/devices/{pcba}"}`: 2,
+		`logparser_http_requests_total{method="POST",route="/api/v1/ingestions",status="202"}`:       1,
+		`logparser_http_requests_total{method="GET",route="unmatched",status="404"}`:                 1,
+	}
+	for series, n := range want {
+		if got := delta(series); got != n {
+			t.Errorf("%s increased by %v, want %v", series, got, n)
+		}
+	}
+	if delta(`logparser_ingest_blocks_extracted_total`) < 9 {
+		t.Errorf("blocks extracted increased by %v, want at least the 9 payloads", delta(`logparser_ingest_blocks_extracted_total`))
+	}
+	if delta(`logparser_ingest_rows_inserted_total{table="test_step"}`) == 0 {
+		t.Error("no test_step rows counted")
+	}
+	bucket := `logparser_http_request_duration_seconds_bucket{method="GET",route="/api/v1/devices/{pcba}",le="+Inf"}`
+	if delta(bucket) != 2 {
+		t.Errorf("%s increased by %v, want 2", bucket, delta(bucket))
+	}
+}
+
+func TestDBStatsMetrics(t *testing.T) {
+	metrics.RegisterDBStats(func() sql.DBStats {
+		return sql.DBStats{MaxOpenConnections: 25, OpenConnections: 4, InUse: 3, Idle: 1, WaitCount: 7}
+	})
+	_, srv := setup(t)
+
+	samples := scrape(t, srv)
+	for series, want := range map[string]float64{
+		"logparser_db_max_open_connections": 25,
+		"logparser_db_open_connections":     4,
+		"logparser_db_in_use_connections":   3,
+		"logparser_db_idle_connections":     1,
+		"logparser_db_wait_count_total":     7,
+	} {
+		if got, ok := samples[series]; !ok || got != want {
+			t.Errorf("%s = %v (present %v), want %v", series, got, ok, want)
+		}
+	}
+}
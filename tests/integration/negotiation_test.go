@@ -0,0 +1,222 @@
+package integration
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/NoroSaroyan/log-parser/internal/domain/models/dto"
+)
+
+// getAs requests path with the given Accept header and returns the response
+// with its body read.
+func getAs(t *testing.T, srv *httptest.Server, path, accept string) (*http.Response, string) {
+	t.Helper()
+
+	req, err := http.NewRequest(http.MethodGet, srv.URL+path, nil)
+	if err != nil {
+		t.Fatalf("NewRequest: %v", err)
+	}
+	if accept != "" {
+		req.Header.Set("Accept", accept)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("GET %s: %v", path, err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("read %s: %v", path, err)
+	}
+	return resp, string(body)
+}
+
+// readCSV parses body into a header and rows keyed by column.
+func readCSV(t *testing.T, body string) ([]string, []map[string]string) {
+	t.Helper()
+
+	records, err := csv.NewReader(strings.NewReader(body)).ReadAll()
+	if err != nil {
+		t.Fatalf("invalid CSV: %v\n%s", err, body)
+	}
+	if len(records) == 0 {
+		t.Fatal("CSV without header")
+	}
+	var rows []map[string]string
+	for _, rec := range records[1:] {
+		row := map[string]string{}
+		for i, col := range records[0] {
+			row[col] = rec[i]
+		}
+		rows = append(rows, row)
+	}
+	return records[0], rows
+}
+
+func TestStationEndpointAsCSV(t *testing.T) {
+	_, srv := setup(t)
+
+	resp, body := getAs(t, srv, "/api/v1/pcba?pcbanumber="+completePCBA, "text/csv")
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status = %d, want 200", resp.StatusCode)
+	}
+	if ct := resp.Header.Get("Content-Type"); !strings.HasPrefix(ct, "text/csv") {
+		t.Errorf("Content-Type = %q, want text/csv", ct)
+	}
+
+	header, rows := readCSV(t, body)
+	for _, col := range []string{"TestStation", "IsAllPassed", "LogisticData.PCBANumber", "TestSteps.TestStepName", "TestSteps.TestMeasuredValue"} {
+		if !slices.Contains(header, col) {
+			t.Errorf("header %v lacks %s", header, col)
+		}
+	}
+	// One row per step, the station columns repeated on each.
+	if len(rows) != 4 {
+		t.Fatalf("got %d rows, want 4", len(rows))
+	}
+	for _, row := range rows {
+		if row["TestStation"] != "PCBA" || row["LogisticData.PCBANumber"] != completePCBA {
+			t.Errorf("station columns not repeated: %v", row)
+		}
+	}
+	if rows[2]["TestSteps.TestStepName"] != "Current Check" || rows[2]["TestSteps.TestMeasuredValue"] != "2" {
+		t.Errorf("unexpected third step row: %v", rows[2])
+	}
+}
+
+func TestDevicesAsNDJSON(t *testing.T) {
+	_, srv := setup(t)
+
+	resp, body := getAs(t, srv, "/api/v1/devices", "application/x-ndjson")
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status = %d, want 200", resp.StatusCode)
+	}
+	if ct := resp.Header.Get("Content-Type"); ct != "application/x-ndjson" {
+		t.Errorf("Content-Type = %q", ct)
+	}
+	if total := resp.Header.Get("X-Total-Count"); total != "2" {
+		t.Errorf("X-Total-Count = %q, want 2", total)
+	}
+
+	lines := strings.Split(strings.TrimSpace(body), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("got %d lines, want 2:\n%s", len(lines), body)
+	}
+	var got []string
+	for _, line := range lines {
+		var d dto.DeviceDTO
+		if err := json.Unmarshal([]byte(line), &d); err != nil {
+			t.Fatalf("line %q: %v", line, err)
+		}
+		got = append(got, d.PCBANumber)
+	}
+	if !slices.Contains(got, completePCBA) || !slices.Contains(got, bug1PCBA) {
+		t.Errorf("devices = %v", got)
+	}
+}
+
+func TestPCBANumbersStreamed(t *testing.T) {
+	_, srv := setup(t)
+
+	var list dto.PCBANumbersResponse
+	if code := get(t, srv, "/api/v1/pcbanumbers", &list); code != http.StatusOK {
+		t.Fatalf("JSON status = %d, want 200", code)
+	}
+	slices.Sort(list.PCBANumbers)
+
+	resp, body := getAs(t, srv, "/api/v1/pcbanumbers", "text/csv")
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("CSV status = %d, want 200", resp.StatusCode)
+	}
+	header, rows := readCSV(t, body)
+	var got []string
+	for _, row := range rows {
+		got = append(got, row["PCBANumber"])
+	}
+	slices.Sort(got)
+	if !slices.Equal(header, []string{"PCBANumber"}) || !slices.Equal(got, list.PCBANumbers) {
+		t.Errorf("CSV = %v %v, want PCBANumber %v", header, got, list.PCBANumbers)
+	}
+
+	resp, body = getAs(t, srv, "/api/v1/pcbanumbers", "application/x-ndjson")
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Vary") != "Accept" {
+		t.Fatalf("NDJSON status = %d, Vary = %q", resp.StatusCode, resp.Header.Get("Vary"))
+	}
+	got = nil
+	for _, line := range strings.Split(strings.TrimSpace(body), "\n") {
+		var row struct{ PCBANumber string }
+		if err := json.Unmarshal([]byte(line), &row); err != nil {
+			t.Fatalf("line %q: %v", line, err)
+		}
+		got = append(got, row.PCBANumber)
+	}
+	slices.Sort(got)
+	if !slices.Equal(got, list.PCBANumbers) {
+		t.Errorf("NDJSON = %v, want %v", got, list.PCBANumbers)
+	}
+}
+
+func TestStepStatsAsCSV(t *testing.T) {
+	_, srv := setup(t)
+
+	resp, body := getAs(t, srv, "/api/v1/analytics/steps/Current%20Check?bins=4", "text/csv")
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status = %d, want 200", resp.StatusCode)
+	}
+	header, rows := readCSV(t, body)
+	for _, col := range []string{"StepName", "Percentiles.p50", "Capability.USL", "Histogram.Lower", "Histogram.Count"} {
+		if !slices.Contains(header, col) {
+			t.Errorf("header %v lacks %s", header, col)
+		}
+	}
+	// One row per histogram bin.
+	var stats dto.StepStatsDTO
+	get(t, srv, "/api/v1/analytics/steps/Current%20Check?bins=4", &stats)
+	if len(rows) == 0 || len(rows) != len(stats.Histogram) {
+		t.Fatalf("got %d rows, want one per bin of %+v", len(rows), stats.Histogram)
+	}
+	if rows[0]["StepName"] != "Current Check" || rows[0]["Percentiles.p50"] != "2" {
+		t.Errorf("unexpected first row: %v", rows[0])
+	}
+}
+
+func TestAcceptNegotiation(t *testing.T) {
+	_, srv := setup(t)
+	path := "/api/v1/analytics/errors"
+
+	cases := []struct {
+		accept, want string
+	}{
+		{"", "application/json"},
+		{"*/*", "application/json"},
+		{"text/html", "application/json"},
+		{"text/csv;q=0.5, application/x-ndjson", "application/x-ndjson"},
+		{"application/json;q=0.2, text/csv", "text/csv"},
+	}
+	for _, c := range cases {
+		resp, _ := getAs(t, srv, path, c.accept)
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("Accept %q: status = %d", c.accept, resp.StatusCode)
+		}
+		if ct := resp.Header.Get("Content-Type"); !strings.HasPrefix(ct, c.want) {
+			t.Errorf("Accept %q: Content-Type = %q, want %s", c.accept, ct, c.want)
+		}
+		if resp.Header.Get("Vary") != "Accept" {
+			t.Errorf("Accept %q: Vary = %q", c.accept, resp.Header.Get("Vary"))
+		}
+	}
+
+	// An empty result is still a CSV with its header.
+	_, body := getAs(t, srv, "/api/v1/analytics/errors/NOPE", "text/csv")
+	header, rows := readCSV(t, body)
+	if len(rows) != 0 || !slices.Contains(header, "PCBANumber") {
+		t.Errorf("empty drill-down: header %v, %d rows", header, len(rows))
+	}
+}
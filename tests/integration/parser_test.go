@@ -1 +1,296 @@
+// Package integration contains end-to-end tests that run the full ingestion
+// pipeline (log text → extraction → parsing → grouping → dispatch) against the
+// in-memory repositories and then query the result through the v1 HTTP API.
 package integration
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/NoroSaroyan/log-parser/internal/app"
+	"github.com/NoroSaroyan/log-parser/internal/domain/models/dto"
+	v1 "github.com/NoroSaroyan/log-parser/internal/handlers/api/v1"
+	"github.com/NoroSaroyan/log-parser/internal/infrastructure/database/memory"
+	"github.com/NoroSaroyan/log-parser/internal/infrastructure/metrics"
+	"github.com/NoroSaroyan/log-parser/internal/infrastructure/tracing"
+	"github.com/NoroSaroyan/log-parser/internal/services/dispatcher"
+	"github.com/NoroSaroyan/log-parser/internal/services/health"
+	"github.com/NoroSaroyan/log-parser/internal/services/ingestion"
+	"github.com/NoroSaroyan/log-parser/internal/services/pipeline"
+)
+
+const (
+	completePCBA = "H8444A11100T32645382"
+	bug1PCBA     = "H8444A11100T32343298"
+	orphanPCBA   = "H8444A11100T32444111"
+)
+
+// Sensitive LogisticData values of every station record in fixtureLog.
+const (
+	fixtureIMSI   = "460001234567890"
+	fixtureICCID  = "8986011234567890123"
+	fixturePhone  = "+8613800138000"
+	fixtureBLEKey = "BLEK-7Q4Z9X"
+)
+
+// fixtureLog returns a log containing one complete device, one device hit by
+// Bug #1 (PCBA steps without a PCBA station record) and one orphan step array.
+func fixtureLog(t *testing.T) string {
+	return newLogBuilder(t).
+		noise("Apr 14 05:00:00", "service started").
+		download("Apr 14 05:10:00", downloadFor(completePCBA)).
+		steps("Apr 14 05:44:00", pcbaStepsFor(completePCBA)).
+		station("Apr 14 05:44:09", stationFor("PCBA", completePCBA, "2026-04-14 05:44:08", true, "")).
+		steps("Apr 14 07:12:00", finalStepsFor(completePCBA)).
+		station("Apr 14 07:12:30", stationFor("Final", completePCBA, "2026-04-14 07:12:29", true, "")).
+		steps("Apr 14 08:00:00", pcbaStepsFor(bug1PCBA)).
+		steps("Apr 14 09:30:00", finalStepsFor(bug1PCBA)).
+		station("Apr 14 09:30:10", stationFor("Final", bug1PCBA, "2026-04-14 09:30:09", false, "F202")).
+		steps("Apr 14 10:00:00", pcbaStepsFor(orphanPCBA)).
+		noise("Apr 14 10:05:00", "POST /v1/addpalletgroup").
+		String()
+}
+
+// ingest runs the same stages as the CLI process mode over the given log text.
+func ingest(t *testing.T, application *app.App, logText string) dispatcher.DispatchReport {
+	t.Helper()
+
+	result, err := pipeline.Parse("fixture.log", []byte(logText))
+	if err != nil {
+		t.Fatalf("pipeline.Parse: %v", err)
+	}
+
+	report, err := newDispatcher(application).DispatchGroups(context.Background(), result.Groups)
+	if err != nil {
+		t.Fatalf("DispatchGroups: %v", err)
+	}
+	return report
+}
+
+func newDispatcher(application *app.App) dispatcher.DispatcherService {
+	return dispatcher.NewDispatcherService(
+		application.DownloadInfoService,
+		application.LogisticService,
+		application.TestStationService,
+		application.TestStepService,
+	)
+}
+
+// newServer wires the v1 API exactly like cmd/api does.
+func newServer(application *app.App) *httptest.Server {
+	r := chi.NewRouter()
+	r.Use(tracing.Middleware)
+	r.Use(metrics.Middleware)
+	r.Use(v1.RequestLogger)
+	r.Handle(metrics.DefaultPath, metrics.Handler())
+	ingestionService := ingestion.NewIngestionService(ingestion.Config{}, newDispatcher(application),
+		application.ValidationService, application.ConsistencyService)
+	v1.RegisterHealth(r, application.HealthService.With(health.IngestionBacklog(ingestionService)))
+	r.Route("/api/v1", func(r chi.Router) {
+		v1.RegisterAPIV1(r, v1.Services{
+			DownloadInfo: application.DownloadInfoService,
+			Logistic:     application.LogisticService,
+			TestStation:  application.TestStationService,
+			TestStep:     application.TestStepService,
+			Consistency:  application.ConsistencyService,
+			Device:       application.DeviceService,
+			Analytics:    application.AnalyticsService,
+			Auth:         application.AuthService,
+			Ingestion:    ingestionService,
+		})
+	})
+	return httptest.NewServer(r)
+}
+
+func get(t *testing.T, srv *httptest.Server, path string, out interface{}) int {
+	t.Helper()
+
+	resp, err := http.Get(srv.URL + path)
+	if err != nil {
+		t.Fatalf("GET %s: %v", path, err)
+	}
+	defer resp.Body.Close()
+
+	if out != nil && resp.StatusCode == http.StatusOK {
+		if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+			t.Fatalf("decode %s: %v", path, err)
+		}
+	}
+	return resp.StatusCode
+}
+
+func setup(t *testing.T) (*memory.Store, *httptest.Server) {
+	t.Helper()
+
+	store := memory.NewStore()
+	application := app.InitializeInMemoryApp(store)
+	ingest(t, application, fixtureLog(t))
+
+	srv := newServer(application)
+	t.Cleanup(srv.Close)
+	return store, srv
+}
+
+func TestPipelineStoresExpectedRows(t *testing.T) {
+	store, _ := setup(t)
+
+	got := store.Counts()
+	want := map[string]int{
+		"download_info":       1,
+		"logistic_data":       3, // PCBA + Final for the complete device, Final for the Bug #1 device
+		"test_station_record": 3,
+		"test_step":           4 + 2 + 4, // complete device: PCBA + Final steps; Bug #1 device: first array only
+	}
+	for table, n := range want {
+		if got[table] != n {
+			t.Errorf("%s: got %d rows, want %d", table, got[table], n)
+		}
+	}
+}
+
+func TestDownloadEndpoint(t *testing.T) {
+	_, srv := setup(t)
+
+	var d dto.DownloadInfoDTO
+	if code := get(t, srv, "/api/v1/download?pcbanumber="+completePCBA, &d); code != http.StatusOK {
+		t.Fatalf("status = %d, want 200", code)
+	}
+	if d.TcuPCBANumber != completePCBA || d.FlashElapsedTime != 95 {
+		t.Errorf("unexpected download info: %+v", d)
+	}
+
+	if code := get(t, srv, "/api/v1/download?pcbanumber="+bug1PCBA, nil); code != http.StatusNotFound {
+		t.Errorf("device without download: status = %d, want 404", code)
+	}
+	if code := get(t, srv, "/api/v1/download", nil); code != http.StatusBadRequest {
+		t.Errorf("missing pcbanumber: status = %d, want 400", code)
+	}
+}
+
+func TestStationEndpoints(t *testing.T) {
+	_, srv := setup(t)
+
+	var pcba []dto.TestStationWithSteps
+	if code := get(t, srv, "/api/v1/pcba?pcbanumber="+completePCBA, &pcba); code != http.StatusOK {
+		t.Fatalf("/pcba status = %d, want 200", code)
+	}
+	if len(pcba) != 1 {
+		t.Fatalf("/pcba returned %d records, want 1", len(pcba))
+	}
+	if pcba[0].TestStation != "PCBA" || pcba[0].LogisticData.PCBANumber != completePCBA {
+		t.Errorf("unexpected PCBA record: %+v", pcba[0].TestStationRecordDTO)
+	}
+	if len(pcba[0].TestSteps) != 4 || pcba[0].TestSteps[1].TestStepName != "PCBA Scan" {
+		t.Errorf("unexpected PCBA steps: %+v", pcba[0].TestSteps)
+	}
+	// Numeric measured values are stored as TEXT and come back as strings.
+	if pcba[0].TestSteps[2].TestMeasuredValue != "2" {
+		t.Errorf("measured value = %#v, want \"2\"", pcba[0].TestSteps[2].TestMeasuredValue)
+	}
+
+	var final []dto.TestStationWithSteps
+	if code := get(t, srv, "/api/v1/final?pcbanumber="+completePCBA, &final); code != http.StatusOK {
+		t.Fatalf("/final status = %d, want 200", code)
+	}
+	if len(final) != 1 || len(final[0].TestSteps) != 2 {
+		t.Fatalf("unexpected Final response: %+v", final)
+	}
+	if final[0].TestSteps[0].TestStepName != "Compare PCBA Serial Number" {
+		t.Errorf("Final steps paired with wrong array: %+v", final[0].TestSteps)
+	}
+}
+
+func TestBug1DeviceDoesNotAbortFile(t *testing.T) {
+	_, srv := setup(t)
+
+	var final []dto.TestStationWithSteps
+	if code := get(t, srv, "/api/v1/final?pcbanumber="+bug1PCBA, &final); code != http.StatusOK {
+		t.Fatalf("/final status = %d, want 200", code)
+	}
+	if len(final) != 1 || final[0].IsAllPassed || final[0].ErrorCodes != "F202" {
+		t.Errorf("unexpected Final record for Bug #1 device: %+v", final)
+	}
+	if code := get(t, srv, "/api/v1/pcba?pcbanumber="+bug1PCBA, nil); code != http.StatusNotFound {
+		t.Errorf("/pcba for Bug #1 device: status = %d, want 404", code)
+	}
+}
+
+func TestOrphanStepsAreNotStored(t *testing.T) {
+	store, _ := setup(t)
+
+	rows, err := memory.NewLogisticDataRepository(store, nil).GetAllByPCBANumber(context.Background(), orphanPCBA)
+	if err != nil {
+		t.Fatalf("GetAllByPCBANumber: %v", err)
+	}
+	if len(rows) != 0 {
+		t.Errorf("orphan device has %d logistic_data rows, want 0", len(rows))
+	}
+}
+
+func TestPCBANumbersEndpoint(t *testing.T) {
+	_, srv := setup(t)
+
+	var resp dto.PCBANumbersResponse
+	if code := get(t, srv, "/api/v1/pcbanumbers", &resp); code != http.StatusOK {
+		t.Fatalf("status = %d, want 200", code)
+	}
+	sort.Strings(resp.PCBANumbers)
+	want := []string{completePCBA, bug1PCBA}
+	sort.Strings(want)
+	if strings.Join(resp.PCBANumbers, ",") != strings.Join(want, ",") {
+		t.Errorf("PCBANumbers = %v, want %v", resp.PCBANumbers, want)
+	}
+}
+
+func TestMemoryRepositoryNotFoundSemantics(t *testing.T) {
+	store, _ := setup(t)
+	ctx := context.Background()
+
+	logisticRepo := memory.NewLogisticDataRepository(store, nil)
+	if _, err := logisticRepo.GetIDByPCBANumber(ctx, "unknown"); err == nil {
+		t.Error("GetIDByPCBANumber: expected sql.ErrNoRows for unknown PCBA")
+	}
+	if d, err := logisticRepo.GetByPCBANumber(ctx, "unknown"); d != nil || err != nil {
+		t.Errorf("GetByPCBANumber: got (%v, %v), want (nil, nil)", d, err)
+	}
+
+	downloadRepo := memory.NewDownloadInfoRepository(store)
+	if d, err := downloadRepo.GetByPCBANumber(ctx, "unknown"); d != nil || err != nil {
+		t.Errorf("DownloadInfo GetByPCBANumber: got (%v, %v), want (nil, nil)", d, err)
+	}
+
+	stepRepo := memory.NewTestStepRepository(store)
+	if err := stepRepo.InsertBatch(ctx, nil, 9999); err == nil {
+		t.Error("InsertBatch: expected foreign key error for unknown station record")
+	}
+}
+
+func TestDispatchReportOutcomes(t *testing.T) {
+	application := app.InitializeInMemoryApp(memory.NewStore())
+	report := ingest(t, application, fixtureLog(t))
+
+	if report.GroupsOK != 2 || report.GroupsFailed != 0 {
+		t.Fatalf("groups ok/failed = %d/%d, want 2/0", report.GroupsOK, report.GroupsFailed)
+	}
+
+	outcomes := map[string]dispatcher.GroupOutcome{}
+	for _, o := range report.Outcomes {
+		outcomes[o.PCBA] = o
+	}
+	if o := outcomes[completePCBA]; o.Status != dispatcher.OutcomeOK {
+		t.Errorf("complete device outcome = %+v, want ok", o)
+	}
+	// The PCBA step array is paired positionally with the Final station and
+	// the Final step array is left over.
+	o := outcomes[bug1PCBA]
+	if o.Status != dispatcher.OutcomeTypeMismatch || o.TypeMismatches != 1 || o.UnmatchedStepArrays != 1 {
+		t.Errorf("Bug #1 device outcome = %+v, want type_mismatch with one excess array", o)
+	}
+}
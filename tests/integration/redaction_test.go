@@ -0,0 +1,263 @@
+package integration
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/NoroSaroyan/log-parser/internal/app"
+	"github.com/NoroSaroyan/log-parser/internal/domain/models/dto"
+	"github.com/NoroSaroyan/log-parser/internal/infrastructure/database/memory"
+	"github.com/NoroSaroyan/log-parser/internal/infrastructure/logger"
+	"github.com/NoroSaroyan/log-parser/internal/infrastructure/redaction"
+	"github.com/NoroSaroyan/log-parser/internal/services/auth"
+	"github.com/NoroSaroyan/log-parser/internal/services/pipeline"
+)
+
+// Values the Final station of completePCBA reports in conflictLog, so the
+// conflicts of sensitive fields carry them.
+const (
+	conflictIMSI  = "460009999999999"
+	conflictPhone = "+8613900139000"
+)
+
+// syncBuffer is a bytes.Buffer safe for the concurrent writes of the logger.
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+// captureLogs sends every log line of the test, at debug level, and the
+// output of the standard log package to the returned buffer.
+func captureLogs(t *testing.T) *syncBuffer {
+	t.Helper()
+
+	buf := &syncBuffer{}
+	if err := logger.InitLoggerWithWriter("DEBUG", buf); err != nil {
+		t.Fatalf("InitLoggerWithWriter: %v", err)
+	}
+	previous := log.Writer()
+	log.SetOutput(buf)
+	t.Cleanup(func() {
+		log.SetOutput(previous)
+		_ = logger.InitLoggerWithWriter("ERROR", io.Discard)
+	})
+	return buf
+}
+
+// usePolicy applies rules on top of the default rules for the duration of
+// the test.
+func usePolicy(t *testing.T, rules map[string]redaction.Rule) {
+	t.Helper()
+
+	p, err := redaction.NewPolicy(rules)
+	if err != nil {
+		t.Fatalf("NewPolicy: %v", err)
+	}
+	previous := redaction.Current()
+	redaction.SetPolicy(p)
+	t.Cleanup(func() { redaction.SetPolicy(previous) })
+}
+
+// conflictLog returns a log in which the Final station of completePCBA
+// reports another IMSI and PhoneNumber than its PCBA station.
+func conflictLog(t *testing.T) string {
+	finalRec := stationFor("Final", completePCBA, "2026-04-14 07:12:29", true, "")
+	finalRec.LogisticData.IMSI = conflictIMSI
+	finalRec.LogisticData.PhoneNumber = conflictPhone
+
+	return newLogBuilder(t).
+		download("Apr 14 05:10:00", downloadFor(completePCBA)).
+		steps("Apr 14 05:44:00", pcbaStepsFor(completePCBA)).
+		station("Apr 14 05:44:09", stationFor("PCBA", completePCBA, "2026-04-14 05:44:08", true, "")).
+		steps("Apr 14 07:12:00", finalStepsFor(completePCBA)).
+		station("Apr 14 07:12:30", finalRec).
+		steps("Apr 14 09:30:00", finalStepsFor(bug1PCBA)).
+		station("Apr 14 09:30:10", stationFor("Final", bug1PCBA, "2026-04-14 09:30:09", false, "F202")).
+		String()
+}
+
+// readPaths are requests covering every read endpoint.
+var readPaths = []string{
+	"/api/v1/download?pcbanumber=" + completePCBA,
+	"/api/v1/pcba?pcbanumber=" + completePCBA,
+	"/api/v1/final?pcbanumber=" + completePCBA,
+	"/api/v1/pcbanumbers",
+	"/api/v1/devices",
+	"/api/v1/devices/" + completePCBA,
+	"/api/v1/devices/search?imei=860000000000009",
+	"/api/v1/devices/search?imsi=" + fixtureIMSI,
+	"/api/v1/devices/search?iccid=" + fixtureICCID,
+	"/api/v1/logistic/conflicts",
+	"/api/v1/analytics/yield",
+	"/api/v1/analytics/steps/Current%20Check",
+	"/api/v1/analytics/errors",
+	"/api/v1/analytics/errors/F202",
+}
+
+func TestNoSensitiveValueLeaks(t *testing.T) {
+	logs := captureLogs(t)
+	secrets := []string{fixtureIMSI, fixtureICCID, fixturePhone, fixtureBLEKey, conflictIMSI, conflictPhone}
+
+	application := app.InitializeInMemoryApp(memory.NewStore())
+	srv := newServer(application)
+	defer srv.Close()
+
+	// Ingest through the upload endpoint, which also records conflicts.
+	var job dto.IngestionJobDTO
+	if resp := upload(t, srv, "conflict.log", []byte(conflictLog(t)), &job); resp.StatusCode != http.StatusAccepted {
+		t.Fatalf("upload status = %d, want 202", resp.StatusCode)
+	}
+	if done := waitForJob(t, srv, job.ID); done.Status != "succeeded" {
+		t.Fatalf("job = %+v, want succeeded", done)
+	}
+
+	var conflicts []dto.DeviceLogisticConflictsDTO
+	get(t, srv, "/api/v1/logistic/conflicts?pcbanumber="+completePCBA, &conflicts)
+	if len(conflicts) != 1 || len(conflicts[0].Conflicts) != 2 {
+		t.Fatalf("want the IMSI and PhoneNumber conflicts, got %+v", conflicts)
+	}
+
+	paths := append(readPaths, "/api/v1/ingestions/"+job.ID)
+	for _, path := range paths {
+		for _, accept := range []string{"application/json", "text/csv", "application/x-ndjson"} {
+			resp, body := getAs(t, srv, path, accept)
+			if resp.StatusCode != http.StatusOK {
+				t.Errorf("%s (%s): status = %d", path, accept, resp.StatusCode)
+				continue
+			}
+			for _, secret := range secrets {
+				if strings.Contains(body, secret) {
+					t.Errorf("%s (%s) leaks %q:\n%s", path, accept, secret, body)
+				}
+			}
+		}
+	}
+
+	// Every logger field map, however deeply the value is nested.
+	station := stationFor("PCBA", completePCBA, "2026-04-14 05:44:08", true, "")
+	logger.Debug("Snapshot", logger.WithFields(map[string]interface{}{
+		"dto_snapshot": station.LogisticData,
+		"record":       &station,
+		"nested":       map[string]interface{}{"IMSI": fixtureIMSI, "TcuICCID": fixtureICCID},
+		"PhoneNumber":  fixturePhone,
+	}))
+	logger.Error("Failed", errors.New("boom"), logger.WithField("BlePassworkKey", fixtureBLEKey))
+	logger.Warn("Conflicts", logger.WithField("conflicts", conflicts))
+
+	out := logs.String()
+	if !strings.Contains(out, "dto_snapshot") || !strings.Contains(out, completePCBA) {
+		t.Fatalf("log lines not captured:\n%s", out)
+	}
+	if !strings.Contains(out, "/api/v1/devices/search") || !strings.Contains(out, "imsi=") {
+		t.Fatalf("request log lines not captured:\n%s", out)
+	}
+	for _, secret := range secrets {
+		if strings.Contains(out, secret) {
+			t.Errorf("log leaks %q:\n%s", secret, out)
+		}
+	}
+}
+
+func TestRedactionActions(t *testing.T) {
+	usePolicy(t, map[string]redaction.Rule{
+		"IMSI":           {Action: redaction.ActionHash},
+		"TcuICCID":       {Action: redaction.ActionNone},
+		"BlePassworkKey": {Action: redaction.ActionDrop},
+		"IMEI":           {Action: redaction.ActionMask},
+	})
+	logs := captureLogs(t)
+	_, srv := setup(t)
+
+	var timeline dto.DeviceTimelineDTO
+	get(t, srv, "/api/v1/devices/"+completePCBA, &timeline)
+	d := timeline.LogisticData
+
+	if !strings.HasPrefix(d.IMSI, "sha256:") || d.IMSI == fixtureIMSI {
+		t.Errorf("IMSI = %q, want a hash", d.IMSI)
+	}
+	if d.TcuICCID != fixtureICCID {
+		t.Errorf("TcuICCID = %q, want it in clear with action none", d.TcuICCID)
+	}
+	if d.BlePassworkKey != "" {
+		t.Errorf("BlePassworkKey = %q, want it dropped", d.BlePassworkKey)
+	}
+	if d.IMEI != "***********0009" {
+		t.Errorf("IMEI = %q, want it masked", d.IMEI)
+	}
+	if d.PhoneNumber == fixturePhone || !strings.HasSuffix(d.PhoneNumber, "8000") {
+		t.Errorf("PhoneNumber = %q, want the default mask", d.PhoneNumber)
+	}
+
+	// Hashes are stable, so redacted values can still be correlated.
+	var res dto.DeviceSearchDTO
+	get(t, srv, "/api/v1/devices/search?imsi="+fixtureIMSI, &res)
+	if len(res.Matches) != 2 || res.Matches[0].IMSI != d.IMSI || res.Value != d.IMSI {
+		t.Errorf("search by imsi = %+v, want matches and value hashed as %s", res, d.IMSI)
+	}
+
+	// Dropped keys disappear from log lines.
+	logger.Info("Key", logger.WithFields(map[string]interface{}{"BlePassworkKey": fixtureBLEKey, "pcba": completePCBA}))
+	if out := logs.String(); strings.Contains(out, "BlePassworkKey") || !strings.Contains(out, completePCBA) {
+		t.Errorf("log line keeps dropped key:\n%s", out)
+	}
+
+	if _, err := redaction.NewPolicy(map[string]redaction.Rule{"IMSI": {Action: "scramble"}}); err == nil {
+		t.Error("NewPolicy accepted an unknown action")
+	}
+}
+
+func TestRedactionRevealedByRole(t *testing.T) {
+	svc, _, _ := authSetup(t)
+	ctx := context.Background()
+
+	// Conflict values are revealed like the field they belong to.
+	store := memory.NewStore()
+	application := app.InitializeInMemoryApp(store)
+	application.AuthService = svc
+	logText := conflictLog(t)
+	ingest(t, application, logText)
+	result, err := pipeline.Parse("conflict.log", []byte(logText))
+	if err != nil {
+		t.Fatalf("pipeline.Parse: %v", err)
+	}
+	if err := application.ConsistencyService.CheckGroups(ctx, result.Groups); err != nil {
+		t.Fatalf("CheckGroups: %v", err)
+	}
+	conflictSrv := newServer(application)
+	defer conflictSrv.Close()
+
+	for _, role := range []auth.Role{auth.RoleViewer, auth.RoleEngineer} {
+		key := createKey(t, svc, "conflicts-"+string(role), role)
+		var devices []dto.DeviceLogisticConflictsDTO
+		getWith(t, conflictSrv, "/api/v1/logistic/conflicts", "X-API-Key", key, &devices)
+		if len(devices) != 1 {
+			t.Fatalf("%s: got %+v, want one device", role, devices)
+		}
+		for _, c := range devices[0].Conflicts {
+			clear := c.PCBAValue == fixtureIMSI || c.FinalValue == conflictIMSI ||
+				c.PCBAValue == fixturePhone || c.FinalValue == conflictPhone
+			if clear != (role == auth.RoleEngineer) {
+				t.Errorf("%s: %s conflict in clear = %v: %+v", role, c.Field, clear, c)
+			}
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package integration
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/NoroSaroyan/log-parser/internal/app"
+	"github.com/NoroSaroyan/log-parser/internal/domain/models/dto"
+	"github.com/NoroSaroyan/log-parser/internal/infrastructure/database/memory"
+)
+
+func TestDeviceSearchEndpoint(t *testing.T) {
+	// stationFor gives every device the same identifiers; only completePCBA
+	// gets its own IMEI and ProductSN.
+	pcbaRec := stationFor("PCBA", completePCBA, "2026-04-14 05:44:08", true, "")
+	pcbaRec.LogisticData.IMEI = "860000000000017"
+	pcbaRec.LogisticData.ProductSN = "YCOT1EBG30900FC#"
+	finalRec := stationFor("Final", completePCBA, "2026-04-14 07:12:29", true, "")
+	finalRec.LogisticData.IMEI = pcbaRec.LogisticData.IMEI
+	finalRec.LogisticData.ProductSN = pcbaRec.LogisticData.ProductSN
+
+	logText := newLogBuilder(t).
+		steps("Apr 14 05:44:00", pcbaStepsFor(completePCBA)).
+		station("Apr 14 05:44:09", pcbaRec).
+		steps("Apr 14 07:12:00", finalStepsFor(completePCBA)).
+		station("Apr 14 07:12:30", finalRec).
+		steps("Apr 14 08:00:00", pcbaStepsFor(bug1PCBA)).
+		station("Apr 14 08:00:09", stationFor("PCBA", bug1PCBA, "2026-04-14 08:00:08", true, "")).
+		steps("Apr 14 08:30:00", pcbaStepsFor(finalOnlyPCBA)).
+		station("Apr 14 08:30:09", stationFor("PCBA", finalOnlyPCBA, "2026-04-14 08:30:08", true, "")).
+		String()
+
+	application := app.InitializeInMemoryApp(memory.NewStore())
+	ingest(t, application, logText)
+	srv := newServer(application)
+	defer srv.Close()
+
+	search := func(query string) dto.DeviceSearchDTO {
+		t.Helper()
+		var res dto.DeviceSearchDTO
+		if code := get(t, srv, "/api/v1/devices/search?"+query, &res); code != http.StatusOK {
+			t.Fatalf("/devices/search?%s status = %d, want 200", query, code)
+		}
+		return res
+	}
+
+	byIMEI := search("imei=860000000000017")
+	if byIMEI.Ambiguous || len(byIMEI.Matches) != 1 {
+		t.Fatalf("IMEI search = %+v, want one unambiguous match", byIMEI)
+	}
+	if m := byIMEI.Matches[0]; m.PCBANumber != completePCBA || m.Snapshots != 2 || m.ProductSN != "YCOT1EBG30900FC#" {
+		t.Errorf("IMEI match = %+v, want %s with 2 snapshots", m, completePCBA)
+	}
+
+	if res := search("product_sn=YCOT1EBG30900FC%23"); len(res.Matches) != 1 || res.Matches[0].PCBANumber != completePCBA {
+		t.Errorf("ProductSN search = %+v, want %s", res, completePCBA)
+	}
+
+	// The shared ICCID and BLE MAC map to all three devices.
+	for _, query := range []string{"iccid=8986011234567890123", "ble_mac=aabbccddeeff", "ble_mac=AA-BB-CC-DD-EE-FF"} {
+		if res := search(query); !res.Ambiguous || len(res.Matches) != 3 {
+			t.Errorf("%s: got %d matches (ambiguous %v), want 3 ambiguous matches", query, len(res.Matches), res.Ambiguous)
+		}
+	}
+
+	if res := search("imsi=000000000000000"); res.Ambiguous || len(res.Matches) != 0 {
+		t.Errorf("unknown IMSI: got %+v, want no matches", res)
+	}
+
+	for _, bad := range []string{"", "imei=1&imsi=2", "imei=", "serial=1"} {
+		if code := get(t, srv, "/api/v1/devices/search?"+bad, nil); code != http.StatusBadRequest {
+			t.Errorf("%q: status = %d, want 400", bad, code)
+		}
+	}
+}
@@ -0,0 +1,93 @@
+package integration
+
+import (
+	"math"
+	"net/http"
+	"testing"
+
+	"github.com/NoroSaroyan/log-parser/internal/app"
+	"github.com/NoroSaroyan/log-parser/internal/domain/models/dto"
+	"github.com/NoroSaroyan/log-parser/internal/infrastructure/database/memory"
+)
+
+// stepsMeasuring returns the PCBA steps of pcba with the given DUT Power On voltage.
+func stepsMeasuring(pcba string, voltage interface{}) []dto.TestStepDTO {
+	steps := pcbaStepsFor(pcba)
+	steps[0].TestMeasuredValue = voltage
+	return steps
+}
+
+func TestStepStatsEndpoint(t *testing.T) {
+	logText := newLogBuilder(t).
+		steps("Apr 14 05:44:00", stepsMeasuring(completePCBA, "11.8")).
+		station("Apr 14 05:44:09", stationFor("PCBA", completePCBA, "2026-04-14 05:44:08", true, "")).
+		steps("Apr 14 07:12:00", finalStepsFor(completePCBA)).
+		station("Apr 14 07:12:30", stationFor("Final", completePCBA, "2026-04-14 07:12:29", true, "")).
+		steps("Apr 14 08:00:00", stepsMeasuring(bug1PCBA, 12.0)).
+		station("Apr 14 08:00:09", stationFor("PCBA", bug1PCBA, "2026-04-14 08:00:08", true, "")).
+		steps("Apr 15 09:00:00", stepsMeasuring(flashedPCBA, "12.2")).
+		station("Apr 15 09:00:09", stationFor("PCBA", flashedPCBA, "2026-04-15 09:00:08", true, "")).
+		steps("Apr 15 10:00:00", stepsMeasuring(finalOnlyPCBA, "n/a")).
+		station("Apr 15 10:00:09", stationFor("PCBA", finalOnlyPCBA, "2026-04-15 10:00:08", false, "E101")).
+		String()
+
+	application := app.InitializeInMemoryApp(memory.NewStore())
+	ingest(t, application, logText)
+	srv := newServer(application)
+	defer srv.Close()
+
+	stats := func(path string) dto.StepStatsDTO {
+		t.Helper()
+		var st dto.StepStatsDTO
+		if code := get(t, srv, "/api/v1/analytics/steps/"+path, &st); code != http.StatusOK {
+			t.Fatalf("/analytics/steps/%s status = %d, want 200", path, code)
+		}
+		return st
+	}
+	near := func(got, want float64) bool { return math.Abs(got-want) < 1e-9 }
+
+	st := stats("DUT%20Power%20On?bins=4")
+	if st.StepName != "DUT Power On" || st.Count != 3 {
+		t.Fatalf("stats = %+v, want 3 numeric measurements of DUT Power On", st)
+	}
+	if !near(st.Min, 11.8) || !near(st.Max, 12.2) || !near(st.Mean, 12.0) || !near(st.StdDev, 0.2) {
+		t.Errorf("min/max/mean/stddev = %v/%v/%v/%v, want 11.8/12.2/12.0/0.2", st.Min, st.Max, st.Mean, st.StdDev)
+	}
+	if !near(st.Percentiles["p50"], 12.0) || !near(st.Percentiles["p25"], 11.9) || len(st.Percentiles) != 7 {
+		t.Errorf("percentiles = %v, want p50 12.0 and p25 11.9 among 7", st.Percentiles)
+	}
+	if len(st.Histogram) != 4 || st.Histogram[0].Count != 1 || st.Histogram[3].Count != 1 ||
+		!near(st.Histogram[0].Lower, 11.8) || !near(st.Histogram[3].Upper, 12.2) {
+		t.Errorf("histogram = %+v, want 4 bins over [11.8,12.2] with the extremes in the outer bins", st.Histogram)
+	}
+	c := st.Capability
+	if c == nil || c.LSL == nil || c.USL == nil || c.Cp == nil || c.Cpk == nil || c.Thresholds != 1 {
+		t.Fatalf("capability = %+v, want both limits, Cp and Cpk", c)
+	}
+	if *c.LSL != 11.5 || *c.USL != 12.5 || !near(*c.Cp, 1.0/1.2) || !near(*c.Cpk, 0.5/0.6) {
+		t.Errorf("LSL/USL/Cp/Cpk = %v/%v/%v/%v, want 11.5/12.5/0.833/0.833", *c.LSL, *c.USL, *c.Cp, *c.Cpk)
+	}
+
+	// The filters apply to the station record.
+	if st := stats("DUT%20Power%20On?from=2026-04-15"); st.Count != 1 || st.Mean != 12.2 {
+		t.Errorf("from filter: count %d mean %v, want the measurement of Apr 15 only", st.Count, st.Mean)
+	}
+	// Identical values: no spread, so limits but no Cp/Cpk, and one bin.
+	if st := stats("Current%20Check"); st.Count != 4 || len(st.Histogram) != 1 || st.Capability == nil || st.Capability.Cpk != nil {
+		t.Errorf("Current Check = %+v, want 4 values in one bin and no Cpk", st)
+	}
+	// Text measurements are not numeric.
+	if st := stats("CAN%20Loopback"); st.Count != 0 || st.Capability != nil || len(st.Histogram) != 0 {
+		t.Errorf("CAN Loopback = %+v, want no numeric measurements", st)
+	}
+	// Neither are identifiers without limits, even when all digits.
+	if st := stats("Write%20IMEI"); st.Count != 0 {
+		t.Errorf("Write IMEI = %+v, want no numeric measurements", st)
+	}
+
+	for _, path := range []string{"DUT%20Power%20On?bins=0x", "DUT%20Power%20On?bins=1000", "DUT%20Power%20On?to=tomorrow"} {
+		if code := get(t, srv, "/api/v1/analytics/steps/"+path, nil); code != http.StatusBadRequest {
+			t.Errorf("/analytics/steps/%s status = %d, want 400", path, code)
+		}
+	}
+}
@@ -0,0 +1,144 @@
+package integration
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/NoroSaroyan/log-parser/internal/app"
+	"github.com/NoroSaroyan/log-parser/internal/domain/models/dto"
+	"github.com/NoroSaroyan/log-parser/internal/infrastructure/database/memory"
+	"github.com/NoroSaroyan/log-parser/internal/services/threshold"
+)
+
+func TestThresholdEvaluate(t *testing.T) {
+	tests := []struct {
+		threshold string
+		measured  string
+		want      string
+	}{
+		{"[11.5,12.5]", "12.1", threshold.ResultPass},
+		{"[11.5,12.5]", "12.5", threshold.ResultPass},
+		{"[11.5,12.5)", "12.5", threshold.ResultFail},
+		{"(0,5]", "0", threshold.ResultFail},
+		{"[,5]", "-3", threshold.ResultPass},
+		{"-1~1", "0.5", threshold.ResultPass},
+		{">=3.3", "3.2", threshold.ResultFail},
+		{"<100", "99", threshold.ResultPass},
+		{"!=0", "0", threshold.ResultFail},
+		{"/V[0-9]+\\.[0-9]+/", "V2.11", threshold.ResultPass},
+		{"/V[0-9]+/", "xV2", threshold.ResultFail},
+		{"OK", "ok", threshold.ResultPass},
+		{"5", "5.0", threshold.ResultPass},
+		{"OK", "NG", threshold.ResultFail},
+		{"", "12", ""},
+		{"N/A", "12", ""},
+		{"[0,5]", "open", ""},
+	}
+	for _, tt := range tests {
+		th, err := threshold.Parse(tt.threshold)
+		if err != nil {
+			t.Errorf("Parse(%q): %v", tt.threshold, err)
+			continue
+		}
+		if got := th.Evaluate(tt.measured); got != tt.want {
+			t.Errorf("%q against %q = %q, want %q", tt.measured, tt.threshold, got, tt.want)
+		}
+	}
+
+	for _, bad := range []string{"[5,1]", "[a,b]", ">=x", "/(/", "[1,2,3]"} {
+		if _, err := threshold.Parse(bad); err == nil {
+			t.Errorf("Parse(%q) succeeded, want error", bad)
+		}
+	}
+}
+
+func TestThresholdNumeric(t *testing.T) {
+	tests := []struct {
+		threshold string
+		measured  interface{}
+		want      float64
+		ok        bool
+	}{
+		{"[11.5,12.5]", "12.1", 12.1, true},
+		{"[0,5]", 2, 2, true},
+		{">=3.3", " -.5 ", -0.5, true},
+		{"5", "5.0", 5, true},
+		{"[0,1e6]", "1e5", 1e5, true},
+		// Only the grammar migration 009 backfills with is a number.
+		{"[0,5]", "1e100", 0, false},
+		{"[0,5]", "Inf", 0, false},
+		{"[0,5]", "NaN", 0, false},
+		{"[0,5]", "0x1p-2", 0, false},
+		{"[0,5]", "1_000", 0, false},
+		{"[0,5]", "open", 0, false},
+		// Identifiers are not measured against numeric limits.
+		{"", "860000000000009", 0, false},
+		{"N/A", "12", 0, false},
+		{"/86[0-9]{13}/", "860000000000009", 0, false},
+		{"OK", "1", 0, false},
+		{"[a,b]", "1", 0, false},
+		// Limits count only when they parse; migration 009 splits them alike.
+		{"(,5]", "3", 3, true},
+		{" 1 ~ ", "3", 3, true},
+		{"< 5", "3", 3, true},
+		{"[OK,NG]", "1", 0, false},
+		{"[1,2,3]", "2", 0, false},
+		{"[5,1]", "3", 0, false},
+		{"[1,]]", "3", 0, false},
+		{">=OK", "1", 0, false},
+		{"=>5", "1", 0, false},
+		{">", "1", 0, false},
+		{"abc~def", "1", 0, false},
+		{"1~2~3", "2", 0, false},
+	}
+	for _, tt := range tests {
+		got, ok := threshold.Numeric(dto.TestStepDTO{TestThresholdValue: tt.threshold, TestMeasuredValue: tt.measured})
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("Numeric(%q, %v) = %v, %v, want %v, %v", tt.threshold, tt.measured, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestVerdictMismatchFilter(t *testing.T) {
+	steps := pcbaStepsFor(completePCBA)
+	steps[0].TestMeasuredValue = "13.0" // outside [11.5,12.5] but logged as PASS
+
+	logText := newLogBuilder(t).
+		steps("Apr 14 05:44:00", steps).
+		station("Apr 14 05:44:09", stationFor("PCBA", completePCBA, "2026-04-14 05:44:08", true, "")).
+		String()
+
+	application := app.InitializeInMemoryApp(memory.NewStore())
+	ingest(t, application, logText)
+	srv := newServer(application)
+	defer srv.Close()
+
+	var all []dto.TestStationWithSteps
+	if code := get(t, srv, "/api/v1/pcba?pcbanumber="+completePCBA, &all); code != http.StatusOK {
+		t.Fatalf("/pcba status = %d, want 200", code)
+	}
+	if len(all) != 1 || len(all[0].TestSteps) != 4 {
+		t.Fatalf("unexpected /pcba response: %+v", all)
+	}
+	if s := all[0].TestSteps[2]; s.RecomputedResult != threshold.ResultPass || s.VerdictMismatch {
+		t.Errorf("Current Check: recomputed %q mismatch %v, want PASS without mismatch", s.RecomputedResult, s.VerdictMismatch)
+	}
+
+	var mismatched []dto.TestStationWithSteps
+	if code := get(t, srv, "/api/v1/pcba?pcbanumber="+completePCBA+"&verdict_mismatch=true", &mismatched); code != http.StatusOK {
+		t.Fatalf("/pcba?verdict_mismatch=true status = %d, want 200", code)
+	}
+	if len(mismatched) != 1 || len(mismatched[0].TestSteps) != 1 {
+		t.Fatalf("unexpected filtered response: %+v", mismatched)
+	}
+	if s := mismatched[0].TestSteps[0]; s.TestStepName != "DUT Power On" || s.RecomputedResult != threshold.ResultFail {
+		t.Errorf("unexpected mismatching step: %+v", s)
+	}
+
+	if code := get(t, srv, "/api/v1/final?pcbanumber="+completePCBA+"&verdict_mismatch=true", nil); code != http.StatusNotFound {
+		t.Errorf("/final without mismatches: status = %d, want 404", code)
+	}
+	if code := get(t, srv, "/api/v1/pcba?pcbanumber="+completePCBA+"&verdict_mismatch=maybe", nil); code != http.StatusBadRequest {
+		t.Errorf("invalid verdict_mismatch: status = %d, want 400", code)
+	}
+}
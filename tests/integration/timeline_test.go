@@ -0,0 +1,89 @@
+package integration
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/NoroSaroyan/log-parser/internal/app"
+	"github.com/NoroSaroyan/log-parser/internal/domain/models/dto"
+	"github.com/NoroSaroyan/log-parser/internal/infrastructure/database/memory"
+	"github.com/NoroSaroyan/log-parser/internal/services/device"
+)
+
+const flashedPCBA = "H8444A11100T32999002"
+
+func TestDeviceTimelineEndpoint(t *testing.T) {
+	logText := newLogBuilder(t).
+		download("Apr 14 05:10:00", downloadFor(completePCBA)).
+		// The retest is logged first but finished later.
+		steps("Apr 14 05:50:00", pcbaStepsFor(completePCBA)).
+		station("Apr 14 05:50:09", stationFor("PCBA", completePCBA, "2026-04-14 05:50:08", true, "")).
+		steps("Apr 14 05:44:00", pcbaStepsFor(completePCBA)).
+		station("Apr 14 05:44:09", stationFor("PCBA", completePCBA, "2026-04-14 05:44:08", false, "E101")).
+		steps("Apr 14 07:12:00", finalStepsFor(completePCBA)).
+		station("Apr 14 07:12:30", stationFor("Final", completePCBA, "2026-04-14 07:12:29", true, "")).
+		steps("Apr 14 08:00:00", pcbaStepsFor(bug1PCBA)).
+		station("Apr 14 08:00:09", stationFor("PCBA", bug1PCBA, "2026-04-14 08:00:08", false, "E101")).
+		download("Apr 14 09:00:00", downloadFor(flashedPCBA)).
+		String()
+
+	application := app.InitializeInMemoryApp(memory.NewStore())
+	ingest(t, application, logText)
+	srv := newServer(application)
+	defer srv.Close()
+
+	var tl dto.DeviceTimelineDTO
+	if code := get(t, srv, "/api/v1/devices/"+completePCBA, &tl); code != http.StatusOK {
+		t.Fatalf("/devices/%s status = %d, want 200", completePCBA, code)
+	}
+	if tl.Status != device.StatusFinalPassed || tl.StuckAt != "" {
+		t.Errorf("status = %q (stuck at %q), want %q", tl.Status, tl.StuckAt, device.StatusFinalPassed)
+	}
+	if len(tl.Downloads) != 1 || tl.Downloads[0].TcuPCBANumber != completePCBA {
+		t.Errorf("downloads = %+v, want one flash of %s", tl.Downloads, completePCBA)
+	}
+	if tl.LogisticData.PCBANumber != completePCBA {
+		t.Errorf("logistic data = %+v, want the snapshot of %s", tl.LogisticData, completePCBA)
+	}
+
+	want := []struct {
+		station string
+		attempt int
+		result  string
+	}{
+		{"PCBA", 1, device.ResultFail},
+		{"PCBA", 2, device.ResultPass},
+		{"Final", 1, device.ResultPass},
+	}
+	if len(tl.Sessions) != len(want) {
+		t.Fatalf("got %d sessions, want %d: %+v", len(tl.Sessions), len(want), tl.Sessions)
+	}
+	for i, w := range want {
+		s := tl.Sessions[i]
+		if s.TestStation != w.station || s.Attempt != w.attempt || s.Result != w.result || len(s.TestSteps) == 0 {
+			t.Errorf("session %d = %s #%d %s with %d steps, want %s #%d %s with steps",
+				i, s.TestStation, s.Attempt, s.Result, len(s.TestSteps), w.station, w.attempt, w.result)
+		}
+	}
+	if tl.Sessions[0].ErrorCodes != "E101" {
+		t.Errorf("first session error codes = %q, want E101", tl.Sessions[0].ErrorCodes)
+	}
+
+	statuses := map[string][2]string{
+		bug1PCBA:    {device.StatusStuck, device.StagePCBA},
+		flashedPCBA: {device.StatusFlashed, ""},
+	}
+	for pcba, w := range statuses {
+		var got dto.DeviceTimelineDTO
+		if code := get(t, srv, "/api/v1/devices/"+pcba, &got); code != http.StatusOK {
+			t.Fatalf("/devices/%s status = %d, want 200", pcba, code)
+		}
+		if got.Status != w[0] || got.StuckAt != w[1] {
+			t.Errorf("%s: status %q stuck at %q, want %q at %q", pcba, got.Status, got.StuckAt, w[0], w[1])
+		}
+	}
+
+	if code := get(t, srv, "/api/v1/devices/"+orphanPCBA, nil); code != http.StatusNotFound {
+		t.Errorf("unknown device: status = %d, want 404", code)
+	}
+}
@@ -0,0 +1,286 @@
+package integration
+
+import (
+	"bufio"
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/NoroSaroyan/log-parser/internal/app"
+	"github.com/NoroSaroyan/log-parser/internal/config"
+	"github.com/NoroSaroyan/log-parser/internal/domain/models/dto"
+	"github.com/NoroSaroyan/log-parser/internal/infrastructure/database/memory"
+	"github.com/NoroSaroyan/log-parser/internal/infrastructure/logger"
+	"github.com/NoroSaroyan/log-parser/internal/infrastructure/tracing"
+)
+
+// exportedSpan is a span as written by the stdout and file exporters.
+type exportedSpan struct {
+	Name        string
+	SpanContext struct{ TraceID, SpanID string }
+	Parent      struct{ TraceID, SpanID string }
+	Links       []struct {
+		SpanContext struct{ TraceID, SpanID string }
+	}
+	Attributes []struct {
+		Key   string
+		Value struct{ Value interface{} }
+	}
+	Status struct{ Code string }
+}
+
+func (s exportedSpan) attr(key string) interface{} {
+	for _, a := range s.Attributes {
+		if a.Key == key {
+			return a.Value.Value
+		}
+	}
+	return nil
+}
+
+// traceToFile installs a tracer provider exporting to a file and returns
+// the function flushing it and reading the spans back by name.
+func traceToFile(t *testing.T) func() map[string][]exportedSpan {
+	t.Helper()
+
+	file := filepath.Join(t.TempDir(), "traces.jsonl")
+	cfg := config.Default().Tracing
+	cfg.Enabled, cfg.Exporter, cfg.File = true, tracing.ExporterFile, file
+	shutdown, err := tracing.Init(context.Background(), cfg)
+	if err != nil {
+		t.Fatalf("Init: %v", err)
+	}
+	t.Cleanup(func() { _ = shutdown(context.Background()) })
+
+	return func() map[string][]exportedSpan {
+		t.Helper()
+		if err := shutdown(context.Background()); err != nil {
+			t.Fatalf("shutdown: %v", err)
+		}
+		f, err := os.Open(file)
+		if err != nil {
+			t.Fatalf("Open: %v", err)
+		}
+		defer f.Close()
+		spans := map[string][]exportedSpan{}
+		sc := bufio.NewScanner(f)
+		sc.Buffer(nil, 1<<20)
+		for sc.Scan() {
+			var s exportedSpan
+			if err := json.Unmarshal(sc.Bytes(), &s); err != nil {
+				t.Fatalf("invalid span %s: %v", sc.Text(), err)
+			}
+			spans[s.Name] = append(spans[s.Name], s)
+		}
+		return spans
+	}
+}
+
+func TestTracing(t *testing.T) {
+	spans := traceToFile(t)
+	logs := captureLogs(t)
+	srv := newServer(app.InitializeInMemoryApp(memory.NewStore()))
+	defer srv.Close()
+
+	var job dto.IngestionJobDTO
+	resp := upload(t, srv, "fixture.log", []byte(fixtureLog(t)), &job)
+	if resp.StatusCode != http.StatusAccepted {
+		t.Fatalf("upload status = %d, want 202", resp.StatusCode)
+	}
+	uploadTrace := resp.Header.Get(tracing.TraceIDHeader)
+	if done := waitForJob(t, srv, job.ID); done.Status != "succeeded" {
+		t.Fatalf("job = %+v, want succeeded", done)
+	}
+
+	// A caller's traceparent is continued.
+	const callerTrace = "4bf92f3577b34da6a3ce929d0e0e4736"
+	req, _ := http.NewRequest(http.MethodGet, srv.URL+"/api/v1/devices/"+completePCBA, nil)
+	req.Header.Set("traceparent", "00-"+callerTrace+"-00f067aa0ba902b7-01")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("GET device: %v", err)
+	}
+	io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+	if got := resp.Header.Get(tracing.TraceIDHeader); got != callerTrace {
+		t.Errorf("%s = %q, want the trace of the caller", tracing.TraceIDHeader, got)
+	}
+
+	byName := spans()
+	one := func(name string) exportedSpan {
+		t.Helper()
+		if len(byName[name]) == 0 {
+			names := make([]string, 0, len(byName))
+			for n := range byName {
+				names = append(names, n)
+			}
+			t.Fatalf("no span %q among %v", name, names)
+		}
+		return byName[name][0]
+	}
+
+	httpSpan := one("GET /api/v1/devices/{pcba}")
+	if httpSpan.SpanContext.TraceID != callerTrace || httpSpan.attr("http.response.status_code") != float64(200) {
+		t.Errorf("HTTP span = %+v", httpSpan)
+	}
+	if svc := one("device.GetTimeline"); svc.Parent.SpanID != httpSpan.SpanContext.SpanID {
+		t.Errorf("service span parent = %s, want the HTTP span %s", svc.Parent.SpanID, httpSpan.SpanContext.SpanID)
+	}
+
+	// The job runs in a trace of its own, linked to the upload request.
+	post, submit := one("POST /api/v1/ingestions"), one("ingestion.Submit")
+	if post.SpanContext.TraceID != uploadTrace || submit.Parent.SpanID != post.SpanContext.SpanID {
+		t.Errorf("upload spans = %+v, %+v, want Submit below the request", post, submit)
+	}
+	jobSpan := one("ingestion.job")
+	if len(jobSpan.Links) != 1 || jobSpan.Links[0].SpanContext.SpanID != submit.SpanContext.SpanID || jobSpan.SpanContext.TraceID == uploadTrace {
+		t.Errorf("job span = %+v, want a new trace linked to the Submit span %+v", jobSpan, submit.SpanContext)
+	}
+	if jobSpan.attr("job_id") != job.ID {
+		t.Errorf("job span job_id = %v, want %s", jobSpan.attr("job_id"), job.ID)
+	}
+	for _, stage := range []string{"ingestion.parsing", "ingestion.saving_findings", "ingestion.dispatching", "ingestion.checking_consistency"} {
+		if s := one(stage); s.Parent.SpanID != jobSpan.SpanContext.SpanID {
+			t.Errorf("%s is not a child of the job span", stage)
+		}
+	}
+	dispatch := one("dispatcher.DispatchGroups")
+	if dispatch.Parent.SpanID != one("ingestion.dispatching").SpanContext.SpanID {
+		t.Error("dispatcher.DispatchGroups is not a child of the dispatching stage")
+	}
+	if n := len(byName["dispatcher.dispatchGroup"]); n != 2 {
+		t.Errorf("%d group spans, want 2", n)
+	}
+	if n := len(byName["teststep.InsertTestSteps"]); n == 0 {
+		t.Error("no service spans below the group spans")
+	}
+
+	// Log lines written within a span carry its trace. The job logs its
+	// completion after it is reported finished.
+	var jobLine string
+	for deadline := time.Now().Add(time.Second); jobLine == "" && time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		for _, line := range strings.Split(logs.String(), "\n") {
+			if strings.Contains(line, "Ingestion job completed") {
+				jobLine = line
+			}
+		}
+	}
+	if !strings.Contains(jobLine, jobSpan.SpanContext.TraceID) {
+		t.Errorf("job log line %q does not carry trace %s", jobLine, jobSpan.SpanContext.TraceID)
+	}
+}
+
+func TestTracedQueries(t *testing.T) {
+	spans := traceToFile(t)
+	db := sql.OpenDB(tracing.WrapConnector(fakeConnector{}, "github.com/NoroSaroyan/log-parser/tests/integration"))
+	defer db.Close()
+
+	repo := fakeRepository{db: db}
+	ctx, parent := tracing.Start(context.Background(), "request")
+	if err := repo.CountDevices(ctx); err != nil {
+		t.Fatalf("CountDevices: %v", err)
+	}
+	if err := repo.DeleteDevice(ctx); err == nil {
+		t.Fatal("DeleteDevice succeeded, want the driver error")
+	}
+	parent.End()
+
+	// Outside the statement package, spans are named after the SQL command.
+	other := sql.OpenDB(tracing.WrapConnector(fakeConnector{}, "example.com/elsewhere"))
+	defer other.Close()
+	if _, err := other.ExecContext(ctx, "  update devices set x = $1", 1); err != nil {
+		t.Fatalf("ExecContext: %v", err)
+	}
+
+	byName := spans()
+	count := byName["fakeRepository.CountDevices"]
+	if len(count) != 1 || count[0].Parent.SpanID != byName["request"][0].SpanContext.SpanID {
+		t.Fatalf("spans = %v, want one CountDevices span below the request", byName)
+	}
+	if q := count[0].attr("db.query.text"); q != "SELECT COUNT(*) FROM devices WHERE pcba_number = $1" {
+		t.Errorf("db.query.text = %v, want the normalised SQL without arguments", q)
+	}
+	if op := count[0].attr("db.operation.name"); op != "SELECT" {
+		t.Errorf("db.operation.name = %v", op)
+	}
+	if del := byName["fakeRepository.DeleteDevice"]; len(del) != 1 || del[0].Status.Code != "Error" {
+		t.Errorf("DeleteDevice spans = %+v, want one failed span", del)
+	}
+	if len(byName["UPDATE"]) != 1 {
+		t.Errorf("spans = %v, want an UPDATE span", byName)
+	}
+}
+
+func TestLogLinesWithoutSpan(t *testing.T) {
+	logs := captureLogs(t)
+	logger.InfoContext(context.Background(), "no span here")
+	if strings.Contains(logs.String(), "trace_id") {
+		t.Errorf("log line without a span has a trace_id: %s", logs.String())
+	}
+}
+
+// fakeRepository runs queries through the traced connector like the
+// Postgres repositories.
+type fakeRepository struct {
+	db *sql.DB
+}
+
+func (r fakeRepository) CountDevices(ctx context.Context) error {
+	var n int
+	return r.db.QueryRowContext(ctx, `
+		SELECT COUNT(*)
+		FROM devices
+		WHERE pcba_number = $1`, completePCBA).Scan(&n)
+}
+
+func (r fakeRepository) DeleteDevice(ctx context.Context) error {
+	_, err := r.db.ExecContext(ctx, "DELETE FROM devices")
+	return err
+}
+
+// fakeConnector is a driver whose queries return one row of one column and
+// whose DELETE statements fail.
+type fakeConnector struct{}
+
+func (fakeConnector) Connect(context.Context) (driver.Conn, error) { return fakeConn{}, nil }
+func (fakeConnector) Driver() driver.Driver                        { return nil }
+
+type fakeConn struct{}
+
+func (fakeConn) Prepare(string) (driver.Stmt, error) { return nil, errors.New("not supported") }
+func (fakeConn) Close() error                        { return nil }
+func (fakeConn) Begin() (driver.Tx, error)           { return nil, errors.New("not supported") }
+
+func (fakeConn) QueryContext(context.Context, string, []driver.NamedValue) (driver.Rows, error) {
+	return &fakeRows{}, nil
+}
+
+func (fakeConn) ExecContext(_ context.Context, query string, _ []driver.NamedValue) (driver.Result, error) {
+	if strings.HasPrefix(query, "DELETE") {
+		return nil, errors.New("permission denied")
+	}
+	return driver.RowsAffected(1), nil
+}
+
+type fakeRows struct{ done bool }
+
+func (*fakeRows) Columns() []string { return []string{"count"} }
+func (*fakeRows) Close() error      { return nil }
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if r.done {
+		return io.EOF
+	}
+	r.done = true
+	dest[0] = int64(1)
+	return nil
+}
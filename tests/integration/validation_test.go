@@ -0,0 +1,129 @@
+package integration
+
+import (
+	"context"
+	"testing"
+
+	"github.com/NoroSaroyan/log-parser/internal/app"
+	"github.com/NoroSaroyan/log-parser/internal/domain/models/dto"
+	"github.com/NoroSaroyan/log-parser/internal/infrastructure/database/memory"
+	"github.com/NoroSaroyan/log-parser/internal/services/pipeline"
+	"github.com/NoroSaroyan/log-parser/internal/services/processor"
+)
+
+const malformedPCBA = "h8444-a111"
+
+func TestValidationRejectsMalformedPCBA(t *testing.T) {
+	badIMEI := stationFor("PCBA", completePCBA, "2026-04-14 05:44:08", true, "")
+	badIMEI.LogisticData.IMEI = "860000000000001"
+
+	logText := newLogBuilder(t).
+		steps("Apr 14 05:44:00", pcbaStepsFor(completePCBA)).
+		station("Apr 14 05:44:09", badIMEI).
+		steps("Apr 14 06:00:00", pcbaStepsFor(malformedPCBA)).
+		station("Apr 14 06:00:09", stationFor("PCBA", malformedPCBA, "2026-04-14 06:00:08", true, "")).
+		String()
+
+	result, err := pipeline.Parse("validation.log", []byte(logText))
+	if err != nil {
+		t.Fatalf("pipeline.Parse: %v", err)
+	}
+
+	summary := result.Validation.Summary
+	if summary.GroupsTotal != 2 || summary.GroupsRejected != 1 {
+		t.Fatalf("got %d groups, %d rejected; want 2, 1", summary.GroupsTotal, summary.GroupsRejected)
+	}
+	if len(result.Groups) != 1 || result.Groups[0].TestStationRecords[0].LogisticData.PCBANumber != completePCBA {
+		t.Fatalf("accepted groups = %+v, want only %s", result.Groups, completePCBA)
+	}
+
+	// Rejected groups are never dispatched, but their findings are stored.
+	store := memory.NewStore()
+	application := app.InitializeInMemoryApp(store)
+	if err := application.ValidationService.SaveReport(context.Background(), result.Validation); err != nil {
+		t.Fatalf("SaveReport: %v", err)
+	}
+	ingest(t, application, logText)
+	if got := store.Counts()["test_station_record"]; got != 1 {
+		t.Errorf("test_station_record: got %d rows, want 1", got)
+	}
+
+	rejected, err := application.ValidationService.GetFindingsByPCBANumber(context.Background(), malformedPCBA)
+	if err != nil {
+		t.Fatalf("GetFindingsByPCBANumber: %v", err)
+	}
+	rejects := 0
+	for _, f := range rejected {
+		if f.Severity == processor.SeverityReject {
+			rejects++
+			if f.Rule != "pcba_format" {
+				t.Errorf("unexpected reject finding %+v", f)
+			}
+		}
+	}
+	if rejects != 2 {
+		t.Errorf("got %d reject findings for %s, want 2 (station and scan step)", rejects, malformedPCBA)
+	}
+
+	warned, err := application.ValidationService.GetFindingsByPCBANumber(context.Background(), completePCBA)
+	if err != nil {
+		t.Fatalf("GetFindingsByPCBANumber: %v", err)
+	}
+	var imei *processor.Finding
+	for i := range warned {
+		if warned[i].Rule == "imei" {
+			imei = &warned[i]
+		}
+	}
+	if imei == nil || imei.Severity != processor.SeverityWarn || imei.RecordType != processor.RecordStation {
+		t.Errorf("IMEI finding = %+v, want a warn finding on the station record", imei)
+	}
+
+	summaries, err := application.ValidationService.GetSummariesBySourceFile(context.Background(), "validation.log")
+	if err != nil {
+		t.Fatalf("GetSummariesBySourceFile: %v", err)
+	}
+	if len(summaries) != 1 || summaries[0].GroupsRejected != 1 || summaries[0].RejectFindings != 2 {
+		t.Errorf("summaries = %+v, want one summary with 1 rejected group and 2 reject findings", summaries)
+	}
+}
+
+func TestValidationRules(t *testing.T) {
+	tests := []struct {
+		name  string
+		rule  processor.Rule
+		field string
+		value string
+		bad   bool
+	}{
+		{"valid IMEI", processor.IMEIRule(processor.SeverityWarn), "IMEI", "860000000000009", false},
+		{"IMEI check digit", processor.IMEIRule(processor.SeverityWarn), "IMEI", "860000000000001", true},
+		{"IMEI length", processor.IMEIRule(processor.SeverityWarn), "IMEI", "86000000000009", true},
+		{"valid ICCID", processor.ICCIDRule(processor.SeverityWarn), "TcuICCID", "89860112345678901237", false},
+		{"ICCID check digit", processor.ICCIDRule(processor.SeverityWarn), "TcuICCID", "89860112345678901234", true},
+		{"ICCID prefix", processor.ICCIDRule(processor.SeverityWarn), "TcuICCID", "1234567890123456782", true},
+		{"BLE MAC with colons", processor.BleMacRule(processor.SeverityWarn), "BleMac", "aa:bb:cc:dd:ee:ff", false},
+		{"BLE MAC without colons", processor.BleMacRule(processor.SeverityWarn), "BleMac", "AABBCCDDEEFF", false},
+		{"BLE MAC malformed", processor.BleMacRule(processor.SeverityWarn), "BleMac", "AA:BB:CC:DD:EE", true},
+		{"empty ProductSN", processor.RequiredLogisticFieldsRule([]string{"ProductSN"}, processor.SeverityWarn), "ProductSN", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := stationFor("Final", completePCBA, "2026-04-14 07:12:29", true, "")
+			switch tt.field {
+			case "IMEI":
+				rec.LogisticData.IMEI = tt.value
+			case "TcuICCID":
+				rec.LogisticData.TcuICCID = tt.value
+			case "BleMac":
+				rec.LogisticData.BleMac = tt.value
+			case "ProductSN":
+				rec.LogisticData.ProductSN = tt.value
+			}
+			findings := tt.rule.Check(dto.GroupedDataDTO{TestStationRecords: []dto.TestStationRecordDTO{rec}})
+			if got := len(findings) > 0; got != tt.bad {
+				t.Errorf("%s=%q: findings %+v, want bad=%v", tt.field, tt.value, findings, tt.bad)
+			}
+		})
+	}
+}
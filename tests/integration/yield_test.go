@@ -0,0 +1,90 @@
+package integration
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/NoroSaroyan/log-parser/internal/app"
+	"github.com/NoroSaroyan/log-parser/internal/domain/models/dto"
+	"github.com/NoroSaroyan/log-parser/internal/infrastructure/database/memory"
+)
+
+func TestYieldEndpoint(t *testing.T) {
+	logText := newLogBuilder(t).
+		// completePCBA fails PCBA once, passes the retest and passes Final.
+		steps("Apr 14 05:44:00", pcbaStepsFor(completePCBA)).
+		station("Apr 14 05:44:09", stationFor("PCBA", completePCBA, "2026-04-14 05:44:08", false, "E101")).
+		steps("Apr 14 05:50:00", pcbaStepsFor(completePCBA)).
+		station("Apr 14 05:50:09", stationFor("PCBA", completePCBA, "2026-04-14 05:50:08", true, "")).
+		steps("Apr 14 07:12:00", finalStepsFor(completePCBA)).
+		station("Apr 14 07:12:30", stationFor("Final", completePCBA, "2026-04-14 07:12:29", true, "")).
+		// bug1PCBA never passes PCBA.
+		steps("Apr 14 08:00:00", pcbaStepsFor(bug1PCBA)).
+		station("Apr 14 08:00:09", stationFor("PCBA", bug1PCBA, "2026-04-14 08:00:08", false, "E101")).
+		// flashedPCBA passes PCBA on the next day.
+		steps("Apr 15 09:00:00", pcbaStepsFor(flashedPCBA)).
+		station("Apr 15 09:00:09", stationFor("PCBA", flashedPCBA, "2026-04-15 09:00:08", true, "")).
+		String()
+
+	application := app.InitializeInMemoryApp(memory.NewStore())
+	ingest(t, application, logText)
+	srv := newServer(application)
+	defer srv.Close()
+
+	yield := func(query string) dto.YieldReportDTO {
+		t.Helper()
+		var rep dto.YieldReportDTO
+		if code := get(t, srv, "/api/v1/analytics/yield"+query, &rep); code != http.StatusOK {
+			t.Fatalf("/analytics/yield%s status = %d, want 200", query, code)
+		}
+		return rep
+	}
+
+	type row struct {
+		bucket, station                                    string
+		attempted, sessions, firstPass, eventually, failed int
+	}
+	check := func(query string, want []row) {
+		t.Helper()
+		rep := yield(query)
+		if len(rep.Rows) != len(want) {
+			t.Fatalf("%s: got %d rows, want %d: %+v", query, len(rep.Rows), len(want), rep.Rows)
+		}
+		for i, w := range want {
+			r := rep.Rows[i]
+			got := row{r.Bucket, r.StationType, r.Attempted, r.Sessions, r.FirstPassPassed, r.EventuallyPassed, r.Failed}
+			if got != w {
+				t.Errorf("%s: row %d = %+v, want %+v", query, i, got, w)
+			}
+		}
+	}
+
+	check("", []row{
+		{"2026-04-14", "Final", 1, 1, 1, 1, 0},
+		{"2026-04-14", "PCBA", 2, 3, 0, 1, 1},
+		{"2026-04-15", "PCBA", 1, 1, 1, 1, 0},
+	})
+	check("?bucket=week&station_type=PCBA", []row{
+		{"2026-04-13", "PCBA", 3, 4, 1, 2, 1},
+	})
+	// The range applies to the first attempt only; the retest still counts.
+	check("?bucket=hour&station_type=PCBA&from=2026-04-14%2005:00:00&to=2026-04-14%2005:45:00", []row{
+		{"2026-04-14 05:00", "PCBA", 1, 2, 0, 1, 0},
+	})
+
+	rep := yield("?bucket=week&station_type=PCBA")
+	if r := rep.Rows[0]; r.FirstPassYield != 1.0/3 || r.FinalYield != 2.0/3 {
+		t.Errorf("yields = %v / %v, want 1/3 and 2/3", r.FirstPassYield, r.FinalYield)
+	}
+
+	rep = yield("?group_by=part_number,product_line&bucket=week")
+	if len(rep.Rows) != 1 || rep.Rows[0].StationType != "" || rep.Rows[0].PartNumber == "" || rep.Rows[0].Attempted != 4 {
+		t.Errorf("part number grouping = %+v, want one row without station type over 4 device-stations", rep.Rows)
+	}
+
+	for _, query := range []string{"?group_by=tester", "?group_by=serial", "?bucket=month", "?from=yesterday", "?from=2026-04-15&to=2026-04-14"} {
+		if code := get(t, srv, "/api/v1/analytics/yield"+query, nil); code != http.StatusBadRequest {
+			t.Errorf("/analytics/yield%s status = %d, want 400", query, code)
+		}
+	}
+}